@@ -29,6 +29,9 @@ type Backend struct {
 	// ArchivePack indicates whether the handler should handle
 	// git-upload-archive requests.
 	// ArchivePack bool // TODO: Implement git-upload-archive support
+	// ReceiveHooks are run when serving git-receive-pack requests. If nil,
+	// no hooks are run.
+	ReceiveHooks *transport.ReceiveHooks
 }
 
 // NewBackend creates a new [Backend] for the given loader. It defaults to
@@ -105,6 +108,7 @@ func (b *Backend) ServeTCP(ctx context.Context, c io.ReadWriteCloser, req *packp
 			io.NopCloser(r), ioutil.WriteNopCloser(wc),
 			&transport.ReceivePackOptions{
 				GitProtocol: version,
+				Hooks:       b.ReceiveHooks,
 			})
 	}
 
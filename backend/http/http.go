@@ -57,6 +57,9 @@ type Backend struct {
 	// Prefix is a path prefix that will be stripped from the URL path before
 	// matching the service patterns.
 	Prefix string
+	// ReceiveHooks are run when serving git-receive-pack requests. If nil,
+	// no hooks are run.
+	ReceiveHooks *transport.ReceiveHooks
 }
 
 // NewBackend returns a Git HTTP handler that serves git repositories over
@@ -106,6 +109,7 @@ func (b *Backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 			ctx := r.Context()
 			ctx = context.WithValue(ctx, contextKey("errorLog"), b.ErrorLog)
+			ctx = context.WithValue(ctx, contextKey("receiveHooks"), b.ReceiveHooks)
 			ctx = context.WithValue(ctx, contextKey("repo"), m[1])
 			ctx = context.WithValue(ctx, contextKey("file"), file)
 			ctx = context.WithValue(ctx, contextKey("service"), s.svc)
@@ -185,11 +189,13 @@ func serviceRPC(w http.ResponseWriter, r *http.Request) {
 				StatelessRPC:  true,
 			})
 	case transport.ReceivePackService:
+		hooks, _ := ctx.Value(contextKey("receiveHooks")).(*transport.ReceiveHooks)
 		err = transport.ReceivePack(ctx, st, reader, frw,
 			&transport.ReceivePackOptions{
 				GitProtocol:   version,
 				AdvertiseRefs: false,
 				StatelessRPC:  true,
+				Hooks:         hooks,
 			})
 	default:
 		// TODO: Support git-upload-archive
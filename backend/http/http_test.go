@@ -48,7 +48,7 @@ e8d3ffab552895c19b9fcf7aa264d277cde33881	refs/remotes/origin/branch
 6ecf0ef2c2dffb796033e5a02219af86ec6584e5	refs/remotes/origin/master
 `
 	expectedSmart := `001e# service=git-upload-pack
-000000c76ecf0ef2c2dffb796033e5a02219af86ec6584e5 HEAD` + "\x00" + `agent=` + capability.DefaultAgent() + ` ofs-delta side-band-64k multi_ack multi_ack_detailed side-band no-progress shallow object-format=sha1 symref=HEAD:refs/heads/master
+000000d26ecf0ef2c2dffb796033e5a02219af86ec6584e5 HEAD` + "\x00" + `agent=` + capability.DefaultAgent() + ` ofs-delta side-band-64k multi_ack multi_ack_detailed side-band no-progress shallow deepen-not object-format=sha1 symref=HEAD:refs/heads/master
 003fe8d3ffab552895c19b9fcf7aa264d277cde33881 refs/heads/branch
 003f6ecf0ef2c2dffb796033e5a02219af86ec6584e5 refs/heads/master
 00466ecf0ef2c2dffb796033e5a02219af86ec6584e5 refs/remotes/origin/HEAD
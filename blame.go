@@ -13,23 +13,71 @@ import (
 	"github.com/sergi/go-diff/diffmatchpatch"
 
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/mailmap"
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/go-git/go-git/v6/utils/diff"
 )
 
+// ErrInvalidLineRange is returned by BlameWithOptions when the line range to
+// blame is not within the lines of the file.
+var ErrInvalidLineRange = errors.New("invalid line range")
+
 // BlameResult represents the result of a Blame operation.
 type BlameResult struct {
 	// Path is the path of the File that we're blaming.
 	Path string
 	// Rev (Revision) is the hash of the specified Commit used to generate this result.
 	Rev plumbing.Hash
-	// Lines contains every line with its authorship.
+	// Lines contains every line with its authorship, or the lines of the
+	// range blamed.
 	Lines []*Line
+	// StartLine is the number of the first line of Lines, starting from 1.
+	// Zero is the same as 1.
+	StartLine int
+}
+
+// BlameOptions describes how a blame should be performed.
+type BlameOptions struct {
+	// Mailmap, when set, rewrites the author of each line to its canonical
+	// form. See Repository.Mailmap.
+	Mailmap *mailmap.Mailmap
+	// LineRange restricts the blame to the lines from LineRange[0] to
+	// LineRange[1], starting from 1 and included, as git blame -L does. Only
+	// these lines are traced through the history. An end of zero, or past
+	// the end of the file, means the last line. The zero value blames the
+	// whole file.
+	LineRange [2]int
+}
+
+// lineRange returns the indexes of the first line to blame, and of the one
+// following the last, among the n lines of the file.
+func (o *BlameOptions) lineRange(n int) (int, int, error) {
+	start, end := o.LineRange[0], o.LineRange[1]
+	if start == 0 && end == 0 {
+		return 0, n, nil
+	}
+
+	if end == 0 || end > n {
+		end = n
+	}
+
+	if start < 1 || start > n || end < start {
+		return 0, 0, fmt.Errorf("%w: %d,%d: file has %d lines", ErrInvalidLineRange, o.LineRange[0], o.LineRange[1], n)
+	}
+
+	return start - 1, end, nil
 }
 
 // Blame returns a BlameResult with the information about the last author of
 // each line from file `path` at commit `c`.
 func Blame(c *object.Commit, path string) (*BlameResult, error) {
+	return BlameWithOptions(c, path, &BlameOptions{})
+}
+
+// BlameWithOptions returns a BlameResult with the information about the last
+// author of each line from file `path` at commit `c`, as described by the
+// given BlameOptions.
+func BlameWithOptions(c *object.Commit, path string, o *BlameOptions) (*BlameResult, error) {
 	// The file to blame is identified by the input arguments:
 	// commit and path. commit is a Commit object obtained from a Repository. Path
 	// represents a path to a specific file contained in the repository.
@@ -59,11 +107,15 @@ func Blame(c *object.Commit, path string) (*BlameResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	finalLength := len(finalLines)
+	start, end, err := o.lineRange(len(finalLines))
+	if err != nil {
+		return nil, err
+	}
 
-	needsMap := make([]lineMap, finalLength)
+	// Only the lines of the range are tracked through the history.
+	needsMap := make([]lineMap, end-start)
 	for i := range needsMap {
-		needsMap[i] = lineMap{i, i, nil, -1}
+		needsMap[i] = lineMap{start + i, start + i, nil, -1}
 	}
 	contents, err := file.Contents()
 	if err != nil {
@@ -103,18 +155,24 @@ func Blame(c *object.Commit, path string) (*BlameResult, error) {
 		}
 	}
 
-	b.lineToCommit = make([]*object.Commit, finalLength)
+	b.lineToCommit = make([]*object.Commit, len(needsMap))
 	for i := range needsMap {
 		b.lineToCommit[i] = needsMap[i].Commit
 	}
 
-	lines := newLines(finalLines, b.lineToCommit)
+	lines := newLines(finalLines[start:end], b.lineToCommit, o.Mailmap)
 
-	return &BlameResult{
+	result := &BlameResult{
 		Path:  path,
 		Rev:   c.Hash,
 		Lines: lines,
-	}, nil
+	}
+
+	if start > 0 {
+		result.StartLine = start + 1
+	}
+
+	return result, nil
 }
 
 // Line values represent the contents and author of a line in BlamedResult values.
@@ -141,12 +199,13 @@ func newLine(author, authorName, text string, date time.Time, hash plumbing.Hash
 	}
 }
 
-func newLines(contents []string, commits []*object.Commit) []*Line {
+func newLines(contents []string, commits []*object.Commit, m *mailmap.Mailmap) []*Line {
 	result := make([]*Line, 0, len(contents))
 	for i := range contents {
+		author := m.ResolveSignature(commits[i].Author)
 		result = append(result, newLine(
-			commits[i].Author.Email, commits[i].Author.Name, contents[i],
-			commits[i].Author.When, commits[i].Hash,
+			author.Email, author.Name, contents[i],
+			author.When, commits[i].Hash,
 		))
 	}
 
@@ -449,15 +508,17 @@ func applyNeeds(child *queueItem, needsMap []lineMap, identicalToChild bool, par
 func (b BlameResult) String() string {
 	var buf bytes.Buffer
 
+	first := max(b.StartLine, 1)
+
 	// max line number length
-	mlnl := len(strconv.Itoa(len(b.Lines)))
+	mlnl := len(strconv.Itoa(first + len(b.Lines) - 1))
 	// max author length
 	mal := b.maxAuthorLength()
 	format := fmt.Sprintf("%%s (%%-%ds %%s %%%dd) %%s\n", mal, mlnl)
 
 	for ln := range b.Lines {
 		_, _ = fmt.Fprintf(&buf, format, b.Lines[ln].Hash.String()[:8],
-			b.Lines[ln].AuthorName, b.Lines[ln].Date.Format("2006-01-02 15:04:05 -0700"), ln+1, b.Lines[ln].Text)
+			b.Lines[ln].AuthorName, b.Lines[ln].Date.Format("2006-01-02 15:04:05 -0700"), first+ln, b.Lines[ln].Text)
 	}
 	return buf.String()
 }
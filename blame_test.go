@@ -25,7 +25,7 @@ func (s *BlameSuite) TestNewLines() {
 	lines := newLines([]string{"foo"}, []*object.Commit{{
 		Hash:    h,
 		Message: "foo",
-	}})
+	}}, nil)
 
 	s.Len(lines, 1)
 	s.Equal("foo", lines[0].Text)
@@ -36,7 +36,7 @@ func (s *BlameSuite) TestNewLinesWithNewLine() {
 	lines := newLines([]string{"foo", ""}, []*object.Commit{
 		{Message: "foo"},
 		{Message: "bar"},
-	})
+	}, nil)
 
 	s.Len(lines, 2)
 	s.Equal("foo", lines[0].Text)
@@ -69,6 +69,43 @@ func (s *BlameSuite) TestBlame() {
 	}
 }
 
+func (s *BlameSuite) TestBlameLineRange() {
+	for _, t := range blameTests {
+		n := len(t.blames)
+		if n < 3 {
+			continue
+		}
+
+		r := s.NewRepositoryFromPackfile(fixtures.ByURL(t.repo).One())
+		exp := s.mockBlame(t, r)
+		commit, err := r.CommitObject(plumbing.NewHash(t.rev))
+		s.Require().NoError(err)
+
+		start, end := n/3+1, 2*n/3
+		obt, err := BlameWithOptions(commit, t.path, &BlameOptions{LineRange: [2]int{start, end}})
+		s.Require().NoError(err)
+		s.Equal(start, obt.StartLine)
+		s.Equal(exp.Lines[start-1:end], obt.Lines, t.path)
+	}
+}
+
+func (s *BlameSuite) TestBlameInvalidLineRange() {
+	t := blameTests[2]
+	r := s.NewRepositoryFromPackfile(fixtures.ByURL(t.repo).One())
+	commit, err := r.CommitObject(plumbing.NewHash(t.rev))
+	s.Require().NoError(err)
+
+	obt, err := BlameWithOptions(commit, t.path, &BlameOptions{LineRange: [2]int{140, 1000}})
+	s.Require().NoError(err)
+	s.Len(obt.Lines, 3)
+	s.Contains(obt.String(), " 142) ")
+
+	for _, lr := range [][2]int{{0, 10}, {143, 0}, {10, 5}} {
+		_, err := BlameWithOptions(commit, t.path, &BlameOptions{LineRange: lr})
+		s.ErrorIs(err, ErrInvalidLineRange)
+	}
+}
+
 func (s *BlameSuite) mockBlame(t blameTest, r *Repository) (blame *BlameResult) {
 	commit, err := r.CommitObject(plumbing.NewHash(t.rev))
 	s.Require().NoError(err, fmt.Sprintf("%v: repo=%s, rev=%s", err, t.repo, t.rev))
@@ -0,0 +1,192 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+)
+
+// BlobFetcher retrieves on demand the blobs omitted by a blobless fetch, from
+// the remote they were fetched from. It is safe for concurrent use.
+//
+// As the blobs are wanted by their hash, the remote must allow any object to
+// be wanted: a git or go-git server needs uploadpack.allowAnySHA1InWant set,
+// and rejects the fetches of the blobs otherwise.
+type BlobFetcher struct {
+	r    *Repository
+	o    *FetchOptions
+	sess transport.Session
+
+	mu   sync.Mutex
+	conn transport.Connection
+}
+
+// FetchBlobless fetches from the remote named as FetchOptions.RemoteName
+// omitting every blob, as with a "blob:none" filter, and returns a
+// BlobFetcher to retrieve the blobs on demand. Any Filter set in the options
+// is replaced. The returned BlobFetcher must be closed after use.
+//
+// Paired with a memory storer, a narrow RefSpec and a shallow Depth, this
+// allows to inspect a remote repository cheaply without touching the disk.
+//
+// Returns NoErrAlreadyUpToDate along with a usable BlobFetcher if there are no
+// changes to be fetched.
+func (r *Repository) FetchBlobless(ctx context.Context, o *FetchOptions) (*BlobFetcher, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	remote, err := r.Remote(o.RemoteName)
+	if err != nil {
+		return nil, err
+	}
+
+	fo := *o
+	fo.Filter = packp.FilterBlobNone()
+
+	_, fetchErr := remote.fetch(ctx, &fo)
+	if fetchErr != nil && !errors.Is(fetchErr, NoErrAlreadyUpToDate) {
+		return nil, fetchErr
+	}
+
+	f, err := newBlobFetcher(r, &fo)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, fetchErr
+}
+
+// newBlobFetcher returns a BlobFetcher for the remote at o.RemoteURL.
+func newBlobFetcher(r *Repository, o *FetchOptions) (*BlobFetcher, error) {
+	c, ep, err := newClient(r.Storer, o.RemoteURL, o.InsecureSkipTLS, o.CABundle, o.ProxyOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := c.NewSession(r.Storer, ep, o.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlobFetcher{r: r, o: o, sess: sess}, nil
+}
+
+// BlobObject returns the blob with the given hash, fetching it from the
+// remote if it is missing from the repository.
+func (f *BlobFetcher) BlobObject(ctx context.Context, h plumbing.Hash) (*object.Blob, error) {
+	b, err := f.r.BlobObject(h)
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return b, err
+	}
+
+	if err := f.fetch(ctx, []plumbing.Hash{h}); err != nil {
+		return nil, err
+	}
+
+	return f.r.BlobObject(h)
+}
+
+// BlobObjects returns the blobs with the given hashes, in the same order,
+// fetching the ones missing from the repository from the remote in a single
+// request.
+func (f *BlobFetcher) BlobObjects(ctx context.Context, hs ...plumbing.Hash) ([]*object.Blob, error) {
+	var missing []plumbing.Hash
+	for _, h := range hs {
+		err := f.r.Storer.HasEncodedObject(h)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			missing = append(missing, h)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := f.fetch(ctx, missing); err != nil {
+			return nil, err
+		}
+	}
+
+	blobs := make([]*object.Blob, len(hs))
+	for i, h := range hs {
+		b, err := f.r.BlobObject(h)
+		if err != nil {
+			return nil, err
+		}
+
+		blobs[i] = b
+	}
+
+	return blobs, nil
+}
+
+// fetch fetches the objects wants, along with the objects they reference,
+// in a single request. The session is kept for the following fetches.
+func (f *BlobFetcher) fetch(ctx context.Context, wants []plumbing.Hash) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	conn, err := f.connection(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = conn.Fetch(ctx, &transport.FetchRequest{
+		Wants:    wants,
+		Progress: f.o.Progress,
+		Promisor: true,
+	})
+
+	// Full-duplex connections are done after a single fetch, a new one being
+	// opened from the session for the following requests, while stateless
+	// ones, such as HTTP, are kept open.
+	if !conn.StatelessRPC() || err != nil {
+		f.conn = nil
+		if cerr := conn.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("error closing connection: %w", cerr)
+		}
+	}
+
+	if err != nil && !errors.Is(err, transport.ErrNoChange) {
+		return err
+	}
+
+	return nil
+}
+
+func (f *BlobFetcher) connection(ctx context.Context) (transport.Connection, error) {
+	if f.conn != nil {
+		return f.conn, nil
+	}
+
+	conn, err := f.sess.Handshake(ctx, transport.UploadPackService)
+	if err != nil {
+		return nil, err
+	}
+
+	f.conn = conn
+	return conn, nil
+}
+
+// Close closes the connection to the remote, if any is open.
+func (f *BlobFetcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.conn == nil {
+		return nil
+	}
+
+	err := f.conn.Close()
+	f.conn = nil
+	return err
+}
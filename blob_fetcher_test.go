@@ -0,0 +1,185 @@
+package git
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// newBlobFetcherTestRemote creates a repository on disk with a master and an
+// other branch, each one with a commit adding a different file. It returns its
+// URL and the hash of the blob only reachable from the other branch.
+func newBlobFetcherTestRemote(t *testing.T) (string, plumbing.Hash) {
+	t.Helper()
+
+	url := t.TempDir()
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	sig := &object.Signature{Name: "foo", Email: "foo@foo.foo", When: time.Now()}
+	commit := func(name, content string) plumbing.Hash {
+		require.NoError(t, util.WriteFile(fs, name, []byte(content), 0o644))
+		_, err := w.Add(name)
+		require.NoError(t, err)
+
+		h, err := w.Commit(name, &CommitOptions{Author: sig, Committer: sig})
+		require.NoError(t, err)
+		return h
+	}
+
+	commit("master.txt", "master\n")
+	require.NoError(t, w.Checkout(&CheckoutOptions{Branch: "refs/heads/other", Create: true}))
+	h := commit("other.txt", "other\n")
+
+	c, err := r.CommitObject(h)
+	require.NoError(t, err)
+	f, err := c.File("other.txt")
+	require.NoError(t, err)
+
+	server, err := PlainInit(url, true)
+	require.NoError(t, err)
+	// The missing objects of partial clones are wanted by hash.
+	cfg, err := server.Config()
+	require.NoError(t, err)
+	cfg.UploadPack.AllowAnySHA1InWant = config.NewOptBool(true)
+	require.NoError(t, server.SetConfig(cfg))
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: "server", URLs: []string{url}})
+	require.NoError(t, err)
+	require.NoError(t, r.Push(&PushOptions{
+		RemoteName: "server",
+		RefSpecs:   []config.RefSpec{"refs/heads/*:refs/heads/*"},
+	}))
+	require.NoError(t, server.Storer.SetReference(
+		plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.Master)))
+
+	return url, f.Hash
+}
+
+func TestFetchIntoMemoryNarrowShallow(t *testing.T) {
+	t.Parallel()
+
+	url, otherBlob := newBlobFetcherTestRemote(t)
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: DefaultRemoteName, URLs: []string{url}})
+	require.NoError(t, err)
+
+	err = r.Fetch(&FetchOptions{
+		RefSpecs: []config.RefSpec{"refs/heads/master:refs/remotes/origin/master"},
+		Depth:    1,
+		Tags:     NoTags,
+	})
+	require.NoError(t, err)
+
+	ref, err := r.Reference("refs/remotes/origin/master", true)
+	require.NoError(t, err)
+
+	c, err := r.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	f, err := c.File("master.txt")
+	require.NoError(t, err)
+	content, err := f.Contents()
+	require.NoError(t, err)
+	assert.Equal(t, "master\n", content)
+
+	_, err = r.Reference("refs/remotes/origin/other", true)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+	_, err = r.BlobObject(otherBlob)
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+}
+
+func TestBlobFetcherBlobObject(t *testing.T) {
+	t.Parallel()
+
+	url, otherBlob := newBlobFetcherTestRemote(t)
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: DefaultRemoteName, URLs: []string{url}})
+	require.NoError(t, err)
+
+	err = r.Fetch(&FetchOptions{
+		RefSpecs: []config.RefSpec{"refs/heads/master:refs/remotes/origin/master"},
+	})
+	require.NoError(t, err)
+
+	f, err := newBlobFetcher(r, &FetchOptions{RemoteURL: url})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+
+	b, err := f.BlobObject(context.Background(), otherBlob)
+	require.NoError(t, err)
+	assert.Equal(t, otherBlob, b.Hash)
+
+	// The blob is now stored locally.
+	_, err = r.BlobObject(otherBlob)
+	assert.NoError(t, err)
+}
+
+func TestBlobFetcherBlobObjects(t *testing.T) {
+	t.Parallel()
+
+	url, otherBlob := newBlobFetcherTestRemote(t)
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: DefaultRemoteName, URLs: []string{url}})
+	require.NoError(t, err)
+
+	err = r.Fetch(&FetchOptions{
+		RefSpecs: []config.RefSpec{"refs/heads/master:refs/remotes/origin/master"},
+	})
+	require.NoError(t, err)
+
+	ref, err := r.Reference("refs/remotes/origin/master", true)
+	require.NoError(t, err)
+	c, err := r.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	masterFile, err := c.File("master.txt")
+	require.NoError(t, err)
+
+	f, err := newBlobFetcher(r, &FetchOptions{RemoteURL: url})
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+
+	blobs, err := f.BlobObjects(context.Background(), otherBlob, masterFile.Hash)
+	require.NoError(t, err)
+	require.Len(t, blobs, 2)
+	assert.Equal(t, otherBlob, blobs[0].Hash)
+	assert.Equal(t, masterFile.Hash, blobs[1].Hash)
+
+	// A second call finds the blobs locally.
+	_, err = f.BlobObjects(context.Background(), otherBlob)
+	assert.NoError(t, err)
+}
+
+func TestFetchBloblessFilterNotSupported(t *testing.T) {
+	t.Parallel()
+
+	url, _ := newBlobFetcherTestRemote(t)
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: DefaultRemoteName, URLs: []string{url}})
+	require.NoError(t, err)
+
+	// The built-in server does not support filters.
+	_, err = r.FetchBlobless(context.Background(), &FetchOptions{})
+	assert.ErrorIs(t, err, transport.ErrFilterNotSupported)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -86,6 +87,30 @@ type Config struct {
 		FileMode bool
 		// HooksPath is the path to look for hooks instead of $GIT_DIR/hooks.
 		HooksPath string
+		// Abbrev is the length object names are abbreviated to, an integer,
+		// "auto" or "no" to disable the abbreviation.
+		Abbrev string
+		// SparseCheckout enables the sparse checkout of the worktree, as
+		// defined by the patterns at $GIT_DIR/info/sparse-checkout.
+		SparseCheckout bool
+		// SparseCheckoutCone indicates that the sparse checkout patterns are
+		// restricted to directories, known as "cone mode".
+		SparseCheckoutCone bool
+		// Symlinks, if explicitly false, checks out the symbolic links as
+		// plain files containing the path of their target.
+		Symlinks OptBool
+		// UseReplaceRefs, if explicitly false, reads the objects as they are
+		// stored, ignoring the replacements refs/replace/ references define.
+		UseReplaceRefs OptBool
+		// LogAllRefUpdates enables the reflogs of the updated references:
+		// "true" creates them for HEAD, the branches, the remote-tracking
+		// branches and the notes, "always" for every reference, and "false"
+		// only appends to the existing ones. If empty, it is true unless the
+		// repository is bare.
+		LogAllRefUpdates string
+		// IgnoreCase matches the files of the worktree with the entries of the
+		// index regardless of their case, for case-insensitive filesystems.
+		IgnoreCase bool
 	}
 
 	User user
@@ -130,6 +155,17 @@ type Config struct {
 		// compression.  The default is 10.  A value of 0 turns off
 		// delta compression entirely.
 		Window uint
+		// Depth is the maximum length of the delta chains. The default
+		// is 50.
+		Depth uint
+		// WindowMemory bounds the memory, in bytes, used by the sliding
+		// window of each delta search, which shrinks as needed. It may
+		// be set with a k, m or g suffix in the config file. The default
+		// is 0, meaning no limit.
+		WindowMemory uint64
+		// Threads is the number of delta searches run concurrently. The
+		// default is 0, meaning the number of CPUs.
+		Threads uint
 		// ReadReverseIndex controls whether Git reads .rev files from
 		// disk. When false, a reverse index is generated in memory on
 		// demand instead. Defaults to true.
@@ -139,6 +175,77 @@ type Config struct {
 		WriteReverseIndex bool
 	}
 
+	Mailmap struct {
+		// File is the path of a mailmap file read in addition to the
+		// .mailmap file at the root of the worktree.
+		File string
+		// Blob is a reference to a blob holding a mailmap, such as
+		// "HEAD:.mailmap". Bare repositories default to "HEAD:.mailmap".
+		Blob string
+	}
+
+	Fetch struct {
+		// NegotiationAlgorithm selects how the haves sent to the server
+		// during a fetch are chosen, "default", "consecutive",
+		// "skipping" or "noop".
+		NegotiationAlgorithm string
+	}
+
+	Trailer struct {
+		// Separators are the characters separating the keys of the
+		// trailers of the commit messages from their values.
+		Separators string
+	}
+
+	Safe struct {
+		// Directories are the repositories which can be opened although
+		// owned by another user, "*" for all of them. An empty value
+		// clears the previous ones. Only the global and system configs
+		// are considered.
+		Directories []string
+	}
+
+	Merge struct {
+		// ConflictStyle is the style of the conflicts written by the merges,
+		// "merge", "diff3" or "zdiff3".
+		ConflictStyle string
+	}
+
+	Pull struct {
+		// Rebase sets whether pull rebases the current branch onto the
+		// fetched one instead of merging it, "true", "false", "merges" or
+		// "interactive". It is overridden by branch.<name>.rebase.
+		Rebase string
+	}
+
+	Receive struct {
+		// DenyNonFastForwards rejects the pushed updates of branches that
+		// are not fast-forwards, even when forced.
+		DenyNonFastForwards OptBool
+		// DenyDeletes rejects the pushed deletions of branches.
+		DenyDeletes OptBool
+		// DenyCurrentBranch sets how the pushed updates of the branch
+		// checked out in a non-bare repository are handled: "refuse" or
+		// "true" reject them, "warn", "ignore" or "false" allow them. The
+		// worktree is never updated, so "updateInstead" rejects them too.
+		// Unset, they are rejected, as with git.
+		DenyCurrentBranch string
+	}
+
+	UploadPack struct {
+		// AllowTipSHA1InWant allows the clients to want the objects at the
+		// tip of any reference, even if not advertised.
+		AllowTipSHA1InWant OptBool
+		// AllowReachableSHA1InWant allows the clients to want the commits
+		// reachable from any reference, even if not advertised.
+		AllowReachableSHA1InWant OptBool
+		// AllowAnySHA1InWant allows the clients to want any object of the
+		// repository. It must be set for the partial clones to fetch the
+		// missing blobs and trees on demand, as they are not reachable
+		// commits.
+		AllowAnySHA1InWant OptBool
+	}
+
 	Init struct {
 		// DefaultBranch Allows overriding the default branch name
 		// e.g. when initializing a new repository or when cloning
@@ -159,6 +266,10 @@ type Config struct {
 		// When true, each worktree may have a config.worktree file that
 		// overrides settings in the common .git/config.
 		WorktreeConfig bool
+		// PartialClone is the name of the promisor remote of a partial
+		// clone, from which the objects missing from the repository are
+		// fetched when read.
+		PartialClone string
 	}
 
 	Protocol struct {
@@ -187,6 +298,12 @@ type Config struct {
 	// URLs list of url rewrite rules, if repo url starts with URL.InsteadOf value, it will be replaced with the
 	// key instead.
 	URLs map[string]*URL
+	// HTTP holds the settings of the HTTP transport, by URL of the
+	// http.<url> sections, the ones of the http section by the empty URL.
+	HTTP map[string]*HTTP
+	// DiffDrivers holds the diff drivers, by name, which should equal
+	// DiffDriver.Name.
+	DiffDrivers map[string]*DiffDriver
 	// Raw contains the raw information of a config file. The main goal is
 	// preserve the parsed information from the original format, to avoid
 	// dropping unsupported fields.
@@ -298,15 +415,18 @@ func merge(dst, src any) {
 // NewConfig returns a new empty Config.
 func NewConfig() *Config {
 	config := &Config{
-		Remotes:    make(map[string]*RemoteConfig),
-		Submodules: make(map[string]*Submodule),
-		Branches:   make(map[string]*Branch),
-		URLs:       make(map[string]*URL),
-		Raw:        format.New(),
+		Remotes:     make(map[string]*RemoteConfig),
+		Submodules:  make(map[string]*Submodule),
+		Branches:    make(map[string]*Branch),
+		URLs:        make(map[string]*URL),
+		HTTP:        make(map[string]*HTTP),
+		DiffDrivers: make(map[string]*DiffDriver),
+		Raw:         format.New(),
 	}
 
 	config.Core.FileMode = DefaultFileMode
 	config.Pack.Window = DefaultPackWindow
+	config.Pack.Depth = DefaultPackDepth
 	config.Pack.ReadReverseIndex = true
 	config.Pack.WriteReverseIndex = true
 	config.Protocol.Version = DefaultProtocolVersion
@@ -423,9 +543,19 @@ const (
 	committerSection           = "committer"
 	gpgSection                 = "gpg"
 	initSection                = "init"
+	pullSection                = "pull"
+	receiveSection             = "receive"
+	uploadPackSection          = "uploadpack"
 	urlSection                 = "url"
+	httpSection                = "http"
+	diffSection                = "diff"
 	extensionsSection          = "extensions"
 	protocolSection            = "protocol"
+	mailmapSection             = "mailmap"
+	mergeSection               = "merge"
+	fetchSection               = "fetch"
+	trailerSection             = "trailer"
+	safeSection                = "safe"
 	fetchKey                   = "fetch"
 	urlKey                     = "url"
 	pushurlKey                 = "pushurl"
@@ -433,6 +563,9 @@ const (
 	worktreeKey                = "worktree"
 	commentCharKey             = "commentChar"
 	windowKey                  = "window"
+	depthKey                   = "depth"
+	windowMemoryKey            = "windowMemory"
+	threadsKey                 = "threads"
 	readReverseIndexKey        = "readReverseIndex"
 	writeReverseIndexKey       = "writeReverseIndex"
 	mergeKey                   = "merge"
@@ -445,18 +578,43 @@ const (
 	repositoryFormatVersionKey = "repositoryformatversion"
 	objectFormatKey            = "objectformat"
 	worktreeConfigKey          = "worktreeConfig"
+	partialCloneKey            = "partialClone"
+	promisorKey                = "promisor"
+	partialCloneFilterKey      = "partialclonefilter"
 	mirrorKey                  = "mirror"
 	versionKey                 = "version"
 	autoCRLFKey                = "autocrlf"
 	fileModeKey                = "filemode"
+	symlinksKey                = "symlinks"
+	useReplaceRefsKey          = "useReplaceRefs"
+	logAllRefUpdatesKey        = "logAllRefUpdates"
+	ignoreCaseKey              = "ignoreCase"
 	hooksPathKey               = "hooksPath"
+	abbrevKey                  = "abbrev"
+	sparseCheckoutKey          = "sparseCheckout"
+	sparseCheckoutConeKey      = "sparseCheckoutCone"
 	formatKey                  = "format"
 	allowedSignersFileKey      = "allowedSignersFile"
 	gpgSignKey                 = "gpgSign"
+	fileKey                    = "file"
+	blobKey                    = "blob"
+	conflictStyleKey           = "conflictStyle"
+	denyNonFastForwardsKey     = "denyNonFastForwards"
+	denyDeletesKey             = "denyDeletes"
+	denyCurrentBranchKey       = "denyCurrentBranch"
+	allowTipSHA1InWantKey      = "allowTipSHA1InWant"
+	allowReachableSHA1Key      = "allowReachableSHA1InWant"
+	allowAnySHA1InWantKey      = "allowAnySHA1InWant"
+	negotiationAlgorithmKey    = "negotiationAlgorithm"
+	separatorsKey              = "separators"
+	directoryKey               = "directory"
 
 	// DefaultPackWindow holds the number of previous objects used to
 	// generate deltas. The value 10 is the same used by git command.
 	DefaultPackWindow = uint(10)
+	// DefaultPackDepth holds the maximum length of the delta chains. The
+	// value 50 is the same used by git command.
+	DefaultPackDepth = uint(50)
 	// DefaultFileMode is the default file mode used by git command.
 	DefaultFileMode = true
 )
@@ -478,6 +636,16 @@ func (c *Config) Unmarshal(b []byte) error {
 	c.unmarshalUser()
 	c.unmarshalGPG()
 	c.unmarshalInit()
+	c.unmarshalPull()
+	c.unmarshalReceive()
+	c.unmarshalUploadPack()
+	c.unmarshalMailmap()
+	c.unmarshalMerge()
+	c.unmarshalFetch()
+	c.unmarshalTrailer()
+	c.unmarshalSafe()
+	c.unmarshalHTTP()
+	c.unmarshalDiffDrivers()
 	if err := c.unmarshalPack(); err != nil {
 		return err
 	}
@@ -508,11 +676,25 @@ func (c *Config) unmarshalCore() {
 	c.Core.CommentChar = s.Options.Get(commentCharKey)
 	c.Core.AutoCRLF = s.Options.Get(autoCRLFKey)
 	c.Core.HooksPath = s.Options.Get(hooksPathKey)
+	c.Core.Abbrev = s.Options.Get(abbrevKey)
+	c.Core.LogAllRefUpdates = s.Options.Get(logAllRefUpdatesKey)
 
 	if fileMode := s.Options.Get(fileModeKey); fileMode == "false" {
 		c.Core.FileMode = false
 	}
 
+	if v, err := strconv.ParseBool(s.Options.Get(symlinksKey)); err == nil {
+		c.Core.Symlinks = NewOptBool(v)
+	}
+
+	if v, err := strconv.ParseBool(s.Options.Get(useReplaceRefsKey)); err == nil {
+		c.Core.UseReplaceRefs = NewOptBool(v)
+	}
+
+	c.Core.IgnoreCase = strings.EqualFold(s.Options.Get(ignoreCaseKey), "true")
+	c.Core.SparseCheckout = strings.EqualFold(s.Options.Get(sparseCheckoutKey), "true")
+	c.Core.SparseCheckoutCone = strings.EqualFold(s.Options.Get(sparseCheckoutConeKey), "true")
+
 	if s.Options.Get(repositoryFormatVersionKey) == string(format.Version1) {
 		c.Core.RepositoryFormatVersion = format.Version1
 	}
@@ -522,6 +704,7 @@ func (c *Config) unmarshalExtensions() {
 	s := c.Raw.Section(extensionsSection)
 	c.Extensions.ObjectFormat = format.ObjectFormat(s.Options.Get(objectFormatKey))
 	c.Extensions.WorktreeConfig = strings.EqualFold(s.Options.Get(worktreeConfigKey), "true")
+	c.Extensions.PartialClone = s.Options.Get(partialCloneKey)
 }
 
 func (c *Config) unmarshalTag() {
@@ -580,6 +763,33 @@ func (c *Config) unmarshalPack() error {
 		c.Pack.Window = uint(winUint)
 	}
 
+	c.Pack.Depth = DefaultPackDepth
+	if depth := s.Options.Get(depthKey); depth != "" {
+		d, err := strconv.ParseUint(depth, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.Pack.Depth = uint(d)
+	}
+
+	c.Pack.WindowMemory = 0
+	if mem := s.Options.Get(windowMemoryKey); mem != "" {
+		m, err := parseSize(mem)
+		if err != nil {
+			return err
+		}
+		c.Pack.WindowMemory = m
+	}
+
+	c.Pack.Threads = 0
+	if threads := s.Options.Get(threadsKey); threads != "" {
+		t, err := strconv.ParseUint(threads, 10, 32)
+		if err != nil {
+			return err
+		}
+		c.Pack.Threads = uint(t)
+	}
+
 	c.Pack.ReadReverseIndex = s.Options.Get(readReverseIndexKey) != "false"
 	c.Pack.WriteReverseIndex = s.Options.Get(writeReverseIndexKey) != "false"
 
@@ -685,6 +895,89 @@ func (c *Config) unmarshalInit() {
 	c.Init.DefaultBranch = s.Options.Get(defaultBranchKey)
 }
 
+func (c *Config) unmarshalPull() {
+	s := c.Raw.Section(pullSection)
+	c.Pull.Rebase = s.Options.Get(rebaseKey)
+}
+
+func (c *Config) unmarshalReceive() {
+	s := c.Raw.Section(receiveSection)
+	if v, err := strconv.ParseBool(s.Options.Get(denyNonFastForwardsKey)); err == nil {
+		c.Receive.DenyNonFastForwards = NewOptBool(v)
+	}
+
+	if v, err := strconv.ParseBool(s.Options.Get(denyDeletesKey)); err == nil {
+		c.Receive.DenyDeletes = NewOptBool(v)
+	}
+
+	c.Receive.DenyCurrentBranch = s.Options.Get(denyCurrentBranchKey)
+}
+
+func (c *Config) unmarshalUploadPack() {
+	s := c.Raw.Section(uploadPackSection)
+	if v, err := strconv.ParseBool(s.Options.Get(allowTipSHA1InWantKey)); err == nil {
+		c.UploadPack.AllowTipSHA1InWant = NewOptBool(v)
+	}
+
+	if v, err := strconv.ParseBool(s.Options.Get(allowReachableSHA1Key)); err == nil {
+		c.UploadPack.AllowReachableSHA1InWant = NewOptBool(v)
+	}
+
+	if v, err := strconv.ParseBool(s.Options.Get(allowAnySHA1InWantKey)); err == nil {
+		c.UploadPack.AllowAnySHA1InWant = NewOptBool(v)
+	}
+}
+
+func (c *Config) unmarshalMailmap() {
+	s := c.Raw.Section(mailmapSection)
+	c.Mailmap.File = s.Options.Get(fileKey)
+	c.Mailmap.Blob = s.Options.Get(blobKey)
+}
+
+func (c *Config) unmarshalMerge() {
+	s := c.Raw.Section(mergeSection)
+	c.Merge.ConflictStyle = s.Options.Get(conflictStyleKey)
+}
+
+func (c *Config) unmarshalFetch() {
+	s := c.Raw.Section(fetchSection)
+	c.Fetch.NegotiationAlgorithm = s.Options.Get(negotiationAlgorithmKey)
+}
+
+func (c *Config) unmarshalTrailer() {
+	s := c.Raw.Section(trailerSection)
+	c.Trailer.Separators = s.Options.Get(separatorsKey)
+}
+
+func (c *Config) unmarshalSafe() {
+	s := c.Raw.Section(safeSection)
+	c.Safe.Directories = s.Options.GetAll(directoryKey)
+}
+
+func (c *Config) unmarshalHTTP() {
+	s := c.Raw.Section(httpSection)
+	if len(s.Options) > 0 {
+		h := &HTTP{}
+		h.unmarshal(s.Options)
+		c.HTTP[""] = h
+	}
+
+	for _, sub := range s.Subsections {
+		h := &HTTP{URL: sub.Name}
+		h.unmarshal(sub.Options)
+		c.HTTP[sub.Name] = h
+	}
+}
+
+func (c *Config) unmarshalDiffDrivers() {
+	s := c.Raw.Section(diffSection)
+	for _, sub := range s.Subsections {
+		d := &DiffDriver{Name: sub.Name}
+		d.unmarshal(sub.Options)
+		c.DiffDrivers[sub.Name] = d
+	}
+}
+
 // Marshal returns Config encoded as a git-config file.
 //
 // This call populates the field Raw with the current values of
@@ -707,6 +1000,16 @@ func (c *Config) Marshal() ([]byte, error) {
 	c.marshalURLs()
 	c.marshalProtocol()
 	c.marshalInit()
+	c.marshalPull()
+	c.marshalReceive()
+	c.marshalUploadPack()
+	c.marshalMailmap()
+	c.marshalMerge()
+	c.marshalFetch()
+	c.marshalTrailer()
+	c.marshalSafe()
+	c.marshalHTTP()
+	c.marshalDiffDrivers()
 
 	buf := bytes.NewBuffer(nil)
 	if err := format.NewEncoder(buf).Encode(c.Raw); err != nil {
@@ -733,9 +1036,37 @@ func (c *Config) marshalCore() {
 
 	s.SetOption(fileModeKey, fmt.Sprintf("%t", c.Core.FileMode))
 
+	if c.Core.Symlinks.IsSet() {
+		s.SetOption(symlinksKey, c.Core.Symlinks.FormatBool())
+	}
+
+	if c.Core.UseReplaceRefs.IsSet() {
+		s.SetOption(useReplaceRefsKey, c.Core.UseReplaceRefs.FormatBool())
+	}
+
 	if c.Core.HooksPath != "" {
 		s.SetOption(hooksPathKey, c.Core.HooksPath)
 	}
+
+	if c.Core.LogAllRefUpdates != "" {
+		s.SetOption(logAllRefUpdatesKey, c.Core.LogAllRefUpdates)
+	}
+
+	if c.Core.IgnoreCase {
+		s.SetOption(ignoreCaseKey, "true")
+	}
+
+	if c.Core.Abbrev != "" {
+		s.SetOption(abbrevKey, c.Core.Abbrev)
+	}
+
+	if c.Core.SparseCheckout || s.Options.Has(sparseCheckoutKey) {
+		s.SetOption(sparseCheckoutKey, fmt.Sprintf("%t", c.Core.SparseCheckout))
+	}
+
+	if c.Core.SparseCheckoutCone || s.Options.Has(sparseCheckoutConeKey) {
+		s.SetOption(sparseCheckoutConeKey, fmt.Sprintf("%t", c.Core.SparseCheckoutCone))
+	}
 }
 
 func (c *Config) marshalExtensions() {
@@ -748,7 +1079,7 @@ func (c *Config) marshalExtensions() {
 	// Only marshal the [extensions] section if there are extension options to write.
 	// This avoids introducing an empty [extensions] section on round-trips.
 	if c.Extensions.ObjectFormat == format.UnsetObjectFormat &&
-		!c.Extensions.WorktreeConfig {
+		!c.Extensions.WorktreeConfig && c.Extensions.PartialClone == "" {
 		return
 	}
 
@@ -760,6 +1091,10 @@ func (c *Config) marshalExtensions() {
 	if c.Extensions.WorktreeConfig {
 		s.SetOption(worktreeConfigKey, "true")
 	}
+
+	if c.Extensions.PartialClone != "" {
+		s.SetOption(partialCloneKey, c.Extensions.PartialClone)
+	}
 }
 
 func (c *Config) marshalTag() {
@@ -822,11 +1157,50 @@ func (c *Config) marshalGPG() {
 	}
 }
 
+// parseSize parses a size in bytes, optionally followed by a k, m or g suffix
+// multiplying it by 1024, 1024^2 or 1024^3, as git does.
+func parseSize(v string) (uint64, error) {
+	var shift uint
+	switch v[len(v)-1] {
+	case 'k', 'K':
+		shift = 10
+	case 'm', 'M':
+		shift = 20
+	case 'g', 'G':
+		shift = 30
+	}
+
+	digits := v
+	if shift != 0 {
+		digits = v[:len(v)-1]
+	}
+
+	n, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if n > math.MaxUint64>>shift {
+		return 0, fmt.Errorf("size out of range: %s", v)
+	}
+
+	return n << shift, nil
+}
+
 func (c *Config) marshalPack() {
 	s := c.Raw.Section(packSection)
 	if c.Pack.Window != DefaultPackWindow {
 		s.SetOption(windowKey, fmt.Sprintf("%d", c.Pack.Window))
 	}
+	if c.Pack.Depth != DefaultPackDepth {
+		s.SetOption(depthKey, fmt.Sprintf("%d", c.Pack.Depth))
+	}
+	if c.Pack.WindowMemory != 0 {
+		s.SetOption(windowMemoryKey, fmt.Sprintf("%d", c.Pack.WindowMemory))
+	}
+	if c.Pack.Threads != 0 {
+		s.SetOption(threadsKey, fmt.Sprintf("%d", c.Pack.Threads))
+	}
 	if !c.Pack.ReadReverseIndex {
 		s.SetOption(readReverseIndexKey, "false")
 	}
@@ -933,6 +1307,140 @@ func (c *Config) marshalInit() {
 	}
 }
 
+func (c *Config) marshalPull() {
+	if c.Pull.Rebase == "" {
+		return
+	}
+
+	s := c.Raw.Section(pullSection)
+	s.SetOption(rebaseKey, c.Pull.Rebase)
+}
+
+func (c *Config) marshalReceive() {
+	if !c.Receive.DenyNonFastForwards.IsSet() && !c.Receive.DenyDeletes.IsSet() &&
+		c.Receive.DenyCurrentBranch == "" {
+		return
+	}
+
+	s := c.Raw.Section(receiveSection)
+	if c.Receive.DenyNonFastForwards.IsSet() {
+		s.SetOption(denyNonFastForwardsKey, c.Receive.DenyNonFastForwards.FormatBool())
+	}
+
+	if c.Receive.DenyDeletes.IsSet() {
+		s.SetOption(denyDeletesKey, c.Receive.DenyDeletes.FormatBool())
+	}
+
+	if c.Receive.DenyCurrentBranch != "" {
+		s.SetOption(denyCurrentBranchKey, c.Receive.DenyCurrentBranch)
+	}
+}
+
+func (c *Config) marshalUploadPack() {
+	if !c.UploadPack.AllowTipSHA1InWant.IsSet() && !c.UploadPack.AllowReachableSHA1InWant.IsSet() &&
+		!c.UploadPack.AllowAnySHA1InWant.IsSet() {
+		return
+	}
+
+	s := c.Raw.Section(uploadPackSection)
+	if c.UploadPack.AllowTipSHA1InWant.IsSet() {
+		s.SetOption(allowTipSHA1InWantKey, c.UploadPack.AllowTipSHA1InWant.FormatBool())
+	}
+
+	if c.UploadPack.AllowReachableSHA1InWant.IsSet() {
+		s.SetOption(allowReachableSHA1Key, c.UploadPack.AllowReachableSHA1InWant.FormatBool())
+	}
+
+	if c.UploadPack.AllowAnySHA1InWant.IsSet() {
+		s.SetOption(allowAnySHA1InWantKey, c.UploadPack.AllowAnySHA1InWant.FormatBool())
+	}
+}
+
+func (c *Config) marshalMailmap() {
+	if c.Mailmap.File == "" && c.Mailmap.Blob == "" {
+		return
+	}
+
+	s := c.Raw.Section(mailmapSection)
+	if c.Mailmap.File != "" {
+		s.SetOption(fileKey, c.Mailmap.File)
+	}
+
+	if c.Mailmap.Blob != "" {
+		s.SetOption(blobKey, c.Mailmap.Blob)
+	}
+}
+
+func (c *Config) marshalMerge() {
+	if c.Merge.ConflictStyle == "" {
+		return
+	}
+
+	s := c.Raw.Section(mergeSection)
+	s.SetOption(conflictStyleKey, c.Merge.ConflictStyle)
+}
+
+func (c *Config) marshalFetch() {
+	if c.Fetch.NegotiationAlgorithm == "" {
+		return
+	}
+
+	s := c.Raw.Section(fetchSection)
+	s.SetOption(negotiationAlgorithmKey, c.Fetch.NegotiationAlgorithm)
+}
+
+func (c *Config) marshalTrailer() {
+	if c.Trailer.Separators == "" {
+		return
+	}
+
+	s := c.Raw.Section(trailerSection)
+	s.SetOption(separatorsKey, c.Trailer.Separators)
+}
+
+func (c *Config) marshalSafe() {
+	if len(c.Safe.Directories) == 0 {
+		return
+	}
+
+	s := c.Raw.Section(safeSection)
+	s.RemoveOption(directoryKey)
+	for _, dir := range c.Safe.Directories {
+		s.AddOption(directoryKey, dir)
+	}
+}
+
+func (c *Config) marshalHTTP() {
+	if len(c.HTTP) == 0 {
+		return
+	}
+
+	s := c.Raw.Section(httpSection)
+	for name, h := range c.HTTP {
+		if name == "" {
+			// The options of the http section are set as the ones of a
+			// subsection.
+			sub := &format.Subsection{Options: s.Options}
+			h.marshal(sub)
+			s.Options = sub.Options
+			continue
+		}
+
+		h.marshal(s.Subsection(name))
+	}
+}
+
+func (c *Config) marshalDiffDrivers() {
+	if len(c.DiffDrivers) == 0 {
+		return
+	}
+
+	s := c.Raw.Section(diffSection)
+	for name, d := range c.DiffDrivers {
+		d.marshal(s.Subsection(name))
+	}
+}
+
 // RemoteConfig contains the configuration for a given remote repository.
 type RemoteConfig struct {
 	// Name of the remote
@@ -951,6 +1459,14 @@ type RemoteConfig struct {
 	// Fetch the default set of "refspec" for fetch operation
 	Fetch []RefSpec
 
+	// Promisor indicates that the remote may be asked for the objects missing
+	// from a partial clone, whose packfiles fetched from it are marked as
+	// promisor packs.
+	Promisor bool
+	// PartialCloneFilter is the filter the objects were fetched with from the
+	// promisor remote.
+	PartialCloneFilter string
+
 	// raw representation of the subsection, filled by marshal or unmarshal are
 	// called
 	raw *format.Subsection
@@ -997,6 +1513,8 @@ func (c *RemoteConfig) unmarshal(s *format.Subsection) error {
 	c.URLs = append(c.URLs, c.raw.Options.GetAll(pushurlKey)...)
 	c.Fetch = fetch
 	c.Mirror = c.raw.Options.Get(mirrorKey) == "true"
+	c.Promisor = strings.EqualFold(c.raw.Options.Get(promisorKey), "true")
+	c.PartialCloneFilter = c.raw.Options.Get(partialCloneFilterKey)
 
 	return nil
 }
@@ -1033,6 +1551,14 @@ func (c *RemoteConfig) marshal() *format.Subsection {
 		c.raw.SetOption(mirrorKey, strconv.FormatBool(c.Mirror))
 	}
 
+	if c.Promisor {
+		c.raw.SetOption(promisorKey, strconv.FormatBool(c.Promisor))
+	}
+
+	if c.PartialCloneFilter != "" {
+		c.raw.SetOption(partialCloneFilterKey, c.PartialCloneFilter)
+	}
+
 	return c.raw
 }
 
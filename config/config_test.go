@@ -32,8 +32,15 @@ func (s *ConfigSuite) TestUnmarshal() {
 		worktree = foo
 		commentchar = bar
 		autocrlf = true
+		abbrev = 12
 		filemode = false
+		symlinks = false
+		useReplaceRefs = false
 		hooksPath = custom-hooks
+		logAllRefUpdates = always
+		ignorecase = true
+		sparsecheckout = true
+		sparseCheckoutCone = true
 [user]
 		name = John Doe
 		email = john@example.com
@@ -67,6 +74,25 @@ func (s *ConfigSuite) TestUnmarshal() {
 		description = "Add support for branch description.\\n\\nEdit branch description: git branch --edit-description\\n"
 [init]
 		defaultBranch = main
+[pull]
+		rebase = true
+[receive]
+		denyNonFastForwards = true
+		denyCurrentBranch = refuse
+[uploadpack]
+		allowReachableSHA1InWant = true
+[mailmap]
+		file = ~/.mailmap
+		blob = HEAD:.mailmap
+[merge]
+		conflictStyle = zdiff3
+[fetch]
+		negotiationAlgorithm = skipping
+[trailer]
+		separators = ":#"
+[safe]
+		directory = /srv/repo
+		directory = *
 [url "ssh://git@github.com/"]
 	insteadOf = https://github.com/
 `)
@@ -80,7 +106,14 @@ func (s *ConfigSuite) TestUnmarshal() {
 	s.Equal("bar", cfg.Core.CommentChar)
 	s.Equal("true", cfg.Core.AutoCRLF)
 	s.False(cfg.Core.FileMode)
+	s.Equal(OptBoolFalse, cfg.Core.Symlinks)
+	s.Equal(OptBoolFalse, cfg.Core.UseReplaceRefs)
 	s.Equal("custom-hooks", cfg.Core.HooksPath)
+	s.Equal("always", cfg.Core.LogAllRefUpdates)
+	s.True(cfg.Core.IgnoreCase)
+	s.Equal("12", cfg.Core.Abbrev)
+	s.True(cfg.Core.SparseCheckout)
+	s.True(cfg.Core.SparseCheckoutCone)
 	s.Equal("John Doe", cfg.User.Name)
 	s.Equal("john@example.com", cfg.User.Email)
 	s.Equal("Jane Roe", cfg.Author.Name)
@@ -106,6 +139,18 @@ func (s *ConfigSuite) TestUnmarshal() {
 	s.Equal(plumbing.ReferenceName("refs/heads/master"), cfg.Branches["master"].Merge)
 	s.Equal("Add support for branch description.\n\nEdit branch description: git branch --edit-description\n", cfg.Branches["master"].Description)
 	s.Equal("main", cfg.Init.DefaultBranch)
+	s.Equal("true", cfg.Pull.Rebase)
+	s.True(cfg.Receive.DenyNonFastForwards.IsTrue())
+	s.False(cfg.Receive.DenyDeletes.IsSet())
+	s.Equal("refuse", cfg.Receive.DenyCurrentBranch)
+	s.True(cfg.UploadPack.AllowReachableSHA1InWant.IsTrue())
+	s.False(cfg.UploadPack.AllowTipSHA1InWant.IsSet())
+	s.Equal("~/.mailmap", cfg.Mailmap.File)
+	s.Equal("HEAD:.mailmap", cfg.Mailmap.Blob)
+	s.Equal("zdiff3", cfg.Merge.ConflictStyle)
+	s.Equal("skipping", cfg.Fetch.NegotiationAlgorithm)
+	s.Equal(":#", cfg.Trailer.Separators)
+	s.Equal([]string{"/srv/repo", "*"}, cfg.Safe.Directories)
 }
 
 func (s *ConfigSuite) TestMarshal() {
@@ -114,7 +159,12 @@ func (s *ConfigSuite) TestMarshal() {
 	worktree = bar
 	autocrlf = true
 	filemode = true
+	symlinks = false
+	useReplaceRefs = false
 	hooksPath = custom-hooks
+	logAllRefUpdates = always
+	ignoreCase = true
+	sparseCheckout = true
 [pack]
 	window = 20
 [remote "alt"]
@@ -138,6 +188,17 @@ func (s *ConfigSuite) TestMarshal() {
 	insteadOf = https://github.com/
 [init]
 	defaultBranch = main
+[mailmap]
+	blob = HEAD:.mailmap
+[merge]
+	conflictStyle = diff3
+[fetch]
+	negotiationAlgorithm = noop
+[trailer]
+	separators = "#"
+[safe]
+	directory = /srv/a
+	directory = /srv/b
 `)
 
 	cfg := NewConfig()
@@ -145,8 +206,18 @@ func (s *ConfigSuite) TestMarshal() {
 	cfg.Core.Worktree = "bar"
 	cfg.Core.AutoCRLF = "true"
 	cfg.Core.HooksPath = "custom-hooks"
+	cfg.Core.LogAllRefUpdates = "always"
+	cfg.Core.IgnoreCase = true
+	cfg.Core.SparseCheckout = true
+	cfg.Core.Symlinks = OptBoolFalse
+	cfg.Core.UseReplaceRefs = OptBoolFalse
 	cfg.Pack.Window = 20
 	cfg.Init.DefaultBranch = "main"
+	cfg.Mailmap.Blob = "HEAD:.mailmap"
+	cfg.Merge.ConflictStyle = "diff3"
+	cfg.Fetch.NegotiationAlgorithm = "noop"
+	cfg.Trailer.Separators = "#"
+	cfg.Safe.Directories = []string{"/srv/a", "/srv/b"}
 	cfg.Remotes["origin"] = &RemoteConfig{
 		Name: "origin",
 		URLs: []string{"git@github.com:mcuadros/go-git.git"},
@@ -247,6 +318,20 @@ func TestUnmarshalMarshal(t *testing.T) {
 	repositoryformatversion = 1
 	bare = false
 	filemode = true
+[remote "origin"]
+	url = https://github.com/git-fixtures/basic.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	promisor = true
+	partialclonefilter = blob:none
+[extensions]
+	partialClone = origin
+`,
+		},
+		{
+			`[core]
+	repositoryformatversion = 1
+	bare = false
+	filemode = true
 [branch "main"]
 	remote = origin
 	merge = refs/heads/main
@@ -296,6 +381,14 @@ func TestMarshalExtensions(t *testing.T) {
 			},
 			wantSection: true,
 		},
+		{
+			name: "PartialClone set writes section",
+			setup: func(c *Config) {
+				c.Core.RepositoryFormatVersion = config.Version1
+				c.Extensions.PartialClone = "origin"
+			},
+			wantSection: true,
+		},
 		{
 			name: "RepositoryFormat = 0 ignores section",
 			setup: func(c *Config) {
@@ -755,6 +848,7 @@ func TestMerge(t *testing.T) {
 					Extensions: struct {
 						ObjectFormat   config.ObjectFormat
 						WorktreeConfig bool
+						PartialClone   string
 					}{
 						ObjectFormat:   config.SHA256,
 						WorktreeConfig: true,
@@ -769,6 +863,7 @@ func TestMerge(t *testing.T) {
 				Extensions: struct {
 					ObjectFormat   config.ObjectFormat
 					WorktreeConfig bool
+					PartialClone   string
 				}{
 					ObjectFormat:   config.SHA256,
 					WorktreeConfig: true,
@@ -1186,3 +1281,97 @@ func TestGPGConfig(t *testing.T) {
 		assert.Equal(t, OptBoolFalse, merged.Commit.GpgSign)
 	})
 }
+
+func TestUnmarshalPackDeltaOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		input            string
+		wantDepth        uint
+		wantWindowMemory uint64
+		wantThreads      uint
+		wantErr          bool
+	}{
+		{
+			name:      "defaults",
+			input:     "[pack]\n",
+			wantDepth: DefaultPackDepth,
+		},
+		{
+			name:             "plain values",
+			input:            "[pack]\n\tdepth = 10\n\twindowMemory = 1024\n\tthreads = 4\n",
+			wantDepth:        10,
+			wantWindowMemory: 1024,
+			wantThreads:      4,
+		},
+		{
+			name:             "window memory with suffix",
+			input:            "[pack]\n\twindowMemory = 256m\n",
+			wantDepth:        DefaultPackDepth,
+			wantWindowMemory: 256 << 20,
+		},
+		{
+			name:             "window memory with upper case suffix",
+			input:            "[pack]\n\twindowMemory = 2G\n",
+			wantDepth:        DefaultPackDepth,
+			wantWindowMemory: 2 << 30,
+		},
+		{
+			name:    "invalid window memory",
+			input:   "[pack]\n\twindowMemory = lots\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid threads",
+			input:   "[pack]\n\tthreads = -1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := NewConfig()
+			err := cfg.Unmarshal([]byte(tc.input))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantDepth, cfg.Pack.Depth)
+			assert.Equal(t, tc.wantWindowMemory, cfg.Pack.WindowMemory)
+			assert.Equal(t, tc.wantThreads, cfg.Pack.Threads)
+		})
+	}
+}
+
+func TestMarshalPackDeltaOptions(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig()
+	b, err := cfg.Marshal()
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "depth")
+	assert.NotContains(t, string(b), "windowMemory")
+	assert.NotContains(t, string(b), "threads")
+
+	cfg.Pack.Depth = 20
+	cfg.Pack.WindowMemory = 1 << 20
+	cfg.Pack.Threads = 2
+	b, err = cfg.Marshal()
+	require.NoError(t, err)
+
+	output := string(b)
+	assert.Contains(t, output, "depth = 20")
+	assert.Contains(t, output, "windowMemory = 1048576")
+	assert.Contains(t, output, "threads = 2")
+
+	decoded := NewConfig()
+	require.NoError(t, decoded.Unmarshal(b))
+	assert.Equal(t, uint(20), decoded.Pack.Depth)
+	assert.Equal(t, uint64(1<<20), decoded.Pack.WindowMemory)
+	assert.Equal(t, uint(2), decoded.Pack.Threads)
+}
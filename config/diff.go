@@ -0,0 +1,28 @@
+package config
+
+import (
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
+)
+
+// DiffDriver holds the settings of a diff driver, of a diff.<driver>
+// section, used to diff the files whose diff attribute is set to its name.
+type DiffDriver struct {
+	// Name is the name of the driver.
+	Name string
+	// TextConv is the command converting the content of a file to the text
+	// diffed in its place, run by the shell with the path of a temporary
+	// file holding the content as argument. Its output is the text.
+	TextConv string
+}
+
+const textConvKey = "textconv"
+
+func (d *DiffDriver) unmarshal(opts format.Options) {
+	d.TextConv = opts.Get(textConvKey)
+}
+
+func (d *DiffDriver) marshal(s *format.Subsection) {
+	if d.TextConv != "" {
+		s.SetOption(textConvKey, d.TextConv)
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffDriverUnmarshalMarshal(t *testing.T) {
+	t.Parallel()
+
+	input := []byte(`[core]
+	bare = false
+	filemode = true
+[diff]
+	renames = copies
+[diff "exif"]
+	textconv = exiftool
+	cachetextconv = true
+`)
+
+	cfg, err := ReadConfig(bytes.NewReader(input))
+	require.NoError(t, err)
+
+	require.Len(t, cfg.DiffDrivers, 1)
+	assert.Equal(t, &DiffDriver{Name: "exif", TextConv: "exiftool"}, cfg.DiffDrivers["exif"])
+
+	output, err := cfg.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, string(input), string(output))
+
+	cfg = NewConfig()
+	cfg.DiffDrivers["zip"] = &DiffDriver{Name: "zip", TextConv: "unzip -l"}
+	output, err = cfg.Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "[diff \"zip\"]\n\ttextconv = unzip -l\n")
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"cmp"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
+)
+
+// HTTP holds the settings of the HTTP transport, of the http section or of
+// a http.<url> one.
+type HTTP struct {
+	// URL is the URL of the http.<url> section, empty for the http one.
+	URL string
+	// ExtraHeaders are added to the requests, as "Name: value". An empty
+	// one clears the ones of the less specific sections.
+	ExtraHeaders []string
+	// SSLVerify, if false, skips the verification of the certificates of
+	// the servers.
+	SSLVerify OptBool
+	// FollowRedirects selects the redirects followed: "true", "false", or
+	// "initial" to follow the ones of the initial request only.
+	FollowRedirects string
+	// Proxy is the URL of the proxy the requests are sent through.
+	Proxy string
+}
+
+const (
+	extraHeaderKey     = "extraHeader"
+	sslVerifyKey       = "sslVerify"
+	followRedirectsKey = "followRedirects"
+	proxyKey           = "proxy"
+)
+
+func (h *HTTP) unmarshal(opts format.Options) {
+	h.ExtraHeaders = opts.GetAll(extraHeaderKey)
+	h.FollowRedirects = opts.Get(followRedirectsKey)
+	h.Proxy = opts.Get(proxyKey)
+	if v, err := strconv.ParseBool(opts.Get(sslVerifyKey)); err == nil {
+		h.SSLVerify = NewOptBool(v)
+	}
+}
+
+func (h *HTTP) marshal(s *format.Subsection) {
+	if len(h.ExtraHeaders) > 0 {
+		s.SetOption(extraHeaderKey, h.ExtraHeaders...)
+	}
+
+	if h.SSLVerify.IsSet() {
+		s.SetOption(sslVerifyKey, strconv.FormatBool(h.SSLVerify.IsTrue()))
+	}
+
+	if h.FollowRedirects != "" {
+		s.SetOption(followRedirectsKey, h.FollowRedirects)
+	}
+
+	if h.Proxy != "" {
+		s.SetOption(proxyKey, h.Proxy)
+	}
+}
+
+// HTTPFor returns the HTTP settings applying to the URL rawURL: the ones of
+// the http section, overridden by the ones of the matching http.<url>
+// sections, the most specific last. A section matches if its URL has the
+// same scheme, host and port, a path prefix of the one of rawURL, and the
+// same user if it has one. Its host may start with "*." to match any
+// subdomain. The extra headers of the matching sections are all added.
+func (c *Config) HTTPFor(rawURL string) HTTP {
+	var result HTTP
+	if base, ok := c.HTTP[""]; ok {
+		result.apply(base)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return result
+	}
+
+	var matches []*HTTP
+	for key, h := range c.HTTP {
+		if key != "" && httpURLMatches(key, u) {
+			matches = append(matches, h)
+		}
+	}
+
+	// The sections are applied from the least specific to the most
+	// specific one, comparing their paths then whether they have a user.
+	slices.SortStableFunc(matches, func(a, b *HTTP) int {
+		return cmp.Compare(httpURLSpecificity(a.URL), httpURLSpecificity(b.URL))
+	})
+
+	for _, h := range matches {
+		result.apply(h)
+	}
+
+	return result
+}
+
+// apply overrides the settings with the ones set in h.
+func (h *HTTP) apply(o *HTTP) {
+	for _, header := range o.ExtraHeaders {
+		if header == "" {
+			h.ExtraHeaders = nil
+			continue
+		}
+
+		h.ExtraHeaders = append(h.ExtraHeaders, header)
+	}
+
+	if o.SSLVerify.IsSet() {
+		h.SSLVerify = o.SSLVerify
+	}
+
+	if o.FollowRedirects != "" {
+		h.FollowRedirects = o.FollowRedirects
+	}
+
+	if o.Proxy != "" {
+		h.Proxy = o.Proxy
+	}
+}
+
+// httpURLMatches returns whether the URL of a http.<url> section matches
+// the URL u, following the rules of git config --get-urlmatch.
+func httpURLMatches(pattern string, u *url.URL) bool {
+	p, err := url.Parse(pattern)
+	if err != nil || p.Host == "" {
+		return false
+	}
+
+	if !strings.EqualFold(p.Scheme, u.Scheme) {
+		return false
+	}
+
+	if p.User != nil && (u.User == nil || p.User.Username() != u.User.Username()) {
+		return false
+	}
+
+	if !httpHostMatches(p.Hostname(), u.Hostname()) || httpPort(p) != httpPort(u) {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(p.Path, "/")
+	return prefix == "" || u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")
+}
+
+// httpURLSpecificity returns how specific the URL of a http.<url> section
+// is, by the length of its path, then whether it has a user.
+func httpURLSpecificity(pattern string) int {
+	p, err := url.Parse(pattern)
+	if err != nil {
+		return 0
+	}
+
+	specificity := 2 * len(strings.TrimSuffix(p.Path, "/"))
+	if p.User != nil {
+		specificity++
+	}
+
+	return specificity
+}
+
+// httpHostMatches returns whether the host matches the pattern, whose
+// labels may be "*" to match any label.
+func httpHostMatches(pattern, host string) bool {
+	pl := strings.Split(strings.ToLower(pattern), ".")
+	hl := strings.Split(strings.ToLower(host), ".")
+	if len(pl) != len(hl) {
+		return false
+	}
+
+	for i := range pl {
+		if pl[i] != "*" && pl[i] != hl[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// httpPort returns the port of the URL u, the default one of its scheme if
+// it has none.
+func httpPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+
+	return ""
+}
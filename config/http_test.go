@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPUnmarshalMarshal(t *testing.T) {
+	t.Parallel()
+
+	input := []byte(`[core]
+	bare = false
+	filemode = true
+[http]
+	sslVerify = false
+	extraHeader = X-Base: 1
+[http "https://example.com/repo"]
+	extraHeader = X-Repo: 1
+	extraHeader = X-Repo: 2
+	followRedirects = initial
+	proxy = proxy.example.com:3128
+`)
+
+	cfg, err := ReadConfig(bytes.NewReader(input))
+	require.NoError(t, err)
+
+	require.Len(t, cfg.HTTP, 2)
+	assert.Equal(t, &HTTP{
+		ExtraHeaders: []string{"X-Base: 1"},
+		SSLVerify:    OptBoolFalse,
+	}, cfg.HTTP[""])
+	assert.Equal(t, &HTTP{
+		URL:             "https://example.com/repo",
+		ExtraHeaders:    []string{"X-Repo: 1", "X-Repo: 2"},
+		FollowRedirects: "initial",
+		Proxy:           "proxy.example.com:3128",
+	}, cfg.HTTP["https://example.com/repo"])
+
+	output, err := cfg.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, string(input), string(output))
+
+	cfg = NewConfig()
+	cfg.HTTP["https://example.com"] = &HTTP{URL: "https://example.com", FollowRedirects: "false"}
+	output, err = cfg.Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "[http \"https://example.com\"]\n\tfollowRedirects = false\n")
+}
+
+func TestHTTPFor(t *testing.T) {
+	t.Parallel()
+
+	cfg := NewConfig()
+	for _, h := range []*HTTP{
+		{ExtraHeaders: []string{"X-Base: 1"}, FollowRedirects: "initial"},
+		{URL: "https://example.com", ExtraHeaders: []string{"X-Host: 1"}, Proxy: "http://proxy:3128"},
+		{URL: "https://example.com/org/repo", SSLVerify: OptBoolFalse},
+		{URL: "https://example.com/org", FollowRedirects: "false"},
+		{URL: "https://user@example.com/org", ExtraHeaders: []string{"", "X-User: 1"}},
+		{URL: "https://*.example.org", FollowRedirects: "true"},
+		{URL: "http://example.com:8080", Proxy: "http://other:3128"},
+	} {
+		cfg.HTTP[h.URL] = h
+	}
+
+	tests := []struct {
+		url  string
+		want HTTP
+	}{
+		{
+			url:  "https://other.com/repo",
+			want: HTTP{ExtraHeaders: []string{"X-Base: 1"}, FollowRedirects: "initial"},
+		},
+		{
+			url: "https://example.com/org/repo.git",
+			want: HTTP{
+				ExtraHeaders:    []string{"X-Base: 1", "X-Host: 1"},
+				FollowRedirects: "false",
+				Proxy:           "http://proxy:3128",
+			},
+		},
+		{
+			url: "https://example.com:443/org/repo/",
+			want: HTTP{
+				ExtraHeaders:    []string{"X-Base: 1", "X-Host: 1"},
+				SSLVerify:       OptBoolFalse,
+				FollowRedirects: "false",
+				Proxy:           "http://proxy:3128",
+			},
+		},
+		{
+			url: "https://user@example.com/org/repo",
+			want: HTTP{
+				ExtraHeaders:    []string{"X-User: 1"},
+				SSLVerify:       OptBoolFalse,
+				FollowRedirects: "false",
+				Proxy:           "http://proxy:3128",
+			},
+		},
+		{
+			url:  "https://git.example.org/repo",
+			want: HTTP{ExtraHeaders: []string{"X-Base: 1"}, FollowRedirects: "true"},
+		},
+		{
+			url:  "https://a.b.example.org/repo",
+			want: HTTP{ExtraHeaders: []string{"X-Base: 1"}, FollowRedirects: "initial"},
+		},
+		{
+			url: "http://example.com:8080/repo",
+			want: HTTP{
+				ExtraHeaders:    []string{"X-Base: 1"},
+				FollowRedirects: "initial",
+				Proxy:           "http://other:3128",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, cfg.HTTPFor(tt.url), tt.url)
+	}
+}
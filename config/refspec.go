@@ -134,12 +134,20 @@ func (s RefSpec) Dst(n plumbing.ReferenceName) plumbing.ReferenceName {
 	return plumbing.ReferenceName(before + match + after)
 }
 
-// Reverse returns the RefSpec with source and destination swapped.
+// Reverse returns the RefSpec with source and destination swapped. The force
+// flag, if any, is kept in front of the reversed RefSpec.
 func (s RefSpec) Reverse() RefSpec {
 	spec := string(s)
+
+	var force string
+	if s.IsForceUpdate() {
+		force = refSpecForce
+		spec = spec[1:]
+	}
+
 	before, after, _ := strings.Cut(spec, refSpecSeparator)
 
-	return RefSpec(after + refSpecSeparator + before)
+	return RefSpec(force + after + refSpecSeparator + before)
 }
 
 func (s RefSpec) String() string {
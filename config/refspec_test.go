@@ -186,6 +186,11 @@ func (s *RefSpecSuite) TestRefSpecDstBlob() {
 func (s *RefSpecSuite) TestRefSpecReverse() {
 	spec := RefSpec("refs/heads/*:refs/remotes/origin/*")
 	s.Equal(RefSpec("refs/remotes/origin/*:refs/heads/*"), spec.Reverse())
+
+	spec = RefSpec("+refs/heads/*:refs/remotes/origin/*")
+	s.Equal(RefSpec("+refs/remotes/origin/*:refs/heads/*"), spec.Reverse())
+	s.Equal(plumbing.ReferenceName("refs/heads/foo"),
+		spec.Reverse().Dst(plumbing.ReferenceName("refs/remotes/origin/foo")))
 }
 
 func (s *RefSpecSuite) TestMatchAny() {
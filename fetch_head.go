@@ -0,0 +1,130 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v6/config"
+	iurl "github.com/go-git/go-git/v6/internal/url"
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// fetchHeadStorer is implemented by the storages recording the references
+// of the last fetch in FETCH_HEAD, such as filesystem.Storage.
+type fetchHeadStorer interface {
+	SetFetchHead(content []byte) error
+}
+
+// writeFetchHead records the fetched references in FETCH_HEAD, as git fetch
+// does, one line per reference. The references to be merged by a following
+// pull come first, the others being marked as not-for-merge. Nothing is
+// written if the storage doesn't implement fetchHeadStorer.
+//
+// The references to merge are the ones of the non wildcard refspecs when
+// explicit, or else the merge reference of the current branch when it tracks
+// the remote, falling back to the first configured refspec if it is not a
+// wildcard.
+func (r *Remote) writeFetchHead(remoteURL string, explicit bool, specs []config.RefSpec, specToRefs [][]*plumbing.Reference) error {
+	fhs, ok := r.s.(fetchHeadStorer)
+	if !ok {
+		return nil
+	}
+
+	merge, err := r.mergeReference()
+	if err != nil {
+		return err
+	}
+
+	var forMerge, notForMerge []*plumbing.Reference
+	seen := make(map[plumbing.ReferenceName]bool)
+	for i, refs := range specToRefs {
+		for _, ref := range refs {
+			if seen[ref.Name()] {
+				continue
+			}
+
+			seen[ref.Name()] = true
+
+			var isMerge bool
+			switch {
+			case i >= len(specs):
+			case explicit:
+				isMerge = !specs[i].IsWildcard()
+			case merge != "":
+				isMerge = ref.Name() == merge
+			default:
+				isMerge = i == 0 && !specs[i].IsWildcard()
+			}
+
+			if isMerge {
+				forMerge = append(forMerge, ref)
+			} else {
+				notForMerge = append(notForMerge, ref)
+			}
+		}
+	}
+
+	u := fetchHeadURL(remoteURL)
+	var b strings.Builder
+	for _, ref := range forMerge {
+		fmt.Fprintf(&b, "%s\t\t%s\n", ref.Hash(), fetchHeadDescription(ref.Name(), u))
+	}
+
+	for _, ref := range notForMerge {
+		fmt.Fprintf(&b, "%s\tnot-for-merge\t%s\n", ref.Hash(), fetchHeadDescription(ref.Name(), u))
+	}
+
+	return fhs.SetFetchHead([]byte(b.String()))
+}
+
+// mergeReference returns the remote reference merged into the current branch
+// when it tracks the remote, or an empty name.
+func (r *Remote) mergeReference() (plumbing.ReferenceName, error) {
+	head, err := r.s.Reference(plumbing.HEAD)
+	if err != nil || head.Type() != plumbing.SymbolicReference {
+		return "", nil
+	}
+
+	cfg, err := r.s.Config()
+	if err != nil {
+		return "", err
+	}
+
+	b, ok := cfg.Branches[head.Target().Short()]
+	if !ok || b.Remote != r.c.Name {
+		return "", nil
+	}
+
+	return b.Merge, nil
+}
+
+// fetchHeadDescription describes the reference as git does in FETCH_HEAD.
+func fetchHeadDescription(name plumbing.ReferenceName, u string) string {
+	switch {
+	case name == plumbing.HEAD:
+		return u
+	case name.IsBranch():
+		return fmt.Sprintf("branch '%s' of %s", name.Short(), u)
+	case name.IsTag():
+		return fmt.Sprintf("tag '%s' of %s", name.Short(), u)
+	case name.IsRemote():
+		return fmt.Sprintf("remote-tracking branch '%s' of %s", name.Short(), u)
+	default:
+		return fmt.Sprintf("'%s' of %s", name, u)
+	}
+}
+
+// fetchHeadURL returns the URL as written in FETCH_HEAD, without credentials
+// nor trailing slashes and .git suffix.
+func fetchHeadURL(remoteURL string) string {
+	if iurl.MatchesScheme(remoteURL) {
+		if u, err := url.Parse(remoteURL); err == nil && u.User != nil {
+			u.User = nil
+			remoteURL = u.String()
+		}
+	}
+
+	remoteURL = strings.TrimRight(remoteURL, "/")
+	return strings.TrimSuffix(remoteURL, ".git")
+}
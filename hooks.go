@@ -0,0 +1,170 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/internal/hook"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+const (
+	hookPreCommit    = "pre-commit"
+	hookCommitMsg    = "commit-msg"
+	hookPostCheckout = "post-checkout"
+	hookPrePush      = "pre-push"
+
+	commitEditMsgFile = "COMMIT_EDITMSG"
+)
+
+// ErrHookFailed is returned when a hook exits with a non-zero status, aborting
+// the operation that ran it.
+var ErrHookFailed = errors.New("hook failed")
+
+// hookRunner runs the hooks of a repository stored in the OS filesystem. A
+// nil hookRunner runs no hooks.
+type hookRunner struct {
+	// gitDir is the absolute path of the git directory.
+	gitDir string
+	runner *hook.Runner
+}
+
+// newHookRunner returns a hookRunner for the repository stored in s with the
+// given worktree, which may be nil. The hooks are run from the root of the
+// worktree, or from the git directory in bare repositories. If the worktree is
+// nil and the git directory is named .git, its parent directory is taken as
+// the worktree. It returns nil if s is not stored in the filesystem.
+func newHookRunner(s storage.Storer, worktree billy.Filesystem) (*hookRunner, error) {
+	type fsBased interface {
+		Filesystem() billy.Filesystem
+	}
+
+	fs, ok := s.(fsBased)
+	if !ok {
+		return nil, nil
+	}
+
+	gitDir, err := filepath.Abs(fs.Filesystem().Root())
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := s.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := gitDir
+	switch {
+	case worktree != nil:
+		if workDir, err = filepath.Abs(worktree.Root()); err != nil {
+			return nil, err
+		}
+	case !cfg.Core.IsBare && filepath.Base(gitDir) == GitDirName:
+		workDir = filepath.Dir(gitDir)
+	}
+
+	runner, err := hook.NewRunner(cfg, gitDir, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hookRunner{gitDir: gitDir, runner: runner}, nil
+}
+
+// run runs the hook called name with the given arguments and stdin, which may
+// be nil. Missing hooks, and on Unix hooks not executable, are ignored. If the
+// hook exits with a non-zero status, an error wrapping ErrHookFailed is
+// returned, including the output of the hook.
+func (h *hookRunner) run(ctx context.Context, name string, stdin io.Reader, args ...string) error {
+	if h == nil {
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := h.runner.Run(ctx, name, stdin, &out, args...); err != nil {
+		msg := strings.TrimSpace(out.String())
+		if msg == "" {
+			return fmt.Errorf("%w: %s: %w", ErrHookFailed, name, err)
+		}
+
+		return fmt.Errorf("%w: %s: %w: %s", ErrHookFailed, name, err, msg)
+	}
+
+	return nil
+}
+
+// runCommitMsg runs the commit-msg hook with the path of a file containing
+// msg, and returns the message as left in the file by the hook.
+func (h *hookRunner) runCommitMsg(ctx context.Context, msg string) (string, error) {
+	if h == nil {
+		return msg, nil
+	}
+
+	path := filepath.Join(h.gitDir, commitEditMsgFile)
+	if err := os.WriteFile(path, []byte(msg), 0o666); err != nil {
+		return "", err
+	}
+
+	if err := h.run(ctx, hookCommitMsg, nil, path); err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// prePushInput returns the input of the pre-push hook for cmds, a line per
+// command with the local reference and hash, and the remote reference and
+// hash.
+func prePushInput(cmds []*packp.Command, refspecs []config.RefSpec, localRefs []*plumbing.Reference) io.Reader {
+	var buf bytes.Buffer
+	for _, cmd := range cmds {
+		local := "(delete)"
+		if !cmd.New.IsZero() {
+			local = prePushLocalRef(cmd, refspecs, localRefs)
+		}
+
+		fmt.Fprintf(&buf, "%s %s %s %s\n", local, cmd.New, cmd.Name, cmd.Old)
+	}
+
+	return &buf
+}
+
+// prePushLocalRef returns the name of the local reference pushed by cmd, or
+// the source of its refspec if it is not a reference.
+func prePushLocalRef(cmd *packp.Command, refspecs []config.RefSpec, localRefs []*plumbing.Reference) string {
+	for _, rs := range refspecs {
+		if rs.IsDelete() {
+			continue
+		}
+
+		for _, ref := range localRefs {
+			if ref.Type() == plumbing.HashReference && ref.Hash() == cmd.New &&
+				rs.Match(ref.Name()) && rs.Dst(ref.Name()) == cmd.Name {
+				return ref.Name().String()
+			}
+		}
+
+		if !rs.IsWildcard() && rs.Dst("") == cmd.Name {
+			return rs.Src()
+		}
+	}
+
+	return cmd.Name.String()
+}
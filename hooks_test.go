@@ -0,0 +1,172 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// newHooksTestRepository creates a repository on disk with a single commit,
+// returning it and the path of its worktree.
+func newHooksTestRepository(t *testing.T) (*Repository, string) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks are shell scripts")
+	}
+
+	dir := t.TempDir()
+	r, err := PlainInit(dir, false)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo"), []byte("foo\n"), 0o644))
+	w, err := r.Worktree()
+	require.NoError(t, err)
+	_, err = w.Add("foo")
+	require.NoError(t, err)
+	_, err = w.Commit("init\n", &CommitOptions{Author: hooksTestSignature()})
+	require.NoError(t, err)
+
+	return r, dir
+}
+
+func hooksTestSignature() *object.Signature {
+	return &object.Signature{Name: "foo", Email: "foo@foo.foo", When: time.Now()}
+}
+
+func writeHook(t *testing.T, dir, name, script string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+}
+
+func TestCommitHooks(t *testing.T) {
+	t.Parallel()
+
+	r, dir := newHooksTestRepository(t)
+	hooks := filepath.Join(dir, GitDirName, "hooks")
+	writeHook(t, hooks, hookPreCommit, "test -f allow || { echo rejected; exit 1; }\n")
+	writeHook(t, hooks, hookCommitMsg, "echo 'Signed-off-by: foo' >> \"$1\"\n")
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	opts := &CommitOptions{Author: hooksTestSignature(), AllowEmptyCommits: true, HooksEnabled: true}
+	_, err = w.Commit("bar\n", opts)
+	assert.ErrorIs(t, err, ErrHookFailed)
+	assert.ErrorContains(t, err, "rejected")
+
+	// Hooks are not run unless enabled.
+	_, err = w.Commit("bar\n", &CommitOptions{Author: hooksTestSignature(), AllowEmptyCommits: true})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "allow"), nil, 0o644))
+	h, err := w.Commit("baz\n", opts)
+	require.NoError(t, err)
+
+	c, err := r.CommitObject(h)
+	require.NoError(t, err)
+	assert.Equal(t, "baz\nSigned-off-by: foo\n", c.Message)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "allow")))
+	opts.NoVerify = true
+	h, err = w.Commit("qux\n", opts)
+	require.NoError(t, err)
+
+	c, err = r.CommitObject(h)
+	require.NoError(t, err)
+	assert.Equal(t, "qux\n", c.Message)
+}
+
+func TestCommitHooksPath(t *testing.T) {
+	t.Parallel()
+
+	r, dir := newHooksTestRepository(t)
+	writeHook(t, filepath.Join(dir, GitDirName, "hooks"), hookPreCommit, "exit 1\n")
+	writeHook(t, filepath.Join(dir, "custom"), hookPreCommit, "exit 0\n")
+
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	cfg.Core.HooksPath = "custom"
+	require.NoError(t, r.SetConfig(cfg))
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+	_, err = w.Commit("bar\n", &CommitOptions{
+		Author:            hooksTestSignature(),
+		AllowEmptyCommits: true,
+		HooksEnabled:      true,
+	})
+	assert.NoError(t, err)
+}
+
+func TestCheckoutPostCheckoutHook(t *testing.T) {
+	t.Parallel()
+
+	r, dir := newHooksTestRepository(t)
+	out := filepath.Join(t.TempDir(), "out")
+	writeHook(t, filepath.Join(dir, GitDirName, "hooks"), hookPostCheckout,
+		fmt.Sprintf("echo \"$@\" > %s\n", out))
+
+	head, err := r.Head()
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, w.Checkout(&CheckoutOptions{
+		Branch:       "refs/heads/other",
+		Create:       true,
+		HooksEnabled: true,
+	}))
+
+	b, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%s %s 1\n", head.Hash(), head.Hash()), string(b))
+}
+
+func TestPushPrePushHook(t *testing.T) {
+	t.Parallel()
+
+	r, dir := newHooksTestRepository(t)
+	url := t.TempDir()
+	_, err := PlainInit(url, true)
+	require.NoError(t, err)
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: DefaultRemoteName, URLs: []string{url}})
+	require.NoError(t, err)
+
+	out := filepath.Join(t.TempDir(), "out")
+	writeHook(t, filepath.Join(dir, GitDirName, "hooks"), hookPrePush,
+		fmt.Sprintf("echo \"$@\" > %[1]s\ncat >> %[1]s\nexit 1\n", out))
+
+	head, err := r.Head()
+	require.NoError(t, err)
+
+	err = r.Push(&PushOptions{
+		RefSpecs:     []config.RefSpec{"refs/heads/master:refs/heads/main"},
+		HooksEnabled: true,
+	})
+	assert.ErrorIs(t, err, ErrHookFailed)
+
+	b, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("origin %s\nrefs/heads/master %s refs/heads/main %s\n",
+		url, head.Hash(), plumbing.ZeroHash), string(b))
+
+	// Nothing was pushed.
+	server, err := PlainOpen(url)
+	require.NoError(t, err)
+	_, err = server.Reference("refs/heads/main", false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+}
@@ -0,0 +1,78 @@
+// Package hook runs the hooks of repositories stored in the OS filesystem.
+package hook
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/internal/pathutil"
+)
+
+const defaultHooksPath = "hooks"
+
+// Runner runs the hooks found in a directory.
+type Runner struct {
+	// Dir is the directory containing the hooks.
+	Dir string
+	// WorkDir is the directory the hooks are run from.
+	WorkDir string
+	// Env is appended to the environment of the hooks.
+	Env []string
+}
+
+// NewRunner returns a Runner for the git directory gitDir, running the hooks
+// from workDir. The hooks are looked up in core.hooksPath if set, relative to
+// workDir, or in $GIT_DIR/hooks otherwise.
+func NewRunner(cfg *config.Config, gitDir, workDir string) (*Runner, error) {
+	dir := filepath.Join(gitDir, defaultHooksPath)
+	if cfg.Core.HooksPath != "" {
+		var err error
+		dir, err = pathutil.ReplaceTildeWithHome(cfg.Core.HooksPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workDir, dir)
+		}
+	}
+
+	return &Runner{Dir: dir, WorkDir: workDir}, nil
+}
+
+// Run runs the hook called name with the given arguments and stdin, which may
+// be nil, writing its standard output and error to out. Missing hooks, and on
+// Unix hooks not executable, are ignored. The error of the hook, if it exits
+// with a non-zero status, is returned as is.
+func (r *Runner) Run(ctx context.Context, name string, stdin io.Reader, out io.Writer, args ...string) error {
+	path := filepath.Join(r.Dir, name)
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() || (runtime.GOOS != "windows" && fi.Mode()&0o111 == 0) {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = r.WorkDir
+	if len(r.Env) > 0 {
+		cmd.Env = append(os.Environ(), r.Env...)
+	}
+
+	cmd.Stdin = stdin
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	return cmd.Run()
+}
@@ -69,9 +69,9 @@ func WriteInfoRefs(w io.Writer, s storage.Storer) error {
 				return fmt.Errorf("writing info reference: %w", err)
 			}
 			if name.IsTag() {
-				tag, err := object.GetTag(s, hash)
-				if err == nil {
-					if _, err := fmt.Fprintf(w, "%s\t%s^{}\n", tag.Target, name); err != nil {
+				peeled, err := object.Peel(s, hash)
+				if err == nil && peeled != hash {
+					if _, err := fmt.Fprintf(w, "%s\t%s^{}\n", peeled, name); err != nil {
 						return fmt.Errorf("writing info tag reference: %w", err)
 					}
 				}
@@ -0,0 +1,169 @@
+package git
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v6/internal/pathutil"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/mailmap"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+const (
+	mailmapFile        = ".mailmap"
+	defaultMailmapBlob = "HEAD:" + mailmapFile
+)
+
+// Mailmap returns the mailmap of the repository, used to map author and
+// committer identities to their canonical form.
+//
+// The mailmap is built, in order, from the .mailmap file at the root of the
+// worktree, the blob configured at mailmap.blob and the file configured at
+// mailmap.file; later entries take precedence. In bare repositories
+// mailmap.blob defaults to HEAD:.mailmap. Missing sources are ignored.
+func (r *Repository) Mailmap() (*mailmap.Mailmap, error) {
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	m := mailmap.New()
+	if r.wt != nil {
+		f, err := r.wt.Open(mailmapFile)
+		if err == nil {
+			err = readMailmap(m, f)
+		}
+
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	blob := cfg.Mailmap.Blob
+	if blob == "" && cfg.Core.IsBare {
+		blob = defaultMailmapBlob
+	}
+
+	if blob != "" {
+		if err := r.readMailmapBlob(m, blob); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Mailmap.File != "" {
+		path, err := pathutil.ReplaceTildeWithHome(cfg.Mailmap.File)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.Open(path)
+		if err == nil {
+			err = readMailmap(m, f)
+		}
+
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// readMailmapBlob reads the mailmap stored in the blob referenced by spec,
+// either a blob hash or a "<revision>:<path>" expression.
+func (r *Repository) readMailmapBlob(m *mailmap.Mailmap, spec string) error {
+	var blob *object.Blob
+	if rev, path, ok := strings.Cut(spec, ":"); ok {
+		h, err := r.ResolveRevision(plumbing.Revision(rev))
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		c, err := r.CommitObject(*h)
+		if err != nil {
+			return err
+		}
+
+		f, err := c.File(path)
+		if errors.Is(err, object.ErrFileNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		blob = &f.Blob
+	} else {
+		h, ok := plumbing.FromHex(spec)
+		if !ok {
+			return nil
+		}
+
+		b, err := r.BlobObject(h)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		blob = b
+	}
+
+	rd, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+
+	return readMailmap(m, rd)
+}
+
+func readMailmap(m *mailmap.Mailmap, rc io.ReadCloser) error {
+	err := m.Read(rc)
+	if cerr := rc.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+type commitMailmapIter struct {
+	object.CommitIter
+	m *mailmap.Mailmap
+}
+
+// newCommitMailmapIter returns a CommitIter rewriting the author and committer
+// of the commits returned by iter according to m.
+func newCommitMailmapIter(iter object.CommitIter, m *mailmap.Mailmap) object.CommitIter {
+	return &commitMailmapIter{CommitIter: iter, m: m}
+}
+
+func (i *commitMailmapIter) Next() (*object.Commit, error) {
+	c, err := i.CommitIter.Next()
+	if c != nil {
+		i.apply(c)
+	}
+
+	return c, err
+}
+
+func (i *commitMailmapIter) ForEach(cb func(*object.Commit) error) error {
+	return i.CommitIter.ForEach(func(c *object.Commit) error {
+		i.apply(c)
+		return cb(c)
+	})
+}
+
+func (i *commitMailmapIter) apply(c *object.Commit) {
+	c.Author = i.m.ResolveSignature(c.Author)
+	c.Committer = i.m.ResolveSignature(c.Committer)
+}
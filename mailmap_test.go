@@ -0,0 +1,102 @@
+package git
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestRepositoryMailmap(t *testing.T) {
+	t.Parallel()
+
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(fs, "file", []byte("foo\n"), 0o644))
+	_, err = w.Add("file")
+	require.NoError(t, err)
+
+	sig := &object.Signature{Name: "nick", Email: "Nick@Laptop", When: time.Now()}
+	h, err := w.Commit("first", &CommitOptions{Author: sig, Committer: sig})
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(fs, ".mailmap",
+		[]byte("Proper Name <proper@example.com> <nick@laptop>\n"), 0o644))
+
+	m, err := r.Mailmap()
+	require.NoError(t, err)
+
+	name, email := m.Resolve("nick", "nick@laptop")
+	assert.Equal(t, "Proper Name", name)
+	assert.Equal(t, "proper@example.com", email)
+
+	iter, err := r.Log(&LogOptions{From: h, Mailmap: m})
+	require.NoError(t, err)
+
+	c, err := iter.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "Proper Name", c.Author.Name)
+	assert.Equal(t, "proper@example.com", c.Author.Email)
+	assert.Equal(t, "proper@example.com", c.Committer.Email)
+
+	commit, err := r.CommitObject(h)
+	require.NoError(t, err)
+
+	result, err := BlameWithOptions(commit, "file", &BlameOptions{Mailmap: m})
+	require.NoError(t, err)
+	require.Len(t, result.Lines, 1)
+	assert.Equal(t, "Proper Name", result.Lines[0].AuthorName)
+	assert.Equal(t, "proper@example.com", result.Lines[0].Author)
+
+	result, err = Blame(commit, "file")
+	require.NoError(t, err)
+	require.Len(t, result.Lines, 1)
+	assert.Equal(t, "nick", result.Lines[0].AuthorName)
+}
+
+func TestRepositoryMailmapBlob(t *testing.T) {
+	t.Parallel()
+
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(fs, ".mailmap",
+		[]byte("Proper Name <nick@laptop>\n"), 0o644))
+	_, err = w.Add(".mailmap")
+	require.NoError(t, err)
+
+	sig := &object.Signature{Name: "nick", Email: "nick@laptop", When: time.Now()}
+	_, err = w.Commit("add mailmap", &CommitOptions{Author: sig, Committer: sig})
+	require.NoError(t, err)
+	require.NoError(t, fs.Remove(".mailmap"))
+
+	m, err := r.Mailmap()
+	require.NoError(t, err)
+	name, _ := m.Resolve("nick", "nick@laptop")
+	assert.Equal(t, "nick", name)
+
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	cfg.Mailmap.Blob = "HEAD:.mailmap"
+	require.NoError(t, r.SetConfig(cfg))
+
+	m, err = r.Mailmap()
+	require.NoError(t, err)
+	name, _ = m.Resolve("nick", "nick@laptop")
+	assert.Equal(t, "Proper Name", name)
+}
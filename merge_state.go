@@ -0,0 +1,193 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/util"
+
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// Files of the git directory recording a merge in progress, as git merge
+// writes them when it stops before committing.
+const (
+	mergeHeadFile = "MERGE_HEAD"
+	mergeMsgFile  = "MERGE_MSG"
+	mergeModeFile = "MERGE_MODE"
+)
+
+// ErrMergeStateNotSupported is returned when the storage can not record the
+// state of a merge in progress, not being based on a filesystem.
+var ErrMergeStateNotSupported = errors.New("merge state not supported")
+
+// RepositoryState is the operation in progress in a repository, stopped
+// before committing, as recorded in its git directory.
+type RepositoryState int8
+
+const (
+	// NoOperation is the state of a repository without operation in
+	// progress.
+	NoOperation RepositoryState = iota
+	// Merging is the state of a repository with a merge in progress, its
+	// MERGE_HEAD being set.
+	Merging
+	// CherryPicking is the state of a repository with a cherry-pick in
+	// progress, its CHERRY_PICK_HEAD being set.
+	CherryPicking
+)
+
+// String returns the description of the state, as git status gives it.
+func (s RepositoryState) String() string {
+	switch s {
+	case Merging:
+		return "merging"
+	case CherryPicking:
+		return "cherry-picking"
+	default:
+		return "no operation in progress"
+	}
+}
+
+// State returns the operation in progress in the repository. Worktree.Commit
+// concludes a merge in progress, its commit having the MERGE_HEAD commits as
+// additional parents.
+func (r *Repository) State() (RepositoryState, error) {
+	heads, err := r.MergeHeads()
+	if err != nil {
+		return NoOperation, err
+	}
+
+	if len(heads) > 0 {
+		return Merging, nil
+	}
+
+	_, err = r.Storer.Reference(plumbing.CherryPickHead)
+	switch {
+	case err == nil:
+		return CherryPicking, nil
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		return NoOperation, nil
+	default:
+		return NoOperation, err
+	}
+}
+
+// MergeHeads returns the commits being merged, recorded in MERGE_HEAD, which
+// are the other parents of the merge commit. It is empty if there is no merge
+// in progress.
+func (r *Repository) MergeHeads() ([]plumbing.Hash, error) {
+	content, err := r.readMergeStateFile(mergeHeadFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var heads []plumbing.Hash
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		h, ok := plumbing.FromHex(line)
+		if !ok {
+			return nil, fmt.Errorf("malformed %s: %q", mergeHeadFile, line)
+		}
+
+		heads = append(heads, h)
+	}
+
+	return heads, nil
+}
+
+// SetMergeHeads records the commits being merged in MERGE_HEAD, starting a
+// merge. An empty list removes the file.
+func (r *Repository) SetMergeHeads(heads []plumbing.Hash) error {
+	var b strings.Builder
+	for _, h := range heads {
+		b.WriteString(h.String() + "\n")
+	}
+
+	return r.writeMergeStateFile(mergeHeadFile, b.String())
+}
+
+// MergeMessage returns the proposed message of the commit concluding the
+// merge or the cherry-pick in progress, recorded in MERGE_MSG.
+func (r *Repository) MergeMessage() (string, error) {
+	return r.readMergeStateFile(mergeMsgFile)
+}
+
+// SetMergeMessage records the proposed message of the commit concluding the
+// merge or the cherry-pick in progress in MERGE_MSG. An empty message removes
+// the file.
+func (r *Repository) SetMergeMessage(msg string) error {
+	return r.writeMergeStateFile(mergeMsgFile, msg)
+}
+
+// ClearMergeState removes the files recording the merge or the cherry-pick
+// in progress, as git commit does once it is concluded. ORIG_HEAD is kept.
+func (r *Repository) ClearMergeState() error {
+	for _, name := range []string{mergeHeadFile, mergeMsgFile, mergeModeFile} {
+		if err := r.writeMergeStateFile(name, ""); err != nil && !errors.Is(err, ErrMergeStateNotSupported) {
+			return err
+		}
+	}
+
+	err := r.Storer.RemoveReference(plumbing.CherryPickHead)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// readMergeStateFile returns the content of the file of the git directory,
+// empty if it does not exist or if the storage is not based on a filesystem.
+func (r *Repository) readMergeStateFile(name string) (string, error) {
+	fs, ok := r.mergeStateFilesystem()
+	if !ok {
+		return "", nil
+	}
+
+	b, err := util.ReadFile(fs, name)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+
+	return string(b), err
+}
+
+// writeMergeStateFile writes the file of the git directory, removing it if
+// the content is empty.
+func (r *Repository) writeMergeStateFile(name, content string) error {
+	fs, ok := r.mergeStateFilesystem()
+	if !ok {
+		return ErrMergeStateNotSupported
+	}
+
+	if content != "" {
+		return util.WriteFile(fs, name, []byte(content), 0o666)
+	}
+
+	if err := fs.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) mergeStateFilesystem() (billy.Filesystem, bool) {
+	type fsBased interface {
+		Filesystem() billy.Filesystem
+	}
+
+	fs, ok := r.Storer.(fsBased)
+	if !ok {
+		return nil, false
+	}
+
+	return fs.Filesystem(), true
+}
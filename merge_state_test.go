@@ -0,0 +1,187 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestMergeState(t *testing.T) {
+	t.Parallel()
+
+	r, err := PlainInit(t.TempDir(), false)
+	require.NoError(t, err)
+
+	state, err := r.State()
+	require.NoError(t, err)
+	assert.Equal(t, NoOperation, state)
+
+	heads := []plumbing.Hash{
+		plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"),
+		plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881"),
+	}
+	require.NoError(t, r.SetMergeHeads(heads))
+	require.NoError(t, r.SetMergeMessage("Merge branch 'foo'\n"))
+
+	got, err := r.MergeHeads()
+	require.NoError(t, err)
+	assert.Equal(t, heads, got)
+
+	msg, err := r.MergeMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "Merge branch 'foo'\n", msg)
+
+	state, err = r.State()
+	require.NoError(t, err)
+	assert.Equal(t, Merging, state)
+
+	require.NoError(t, r.ClearMergeState())
+
+	got, err = r.MergeHeads()
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	msg, err = r.MergeMessage()
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(plumbing.CherryPickHead, heads[0])))
+	state, err = r.State()
+	require.NoError(t, err)
+	assert.Equal(t, CherryPicking, state)
+}
+
+func TestMergeStateNotSupported(t *testing.T) {
+	t.Parallel()
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+
+	heads, err := r.MergeHeads()
+	require.NoError(t, err)
+	assert.Empty(t, heads)
+
+	err = r.SetMergeHeads([]plumbing.Hash{plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")})
+	assert.ErrorIs(t, err, ErrMergeStateNotSupported)
+	assert.NoError(t, r.ClearMergeState())
+}
+
+func TestCommitConcludesMerge(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=foo", "GIT_AUTHOR_EMAIL=foo@example.com",
+			"GIT_COMMITTER_NAME=foo", "GIT_COMMITTER_EMAIL=foo@example.com")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "-q", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0o644))
+	git("add", "a")
+	git("commit", "-q", "-m", "a")
+	git("checkout", "-q", "-b", "topic")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b"), []byte("b"), 0o644))
+	git("add", "b")
+	git("commit", "-q", "-m", "b")
+	topic := git("rev-parse", "HEAD")
+	git("checkout", "-q", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c"), []byte("c"), 0o644))
+	git("add", "c")
+	git("commit", "-q", "-m", "c")
+	main := git("rev-parse", "HEAD")
+	git("merge", "-q", "--no-commit", "--no-ff", "topic")
+
+	r, err := PlainOpen(dir)
+	require.NoError(t, err)
+
+	state, err := r.State()
+	require.NoError(t, err)
+	assert.Equal(t, Merging, state)
+
+	heads, err := r.MergeHeads()
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{plumbing.NewHash(topic)}, heads)
+
+	msg, err := r.MergeMessage()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(msg, "Merge branch 'topic'"), msg)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	_, state, err = w.StatusWithState(StatusOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, Merging, state)
+
+	// The message proposed in MERGE_MSG is used by default.
+	h, err := w.Commit("", &CommitOptions{
+		Author: &object.Signature{Name: "foo", Email: "foo@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	c, err := r.CommitObject(h)
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{plumbing.NewHash(main), plumbing.NewHash(topic)}, c.ParentHashes)
+	assert.Equal(t, msg, c.Message)
+
+	state, err = r.State()
+	require.NoError(t, err)
+	assert.Equal(t, NoOperation, state)
+
+	_, err = os.Stat(filepath.Join(dir, ".git", "MERGE_MSG"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Empty(t, git("status", "--porcelain"))
+}
+
+func TestCommitMergeKeepingTree(t *testing.T) {
+	t.Parallel()
+
+	r, err := PlainInit(t.TempDir(), false)
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	opts := &CommitOptions{Author: defaultSignature(), AllowEmptyCommits: true}
+	base, err := w.Commit("base", opts)
+	require.NoError(t, err)
+
+	c, err := r.CommitObject(base)
+	require.NoError(t, err)
+
+	other, err := r.CommitTree(c.TreeHash, []plumbing.Hash{base}, &CommitTreeOptions{
+		Author: defaultSignature(), Message: "other",
+	})
+	require.NoError(t, err)
+
+	// As git merge -s ours, the merge keeps the tree of HEAD.
+	require.NoError(t, r.SetMergeHeads([]plumbing.Hash{other}))
+
+	h, err := w.Commit("merge", &CommitOptions{Author: defaultSignature()})
+	require.NoError(t, err)
+
+	c, err = r.CommitObject(h)
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{base, other}, c.ParentHashes)
+}
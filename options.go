@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 
 	"github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/mailmap"
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp/sideband"
@@ -58,6 +60,10 @@ type CloneOptions struct {
 	NoCheckout bool
 	// Limit fetching to the specified number of commits.
 	Depth int
+	// ShallowExclude limits fetching to the commits not reachable from the
+	// given remote branches or tags, as git clone --shallow-exclude does.
+	// It cannot be used together with Depth.
+	ShallowExclude []string
 	// RecurseSubmodules after the clone is created, initialize all submodules
 	// within, using their default settings. This option is ignored if the
 	// cloned repository does not have a worktree.
@@ -78,6 +84,17 @@ type CloneOptions struct {
 	CABundle []byte
 	// ProxyOptions provides info required for connecting to a proxy.
 	ProxyOptions transport.ProxyOptions
+	// MaxBytesPerSec, if positive, limits the rate at which the packfile is
+	// received, in bytes per second.
+	MaxBytesPerSec int64
+	// MaxBlobSize, if positive, is the maximum size of the blobs received,
+	// in bytes, to defend against maliciously huge ones. The clone fails with
+	// a *packfile.ObjectLimitError naming the first blob above it.
+	MaxBlobSize int64
+	// MaxTreeEntries, if positive, is the maximum number of entries of the
+	// trees received. The clone fails with a *packfile.ObjectLimitError
+	// naming the first tree above it.
+	MaxTreeEntries int
 	// When the repository to clone is on the local machine, instead of
 	// using hard links, automatically setup .git/objects/info/alternates
 	// to share the objects with the source repository.
@@ -185,8 +202,40 @@ type PullOptions struct {
 	CABundle []byte
 	// ProxyOptions provides info required for connecting to a proxy.
 	ProxyOptions transport.ProxyOptions
+	// MaxBytesPerSec, if positive, limits the rate at which the packfile is
+	// received, in bytes per second.
+	MaxBytesPerSec int64
+	// Autostash stashes the local changes of the tracked files before
+	// updating the worktree, and re-applies them after, as rebase.autoStash
+	// does. They are merged with the pulled changes, and if they conflict,
+	// ErrAutostashConflict is returned and the changes are kept in the stash.
+	Autostash bool
+	// Strategy sets how the fetched branch is integrated when it does not
+	// descend from the current one. If a merge or a rebase conflicts, a
+	// *MergeConflictError is returned and nothing is changed.
+	Strategy PullStrategy
 }
 
+// PullStrategy sets how Pull integrates a fetched branch that diverged from
+// the current one.
+type PullStrategy int8
+
+const (
+	// PullDefault rebases if branch.<name>.rebase, or else pull.rebase, is
+	// true, merges if it is false and only fast-forwards if none is set, as
+	// git does.
+	PullDefault PullStrategy = iota
+	// PullFastForwardOnly only fast-forwards the current branch, failing
+	// with ErrNonFastForwardUpdate if it diverged.
+	PullFastForwardOnly
+	// PullMerge creates a merge commit of the fetched branch into the
+	// current one, signed with the author and committer of the config.
+	PullMerge
+	// PullRebase replays the local commits of the current branch on top of
+	// the fetched one.
+	PullRebase
+)
+
 // Validate validates the fields and sets the default values.
 func (o *PullOptions) Validate() error {
 	if o.RemoteName == "" {
@@ -204,8 +253,9 @@ func (o *PullOptions) Validate() error {
 const (
 	InvalidTagMode = plumbing.InvalidTagMode
 	// TagFollowing any tag that points into the histories being fetched is also
-	// fetched. TagFollowing requires a server with `include-tag` capability
-	// in order to fetch the annotated tags objects.
+	// fetched. The annotated tag objects are sent along with the objects they
+	// point to by servers with the `include-tag` capability, and fetched
+	// afterwards from the others.
 	TagFollowing = plumbing.TagFollowing
 	// AllTags fetch all tags from the remote (i.e., fetch remote tags
 	// refs/tags/* into local tags with the same name)
@@ -224,6 +274,10 @@ type FetchOptions struct {
 	// Depth limit fetching to the specified number of commits from the tip of
 	// each remote branch history.
 	Depth int
+	// ShallowExclude limits fetching to the commits not reachable from the
+	// given remote branches or tags, as git fetch --shallow-exclude does.
+	// It cannot be used together with Depth.
+	ShallowExclude []string
 	// Auth credentials, if required, to use with the remote repository.
 	Auth transport.AuthMethod
 	// Progress is where the human readable information sent by the server is
@@ -242,12 +296,105 @@ type FetchOptions struct {
 	CABundle []byte
 	// ProxyOptions provides info required for connecting to a proxy.
 	ProxyOptions transport.ProxyOptions
+	// MaxBytesPerSec, if positive, limits the rate at which the packfile is
+	// received, in bytes per second, so that the fetch does not saturate
+	// the link.
+	MaxBytesPerSec int64
+	// MaxBlobSize, if positive, is the maximum size of the blobs received,
+	// in bytes, to defend against maliciously huge ones. The fetch fails with
+	// a *packfile.ObjectLimitError naming the first blob above it.
+	MaxBlobSize int64
+	// MaxTreeEntries, if positive, is the maximum number of entries of the
+	// trees received. The fetch fails with a *packfile.ObjectLimitError
+	// naming the first tree above it.
+	MaxTreeEntries int
 	// Prune specify that local refs that match given RefSpecs and that do
 	// not exist remotely will be removed.
 	Prune bool
+	// PruneTags specify that local tags that do not exist remotely will be
+	// removed, as if refs/tags/*:refs/tags/* was part of the RefSpecs. It
+	// only takes effect when Prune is set.
+	PruneTags bool
 	// Filter requests that the server to send only a subset of the objects.
 	// See https://git-scm.com/docs/git-clone#Documentation/git-clone.txt-code--filterltfilter-specgtcode
 	Filter packp.Filter
+	// NegotiationCallback, if set, is called with the references advertised
+	// by the remote to customize the pack negotiation. If nil, the haves are
+	// computed from the local references.
+	NegotiationCallback NegotiationCallback
+	// WriteFetchHead records the fetched references in FETCH_HEAD, as git
+	// fetch does, when the repository is stored on a filesystem.
+	WriteFetchHead bool
+	// Hashes are commits fetched along with their histories, in addition to
+	// the references matching RefSpecs, without updating any reference. If
+	// set without RefSpecs, the fetch RefSpecs of the remote are not used.
+	// Unless a hash is a reference tip advertised by the remote, the server
+	// must allow it to be wanted, as with uploadpack.allowReachableSHA1InWant
+	// or uploadpack.allowAnySHA1InWant, or ErrExactSHA1NotSupported is
+	// returned.
+	Hashes []plumbing.Hash
+	// UpdateRemoteHead points refs/remotes/<remote>/HEAD to the
+	// remote-tracking branch of the branch the HEAD of the remote points to,
+	// as advertised with the symref capability, so that it follows the
+	// changes of the default branch of the remote. The remote-tracking
+	// branch must exist after the fetch.
+	UpdateRemoteHead bool
+	// NegotiationAlgorithm selects how the haves sent to the remote are
+	// chosen. Defaults to fetch.negotiationAlgorithm of the repository
+	// config, and then to DefaultNegotiationAlgorithm. It is not used for
+	// the haves given by NegotiationCallback.
+	NegotiationAlgorithm NegotiationAlgorithm
+}
+
+// NegotiationAlgorithm is an algorithm choosing the haves sent to the remote
+// during the pack negotiation of a fetch, as fetch.negotiationAlgorithm.
+type NegotiationAlgorithm string
+
+const (
+	// DefaultNegotiationAlgorithm sends the recent commits of the local
+	// references.
+	DefaultNegotiationAlgorithm NegotiationAlgorithm = "default"
+	// SkippingNegotiationAlgorithm sends commits spaced exponentially along
+	// the history of the local references, converging in fewer rounds on
+	// long histories.
+	SkippingNegotiationAlgorithm NegotiationAlgorithm = "skipping"
+	// NoopNegotiationAlgorithm sends no haves, fetching the whole history
+	// of the references wanted.
+	NoopNegotiationAlgorithm NegotiationAlgorithm = "noop"
+)
+
+// ErrInvalidNegotiationAlgorithm is returned when a fetch uses an unknown
+// negotiation algorithm.
+var ErrInvalidNegotiationAlgorithm = errors.New("invalid negotiation algorithm")
+
+// parseNegotiationAlgorithm parses the value of fetch.negotiationAlgorithm,
+// "consecutive" being the name of the default algorithm in recent versions
+// of git.
+func parseNegotiationAlgorithm(v string) (NegotiationAlgorithm, error) {
+	switch a := NegotiationAlgorithm(v); a {
+	case "", "consecutive":
+		return DefaultNegotiationAlgorithm, nil
+	case DefaultNegotiationAlgorithm, SkippingNegotiationAlgorithm, NoopNegotiationAlgorithm:
+		return a, nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrInvalidNegotiationAlgorithm, v)
+}
+
+// NegotiationCallback is called during a fetch with the references advertised
+// by the remote, and returns how the pack negotiation should be performed. A
+// nil Negotiation keeps the default behavior.
+type NegotiationCallback func(remoteRefs []*plumbing.Reference) (*Negotiation, error)
+
+// Negotiation describes how the pack negotiation of a fetch is performed.
+type Negotiation struct {
+	// Haves are the commits the local repository has in common with the
+	// remote, sent to it to narrow down the objects to fetch. If nil, the
+	// haves are computed from the local references.
+	Haves []plumbing.Hash
+	// MaxRounds limits the number of rounds of haves sent to the remote,
+	// ending the negotiation once reached. Zero means no limit.
+	MaxRounds int
 }
 
 // Validate validates the fields and sets the default values.
@@ -266,6 +413,45 @@ func (o *FetchOptions) Validate() error {
 		}
 	}
 
+	if o.NegotiationAlgorithm != "" {
+		if _, err := parseNegotiationAlgorithm(string(o.NegotiationAlgorithm)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PruneRefsOptions describes how stale remote-tracking references should be
+// pruned.
+type PruneRefsOptions struct {
+	// RefSpecs select the local references managed by the remote. Defaults to
+	// the fetch RefSpecs of the remote.
+	RefSpecs []config.RefSpec
+	// PruneTags specify that local tags that do not exist remotely will be
+	// removed too, as if refs/tags/*:refs/tags/* was part of the RefSpecs.
+	PruneTags bool
+	// DryRun reports the references that would be pruned without removing
+	// them.
+	DryRun bool
+	// Auth credentials, if required, to use with the remote repository.
+	Auth transport.AuthMethod
+	// InsecureSkipTLS skips ssl verify if protocol is https
+	InsecureSkipTLS bool
+	// CABundle specify additional ca bundle with system cert pool
+	CABundle []byte
+	// ProxyOptions provides info required for connecting to a proxy.
+	ProxyOptions transport.ProxyOptions
+}
+
+// Validate validates the fields and sets the default values.
+func (o *PruneRefsOptions) Validate() error {
+	for _, r := range o.RefSpecs {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -313,9 +499,16 @@ type PushOptions struct {
 	Atomic bool
 	// ProxyOptions provides info required for connecting to a proxy.
 	ProxyOptions transport.ProxyOptions
+	// MaxBytesPerSec, if positive, limits the rate at which the packfile is
+	// sent, in bytes per second.
+	MaxBytesPerSec int64
 	// Quiet indicates whether the server should suppress human-readable
 	// output.
 	Quiet bool
+	// HooksEnabled runs the pre-push hook of the repository, from
+	// $GIT_DIR/hooks or core.hooksPath, before sending any object. The push
+	// is aborted if it exits with a non-zero status.
+	HooksEnabled bool
 }
 
 // ForceWithLease sets fields on the lease
@@ -396,6 +589,20 @@ type CheckoutOptions struct {
 	Keep bool
 	// SparseCheckoutDirectories
 	SparseCheckoutDirectories []string
+	// HooksEnabled runs the post-checkout hook of the repository, from
+	// $GIT_DIR/hooks or core.hooksPath, once the checkout is completed.
+	HooksEnabled bool
+	// PathSpecs, if not empty, constrains the updates of the index and the
+	// working tree to the paths matched by them, as ResetOptions.PathSpecs.
+	// HEAD is updated regardless.
+	PathSpecs []string
+	// Prefetch reads the blobs written to the working tree ahead, as
+	// ResetOptions.Prefetch.
+	Prefetch bool
+	// SubmoduleStrategy selects how the working trees of the populated
+	// submodules are updated to the commits recorded by the checked out
+	// commit. By default they are left untouched.
+	SubmoduleStrategy SubmoduleStrategy
 }
 
 // Validate validates the fields and sets the default values.
@@ -415,6 +622,25 @@ func (o *CheckoutOptions) Validate() error {
 	return nil
 }
 
+// SubmoduleStrategy defines how a checkout updates the working trees of the
+// submodules.
+type SubmoduleStrategy int8
+
+const (
+	// NoSubmoduleUpdate leaves the submodules untouched, only their gitlinks
+	// being updated in the index. This is the default strategy.
+	NoSubmoduleUpdate SubmoduleStrategy = iota
+	// CheckoutSubmoduleUpdate checks out the recorded commit in the
+	// submodules, detaching their HEAD, as git checkout
+	// --recurse-submodules does.
+	CheckoutSubmoduleUpdate
+	// MergeSubmoduleUpdate fast-forwards the current branch of the
+	// submodules to the recorded commit, as git submodule update --merge
+	// does for the fast-forward merges. The submodules with a detached HEAD
+	// are checked out.
+	MergeSubmoduleUpdate
+)
+
 // ResetMode defines the mode of a reset operation.
 type ResetMode int8
 
@@ -464,6 +690,20 @@ type ResetOptions struct {
 
 	// SkipSparseDirValidation will skip the validation for SparseDirs.
 	SkipSparseDirValidation bool
+
+	// PathSpecs, if not empty, constrains the reset to the paths matched by
+	// them. A pathspec matches a path, the paths under it or, if it has
+	// wildcards, the paths matching them. Pathspecs prefixed with the
+	// ":(exclude)" magic, or its short forms ":!" and ":^", exclude the paths
+	// they match, so that ":(exclude)vendor" resets everything but vendor/.
+	PathSpecs []string
+
+	// Prefetch reads the blobs written to the working tree ahead, in batches
+	// bounded by the object cache, when the storer implements
+	// storer.ObjectPrefetcher. On packed repositories, they are then read in
+	// the order they are stored instead of one at a time, which speeds up
+	// large checkouts.
+	Prefetch bool
 }
 
 // Validate validates the fields and sets the default values.
@@ -510,6 +750,18 @@ type LogOptions struct {
 	// specified hash. The default value for this field in nil
 	To plumbing.Hash
 
+	// Excludes are commits whose history is left out of the log, along
+	// with themselves. It is equivalent to running `git log ^<commit>`.
+	Excludes []plumbing.Hash
+
+	// Range is a range of commits in the A..B or A...B form, each side being
+	// a revision as accepted by Repository.ResolveRevision, HEAD if empty.
+	// A..B logs the commits reachable from B but not from A, as From set to B
+	// and A in Excludes would. A...B logs the commits reachable from either
+	// side but not from both, those reachable from B first. When set, From
+	// is ignored.
+	Range string
+
 	// The default traversal algorithm is Depth-first search
 	// set Order=LogOrderCommitterTime for ordering by committer time (more compatible with `git log`)
 	// set Order=LogOrderBSF for Breadth-first search
@@ -538,6 +790,66 @@ type LogOptions struct {
 	// Show commits older than a specific date.
 	// It is equivalent to running `git log --until <date>` or `git log --before <date>`.
 	Until *time.Time
+
+	// Show only the commits whose author, as "Name <email>", matches the
+	// regexp. It is equivalent to running `git log --author <pattern>`.
+	Author *regexp.Regexp
+
+	// Show only the commits whose committer, as "Name <email>", matches the
+	// regexp. It is equivalent to running `git log --committer <pattern>`.
+	Committer *regexp.Regexp
+
+	// Show only the commits whose message matches any of the regexps.
+	// It is equivalent to running `git log --grep <pattern>...`. The commits
+	// shown also match Author and Committer, if set.
+	MessageGrep []*regexp.Regexp
+
+	// AllMatch shows only the commits whose message matches all of the
+	// MessageGrep regexps, instead of any of them, as `git log --all-match`
+	// does.
+	AllMatch bool
+
+	// Mailmap, when set, rewrites the author and committer of the returned
+	// commits to their canonical form. See Repository.Mailmap.
+	Mailmap *mailmap.Mailmap
+}
+
+// ObjectGraphOptions describes how the commit graph returned by
+// Repository.ObjectGraph is walked.
+type ObjectGraphOptions struct {
+	// From are the commits to walk from. If empty, the commits of HEAD and
+	// of the branches are used, along with the ones of the tags and remote
+	// branches when included.
+	From []plumbing.Hash
+	// Limit is the maximum number of commits returned, 0 for no limit. When
+	// the repository has a commit-graph, the history below the commits
+	// returned is not walked.
+	Limit int
+	// Tags includes the tags in the references of the commits.
+	Tags bool
+	// Remotes includes the remote branches in the references of the
+	// commits.
+	Remotes bool
+}
+
+// WorktreeDiffOptions describes the changes returned by Worktree.Diff.
+type WorktreeDiffOptions struct {
+	// Staged includes the changes staged in the index, compared to HEAD, as
+	// git diff --cached shows.
+	Staged bool
+	// Unstaged includes the changes of the working tree not staged in the
+	// index, as git diff shows. Along with Staged, the working tree is
+	// compared to HEAD, as git diff HEAD does.
+	Unstaged bool
+}
+
+// Validate validates the fields and sets the default values.
+func (o *WorktreeDiffOptions) Validate() error {
+	if !o.Staged && !o.Unstaged {
+		o.Unstaged = true
+	}
+
+	return nil
 }
 
 // ErrMissingAuthor is returned when the author field is required but not provided.
@@ -558,9 +870,14 @@ type AddOptions struct {
 	Glob string
 	// SkipStatus adds the path with no status check. This option is relevant only
 	// when the `Path` option is specified and does not apply when the `All` option is used.
-	// Notice that when passing an ignored path it will be added anyway.
+	// Notice that an ignored path is still refused unless Force is set.
 	// When true it can speed up adding files to the worktree in very large repositories.
 	SkipStatus bool
+	// Force allows adding untracked paths ignored by a gitignore pattern,
+	// like git add --force. Otherwise an *IgnoredPathsError is returned for
+	// the ignored paths given by Path or matching Glob, while the other paths
+	// are added, and the ignored files found in a directory are skipped.
+	Force bool
 }
 
 // Validate validates the fields and sets the default values.
@@ -572,6 +889,30 @@ func (o *AddOptions) Validate(_ *Repository) error {
 	return nil
 }
 
+// RemoveOptions describes how a remove operation should be performed.
+type RemoveOptions struct {
+	// Path is the exact filepath to the file or directory to be removed.
+	Path string
+	// Glob removes all paths, matching pattern, from the index.
+	Glob string
+	// Cached only removes the paths from the index, keeping the files in the
+	// working tree, like git rm --cached.
+	Cached bool
+}
+
+// Validate validates the fields and sets the default values.
+func (o *RemoveOptions) Validate(_ *Repository) error {
+	if o.Path != "" && o.Glob != "" {
+		return fmt.Errorf("fields Path and Glob are mutual exclusive")
+	}
+
+	if o.Path == "" && o.Glob == "" {
+		return fmt.Errorf("either Path or Glob is required")
+	}
+
+	return nil
+}
+
 // CommitOptions describes how a commit operation should be performed.
 type CommitOptions struct {
 	// All automatically stage files that have been modified and deleted, but
@@ -588,14 +929,36 @@ type CommitOptions struct {
 	// nil the Author signature is used.
 	Committer *object.Signature
 	// Parents are the parents commits for the new commit, by default when
-	// len(Parents) is zero, the hash of HEAD reference is used.
+	// len(Parents) is zero, the hash of HEAD reference is used, followed by
+	// the commits of MERGE_HEAD if a merge is in progress.
 	Parents []plumbing.Hash
 	// Signer denotes a cryptographic signer to sign the commit with.
 	// A nil value here means the commit will not be signed.
 	Signer Signer
 	// Amend will create a new commit object and replace the commit that HEAD currently
-	// points to. Cannot be used with All nor Parents.
+	// points to. Cannot be used with All nor Parents. The new commit has the
+	// parents of the replaced one, and its author unless Author is set,
+	// while the committer is updated. The replacement is recorded in the
+	// reflogs of HEAD and of the current branch.
 	Amend bool
+	// HooksEnabled runs the pre-commit and commit-msg hooks of the
+	// repository, from $GIT_DIR/hooks or core.hooksPath. The commit is
+	// aborted if any of them exits with a non-zero status.
+	HooksEnabled bool
+	// NoVerify skips the pre-commit and commit-msg hooks even if
+	// HooksEnabled is set, as git commit --no-verify does.
+	NoVerify bool
+	// SignOff appends a Signed-off-by trailer with the committer identity to
+	// the commit message, unless it already ends with the same trailer.
+	SignOff bool
+	// Trailers are added to the trailer block ending the commit message,
+	// after the Signed-off-by one, as git commit --trailer does. The keys
+	// and values are separated by the first of the trailer.separators.
+	Trailers []object.Trailer
+	// StripComments removes the lines starting with core.commentChar from
+	// the commit message, and its superfluous whitespaces, as git commit
+	// --cleanup=strip does.
+	StripComments bool
 }
 
 // Validate validates the fields and sets the default values.
@@ -608,6 +971,33 @@ func (o *CommitOptions) Validate(r *Repository) error {
 		return errors.New("parents cannot be used with amend")
 	}
 
+	var amended *object.Commit
+	if o.Amend {
+		head, err := r.Head()
+		if err != nil {
+			return err
+		}
+
+		if amended, err = r.CommitObject(head.Hash()); err != nil {
+			return err
+		}
+	}
+
+	if amended != nil && o.Author == nil {
+		if o.Committer == nil {
+			if err := o.loadConfigAuthorAndCommitter(r); err != nil {
+				return err
+			}
+
+			if o.Committer == nil {
+				o.Committer = o.Author
+			}
+		}
+
+		author := amended.Author
+		o.Author = &author
+	}
+
 	if o.Author == nil {
 		if err := o.loadConfigAuthorAndCommitter(r); err != nil {
 			return err
@@ -618,6 +1008,11 @@ func (o *CommitOptions) Validate(r *Repository) error {
 		o.Committer = o.Author
 	}
 
+	if amended != nil {
+		o.Parents = amended.ParentHashes
+		return nil
+	}
+
 	if len(o.Parents) == 0 {
 		head, err := r.Head()
 		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
@@ -627,6 +1022,15 @@ func (o *CommitOptions) Validate(r *Repository) error {
 		if head != nil {
 			o.Parents = []plumbing.Hash{head.Hash()}
 		}
+
+		// The commit concluding a merge in progress has the commits being
+		// merged as additional parents.
+		heads, err := r.MergeHeads()
+		if err != nil {
+			return err
+		}
+
+		o.Parents = append(o.Parents, heads...)
 	}
 
 	return nil
@@ -669,6 +1073,39 @@ func (o *CommitOptions) loadConfigAuthorAndCommitter(r *Repository) error {
 	return nil
 }
 
+// CommitTreeOptions describes how a commit-tree operation should be performed.
+type CommitTreeOptions struct {
+	// Author is the author's signature of the commit. If Author is empty the
+	// Name and Email is read from the config, and time.Now it's used as When.
+	Author *object.Signature
+	// Committer is the committer's signature of the commit. If Committer is
+	// nil the Author signature is used.
+	Committer *object.Signature
+	// Message is the log message of the commit.
+	Message string
+	// Signer denotes a cryptographic signer to sign the commit with.
+	// A nil value here means the commit will not be signed.
+	Signer Signer
+}
+
+// Validate validates the fields and sets the default values.
+func (o *CommitTreeOptions) Validate(r *Repository) error {
+	if o.Author == nil {
+		co := &CommitOptions{Committer: o.Committer}
+		if err := co.loadConfigAuthorAndCommitter(r); err != nil {
+			return err
+		}
+
+		o.Author, o.Committer = co.Author, co.Committer
+	}
+
+	if o.Committer == nil {
+		o.Committer = o.Author
+	}
+
+	return nil
+}
+
 // Tag creation errors.
 var (
 	ErrMissingName    = errors.New("name field is required")
@@ -752,6 +1189,11 @@ type ListOptions struct {
 	ProxyOptions transport.ProxyOptions
 	// Timeout specifies the timeout in seconds for list operations
 	Timeout int
+	// RefPrefixFilter, if not empty, keeps only the references whose name
+	// starts with any of them. The filter is applied locally once the
+	// references advertised by the remote are received: all of them are
+	// still transferred.
+	RefPrefixFilter []string
 }
 
 // PeelingOption represents the different ways to handle peeled references.
@@ -823,13 +1265,49 @@ type PlainOpenOptions struct {
 	// DetectDotGit defines whether parent directories should be
 	// walked until a .git directory or file is found.
 	DetectDotGit bool
+	// CheckOwnership returns a *DubiousOwnershipError if the repository is
+	// owned by another user, unless it is listed in the safe.directory
+	// config, as git does. The ownership is only checked on Unix systems.
+	CheckOwnership bool
 }
 
 // Validate validates the fields and sets the default values.
 func (o *PlainOpenOptions) Validate() error { return nil }
 
-// ErrNoRestorePaths is returned when no paths are specified to restore.
-var ErrNoRestorePaths = errors.New("you must specify path(s) to restore")
+// ErrSparseCheckoutDirsAndPatterns is returned when both the directories and
+// the patterns of a sparse checkout are given.
+var ErrSparseCheckoutDirsAndPatterns = errors.New("Dirs and Patterns are mutually exclusive")
+
+// SparseCheckoutOptions describes how the sparse checkout of a worktree is
+// changed.
+type SparseCheckoutOptions struct {
+	// Dirs are the directories to check out, as ResetOptions.SparseDirs.
+	Dirs []string
+	// Patterns are the patterns of the paths to check out, in the format of
+	// $GIT_DIR/info/sparse-checkout. If both Dirs and Patterns are empty,
+	// every path is checked out.
+	Patterns []string
+	// DryRun only reports the paths that would be added to or removed from
+	// the worktree, leaving the index and the worktree untouched.
+	DryRun bool
+}
+
+// Validate validates the fields and sets the default values.
+func (o *SparseCheckoutOptions) Validate() error {
+	if len(o.Dirs) > 0 && len(o.Patterns) > 0 {
+		return ErrSparseCheckoutDirsAndPatterns
+	}
+
+	return nil
+}
+
+var (
+	// ErrNoRestorePaths is returned when no paths are specified to restore.
+	ErrNoRestorePaths = errors.New("you must specify path(s) to restore")
+	// ErrRestoreMergeStaged is returned when RestoreOptions.Merge is used
+	// along with RestoreOptions.Staged.
+	ErrRestoreMergeStaged = errors.New("merge cannot be used with staged")
+)
 
 // RestoreOptions describes how a restore should be performed.
 type RestoreOptions struct {
@@ -839,6 +1317,10 @@ type RestoreOptions struct {
 	Worktree bool
 	// List of file paths that will be restored
 	Files []string
+	// Merge recreates in the working tree the conflicted merge of the
+	// unmerged paths, from their stages in the index, as git restore --merge
+	// does. The index is left untouched, so Staged can't be set.
+	Merge bool
 }
 
 // Validate validates the fields and sets the default values.
@@ -847,5 +1329,84 @@ func (o *RestoreOptions) Validate() error {
 		return ErrNoRestorePaths
 	}
 
+	if o.Merge && o.Staged {
+		return ErrRestoreMergeStaged
+	}
+
+	return nil
+}
+
+// RewriteOptions describes how the history is rewritten by
+// Repository.RewriteHistory.
+type RewriteOptions struct {
+	// Refs are the references whose history is rewritten, and which are
+	// updated to point to the rewritten commits. If empty, all the branches
+	// and tags are, and HEAD if it is detached.
+	Refs []plumbing.ReferenceName
+	// TreeEntryFilter, if set, is called with the path and the entry of each
+	// file, symlink and submodule of the trees of the commits. It returns the
+	// entry to keep, with a different hash or mode to change it, or nil to
+	// remove it. The blobs of the changed entries must be stored by the
+	// caller. Directories left empty are removed.
+	TreeEntryFilter func(path string, e object.TreeEntry) (*object.TreeEntry, error)
+	// CommitFilter, if set, is called with each commit, once its tree and
+	// parents are rewritten, to change its author, committer or message.
+	CommitFilter func(c *object.Commit) error
+}
+
+// Validate validates the fields and sets the default values.
+func (o *RewriteOptions) Validate(r *Repository) error {
+	if len(o.Refs) > 0 {
+		return nil
+	}
+
+	refs, err := r.Storer.IterReferences()
+	if err != nil {
+		return err
+	}
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference &&
+			(ref.Name().IsBranch() || ref.Name().IsTag() || ref.Name() == plumbing.HEAD) {
+			o.Refs = append(o.Refs, ref.Name())
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// FastExportOptions describes the history written by Repository.FastExport.
+type FastExportOptions struct {
+	// Refs are the references whose history is exported. If empty, all the
+	// branches and tags are.
+	Refs []plumbing.ReferenceName
+}
+
+// Validate validates the fields and sets the default values.
+func (o *FastExportOptions) Validate(r *Repository) error {
+	if len(o.Refs) > 0 {
+		return nil
+	}
+
+	refs, err := r.Storer.IterReferences()
+	if err != nil {
+		return err
+	}
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsBranch() || ref.Name().IsTag() {
+			o.Refs = append(o.Refs, ref.Name())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The references are sorted for the stream to be the same across runs.
+	slices.Sort(o.Refs)
 	return nil
 }
@@ -0,0 +1,121 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultFuncname matches the lines starting a function when no diff driver
+// is set, as git does: those starting with a letter, an underscore or a
+// dollar sign.
+var defaultFuncname = regexp.MustCompile(`^[[:alpha:]$_]`)
+
+// diffLine is a line of a file patch.
+type diffLine struct {
+	text string
+	op   Operation
+}
+
+// functionHunks returns the hunks of chunks with ctxLines lines of context,
+// extended to the whole functions enclosing the changes, the function starts
+// being the lines of the old file matching funcname.
+func functionHunks(chunks []Chunk, ctxLines int, funcname *regexp.Regexp) []*hunk {
+	var lines []diffLine
+	for _, c := range chunks {
+		for _, l := range splitLines(c.Content()) {
+			lines = append(lines, diffLine{text: l, op: c.Type()})
+		}
+	}
+
+	isFunc := func(i int) bool {
+		return lines[i].op != Add && funcname.MatchString(strings.TrimSuffix(lines[i].text, "\n"))
+	}
+
+	// Compute the range of lines of each change, merging the overlapping ones.
+	type lineRange struct{ start, end int }
+	var ranges []lineRange
+	for i := 0; i < len(lines); i++ {
+		if lines[i].op == Equal {
+			continue
+		}
+
+		first := i
+		for i+1 < len(lines) && lines[i+1].op != Equal {
+			i++
+		}
+
+		start := max(first-ctxLines, 0)
+		for j := first; j >= 0; j-- {
+			if isFunc(j) || j == 0 {
+				start = min(start, j)
+				break
+			}
+		}
+
+		funcEnd := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if isFunc(j) {
+				funcEnd = j
+				break
+			}
+		}
+
+		for funcEnd > i+1 && strings.TrimSpace(lines[funcEnd-1].text) == "" {
+			funcEnd--
+		}
+
+		end := max(min(i+1+ctxLines, len(lines)), funcEnd)
+		if n := len(ranges); n > 0 && start <= ranges[n-1].end {
+			ranges[n-1].end = max(ranges[n-1].end, end)
+			continue
+		}
+
+		ranges = append(ranges, lineRange{start, end})
+	}
+
+	hunks := make([]*hunk, 0, len(ranges))
+	var fromLine, toLine, next int
+	for _, r := range ranges {
+		for ; next < r.start; next++ {
+			fromLine, toLine = advanceLines(lines[next].op, fromLine, toLine)
+		}
+
+		h := &hunk{fromLine: fromLine + 1, toLine: toLine + 1}
+		for j := r.start - 1; j >= 0; j-- {
+			if isFunc(j) {
+				h.ctxPrefix = strings.TrimSuffix(lines[j].text, "\n")
+				break
+			}
+		}
+
+		for ; next < r.end; next++ {
+			h.AddOp(lines[next].op, lines[next].text)
+			fromLine, toLine = advanceLines(lines[next].op, fromLine, toLine)
+		}
+
+		if h.fromCount == 0 {
+			h.fromLine--
+		}
+
+		if h.toCount == 0 {
+			h.toLine--
+		}
+
+		hunks = append(hunks, h)
+	}
+
+	return hunks
+}
+
+// advanceLines returns the line numbers of the old and new files after a line
+// with the given operation.
+func advanceLines(op Operation, fromLine, toLine int) (int, int) {
+	switch op {
+	case Equal:
+		return fromLine + 1, toLine + 1
+	case Delete:
+		return fromLine + 1, toLine
+	default:
+		return fromLine, toLine + 1
+	}
+}
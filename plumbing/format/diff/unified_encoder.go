@@ -44,6 +44,10 @@ type UnifiedEncoder struct {
 
 	// colorConfig is the color configuration. The default is no color.
 	color ColorConfig
+
+	// funcname matches the lines starting a function, if the hunks are
+	// extended to the functions enclosing the changes.
+	funcname *regexp.Regexp
 }
 
 // NewUnifiedEncoder returns a new UnifiedEncoder that writes to w.
@@ -74,6 +78,20 @@ func (e *UnifiedEncoder) SetDstPrefix(prefix string) *UnifiedEncoder {
 	return e
 }
 
+// SetFunctionContext makes e extend the hunks to the whole functions enclosing
+// the changes, as git diff --function-context does, where functions start at
+// the lines matching funcname. A nil funcname matches the lines starting
+// with a letter, an underscore or a dollar sign, as git does when no diff
+// driver is set. It returns e.
+func (e *UnifiedEncoder) SetFunctionContext(funcname *regexp.Regexp) *UnifiedEncoder {
+	if funcname == nil {
+		funcname = defaultFuncname
+	}
+
+	e.funcname = funcname
+	return e
+}
+
 // Encode encodes patch.
 func (e *UnifiedEncoder) Encode(patch Patch) error {
 	sb := &strings.Builder{}
@@ -87,8 +105,15 @@ func (e *UnifiedEncoder) Encode(patch Patch) error {
 
 	for _, filePatch := range patch.FilePatches() {
 		e.writeFilePatchHeader(sb, filePatch)
-		g := newHunksGenerator(filePatch.Chunks(), e.contextLines)
-		for _, hunk := range g.Generate() {
+
+		var hunks []*hunk
+		if e.funcname != nil {
+			hunks = functionHunks(filePatch.Chunks(), e.contextLines, e.funcname)
+		} else {
+			hunks = newHunksGenerator(filePatch.Chunks(), e.contextLines).Generate()
+		}
+
+		for _, hunk := range hunks {
 			hunk.writeTo(sb, e.color)
 		}
 	}
@@ -140,14 +165,14 @@ func (e *UnifiedEncoder) writeFilePatchHeader(sb *strings.Builder, filePatch Fil
 		lines = append(lines,
 			fmt.Sprintf("diff --git %s %s", e.srcPrefix+to.Path(), e.dstPrefix+to.Path()),
 			fmt.Sprintf("new file mode %o", to.Mode()),
-			fmt.Sprintf("index %s..%s", plumbing.ZeroHash, to.Hash()),
+			fmt.Sprintf("index %s..%s", plumbing.ZeroHashFor(to.Hash().Format()), to.Hash()),
 		)
 		lines = e.appendPathLines(lines, "/dev/null", e.dstPrefix+to.Path(), isBinary)
 	case to == nil:
 		lines = append(lines,
 			fmt.Sprintf("diff --git %s %s", e.srcPrefix+from.Path(), e.dstPrefix+from.Path()),
 			fmt.Sprintf("deleted file mode %o", from.Mode()),
-			fmt.Sprintf("index %s..%s", from.Hash(), plumbing.ZeroHash),
+			fmt.Sprintf("index %s..%s", from.Hash(), plumbing.ZeroHashFor(from.Hash().Format())),
 		)
 		lines = e.appendPathLines(lines, e.srcPrefix+from.Path(), "/dev/null", isBinary)
 	}
@@ -2,6 +2,7 @@ package diff
 
 import (
 	"bytes"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -100,6 +101,53 @@ func (s *UnifiedEncoderTestSuite) TestEncode() {
 	}
 }
 
+func (s *UnifiedEncoderTestSuite) TestEncodeFunctionContext() {
+	p := testPatch{
+		filePatches: []testFilePatch{{
+			from: &testFile{mode: filemode.Regular, path: "main.go", seed: "old"},
+			to:   &testFile{mode: filemode.Regular, path: "main.go", seed: "new"},
+			chunks: []testChunk{{
+				content: "package main\n\nimport \"fmt\"\n\nfunc a() {\n\tfmt.Println(\"a\")\n}\n\nfunc b() {\n\tx := 1\n\ty := 2\n",
+				op:      Equal,
+			}, {
+				content: "\tfmt.Println(x + y)\n",
+				op:      Delete,
+			}, {
+				content: "\tfmt.Println(x * y)\n",
+				op:      Add,
+			}, {
+				content: "\tz := 3\n\tw := 4\n}\n\nfunc c() {\n\tfmt.Println(\"c\")\n}\n",
+				op:      Equal,
+			}},
+		}},
+	}
+
+	// Generated with git diff -W -U1.
+	expected := `diff --git a/main.go b/main.go
+index 489ce0f857e7634a0eb9f328265a3e91fad49f61..3e5126c4e761fd09582fc517918a1601b218dff0 100644
+--- a/main.go
++++ b/main.go
+@@ -9,7 +9,7 @@ func a() {
+ func b() {
+ 	x := 1
+ 	y := 2
+-	fmt.Println(x + y)
++	fmt.Println(x * y)
+ 	z := 3
+ 	w := 4
+ }
+`
+
+	// The default, and the one of the golang diff driver of git.
+	goFuncname := regexp.MustCompile(`^[ \t]*(func[ \t]*.*(\{[ \t]*)?|type[ \t].*(struct|interface)[ \t]*(\{[ \t]*)?)$`)
+	for _, funcname := range []*regexp.Regexp{nil, goFuncname} {
+		buffer := bytes.NewBuffer(nil)
+		err := NewUnifiedEncoder(buffer, 1).SetFunctionContext(funcname).Encode(p)
+		s.NoError(err)
+		s.Equal(expected, buffer.String())
+	}
+}
+
 var oneChunkPatch Patch = testPatch{
 	message: "",
 	filePatches: []testFilePatch{{
@@ -0,0 +1,408 @@
+package fastimport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// Decoder reads the commands of a stream one at a time.
+type Decoder struct {
+	r *bufio.Reader
+	// line is the number of the last line read.
+	line int
+	// pending is a line read ahead, which belongs to the next command.
+	pending *string
+}
+
+// NewDecoder returns a Decoder reading the stream from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Next returns the next command of the stream. It returns io.EOF when there
+// are no more commands. The progress, checkpoint and feature commands, and
+// the comments, are skipped.
+func (d *Decoder) Next() (Command, error) {
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch cmd {
+		case "":
+			continue
+		case "blob":
+			return d.decodeBlob()
+		case "commit":
+			return d.decodeCommit(arg)
+		case "reset":
+			return d.decodeReset(arg)
+		case "tag":
+			return d.decodeTag(arg)
+		case "done":
+			return &Done{}, nil
+		case "progress", "checkpoint", "feature", "option":
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		return nil, d.errorf("unsupported command %q", cmd)
+	}
+}
+
+func (d *Decoder) decodeBlob() (*Blob, error) {
+	b := &Blob{}
+	line, err := d.readLine()
+	if err != nil {
+		return nil, d.unexpectedEOF(err)
+	}
+
+	if line, err = d.decodeMark(line, &b.Mark); err != nil {
+		return nil, err
+	}
+
+	if line, err = d.skipOriginalOID(line); err != nil {
+		return nil, err
+	}
+
+	b.Data, err = d.decodeData(line)
+	return b, err
+}
+
+func (d *Decoder) decodeCommit(ref string) (*Commit, error) {
+	c := &Commit{Ref: ref}
+	line, err := d.readLine()
+	if err != nil {
+		return nil, d.unexpectedEOF(err)
+	}
+
+	if line, err = d.decodeMark(line, &c.Mark); err != nil {
+		return nil, err
+	}
+
+	if line, err = d.skipOriginalOID(line); err != nil {
+		return nil, err
+	}
+
+	if arg, ok := strings.CutPrefix(line, "author "); ok {
+		c.Author = decodeSignature(arg)
+		if line, err = d.readLine(); err != nil {
+			return nil, d.unexpectedEOF(err)
+		}
+	}
+
+	arg, ok := strings.CutPrefix(line, "committer ")
+	if !ok {
+		return nil, d.errorf("expected committer, got %q", line)
+	}
+
+	c.Committer = *decodeSignature(arg)
+	if line, err = d.readLine(); err != nil {
+		return nil, d.unexpectedEOF(err)
+	}
+
+	if strings.HasPrefix(line, "encoding ") {
+		if line, err = d.readLine(); err != nil {
+			return nil, d.unexpectedEOF(err)
+		}
+	}
+
+	msg, err := d.decodeData(line)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Message = string(msg)
+	for {
+		line, err := d.readLine()
+		if err == io.EOF {
+			return c, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if line == "" {
+			return c, nil
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch cmd {
+		case "from":
+			c.From = arg
+		case "merge":
+			c.Merges = append(c.Merges, arg)
+		case "M", "D", "deleteall":
+			fc, err := d.decodeFileChange(cmd, arg)
+			if err != nil {
+				return nil, err
+			}
+
+			c.Changes = append(c.Changes, fc)
+		default:
+			// The line belongs to the next command.
+			d.unread(line)
+			return c, nil
+		}
+	}
+}
+
+// decodeFileChange decodes a file change of a commit, reading its inline
+// data.
+func (d *Decoder) decodeFileChange(cmd, arg string) (FileChange, error) {
+	switch cmd {
+	case "deleteall":
+		return FileChange{Type: FileDeleteAll}, nil
+	case "D":
+		p, err := unquotePath(arg)
+		if err != nil || !validPath(p) {
+			return FileChange{}, d.errorf("invalid path %q", arg)
+		}
+
+		return FileChange{Type: FileDelete, Path: p}, nil
+	}
+
+	fields := strings.SplitN(arg, " ", 3)
+	if len(fields) != 3 {
+		return FileChange{}, d.errorf("invalid file change %q", cmd+" "+arg)
+	}
+
+	mode, err := parseMode(fields[0])
+	if err != nil {
+		return FileChange{}, d.errorf("%s", err)
+	}
+
+	p, err := unquotePath(fields[2])
+	if err != nil || !validPath(p) {
+		return FileChange{}, d.errorf("invalid path %q", fields[2])
+	}
+
+	fc := FileChange{Type: FileModify, Path: p, Mode: mode, DataRef: fields[1]}
+	if fc.DataRef != "inline" {
+		return fc, nil
+	}
+
+	line, err := d.readLine()
+	if err != nil {
+		return FileChange{}, d.unexpectedEOF(err)
+	}
+
+	fc.DataRef = ""
+	if fc.Data, err = d.decodeData(line); err != nil {
+		return FileChange{}, err
+	}
+
+	if fc.Data == nil {
+		fc.Data = []byte{}
+	}
+
+	return fc, nil
+}
+
+func (d *Decoder) decodeReset(ref string) (*Reset, error) {
+	r := &Reset{Ref: ref}
+	line, err := d.readLine()
+	if err == io.EOF {
+		return r, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if from, ok := strings.CutPrefix(line, "from "); ok {
+		r.From = from
+		return r, nil
+	}
+
+	if line != "" {
+		d.unread(line)
+	}
+
+	return r, nil
+}
+
+func (d *Decoder) decodeTag(name string) (*Tag, error) {
+	t := &Tag{Name: name}
+	line, err := d.readLine()
+	if err != nil {
+		return nil, d.unexpectedEOF(err)
+	}
+
+	if line, err = d.decodeMark(line, &t.Mark); err != nil {
+		return nil, err
+	}
+
+	from, ok := strings.CutPrefix(line, "from ")
+	if !ok {
+		return nil, d.errorf("expected from, got %q", line)
+	}
+
+	t.From = from
+	if line, err = d.readLine(); err != nil {
+		return nil, d.unexpectedEOF(err)
+	}
+
+	if line, err = d.skipOriginalOID(line); err != nil {
+		return nil, err
+	}
+
+	if arg, ok := strings.CutPrefix(line, "tagger "); ok {
+		t.Tagger = decodeSignature(arg)
+		if line, err = d.readLine(); err != nil {
+			return nil, d.unexpectedEOF(err)
+		}
+	}
+
+	msg, err := d.decodeData(line)
+	t.Message = string(msg)
+	return t, err
+}
+
+// decodeMark decodes the mark of a command if line is one, returning the
+// line following it.
+func (d *Decoder) decodeMark(line string, mark *int) (string, error) {
+	arg, ok := strings.CutPrefix(line, "mark ")
+	if !ok {
+		return line, nil
+	}
+
+	m, ok := ParseMarkRef(arg)
+	if !ok {
+		return "", d.errorf("invalid mark %q", arg)
+	}
+
+	*mark = m
+	next, err := d.readLine()
+	if err != nil {
+		return "", d.unexpectedEOF(err)
+	}
+
+	return next, nil
+}
+
+// skipOriginalOID skips the original-oid line of a command if line is one,
+// returning the line following it.
+func (d *Decoder) skipOriginalOID(line string) (string, error) {
+	if !strings.HasPrefix(line, "original-oid ") {
+		return line, nil
+	}
+
+	next, err := d.readLine()
+	if err != nil {
+		return "", d.unexpectedEOF(err)
+	}
+
+	return next, nil
+}
+
+// decodeData decodes the data command line, either with the exact count of
+// bytes, or delimited.
+func (d *Decoder) decodeData(line string) ([]byte, error) {
+	arg, ok := strings.CutPrefix(line, "data ")
+	if !ok {
+		return nil, d.errorf("expected data, got %q", line)
+	}
+
+	if delim, ok := strings.CutPrefix(arg, "<<"); ok {
+		return d.decodeDelimitedData(delim)
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return nil, d.errorf("invalid data length %q", arg)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(d.r, data); err != nil {
+		return nil, d.unexpectedEOF(err)
+	}
+
+	d.line += bytes.Count(data, []byte("\n"))
+
+	// The data may be followed by an optional LF.
+	if b, err := d.r.ReadByte(); err == nil {
+		if b == '\n' {
+			d.line++
+		} else {
+			_ = d.r.UnreadByte()
+		}
+	}
+
+	return data, nil
+}
+
+func (d *Decoder) decodeDelimitedData(delim string) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, d.unexpectedEOF(err)
+		}
+
+		if line == delim {
+			return buf.Bytes(), nil
+		}
+
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+}
+
+// readLine returns the next line, without its LF.
+func (d *Decoder) readLine() (string, error) {
+	if d.pending != nil {
+		line := *d.pending
+		d.pending = nil
+		return line, nil
+	}
+
+	line, err := d.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	d.line++
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// unread makes the line the next one returned by readLine.
+func (d *Decoder) unread(line string) {
+	d.pending = &line
+}
+
+func (d *Decoder) unexpectedEOF(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return d.errorf("unexpected end of stream")
+	}
+
+	return err
+}
+
+func (d *Decoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s", ErrMalformedStream, d.line, fmt.Sprintf(format, args...))
+}
+
+// decodeSignature decodes the signature of an author, committer or tagger,
+// in the raw date format.
+func decodeSignature(s string) *object.Signature {
+	sig := &object.Signature{}
+	sig.Decode([]byte(s))
+	return sig
+}
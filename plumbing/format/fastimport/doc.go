@@ -0,0 +1,22 @@
+// Package fastimport implements encoding and decoding of the streams of git
+// fast-import and git fast-export, which describe a history as a sequence of
+// commands, so that it can be moved between version control systems.
+//
+// The commands supported are blob, commit, reset, tag and done, along with
+// the modify, delete and deleteall file changes of commits:
+//
+//	blob
+//	mark :1
+//	data 6
+//	hello
+//
+//	commit refs/heads/master
+//	mark :2
+//	author Author Name <author@example.com> 1234567890 +0000
+//	committer Author Name <author@example.com> 1234567890 +0000
+//	data 15
+//	Initial commit
+//	M 100644 :1 hello.txt
+//
+// See https://git-scm.com/docs/git-fast-import.
+package fastimport
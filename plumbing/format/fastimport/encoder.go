@@ -0,0 +1,133 @@
+package fastimport
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// Encoder writes the commands of a stream.
+type Encoder struct {
+	w *bufio.Writer
+}
+
+// NewEncoder returns an Encoder writing the stream to w. Flush must be
+// called once all the commands are written.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes the command.
+func (e *Encoder) Encode(cmd Command) error {
+	switch c := cmd.(type) {
+	case *Blob:
+		e.encodeBlob(c)
+	case *Commit:
+		e.encodeCommit(c)
+	case *Reset:
+		e.encodeReset(c)
+	case *Tag:
+		e.encodeTag(c)
+	case *Done:
+		e.printf("done\n")
+	default:
+		return fmt.Errorf("unsupported command %T", cmd)
+	}
+
+	return nil
+}
+
+// Flush writes the buffered commands to the underlying writer.
+func (e *Encoder) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *Encoder) encodeBlob(b *Blob) {
+	e.printf("blob\n")
+	e.encodeMark(b.Mark)
+	e.encodeData(b.Data)
+}
+
+func (e *Encoder) encodeCommit(c *Commit) {
+	e.printf("commit %s\n", c.Ref)
+	e.encodeMark(c.Mark)
+	if c.Author != nil {
+		e.encodeSignature("author", c.Author)
+	}
+
+	e.encodeSignature("committer", &c.Committer)
+	e.encodeData([]byte(c.Message))
+	if c.From != "" {
+		e.printf("from %s\n", c.From)
+	}
+
+	for _, m := range c.Merges {
+		e.printf("merge %s\n", m)
+	}
+
+	for _, fc := range c.Changes {
+		switch fc.Type {
+		case FileDeleteAll:
+			e.printf("deleteall\n")
+		case FileDelete:
+			e.printf("D %s\n", quotePath(fc.Path))
+		case FileModify:
+			if fc.DataRef != "" {
+				e.printf("M %o %s %s\n", uint32(fc.Mode), fc.DataRef, quotePath(fc.Path))
+				continue
+			}
+
+			e.printf("M %o inline %s\n", uint32(fc.Mode), quotePath(fc.Path))
+			e.encodeData(fc.Data)
+		}
+	}
+
+	e.printf("\n")
+}
+
+func (e *Encoder) encodeReset(r *Reset) {
+	e.printf("reset %s\n", r.Ref)
+	if r.From != "" {
+		e.printf("from %s\n", r.From)
+	}
+
+	e.printf("\n")
+}
+
+func (e *Encoder) encodeTag(t *Tag) {
+	e.printf("tag %s\n", t.Name)
+	e.encodeMark(t.Mark)
+	e.printf("from %s\n", t.From)
+	if t.Tagger != nil {
+		e.encodeSignature("tagger", t.Tagger)
+	}
+
+	e.encodeData([]byte(t.Message))
+}
+
+func (e *Encoder) encodeMark(mark int) {
+	if mark > 0 {
+		e.printf("mark %s\n", MarkRef(mark))
+	}
+}
+
+func (e *Encoder) encodeSignature(name string, sig *object.Signature) {
+	e.printf("%s ", name)
+	_ = sig.Encode(e.w)
+	e.printf("\n")
+}
+
+// encodeData writes the data with its exact count of bytes, followed by the
+// optional LF.
+func (e *Encoder) encodeData(data []byte) {
+	e.printf("data %d\n", len(data))
+	_, _ = e.w.Write(data)
+	e.printf("\n")
+}
+
+// printf writes to the buffer, whose errors are reported by Flush.
+func (e *Encoder) printf(format string, args ...any) {
+	_, _ = fmt.Fprintf(e.w, format, args...)
+}
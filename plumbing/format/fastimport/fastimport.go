@@ -0,0 +1,184 @@
+package fastimport
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ErrMalformedStream is returned when a stream can't be decoded.
+var ErrMalformedStream = errors.New("malformed fast-import stream")
+
+// Command is a command of a stream: *Blob, *Commit, *Reset, *Tag or *Done.
+type Command interface {
+	command()
+}
+
+// Blob creates a blob, which can be referenced by its mark afterwards.
+type Blob struct {
+	// Mark identifies the blob in the stream, zero if it has none.
+	Mark int
+	// Data is the content of the blob.
+	Data []byte
+}
+
+// Commit creates a commit on a branch, whose tree is the one of its first
+// parent changed by its file changes.
+type Commit struct {
+	// Ref is the name of the branch the commit is made on.
+	Ref string
+	// Mark identifies the commit in the stream, zero if it has none.
+	Mark int
+	// Author is the author of the commit, the committer if nil.
+	Author *object.Signature
+	// Committer is the committer of the commit.
+	Committer object.Signature
+	// Message is the message of the commit.
+	Message string
+	// From is the first parent of the commit, as a mark, hash or reference.
+	// If empty, the commit follows the current tip of the branch.
+	From string
+	// Merges are the other parents of the commit.
+	Merges []string
+	// Changes are the changes of the files from the tree of the first
+	// parent.
+	Changes []FileChange
+}
+
+// FileChangeType is the type of a FileChange.
+type FileChangeType int
+
+const (
+	// FileModify adds or modifies a file.
+	FileModify FileChangeType = iota
+	// FileDelete removes a file, or a directory recursively.
+	FileDelete
+	// FileDeleteAll removes all the files, so that the tree is built from
+	// the following changes only.
+	FileDeleteAll
+)
+
+// FileChange is a change of a file made by a Commit.
+type FileChange struct {
+	// Type is the type of the change.
+	Type FileChangeType
+	// Path is the path of the file, empty for FileDeleteAll.
+	Path string
+	// Mode is the mode of the file modified.
+	Mode filemode.FileMode
+	// DataRef is the blob of the file modified, as a mark or hash, or the
+	// commit of a submodule. If empty, the content is given inline by Data.
+	DataRef string
+	// Data is the inline content of the file modified.
+	Data []byte
+}
+
+// Reset sets a branch to a commit, or makes the next commit on it a root
+// commit when From is empty.
+type Reset struct {
+	// Ref is the name of the branch.
+	Ref string
+	// From is the commit the branch is set to, as a mark, hash or reference.
+	From string
+}
+
+// Tag creates an annotated tag, and its reference refs/tags/<Name>.
+type Tag struct {
+	// Name is the name of the tag.
+	Name string
+	// Mark identifies the tag in the stream, zero if it has none.
+	Mark int
+	// From is the object tagged, as a mark, hash or reference.
+	From string
+	// Tagger is the tagger, nil if the tag has none.
+	Tagger *object.Signature
+	// Message is the message of the tag.
+	Message string
+}
+
+// Done marks the end of the stream.
+type Done struct{}
+
+func (*Blob) command()   {}
+func (*Commit) command() {}
+func (*Reset) command()  {}
+func (*Tag) command()    {}
+func (*Done) command()   {}
+
+// MarkRef returns the reference to the mark in a stream, as ":<mark>".
+func MarkRef(mark int) string {
+	return ":" + strconv.Itoa(mark)
+}
+
+// ParseMarkRef returns the mark referenced by ref, false if it isn't a
+// reference to a mark.
+func ParseMarkRef(ref string) (int, bool) {
+	if !strings.HasPrefix(ref, ":") {
+		return 0, false
+	}
+
+	mark, err := strconv.Atoi(ref[1:])
+	if err != nil || mark <= 0 {
+		return 0, false
+	}
+
+	return mark, true
+}
+
+// parseMode parses the mode of a modified file, which may be given in its
+// short form for regular files.
+func parseMode(s string) (filemode.FileMode, error) {
+	switch s {
+	case "644":
+		return filemode.Regular, nil
+	case "755":
+		return filemode.Executable, nil
+	}
+
+	m, err := filemode.New(s)
+	if err != nil {
+		return filemode.Empty, err
+	}
+
+	switch m {
+	case filemode.Regular, filemode.Executable, filemode.Symlink, filemode.Submodule:
+		return m, nil
+	}
+
+	return filemode.Empty, fmt.Errorf("unsupported mode %s", s)
+}
+
+// quotePath quotes a path as a C string when it can't be written as is.
+func quotePath(p string) string {
+	if strings.ContainsAny(p, "\n\"") || strings.HasPrefix(p, "\"") {
+		return strconv.Quote(p)
+	}
+
+	return p
+}
+
+// unquotePath returns the path, unquoted if it was quoted as a C string.
+func unquotePath(p string) (string, error) {
+	if !strings.HasPrefix(p, "\"") {
+		return p, nil
+	}
+
+	return strconv.Unquote(p)
+}
+
+// validPath returns whether the path of a file change can be written to a
+// tree: a relative path with no empty, ".", ".." or ".git" components.
+func validPath(p string) bool {
+	for _, c := range strings.Split(p, "/") {
+		switch {
+		case c == "", c == ".", c == "..", strings.EqualFold(c, ".git"):
+			return false
+		}
+	}
+
+	return true
+}
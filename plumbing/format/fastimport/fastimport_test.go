@@ -0,0 +1,184 @@
+package fastimport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+func decodeAll(t *testing.T, r io.Reader) []Command {
+	t.Helper()
+
+	var cmds []Command
+	d := NewDecoder(r)
+	for {
+		cmd, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return cmds
+		}
+
+		require.NoError(t, err)
+		cmds = append(cmds, cmd)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	t.Parallel()
+
+	sig := object.Signature{
+		Name:  "Foo Bar",
+		Email: "foo@example.com",
+		When:  time.Unix(1234567890, 0).In(time.FixedZone("", 3600)),
+	}
+
+	cmds := []Command{
+		&Blob{Mark: 1, Data: []byte("hello\n")},
+		&Blob{Data: []byte("no trailing newline")},
+		&Commit{
+			Ref:       "refs/heads/master",
+			Mark:      2,
+			Author:    &sig,
+			Committer: sig,
+			Message:   "first\n",
+			Changes: []FileChange{
+				{Type: FileModify, Path: "hello.txt", Mode: filemode.Regular, DataRef: ":1"},
+				{Type: FileModify, Path: "dir/with space", Mode: filemode.Executable, Data: []byte("#!/bin/sh\n")},
+				{Type: FileModify, Path: "quoted\"\nname", Mode: filemode.Symlink, Data: []byte("target")},
+			},
+		},
+		&Reset{Ref: "refs/heads/other"},
+		&Commit{
+			Ref:       "refs/heads/other",
+			Mark:      3,
+			Committer: sig,
+			Message:   "second without newline",
+			From:      ":2",
+			Merges:    []string{"refs/heads/master"},
+			Changes: []FileChange{
+				{Type: FileDeleteAll},
+				{Type: FileDelete, Path: "hello.txt"},
+			},
+		},
+		&Reset{Ref: "refs/heads/master", From: ":3"},
+		&Tag{Name: "v1.0", Mark: 4, From: ":3", Tagger: &sig, Message: "release\n"},
+		&Done{},
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	for _, cmd := range cmds {
+		require.NoError(t, e.Encode(cmd))
+	}
+	require.NoError(t, e.Flush())
+
+	decoded := decodeAll(t, &buf)
+	require.Len(t, decoded, len(cmds))
+	for i := range cmds {
+		assert.Equal(t, cmds[i], decoded[i], "command %d", i)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	stream := `# a comment
+feature done
+blob
+mark :1
+original-oid 0123456789012345678901234567890123456789
+data <<EOF
+line 1
+line 2
+EOF
+
+commit refs/heads/master
+committer Foo <foo@example.com> 1234567890 +0000
+data 7
+message
+M 644 :1 a.txt
+M 755 inline b.sh
+data 3
+foo
+D old.txt
+progress halfway
+
+commit refs/heads/master
+committer Foo <foo@example.com> 1234567891 +0000
+data 5
+again
+reset refs/heads/branch
+from :1
+done
+`
+
+	cmds := decodeAll(t, strings.NewReader(stream))
+	require.Len(t, cmds, 5)
+
+	blob := cmds[0].(*Blob)
+	assert.Equal(t, 1, blob.Mark)
+	assert.Equal(t, "line 1\nline 2\n", string(blob.Data))
+
+	commit := cmds[1].(*Commit)
+	assert.Nil(t, commit.Author)
+	assert.Equal(t, "Foo", commit.Committer.Name)
+	assert.Equal(t, "message", commit.Message)
+	assert.Equal(t, []FileChange{
+		{Type: FileModify, Path: "a.txt", Mode: filemode.Regular, DataRef: ":1"},
+		{Type: FileModify, Path: "b.sh", Mode: filemode.Executable, Data: []byte("foo")},
+		{Type: FileDelete, Path: "old.txt"},
+	}, commit.Changes)
+
+	assert.Equal(t, "again", cmds[2].(*Commit).Message)
+	assert.Equal(t, &Reset{Ref: "refs/heads/branch", From: ":1"}, cmds[3])
+	assert.Equal(t, &Done{}, cmds[4])
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	t.Parallel()
+
+	for _, stream := range []string{
+		"frobnicate\n",
+		"blob\nmark :x\ndata 1\na\n",
+		"blob\ndata 10\nshort",
+		"commit refs/heads/master\ndata 0\n\n",
+		"commit refs/heads/master\ncommitter Foo <foo@example.com> 1 +0000\ndata 0\nM 040000 :1 dir\n",
+		"tag v1\ndata 0\n",
+	} {
+		d := NewDecoder(strings.NewReader(stream))
+		_, err := d.Next()
+		assert.ErrorIs(t, err, ErrMalformedStream, stream)
+	}
+}
+
+func TestDecodeInvalidPath(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{
+		`""`,
+		".",
+		"..",
+		"a/../b",
+		"./a",
+		".git/config",
+		"a/.GIT/config",
+		"/a",
+		"a/",
+		"a//b",
+	} {
+		for _, change := range []string{"M 100644 :1 " + path, "D " + path} {
+			stream := "commit refs/heads/master\ncommitter Foo <foo@example.com> 1 +0000\ndata 0\n" + change + "\n"
+			d := NewDecoder(strings.NewReader(stream))
+			_, err := d.Next()
+			assert.ErrorIs(t, err, ErrMalformedStream, change)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package gitattributes
+
+import "strconv"
+
+// Text is the state of the text attribute of a path, deciding whether the
+// line endings of the file are normalized to LF when it is added.
+type Text int
+
+const (
+	// TextUnspecified leaves the normalization to core.autocrlf.
+	TextUnspecified Text = iota
+	// TextSet always normalizes the line endings.
+	TextSet
+	// TextUnset never converts the line endings, as for binary files.
+	TextUnset
+	// TextAuto normalizes the line endings of the files detected as text.
+	TextAuto
+)
+
+// binaryMacro is the binary macro built into git, used unless redefined.
+var binaryMacro = MatchAttribute{
+	Name: "binary",
+	Attributes: []Attribute{
+		attribute{name: "diff", state: attributeUnset},
+		attribute{name: "merge", state: attributeUnset},
+		attribute{name: "text", state: attributeUnset},
+	},
+}
+
+// MatchText returns the state of the text attribute of path, given the
+// patterns in ascending order of priority, as returned by ReadPatterns. As in
+// git, the matching line with the highest priority specifying the attribute
+// wins, either directly or through a macro such as binary, and setting the
+// eol attribute implies the text one when it is not specified.
+func MatchText(stack []MatchAttribute, path []string) Text {
+	macros := map[string]MatchAttribute{binaryMacro.Name: binaryMacro}
+	for _, ma := range stack {
+		if ma.Pattern == nil {
+			macros[ma.Name] = ma
+		}
+	}
+
+	var text, eol Attribute
+	for i := len(stack) - 1; i >= 0 && (text == nil || eol == nil); i-- {
+		ma := stack[i]
+		if ma.Pattern == nil || !ma.Pattern.Match(path) {
+			continue
+		}
+
+		line := lineAttributes(ma.Attributes, macros)
+		if text == nil {
+			text = line["text"]
+		}
+
+		if eol == nil {
+			eol = line["eol"]
+		}
+	}
+
+	switch {
+	case text != nil && text.IsSet():
+		return TextSet
+	case text != nil && text.IsUnset():
+		return TextUnset
+	case text != nil && text.IsValueSet() && text.Value() == "auto":
+		return TextAuto
+	case (text == nil || text.IsUnspecified()) && eol != nil && eol.IsValueSet():
+		return TextSet
+	default:
+		return TextUnspecified
+	}
+}
+
+// lineAttributes returns the attributes of a line with its macros expanded,
+// each one overriding the previous ones.
+func lineAttributes(attrs []Attribute, macros map[string]MatchAttribute) map[string]Attribute {
+	line := make(map[string]Attribute, len(attrs))
+	for _, attr := range attrs {
+		if macro, ok := macros[attr.Name()]; ok && attr.IsSet() {
+			for _, a := range macro.Attributes {
+				line[a.Name()] = a
+			}
+		}
+
+		line[attr.Name()] = attr
+	}
+
+	return line
+}
+
+// Normalize returns whether the line endings of a file with this text
+// attribute are converted to LF when it is added, given whether core.autocrlf
+// enables the conversion and whether the content of the file looks binary.
+func (t Text) Normalize(autoCRLF, binary bool) bool {
+	switch t {
+	case TextSet:
+		return true
+	case TextUnset:
+		return false
+	case TextAuto:
+		return !binary
+	default:
+		return autoCRLF && !binary
+	}
+}
+
+// MatchMerge returns the merge attribute of path, given the patterns in
+// ascending order of priority, as returned by ReadPatterns, or nil if it is
+// not specified. It is unset for binary files, set for a line by line merge,
+// and its value is the name of the merge driver to use otherwise.
+func MatchMerge(stack []MatchAttribute, path []string) Attribute {
+	return matchAttribute(stack, path, "merge")
+}
+
+// MatchDiff returns the diff attribute of path, given the patterns in
+// ascending order of priority, as returned by ReadPatterns, or nil if it is
+// not specified. It is unset for binary files, set for a diff as text, and
+// its value is the name of the diff driver to use otherwise.
+func MatchDiff(stack []MatchAttribute, path []string) Attribute {
+	return matchAttribute(stack, path, "diff")
+}
+
+// MatchConflictMarkerSize returns the length of the conflict markers of path,
+// set by its conflict-marker-size attribute, given the patterns in ascending
+// order of priority, or 0 if it is not specified or invalid.
+func MatchConflictMarkerSize(stack []MatchAttribute, path []string) int {
+	attr := matchAttribute(stack, path, "conflict-marker-size")
+	if attr == nil || !attr.IsValueSet() {
+		return 0
+	}
+
+	size, err := strconv.Atoi(attr.Value())
+	if err != nil || size <= 0 {
+		return 0
+	}
+
+	return size
+}
+
+// matchAttribute returns the attribute of path with the given name, or nil
+// if it is not specified.
+func matchAttribute(stack []MatchAttribute, path []string, name string) Attribute {
+	macros := map[string]MatchAttribute{binaryMacro.Name: binaryMacro}
+	for _, ma := range stack {
+		if ma.Pattern == nil {
+			macros[ma.Name] = ma
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		ma := stack[i]
+		if ma.Pattern == nil || !ma.Pattern.Match(path) {
+			continue
+		}
+
+		attr := lineAttributes(ma.Attributes, macros)[name]
+		switch {
+		case attr == nil:
+		case attr.IsUnspecified():
+			return nil
+		default:
+			return attr
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package gitattributes
+
+import (
+	"strings"
+)
+
+func (s *MatcherSuite) TestMatchText() {
+	lines := []string{
+		"[attr]generated -text -diff",
+		"* text=auto",
+		"*.png binary",
+		"*.txt text",
+		"*.bat eol=crlf",
+		"*.gen generated",
+		"raw/** -text",
+		"raw/keep.txt !text",
+	}
+
+	stack, err := ReadAttributes(strings.NewReader(strings.Join(lines, "\n")), nil, true)
+	s.Require().NoError(err)
+
+	s.Equal(TextAuto, MatchText(stack, []string{"main.go"}))
+	s.Equal(TextUnset, MatchText(stack, []string{"img", "logo.png"}))
+	s.Equal(TextSet, MatchText(stack, []string{"README.txt"}))
+	s.Equal(TextAuto, MatchText(stack, []string{"run.bat"}))
+	s.Equal(TextUnset, MatchText(stack, []string{"foo.gen"}))
+	s.Equal(TextUnset, MatchText(stack, []string{"raw", "foo.txt"}))
+	s.Equal(TextUnspecified, MatchText(stack, []string{"raw", "keep.txt"}))
+	s.Equal(TextUnspecified, MatchText(nil, []string{"main.go"}))
+
+	stack, err = ReadAttributes(strings.NewReader("*.bat eol=crlf\n"), nil, true)
+	s.Require().NoError(err)
+	s.Equal(TextSet, MatchText(stack, []string{"run.bat"}))
+}
+
+func (s *MatcherSuite) TestTextNormalize() {
+	s.True(TextSet.Normalize(false, true))
+	s.False(TextUnset.Normalize(true, false))
+	s.True(TextAuto.Normalize(false, false))
+	s.False(TextAuto.Normalize(true, true))
+	s.True(TextUnspecified.Normalize(true, false))
+	s.False(TextUnspecified.Normalize(false, false))
+	s.False(TextUnspecified.Normalize(true, true))
+}
+
+func (s *MatcherSuite) TestMatchMerge() {
+	lines := []string{
+		"*.json merge=json",
+		"*.png binary",
+		"*.txt merge",
+		"vendor/** -merge",
+		"vendor/keep.json !merge",
+	}
+
+	stack, err := ReadAttributes(strings.NewReader(strings.Join(lines, "\n")), nil, true)
+	s.Require().NoError(err)
+
+	merge := MatchMerge(stack, []string{"a.json"})
+	s.Require().NotNil(merge)
+	s.True(merge.IsValueSet())
+	s.Equal("json", merge.Value())
+
+	s.True(MatchMerge(stack, []string{"logo.png"}).IsUnset())
+	s.True(MatchMerge(stack, []string{"a.txt"}).IsSet())
+	s.True(MatchMerge(stack, []string{"vendor", "a.json"}).IsUnset())
+	s.Nil(MatchMerge(stack, []string{"vendor", "keep.json"}))
+	s.Nil(MatchMerge(stack, []string{"main.go"}))
+}
+
+func (s *MatcherSuite) TestMatchDiff() {
+	lines := []string{
+		"*.png diff=exif",
+		"*.bin binary",
+		"icons/*.png -diff",
+	}
+
+	stack, err := ReadAttributes(strings.NewReader(strings.Join(lines, "\n")), nil, true)
+	s.Require().NoError(err)
+
+	diff := MatchDiff(stack, []string{"logo.png"})
+	s.Require().NotNil(diff)
+	s.Equal("exif", diff.Value())
+
+	s.True(MatchDiff(stack, []string{"a.bin"}).IsUnset())
+	s.True(MatchDiff(stack, []string{"icons", "a.png"}).IsUnset())
+	s.Nil(MatchDiff(stack, []string{"main.go"}))
+}
+
+func (s *MatcherSuite) TestMatchConflictMarkerSize() {
+	lines := []string{
+		"*.adoc conflict-marker-size=32",
+		"*.txt conflict-marker-size=foo",
+	}
+
+	stack, err := ReadAttributes(strings.NewReader(strings.Join(lines, "\n")), nil, true)
+	s.Require().NoError(err)
+
+	s.Equal(32, MatchConflictMarkerSize(stack, []string{"doc", "a.adoc"}))
+	s.Equal(0, MatchConflictMarkerSize(stack, []string{"a.txt"}))
+	s.Equal(0, MatchConflictMarkerSize(stack, []string{"main.go"}))
+}
@@ -0,0 +1,74 @@
+package gitattributes
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	gioutil "github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+// ReadTreePatterns reads gitattributes patterns recursively through the
+// .gitattributes files of the tree, for the operations on a commit rather
+// than on a worktree, as in bare repositories. The result is in ascending
+// order of priority (last higher), as with ReadPatterns.
+//
+// The .gitattribute file at the root of the tree will allow custom macro
+// definitions. Custom macro definitions in other directories .gitattributes
+// will return an error.
+func ReadTreePatterns(t *object.Tree) ([]MatchAttribute, error) {
+	return readTree(t, nil)
+}
+
+func readTree(t *object.Tree, path []string) (attributes []MatchAttribute, err error) {
+	attributes, err = readTreeAttributesFile(t, path)
+	if err != nil {
+		return attributes, err
+	}
+
+	for _, e := range t.Entries {
+		if e.Mode != filemode.Dir {
+			continue
+		}
+
+		sub, err := t.Tree(e.Name)
+		if err != nil {
+			return attributes, err
+		}
+
+		subAttributes, err := readTree(sub, slices.Concat(path, []string{e.Name}))
+		if err != nil {
+			return attributes, err
+		}
+
+		attributes = append(attributes, subAttributes...)
+	}
+
+	return attributes, nil
+}
+
+func readTreeAttributesFile(t *object.Tree, path []string) (attributes []MatchAttribute, err error) {
+	e, err := t.FindEntry(gitattributesFile)
+	if errors.Is(err, object.ErrEntryNotFound) || err == nil && !e.Mode.IsFile() {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := t.TreeEntryFile(e)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	defer gioutil.CheckClose(r, &err)
+
+	return ReadAttributes(r, path, len(path) == 0)
+}
@@ -0,0 +1,97 @@
+package gitattributes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestReadTreePatterns(t *testing.T) {
+	t.Parallel()
+
+	st := memory.NewStorage()
+	sub := storeTree(t, st, []object.TreeEntry{
+		{Name: ".gitattributes", Mode: filemode.Regular, Hash: storeBlob(t, st, "*.go text\n")},
+	})
+	empty := storeTree(t, st, []object.TreeEntry{
+		{Name: "README", Mode: filemode.Regular, Hash: storeBlob(t, st, "readme\n")},
+	})
+	root := storeTree(t, st, []object.TreeEntry{
+		{Name: ".gitattributes", Mode: filemode.Regular, Hash: storeBlob(t, st, "[attr]bin -diff -merge -text\n*.png bin\n")},
+		{Name: "doc", Mode: filemode.Dir, Hash: empty},
+		{Name: "src", Mode: filemode.Dir, Hash: sub},
+	})
+
+	tree, err := object.GetTree(st, root)
+	require.NoError(t, err)
+
+	attrs, err := ReadTreePatterns(tree)
+	require.NoError(t, err)
+	require.Len(t, attrs, 3)
+	assert.Equal(t, "bin", attrs[0].Name)
+	assert.Equal(t, "*.png", attrs[1].Name)
+	assert.Equal(t, "*.go", attrs[2].Name)
+
+	m := NewMatcher(attrs)
+	results, matched := m.Match([]string{"src", "main.go"}, []string{"text"})
+	assert.True(t, matched)
+	assert.True(t, results["text"].IsSet())
+
+	results, matched = m.Match([]string{"main.go"}, []string{"text"})
+	assert.False(t, matched)
+	assert.Empty(t, results)
+
+	results, matched = m.Match([]string{"doc", "logo.png"}, []string{"diff"})
+	assert.True(t, matched)
+	assert.True(t, results["diff"].IsUnset())
+}
+
+func TestReadTreePatternsMacroInSubdirectory(t *testing.T) {
+	t.Parallel()
+
+	st := memory.NewStorage()
+	sub := storeTree(t, st, []object.TreeEntry{
+		{Name: ".gitattributes", Mode: filemode.Regular, Hash: storeBlob(t, st, "[attr]bin -diff\n")},
+	})
+	root := storeTree(t, st, []object.TreeEntry{
+		{Name: "src", Mode: filemode.Dir, Hash: sub},
+	})
+
+	tree, err := object.GetTree(st, root)
+	require.NoError(t, err)
+
+	_, err = ReadTreePatterns(tree)
+	assert.ErrorIs(t, err, ErrMacroNotAllowed)
+}
+
+func storeBlob(t *testing.T, s storer.EncodedObjectStorer, content string) plumbing.Hash {
+	obj := s.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	h, err := s.SetEncodedObject(obj)
+	require.NoError(t, err)
+
+	return h
+}
+
+func storeTree(t *testing.T, s storer.EncodedObjectStorer, entries []object.TreeEntry) plumbing.Hash {
+	obj := s.NewEncodedObject()
+	require.NoError(t, (&object.Tree{Entries: entries}).Encode(obj))
+
+	h, err := s.SetEncodedObject(obj)
+	require.NoError(t, err)
+
+	return h
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	gofs "io/fs"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/go-git/go-billy/v6"
@@ -84,6 +85,29 @@ func ReadPatterns(fs billy.Filesystem, path []string) (ps []Pattern, err error)
 	return ps, err
 }
 
+// ReadPathPatterns reads the .git/info/exclude patterns and then the ones of
+// the gitignore files of the directories from the root down to path, the
+// only ones matching the entries of the directory path. Unlike ReadPatterns,
+// the rest of the directory structure is not traversed. The result is in the
+// ascending order of priority (last higher).
+func ReadPathPatterns(fs billy.Filesystem, path []string) (ps []Pattern, err error) {
+	ps, _ = readIgnoreFile(fs, nil, infoExcludeFile)
+
+	path = slices.Clone(path)
+	for i := 0; i <= len(path); i++ {
+		// The capacity is limited, so that appending to the domain of the
+		// patterns doesn't overwrite the rest of path.
+		subps, err := readIgnoreFile(fs, path[:i:i], gitignoreFile)
+		if err != nil && !os.IsNotExist(err) {
+			return ps, err
+		}
+
+		ps = append(ps, subps...)
+	}
+
+	return ps, nil
+}
+
 func loadPatterns(fs billy.Filesystem, path string) (ps []Pattern, err error) {
 	f, err := fs.Open(path)
 	if err != nil {
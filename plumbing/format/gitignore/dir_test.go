@@ -307,6 +307,22 @@ func (s *MatcherSuite) TestDir_ReadPatterns() {
 	checkPatterns(ps)
 }
 
+func (s *MatcherSuite) TestDir_ReadPathPatterns() {
+	ps, err := ReadPathPatterns(s.GFS, []string{"multiple", "sub", "ignores", "first"})
+	s.NoError(err)
+	s.Len(ps, 5)
+
+	m := NewMatcher(ps)
+	s.True(m.Match([]string{"exclude.crlf"}, true))
+	s.True(m.Match([]string{"multiple", "sub", "ignores", "first", "ignore_dir"}, true))
+	// The patterns of vendor/.gitignore are not read.
+	s.True(m.Match([]string{"vendor", "github.com"}, true))
+
+	ps, err = ReadPathPatterns(s.GFS, nil)
+	s.NoError(err)
+	s.Len(ps, 4)
+}
+
 func (s *MatcherSuite) TestDir_ReadRelativeGlobalGitIgnore() {
 	for _, fs := range []billy.Filesystem{s.RFSR, s.RFSU} {
 		ps, err := LoadGlobalPatterns(fs)
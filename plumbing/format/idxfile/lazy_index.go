@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"sort"
 
 	"github.com/go-git/go-git/v6/plumbing"
 	gsync "github.com/go-git/go-git/v6/utils/sync"
@@ -266,7 +265,8 @@ func (s *LazyIndex) Entries() (EntryIter, error) {
 }
 
 // EntriesByOffset returns an iterator over all index entries sorted by
-// their packfile offset.
+// packfile offset. The entries are read in the order of the .rev reverse
+// index, so that they don't need to be sorted.
 func (s *LazyIndex) EntriesByOffset() (EntryIter, error) {
 	idx, err := s.idx.acquire()
 	if err != nil {
@@ -274,18 +274,45 @@ func (s *LazyIndex) EntriesByOffset() (EntryIter, error) {
 	}
 	defer s.idx.release()
 
-	count := s.count
-	entries := make(entriesByOffset, count)
+	rev, err := s.rev.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer s.rev.release()
 
-	for i := range count {
-		e, err := s.entryAt(idx, i)
-		if err != nil {
-			return nil, err
+	bufp := gsync.GetByteSlice()
+	defer gsync.PutByteSlice(bufp)
+
+	buf := *bufp
+	// Round down to a multiple of 4 so we always read whole entries.
+	buf = buf[:len(buf)&^3]
+
+	entries := make(entriesByOffset, 0, s.count)
+	pos := int64(revHeaderSize)
+	for remaining := s.count; remaining > 0; {
+		chunk := min(remaining*4, len(buf))
+		if _, err := rev.ReadAt(buf[:chunk], pos); err != nil {
+			return nil, fmt.Errorf("read rev entries: %w", err)
 		}
-		entries[i] = e
+
+		for i := 0; i < chunk; i += 4 {
+			idxPos := int(binary.BigEndian.Uint32(buf[i:]))
+			if idxPos >= s.count {
+				return nil, fmt.Errorf("%w: rev entry out of range", ErrMalformedIdxFile)
+			}
+
+			e, err := s.entryAt(idx, idxPos)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, e)
+		}
+
+		pos += int64(chunk)
+		remaining -= chunk / 4
 	}
 
-	sort.Sort(entries)
 	return &idxfileEntryOffsetIter{entries: entries}, nil
 }
 
@@ -157,6 +157,22 @@ func (s *LazyIndexSuite) TestEntriesByOffset() {
 	}
 }
 
+func (s *LazyIndexSuite) TestEntriesByOffsetMalformedRev() {
+	idx, err := fixtureLazyIndex(true)
+	s.Require().NoError(err)
+	defer idx.Close()
+
+	rev := make([]byte, revHeaderSize+idx.count*4)
+	copy(rev, "RIDX\x00\x00\x00\x01\x00\x00\x00\x01")
+	binary.BigEndian.PutUint32(rev[revHeaderSize:], uint32(idx.count))
+	idx.rev = newSharedFile(func() (ReadAtCloser, error) {
+		return nopCloserReaderAt{bytes.NewReader(rev)}, nil
+	})
+
+	_, err = idx.EntriesByOffset()
+	s.ErrorIs(err, ErrMalformedIdxFile)
+}
+
 func (s *LazyIndexSuite) TestCloseIdempotent() {
 	idx, err := fixtureLazyIndex(true)
 	s.Require().NoError(err)
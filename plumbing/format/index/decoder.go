@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
 	"time"
@@ -20,9 +21,13 @@ var (
 	// ErrMalformedSignature is returned by Decode when the index header file is
 	// malformed.
 	ErrMalformedSignature = errors.New("index decoder: malformed index signature file")
+	// ErrIndexCorrupt is returned by Decode when the index is corrupt, its
+	// checksum not matching its content or one of its extensions being
+	// malformed.
+	ErrIndexCorrupt = errors.New("index decoder: corrupt index")
 	// ErrInvalidChecksum is returned by Decode if the SHA1/SHA256 hash mismatch with
-	// the read content.
-	ErrInvalidChecksum = errors.New("index decoder: invalid checksum")
+	// the read content. It wraps ErrIndexCorrupt.
+	ErrInvalidChecksum = fmt.Errorf("%w: invalid checksum", ErrIndexCorrupt)
 	// ErrUnknownExtension is returned when an index extension is encountered that is considered mandatory.
 	ErrUnknownExtension = errors.New("index decoder: unknown extension")
 )
@@ -134,6 +139,7 @@ func (d *Decoder) readEntry(idx *Index) (*Entry, error) {
 	}
 
 	e.Stage = Stage(flags>>12) & 0x3
+	e.AssumeUnchanged = flags&entryValid != 0
 
 	if flags&entryExtended != 0 {
 		extended, err := binary.ReadUint16(d.r)
@@ -253,11 +259,42 @@ func (d *Decoder) readExtension(idx *Index) error {
 		return err
 	}
 
-	r, err := d.getExtensionReader()
+	extLen, err := binary.ReadUint32(d.r)
+	if err != nil {
+		return err
+	}
+
+	lr := &io.LimitedReader{R: d.r, N: int64(extLen)}
+	d.extReader.Reset(lr)
+
+	err = d.decodeExtension(idx, header, d.extReader)
+	if errors.Is(err, ErrUnknownExtension) {
+		return err
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s extension: %w", ErrIndexCorrupt, header[:], err)
+	}
+
+	// The extension must be fully read by its decoder, and must hold as many
+	// bytes as its size says.
+	n, err := io.Copy(io.Discard, d.extReader)
 	if err != nil {
 		return err
 	}
 
+	if n > 0 {
+		return fmt.Errorf("%w: %s extension: %d bytes left unread", ErrIndexCorrupt, header[:], n)
+	}
+
+	if lr.N > 0 {
+		return fmt.Errorf("%w: %s extension: %w", ErrIndexCorrupt, header[:], io.ErrUnexpectedEOF)
+	}
+
+	return nil
+}
+
+func (d *Decoder) decodeExtension(idx *Index, header [4]byte, r *bufio.Reader) error {
 	switch {
 	case bytes.Equal(header[:], treeExtSignature):
 		idx.Cache = &Tree{}
@@ -281,7 +318,7 @@ func (d *Decoder) readExtension(idx *Index) error {
 		// See https://git-scm.com/docs/index-format, which says:
 		// If the first byte is 'A'..'Z' the extension is optional and can be ignored.
 		if header[0] < 'A' || header[0] > 'Z' {
-			return ErrUnknownExtension
+			return fmt.Errorf("%w: %s", ErrUnknownExtension, header[:])
 		}
 
 		d := &unknownExtensionDecoder{r}
@@ -293,22 +330,22 @@ func (d *Decoder) readExtension(idx *Index) error {
 	return nil
 }
 
-func (d *Decoder) getExtensionReader() (*bufio.Reader, error) {
-	extLen, err := binary.ReadUint32(d.r)
-	if err != nil {
-		return nil, err
-	}
-
-	d.extReader.Reset(&io.LimitedReader{R: d.r, N: int64(extLen)})
-	return d.extReader, nil
-}
-
 func (d *Decoder) readChecksum(expected []byte) error {
 	var h plumbing.Hash
 	h.ResetBySize(d.hash.Size())
 
 	if _, err := h.ReadFrom(d.r); err != nil {
-		return err
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return fmt.Errorf("%w: checksum: %w", ErrIndexCorrupt, err)
+	}
+
+	// A null checksum is written by git when index.skipHash is set, to skip
+	// computing it.
+	if h.IsZero() {
+		return nil
 	}
 
 	if h.Compare(expected) != 0 {
@@ -347,10 +384,14 @@ type treeExtensionDecoder struct {
 
 func (d *treeExtensionDecoder) Decode(t *Tree) error {
 	for {
+		if _, err := d.r.Peek(1); err == io.EOF {
+			return nil
+		}
+
 		e, err := d.readEntry()
 		if err != nil {
 			if err == io.EOF {
-				return nil
+				return io.ErrUnexpectedEOF
 			}
 
 			return err
@@ -384,12 +425,6 @@ func (d *treeExtensionDecoder) readEntry() (*TreeEntry, error) {
 		return nil, err
 	}
 
-	// An entry can be in an invalidated state and is represented by having a
-	// negative number in the entry_count field.
-	if i == -1 {
-		return nil, nil
-	}
-
 	e.Entries = i
 	trees, err := binary.ReadUntil(d.r, '\n')
 	if err != nil {
@@ -402,6 +437,14 @@ func (d *treeExtensionDecoder) readEntry() (*TreeEntry, error) {
 	}
 
 	e.Trees = i
+
+	// An entry can be in an invalidated state and is represented by having a
+	// negative number in the entry_count field, and no object name.
+	if e.Entries < 0 {
+		e.Entries = -1
+		return e, nil
+	}
+
 	e.Hash.ResetBySize(d.h.Size())
 	_, err = e.Hash.ReadFrom(d.r)
 	if err != nil {
@@ -417,10 +460,14 @@ type resolveUndoDecoder struct {
 
 func (d *resolveUndoDecoder) Decode(ru *ResolveUndo) error {
 	for {
+		if _, err := d.r.Peek(1); err == io.EOF {
+			return nil
+		}
+
 		e, err := d.readEntry()
 		if err != nil {
 			if err == io.EOF {
-				return nil
+				return io.ErrUnexpectedEOF
 			}
 
 			return err
@@ -419,3 +419,93 @@ func (s *IndexSuite) TestDecodeInvalidHash() {
 	err = d.Decode(idx)
 	s.ErrorContains(err, ErrInvalidChecksum.Error())
 }
+
+// encodedBasicIndex returns the index of the basic fixture, encoded with SHA1.
+func encodedBasicIndex(t *testing.T) []byte {
+	t.Helper()
+
+	f, err := fixtures.Basic().One().DotGit().Open("index")
+	require.NoError(t, err)
+	defer f.Close()
+
+	idx := &Index{}
+	require.NoError(t, NewDecoder(f, crypto.SHA1.New()).Decode(idx))
+
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf, crypto.SHA1.New()).Encode(idx))
+	return buf.Bytes()
+}
+
+func TestDecodeCorruptEntries(t *testing.T) {
+	t.Parallel()
+
+	b := encodedBasicIndex(t)
+	// Flip a byte of the name of the first entry.
+	b[12+entryHeaderLength+crypto.SHA1.Size()] ^= 0xff
+
+	err := NewDecoder(bytes.NewReader(b), crypto.SHA1.New()).Decode(&Index{})
+	assert.ErrorIs(t, err, ErrIndexCorrupt)
+	assert.ErrorIs(t, err, ErrInvalidChecksum)
+}
+
+func TestDecodeNullChecksum(t *testing.T) {
+	t.Parallel()
+
+	b := encodedBasicIndex(t)
+	copy(b[len(b)-crypto.SHA1.Size():], make([]byte, crypto.SHA1.Size()))
+
+	idx := &Index{}
+	require.NoError(t, NewDecoder(bytes.NewReader(b), crypto.SHA1.New()).Decode(idx))
+	assert.Len(t, idx.Entries, 9)
+}
+
+func TestDecodeMissingChecksum(t *testing.T) {
+	t.Parallel()
+
+	b := encodedBasicIndex(t)
+	err := NewDecoder(bytes.NewReader(b[:len(b)-1]), crypto.SHA1.New()).Decode(&Index{})
+	assert.ErrorIs(t, err, ErrIndexCorrupt)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestDecodeMalformedExt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		signature string
+		data      string
+	}{
+		{"truncated TREE entry", "TREE", "\x002 1\n"},
+		{"truncated REUC entry", "REUC", "foo\x00100644\x00"},
+		{"EOIE too long", "EOIE", "\x00\x00\x00\x01" + string(make([]byte, crypto.SHA1.Size())) + "garbage"},
+		{"size past the end", "TEST", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := encodedBasicIndex(t)
+			b = b[:len(b)-crypto.SHA1.Size()]
+
+			var buf bytes.Buffer
+			e := NewEncoder(&buf, crypto.SHA1.New())
+			_, err := e.w.Write(b)
+			require.NoError(t, err)
+
+			if tc.data == "" {
+				_, err = e.w.Write([]byte(tc.signature))
+				require.NoError(t, err)
+				require.NoError(t, binary.WriteUint32(e.w, 100))
+			} else {
+				require.NoError(t, e.encodeRawExtension(tc.signature, []byte(tc.data)))
+			}
+			require.NoError(t, e.encodeFooter())
+
+			err = NewDecoder(&buf, crypto.SHA1.New()).Decode(&Index{})
+			assert.ErrorIs(t, err, ErrIndexCorrupt)
+			assert.ErrorContains(t, err, tc.signature+" extension")
+		})
+	}
+}
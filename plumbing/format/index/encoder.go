@@ -43,7 +43,7 @@ func (e *Encoder) Encode(idx *Index) error {
 }
 
 func (e *Encoder) encode(idx *Index, footer bool) error {
-	// TODO: support extensions
+	// TODO: support the extensions other than TREE
 	if idx.Version > EncodeVersionSupported {
 		return ErrUnsupportedVersion
 	}
@@ -56,6 +56,10 @@ func (e *Encoder) encode(idx *Index, footer bool) error {
 		return err
 	}
 
+	if err := e.encodeTreeExtension(idx.Cache); err != nil {
+		return err
+	}
+
 	if footer {
 		return e.encodeFooter()
 	}
@@ -103,6 +107,10 @@ func (e *Encoder) encodeEntry(idx *Index, entry *Entry) error {
 	}
 
 	flags := uint16(entry.Stage&0x3) << 12
+	if entry.AssumeUnchanged {
+		flags |= entryValid
+	}
+
 	if l := len(entry.Name); l < nameMask {
 		flags |= uint16(l)
 	} else {
@@ -181,6 +189,24 @@ func (e *Encoder) encodeEntryNameV4(entry *Entry) error {
 	return binary.Write(e.w, []byte(name+string('\x00')))
 }
 
+// encodeTreeExtension writes the cached trees as the TREE extension, unless
+// there are none.
+func (e *Encoder) encodeTreeExtension(t *Tree) error {
+	if t == nil || len(t.Entries) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, te := range t.Entries {
+		fmt.Fprintf(&buf, "%s\x00%d %d\n", te.Path, te.Entries, te.Trees)
+		if te.Entries >= 0 {
+			buf.Write(te.Hash.Bytes())
+		}
+	}
+
+	return e.encodeRawExtension(string(treeExtSignature), buf.Bytes())
+}
+
 func (e *Encoder) encodeRawExtension(signature string, data []byte) error {
 	if len(signature) != 4 {
 		return fmt.Errorf("invalid signature length")
@@ -233,6 +259,12 @@ func (e *Encoder) encodeFooter() error {
 
 type byName []*Entry
 
-func (l byName) Len() int           { return len(l) }
-func (l byName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
-func (l byName) Less(i, j int) bool { return l[i].Name < l[j].Name }
+func (l byName) Len() int      { return len(l) }
+func (l byName) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l byName) Less(i, j int) bool {
+	if l[i].Name != l[j].Name {
+		return l[i].Name < l[j].Name
+	}
+
+	return l[i].Stage < l[j].Stage
+}
@@ -58,6 +58,84 @@ func TestEncode(t *testing.T) {
 	assert.Equal(t, "foo", output.Entries[2].Name)
 }
 
+func TestEncodeConflicts(t *testing.T) {
+	t.Parallel()
+	idx := &Index{
+		Version: 2,
+		Entries: []*Entry{
+			{Name: "foo", Stage: TheirMode, Hash: plumbing.NewHash("e25b29c8946e0e192fae2edc1dabf7be71e8ecf3")},
+			{Name: "foo", Stage: AncestorMode, Hash: plumbing.NewHash("880cd14280f4b9b6ed3986d6671f907d7cc2a198")},
+			{Name: "bar", Stage: Merged},
+			{Name: "foo", Stage: OurMode, Hash: plumbing.NewHash("d499a1a0b79b7d87a35155afd0c1cce78b37a91c")},
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	e := NewEncoder(buf, crypto.SHA1.New())
+	require.NoError(t, e.Encode(idx))
+
+	output := &Index{}
+	d := NewDecoder(buf, crypto.SHA1.New())
+	require.NoError(t, d.Decode(output))
+
+	require.Len(t, output.Entries, 4)
+	expected := []struct {
+		name  string
+		stage Stage
+	}{
+		{"bar", Merged},
+		{"foo", AncestorMode},
+		{"foo", OurMode},
+		{"foo", TheirMode},
+	}
+
+	for i, exp := range expected {
+		assert.Equal(t, exp.name, output.Entries[i].Name)
+		assert.Equal(t, exp.stage, output.Entries[i].Stage)
+	}
+
+	conflicts := output.Conflicts()
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "880cd14280f4b9b6ed3986d6671f907d7cc2a198", conflicts[0].Ancestor.Hash.String())
+	assert.Equal(t, "d499a1a0b79b7d87a35155afd0c1cce78b37a91c", conflicts[0].Ours.Hash.String())
+	assert.Equal(t, "e25b29c8946e0e192fae2edc1dabf7be71e8ecf3", conflicts[0].Theirs.Hash.String())
+}
+
+func TestEncodeTreeExtension(t *testing.T) {
+	t.Parallel()
+	idx := &Index{
+		Version: 2,
+		Entries: []*Entry{
+			{Name: "a/b/foo", Hash: plumbing.NewHash("e25b29c8946e0e192fae2edc1dabf7be71e8ecf3")},
+			{Name: "a/bar", Hash: plumbing.NewHash("880cd14280f4b9b6ed3986d6671f907d7cc2a198")},
+			{Name: "baz", Hash: plumbing.NewHash("d499a1a0b79b7d87a35155afd0c1cce78b37a91c")},
+		},
+		Cache: &Tree{Entries: []TreeEntry{
+			{Path: "", Entries: -1, Trees: 1},
+			{Path: "a", Entries: -1, Trees: 1},
+			{Path: "b", Entries: 1, Trees: 0, Hash: plumbing.NewHash("a39771a7651f97faf5c72e08224d857fc35133db")},
+		}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	e := NewEncoder(buf, crypto.SHA1.New())
+	require.NoError(t, e.Encode(idx))
+
+	output := &Index{}
+	d := NewDecoder(buf, crypto.SHA1.New())
+	require.NoError(t, d.Decode(output))
+
+	require.NotNil(t, output.Cache)
+	require.Len(t, output.Cache.Entries, 3)
+	for i, te := range idx.Cache.Entries {
+		assert.Equal(t, te.Path, output.Cache.Entries[i].Path)
+		assert.Equal(t, te.Entries, output.Cache.Entries[i].Entries)
+		assert.Equal(t, te.Trees, output.Cache.Entries[i].Trees)
+	}
+
+	assert.Equal(t, "a39771a7651f97faf5c72e08224d857fc35133db", output.Cache.Entries[2].Hash.String())
+}
+
 func TestEncodeV4(t *testing.T) {
 	t.Parallel()
 	idx := &Index{
@@ -166,3 +244,25 @@ func TestEncodeWithSkipWorktreeUnsupportedVersion(t *testing.T) {
 	assert.EqualExportedValues(t, idx, output)
 	assert.Equal(t, true, output.Entries[0].SkipWorktree)
 }
+
+func TestEncodeWithAssumeUnchanged(t *testing.T) {
+	t.Parallel()
+	idx := &Index{
+		Version: 2,
+		Entries: []*Entry{{Name: "foo", AssumeUnchanged: true}, {Name: "bar"}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	e := NewEncoder(buf, crypto.SHA1.New())
+	err := e.Encode(idx)
+	assert.NoError(t, err)
+
+	output := &Index{}
+	d := NewDecoder(buf, crypto.SHA1.New())
+	err = d.Decode(output)
+	assert.NoError(t, err)
+
+	assert.EqualExportedValues(t, idx, output)
+	assert.False(t, output.Entries[0].AssumeUnchanged)
+	assert.True(t, output.Entries[1].AssumeUnchanged)
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -30,7 +32,7 @@ type Stage int
 
 const (
 	// Merged is the default stage, fully merged
-	Merged Stage = 1
+	Merged Stage = 0
 	// AncestorMode is the base revision
 	AncestorMode Stage = 1
 	// OurMode is the first tree revision, ours
@@ -59,13 +61,15 @@ type Index struct {
 }
 
 // Add creates a new Entry and returns it. The caller should first check that
-// another entry with the same path does not exist.
+// another entry with the same path does not exist. The cached trees of its
+// directories are invalidated.
 func (i *Index) Add(path string) *Entry {
 	e := &Entry{
 		Name: filepath.ToSlash(path),
 	}
 
 	i.Entries = append(i.Entries, e)
+	i.InvalidateCache(e.Name)
 	return e
 }
 
@@ -82,11 +86,13 @@ func (i *Index) Entry(path string) (*Entry, error) {
 }
 
 // Remove remove the entry that match the give path and returns deleted entry.
+// The cached trees of its directories are invalidated.
 func (i *Index) Remove(path string) (*Entry, error) {
 	path = filepath.ToSlash(path)
 	for index, e := range i.Entries {
 		if e.Name == path {
 			i.Entries = append(i.Entries[:index], i.Entries[index+1:]...)
+			i.InvalidateCache(path)
 			return e, nil
 		}
 	}
@@ -94,6 +100,88 @@ func (i *Index) Remove(path string) (*Entry, error) {
 	return nil, ErrEntryNotFound
 }
 
+// InvalidateCache invalidates the cached trees of the directories containing
+// path, as git does when an entry is added, changed or removed, so that they
+// are built again. Code changing the Entries directly must call it.
+func (i *Index) InvalidateCache(path string) {
+	if i.Cache == nil || len(i.Cache.Entries) == 0 {
+		return
+	}
+
+	entries := i.Cache.Entries
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	pos := 0
+	for _, part := range parts {
+		entries[pos].Entries = -1
+
+		child, found := pos+1, false
+		for range entries[pos].Trees {
+			if child >= len(entries) {
+				break
+			}
+
+			if entries[child].Path == part {
+				found = true
+				break
+			}
+
+			child = i.Cache.subtreeEnd(child)
+		}
+
+		if !found {
+			return
+		}
+
+		pos = child
+	}
+}
+
+// Conflict is a path of the index which is unmerged, with its entries in the
+// stages of the merge, nil for the sides missing the path.
+type Conflict struct {
+	// Name is the path of the conflict.
+	Name string
+	// Ancestor is the entry of the base revision.
+	Ancestor *Entry
+	// Ours is the entry of our revision.
+	Ours *Entry
+	// Theirs is the entry of their revision.
+	Theirs *Entry
+}
+
+// Conflicts returns the unmerged paths of the index, sorted by name.
+func (i *Index) Conflicts() []*Conflict {
+	byPath := make(map[string]*Conflict)
+	var conflicts []*Conflict
+	for _, e := range i.Entries {
+		if e.Stage == Merged {
+			continue
+		}
+
+		c, ok := byPath[e.Name]
+		if !ok {
+			c = &Conflict{Name: e.Name}
+			byPath[e.Name] = c
+			conflicts = append(conflicts, c)
+		}
+
+		switch e.Stage {
+		case AncestorMode:
+			c.Ancestor = e
+		case OurMode:
+			c.Ours = e
+		case TheirMode:
+			c.Theirs = e
+		}
+	}
+
+	sort.Slice(conflicts, func(a, b int) bool {
+		return conflicts[a].Name < conflicts[b].Name
+	})
+
+	return conflicts
+}
+
 // Glob returns the all entries matching pattern or nil if there is no matching
 // entry. The syntax of patterns is the same as in filepath.Glob.
 func (i *Index) Glob(pattern string) (matches []*Entry, err error) {
@@ -151,6 +239,9 @@ type Entry struct {
 	// IntentToAdd record only the fact that the path will be added later
 	// https://git-scm.com/docs/git-add ("git add -N")
 	IntentToAdd bool
+	// AssumeUnchanged makes the file be assumed unchanged in the worktree
+	// https://git-scm.com/docs/git-update-index ("--assume-unchanged")
+	AssumeUnchanged bool
 }
 
 func (e Entry) String() string {
@@ -172,12 +263,59 @@ type Tree struct {
 	Entries []TreeEntry
 }
 
+// ValidEntries returns the entries of the cached trees which are valid, by
+// path of their directory, "" being the root one.
+func (t *Tree) ValidEntries() map[string]TreeEntry {
+	valid := make(map[string]TreeEntry)
+	if len(t.Entries) > 0 {
+		t.collectValid(0, "", valid)
+	}
+
+	return valid
+}
+
+// collectValid adds the valid entries of the subtree at pos, whose parent
+// directory is dir, to valid, returning the position following the subtree.
+func (t *Tree) collectValid(pos int, dir string, valid map[string]TreeEntry) int {
+	e := t.Entries[pos]
+	name := path.Join(dir, e.Path)
+	if e.Entries >= 0 {
+		valid[name] = e
+	}
+
+	next := pos + 1
+	for range e.Trees {
+		if next >= len(t.Entries) {
+			break
+		}
+
+		next = t.collectValid(next, name, valid)
+	}
+
+	return next
+}
+
+// subtreeEnd returns the position following the subtree at pos.
+func (t *Tree) subtreeEnd(pos int) int {
+	next := pos + 1
+	for range t.Entries[pos].Trees {
+		if next >= len(t.Entries) {
+			break
+		}
+
+		next = t.subtreeEnd(next)
+	}
+
+	return next
+}
+
 // TreeEntry entry of a cached Tree
 type TreeEntry struct {
 	// Path component (relative to its parent directory)
 	Path string
 	// Entries is the number of entries in the index that is covered by the tree
-	// this entry represents.
+	// this entry represents, -1 if it is invalid, in which case it has no
+	// Hash.
 	Entries int
 	// Trees is the number that represents the number of subtrees this tree has
 	Trees int
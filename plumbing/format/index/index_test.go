@@ -55,6 +55,65 @@ func (s *IndexSuite) TestIndexRemove() {
 	s.ErrorIs(err, ErrEntryNotFound)
 }
 
+func (s *IndexSuite) TestIndexConflicts() {
+	idx := &Index{
+		Entries: []*Entry{
+			{Name: "foo", Stage: TheirMode},
+			{Name: "bar"},
+			{Name: "foo", Stage: AncestorMode},
+			{Name: "baz", Stage: OurMode},
+			{Name: "foo", Stage: OurMode},
+		},
+	}
+
+	conflicts := idx.Conflicts()
+	s.Require().Len(conflicts, 2)
+
+	s.Equal("baz", conflicts[0].Name)
+	s.Nil(conflicts[0].Ancestor)
+	s.Same(idx.Entries[3], conflicts[0].Ours)
+	s.Nil(conflicts[0].Theirs)
+
+	s.Equal("foo", conflicts[1].Name)
+	s.Same(idx.Entries[2], conflicts[1].Ancestor)
+	s.Same(idx.Entries[4], conflicts[1].Ours)
+	s.Same(idx.Entries[0], conflicts[1].Theirs)
+}
+
+func (s *IndexSuite) TestIndexInvalidateCache() {
+	idx := &Index{
+		Entries: []*Entry{
+			{Name: "a/b/foo"},
+			{Name: "a/bar"},
+			{Name: "c/qux"},
+			{Name: "baz"},
+		},
+		Cache: &Tree{Entries: []TreeEntry{
+			{Path: "", Entries: 4, Trees: 2},
+			{Path: "a", Entries: 2, Trees: 1},
+			{Path: "b", Entries: 1, Trees: 0},
+			{Path: "c", Entries: 1, Trees: 0},
+		}},
+	}
+
+	s.Len(idx.Cache.ValidEntries(), 4)
+
+	idx.InvalidateCache("a/bar")
+	valid := idx.Cache.ValidEntries()
+	s.Len(valid, 2)
+	s.Contains(valid, "a/b")
+	s.Contains(valid, "c")
+
+	_, err := idx.Remove("c/qux")
+	s.NoError(err)
+	valid = idx.Cache.ValidEntries()
+	s.Len(valid, 1)
+	s.Equal(1, valid["a/b"].Entries)
+
+	idx.Add("a/b/new")
+	s.Empty(idx.Cache.ValidEntries())
+}
+
 func (s *IndexSuite) TestIndexGlob() {
 	idx := &Index{
 		Entries: []*Entry{
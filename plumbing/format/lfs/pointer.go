@@ -0,0 +1,99 @@
+// Package lfs implements the decoding and encoding of the Git LFS pointer
+// files, stored in place of the large files tracked by Git LFS.
+//
+// See https://github.com/git-lfs/git-lfs/blob/main/docs/spec.md
+package lfs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Version is the URL identifying the version of the pointer format.
+	Version = "https://git-lfs.github.com/spec/v1"
+	// MaxPointerSize is the maximum size of a pointer file.
+	MaxPointerSize = 1024
+
+	// legacyVersion is the version written by the pre-release clients.
+	legacyVersion = "https://hawser.github.com/spec/v1"
+	oidPrefix     = "sha256:"
+)
+
+// ErrNotPointer is returned by Decode when the content is not a valid
+// pointer file.
+var ErrNotPointer = errors.New("not a git lfs pointer")
+
+// Pointer is a Git LFS pointer file, identifying the content of a large file.
+type Pointer struct {
+	// Oid is the hexadecimal SHA-256 hash of the content.
+	Oid string
+	// Size is the size of the content, in bytes.
+	Size int64
+}
+
+// Decode reads a pointer file from r, returning ErrNotPointer if r holds
+// anything else. At most MaxPointerSize+1 bytes are read.
+func Decode(r io.Reader) (*Pointer, error) {
+	b, err := io.ReadAll(io.LimitReader(r, MaxPointerSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > MaxPointerSize || !bytes.HasSuffix(b, []byte("\n")) {
+		return nil, ErrNotPointer
+	}
+
+	var p Pointer
+	var hasOid, hasSize bool
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for i := 0; s.Scan(); i++ {
+		key, value, ok := strings.Cut(s.Text(), " ")
+		if !ok {
+			return nil, ErrNotPointer
+		}
+
+		switch {
+		case i == 0:
+			if key != "version" || (value != Version && value != legacyVersion) {
+				return nil, ErrNotPointer
+			}
+		case key == "oid":
+			oid, ok := strings.CutPrefix(value, oidPrefix)
+			if !ok || len(oid) != 64 {
+				return nil, ErrNotPointer
+			}
+
+			if _, err := hex.DecodeString(oid); err != nil {
+				return nil, ErrNotPointer
+			}
+
+			p.Oid, hasOid = oid, true
+		case key == "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return nil, ErrNotPointer
+			}
+
+			p.Size, hasSize = size, true
+		}
+	}
+
+	if !hasOid || !hasSize {
+		return nil, ErrNotPointer
+	}
+
+	return &p, nil
+}
+
+// Encode writes the pointer file to w.
+func (p *Pointer) Encode(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "version %s\noid %s%s\nsize %d\n", Version, oidPrefix, p.Oid, p.Size)
+	return err
+}
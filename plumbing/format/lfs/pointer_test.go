@@ -0,0 +1,51 @@
+package lfs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const oid = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	p, err := Decode(strings.NewReader("version https://git-lfs.github.com/spec/v1\n" +
+		"ext-0-foo sha256:" + oid + "\n" +
+		"oid sha256:" + oid + "\n" +
+		"size 12345\n"))
+	require.NoError(t, err)
+	assert.Equal(t, &Pointer{Oid: oid, Size: 12345}, p)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.Encode(&buf))
+	assert.Equal(t, "version https://git-lfs.github.com/spec/v1\noid sha256:"+oid+"\nsize 12345\n", buf.String())
+
+	p, err = Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, &Pointer{Oid: oid, Size: 12345}, p)
+}
+
+func TestDecodeNotPointer(t *testing.T) {
+	t.Parallel()
+
+	for _, content := range []string{
+		"",
+		"foo\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 12345",
+		"version https://git-lfs.github.com/spec/v2\noid sha256:" + oid + "\nsize 12345\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:foo\nsize 12345\n",
+		"version https://git-lfs.github.com/spec/v1\noid md5:" + oid + "\nsize 12345\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize -1\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 1\n" +
+			strings.Repeat("x", MaxPointerSize) + "\n",
+	} {
+		_, err := Decode(strings.NewReader(content))
+		assert.ErrorIs(t, err, ErrNotPointer, content)
+	}
+}
@@ -0,0 +1,189 @@
+// Package mailmap implements parsing of .mailmap files and the mapping of
+// author and committer identities to their canonical form.
+//
+// Each non-comment line of a mailmap file takes one of the following forms:
+//
+//	Proper Name <commit@email.xx>
+//	<proper@email.xx> <commit@email.xx>
+//	Proper Name <proper@email.xx> <commit@email.xx>
+//	Proper Name <proper@email.xx> Commit Name <commit@email.xx>
+//
+// The first three forms apply to any identity using the commit email, while
+// the last one only applies when both the commit name and email match. Emails
+// and names are matched case-insensitively.
+//
+// See https://git-scm.com/docs/gitmailmap.
+package mailmap
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+const commentPrefix = "#"
+
+// Mailmap maps commit identities to their canonical name and email.
+type Mailmap struct {
+	emails map[string]*emailEntry
+}
+
+type identity struct {
+	name  string
+	email string
+}
+
+// emailEntry holds the mappings for a single commit email. The embedded
+// identity applies to any name, while names holds the mappings restricted to
+// a given commit name.
+type emailEntry struct {
+	identity
+	names map[string]*identity
+}
+
+// New returns an empty Mailmap.
+func New() *Mailmap {
+	return &Mailmap{emails: make(map[string]*emailEntry)}
+}
+
+// Parse reads a mailmap file from r.
+func Parse(r io.Reader) (*Mailmap, error) {
+	m := New()
+	if err := m.Read(r); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Read reads the mailmap file from r and adds its entries to m. Entries read
+// later take precedence over existing ones for the same identity.
+func (m *Mailmap) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m.readLine(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+func (m *Mailmap) readLine(line string) {
+	if strings.HasPrefix(line, commentPrefix) {
+		return
+	}
+
+	name1, email1, rest, ok := parseNameAndEmail(line, false)
+	if !ok {
+		return
+	}
+
+	name2, email2, _, ok := parseNameAndEmail(rest, true)
+	if !ok {
+		name2, email2 = "", ""
+	}
+
+	m.add(name1, email1, name2, email2, ok)
+}
+
+// add records the mapping of the commit identity (oldName, oldEmail) to
+// (newName, newEmail). When the line holds a single email, it is the commit
+// email and only the name is mapped.
+func (m *Mailmap) add(newName, newEmail, oldName, oldEmail string, hasOld bool) {
+	if !hasOld {
+		oldEmail = newEmail
+		newEmail = ""
+	}
+
+	key := strings.ToLower(oldEmail)
+	e, ok := m.emails[key]
+	if !ok {
+		e = &emailEntry{}
+		m.emails[key] = e
+	}
+
+	target := &e.identity
+	if oldName != "" {
+		if e.names == nil {
+			e.names = make(map[string]*identity)
+		}
+
+		nameKey := strings.ToLower(oldName)
+		id, ok := e.names[nameKey]
+		if !ok {
+			id = &identity{}
+			e.names[nameKey] = id
+		}
+
+		target = id
+	}
+
+	if newName != "" {
+		target.name = newName
+	}
+
+	if newEmail != "" {
+		target.email = newEmail
+	}
+}
+
+// parseNameAndEmail parses a "Name <email>" pair at the beginning of line,
+// returning the trimmed name, the email and the remaining of the line. The
+// name is optional, the email may only be empty if allowEmptyEmail is set.
+func parseNameAndEmail(line string, allowEmptyEmail bool) (name, email, rest string, ok bool) {
+	left := strings.IndexByte(line, '<')
+	if left == -1 {
+		return "", "", "", false
+	}
+
+	right := strings.IndexByte(line[left+1:], '>')
+	if right == -1 {
+		return "", "", "", false
+	}
+
+	right += left + 1
+	if !allowEmptyEmail && right == left+1 {
+		return "", "", "", false
+	}
+
+	name = strings.TrimSpace(line[:left])
+	email = line[left+1 : right]
+
+	return name, email, line[right+1:], true
+}
+
+// Resolve returns the canonical name and email of the given commit identity.
+// Any part without a mapping is returned unchanged.
+func (m *Mailmap) Resolve(name, email string) (string, string) {
+	if m == nil {
+		return name, email
+	}
+
+	e, ok := m.emails[strings.ToLower(email)]
+	if !ok {
+		return name, email
+	}
+
+	id := &e.identity
+	if named, ok := e.names[strings.ToLower(name)]; ok {
+		id = named
+	}
+
+	if id.name != "" {
+		name = id.name
+	}
+
+	if id.email != "" {
+		email = id.email
+	}
+
+	return name, email
+}
+
+// ResolveSignature returns a copy of s with its name and email replaced by
+// their canonical form.
+func (m *Mailmap) ResolveSignature(s object.Signature) object.Signature {
+	s.Name, s.Email = m.Resolve(s.Name, s.Email)
+	return s
+}
@@ -0,0 +1,86 @@
+package mailmap
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+type MailmapSuite struct {
+	suite.Suite
+}
+
+func TestMailmapSuite(t *testing.T) {
+	t.Parallel()
+	suite.Run(t, new(MailmapSuite))
+}
+
+const fixture = `# A comment line
+Proper Name <commit@example.com>
+<proper@example.com> <Other@Example.com>
+Joe Developer <joe@example.com> <joe@laptop.(none)>
+Jane Doe <jane@example.com> jane <bugs@example.com>
+Bug Fixer <fixer@example.com> Fixer <bugs@example.com> # trailing comment
+
+invalid line without email
+`
+
+func (s *MailmapSuite) TestResolve() {
+	m, err := Parse(strings.NewReader(fixture))
+	s.Require().NoError(err)
+
+	tests := []struct {
+		name, email         string
+		wantName, wantEmail string
+	}{
+		// Proper Name <commit@email>
+		{"nick", "commit@example.com", "Proper Name", "commit@example.com"},
+		// <proper@email> <commit@email>, emails are case-insensitive.
+		{"Other", "other@example.com", "Other", "proper@example.com"},
+		// Proper Name <proper@email> <commit@email>
+		{"joe", "joe@laptop.(none)", "Joe Developer", "joe@example.com"},
+		// Proper Name <proper@email> Commit Name <commit@email>
+		{"Jane", "bugs@example.com", "Jane Doe", "jane@example.com"},
+		{"fixer", "bugs@example.com", "Bug Fixer", "fixer@example.com"},
+		{"someone", "bugs@example.com", "someone", "bugs@example.com"},
+		// Unknown identity.
+		{"unknown", "unknown@example.com", "unknown", "unknown@example.com"},
+	}
+
+	for _, tc := range tests {
+		name, email := m.Resolve(tc.name, tc.email)
+		s.Equal(tc.wantName, name, "name for %s <%s>", tc.name, tc.email)
+		s.Equal(tc.wantEmail, email, "email for %s <%s>", tc.name, tc.email)
+	}
+}
+
+func (s *MailmapSuite) TestReadOverrides() {
+	m, err := Parse(strings.NewReader("Old Name <a@example.com>\n"))
+	s.Require().NoError(err)
+
+	s.Require().NoError(m.Read(strings.NewReader("<b@example.com> <a@example.com>\nNew Name <a@example.com>\n")))
+
+	name, email := m.Resolve("a", "a@example.com")
+	s.Equal("New Name", name)
+	s.Equal("b@example.com", email)
+}
+
+func (s *MailmapSuite) TestResolveSignature() {
+	m, err := Parse(strings.NewReader("Proper Name <proper@example.com> <commit@example.com>\n"))
+	s.Require().NoError(err)
+
+	when := time.Unix(1257894000, 0)
+	sig := m.ResolveSignature(object.Signature{Name: "nick", Email: "commit@example.com", When: when})
+	s.Equal(object.Signature{Name: "Proper Name", Email: "proper@example.com", When: when}, sig)
+}
+
+func (s *MailmapSuite) TestNilMailmap() {
+	var m *Mailmap
+	name, email := m.Resolve("name", "email@example.com")
+	s.Equal("name", name)
+	s.Equal("email@example.com", email)
+}
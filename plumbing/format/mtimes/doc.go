@@ -0,0 +1,20 @@
+// Package mtimes implements encoding and decoding logic of the mtimes files
+// (MTME) of the cruft packs, which record the modification time of their
+// objects.
+//
+// MTME files are named "pack-*.mtimes" and have the format:
+//   - A 4-byte magic number '0x4d544d45' ('MTME').
+//   - A 4-byte version identifier (= 1).
+//   - A 4-byte hash function identifier (= 1 for SHA-1, 2 for SHA-256).
+//   - A table of mtimes (one per packed object, num_objects in total, each
+//     a 4-byte unsigned integer of seconds since the epoch), in the same
+//     order as the objects in the corresponding index, sorted by hash.
+//   - A trailer, containing a:
+//     checksum of the corresponding packfile, and
+//     a checksum of all of the above.
+//
+// All 4-byte numbers are in network order.
+//
+// Refer to:
+// https://github.com/git/git/blob/master/Documentation/gitformat-pack.adoc
+package mtimes
@@ -0,0 +1,159 @@
+package mtimes
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	gogithash "github.com/go-git/go-git/v6/plumbing/hash"
+	"github.com/go-git/go-git/v6/utils/binary"
+)
+
+var (
+	// ErrUnsupportedVersion is returned by Decode when the mtimes file
+	// version is not supported.
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	// ErrMalformedMtimesFile is returned by Decode when the mtimes file is
+	// corrupted.
+	ErrMalformedMtimesFile = errors.New("malformed mtimes file")
+	// ErrUnsupportedHashFunction is returned by Decode when the mtimes file
+	// defines an unsupported hash function.
+	ErrUnsupportedHashFunction = errors.New("unsupported hash function")
+
+	mtimesHeader = []byte{'M', 'T', 'M', 'E'}
+)
+
+// Mtimes file constants.
+const (
+	VersionSupported        = 1
+	sha1Hash         uint32 = 1
+	sha256Hash       uint32 = 2
+)
+
+// Encode writes the mtimes file of the packfile with the given checksum,
+// hashing it with h. The mtimes are the ones of the objects of the packfile,
+// in the order of its index.
+func Encode(w io.Writer, h hash.Hash, packChecksum plumbing.Hash, mtimes []uint32) error {
+	if w == nil {
+		return fmt.Errorf("nil writer")
+	}
+
+	hf := sha1Hash
+	if h.Size() == crypto.SHA256.Size() {
+		hf = sha256Hash
+	}
+
+	h.Reset()
+	mw := io.MultiWriter(w, h)
+	if err := binary.Write(mw, mtimesHeader, uint32(VersionSupported), hf); err != nil {
+		return err
+	}
+
+	for _, mtime := range mtimes {
+		if err := binary.WriteUint32(mw, mtime); err != nil {
+			return err
+		}
+	}
+
+	if _, err := mw.Write(packChecksum.Bytes()); err != nil {
+		return err
+	}
+
+	_, err := w.Write(h.Sum(nil))
+	return err
+}
+
+// Decode reads the mtimes file of the packfile with the given checksum,
+// holding count objects, and returns their mtimes in the order of its index.
+func Decode(r io.Reader, count int64, packChecksum plumbing.Hash) ([]uint32, error) {
+	if r == nil {
+		return nil, fmt.Errorf("%w: nil reader", ErrMalformedMtimesFile)
+	}
+
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(mtimesHeader))
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, malformed(err)
+	}
+
+	if !bytes.Equal(header, mtimesHeader) {
+		return nil, ErrMalformedMtimesFile
+	}
+
+	version, err := binary.ReadUint32(br)
+	if err != nil {
+		return nil, malformed(err)
+	}
+
+	if version != VersionSupported {
+		return nil, ErrUnsupportedVersion
+	}
+
+	hf, err := binary.ReadUint32(br)
+	if err != nil {
+		return nil, malformed(err)
+	}
+
+	var h hash.Hash
+	switch hf {
+	case sha1Hash:
+		h = gogithash.New(crypto.SHA1)
+	case sha256Hash:
+		h = gogithash.New(crypto.SHA256)
+	default:
+		return nil, ErrUnsupportedHashFunction
+	}
+
+	if err := binary.Write(h, mtimesHeader, version, hf); err != nil {
+		return nil, err
+	}
+
+	tr := io.TeeReader(br, h)
+	mtimes := make([]uint32, count)
+	for i := range mtimes {
+		mtimes[i], err = binary.ReadUint32(tr)
+		if err != nil {
+			return nil, malformed(err)
+		}
+	}
+
+	pack := make([]byte, h.Size())
+	if _, err := io.ReadFull(tr, pack); err != nil {
+		return nil, malformed(err)
+	}
+
+	if !bytes.Equal(pack, packChecksum.Bytes()) {
+		return nil, fmt.Errorf("%w: packfile hash mismatch wanted %q got %x",
+			ErrMalformedMtimesFile, packChecksum.String(), pack)
+	}
+
+	sum := h.Sum(nil)
+	checksum := make([]byte, h.Size())
+	if _, err := io.ReadFull(br, checksum); err != nil {
+		return nil, malformed(err)
+	}
+
+	if !bytes.Equal(checksum, sum) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrMalformedMtimesFile)
+	}
+
+	if _, err := br.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("%w: expected EOF", ErrMalformedMtimesFile)
+	}
+
+	return mtimes, nil
+}
+
+func malformed(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: unexpected EOF", ErrMalformedMtimesFile)
+	}
+
+	return err
+}
@@ -0,0 +1,80 @@
+package mtimes
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/hash"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		hash crypto.Hash
+		pack plumbing.Hash
+	}{
+		{"sha1", crypto.SHA1, plumbing.NewHash("a3fed42da1e8189a077c0e6846c040dcf73fc9dd")},
+		{"sha256", crypto.SHA256, plumbing.NewHash("407497645643e18a7ba56c6132603f167fe9c51c00361ee0c81d74a8f55d0ee2")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want := []uint32{1700000000, 1, 1800000000}
+
+			var buf bytes.Buffer
+			require.NoError(t, Encode(&buf, hash.New(tc.hash), tc.pack, want))
+			assert.Equal(t, 12+4*len(want)+2*tc.hash.Size(), buf.Len())
+			assert.Equal(t, []byte("MTME"), buf.Bytes()[:4])
+
+			got, err := Decode(bytes.NewReader(buf.Bytes()), int64(len(want)), tc.pack)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	t.Parallel()
+
+	pack := plumbing.NewHash("a3fed42da1e8189a077c0e6846c040dcf73fc9dd")
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, hash.New(crypto.SHA1), pack, []uint32{1, 2}))
+	valid := buf.Bytes()
+
+	corrupt := func(i int) []byte {
+		b := bytes.Clone(valid)
+		b[i] ^= 0xff
+		return b
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input []byte
+		count int64
+		pack  plumbing.Hash
+		err   error
+	}{
+		{"header", corrupt(0), 2, pack, ErrMalformedMtimesFile},
+		{"version", corrupt(7), 2, pack, ErrUnsupportedVersion},
+		{"hash function", corrupt(11), 2, pack, ErrUnsupportedHashFunction},
+		{"entry", corrupt(12), 2, pack, ErrMalformedMtimesFile},
+		{"truncated", valid[:len(valid)-1], 2, pack, ErrMalformedMtimesFile},
+		{"trailing data", append(bytes.Clone(valid), 0), 2, pack, ErrMalformedMtimesFile},
+		{"count", valid, 3, pack, ErrMalformedMtimesFile},
+		{"pack", valid, 2, plumbing.NewHash("0000000000000000000000000000000000000001"), ErrMalformedMtimesFile},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Decode(bytes.NewReader(tc.input), tc.count, tc.pack)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
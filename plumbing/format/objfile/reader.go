@@ -2,6 +2,7 @@ package objfile
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"strconv"
 
@@ -32,7 +33,7 @@ type Reader struct {
 func NewReader(r io.Reader) (*Reader, error) {
 	zlib, err := sync.GetZlibReader(r)
 	if err != nil {
-		return nil, packfile.ErrZLib.AddDetails("%s", err.Error())
+		return nil, fmt.Errorf("%w: %w", packfile.ErrZLib, err)
 	}
 
 	return &Reader{zlib: zlib}, nil
@@ -24,9 +24,28 @@ const (
 	maskLength      = uint8(127) // 0111 1111
 )
 
+// LimitedPackfileWriter is implemented by the storer.PackfileWriter able to
+// enforce ObjectLimits on the packfiles written.
+type LimitedPackfileWriter interface {
+	storer.PackfileWriter
+	// LimitedPackfileWriter returns a writer for writing a packfile to the
+	// storage, whose Write and Close fail with an *ObjectLimitError if one
+	// of its objects exceeds the limits.
+	LimitedPackfileWriter(limits ObjectLimits) (io.WriteCloser, error)
+}
+
 // UpdateObjectStorage updates the storer with the objects in the given
 // packfile.
 func UpdateObjectStorage(s storer.Storer, packfile io.Reader) error {
+	return UpdateObjectStorageWithLimits(s, packfile, ObjectLimits{})
+}
+
+// UpdateObjectStorageWithLimits updates the storer with the objects in the
+// given packfile, failing with an *ObjectLimitError on the first object
+// exceeding the limits. When the limits are set, the packfile is written
+// directly only to a LimitedPackfileWriter, the objects being decoded and
+// stored one by one otherwise.
+func UpdateObjectStorageWithLimits(s storer.Storer, packfile io.Reader, limits ObjectLimits) error {
 	if trace.Performance.Enabled() {
 		start := time.Now()
 		defer func() {
@@ -34,7 +53,13 @@ func UpdateObjectStorage(s storer.Storer, packfile io.Reader) error {
 		}()
 	}
 
-	if pw, ok := s.(storer.PackfileWriter); ok {
+	if lw, ok := s.(LimitedPackfileWriter); ok && !limits.IsZero() {
+		return writePackfile(packfile, func() (io.WriteCloser, error) {
+			return lw.LimitedPackfileWriter(limits)
+		})
+	}
+
+	if pw, ok := s.(storer.PackfileWriter); ok && limits.IsZero() {
 		return WritePackfileToObjectStorage(pw, packfile)
 	}
 
@@ -46,7 +71,7 @@ func UpdateObjectStorage(s storer.Storer, packfile io.Reader) error {
 		}
 	}
 
-	p := NewParser(packfile, WithStorage(s), WithObjectFormat(of))
+	p := NewParser(packfile, WithStorage(s), WithObjectFormat(of), WithObjectLimits(limits))
 	_, err := p.Parse()
 	return err
 }
@@ -56,8 +81,12 @@ func UpdateObjectStorage(s storer.Storer, packfile io.Reader) error {
 func WritePackfileToObjectStorage(
 	sw storer.PackfileWriter,
 	packfile io.Reader,
-) (err error) {
-	w, err := sw.PackfileWriter()
+) error {
+	return writePackfile(packfile, sw.PackfileWriter)
+}
+
+func writePackfile(packfile io.Reader, newWriter func() (io.WriteCloser, error)) (err error) {
+	w, err := newWriter()
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package packfile
 
 import (
+	"runtime"
 	"sort"
 	"sync"
 
@@ -22,10 +23,34 @@ var applyDelta = map[plumbing.ObjectType]bool{
 
 type deltaSelector struct {
 	storer storer.EncodedObjectStorer
+
+	// depth is the maximum length of the delta chains.
+	depth int64
+	// windowMemory bounds the size of the objects kept in the window, 0
+	// meaning no limit.
+	windowMemory int64
+	// threads is the number of delta searches run concurrently, 0 meaning
+	// one per object type.
+	threads int
 }
 
 func newDeltaSelector(s storer.EncodedObjectStorer) *deltaSelector {
-	return &deltaSelector{s}
+	return &deltaSelector{storer: s, depth: maxDepth}
+}
+
+// configure sets the depth, window memory and threads of the delta search
+// from o.
+func (dw *deltaSelector) configure(o EncodeOptions) {
+	dw.depth = maxDepth
+	if o.Depth > 0 {
+		dw.depth = int64(o.Depth)
+	}
+
+	dw.windowMemory = int64(o.WindowMemory)
+	dw.threads = int(o.Threads)
+	if dw.threads == 0 {
+		dw.threads = runtime.NumCPU()
+	}
 }
 
 // ObjectsToPack creates a list of ObjectToPack from the hashes
@@ -61,16 +86,25 @@ func (dw *deltaSelector) ObjectsToPack(
 		}
 	}
 
+	threads := len(objectGroups)
+	if dw.threads > 0 {
+		threads = dw.threads
+		objectGroups = splitObjectGroups(objectGroups, threads, packWindow)
+	}
+
 	var wg sync.WaitGroup
 	var once sync.Once
+	sem := make(chan struct{}, max(threads, 1))
 	for _, objs := range objectGroups {
 		wg.Add(1)
+		sem <- struct{}{}
 		go func() {
 			if walkErr := dw.walk(objs, packWindow); walkErr != nil {
 				once.Do(func() {
 					err = walkErr
 				})
 			}
+			<-sem
 			wg.Done()
 		}()
 	}
@@ -83,6 +117,30 @@ func (dw *deltaSelector) ObjectsToPack(
 	return otp, nil
 }
 
+// splitObjectGroups splits the groups of objects into segments searched for
+// deltas concurrently, as git does, so that the work is spread over the given
+// number of threads. A segment holds at least twice packWindow objects, since
+// no delta is searched across segments.
+func splitObjectGroups(groups [][]*ObjectToPack, threads int, packWindow uint) [][]*ObjectToPack {
+	total := 0
+	for _, objs := range groups {
+		total += len(objs)
+	}
+
+	size := max((total+threads-1)/threads, 2*int(packWindow), 1)
+	segments := make([][]*ObjectToPack, 0, len(groups))
+	for _, objs := range groups {
+		for len(objs) > size+size/2 {
+			segments = append(segments, objs[:size])
+			objs = objs[size:]
+		}
+
+		segments = append(segments, objs)
+	}
+
+	return segments
+}
+
 func (dw *deltaSelector) objectsToPack(
 	hashes []plumbing.Hash,
 	packWindow uint,
@@ -221,21 +279,39 @@ func (dw *deltaSelector) walk(
 	packWindow uint,
 ) error {
 	indexMap := make(map[plumbing.Hash]*deltaIndex)
-	for i := range len(objectsToPack) {
-		// Clean up the index map and reconstructed delta objects for anything
-		// outside our pack window, to save memory.
-		if i > int(packWindow) {
-			obj := objectsToPack[i-int(packWindow)]
 
-			delete(indexMap, obj.Hash())
+	// The window holds the objects from start to the current one, and memory
+	// is the size of those objects.
+	start := 0
+	var memory int64
+	evict := func() {
+		// Clean up the index map and reconstructed delta objects for
+		// anything outside our pack window, to save memory.
+		obj := objectsToPack[start]
+		start++
+		memory -= obj.Size()
+
+		delete(indexMap, obj.Hash())
+
+		if obj.IsDelta() {
+			obj.SaveOriginalMetadata()
+			obj.CleanOriginal()
+		}
+	}
+
+	for i := range len(objectsToPack) {
+		for i-start >= int(packWindow) {
+			evict()
+		}
 
-			if obj.IsDelta() {
-				obj.SaveOriginalMetadata()
-				obj.CleanOriginal()
-			}
+		// Shrink the window until it fits in the window memory, always
+		// keeping one object to compare with.
+		for dw.windowMemory > 0 && memory > dw.windowMemory && i-start > 1 {
+			evict()
 		}
 
 		target := objectsToPack[i]
+		memory += target.Size()
 
 		// If we already have a delta, we don't try to find a new one for this
 		// object. This happens when a delta is set to be reused from an existing
@@ -249,7 +325,7 @@ func (dw *deltaSelector) walk(
 			continue
 		}
 
-		for j := i - 1; j >= 0 && i-j < int(packWindow); j-- {
+		for j := i - 1; j >= start; j-- {
 			base := objectsToPack[j]
 			// Objects must use only the same type as their delta base.
 			// Since objectsToPack is sorted by type and size, once we find
@@ -329,7 +405,7 @@ func (dw *deltaSelector) deltaSizeLimit(targetSize int64, baseDepth int,
 		// Evenly distribute delta size limits over allowed depth.
 		// If src is non-delta (depth = 0), delta <= 50% of original.
 		// If src is almost at limit (9/10), delta <= 10% of original.
-		return n * (maxDepth - int64(baseDepth)) / maxDepth
+		return n * (dw.depth - int64(baseDepth)) / dw.depth
 	}
 
 	// With a delta base chosen any new delta must be "better".
@@ -337,8 +413,9 @@ func (dw *deltaSelector) deltaSizeLimit(targetSize int64, baseDepth int,
 	d := int64(targetDepth)
 	n := targetSize
 
-	// If target depth is bigger than maxDepth, this delta is not suitable to be used.
-	if d >= maxDepth {
+	// If target depth is bigger than the maximum depth, this delta is not
+	// suitable to be used.
+	if d >= dw.depth {
 		return 0
 	}
 
@@ -347,7 +424,7 @@ func (dw *deltaSelector) deltaSizeLimit(targetSize int64, baseDepth int,
 	//
 	// If src is near limit (depth=9/10) and base is whole (depth=0)
 	// a new delta dependent on src must be 1/10th the size.
-	return n * (maxDepth - int64(baseDepth)) / (maxDepth - d)
+	return n * (dw.depth - int64(baseDepth)) / (dw.depth - d)
 }
 
 type byTypeAndSize []*ObjectToPack
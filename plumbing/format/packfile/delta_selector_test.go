@@ -3,6 +3,7 @@ package packfile
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/go-git/go-git/v6/plumbing"
@@ -240,3 +241,64 @@ func (s *DeltaSelectorSuite) TestMaxDepth() {
 	dsl := s.ds.deltaSizeLimit(0, 0, int(maxDepth), true)
 	s.Equal(int64(0), dsl)
 }
+
+func (s *DeltaSelectorSuite) TestConfigure() {
+	hashes := []plumbing.Hash{s.hashes["o1"], s.hashes["o2"], s.hashes["o3"]}
+
+	// The delta chains are cut at the configured depth.
+	s.ds.configure(EncodeOptions{Depth: 1, Threads: 1})
+	otp, err := s.ds.ObjectsToPack(hashes, 10)
+	s.NoError(err)
+	s.Len(otp, 3)
+	s.True(otp[1].IsDelta())
+	s.Equal(1, otp[1].Depth)
+	s.True(otp[2].IsDelta())
+	s.Equal(1, otp[2].Depth)
+	s.Equal(otp[0], otp[2].Base)
+
+	// The window shrinks to fit in the window memory, so o1 is no longer a
+	// candidate base of o3.
+	s.ds.configure(EncodeOptions{Depth: 1, WindowMemory: 1, Threads: 1})
+	otp, err = s.ds.ObjectsToPack(hashes, 10)
+	s.NoError(err)
+	s.Len(otp, 3)
+	s.True(otp[1].IsDelta())
+	s.False(otp[2].IsDelta())
+}
+
+func TestSplitObjectGroups(t *testing.T) {
+	t.Parallel()
+
+	objs := func(n int) []*ObjectToPack {
+		return make([]*ObjectToPack, n)
+	}
+
+	lens := func(groups [][]*ObjectToPack) []int {
+		var l []int
+		for _, g := range groups {
+			l = append(l, len(g))
+		}
+		return l
+	}
+
+	tests := []struct {
+		name    string
+		groups  [][]*ObjectToPack
+		threads int
+		window  uint
+		want    []int
+	}{
+		{"single thread", [][]*ObjectToPack{objs(100), objs(50)}, 1, 10, []int{100, 50}},
+		{"split per thread", [][]*ObjectToPack{objs(100)}, 4, 10, []int{25, 25, 25, 25}},
+		{"remainder kept in last segment", [][]*ObjectToPack{objs(110)}, 4, 10, []int{28, 28, 28, 26}},
+		{"segments hold twice the window", [][]*ObjectToPack{objs(100)}, 8, 20, []int{40, 60}},
+		{"small groups not split", [][]*ObjectToPack{objs(10), objs(5)}, 8, 10, []int{10, 5}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, lens(splitObjectGroups(tc.groups, tc.threads, tc.window)))
+		})
+	}
+}
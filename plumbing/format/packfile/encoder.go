@@ -74,6 +74,43 @@ func (e *Encoder) Encode(
 	return e.encode(objects)
 }
 
+// EncodeOptions configures the delta compression of EncodeWithOptions, as
+// the pack.* options of git do.
+type EncodeOptions struct {
+	// Window is the size of the sliding window used to compare objects
+	// for delta compression; 0 turns off delta compression entirely.
+	Window uint
+	// Depth is the maximum length of the delta chains. 0 means 50, the
+	// default of git.
+	Depth uint
+	// WindowMemory bounds the size, in bytes, of the objects kept in each
+	// window, which shrinks as needed. 0 means no limit.
+	WindowMemory uint64
+	// Threads is the number of delta searches run concurrently. 0 means the
+	// number of CPUs.
+	Threads uint
+}
+
+// NewEncodeOptions returns the EncodeOptions set by the pack section of c.
+func NewEncodeOptions(c *config.Config) EncodeOptions {
+	return EncodeOptions{
+		Window:       c.Pack.Window,
+		Depth:        c.Pack.Depth,
+		WindowMemory: c.Pack.WindowMemory,
+		Threads:      c.Pack.Threads,
+	}
+}
+
+// EncodeWithOptions creates a packfile containing all the objects referenced
+// in hashes, as Encode does, searching for deltas as configured by o.
+func (e *Encoder) EncodeWithOptions(
+	hashes []plumbing.Hash,
+	o EncodeOptions,
+) (plumbing.Hash, error) {
+	e.selector.configure(o)
+	return e.Encode(hashes, o.Window)
+}
+
 func (e *Encoder) encode(objects []*ObjectToPack) (plumbing.Hash, error) {
 	if err := e.head(len(objects)); err != nil {
 		return plumbing.ZeroHash, err
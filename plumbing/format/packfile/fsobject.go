@@ -86,21 +86,38 @@ func (o *FSObject) Reader() (io.ReadCloser, error) {
 
 	zr, err := sync.GetZlibReader(br)
 	if err != nil {
-		return nil, err
+		return nil, o.inflateError(err)
+	}
+	return &zlibReadCloser{r: zr, f: file, rbuf: br, obj: o}, nil
+}
+
+// inflateError returns an ObjectCorruptError for o if err was returned when
+// inflating it, and err otherwise.
+func (o *FSObject) inflateError(err error) error {
+	if !sync.IsInflateError(err) {
+		return err
 	}
-	return &zlibReadCloser{r: zr, f: file, rbuf: br}, nil
+
+	offset, ferr := o.index.FindOffset(o.hash)
+	if ferr != nil {
+		offset = o.offset
+	}
+
+	return newObjectCorruptError(o.hash, o.packPath, offset, err)
 }
 
 type zlibReadCloser struct {
 	r      *sync.ZLibReader
 	f      io.Closer
 	rbuf   *bufio.Reader
+	obj    *FSObject
 	closed bool
 }
 
 // Read reads up to len(p) bytes into p from the data.
 func (r *zlibReadCloser) Read(p []byte) (int, error) {
-	return r.r.Read(p)
+	n, err := r.r.Read(p)
+	return n, r.obj.inflateError(err)
 }
 
 func (r *zlibReadCloser) Close() (err error) {
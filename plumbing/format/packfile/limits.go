@@ -0,0 +1,166 @@
+package packfile
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	packutil "github.com/go-git/go-git/v6/plumbing/format/packfile/util"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+// ErrObjectLimitExceeded is returned when an object of a packfile exceeds the
+// ObjectLimits set to the parser.
+var ErrObjectLimitExceeded = errors.New("object limit exceeded")
+
+// ObjectLimits are the limits on the objects of a packfile, to defend against
+// the maliciously huge ones. The zero values set no limit.
+type ObjectLimits struct {
+	// MaxBlobSize, if positive, is the maximum size of the blobs, in bytes.
+	MaxBlobSize int64
+	// MaxTreeEntries, if positive, is the maximum number of entries of the
+	// trees.
+	MaxTreeEntries int
+}
+
+// IsZero returns whether no limit is set.
+func (l ObjectLimits) IsZero() bool {
+	return l.MaxBlobSize <= 0 && l.MaxTreeEntries <= 0
+}
+
+// ObjectLimitError is returned when an object of a packfile exceeds the
+// ObjectLimits. It matches ErrObjectLimitExceeded.
+type ObjectLimitError struct {
+	// Hash is the hash of the object, zero for a delta rejected before being
+	// applied.
+	Hash plumbing.Hash
+	// Type is the type of the object, a blob or a tree.
+	Type plumbing.ObjectType
+	// Size is the size of the blob, or the number of entries of the tree.
+	Size int64
+	// Limit is the limit exceeded.
+	Limit int64
+}
+
+func (e *ObjectLimitError) Error() string {
+	if e.Type == plumbing.TreeObject {
+		return fmt.Sprintf("%s: tree %s has %d entries, more than %d", ErrObjectLimitExceeded, e.Hash, e.Size, e.Limit)
+	}
+
+	return fmt.Sprintf("%s: blob %s has %d bytes, more than %d", ErrObjectLimitExceeded, e.Hash, e.Size, e.Limit)
+}
+
+// Unwrap returns ErrObjectLimitExceeded.
+func (e *ObjectLimitError) Unwrap() error {
+	return ErrObjectLimitExceeded
+}
+
+// CheckObject returns an *ObjectLimitError if the object exceeds the limits.
+func (l ObjectLimits) CheckObject(obj plumbing.EncodedObject) (err error) {
+	if obj.Type() != plumbing.TreeObject || l.MaxTreeEntries <= 0 {
+		return l.check(obj.Hash(), obj.Type(), obj.Size(), nil)
+	}
+
+	r, err := obj.Reader()
+	if err != nil {
+		return err
+	}
+
+	defer ioutil.CheckClose(r, &err)
+	return l.checkContent(obj.Hash(), obj.Type(), obj.Size(), r)
+}
+
+// entryCounter returns a writer counting the entries of the tree written to
+// it, if the object is a tree whose entries are limited, nil otherwise.
+func (l ObjectLimits) entryCounter(t plumbing.ObjectType, hashSize int) *treeEntryCounter {
+	if t != plumbing.TreeObject || l.MaxTreeEntries <= 0 {
+		return nil
+	}
+
+	return &treeEntryCounter{hashSize: hashSize}
+}
+
+// check returns an *ObjectLimitError if the object exceeds the limits, given
+// the counter of its entries if it is a tree.
+func (l ObjectLimits) check(h plumbing.Hash, t plumbing.ObjectType, size int64, entries *treeEntryCounter) error {
+	switch {
+	case t == plumbing.BlobObject && l.MaxBlobSize > 0 && size > l.MaxBlobSize:
+		return &ObjectLimitError{Hash: h, Type: t, Size: size, Limit: l.MaxBlobSize}
+	case entries != nil && entries.entries > l.MaxTreeEntries:
+		return &ObjectLimitError{Hash: h, Type: t, Size: int64(entries.entries), Limit: int64(l.MaxTreeEntries)}
+	}
+
+	return nil
+}
+
+// checkContent returns an *ObjectLimitError if the object with the given
+// content exceeds the limits.
+func (l ObjectLimits) checkContent(h plumbing.Hash, t plumbing.ObjectType, size int64, content io.Reader) error {
+	entries := l.entryCounter(t, h.Size())
+	if entries != nil {
+		if _, err := io.Copy(entries, content); err != nil {
+			return err
+		}
+	}
+
+	return l.check(h, t, size, entries)
+}
+
+// deltaTargetSize returns the size of the object produced by the delta,
+// read from its header, which holds the size of the base object and then the
+// one of the target, without consuming it.
+func deltaTargetSize(delta *bufio.Reader) (int64, error) {
+	// Each size is encoded in at most 10 bytes.
+	header, err := delta.Peek(20)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	r := bytes.NewReader(header)
+	if _, err := packutil.DecodeLEB128FromReader(r); err != nil {
+		return 0, ErrInvalidDelta
+	}
+
+	size, err := packutil.DecodeLEB128FromReader(r)
+	if err != nil {
+		return 0, ErrInvalidDelta
+	}
+
+	return int64(size), nil
+}
+
+// treeEntryCounter counts the entries of the tree written to it. Each entry
+// is its mode and name ended by a NUL byte, followed by the hash of the
+// object.
+type treeEntryCounter struct {
+	hashSize int
+	// skip is the number of bytes of the hash of the current entry left.
+	skip    int
+	entries int
+}
+
+func (c *treeEntryCounter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if c.skip > 0 {
+			k := min(c.skip, len(p))
+			c.skip -= k
+			p = p[k:]
+			continue
+		}
+
+		i := bytes.IndexByte(p, 0)
+		if i < 0 {
+			break
+		}
+
+		c.entries++
+		c.skip = c.hashSize
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
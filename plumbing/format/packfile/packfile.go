@@ -2,7 +2,9 @@ package packfile
 
 import (
 	"bufio"
+	"context"
 	"crypto"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -37,6 +39,8 @@ type Packfile struct {
 	cache cache.Object
 	rbuf  *bufio.Reader
 
+	externalBase func(context.Context, plumbing.Hash) (plumbing.EncodedObject, error)
+
 	id           plumbing.Hash
 	m            sync.Mutex
 	objectIdSize int
@@ -72,7 +76,7 @@ func (p *Packfile) Get(h plumbing.Hash) (plumbing.EncodedObject, error) {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	return p.get(h)
+	return p.get(context.Background(), h)
 }
 
 // GetByOffset retrieves the encoded object from the packfile at the given
@@ -84,7 +88,23 @@ func (p *Packfile) GetByOffset(offset int64) (plumbing.EncodedObject, error) {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	return p.getByOffset(offset)
+	return p.getByOffset(context.Background(), offset)
+}
+
+// GetByOffsetContext is like GetByOffset, but returns ctx.Err() as soon as
+// the context is cancelled while resolving the chain of deltas of the object.
+func (p *Packfile) GetByOffsetContext(ctx context.Context, offset int64) (plumbing.EncodedObject, error) {
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.getByOffset(ctx, offset)
 }
 
 // GetSizeByOffset retrieves the size of the encoded object from the
@@ -159,7 +179,7 @@ func (p *Packfile) ID() (plumbing.Hash, error) {
 }
 
 // get is not threat-safe, and should only be called within packfile.go.
-func (p *Packfile) get(h plumbing.Hash) (plumbing.EncodedObject, error) {
+func (p *Packfile) get(ctx context.Context, h plumbing.Hash) (plumbing.EncodedObject, error) {
 	if obj, ok := p.cache.Get(h); ok {
 		return obj, nil
 	}
@@ -174,11 +194,11 @@ func (p *Packfile) get(h plumbing.Hash) (plumbing.EncodedObject, error) {
 		return nil, err
 	}
 
-	return p.objectFromHeader(oh)
+	return p.objectFromHeader(ctx, oh)
 }
 
 // getByOffset is not threat-safe, and should only be called within packfile.go.
-func (p *Packfile) getByOffset(offset int64) (plumbing.EncodedObject, error) {
+func (p *Packfile) getByOffset(ctx context.Context, offset int64) (plumbing.EncodedObject, error) {
 	h, err := p.FindHash(offset)
 	if err != nil {
 		return nil, err
@@ -193,7 +213,7 @@ func (p *Packfile) getByOffset(offset int64) (plumbing.EncodedObject, error) {
 		return nil, err
 	}
 
-	return p.objectFromHeader(oh)
+	return p.objectFromHeader(ctx, oh)
 }
 
 func (p *Packfile) init() error {
@@ -250,6 +270,10 @@ func (p *Packfile) headerFromOffset(offset int64) (*ObjectHeader, error) {
 	}
 
 	if !p.scanner.Scan() {
+		if err := p.scanner.Error(); gogitsync.IsInflateError(err) {
+			return nil, p.inflateError(&ObjectHeader{Offset: offset}, err)
+		}
+
 		return nil, plumbing.ErrObjectNotFound
 	}
 
@@ -272,7 +296,7 @@ func (p *Packfile) Close() error {
 	return closer.Close()
 }
 
-func (p *Packfile) objectFromHeader(oh *ObjectHeader) (plumbing.EncodedObject, error) {
+func (p *Packfile) objectFromHeader(ctx context.Context, oh *ObjectHeader) (plumbing.EncodedObject, error) {
 	if oh == nil {
 		return nil, plumbing.ErrObjectNotFound
 	}
@@ -296,10 +320,41 @@ func (p *Packfile) objectFromHeader(oh *ObjectHeader) (plumbing.EncodedObject, e
 		return fs, nil
 	}
 
-	return p.getMemoryObject(oh)
+	return p.getMemoryObject(ctx, oh)
 }
 
-func (p *Packfile) getMemoryObject(oh *ObjectHeader) (plumbing.EncodedObject, error) {
+// inflateError returns an ObjectCorruptError for the object oh if err was
+// returned when inflating it, and err otherwise.
+func (p *Packfile) inflateError(oh *ObjectHeader, err error) error {
+	if !gogitsync.IsInflateError(err) {
+		return err
+	}
+
+	h := oh.Hash
+	if h.IsZero() && p.Index != nil {
+		if found, ferr := p.FindHash(oh.Offset); ferr == nil {
+			h = found
+		}
+	}
+
+	return newObjectCorruptError(h, p.file.Name(), oh.Offset, err)
+}
+
+// newObjectCorruptError returns an ObjectCorruptError for the object h at
+// offset in the packfile name.
+func newObjectCorruptError(h plumbing.Hash, name string, offset int64, err error) error {
+	return &plumbing.ObjectCorruptError{
+		Hash:   h,
+		Source: fmt.Sprintf("%s at offset %d", name, offset),
+		Err:    err,
+	}
+}
+
+func (p *Packfile) getMemoryObject(ctx context.Context, oh *ObjectHeader) (plumbing.EncodedObject, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	of := format.SHA1
 	if p.objectIdSize == format.SHA256.Size() {
 		of = format.SHA256
@@ -318,7 +373,7 @@ func (p *Packfile) getMemoryObject(oh *ObjectHeader) (plumbing.EncodedObject, er
 
 	switch oh.Type {
 	case plumbing.CommitObject, plumbing.TreeObject, plumbing.BlobObject, plumbing.TagObject:
-		err = p.scanner.inflateContent(oh.ContentOffset, w)
+		err = p.inflateError(oh, p.scanner.inflateContent(oh.ContentOffset, w))
 
 	case plumbing.REFDeltaObject, plumbing.OFSDeltaObject:
 		var parent plumbing.EncodedObject
@@ -328,10 +383,13 @@ func (p *Packfile) getMemoryObject(oh *ObjectHeader) (plumbing.EncodedObject, er
 			var ok bool
 			parent, ok = p.cache.Get(oh.Reference)
 			if !ok {
-				parent, err = p.get(oh.Reference)
+				parent, err = p.get(ctx, oh.Reference)
+				if errors.Is(err, plumbing.ErrObjectNotFound) && p.externalBase != nil {
+					parent, err = p.externalBase(ctx, oh.Reference)
+				}
 			}
 		case plumbing.OFSDeltaObject:
-			parent, err = p.getByOffset(oh.OffsetReference)
+			parent, err = p.getByOffset(ctx, oh.OffsetReference)
 		}
 
 		if err != nil {
@@ -344,7 +402,7 @@ func (p *Packfile) getMemoryObject(oh *ObjectHeader) (plumbing.EncodedObject, er
 
 		err = p.scanner.inflateContent(oh.ContentOffset, oh.content)
 		if err != nil {
-			return nil, fmt.Errorf("cannot inflate content: %w", err)
+			return nil, fmt.Errorf("cannot inflate content: %w", p.inflateError(oh, err))
 		}
 
 		obj.SetType(parent.Type())
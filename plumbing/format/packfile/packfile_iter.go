@@ -1,6 +1,7 @@
 package packfile
 
 import (
+	"context"
 	"io"
 
 	"github.com/go-git/go-git/v6/plumbing"
@@ -37,13 +38,13 @@ func (i *objectIter) next() (plumbing.EncodedObject, error) {
 		}
 
 		if i.typ == plumbing.AnyObject {
-			return i.p.objectFromHeader(oh)
+			return i.p.objectFromHeader(context.Background(), oh)
 		}
 
 		// Current object header type is a delta, get the actual object to
 		// assess the actual type.
 		if oh.Type.IsDelta() {
-			o, err := i.p.objectFromHeader(oh)
+			o, err := i.p.objectFromHeader(context.Background(), oh)
 			if o.Type() == i.typ {
 				return o, err
 			}
@@ -52,7 +53,7 @@ func (i *objectIter) next() (plumbing.EncodedObject, error) {
 		}
 
 		if oh.Type == i.typ {
-			return i.p.objectFromHeader(oh)
+			return i.p.objectFromHeader(context.Background(), oh)
 		}
 
 		continue
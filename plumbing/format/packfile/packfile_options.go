@@ -1,8 +1,11 @@
 package packfile
 
 import (
+	"context"
+
 	billy "github.com/go-git/go-billy/v6"
 
+	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/cache"
 	"github.com/go-git/go-git/v6/plumbing/format/idxfile"
 )
@@ -42,3 +45,13 @@ func WithObjectIDSize(sz int) PackfileOption {
 		p.objectIdSize = sz
 	}
 }
+
+// WithExternalBases sets the function used to retrieve the base objects of
+// REF_DELTA objects that are not found in the packfile itself, as happens
+// when the base lives in another packfile of the same repository. It is
+// given the context of the object read.
+func WithExternalBases(get func(context.Context, plumbing.Hash) (plumbing.EncodedObject, error)) PackfileOption {
+	return func(p *Packfile) {
+		p.externalBase = get
+	}
+}
@@ -1,12 +1,16 @@
 package packfile_test
 
 import (
+	"context"
 	"crypto"
 	"io"
 	"math"
 	"testing"
 
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
 	"github.com/go-git/go-billy/v6/osfs"
+	"github.com/go-git/go-billy/v6/util"
 	fixtures "github.com/go-git/go-git-fixtures/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -71,6 +75,26 @@ func TestGetByOffset(t *testing.T) {
 	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
 }
 
+func TestGetByOffsetContext(t *testing.T) {
+	t.Parallel()
+
+	f := fixtures.Basic().One()
+	index := getIndexFromIdxFile(f.Idx())
+	p := packfile.NewPackfile(f.Packfile(), packfile.WithIdx(index))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for h, o := range expectedEntries {
+		_, err := p.GetByOffsetContext(ctx, o)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		obj, err := p.GetByOffsetContext(context.Background(), o)
+		require.NoError(t, err)
+		assert.Equal(t, h.String(), obj.Hash().String())
+	}
+}
+
 func TestGetAll(t *testing.T) {
 	t.Parallel()
 
@@ -227,6 +251,58 @@ func TestDecodeByTypeConstructor(t *testing.T) {
 	assert.ErrorIs(t, err, plumbing.ErrInvalidType)
 }
 
+func TestGetCorruptObject(t *testing.T) {
+	t.Parallel()
+
+	f := fixtures.Basic().One()
+	data, err := io.ReadAll(f.Packfile())
+	require.NoError(t, err)
+
+	h := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	offset := expectedEntries[h]
+	for i := offset + 8; i < offset+16; i++ {
+		data[i] ^= 0xff
+	}
+
+	fs := memfs.New()
+	require.NoError(t, util.WriteFile(fs, "pack-corrupt.pack", data, 0o644))
+
+	open := func() billy.File {
+		pf, err := fs.Open("pack-corrupt.pack")
+		require.NoError(t, err)
+		t.Cleanup(func() { pf.Close() })
+		return pf
+	}
+
+	assertCorrupt := func(err error) {
+		t.Helper()
+		require.ErrorIs(t, err, plumbing.ErrObjectCorrupt)
+
+		var corrupt *plumbing.ObjectCorruptError
+		require.ErrorAs(t, err, &corrupt)
+		assert.Equal(t, h, corrupt.Hash)
+		assert.Contains(t, corrupt.Source, "pack-corrupt.pack at offset ")
+	}
+
+	p := packfile.NewPackfile(open(), packfile.WithIdx(getIndexFromIdxFile(f.Idx())))
+	_, err = p.Get(h)
+	assertCorrupt(err)
+
+	_, err = p.GetByOffset(offset)
+	assertCorrupt(err)
+
+	p = packfile.NewPackfile(open(),
+		packfile.WithIdx(getIndexFromIdxFile(f.Idx())), packfile.WithFs(fs),
+	)
+	_, err = p.Get(h)
+	assertCorrupt(err)
+
+	// The other objects are still read.
+	obj, err := p.Get(plumbing.NewHash("1669dce138d9b841a518c64b10914d88f5e488ea"))
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.CommitObject, obj.Type())
+}
+
 func getIndexFromIdxFile(r io.ReadCloser) idxfile.Index {
 	defer r.Close()
 
@@ -1,17 +1,21 @@
 package packfile
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	stdsync "sync"
+	"time"
 
 	"github.com/go-git/go-git/v6/plumbing"
 	format "github.com/go-git/go-git/v6/plumbing/format/config"
 	"github.com/go-git/go-git/v6/plumbing/storer"
 	"github.com/go-git/go-git/v6/utils/ioutil"
 	"github.com/go-git/go-git/v6/utils/sync"
+	"github.com/go-git/go-git/v6/utils/trace"
 )
 
 var (
@@ -33,6 +37,7 @@ type Parser struct {
 	storage       storer.EncodedObjectStorer
 	cache         *parserCache
 	lowMemoryMode bool
+	limits        ObjectLimits
 
 	scanner   *Scanner
 	observers []Observer
@@ -42,6 +47,11 @@ type Parser struct {
 
 	checksum plumbing.Hash
 	m        stdsync.Mutex
+
+	// deltasByOffset and deltasByHash count the deltas not yet resolved of
+	// each base object, by offset for ofs-deltas and by hash for ref-deltas.
+	deltasByOffset map[int64]int
+	deltasByHash   map[plumbing.Hash]int
 }
 
 // LowMemoryCapable is implemented by storage types that are capable of
@@ -72,6 +82,7 @@ func NewParser(data io.Reader, opts ...ParserOption) *Parser {
 	}
 
 	p.scanner = NewScanner(data, sopts...)
+	p.scanner.limits = p.limits
 
 	if p.storage != nil {
 		p.scanner.storage = p.storage
@@ -138,6 +149,21 @@ func (p *Parser) Parse() (plumbing.Hash, error) {
 	p.m.Lock()
 	defer p.m.Unlock()
 
+	if !trace.General.Emitting() {
+		return p.parse()
+	}
+
+	start := time.Now()
+	h, err := p.parse()
+	trace.General.Emit("packfile.parse",
+		slog.Uint64("objects", uint64(p.scanner.objects)),
+		slog.Duration("duration", time.Since(start)),
+		slog.Any("error", err))
+
+	return h, err
+}
+
+func (p *Parser) parse() (plumbing.Hash, error) {
 	var pendingDeltas []*ObjectHeader
 	var pendingDeltaREFs []*ObjectHeader
 
@@ -183,6 +209,8 @@ func (p *Parser) Parse() (plumbing.Hash, error) {
 		return plumbing.ZeroHash, err
 	}
 
+	p.countDeltas(pendingDeltaREFs, pendingDeltas)
+
 	for _, oh := range pendingDeltaREFs {
 		err := p.processDelta(oh)
 		if err != nil {
@@ -282,7 +310,62 @@ func (p *Parser) processDelta(oh *ObjectHeader) error {
 		return err
 	}
 
-	return p.storeOrCache(oh)
+	if err := p.limits.checkContent(oh.Hash, oh.Type, oh.Size, bytes.NewReader(oh.content.Bytes())); err != nil {
+		return err
+	}
+
+	if err := p.storeOrCache(oh); err != nil {
+		return err
+	}
+
+	p.resolved(oh)
+	return nil
+}
+
+// countDeltas counts the deltas of each base object, so that the inflated
+// content of the objects can be released as soon as no delta needs it,
+// instead of being held until the whole packfile is parsed.
+func (p *Parser) countDeltas(deltas ...[]*ObjectHeader) {
+	p.deltasByOffset = make(map[int64]int)
+	p.deltasByHash = make(map[plumbing.Hash]int)
+	for _, list := range deltas {
+		for _, oh := range list {
+			if oh.Type == plumbing.OFSDeltaObject {
+				p.deltasByOffset[oh.OffsetReference]++
+			} else {
+				p.deltasByHash[oh.Reference]++
+			}
+		}
+	}
+
+	for _, oh := range p.cache.oi {
+		p.release(oh)
+	}
+}
+
+// resolved records that the delta was resolved, releasing its content and
+// the one of its base if no other delta needs them.
+func (p *Parser) resolved(oh *ObjectHeader) {
+	switch oh.diskType {
+	case plumbing.OFSDeltaObject:
+		p.deltasByOffset[oh.OffsetReference]--
+	case plumbing.REFDeltaObject:
+		p.deltasByHash[oh.Reference]--
+	}
+
+	p.release(oh.parent)
+	p.release(oh)
+}
+
+// release returns the inflated content of the object to the pool, unless a
+// delta not yet resolved needs it.
+func (p *Parser) release(oh *ObjectHeader) {
+	if oh == nil || oh.content == nil || p.deltasByOffset[oh.Offset] > 0 || p.deltasByHash[oh.Hash] > 0 {
+		return
+	}
+
+	sync.PutBytesBuffer(oh.content)
+	oh.content = nil
 }
 
 // parentReader returns a [io.ReaderAt] for the decompressed contents
@@ -358,6 +441,22 @@ func (p *Parser) applyPatchBaseHeader(ota *ObjectHeader, delta io.Reader, target
 		typ = ota.parent.Type
 	}
 
+	// The size of the object is checked before the delta is applied, which
+	// would inflate it in memory.
+	if !p.limits.IsZero() {
+		br := bufio.NewReader(delta)
+		size, err := deltaTargetSize(br)
+		if err != nil {
+			return err
+		}
+
+		if err := p.limits.check(ota.Hash, typ, size, nil); err != nil {
+			return err
+		}
+
+		delta = br
+	}
+
 	sz, h, err := patchDeltaWriter(target, parentContents, delta, typ, wh, p.objectFormat)
 	if err != nil {
 		return err
@@ -28,6 +28,15 @@ func WithScannerObservers(ob ...Observer) ParserOption {
 	}
 }
 
+// WithObjectLimits sets the limits on the objects of the pack file, the
+// parsing failing with an *ObjectLimitError on the first object exceeding
+// them.
+func WithObjectLimits(limits ObjectLimits) ParserOption {
+	return func(p *Parser) {
+		p.limits = limits
+	}
+}
+
 func WithObjectFormat(of config.ObjectFormat) ParserOption {
 	return func(p *Parser) {
 		if of == config.UnsetObjectFormat {
@@ -1,6 +1,10 @@
 package packfile_test
 
 import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
 	"io"
 	"os"
 	"reflect"
@@ -359,3 +363,133 @@ func TestMalformedPack(t *testing.T) {
 	_, err = parser.Parse()
 	require.ErrorContains(t, err, "malformed pack")
 }
+
+func TestParserObjectLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		limits  packfile.ObjectLimits
+		storage storer.EncodedObjectStorer
+		want    packfile.ObjectLimitError
+	}{
+		{
+			name:   "blob size",
+			limits: packfile.ObjectLimits{MaxBlobSize: 70000},
+			want: packfile.ObjectLimitError{
+				Hash:  plumbing.NewHash("d5c0f4ab811897cadf03aec358ae60d21f91c50d"),
+				Type:  plumbing.BlobObject,
+				Size:  76110,
+				Limit: 70000,
+			},
+		},
+		{
+			name:    "blob size with storage",
+			limits:  packfile.ObjectLimits{MaxBlobSize: 70000},
+			storage: memory.NewStorage(),
+			want: packfile.ObjectLimitError{
+				Hash:  plumbing.NewHash("d5c0f4ab811897cadf03aec358ae60d21f91c50d"),
+				Type:  plumbing.BlobObject,
+				Size:  76110,
+				Limit: 70000,
+			},
+		},
+		{
+			name:   "tree entries",
+			limits: packfile.ObjectLimits{MaxTreeEntries: 2},
+			want: packfile.ObjectLimitError{
+				Hash:  plumbing.NewHash("dbd3641b371024f44d0e469a9c8f5457b0660de1"),
+				Type:  plumbing.TreeObject,
+				Size:  8,
+				Limit: 2,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []packfile.ParserOption
+			if tc.storage != nil {
+				opts = append(opts, packfile.WithStorage(tc.storage))
+			}
+
+			opts = append(opts, packfile.WithObjectLimits(tc.limits))
+			_, err := packfile.NewParser(fixtures.Basic().One().Packfile(), opts...).Parse()
+			require.ErrorIs(t, err, packfile.ErrObjectLimitExceeded)
+
+			var lerr *packfile.ObjectLimitError
+			require.ErrorAs(t, err, &lerr)
+			assert.Equal(t, tc.want, *lerr)
+		})
+	}
+
+	_, err := packfile.NewParser(fixtures.Basic().One().Packfile(),
+		packfile.WithObjectLimits(packfile.ObjectLimits{MaxBlobSize: 217848, MaxTreeEntries: 8})).Parse()
+	assert.NoError(t, err)
+}
+
+func TestParserObjectLimitsDelta(t *testing.T) {
+	t.Parallel()
+
+	base := []byte("a")
+	obj := &plumbing.MemoryObject{}
+	obj.SetType(plumbing.BlobObject)
+	_, _ = obj.Write(base)
+
+	// The delta declares a target of 200000 bytes, built from insert
+	// instructions compressing to a few hundred bytes.
+	const size = 200000
+	var delta []byte
+	delta = binary.AppendUvarint(delta, uint64(len(base)))
+	delta = binary.AppendUvarint(delta, size)
+	for n := size; n > 0; n -= 127 {
+		chunk := min(n, 127)
+		delta = append(delta, byte(chunk))
+		delta = append(delta, bytes.Repeat([]byte{'x'}, chunk)...)
+	}
+
+	var pack bytes.Buffer
+	pack.WriteString("PACK")
+	_ = binary.Write(&pack, binary.BigEndian, uint32(2))
+	_ = binary.Write(&pack, binary.BigEndian, uint32(2))
+	writePackObject(&pack, plumbing.BlobObject, base, nil)
+	writePackObject(&pack, plumbing.REFDeltaObject, delta, obj.Hash().Bytes())
+	sum := sha1.Sum(pack.Bytes())
+	pack.Write(sum[:])
+
+	_, err := packfile.NewParser(bytes.NewReader(pack.Bytes()),
+		packfile.WithObjectLimits(packfile.ObjectLimits{MaxBlobSize: 70000})).Parse()
+
+	var lerr *packfile.ObjectLimitError
+	require.ErrorAs(t, err, &lerr)
+	assert.Equal(t, packfile.ObjectLimitError{
+		Type:  plumbing.BlobObject,
+		Size:  size,
+		Limit: 70000,
+	}, *lerr)
+
+	_, err = packfile.NewParser(bytes.NewReader(pack.Bytes())).Parse()
+	assert.NoError(t, err)
+}
+
+// writePackObject writes to w the packfile entry of an object, given the
+// hash of its base if it is a REF_DELTA.
+func writePackObject(w *bytes.Buffer, t plumbing.ObjectType, content, base []byte) {
+	size := len(content)
+	c := byte(t)<<4 | byte(size&0x0f)
+	size >>= 4
+	for size > 0 {
+		w.WriteByte(c | 0x80)
+		c = byte(size & 0x7f)
+		size >>= 7
+	}
+
+	w.WriteByte(c)
+	w.Write(base)
+
+	zw := zlib.NewWriter(w)
+	_, _ = zw.Write(content)
+	_ = zw.Close()
+}
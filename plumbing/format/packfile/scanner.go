@@ -94,6 +94,9 @@ type Scanner struct {
 	// Note that delta objects are not stored.
 	storage storer.EncodedObjectStorer
 
+	// limits are the limits on the full objects found.
+	limits ObjectLimits
+
 	*scannerReader
 	rbuf *bufio.Reader
 
@@ -244,7 +247,7 @@ func (r *Scanner) inflateContent(contentOffset int64, writer io.Writer) error {
 
 	zr, err := gogitsync.GetZlibReader(r.scannerReader)
 	if err != nil {
-		return fmt.Errorf("zlib reset error: %s", err)
+		return fmt.Errorf("zlib reset error: %w", err)
 	}
 	defer gogitsync.PutZlibReader(zr)
 
@@ -406,25 +409,34 @@ func objectEntry(r *Scanner) (stateFn, error) {
 	}
 	defer gogitsync.PutZlibReader(zr)
 
+	// The blobs above the size limit are only hashed, to be named in the
+	// error, but neither stored nor kept in memory.
+	tooLarge := r.limits.check(plumbing.ZeroHash, oh.Type, oh.Size, nil) != nil
+
 	mw := io.Discard
+	var entries *treeEntryCounter
 	if !oh.Type.IsDelta() {
 		r.hasher.Reset(oh.Type, oh.Size)
 		mw = r.hasher
-		if r.storage != nil {
+		if entries = r.limits.entryCounter(oh.Type, r.objectIDSize); entries != nil {
+			mw = io.MultiWriter(mw, entries)
+		}
+
+		if r.storage != nil && !tooLarge {
 			w, err := r.storage.RawObjectWriter(oh.Type, oh.Size)
 			if err != nil {
 				return nil, err
 			}
 
 			defer func() { _ = w.Close() }()
-			mw = io.MultiWriter(r.hasher, w)
+			mw = io.MultiWriter(mw, w)
 		}
 	}
 
 	// If low memory mode isn't supported, and either the reader
 	// isn't seekable or this is a delta object, keep the contents
 	// of the objects in memory.
-	if !r.lowMemoryMode && (oh.Type.IsDelta() || r.seeker == nil) {
+	if !r.lowMemoryMode && !tooLarge && (oh.Type.IsDelta() || r.seeker == nil) {
 		oh.content = gogitsync.GetBytesBuffer()
 		mw = io.MultiWriter(mw, oh.content)
 	}
@@ -441,6 +453,9 @@ func objectEntry(r *Scanner) (stateFn, error) {
 	oh.Crc32 = r.crc.Sum32()
 	if !oh.Type.IsDelta() {
 		oh.Hash = r.hasher.Sum()
+		if err := r.limits.check(oh.Hash, oh.Type, oh.Size, entries); err != nil {
+			return nil, err
+		}
 	}
 
 	r.packData.Section = ObjectSection
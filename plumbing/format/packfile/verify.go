@@ -0,0 +1,206 @@
+package packfile
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
+)
+
+// VerifiedObject describes an object of a packfile, as listed by
+// git verify-pack -v.
+type VerifiedObject struct {
+	Hash plumbing.Hash
+	// Type is the type of the object, resolving its deltas.
+	Type plumbing.ObjectType
+	// Size is the size of the object, or of its delta data if it is stored
+	// as a delta.
+	Size int64
+	// PackedSize is the space used by the object in the packfile.
+	PackedSize int64
+	// Offset is the position of the object in the packfile.
+	Offset int64
+	// Depth is the length of the delta chain of the object, 0 if it is not
+	// stored as a delta.
+	Depth int
+	// Base is the hash of the object the delta applies to, zero if the
+	// object is not stored as a delta.
+	Base plumbing.Hash
+}
+
+// VerifyReport is the result of Verify.
+type VerifyReport struct {
+	// Checksum is the checksum of the packfile.
+	Checksum plumbing.Hash
+	// Objects are the objects of the packfile, sorted by offset.
+	Objects []VerifiedObject
+	// ChainLengths is the histogram of the delta chain lengths: the element
+	// n is the number of objects with a delta chain of length n, the first
+	// one being the number of objects not stored as deltas.
+	ChainLengths []int
+}
+
+// String returns the report in the format of git verify-pack -v.
+func (r *VerifyReport) String() string {
+	var b strings.Builder
+	for _, o := range r.Objects {
+		fmt.Fprintf(&b, "%s %-6s %d %d %d", o.Hash, o.Type, o.Size, o.PackedSize, o.Offset)
+		if o.Depth > 0 {
+			fmt.Fprintf(&b, " %d %s", o.Depth, o.Base)
+		}
+
+		b.WriteString("\n")
+	}
+
+	for n, count := range r.ChainLengths {
+		if count == 0 {
+			continue
+		}
+
+		objects := "objects"
+		if count == 1 {
+			objects = "object"
+		}
+
+		if n == 0 {
+			fmt.Fprintf(&b, "non delta: %d %s\n", count, objects)
+		} else {
+			fmt.Fprintf(&b, "chain length = %d: %d %s\n", n, count, objects)
+		}
+	}
+
+	return b.String()
+}
+
+// Verify decodes all the objects of the packfile, checking its checksum,
+// and reports their types, sizes and delta chains, as git verify-pack -v
+// does. The packfile is read twice, the parser options configuring the
+// decoding pass.
+func Verify(pack io.ReadSeeker, opts ...ParserOption) (*VerifyReport, error) {
+	objects := &verifyObserver{byOffset: map[int64]*VerifiedObject{}}
+	p := NewParser(pack, append(opts, WithScannerObservers(objects))...)
+	checksum, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := pack.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := pack.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var sopts []ScannerOption
+	if p.objectFormat == format.SHA256 {
+		sopts = append(sopts, WithSHA256())
+	}
+
+	s := NewScanner(pack, sopts...)
+	s.lowMemoryMode = true
+
+	var headers []ObjectHeader
+	for s.Scan() {
+		if d := s.Data(); d.Section == ObjectSection {
+			headers = append(headers, d.Value().(ObjectHeader))
+		}
+	}
+
+	if err := s.Error(); err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[plumbing.Hash]*VerifiedObject, len(headers))
+	for _, o := range objects.byOffset {
+		byHash[o.Hash] = o
+	}
+
+	report := &VerifyReport{Checksum: checksum}
+	bases := make(map[int64]ObjectHeader, len(headers))
+	for i, oh := range headers {
+		o, ok := objects.byOffset[oh.Offset]
+		if !ok {
+			return nil, fmt.Errorf("%w: object at offset %d not decoded", ErrInvalidObject, oh.Offset)
+		}
+
+		next := end - int64(p.objectFormat.Size())
+		if i+1 < len(headers) {
+			next = headers[i+1].Offset
+		}
+
+		o.Size = oh.Size
+		o.PackedSize = next - oh.Offset
+		bases[oh.Offset] = oh
+	}
+
+	var depth func(o *VerifiedObject) int
+	depth = func(o *VerifiedObject) int {
+		if o.Depth >= 0 {
+			return o.Depth
+		}
+
+		oh := bases[o.Offset]
+		var base *VerifiedObject
+		switch oh.Type {
+		case plumbing.OFSDeltaObject:
+			base = objects.byOffset[oh.OffsetReference]
+		case plumbing.REFDeltaObject:
+			base = byHash[oh.Reference]
+		default:
+			o.Depth = 0
+			return 0
+		}
+
+		if base == nil {
+			o.Depth, o.Base = 1, oh.Reference
+			return 1
+		}
+
+		o.Depth, o.Base = depth(base)+1, base.Hash
+		return o.Depth
+	}
+
+	for _, oh := range headers {
+		o := objects.byOffset[oh.Offset]
+		n := depth(o)
+		for len(report.ChainLengths) <= n {
+			report.ChainLengths = append(report.ChainLengths, 0)
+		}
+
+		report.ChainLengths[n]++
+		report.Objects = append(report.Objects, *o)
+	}
+
+	return report, nil
+}
+
+// verifyObserver collects the hashes and types of the objects decoded by
+// a Parser.
+type verifyObserver struct {
+	byOffset map[int64]*VerifiedObject
+}
+
+func (o *verifyObserver) OnHeader(uint32) error {
+	return nil
+}
+
+func (o *verifyObserver) OnInflatedObjectHeader(t plumbing.ObjectType, _, pos int64) error {
+	o.byOffset[pos] = &VerifiedObject{Type: t, Offset: pos, Depth: -1}
+	return nil
+}
+
+func (o *verifyObserver) OnInflatedObjectContent(h plumbing.Hash, pos int64, _ uint32, _ []byte) error {
+	if obj, ok := o.byOffset[pos]; ok {
+		obj.Hash = h
+	}
+
+	return nil
+}
+
+func (o *verifyObserver) OnFooter(plumbing.Hash) error {
+	return nil
+}
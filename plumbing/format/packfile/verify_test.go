@@ -0,0 +1,66 @@
+package packfile_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/packfile"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	f := fixtures.Basic().One()
+	report, err := packfile.Verify(f.Packfile())
+	require.NoError(t, err)
+
+	assert.Equal(t, plumbing.NewHash(f.PackfileHash), report.Checksum)
+	require.Len(t, report.Objects, 31)
+	assert.Equal(t, []int{23, 3, 4, 1}, report.ChainLengths)
+
+	assert.Equal(t, packfile.VerifiedObject{
+		Hash:       plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881"),
+		Type:       plumbing.CommitObject,
+		Size:       254,
+		PackedSize: 174,
+		Offset:     12,
+	}, report.Objects[0])
+
+	assert.Equal(t, packfile.VerifiedObject{
+		Hash:       plumbing.NewHash("aa9b383c260e1d05fbbf6b30a02914555e20c725"),
+		Type:       plumbing.TreeObject,
+		Size:       4,
+		PackedSize: 14,
+		Offset:     84760,
+		Depth:      3,
+		Base:       plumbing.NewHash("8dcef98b1d52143e1e2dbc458ffe38f925786bf2"),
+	}, report.Objects[30])
+
+	out := report.String()
+	assert.True(t, strings.HasPrefix(out,
+		"e8d3ffab552895c19b9fcf7aa264d277cde33881 commit 254 174 12\n"+
+			"6ecf0ef2c2dffb796033e5a02219af86ec6584e5 commit 93 100 186 1 e8d3ffab552895c19b9fcf7aa264d277cde33881\n"))
+	assert.True(t, strings.HasSuffix(out,
+		"non delta: 23 objects\n"+
+			"chain length = 1: 3 objects\n"+
+			"chain length = 2: 4 objects\n"+
+			"chain length = 3: 1 object\n"))
+}
+
+func TestVerifyCorrupted(t *testing.T) {
+	t.Parallel()
+
+	data, err := io.ReadAll(fixtures.Basic().One().Packfile())
+	require.NoError(t, err)
+
+	data[len(data)-1] ^= 0xff
+	_, err = packfile.Verify(bytes.NewReader(data))
+	assert.ErrorIs(t, err, packfile.ErrMalformedPackfile)
+}
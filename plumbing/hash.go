@@ -16,6 +16,45 @@ type Hash = ObjectID
 // ZeroHash is an ObjectID with value zero.
 var ZeroHash ObjectID
 
+var (
+	emptyTreeSHA1   = NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+	emptyTreeSHA256 = NewHash("6ef19b41225c5369f1c104d45d8d85efa9b057b53b14b4b9b939dd74decc5321")
+	emptyBlobSHA1   = NewHash("e69de29bb2d1d6434b8b29ae775ad8c2e48c5391")
+	emptyBlobSHA256 = NewHash("473a0f4c3be8a93681a267e3b1e9a7dcda1185436fe141f7749120a303721813")
+)
+
+// ZeroHashFor returns the ObjectID with value zero of the object format,
+// written as as many zeros as the hashes of the format. ZeroHash is the one
+// of SHA-1.
+func ZeroHashFor(f format.ObjectFormat) Hash {
+	var h Hash
+	if f == format.SHA256 {
+		h.format = f
+	}
+
+	return h
+}
+
+// EmptyTree returns the hash of the tree without entries in the object
+// format, which git uses to compare to nothing, as for a root commit.
+func EmptyTree(f format.ObjectFormat) Hash {
+	if f == format.SHA256 {
+		return emptyTreeSHA256
+	}
+
+	return emptyTreeSHA1
+}
+
+// EmptyBlob returns the hash of the blob without content in the object
+// format.
+func EmptyBlob(f format.ObjectFormat) Hash {
+	if f == format.SHA256 {
+		return emptyBlobSHA256
+	}
+
+	return emptyBlobSHA1
+}
+
 // NewHash return a new Hash based on a hexadecimal hash representation.
 // Invalid input results into an empty hash.
 //
@@ -1,9 +1,12 @@
 package plumbing
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
 )
 
 type HashSuite struct {
@@ -23,6 +26,22 @@ func (s *HashSuite) TestIsZero() {
 	s.False(hash.IsZero())
 }
 
+func (s *HashSuite) TestZeroHashFor() {
+	s.Equal(ZeroHash, ZeroHashFor(format.SHA1))
+	s.Equal("0000000000000000000000000000000000000000", ZeroHashFor(format.UnsetObjectFormat).String())
+	s.Equal(strings.Repeat("0", format.SHA256HexSize), ZeroHashFor(format.SHA256).String())
+	s.True(ZeroHashFor(format.SHA256).IsZero())
+}
+
+func (s *HashSuite) TestEmptyTreeAndBlob() {
+	for _, f := range []format.ObjectFormat{format.SHA1, format.SHA256} {
+		s.Equal(NewHasher(f, TreeObject, 0).Sum(), EmptyTree(f))
+		s.Equal(NewHasher(f, BlobObject, 0).Sum(), EmptyBlob(f))
+	}
+
+	s.Equal("4b825dc642cb6eb9a060e54bf8d69288fbee4904", EmptyTree(format.UnsetObjectFormat).String())
+}
+
 func (s *HashSuite) TestHashesSort() {
 	i := []Hash{
 		NewHash("2222222222222222222222222222222222222222"),
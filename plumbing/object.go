@@ -3,6 +3,7 @@ package plumbing
 
 import (
 	"errors"
+	"fmt"
 	"io"
 )
 
@@ -11,8 +12,35 @@ var (
 	ErrObjectNotFound = errors.New("object not found")
 	// ErrInvalidType is returned when an invalid object type is provided.
 	ErrInvalidType = errors.New("invalid object type")
+	// ErrObjectCorrupt is returned when the compressed data of an object
+	// can't be inflated, because it is truncated or corrupt.
+	ErrObjectCorrupt = errors.New("object corrupt")
 )
 
+// ObjectCorruptError is returned when the compressed data of an object can't
+// be inflated. It matches both ErrObjectCorrupt and the inflate error.
+type ObjectCorruptError struct {
+	// Hash is the hash of the object, zero if it is not known.
+	Hash Hash
+	// Source is where the object is stored: the path of a loose object, or
+	// the path of a packfile and the offset of the object in it.
+	Source string
+	// Err is the error returned when inflating the object.
+	Err error
+}
+
+func (e *ObjectCorruptError) Error() string {
+	if e.Hash.IsZero() {
+		return fmt.Sprintf("%s: %s: %s", ErrObjectCorrupt, e.Source, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s in %s: %s", ErrObjectCorrupt, e.Hash, e.Source, e.Err)
+}
+
+func (e *ObjectCorruptError) Unwrap() []error {
+	return []error{ErrObjectCorrupt, e.Err}
+}
+
 // EncodedObject is a generic representation of any git object.
 type EncodedObject interface {
 	Hash() Hash
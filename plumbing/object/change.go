@@ -92,7 +92,13 @@ func (c *Change) Patch() (*Patch, error) {
 // If context expires, an non-nil error will be returned.
 // Provided context must be non-nil.
 func (c *Change) PatchContext(ctx context.Context) (*Patch, error) {
-	return getPatchContext(ctx, "", c)
+	return getPatchContext(ctx, "", nil, c)
+}
+
+// PatchWithOptions returns a Patch with all the file changes in chunks,
+// computed with the given options. Provided context must be non-nil.
+func (c *Change) PatchWithOptions(ctx context.Context, opts *PatchOptions) (*Patch, error) {
+	return getPatchContext(ctx, "", opts, c)
 }
 
 func (c *Change) name() string {
@@ -155,5 +161,11 @@ func (c Changes) Patch() (*Patch, error) {
 // If context expires, an non-nil error will be returned.
 // Provided context must be non-nil.
 func (c Changes) PatchContext(ctx context.Context) (*Patch, error) {
-	return getPatchContext(ctx, "", c...)
+	return getPatchContext(ctx, "", nil, c...)
+}
+
+// PatchWithOptions returns a Patch with all the changes in chunks, computed
+// with the given options. Provided context must be non-nil.
+func (c Changes) PatchWithOptions(ctx context.Context, opts *PatchOptions) (*Patch, error) {
+	return getPatchContext(ctx, "", opts, c...)
 }
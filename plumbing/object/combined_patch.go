@@ -0,0 +1,576 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	dmp "github.com/sergi/go-diff/diffmatchpatch"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v6/plumbing/format/diff"
+	"github.com/go-git/go-git/v6/utils/diff"
+)
+
+// ErrNotMergeCommit is returned when a combined diff is requested for a
+// commit with less than two parents.
+var ErrNotMergeCommit = errors.New("not a merge commit")
+
+// combinedContextLines is the number of unchanged lines shown around the
+// changes of the combined diffs, as git does by default.
+const combinedContextLines = 3
+
+// CombinedPatch is the combined diff of a merge commit with all its parents,
+// as git show --cc gives it. Only the files differing from every parent are
+// included, and only their hunks differing from every parent: the hunks of
+// a file taken as is from one of the parents are omitted.
+type CombinedPatch struct {
+	filePatches []*CombinedFilePatch
+}
+
+// FilePatches returns the combined diffs of the files.
+func (p *CombinedPatch) FilePatches() []*CombinedFilePatch {
+	return p.filePatches
+}
+
+// Encode writes the combined diff in the format of git diff --cc.
+func (p *CombinedPatch) Encode(w io.Writer) error {
+	for _, fp := range p.filePatches {
+		if err := fp.encode(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String returns the combined diff in the format of git diff --cc.
+func (p *CombinedPatch) String() string {
+	var buf bytes.Buffer
+	if err := p.Encode(&buf); err != nil {
+		return fmt.Sprintf("malformed combined patch: %s", err.Error())
+	}
+
+	return buf.String()
+}
+
+// CombinedFile is a version of a file of a combined diff.
+type CombinedFile struct {
+	// Hash is the hash of the blob of the file, or of the commit of a
+	// submodule.
+	Hash plumbing.Hash
+	// Mode is the mode of the file.
+	Mode filemode.FileMode
+}
+
+// CombinedFilePatch is the combined diff of a file of a merge commit with
+// the versions of all its parents.
+type CombinedFilePatch struct {
+	// Path is the path of the file.
+	Path string
+	// Parents are the versions of the file of the parents, in their order,
+	// nil for the parents missing the file.
+	Parents []*CombinedFile
+	// File is the version of the file of the merge commit, nil if it was
+	// deleted.
+	File *CombinedFile
+	// IsBinary is true if any version of the file is binary, in which case
+	// there are no hunks.
+	IsBinary bool
+	// Hunks are the hunks differing from every parent.
+	Hunks []*CombinedHunk
+}
+
+// CombinedHunk is a hunk of a combined diff.
+type CombinedHunk struct {
+	// ParentStarts are the numbers of the first lines of the hunk in each
+	// parent, and ParentCounts the numbers of lines of the hunk in them.
+	ParentStarts, ParentCounts []int
+	// Start is the number of the first line of the hunk in the merge
+	// commit, and Count its number of lines there.
+	Start, Count int
+	// Lines are the lines of the hunk.
+	Lines []*CombinedLine
+}
+
+// CombinedLine is a line of a combined diff.
+type CombinedLine struct {
+	// Content is the line, without its line ending.
+	Content string
+	// Ops are the operations of the line relative to each parent: Add for
+	// the lines of the merge commit missing from the parent, Delete for the
+	// lines of the parent missing from the merge commit, and Equal for the
+	// others.
+	Ops []fdiff.Operation
+}
+
+// IsRemoved returns whether the line was removed from one of the parents,
+// being missing from the merge commit.
+func (l *CombinedLine) IsRemoved() bool {
+	for _, op := range l.Ops {
+		if op == fdiff.Delete {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *CombinedLine) changed() bool {
+	for _, op := range l.Ops {
+		if op != fdiff.Equal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CombinedPatch returns the combined diff of the merge commit with all its
+// parents, as git show --cc gives it. It returns ErrNotMergeCommit if the
+// commit has less than two parents.
+func (c *Commit) CombinedPatch() (*CombinedPatch, error) {
+	return c.CombinedPatchContext(context.Background())
+}
+
+// CombinedPatchContext returns the combined diff of the merge commit with all
+// its parents, as CombinedPatch does. An error will be returned if the
+// context expires. Provided context must be non-nil.
+func (c *Commit) CombinedPatchContext(ctx context.Context) (*CombinedPatch, error) {
+	if c.NumParents() < 2 {
+		return nil, ErrNotMergeCommit
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var parents []*Tree
+	err = c.Parents().ForEach(func(p *Commit) error {
+		t, err := p.Tree()
+		if err != nil {
+			return err
+		}
+
+		parents = append(parents, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The paths changed relative to every parent, in the order of the
+	// changes relative to the first one.
+	var paths []string
+	counts := make(map[string]int)
+	for i, parent := range parents {
+		changes, err := DiffTreeContext(ctx, parent, tree)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ch := range changes {
+			name := ch.name()
+			counts[name]++
+			if i == 0 {
+				paths = append(paths, name)
+			}
+		}
+	}
+
+	p := &CombinedPatch{}
+	for _, path := range paths {
+		if counts[path] != len(parents) {
+			continue
+		}
+
+		fp, err := combinedFilePatch(ctx, path, tree, parents)
+		if err != nil {
+			return nil, err
+		}
+
+		if fp != nil {
+			p.filePatches = append(p.filePatches, fp)
+		}
+	}
+
+	return p, nil
+}
+
+// combinedFilePatch returns the combined diff of the file at path of tree
+// with the versions of the parents, nil if none of its hunks differs from
+// every parent.
+func combinedFilePatch(ctx context.Context, path string, tree *Tree, parents []*Tree) (*CombinedFilePatch, error) {
+	fp := &CombinedFilePatch{Path: path}
+
+	file, content, isBinary, err := combinedFileContent(tree, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fp.File = file
+	fp.IsBinary = isBinary
+
+	contents := make([]string, len(parents))
+	for i, parent := range parents {
+		pf, pc, isBinary, err := combinedFileContent(parent, path)
+		if err != nil {
+			return nil, err
+		}
+
+		fp.Parents = append(fp.Parents, pf)
+		fp.IsBinary = fp.IsBinary || isBinary
+		contents[i] = pc
+	}
+
+	if fp.IsBinary {
+		return fp, nil
+	}
+
+	rows, err := combinedRows(ctx, content, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	fp.Hunks = combinedHunks(rows, len(parents))
+	if len(fp.Hunks) == 0 {
+		return nil, nil
+	}
+
+	return fp, nil
+}
+
+// combinedFileContent returns the version of the file at path of the tree,
+// with its content, nil if it is missing. The content of a submodule is the
+// commit it points to, as git shows it.
+func combinedFileContent(t *Tree, path string) (f *CombinedFile, content string, isBinary bool, err error) {
+	e, err := t.FindEntry(path)
+	if errors.Is(err, ErrEntryNotFound) || errors.Is(err, ErrDirectoryNotFound) {
+		return nil, "", false, nil
+	}
+
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	f = &CombinedFile{Hash: e.Hash, Mode: e.Mode}
+	switch {
+	case e.Mode == filemode.Submodule:
+		return f, fmt.Sprintf("Subproject commit %s\n", e.Hash), false, nil
+	case !e.Mode.IsFile():
+		return f, "", false, nil
+	}
+
+	tf, err := t.TreeEntryFile(e)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	content, isBinary, err = fileContent(tf)
+	return f, content, isBinary, err
+}
+
+// combinedRows returns the lines of the combined diff of the content with
+// the ones of the parents: the lines of the content, preceded by the lines
+// removed from the parents before them. The lines removed from several
+// parents are shown once.
+func combinedRows(ctx context.Context, content string, parents []string) ([]*CombinedLine, error) {
+	lines := splitLines(content)
+	result := make([]*CombinedLine, len(lines))
+	for k, line := range lines {
+		result[k] = &CombinedLine{Content: line, Ops: make([]fdiff.Operation, len(parents))}
+	}
+
+	// removed[k] are the lines removed before the line k of the content.
+	removed := make([][]*CombinedLine, len(lines)+1)
+	for i, parent := range parents {
+		k := 0
+		for _, d := range diff.Do(parent, content) {
+			select {
+			case <-ctx.Done():
+				return nil, ErrCanceled
+			default:
+			}
+
+			dl := splitLines(d.Text)
+			switch d.Type {
+			case dmp.DiffEqual:
+				k += len(dl)
+			case dmp.DiffInsert:
+				for range dl {
+					result[k].Ops[i] = fdiff.Add
+					k++
+				}
+			case dmp.DiffDelete:
+				removed[k] = addRemovedLines(removed[k], dl, i, len(parents))
+			}
+		}
+	}
+
+	var rows []*CombinedLine
+	for k, rm := range removed {
+		rows = append(rows, rm...)
+		if k < len(result) {
+			rows = append(rows, result[k])
+		}
+	}
+
+	return rows, nil
+}
+
+// addRemovedLines adds the lines removed from the parent i to the ones
+// removed from the other parents at the same place, sharing the identical
+// lines in the same order. The other lines are added before the next shared
+// one.
+func addRemovedLines(removed []*CombinedLine, lines []string, i, parents int) []*CombinedLine {
+	var result, pending []*CombinedLine
+	next := 0
+	for _, line := range lines {
+		j := next
+		for j < len(removed) && removed[j].Content != line {
+			j++
+		}
+
+		if j == len(removed) {
+			l := &CombinedLine{Content: line, Ops: make([]fdiff.Operation, parents)}
+			l.Ops[i] = fdiff.Delete
+			pending = append(pending, l)
+			continue
+		}
+
+		result = append(result, removed[next:j]...)
+		result = append(result, pending...)
+		removed[j].Ops[i] = fdiff.Delete
+		result = append(result, removed[j])
+		pending = nil
+		next = j + 1
+	}
+
+	result = append(result, removed[next:]...)
+	return append(result, pending...)
+}
+
+// combinedHunks returns the hunks of the rows differing from every parent,
+// with their context.
+func combinedHunks(rows []*CombinedLine, parents int) []*CombinedHunk {
+	// The runs of changed rows differing from every parent.
+	var runs [][2]int
+	for start := 0; start < len(rows); {
+		if !rows[start].changed() {
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(rows) && rows[end].changed() {
+			end++
+		}
+
+		if differsFromAll(rows[start:end], parents) {
+			runs = append(runs, [2]int{start, end})
+		}
+
+		start = end
+	}
+
+	var hunks []*CombinedHunk
+	for i := 0; i < len(runs); {
+		start := contextStart(rows, runs[i][0], combinedContextLines)
+		end := runs[i][1]
+		for i++; i < len(runs) && contextStart(rows, runs[i][0], 2*combinedContextLines) <= end; i++ {
+			end = runs[i][1]
+		}
+
+		end = contextEnd(rows, end, combinedContextLines)
+		hunks = append(hunks, newCombinedHunk(rows, start, end, parents))
+	}
+
+	return hunks
+}
+
+// contextStart returns the first row of the context of n lines of the merge
+// commit before the row start, with the lines removed between them.
+func contextStart(rows []*CombinedLine, start, n int) int {
+	for start > 0 && n > 0 {
+		start--
+		if !rows[start].IsRemoved() {
+			n--
+		}
+	}
+
+	return start
+}
+
+// contextEnd returns the end of the context of n lines of the merge commit
+// after the row end, with the lines removed between them.
+func contextEnd(rows []*CombinedLine, end, n int) int {
+	for end < len(rows) && n > 0 {
+		if !rows[end].IsRemoved() {
+			n--
+		}
+
+		end++
+	}
+
+	return end
+}
+
+// differsFromAll returns whether the rows hold changes relative to every
+// parent.
+func differsFromAll(rows []*CombinedLine, parents int) bool {
+	for i := range parents {
+		changed := false
+		for _, r := range rows {
+			if r.Ops[i] != fdiff.Equal {
+				changed = true
+				break
+			}
+		}
+
+		if !changed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newCombinedHunk returns the hunk of the rows from start to end, numbering
+// their lines in each version of the file.
+func newCombinedHunk(rows []*CombinedLine, start, end, parents int) *CombinedHunk {
+	h := &CombinedHunk{
+		ParentStarts: make([]int, parents),
+		ParentCounts: make([]int, parents),
+		Lines:        rows[start:end],
+	}
+
+	for k, r := range rows[:end] {
+		inHunk := k >= start
+		removed := r.IsRemoved()
+		for i, op := range r.Ops {
+			if removed && op != fdiff.Delete || !removed && op != fdiff.Equal {
+				// The line is missing from the parent.
+				continue
+			}
+
+			if inHunk {
+				h.ParentCounts[i]++
+			} else {
+				h.ParentStarts[i]++
+			}
+		}
+
+		if removed {
+			continue
+		}
+
+		if inHunk {
+			h.Count++
+		} else {
+			h.Start++
+		}
+	}
+
+	for i := range h.ParentStarts {
+		h.ParentStarts[i]++
+	}
+
+	h.Start++
+
+	return h
+}
+
+// splitLines returns the lines of the text, without their line endings.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func (fp *CombinedFilePatch) encode(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --cc %s\n", fp.Path)
+
+	hashes := make([]string, len(fp.Parents))
+	modes := make([]string, len(fp.Parents))
+	missing := true
+	modeChanged := false
+	for i, pf := range fp.Parents {
+		hashes[i], modes[i] = combinedFileHeader(pf)
+		missing = missing && pf == nil
+		modeChanged = modeChanged || (pf != nil && fp.File != nil && pf.Mode != fp.File.Mode)
+	}
+
+	hash, mode := combinedFileHeader(fp.File)
+	fmt.Fprintf(&b, "index %s..%s\n", strings.Join(hashes, ","), hash)
+	switch {
+	case fp.File == nil:
+		fmt.Fprintf(&b, "deleted file mode %s\n", strings.Join(modes, ","))
+	case missing:
+		fmt.Fprintf(&b, "new file mode %s\n", mode)
+	case modeChanged:
+		fmt.Fprintf(&b, "mode %s..%s\n", strings.Join(modes, ","), mode)
+	}
+
+	if fp.IsBinary {
+		b.WriteString("Binary files differ\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	from, to := "a/"+fp.Path, "b/"+fp.Path
+	if missing {
+		from = "/dev/null"
+	}
+
+	if fp.File == nil {
+		to = "/dev/null"
+	}
+
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+
+	marker := strings.Repeat("@", len(fp.Parents)+1)
+	for _, h := range fp.Hunks {
+		b.WriteString(marker)
+		for i := range fp.Parents {
+			fmt.Fprintf(&b, " -%d,%d", h.ParentStarts[i], h.ParentCounts[i])
+		}
+
+		fmt.Fprintf(&b, " +%d,%d %s\n", h.Start, h.Count, marker)
+
+		for _, l := range h.Lines {
+			for _, op := range l.Ops {
+				switch op {
+				case fdiff.Add:
+					b.WriteByte('+')
+				case fdiff.Delete:
+					b.WriteByte('-')
+				default:
+					b.WriteByte(' ')
+				}
+			}
+
+			b.WriteString(l.Content + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// combinedFileHeader returns the abbreviated hash and the mode of the file,
+// as shown in the header of a combined diff.
+func combinedFileHeader(f *CombinedFile) (hash, mode string) {
+	if f == nil {
+		return strings.Repeat("0", 7), "000000"
+	}
+
+	return f.Hash.String()[:7], fmt.Sprintf("%06o", uint32(f.Mode))
+}
@@ -0,0 +1,155 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v6/plumbing/format/diff"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestCombinedPatch(t *testing.T) {
+	t.Parallel()
+
+	s := memory.NewStorage()
+	base := writeCommit(t, s, map[string]string{
+		"f":     "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+		"other": "o\n",
+	})
+	topic := writeCommit(t, s, map[string]string{
+		"f":     "a\nB2\nc\nd\ne\nf\ng\nh\ni\nj\n",
+		"other": "o\n",
+	}, base)
+	main := writeCommit(t, s, map[string]string{
+		"f":     "a\nb\nc\nd\ne\nf\ng\nh\ni\nJ1\n",
+		"other": "O\n",
+	}, base)
+	merge := writeCommit(t, s, map[string]string{
+		"f":     "a\nB2\nc\nx\nd\ne\nf\ng\nh\ni\nJ1\n",
+		"n":     "new\n",
+		"other": "O\n",
+	}, main, topic)
+
+	c, err := object.GetCommit(s, merge)
+	require.NoError(t, err)
+
+	p, err := c.CombinedPatch()
+	require.NoError(t, err)
+
+	// The output of git show --cc of the same merge.
+	assert.Equal(t, "diff --cc f\n"+
+		"index 68d92cb,edf1d1b..29d6136\n"+
+		"--- a/f\n"+
+		"+++ b/f\n"+
+		"@@@ -1,6 -1,6 +1,7 @@@\n"+
+		"  a\n"+
+		"- b\n"+
+		"+ B2\n"+
+		"  c\n"+
+		"++x\n"+
+		"  d\n"+
+		"  e\n"+
+		"  f\n"+
+		"diff --cc n\n"+
+		"index 0000000,0000000..3e75765\n"+
+		"new file mode 100644\n"+
+		"--- /dev/null\n"+
+		"+++ b/n\n"+
+		"@@@ -1,0 -1,0 +1,1 @@@\n"+
+		"++new\n", p.String())
+
+	fps := p.FilePatches()
+	require.Len(t, fps, 2)
+	assert.Equal(t, "f", fps[0].Path)
+	require.Len(t, fps[0].Parents, 2)
+	assert.Equal(t, filemode.Regular, fps[0].File.Mode)
+	require.Len(t, fps[0].Hunks, 1)
+	assert.Equal(t, []int{1, 1}, fps[0].Hunks[0].ParentStarts)
+	assert.Equal(t, []fdiff.Operation{fdiff.Add, fdiff.Add}, fps[0].Hunks[0].Lines[4].Ops)
+	assert.Nil(t, fps[1].Parents[0])
+
+	c, err = object.GetCommit(s, topic)
+	require.NoError(t, err)
+
+	_, err = c.CombinedPatch()
+	assert.ErrorIs(t, err, object.ErrNotMergeCommit)
+}
+
+func TestCombinedPatchRemoved(t *testing.T) {
+	t.Parallel()
+
+	s := memory.NewStorage()
+	base := writeCommit(t, s, map[string]string{"f": "a\nb\nc\n", "g": "g\n"})
+	p1 := writeCommit(t, s, map[string]string{"f": "a\nb1\nc\n", "g": "g1\n"}, base)
+	p2 := writeCommit(t, s, map[string]string{"f": "a\nb2\nc\n", "g": "g2\n"}, base)
+	merge := writeCommit(t, s, map[string]string{"f": "a\nb\nc\n"}, p1, p2)
+
+	c, err := object.GetCommit(s, merge)
+	require.NoError(t, err)
+
+	p, err := c.CombinedPatch()
+	require.NoError(t, err)
+
+	assert.Equal(t, "diff --cc f\n"+
+		"index 7336c47,59362d4..de98044\n"+
+		"--- a/f\n"+
+		"+++ b/f\n"+
+		"@@@ -1,3 -1,3 +1,3 @@@\n"+
+		"  a\n"+
+		"- b1\n"+
+		" -b2\n"+
+		"++b\n"+
+		"  c\n"+
+		"diff --cc g\n"+
+		"index d8a17ff,247c4ab..0000000\n"+
+		"deleted file mode 100644,100644\n"+
+		"--- a/g\n"+
+		"+++ /dev/null\n"+
+		"@@@ -1,1 -1,1 +1,0 @@@\n"+
+		"- g1\n"+
+		" -g2\n", p.String())
+}
+
+// writeCommit writes a commit of the files, with the given parents.
+func writeCommit(t *testing.T, s storer.EncodedObjectStorer, files map[string]string, parents ...plumbing.Hash) plumbing.Hash {
+	t.Helper()
+
+	tree := &object.Tree{}
+	for _, name := range []string{"f", "g", "n", "other"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+
+		obj := s.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		h, err := s.SetEncodedObject(obj)
+		require.NoError(t, err)
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: h})
+	}
+
+	obj := s.NewEncodedObject()
+	require.NoError(t, tree.Encode(obj))
+	treeHash, err := s.SetEncodedObject(obj)
+	require.NoError(t, err)
+
+	sig := object.Signature{Name: "foo", Email: "foo@example.com"}
+	c := &object.Commit{Author: sig, Committer: sig, Message: "commit\n", TreeHash: treeHash, ParentHashes: parents}
+	obj = s.NewEncodedObject()
+	require.NoError(t, c.Encode(obj))
+	h, err := s.SetEncodedObject(obj)
+	require.NoError(t, err)
+	return h
+}
@@ -66,6 +66,11 @@ type Commit struct {
 	ExtraHeaders []ExtraHeader
 
 	s storer.EncodedObjectStorer
+
+	// raw holds the bytes of a commit decoded with DecodeLenient, written
+	// as is by Encode as long as the commit is unchanged from decoded.
+	raw     []byte
+	decoded *Commit
 }
 
 // ExtraHeader holds any non-standard header
@@ -142,6 +147,12 @@ func (c *Commit) Tree() (*Tree, error) {
 // NOTE: Since version 5.1.0 the renames are correctly handled, the settings
 // used are the recommended options DefaultDiffTreeOptions.
 func (c *Commit) PatchContext(ctx context.Context, to *Commit) (*Patch, error) {
+	return c.PatchWithOptions(ctx, to, nil)
+}
+
+// PatchWithOptions returns the Patch between the actual commit and the
+// provided one, as PatchContext does, computed with the given options.
+func (c *Commit) PatchWithOptions(ctx context.Context, to *Commit, opts *PatchOptions) (*Patch, error) {
 	fromTree, err := c.Tree()
 	if err != nil {
 		return nil, err
@@ -155,7 +166,7 @@ func (c *Commit) PatchContext(ctx context.Context, to *Commit) (*Patch, error) {
 		}
 	}
 
-	return fromTree.PatchContext(ctx, toTree)
+	return fromTree.PatchWithOptions(ctx, toTree, opts)
 }
 
 // Patch returns the Patch between the actual commit and the provided one.
@@ -229,14 +240,19 @@ func (c *Commit) Type() plumbing.ObjectType {
 
 // Decode transforms a plumbing.EncodedObject into a Commit struct.
 func (c *Commit) Decode(o plumbing.EncodedObject) (err error) {
+	return c.decode(o, nil)
+}
+
+func (c *Commit) decode(o plumbing.EncodedObject, ld *lenientDecoder) (err error) {
 	if o.Type() != plumbing.CommitObject {
 		return ErrUnsupportedObject
 	}
 
 	c.Hash = o.Hash()
 	c.Encoding = defaultUtf8CommitMessageEncoding
+	c.raw, c.decoded = nil, nil
 
-	reader, err := o.Reader()
+	reader, err := ld.reader(o)
 	if err != nil {
 		return err
 	}
@@ -256,6 +272,8 @@ func (c *Commit) Decode(o plumbing.EncodedObject) (err error) {
 			return err
 		}
 
+		ld.nextLine()
+
 		if mergetag {
 			if len(line) > 0 && line[0] == ' ' {
 				line = bytes.TrimLeft(line, " ")
@@ -299,15 +317,19 @@ func (c *Commit) Decode(o plumbing.EncodedObject) (err error) {
 				data = split[1]
 			}
 
+			if ld.duplicate(string(split[0])) {
+				continue
+			}
+
 			switch string(split[0]) {
 			case "tree":
-				c.TreeHash = plumbing.NewHash(string(data))
+				c.TreeHash = ld.hash(data)
 			case "parent":
-				c.ParentHashes = append(c.ParentHashes, plumbing.NewHash(string(data)))
+				c.ParentHashes = append(c.ParentHashes, ld.hash(data))
 			case "author":
-				c.Author.Decode(data)
+				ld.signature(&c.Author, data)
 			case "committer":
-				c.Committer.Decode(data)
+				ld.signature(&c.Committer, data)
 			case headermergetag:
 				c.MergeTag += string(data) + "\n"
 				mergetag = true
@@ -329,10 +351,20 @@ func (c *Commit) Decode(o plumbing.EncodedObject) (err error) {
 		}
 
 		if err == io.EOF {
+			if !message {
+				ld.warn("missing blank line before the message")
+			}
+
 			break
 		}
 	}
 	c.Message = msgbuf.String()
+
+	if ld != nil {
+		c.raw = ld.raw
+		c.decoded = c.snapshot()
+	}
+
 	return nil
 }
 
@@ -355,6 +387,11 @@ func (c *Commit) encode(o plumbing.EncodedObject, includeSig bool) (err error) {
 
 	defer ioutil.CheckClose(w, &err)
 
+	if includeSig && c.raw != nil && c.unchanged() {
+		_, err = w.Write(c.raw)
+		return err
+	}
+
 	if _, err = fmt.Fprintf(w, "tree %s\n", c.TreeHash.String()); err != nil {
 		return err
 	}
@@ -87,6 +87,30 @@ func (s *SuiteCommit) TestParentNotFound() {
 	s.Nil(commit)
 }
 
+func (s *SuiteCommit) TestPatchID() {
+	// The expected patch-ids are the ones of git patch-id --stable.
+	for commit, expected := range map[string]string{
+		"b029517f6300c2da0f4b651b8642506cd6aaf45d": "b232dee86c61f46101a4c0df249440e1785dc785",
+		"6ecf0ef2c2dffb796033e5a02219af86ec6584e5": "f918db3e4e98c331403ae6de1e2abc3a85f9fa6c",
+		"e8d3ffab552895c19b9fcf7aa264d277cde33881": "5709e691eb6cf10d6011cbca565d3a8983899409",
+	} {
+		id, err := s.commit(plumbing.NewHash(commit)).PatchID()
+		s.NoError(err)
+		s.Equal(expected, id.String(), commit)
+	}
+
+	// The patch-id does not depend on the metadata of the commit.
+	c := *s.commit(plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881"))
+	c.Message = "another message"
+	c.Author.When = c.Author.When.Add(time.Hour)
+	id, err := c.PatchID()
+	s.NoError(err)
+	s.Equal("5709e691eb6cf10d6011cbca565d3a8983899409", id.String())
+
+	_, err = s.Commit.PatchID()
+	s.ErrorIs(err, ErrPatchIDMergeCommit)
+}
+
 func (s *SuiteCommit) TestPatch() {
 	from := s.commit(plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))
 	to := s.commit(plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"))
@@ -744,3 +768,49 @@ func (s *SuiteCommit) TestLess() {
 		s.Equal(t.Exp, commit1.Less(commit2))
 	}
 }
+
+func (s *SuiteCommit) TestDecodeLenient() {
+	raw := "tree eba74343e2f15d62adedfd8c883ee0262b5c8021\n" +
+		"tree 0000000000000000000000000000000000000000\n" +
+		"author John Doe <john@example.com> 1474485215 +530\n" +
+		"committer John Doe <john@example.com> 1474485215 +05:30\n" +
+		"\n" +
+		"message without trailing newline"
+
+	obj := &plumbing.MemoryObject{}
+	obj.SetType(plumbing.CommitObject)
+	_, err := obj.Write([]byte(raw))
+	s.Require().NoError(err)
+
+	commit := &Commit{}
+	warnings, err := commit.DecodeLenient(obj)
+	s.Require().NoError(err)
+	s.Equal([]DecodeWarning{
+		{Line: 2, Message: `duplicate "tree" header ignored`},
+		{Line: 3, Message: `non-standard timezone "+530" in signature`},
+		{Line: 4, Message: `non-standard timezone "+05:30" in signature`},
+		{Message: "missing trailing newline"},
+	}, warnings)
+
+	s.Equal("eba74343e2f15d62adedfd8c883ee0262b5c8021", commit.TreeHash.String())
+	_, offset := commit.Author.When.Zone()
+	s.Equal(5*3600+30*60, offset)
+	_, offset = commit.Committer.When.Zone()
+	s.Equal(5*3600+30*60, offset)
+	s.Equal("message without trailing newline", commit.Message)
+
+	encoded := &plumbing.MemoryObject{}
+	s.Require().NoError(commit.Encode(encoded))
+	s.Equal(obj.Hash(), encoded.Hash())
+
+	commit.Message = "changed\n"
+	encoded = &plumbing.MemoryObject{}
+	s.Require().NoError(commit.Encode(encoded))
+	s.NotEqual(obj.Hash(), encoded.Hash())
+
+	strict := &Commit{}
+	s.Require().NoError(strict.Decode(obj))
+	s.Equal("0000000000000000000000000000000000000000", strict.TreeHash.String())
+	_, offset = strict.Author.When.Zone()
+	s.Equal(0, offset)
+}
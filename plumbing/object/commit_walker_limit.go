@@ -3,6 +3,8 @@ package object
 import (
 	"errors"
 	"io"
+	"regexp"
+	"slices"
 	"time"
 
 	"github.com/go-git/go-git/v6/plumbing"
@@ -19,6 +21,39 @@ type LogLimitOptions struct {
 	Since    *time.Time
 	Until    *time.Time
 	TailHash plumbing.Hash
+
+	// Author and Committer, if set, skip the commits whose author or
+	// committer, as "Name <email>", do not match them. MessageGrep, if set,
+	// skips the commits whose message matches none of the regexps, or not
+	// all of them if AllMatch is set. As with git log, a commit is kept
+	// only if it passes all of those filters.
+	Author      *regexp.Regexp
+	Committer   *regexp.Regexp
+	MessageGrep []*regexp.Regexp
+	AllMatch    bool
+}
+
+// matches returns whether the commit matches the Author, Committer and
+// MessageGrep filters.
+func (o *LogLimitOptions) matches(c *Commit) bool {
+	if o.Author != nil && !o.Author.MatchString(c.Author.String()) {
+		return false
+	}
+
+	if o.Committer != nil && !o.Committer.MatchString(c.Committer.String()) {
+		return false
+	}
+
+	if len(o.MessageGrep) == 0 {
+		return true
+	}
+
+	match := func(re *regexp.Regexp) bool { return re.MatchString(c.Message) }
+	if o.AllMatch {
+		return !slices.ContainsFunc(o.MessageGrep, func(re *regexp.Regexp) bool { return !match(re) })
+	}
+
+	return slices.ContainsFunc(o.MessageGrep, match)
 }
 
 // NewCommitLimitIterFromIter creates a new commit iterator with limits applied.
@@ -42,6 +77,13 @@ func (c *commitLimitIter) Next() (*Commit, error) {
 		if c.limitOptions.Until != nil && commit.Committer.When.After(*c.limitOptions.Until) {
 			continue
 		}
+		if !c.limitOptions.matches(commit) {
+			if c.limitOptions.TailHash == commit.Hash {
+				return nil, io.EOF
+			}
+
+			continue
+		}
 		if c.limitOptions.TailHash == commit.Hash {
 			return commit, storer.ErrStop
 		}
@@ -0,0 +1,192 @@
+package commitgraph
+
+import (
+	"math"
+
+	"github.com/emirpasic/gods/trees/binaryheap"
+
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// paint flags of the commits visited by MergeBase.
+const (
+	paintParent1 uint8 = 1 << iota
+	paintParent2
+	paintStale
+	paintResult
+)
+
+// IsAncestor returns true if c is an ancestor of other, or other itself. It
+// mimics the behavior of `git merge-base --is-ancestor c other`.
+//
+// The generation numbers of the commit-graph are used to prune the walk: a
+// commit is never reachable from a commit of a lower generation, so c is
+// rejected without walking if its generation is greater than the one of
+// other, and the history below the generation of c is not walked.
+func IsAncestor(c, other CommitNode) (bool, error) {
+	gen := c.Generation()
+	seen := map[plumbing.Hash]struct{}{other.ID(): {}}
+	queue := []CommitNode{other}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n.ID() == c.ID() {
+			return true, nil
+		}
+
+		if cannotReach(n, gen) {
+			continue
+		}
+
+		for i, h := range n.ParentHashes() {
+			if _, ok := seen[h]; ok {
+				continue
+			}
+
+			seen[h] = struct{}{}
+			p, err := n.ParentNode(i)
+			if err != nil {
+				return false, err
+			}
+
+			queue = append(queue, p)
+		}
+	}
+
+	return false, nil
+}
+
+// MergeBase returns the best common ancestors of c and other, the common
+// ancestors not reachable from other common ancestors. It mimics the behavior
+// of `git merge-base --all c other`.
+//
+// The commits are walked by descending generation number, as git does, so the
+// walk stops as soon as the remaining commits are all known to be reachable
+// from a common ancestor.
+func MergeBase(c, other CommitNode) ([]CommitNode, error) {
+	if c.ID() == other.ID() {
+		return []CommitNode{c}, nil
+	}
+
+	flags := map[plumbing.Hash]uint8{
+		c.ID():     paintParent1,
+		other.ID(): paintParent2,
+	}
+
+	queue := binaryheap.NewWith(func(a, b any) int {
+		return compareByGeneration(a.(CommitNode), b.(CommitNode))
+	})
+	queue.Push(c)
+	queue.Push(other)
+
+	var result []CommitNode
+	for hasNonStale(queue, flags) {
+		v, _ := queue.Pop()
+		n := v.(CommitNode)
+
+		f := flags[n.ID()] & (paintParent1 | paintParent2 | paintStale)
+		if f == paintParent1|paintParent2 {
+			if flags[n.ID()]&paintResult == 0 {
+				flags[n.ID()] |= paintResult
+				result = append(result, n)
+			}
+
+			f |= paintStale
+		}
+
+		for i, h := range n.ParentHashes() {
+			if flags[h]&f == f {
+				continue
+			}
+
+			p, err := n.ParentNode(i)
+			if err != nil {
+				return nil, err
+			}
+
+			flags[h] |= f
+			queue.Push(p)
+		}
+	}
+
+	var bases []CommitNode
+	for _, n := range result {
+		if flags[n.ID()]&paintStale == 0 {
+			bases = append(bases, n)
+		}
+	}
+
+	return independents(bases)
+}
+
+// independents returns the commits not reachable from the other ones.
+func independents(commits []CommitNode) ([]CommitNode, error) {
+	if len(commits) < 2 {
+		return commits, nil
+	}
+
+	var res []CommitNode
+	for i, c := range commits {
+		redundant := false
+		for j, other := range commits {
+			if i == j {
+				continue
+			}
+
+			ok, err := IsAncestor(c, other)
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				redundant = true
+				break
+			}
+		}
+
+		if !redundant {
+			res = append(res, c)
+		}
+	}
+
+	return res, nil
+}
+
+// cannotReach returns whether the commit n can't reach a commit of
+// generation gen, other than itself. The ancestors of a commit of the
+// commit-graph have a lower generation than its own. The commits outside the
+// commit-graph, with the highest generation, and the ones written by old
+// versions of git, with a zero generation, are unknown.
+func cannotReach(n CommitNode, gen uint64) bool {
+	g := n.Generation()
+	return g != 0 && g != math.MaxUint64 && g <= gen
+}
+
+// compareByGeneration orders the commits by descending generation, then by
+// descending commit time.
+func compareByGeneration(a, b CommitNode) int {
+	ga, gb := a.Generation(), b.Generation()
+	switch {
+	case ga > gb:
+		return -1
+	case ga < gb:
+		return 1
+	case a.CommitTime().After(b.CommitTime()):
+		return -1
+	case a.CommitTime().Before(b.CommitTime()):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// hasNonStale returns whether any commit of the queue is not stale.
+func hasNonStale(queue *binaryheap.Heap, flags map[plumbing.Hash]uint8) bool {
+	for _, v := range queue.Values() {
+		if flags[v.(CommitNode).ID()]&paintStale == 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,144 @@
+package commitgraph
+
+import (
+	"errors"
+	"path"
+
+	fixtures "github.com/go-git/go-git-fixtures/v5"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	commitgraph "github.com/go-git/go-git/v6/plumbing/format/commitgraph"
+)
+
+// unwalkableNode is a CommitNode whose parents can't be read.
+type unwalkableNode struct {
+	CommitNode
+}
+
+func (unwalkableNode) ParentNode(int) (CommitNode, error) {
+	return nil, errors.New("parents read")
+}
+
+func testIsAncestor(s *CommitNodeSuite, nodeIndex CommitNodeIndex) {
+	get := func(h string) CommitNode {
+		n, err := nodeIndex.Get(plumbing.NewHash(h))
+		s.Require().NoError(err)
+		return n
+	}
+
+	tests := []struct {
+		ancestor, descendant string
+		expected             bool
+	}{
+		{"347c91919944a68e9413581a1bc15519550a3afe", "b9d69064b190e7aedccf84731ca1d917871f8a1c", true},
+		{"ce275064ad67d51e99f026084e20827901a8361c", "6f6c5d2be7852c782be1dd13e36496dd7ad39560", true},
+		{"b9d69064b190e7aedccf84731ca1d917871f8a1c", "b9d69064b190e7aedccf84731ca1d917871f8a1c", true},
+		{"b9d69064b190e7aedccf84731ca1d917871f8a1c", "347c91919944a68e9413581a1bc15519550a3afe", false},
+		{"ce275064ad67d51e99f026084e20827901a8361c", "a45273fe2d63300e1962a9e26a6b15c276cd7082", false},
+	}
+
+	for _, t := range tests {
+		ok, err := IsAncestor(get(t.ancestor), get(t.descendant))
+		s.NoError(err)
+		s.Equal(t.expected, ok, "%s %s", t.ancestor, t.descendant)
+	}
+}
+
+func testMergeBase(s *CommitNodeSuite, nodeIndex CommitNodeIndex) {
+	get := func(h string) CommitNode {
+		n, err := nodeIndex.Get(plumbing.NewHash(h))
+		s.Require().NoError(err)
+		return n
+	}
+
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{"ce275064ad67d51e99f026084e20827901a8361c", "a45273fe2d63300e1962a9e26a6b15c276cd7082", "347c91919944a68e9413581a1bc15519550a3afe"},
+		{"bb13916df33ed23004c3ce9ed3b8487528e655c1", "03d2c021ff68954cf3ef0a36825e194a4b98f981", "03d2c021ff68954cf3ef0a36825e194a4b98f981"},
+		{"ce275064ad67d51e99f026084e20827901a8361c", "b9d69064b190e7aedccf84731ca1d917871f8a1c", "ce275064ad67d51e99f026084e20827901a8361c"},
+		{"b9d69064b190e7aedccf84731ca1d917871f8a1c", "b9d69064b190e7aedccf84731ca1d917871f8a1c", "b9d69064b190e7aedccf84731ca1d917871f8a1c"},
+	}
+
+	for _, t := range tests {
+		bases, err := MergeBase(get(t.a), get(t.b))
+		s.NoError(err)
+		s.Require().Len(bases, 1, "%s %s", t.a, t.b)
+		s.Equal(t.expected, bases[0].ID().String())
+
+		bases, err = MergeBase(get(t.b), get(t.a))
+		s.NoError(err)
+		s.Require().Len(bases, 1, "%s %s", t.b, t.a)
+		s.Equal(t.expected, bases[0].ID().String())
+	}
+}
+
+func (s *CommitNodeSuite) TestObjectGraphMergeBase() {
+	f := fixtures.ByTag("commit-graph").One()
+	storer := unpackRepository(f)
+
+	nodeIndex := NewObjectCommitNodeIndex(storer)
+	testIsAncestor(s, nodeIndex)
+	testMergeBase(s, nodeIndex)
+}
+
+func (s *CommitNodeSuite) TestCommitGraphMergeBase() {
+	f := fixtures.ByTag("commit-graph").One()
+	storer := unpackRepository(f)
+	reader, err := storer.Filesystem().Open(path.Join("objects", "info", "commit-graph"))
+	s.NoError(err)
+	defer reader.Close()
+	index, err := commitgraph.OpenFileIndex(reader)
+	s.NoError(err)
+	defer index.Close()
+
+	nodeIndex := NewGraphCommitNodeIndex(index, storer)
+	testIsAncestor(s, nodeIndex)
+	testMergeBase(s, nodeIndex)
+
+	// The generation of the merge is greater than the one of the branch, so
+	// it is rejected without walking the branch.
+	merge, err := nodeIndex.Get(plumbing.NewHash("6f6c5d2be7852c782be1dd13e36496dd7ad39560"))
+	s.NoError(err)
+	branch, err := nodeIndex.Get(plumbing.NewHash("ce275064ad67d51e99f026084e20827901a8361c"))
+	s.NoError(err)
+
+	ok, err := IsAncestor(merge, unwalkableNode{branch})
+	s.NoError(err)
+	s.False(ok)
+
+	// Without the commit-graph, the branch is walked.
+	objectBranch, err := NewObjectCommitNodeIndex(storer).Get(branch.ID())
+	s.NoError(err)
+
+	_, err = IsAncestor(merge, unwalkableNode{objectBranch})
+	s.Error(err)
+}
+
+func (s *CommitNodeSuite) TestMixedGraphMergeBase() {
+	f := fixtures.ByTag("commit-graph").One()
+	storer := unpackRepository(f)
+
+	reader, err := storer.Filesystem().Open(path.Join("objects", "info", "commit-graph"))
+	s.NoError(err)
+	defer reader.Close()
+	fileIndex, err := commitgraph.OpenFileIndex(reader)
+	s.NoError(err)
+	defer fileIndex.Close()
+
+	memoryIndex := commitgraph.NewMemoryIndex()
+	defer memoryIndex.Close()
+
+	for i, hash := range fileIndex.Hashes() {
+		if hash.String() != "b9d69064b190e7aedccf84731ca1d917871f8a1c" {
+			node, err := fileIndex.GetCommitDataByIndex(uint32(i))
+			s.NoError(err)
+			memoryIndex.Add(hash, node)
+		}
+	}
+
+	nodeIndex := NewGraphCommitNodeIndex(memoryIndex, storer)
+	testIsAncestor(s, nodeIndex)
+	testMergeBase(s, nodeIndex)
+}
@@ -0,0 +1,279 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+// DecodeWarning describes a malformation found by DecodeLenient, which the
+// strict decoding either rejects or silently gets wrong.
+type DecodeWarning struct {
+	// Line is the line of the object the malformation was found at,
+	// starting at 1, or 0 if it concerns the whole object.
+	Line int
+	// Message describes the malformation.
+	Message string
+}
+
+func (w DecodeWarning) String() string {
+	if w.Line == 0 {
+		return w.Message
+	}
+
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// DecodeLenient transforms a plumbing.EncodedObject into a Commit struct as
+// Decode does, but accepting the malformations found in the wild, such as a
+// missing trailing newline, a non-standard timezone or a duplicate header, of
+// which it returns the list. The first of duplicate headers is kept.
+//
+// The bytes of the object are kept, so that Encode writes them as is as long
+// as the commit is not modified, and its hash doesn't change.
+func (c *Commit) DecodeLenient(o plumbing.EncodedObject) ([]DecodeWarning, error) {
+	ld := &lenientDecoder{unique: []string{"tree", "author", "committer", headerencoding}}
+	if err := c.decode(o, ld); err != nil {
+		return nil, err
+	}
+
+	ld.checkTrailingNewline()
+	return ld.warnings, nil
+}
+
+// DecodeLenient transforms a plumbing.EncodedObject into a Tag struct as
+// Decode does, but accepting the malformations found in the wild, such as a
+// missing trailing newline, a non-standard timezone, a duplicate header or an
+// unknown target type, of which it returns the list. The first of duplicate
+// headers is kept.
+//
+// The bytes of the object are kept, so that Encode writes them as is as long
+// as the tag is not modified, and its hash doesn't change.
+func (t *Tag) DecodeLenient(o plumbing.EncodedObject) ([]DecodeWarning, error) {
+	ld := &lenientDecoder{unique: []string{"object", "type", "tag", "tagger"}}
+	if err := t.decode(o, ld); err != nil {
+		return nil, err
+	}
+
+	ld.checkTrailingNewline()
+	return ld.warnings, nil
+}
+
+// lenientDecoder collects the malformations found while decoding an object.
+// A nil lenientDecoder decodes strictly, as Decode does.
+type lenientDecoder struct {
+	// unique are the headers that may only appear once.
+	unique []string
+	seen   map[string]bool
+
+	raw      []byte
+	line     int
+	warnings []DecodeWarning
+}
+
+// reader returns the reader of o, keeping its bytes when lenient.
+func (ld *lenientDecoder) reader(o plumbing.EncodedObject) (io.ReadCloser, error) {
+	r, err := o.Reader()
+	if err != nil || ld == nil {
+		return r, err
+	}
+
+	defer r.Close()
+	ld.raw, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(ld.raw)), nil
+}
+
+func (ld *lenientDecoder) nextLine() {
+	if ld != nil {
+		ld.line++
+	}
+}
+
+func (ld *lenientDecoder) warn(msg string) {
+	if ld != nil {
+		ld.warnings = append(ld.warnings, DecodeWarning{Line: ld.line, Message: msg})
+	}
+}
+
+// duplicate returns whether the header name was already seen and may only
+// appear once, in which case it must be skipped. It is always false when
+// strict.
+func (ld *lenientDecoder) duplicate(name string) bool {
+	if ld == nil || !slices.Contains(ld.unique, name) {
+		return false
+	}
+
+	if ld.seen[name] {
+		ld.warn(fmt.Sprintf("duplicate %q header ignored", name))
+		return true
+	}
+
+	if ld.seen == nil {
+		ld.seen = make(map[string]bool)
+	}
+
+	ld.seen[name] = true
+	return false
+}
+
+// hash decodes the hash of a header.
+func (ld *lenientDecoder) hash(data []byte) plumbing.Hash {
+	if ld != nil && !plumbing.IsHash(string(data)) {
+		ld.warn(fmt.Sprintf("invalid hash %q", data))
+	}
+
+	return plumbing.NewHash(string(data))
+}
+
+// signature decodes the signature of a header into s. When lenient, it also
+// reads the non-standard timezones, such as "+5", "+530" or "+05:30", which
+// the strict decoding ignores.
+func (ld *lenientDecoder) signature(s *Signature, data []byte) {
+	s.Decode(data)
+	if ld == nil {
+		return
+	}
+
+	closeBracket := bytes.LastIndexByte(data, '>')
+	if closeBracket == -1 || bytes.LastIndexByte(data, '<') == -1 {
+		ld.warn(fmt.Sprintf("malformed signature %q", data))
+		return
+	}
+
+	fields := strings.Fields(string(data[closeBracket+1:]))
+	if len(fields) == 0 {
+		ld.warn("missing date in signature")
+		return
+	}
+
+	ts, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		ld.warn(fmt.Sprintf("invalid date %q in signature", fields[0]))
+		return
+	}
+
+	if len(fields) == 1 {
+		ld.warn("missing timezone in signature")
+		return
+	}
+
+	tz := fields[1]
+	if isStandardTimeZone(tz) {
+		return
+	}
+
+	offset, ok := parseTimeZone(tz)
+	if !ok {
+		ld.warn(fmt.Sprintf("invalid timezone %q in signature", tz))
+		return
+	}
+
+	ld.warn(fmt.Sprintf("non-standard timezone %q in signature", tz))
+	s.When = time.Unix(ts, 0).In(time.FixedZone("", offset))
+}
+
+// checkTrailingNewline warns about an object not ending with a newline.
+func (ld *lenientDecoder) checkTrailingNewline() {
+	if len(ld.raw) > 0 && ld.raw[len(ld.raw)-1] != '\n' {
+		ld.warnings = append(ld.warnings, DecodeWarning{Message: "missing trailing newline"})
+	}
+}
+
+// isStandardTimeZone returns whether tz has the +hhmm form git writes.
+func isStandardTimeZone(tz string) bool {
+	if len(tz) != timeZoneLength || (tz[0] != '+' && tz[0] != '-') {
+		return false
+	}
+
+	for _, c := range tz[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseTimeZone parses a timezone with an optional sign, hours of one or two
+// digits and optional minutes, optionally separated by a colon, returning its
+// offset in seconds.
+func parseTimeZone(tz string) (int, bool) {
+	sign := 1
+	switch {
+	case strings.HasPrefix(tz, "+"):
+		tz = tz[1:]
+	case strings.HasPrefix(tz, "-"):
+		sign = -1
+		tz = tz[1:]
+	}
+
+	hours, minutes, found := strings.Cut(tz, ":")
+	if !found {
+		switch len(tz) {
+		case 1, 2:
+			hours, minutes = tz, "0"
+		case 3, 4:
+			hours, minutes = tz[:len(tz)-2], tz[len(tz)-2:]
+		default:
+			return 0, false
+		}
+	}
+
+	h, err1 := strconv.ParseUint(hours, 10, 8)
+	m, err2 := strconv.ParseUint(minutes, 10, 8)
+	if err1 != nil || err2 != nil || hours == "" || h > 14 || m > 59 {
+		return 0, false
+	}
+
+	return sign * int(h*3600+m*60), true
+}
+
+// snapshot returns a copy of the decoded fields of c.
+func (c *Commit) snapshot() *Commit {
+	return &Commit{
+		Hash:         c.Hash,
+		Author:       c.Author,
+		Committer:    c.Committer,
+		MergeTag:     c.MergeTag,
+		Signature:    c.Signature,
+		Message:      c.Message,
+		TreeHash:     c.TreeHash,
+		ParentHashes: slices.Clone(c.ParentHashes),
+		Encoding:     c.Encoding,
+		ExtraHeaders: slices.Clone(c.ExtraHeaders),
+	}
+}
+
+// unchanged returns whether c has the fields it was decoded with.
+func (c *Commit) unchanged() bool {
+	return c.decoded != nil && reflect.DeepEqual(c.snapshot(), c.decoded)
+}
+
+// snapshot returns a copy of the decoded fields of t.
+func (t *Tag) snapshot() *Tag {
+	return &Tag{
+		Hash:       t.Hash,
+		Name:       t.Name,
+		Tagger:     t.Tagger,
+		Message:    t.Message,
+		Signature:  t.Signature,
+		TargetType: t.TargetType,
+		Target:     t.Target,
+	}
+}
+
+// unchanged returns whether t has the fields it was decoded with.
+func (t *Tag) unchanged() bool {
+	return t.decoded != nil && reflect.DeepEqual(t.snapshot(), t.decoded)
+}
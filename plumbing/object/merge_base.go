@@ -15,6 +15,8 @@ var errIsReachable = fmt.Errorf("first is reachable from second")
 // MergeBase mimics the behavior of `git merge-base actual other`, returning the
 // best common ancestor between the actual and the passed one.
 // The best common ancestors can not be reached from other common ancestors.
+// commitgraph.MergeBase walks the history pruned by the generation numbers of
+// a commit-graph instead.
 func (c *Commit) MergeBase(other *Commit) ([]*Commit, error) {
 	// use sortedByCommitDateDesc strategy
 	sorted := sortByCommitDateDesc(c, other)
@@ -44,6 +46,8 @@ func (c *Commit) MergeBase(other *Commit) ([]*Commit, error) {
 // IsAncestor returns true if the actual commit is ancestor of the passed one.
 // It returns an error if the history is not transversable
 // It mimics the behavior of `git merge --is-ancestor actual other`
+// commitgraph.IsAncestor walks the history pruned by the generation numbers of
+// a commit-graph instead.
 func (c *Commit) IsAncestor(other *Commit) (bool, error) {
 	found := false
 	iter := NewCommitPreorderIter(other, nil, nil)
@@ -0,0 +1,264 @@
+package object
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultTrailerSeparators are the characters separating the keys of
+	// the trailers from their values, as the default trailer.separators.
+	DefaultTrailerSeparators = ":"
+	// DefaultCommentChar starts the comment lines of a message, as the
+	// default core.commentChar.
+	DefaultCommentChar = "#"
+)
+
+var (
+	// trailerKeyRe matches the key of a trailer.
+	trailerKeyRe = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+	// gitGeneratedPrefixes start the lines of a trailer block added by git,
+	// which make a block with other lines a trailer block.
+	gitGeneratedPrefixes = []string{"Signed-off-by: ", "(cherry picked from commit "}
+)
+
+// Trailer is a "key: value" line of the trailer block ending a message. Its
+// value includes its continuation lines, which start with a whitespace.
+type Trailer struct {
+	// Key is the key of the trailer, empty for the lines of a trailer block
+	// which are not trailers, kept as Value.
+	Key string
+	// Value is the value of the trailer.
+	Value string
+
+	// raw is the text the trailer was parsed from, written back as is while
+	// the trailer is unchanged.
+	raw string
+}
+
+// Message is a commit or tag message, split in its body and the trailer
+// block ending it, following the rules of git interpret-trailers.
+type Message struct {
+	// Body is the message before the trailer block, without the blank lines
+	// ending it.
+	Body string
+	// Trailers are the lines of the trailer block, in order.
+	Trailers []Trailer
+
+	separators string
+}
+
+// ParseMessage parses the message msg. The trailer block is its last
+// paragraph, but not its first, if all its lines are trailers, or if at
+// least a quarter of them are and one was added by git, as Signed-off-by.
+// The keys of the trailers are separated from their values by one of the
+// characters of separators, DefaultTrailerSeparators if empty.
+func ParseMessage(msg, separators string) *Message {
+	if separators == "" {
+		separators = DefaultTrailerSeparators
+	}
+
+	m := &Message{separators: separators}
+	body := strings.TrimRight(msg, "\n")
+	i := strings.LastIndex(body, "\n\n")
+	if i < 0 {
+		m.Body = body
+		return m
+	}
+
+	trailers, ok := parseTrailerBlock(body[i+2:], separators)
+	if !ok {
+		m.Body = body
+		return m
+	}
+
+	m.Body = strings.TrimRight(body[:i], "\n")
+	m.Trailers = trailers
+	return m
+}
+
+func parseTrailerBlock(block, separators string) ([]Trailer, bool) {
+	var raws []string
+	for _, line := range strings.Split(block, "\n") {
+		continued := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		if continued && len(raws) > 0 {
+			raws[len(raws)-1] += "\n" + line
+			continue
+		}
+
+		raws = append(raws, line)
+	}
+
+	var trailers []Trailer
+	var count, others int
+	var gitGenerated bool
+	for _, raw := range raws {
+		for _, prefix := range gitGeneratedPrefixes {
+			gitGenerated = gitGenerated || strings.HasPrefix(raw, prefix)
+		}
+
+		t, ok := parseTrailer(raw, separators)
+		if !ok {
+			t = Trailer{Value: raw, raw: raw}
+			others++
+		} else {
+			count++
+		}
+
+		trailers = append(trailers, t)
+	}
+
+	if count == 0 || (others > 0 && (!gitGenerated || count*3 < others)) {
+		return nil, false
+	}
+
+	return trailers, true
+}
+
+// parseTrailer parses the trailer raw, with its continuation lines.
+func parseTrailer(raw, separators string) (Trailer, bool) {
+	first, _, _ := strings.Cut(raw, "\n")
+	i := strings.IndexAny(first, separators)
+	if i < 0 {
+		return Trailer{}, false
+	}
+
+	key := strings.TrimRight(raw[:i], " \t")
+	if !trailerKeyRe.MatchString(key) {
+		return Trailer{}, false
+	}
+
+	value := strings.TrimLeft(raw[i+1:], " \t")
+	return Trailer{Key: key, Value: value, raw: raw}, true
+}
+
+// TrailerValues returns the values of the trailers with the key, compared
+// case-insensitively.
+func (m *Message) TrailerValues(key string) []string {
+	var values []string
+	for _, t := range m.Trailers {
+		if t.hasKey(key) {
+			values = append(values, t.Value)
+		}
+	}
+
+	return values
+}
+
+// AddTrailer adds the trailer at the end of the trailer block.
+func (m *Message) AddTrailer(key, value string) {
+	m.Trailers = append(m.Trailers, Trailer{Key: key, Value: value})
+}
+
+// SetTrailer sets the value of the first trailer with the key, and removes
+// the others, or adds it if there are none.
+func (m *Message) SetTrailer(key, value string) {
+	i := slices.IndexFunc(m.Trailers, func(t Trailer) bool { return t.hasKey(key) })
+	if i < 0 {
+		m.AddTrailer(key, value)
+		return
+	}
+
+	m.Trailers[i].Value = value
+	m.Trailers = slices.Concat(m.Trailers[:i+1], removeTrailers(m.Trailers[i+1:], key))
+}
+
+// RemoveTrailer removes the trailers with the key.
+func (m *Message) RemoveTrailer(key string) {
+	m.Trailers = removeTrailers(m.Trailers, key)
+}
+
+func removeTrailers(trailers []Trailer, key string) []Trailer {
+	return slices.DeleteFunc(slices.Clone(trailers), func(t Trailer) bool { return t.hasKey(key) })
+}
+
+// hasKey returns whether t is a trailer with the key, compared
+// case-insensitively.
+func (t Trailer) hasKey(key string) bool {
+	return t.Key != "" && strings.EqualFold(t.Key, key)
+}
+
+// String returns the message, with its trailer block separated from its
+// body by a blank line, ending with a LF unless it is empty. The trailers parsed are written
+// as they were while unchanged, the others as "key: value", with the first
+// of the separators.
+func (m *Message) String() string {
+	separators := m.separators
+	if separators == "" {
+		separators = DefaultTrailerSeparators
+	}
+
+	if len(m.Trailers) == 0 {
+		if m.Body == "" {
+			return ""
+		}
+
+		return m.Body + "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(m.Body)
+	b.WriteString("\n\n")
+	for _, t := range m.Trailers {
+		b.WriteString(t.format(separators))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func (t Trailer) format(separators string) string {
+	if t.raw != "" {
+		p, ok := parseTrailer(t.raw, separators)
+		if !ok {
+			p = Trailer{Value: t.raw}
+		}
+
+		if p.Key == t.Key && p.Value == t.Value {
+			return t.raw
+		}
+	}
+
+	if t.Key == "" {
+		return t.Value
+	}
+
+	return t.Key + separators[:1] + " " + t.Value
+}
+
+// CleanupMessage cleans up the message msg, as git commit --cleanup=strip
+// does: the lines starting with commentChar, DefaultCommentChar if empty
+// or "auto", are removed, as the trailing whitespaces of the lines, the
+// leading and trailing blank lines, and the consecutive blank lines but
+// one. The message ends with a LF unless it is empty.
+func CleanupMessage(msg, commentChar string) string {
+	if commentChar == "" || commentChar == "auto" {
+		commentChar = DefaultCommentChar
+	}
+
+	var b strings.Builder
+	var blank bool
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.HasPrefix(line, commentChar) {
+			continue
+		}
+
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = b.Len() > 0
+			continue
+		}
+
+		if blank {
+			b.WriteString("\n")
+			blank = false
+		}
+
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		msg        string
+		separators string
+		body       string
+		trailers   []string
+	}{
+		{
+			name: "no trailers",
+			msg:  "foo\n\nbar\n",
+			body: "foo\n\nbar",
+		},
+		{
+			name: "title only",
+			msg:  "Fixes: foo\n",
+			body: "Fixes: foo",
+		},
+		{
+			name:     "trailers",
+			msg:      "foo\n\nbar\n\nReviewed-by: a <a@a.a>\nFixes : 123\n",
+			body:     "foo\n\nbar",
+			trailers: []string{"Reviewed-by=a <a@a.a>", "Fixes=123"},
+		},
+		{
+			name:     "continuation",
+			msg:      "foo\n\nAcked-by: a\n  <a@a.a>\n",
+			body:     "foo",
+			trailers: []string{"Acked-by=a\n  <a@a.a>"},
+		},
+		{
+			name: "not all trailers",
+			msg:  "foo\n\nsee: the doc\nfor details\n",
+			body: "foo\n\nsee: the doc\nfor details",
+		},
+		{
+			name:     "git generated",
+			msg:      "foo\n\nsome text\nSigned-off-by: a <a@a.a>\n",
+			body:     "foo",
+			trailers: []string{"=some text", "Signed-off-by=a <a@a.a>"},
+		},
+		{
+			name: "git generated with few trailers",
+			msg:  "foo\n\na\nb\nc\nd\nSigned-off-by: a <a@a.a>\n",
+			body: "foo\n\na\nb\nc\nd\nSigned-off-by: a <a@a.a>",
+		},
+		{
+			name:       "separators",
+			msg:        "foo\n\nFixes #123\nAcked-by: a\n",
+			separators: ":#",
+			body:       "foo",
+			trailers:   []string{"Fixes=123", "Acked-by=a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := ParseMessage(tt.msg, tt.separators)
+			assert.Equal(t, tt.body, m.Body)
+
+			var trailers []string
+			for _, tr := range m.Trailers {
+				trailers = append(trailers, tr.Key+"="+tr.Value)
+			}
+
+			assert.Equal(t, tt.trailers, trailers)
+			assert.Equal(t, tt.msg, m.String())
+		})
+	}
+}
+
+func TestMessageTrailers(t *testing.T) {
+	t.Parallel()
+
+	m := ParseMessage("foo\n\nfixes #1\nAcked-by: a\nFixes #2\n", ":#")
+	assert.Equal(t, []string{"1", "2"}, m.TrailerValues("Fixes"))
+
+	m.AddTrailer("Reviewed-by", "b")
+	assert.Equal(t, "foo\n\nfixes #1\nAcked-by: a\nFixes #2\nReviewed-by: b\n", m.String())
+
+	m.SetTrailer("FIXES", "3")
+	assert.Equal(t, "foo\n\nfixes: 3\nAcked-by: a\nReviewed-by: b\n", m.String())
+
+	m.RemoveTrailer("acked-by")
+	assert.Equal(t, "foo\n\nfixes: 3\nReviewed-by: b\n", m.String())
+
+	m.RemoveTrailer("fixes")
+	m.RemoveTrailer("reviewed-by")
+	assert.Equal(t, "foo\n", m.String())
+
+	m = ParseMessage("foo", "")
+	m.AddTrailer("Fixes", "1")
+	assert.Equal(t, "foo\n\nFixes: 1\n", m.String())
+
+	m = ParseMessage("", "")
+	m.AddTrailer("Fixes", "1")
+	assert.Equal(t, "\n\nFixes: 1\n", m.String())
+	assert.Equal(t, m.String(), ParseMessage(m.String(), "").String())
+}
+
+func TestCleanupMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		msg         string
+		commentChar string
+		want        string
+	}{
+		{"", "", ""},
+		{"# only a comment\n", "", ""},
+		{"\n\nfoo  \n\n\n\nbar\t\n\n", "", "foo\n\nbar\n"},
+		{"foo\n# comment\n\n#\nbar", "", "foo\n\nbar\n"},
+		{"foo\n# not a comment\n; comment\n", ";", "foo\n# not a comment\n"},
+		{"foo\n# comment\n", "auto", "foo\n"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CleanupMessage(tt.msg, tt.commentChar), "%q", tt.msg)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -14,18 +15,32 @@ import (
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/filemode"
 	fdiff "github.com/go-git/go-git/v6/plumbing/format/diff"
+	"github.com/go-git/go-git/v6/utils/binary"
 	"github.com/go-git/go-git/v6/utils/diff"
+	"github.com/go-git/go-git/v6/utils/ioutil"
 )
 
 // ErrCanceled is returned when the operation is canceled.
 var ErrCanceled = errors.New("operation canceled")
 
+// TextConv converts the content of the file at path, a full path using "/"
+// as separator, to the text diffed in its place, as the textconv commands of
+// the git diff drivers do. It returns false if the file is not converted.
+type TextConv func(path string, content []byte) (text []byte, ok bool, err error)
+
+// PatchOptions are the options of the patches computed from changes.
+type PatchOptions struct {
+	// TextConv, if set, converts the contents of the files before they are
+	// diffed. The converted contents are always diffed as text.
+	TextConv TextConv
+}
+
 func getPatch(message string, changes ...*Change) (*Patch, error) {
 	ctx := context.Background()
-	return getPatchContext(ctx, message, changes...)
+	return getPatchContext(ctx, message, nil, changes...)
 }
 
-func getPatchContext(ctx context.Context, message string, changes ...*Change) (*Patch, error) {
+func getPatchContext(ctx context.Context, message string, opts *PatchOptions, changes ...*Change) (*Patch, error) {
 	if len(changes) == 0 {
 		return &Patch{message: message}, nil
 	}
@@ -38,7 +53,7 @@ func getPatchContext(ctx context.Context, message string, changes ...*Change) (*
 		default:
 		}
 
-		fp, err := filePatchWithContext(ctx, c)
+		fp, err := filePatchWithContext(ctx, c, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -49,17 +64,27 @@ func getPatchContext(ctx context.Context, message string, changes ...*Change) (*
 	return &Patch{message, filePatches}, nil
 }
 
-func filePatchWithContext(ctx context.Context, c *Change) (fdiff.FilePatch, error) {
+func filePatchWithContext(ctx context.Context, c *Change, opts *PatchOptions) (fdiff.FilePatch, error) {
+	if c.From.TreeEntry.Mode == filemode.Submodule || c.To.TreeEntry.Mode == filemode.Submodule {
+		return submoduleFilePatch(ctx, c)
+	}
+
 	from, to, err := c.Files()
 	if err != nil {
 		return nil, err
 	}
-	fromContent, fIsBinary, err := fileContent(from)
+
+	var textConv TextConv
+	if opts != nil {
+		textConv = opts.TextConv
+	}
+
+	fromContent, fIsBinary, err := convertedFileContent(from, c.From.Name, textConv)
 	if err != nil {
 		return nil, err
 	}
 
-	toContent, tIsBinary, err := fileContent(to)
+	toContent, tIsBinary, err := convertedFileContent(to, c.To.Name, textConv)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +93,59 @@ func filePatchWithContext(ctx context.Context, c *Change) (fdiff.FilePatch, erro
 		return &textFilePatch{from: c.From, to: c.To}, nil
 	}
 
+	chunks, err := textChunks(ctx, fromContent, toContent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &textFilePatch{
+		chunks: chunks,
+		from:   c.From,
+		to:     c.To,
+	}, nil
+}
+
+// submoduleFilePatch returns the patch of a change of a submodule, whose
+// content is summarized by the commit it points to, as git does.
+func submoduleFilePatch(ctx context.Context, c *Change) (fdiff.FilePatch, error) {
+	var contents [2]string
+	for i, e := range []ChangeEntry{c.From, c.To} {
+		switch {
+		case e.TreeEntry.Mode == filemode.Submodule:
+			contents[i] = fmt.Sprintf("Subproject commit %s\n", e.TreeEntry.Hash)
+		case e.TreeEntry.Mode.IsFile():
+			f, err := e.Tree.TreeEntryFile(&e.TreeEntry)
+			if err != nil {
+				return nil, err
+			}
+
+			content, isBinary, err := fileContent(f)
+			if err != nil {
+				return nil, err
+			}
+
+			if isBinary {
+				return &textFilePatch{from: c.From, to: c.To}, nil
+			}
+
+			contents[i] = content
+		}
+	}
+
+	chunks, err := textChunks(ctx, contents[0], contents[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &textFilePatch{
+		chunks: chunks,
+		from:   c.From,
+		to:     c.To,
+	}, nil
+}
+
+// textChunks returns the chunks turning the from content into the to one.
+func textChunks(ctx context.Context, fromContent, toContent string) ([]fdiff.Chunk, error) {
 	diffs := diff.Do(fromContent, toContent)
 
 	chunks := make([]fdiff.Chunk, 0, len(diffs))
@@ -91,28 +169,186 @@ func filePatchWithContext(ctx context.Context, c *Change) (fdiff.FilePatch, erro
 		chunks = append(chunks, &textChunk{d.Text, op})
 	}
 
-	return &textFilePatch{
-		chunks: chunks,
-		from:   c.From,
-		to:     c.To,
-	}, nil
+	return chunks, nil
 }
 
-func fileContent(f *File) (content string, isBinary bool, err error) {
+// convertedFileContent returns the content of the file at path, converted to
+// text by textConv if it is not nil and converts it.
+func convertedFileContent(f *File, path string, textConv TextConv) (content string, isBinary bool, err error) {
 	if f == nil {
-		return content, isBinary, err
+		return "", false, nil
 	}
 
-	isBinary, err = f.IsBinary()
+	return blobContent(&f.Blob, path, textConv)
+}
+
+func fileContent(f *File) (content string, isBinary bool, err error) {
+	return convertedFileContent(f, "", nil)
+}
+
+// NewPatch returns a patch made of the file patches, such as the ones built
+// by NewFilePatch.
+func NewPatch(message string, filePatches []fdiff.FilePatch) *Patch {
+	return &Patch{message: message, filePatches: filePatches}
+}
+
+// NewFilePatch returns the patch of a file whose content changes from
+// fromContent to toContent, a nil file being a missing one. The chunks are
+// computed as for the changes between trees, none being computed if any of
+// the contents is binary.
+func NewFilePatch(ctx context.Context, from, to fdiff.File, fromContent, toContent []byte) (fdiff.FilePatch, error) {
+	fp := &contentFilePatch{from: from, to: to}
+	for _, content := range [][]byte{fromContent, toContent} {
+		isBinary, err := binary.IsBinary(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+
+		if isBinary {
+			fp.isBinary = true
+			return fp, nil
+		}
+	}
+
+	chunks, err := textChunks(ctx, string(fromContent), string(toContent))
+	if err != nil {
+		return nil, err
+	}
+
+	fp.chunks = chunks
+	return fp, nil
+}
+
+// DiffBlobs returns the patch turning the content of the blob a into the one
+// of b, a nil blob being a missing one. Both files of the patch have the hash
+// of a as path, or the one of b if a is nil, so that the patch is not taken
+// for a rename. The TextConv of the options is called with this path.
+func DiffBlobs(a, b *Blob, opts *PatchOptions) (*Patch, error) {
+	return DiffBlobsContext(context.Background(), a, b, opts)
+}
+
+// DiffBlobsContext returns the patch turning the content of the blob a into
+// the one of b, as DiffBlobs does. An error will be returned if the context
+// expires. Provided context must be non-nil.
+func DiffBlobsContext(ctx context.Context, a, b *Blob, opts *PatchOptions) (*Patch, error) {
+	var textConv TextConv
+	if opts != nil {
+		textConv = opts.TextConv
+	}
+
+	path := ""
+	switch {
+	case a != nil:
+		path = a.Hash.String()
+	case b != nil:
+		path = b.Hash.String()
+	}
+
+	fp := &contentFilePatch{from: newBlobFile(a, path), to: newBlobFile(b, path)}
+	fromContent, fromBinary, err := blobContent(a, path, textConv)
+	if err != nil {
+		return nil, err
+	}
+
+	toContent, toBinary, err := blobContent(b, path, textConv)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromBinary || toBinary {
+		fp.isBinary = true
+	} else {
+		fp.chunks, err = textChunks(ctx, fromContent, toContent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Patch{filePatches: []fdiff.FilePatch{fp}}, nil
+}
+
+// blobContent returns the content of the blob, converted to text by textConv
+// if it is not nil and converts it. The content of binary blobs not converted
+// is left empty.
+func blobContent(b *Blob, path string, textConv TextConv) (content string, isBinary bool, err error) {
+	if b == nil {
+		return "", false, nil
+	}
+
+	// Without textconv, the binary blobs are detected without reading them
+	// whole.
+	if textConv == nil {
+		if isBinary, err = blobIsBinary(b); err != nil || isBinary {
+			return "", isBinary, err
+		}
+	}
+
+	raw, err := blobBytes(b)
+	if err != nil {
+		return "", false, err
+	}
+
+	if textConv == nil {
+		return string(raw), false, nil
+	}
+
+	text, ok, err := textConv(path, raw)
+	if err != nil {
+		return "", false, err
+	}
+
+	if ok {
+		return string(text), false, nil
+	}
+
+	isBinary, err = binary.IsBinary(bytes.NewReader(raw))
 	if err != nil || isBinary {
-		return content, isBinary, err
+		return "", isBinary, err
 	}
 
-	content, err = f.Contents()
+	return string(raw), false, nil
+}
 
-	return content, isBinary, err
+func blobIsBinary(b *Blob) (isBinary bool, err error) {
+	r, err := b.Reader()
+	if err != nil {
+		return false, err
+	}
+
+	defer ioutil.CheckClose(r, &err)
+	return binary.IsBinary(r)
+}
+
+func blobBytes(b *Blob) (raw []byte, err error) {
+	r, err := b.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	defer ioutil.CheckClose(r, &err)
+	return io.ReadAll(r)
+}
+
+// blobFile is an implementation of fdiff.File interface for the blobs diffed
+// directly.
+type blobFile struct {
+	hash plumbing.Hash
+	path string
+}
+
+// newBlobFile returns the file of the blob at path, nil if the blob is nil.
+func newBlobFile(b *Blob, path string) fdiff.File {
+	if b == nil {
+		return nil
+	}
+
+	return &blobFile{hash: b.Hash, path: path}
 }
 
+func (f *blobFile) Hash() plumbing.Hash     { return f.hash }
+func (f *blobFile) Mode() filemode.FileMode { return filemode.Regular }
+func (f *blobFile) Path() string            { return f.path }
+
 // Patch is an implementation of fdiff.Patch interface
 type Patch struct {
 	message     string
@@ -136,6 +372,44 @@ func (p *Patch) Encode(w io.Writer) error {
 	return ue.Encode(p)
 }
 
+// PatchEncodeOptions describes how to encode a Patch.
+type PatchEncodeOptions struct {
+	// ContextLines is the number of context lines around the changes,
+	// fdiff.DefaultContextLines if zero. A negative value means no context
+	// lines, as git diff -U0 does.
+	ContextLines int
+	// FunctionContext shows the whole function enclosing each change as
+	// context, as git diff -W does.
+	FunctionContext bool
+	// Funcname matches the lines starting a function when FunctionContext is
+	// set. If nil, they are the lines starting with a letter, an underscore
+	// or a dollar sign, as with git when no diff driver is set.
+	Funcname *regexp.Regexp
+}
+
+// EncodeWithOptions encodes the patch to the given writer using the given
+// options.
+func (p *Patch) EncodeWithOptions(w io.Writer, opts *PatchEncodeOptions) error {
+	if opts == nil {
+		opts = &PatchEncodeOptions{}
+	}
+
+	ctxLines := opts.ContextLines
+	switch {
+	case ctxLines == 0:
+		ctxLines = fdiff.DefaultContextLines
+	case ctxLines < 0:
+		ctxLines = 0
+	}
+
+	ue := fdiff.NewUnifiedEncoder(w, ctxLines)
+	if opts.FunctionContext {
+		ue.SetFunctionContext(opts.Funcname)
+	}
+
+	return ue.Encode(p)
+}
+
 // Stats returns the file stats.
 func (p *Patch) Stats() FileStats {
 	return getFileStatsFromFilePatches(p.FilePatches())
@@ -157,7 +431,7 @@ type changeEntryWrapper struct {
 }
 
 func (f *changeEntryWrapper) Hash() plumbing.Hash {
-	if !f.ce.TreeEntry.Mode.IsFile() {
+	if f.Empty() {
 		return plumbing.ZeroHash
 	}
 
@@ -169,7 +443,7 @@ func (f *changeEntryWrapper) Mode() filemode.FileMode {
 }
 
 func (f *changeEntryWrapper) Path() string {
-	if !f.ce.TreeEntry.Mode.IsFile() {
+	if f.Empty() {
 		return ""
 	}
 
@@ -177,7 +451,7 @@ func (f *changeEntryWrapper) Path() string {
 }
 
 func (f *changeEntryWrapper) Empty() bool {
-	return !f.ce.TreeEntry.Mode.IsFile()
+	return !f.ce.TreeEntry.Mode.IsFile() && f.ce.TreeEntry.Mode != filemode.Submodule
 }
 
 // textFilePatch is an implementation of fdiff.FilePatch interface
@@ -209,6 +483,26 @@ func (tf *textFilePatch) Chunks() []fdiff.Chunk {
 	return tf.chunks
 }
 
+// contentFilePatch is an implementation of fdiff.FilePatch interface, for
+// files given with their content.
+type contentFilePatch struct {
+	chunks   []fdiff.Chunk
+	from, to fdiff.File
+	isBinary bool
+}
+
+func (cf *contentFilePatch) Files() (from, to fdiff.File) {
+	return cf.from, cf.to
+}
+
+func (cf *contentFilePatch) IsBinary() bool {
+	return cf.isBinary
+}
+
+func (cf *contentFilePatch) Chunks() []fdiff.Chunk {
+	return cf.chunks
+}
+
 // textChunk is an implementation of fdiff.Chunk interface
 type textChunk struct {
 	content string
@@ -297,7 +591,7 @@ func getFileStatsFromFilePatches(filePatches []fdiff.FilePatch) FileStats {
 	fileStats := make(FileStats, 0, len(filePatches))
 
 	for _, fp := range filePatches {
-		// ignore empty patches (binary files, submodule refs updates)
+		// ignore empty patches (binary files)
 		if len(fp.Chunks()) == 0 {
 			continue
 		}
@@ -318,28 +612,156 @@ func getFileStatsFromFilePatches(filePatches []fdiff.FilePatch) FileStats {
 			cs.Name = from.Path()
 		}
 
-		for _, chunk := range fp.Chunks() {
-			s := chunk.Content()
-			if len(s) == 0 {
-				continue
-			}
+		cs.Addition, cs.Deletion = countChangedLines(fp)
+		fileStats = append(fileStats, cs)
+	}
 
-			switch chunk.Type() {
-			case fdiff.Add:
-				cs.Addition += strings.Count(s, "\n")
-				if s[len(s)-1] != '\n' {
-					cs.Addition++
-				}
-			case fdiff.Delete:
-				cs.Deletion += strings.Count(s, "\n")
-				if s[len(s)-1] != '\n' {
-					cs.Deletion++
-				}
-			}
+	return fileStats
+}
+
+// countChangedLines returns the number of lines added and deleted by the
+// file patch.
+func countChangedLines(fp fdiff.FilePatch) (added, deleted int) {
+	for _, chunk := range fp.Chunks() {
+		s := chunk.Content()
+		if len(s) == 0 {
+			continue
 		}
 
-		fileStats = append(fileStats, cs)
+		n := strings.Count(s, "\n")
+		if s[len(s)-1] != '\n' {
+			n++
+		}
+
+		switch chunk.Type() {
+		case fdiff.Add:
+			added += n
+		case fdiff.Delete:
+			deleted += n
+		}
 	}
 
-	return fileStats
+	return added, deleted
+}
+
+// NumStat returns the changes as git diff --numstat prints them, one line
+// per file with the number of added and deleted lines and its path, the
+// numbers being "-" for binary files. Renamed files are printed as git does,
+// the parts of their paths in common outside of braces.
+func (p *Patch) NumStat() string {
+	var b strings.Builder
+	for _, fp := range p.FilePatches() {
+		from, to := fp.Files()
+		var name string
+		switch {
+		case from == nil:
+			name = to.Path()
+		case to == nil:
+			name = from.Path()
+		default:
+			name = renameName(from.Path(), to.Path())
+		}
+
+		if fp.IsBinary() {
+			fmt.Fprintf(&b, "-\t-\t%s\n", name)
+			continue
+		}
+
+		added, deleted := countChangedLines(fp)
+		fmt.Fprintf(&b, "%d\t%d\t%s\n", added, deleted, name)
+	}
+
+	return b.String()
+}
+
+// ShortStat returns the summary of the changes as git diff --shortstat
+// prints it, the lines of the binary files not being counted. As with git,
+// it is empty when nothing changed.
+func (p *Patch) ShortStat() string {
+	filePatches := p.FilePatches()
+	if len(filePatches) == 0 {
+		return ""
+	}
+
+	var added, deleted int
+	for _, fp := range filePatches {
+		if fp.IsBinary() {
+			continue
+		}
+
+		a, d := countChangedLines(fp)
+		added += a
+		deleted += d
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, " %d %s changed", len(filePatches), plural(len(filePatches), "file", "files"))
+	if added != 0 || deleted == 0 {
+		fmt.Fprintf(&b, ", %d %s(+)", added, plural(added, "insertion", "insertions"))
+	}
+
+	if deleted != 0 || added == 0 {
+		fmt.Fprintf(&b, ", %d %s(-)", deleted, plural(deleted, "deletion", "deletions"))
+	}
+
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+
+	return many
+}
+
+// renameName returns the name of a file renamed from a to b, as git prints
+// it in stats: the common leading directories and trailing path components
+// are written once, around braces holding the parts that differ.
+// Original implementation: https://github.com/git/git/blob/v2.47.0/diff.c#L2345
+func renameName(a, b string) string {
+	if a == b {
+		return a
+	}
+
+	pfx := 0
+	for i := 0; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
+		if a[i] == '/' {
+			pfx = i + 1
+		}
+	}
+
+	// byteAt returns the byte at i, 0 past the end as the C strings the
+	// original implementation walks.
+	byteAt := func(s string, i int) byte {
+		if i >= len(s) {
+			return 0
+		}
+
+		return s[i]
+	}
+
+	// If there is a common prefix, it ends with a slash, which the common
+	// suffix may reuse.
+	adjust := 0
+	if pfx > 0 {
+		adjust = 1
+	}
+
+	sfx := 0
+	for i, j := len(a), len(b); pfx-adjust <= i && pfx-adjust <= j && byteAt(a, i) == byteAt(b, j); i, j = i-1, j-1 {
+		if byteAt(a, i) == '/' {
+			sfx = len(a) - i
+		}
+	}
+
+	if pfx+sfx == 0 {
+		return a + " => " + b
+	}
+
+	aMid := max(len(a)-pfx-sfx, 0)
+	bMid := max(len(b)-pfx-sfx, 0)
+
+	return a[:pfx] + "{" + a[pfx:pfx+aMid] + " => " + b[pfx:pfx+bMid] + "}" + a[len(a)-sfx:]
 }
@@ -0,0 +1,196 @@
+package object
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
+	fdiff "github.com/go-git/go-git/v6/plumbing/format/diff"
+)
+
+// ErrPatchIDMergeCommit is returned when the patch-id of a merge commit is
+// requested, as it is not defined.
+var ErrPatchIDMergeCommit = errors.New("patch-id is not defined for merge commits")
+
+// patchIDContextLines is the number of context lines of the diff hashed by
+// the patch-id, as git uses.
+const patchIDContextLines = 3
+
+// PatchID returns the patch-id of the commit, as git patch-id --stable: a
+// hash of the diff it introduces to its parent, independent of the line
+// numbers, the whitespaces and the order of the files, and of its metadata.
+// Two commits with the same patch-id introduce the same change, for
+// example when one was cherry-picked from the other. The renames are not
+// detected. ErrPatchIDMergeCommit is returned for a merge commit.
+func (c *Commit) PatchID() (plumbing.Hash, error) {
+	return c.PatchIDContext(context.Background())
+}
+
+// PatchIDContext returns the patch-id of the commit, as PatchID. Error will
+// be return if context expires. Provided context must be non-nil.
+func (c *Commit) PatchIDContext(ctx context.Context) (plumbing.Hash, error) {
+	if c.NumParents() > 1 {
+		return plumbing.ZeroHash, ErrPatchIDMergeCommit
+	}
+
+	toTree, err := c.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	fromTree := &Tree{}
+	if c.NumParents() == 1 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		fromTree, err = parent.Tree()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	changes, err := DiffTreeWithOptions(ctx, fromTree, toTree, nil)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	patch, err := changes.PatchContext(ctx)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return patchID(patch.FilePatches(), c.Hash.Format())
+}
+
+// patchID returns the patch-id of the file patches: the sum, with carry, of
+// the hashes of their normalized diffs, in the object format f.
+func patchID(filePatches []fdiff.FilePatch, f format.ObjectFormat) (plumbing.Hash, error) {
+	h := crypto.SHA1.New()
+	if f == format.SHA256 {
+		h = crypto.SHA256.New()
+	}
+
+	sum := make([]byte, h.Size())
+	for _, fp := range filePatches {
+		h.Reset()
+		if err := writePatchID(h, fp, f); err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		var carry uint
+		for i, b := range h.Sum(nil) {
+			carry += uint(sum[i]) + uint(b)
+			sum[i] = byte(carry)
+			carry >>= 8
+		}
+	}
+
+	id, _ := plumbing.FromBytes(sum)
+	return id, nil
+}
+
+// writePatchID writes the normalized diff of the file patch fp to h: its
+// header, then the lines of its hunks, without the whitespaces.
+func writePatchID(h hash.Hash, fp fdiff.FilePatch, f format.ObjectFormat) error {
+	from, to := fp.Files()
+	if from == nil && to == nil {
+		return nil
+	}
+
+	fromPath, toPath := patchIDPath(from), patchIDPath(to)
+	if from == nil {
+		fromPath = toPath
+	} else if to == nil {
+		toPath = fromPath
+	}
+
+	writePatchIDString(h, "diff--git", "a/", fromPath, "b/", toPath)
+	switch {
+	case from == nil:
+		writePatchIDString(h, "newfilemode", patchIDMode(to.Mode()))
+	case to == nil:
+		writePatchIDString(h, "deletedfilemode", patchIDMode(from.Mode()))
+	case from.Mode() != to.Mode():
+		writePatchIDString(h, "oldmode", patchIDMode(from.Mode()), "newmode", patchIDMode(to.Mode()))
+	}
+
+	if fp.IsBinary() {
+		zero := plumbing.ZeroHashFor(f).String()
+		fromHash, toHash := zero, zero
+		if from != nil {
+			fromHash = from.Hash().String()
+		}
+		if to != nil {
+			toHash = to.Hash().String()
+		}
+
+		writePatchIDString(h, fromHash, toHash)
+		return nil
+	}
+
+	switch {
+	case from == nil:
+		writePatchIDString(h, "---/dev/null", "+++b/", toPath)
+	case to == nil:
+		writePatchIDString(h, "---a/", fromPath, "+++/dev/null")
+	default:
+		writePatchIDString(h, "---a/", fromPath, "+++b/", toPath)
+	}
+
+	var buf bytes.Buffer
+	e := fdiff.NewUnifiedEncoder(&buf, patchIDContextLines)
+	if err := e.Encode(NewPatch("", []fdiff.FilePatch{fp})); err != nil {
+		return fmt.Errorf("patch-id of %s: %w", toPath, err)
+	}
+
+	// The lines of the hunks follow the first hunk header, whose headers
+	// are not hashed, as the lines about missing newlines.
+	var inHunks bool
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "@@") {
+			inHunks = true
+			continue
+		}
+
+		if inHunks && !strings.HasPrefix(line, "\\ ") {
+			writePatchIDString(h, line)
+		}
+	}
+
+	return nil
+}
+
+func patchIDPath(f fdiff.File) string {
+	if f == nil {
+		return ""
+	}
+
+	return f.Path()
+}
+
+func patchIDMode(m filemode.FileMode) string {
+	return fmt.Sprintf("%06o", uint32(m))
+}
+
+// writePatchIDString writes the strings to h, without their whitespaces.
+func writePatchIDString(h hash.Hash, ss ...string) {
+	for _, s := range ss {
+		_, _ = h.Write([]byte(strings.Map(func(r rune) rune {
+			switch r {
+			case ' ', '\t', '\n', '\r':
+				return -1
+			}
+
+			return r
+		}, s)))
+	}
+}
@@ -1,14 +1,22 @@
 package object
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 
 	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v6/plumbing/format/diff"
 	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/storage/memory"
 )
 
 type PatchSuite struct {
@@ -52,6 +60,138 @@ func (s *PatchSuite) TestStatsWithSubmodules() {
 	s.NotNil(p)
 }
 
+func (s *PatchSuite) TestPatchSubmodule() {
+	storer := filesystem.NewStorage(
+		fixtures.ByURL("https://github.com/git-fixtures/submodule.git").One().DotGit(), cache.NewObjectLRUDefault())
+
+	commit, err := GetCommit(storer, plumbing.NewHash("b685400c1f9316f350965a5993d350bc746b0bf4"))
+	s.Require().NoError(err)
+
+	tree, err := commit.Tree()
+	s.Require().NoError(err)
+
+	e, err := tree.entry("basic")
+	s.Require().NoError(err)
+
+	bumped := *e
+	bumped.Hash = plumbing.NewHash("a8a5c13a3d4b9e0b5b0f3f0c3c4e7d2e0b1e9a2f")
+
+	p, err := getPatch("", &Change{
+		From: ChangeEntry{Name: "basic", Tree: tree, TreeEntry: *e},
+		To:   ChangeEntry{Name: "basic", Tree: tree, TreeEntry: bumped},
+	})
+	s.Require().NoError(err)
+	s.Equal("diff --git a/basic b/basic\n"+
+		"index "+e.Hash.String()+".."+bumped.Hash.String()+" 160000\n"+
+		"--- a/basic\n"+
+		"+++ b/basic\n"+
+		"@@ -1 +1 @@\n"+
+		"-Subproject commit "+e.Hash.String()+"\n"+
+		"+Subproject commit "+bumped.Hash.String()+"\n", p.String())
+	s.Equal(FileStats{{Name: "basic", Addition: 1, Deletion: 1}}, p.Stats())
+
+	p, err = getPatch("", &Change{
+		To: ChangeEntry{Name: "basic", Tree: tree, TreeEntry: *e},
+	})
+	s.Require().NoError(err)
+	s.Contains(p.String(), "new file mode 160000\n")
+	s.Contains(p.String(), "@@ -0,0 +1 @@\n+Subproject commit "+e.Hash.String()+"\n")
+}
+
+func (s *PatchSuite) TestPatchTextConv() {
+	storer := filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault())
+
+	from, err := GetCommit(storer, plumbing.NewHash("b029517f6300c2da0f4b651b8642506cd6aaf45d"))
+	s.Require().NoError(err)
+
+	to, err := GetCommit(storer, plumbing.NewHash("35e85108805c84807bc66a02d91535e1e24b38b9"))
+	s.Require().NoError(err)
+
+	p, err := from.Patch(to)
+	s.Require().NoError(err)
+	s.Require().Len(p.FilePatches(), 1)
+	s.True(p.FilePatches()[0].IsBinary())
+
+	var paths []string
+	p, err = from.PatchWithOptions(context.Background(), to, &PatchOptions{
+		TextConv: func(path string, content []byte) ([]byte, bool, error) {
+			paths = append(paths, path)
+			return []byte(fmt.Sprintf("%d bytes\n", len(content))), true, nil
+		},
+	})
+	s.Require().NoError(err)
+	s.Equal([]string{"binary.jpg"}, paths)
+	s.Require().Len(p.FilePatches(), 1)
+	s.False(p.FilePatches()[0].IsBinary())
+	s.Contains(p.String(), "@@ -0,0 +1 @@\n+76110 bytes\n")
+
+	p, err = from.PatchWithOptions(context.Background(), to, &PatchOptions{
+		TextConv: func(string, []byte) ([]byte, bool, error) {
+			return nil, false, nil
+		},
+	})
+	s.Require().NoError(err)
+	s.True(p.FilePatches()[0].IsBinary())
+}
+
+func (s *PatchSuite) TestDiffBlobs() {
+	storer := memory.NewStorage()
+	blob := func(content string) *Blob {
+		obj := storer.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		s.Require().NoError(err)
+		_, err = w.Write([]byte(content))
+		s.Require().NoError(err)
+		s.Require().NoError(w.Close())
+
+		h, err := storer.SetEncodedObject(obj)
+		s.Require().NoError(err)
+
+		b, err := GetBlob(storer, h)
+		s.Require().NoError(err)
+		return b
+	}
+
+	a, b := blob("a\nb\n"), blob("a\nc\n")
+	p, err := DiffBlobs(a, b, nil)
+	s.Require().NoError(err)
+
+	s.Equal("diff --git a/422c2b7ab3b3c668038da977e4e93a5fc623169c b/422c2b7ab3b3c668038da977e4e93a5fc623169c\n"+
+		"index 422c2b7ab3b3c668038da977e4e93a5fc623169c..0f7bc766052a5a0ee28a393d51d2370f96d8ceb8 100644\n"+
+		"--- a/422c2b7ab3b3c668038da977e4e93a5fc623169c\n"+
+		"+++ b/422c2b7ab3b3c668038da977e4e93a5fc623169c\n"+
+		"@@ -1,2 +1,2 @@\n"+
+		" a\n"+
+		"-b\n"+
+		"+c\n", p.String())
+
+	p, err = DiffBlobs(nil, b, nil)
+	s.Require().NoError(err)
+	s.Require().Len(p.FilePatches(), 1)
+	from, to := p.FilePatches()[0].Files()
+	s.Nil(from)
+	s.Equal(b.Hash, to.Hash())
+	s.Contains(p.String(), "@@ -0,0 +1,2 @@\n+a\n+c\n")
+
+	bin := blob("\x00a\n")
+	p, err = DiffBlobs(a, bin, nil)
+	s.Require().NoError(err)
+	s.True(p.FilePatches()[0].IsBinary())
+
+	var paths []string
+	p, err = DiffBlobs(a, bin, &PatchOptions{
+		TextConv: func(path string, content []byte) ([]byte, bool, error) {
+			paths = append(paths, path)
+			return bytes.TrimPrefix(content, []byte{0}), true, nil
+		},
+	})
+	s.Require().NoError(err)
+	s.Equal([]string{a.Hash.String(), a.Hash.String()}, paths)
+	s.False(p.FilePatches()[0].IsBinary())
+	s.Contains(p.String(), "@@ -1,2 +1 @@\n a\n-b\n")
+}
+
 func (s *PatchSuite) TestFileStatsString() {
 	testCases := []struct {
 		description string
@@ -160,3 +300,127 @@ func (s *PatchSuite) TestFileStatsString() {
 		s.Equal(tc.expected, printStat(tc.input))
 	}
 }
+
+func (s *PatchSuite) TestEncodeWithOptions() {
+	st := memory.NewStorage()
+	tree := func(content string) *Tree {
+		blob := st.NewEncodedObject()
+		blob.SetType(plumbing.BlobObject)
+		w, err := blob.Writer()
+		s.Require().NoError(err)
+		_, err = w.Write([]byte(content))
+		s.Require().NoError(err)
+		s.Require().NoError(w.Close())
+		bh, err := st.SetEncodedObject(blob)
+		s.Require().NoError(err)
+
+		obj := st.NewEncodedObject()
+		t := &Tree{Entries: []TreeEntry{{Name: "main.go", Mode: filemode.Regular, Hash: bh}}}
+		s.Require().NoError(t.Encode(obj))
+		th, err := st.SetEncodedObject(obj)
+		s.Require().NoError(err)
+
+		t, err = GetTree(st, th)
+		s.Require().NoError(err)
+		return t
+	}
+
+	from := tree("package main\n\nfunc a() {\n\tx := 1\n\ty := 2\n\tz := 3\n\tw := 4\n}\n\nfunc b() {}\n")
+	to := tree("package main\n\nfunc a() {\n\tx := 1\n\ty := 2\n\tz := 30\n\tw := 4\n}\n\nfunc b() {}\n")
+
+	patch, err := from.Patch(to)
+	s.Require().NoError(err)
+
+	var def, buf bytes.Buffer
+	s.NoError(patch.Encode(&def))
+	s.NoError(patch.EncodeWithOptions(&buf, nil))
+	s.Equal(def.String(), buf.String())
+
+	hunk := func(opts *PatchEncodeOptions) string {
+		buf.Reset()
+		s.NoError(patch.EncodeWithOptions(&buf, opts))
+		_, h, _ := strings.Cut(buf.String(), "@@ -")
+		return "@@ -" + h
+	}
+
+	s.True(strings.HasSuffix(hunk(&PatchEncodeOptions{ContextLines: -1}), " @@ \ty := 2\n-\tz := 3\n+\tz := 30\n"))
+	s.Equal("@@ -5,3 +5,3 @@ \tx := 1\n \ty := 2\n-\tz := 3\n+\tz := 30\n \tw := 4\n",
+		hunk(&PatchEncodeOptions{ContextLines: 1}))
+	s.Equal("@@ -3,6 +3,6 @@ package main\n func a() {\n \tx := 1\n \ty := 2\n-\tz := 3\n+\tz := 30\n \tw := 4\n }\n",
+		hunk(&PatchEncodeOptions{ContextLines: -1, FunctionContext: true}))
+}
+
+type testFile struct {
+	path string
+	hash plumbing.Hash
+}
+
+func (f testFile) Hash() plumbing.Hash     { return f.hash }
+func (f testFile) Mode() filemode.FileMode { return filemode.Regular }
+func (f testFile) Path() string            { return f.path }
+
+func (s *PatchSuite) TestNewFilePatch() {
+	from := testFile{"foo", plumbing.NewHash("422c2b7ab3b3c668038da977e4e93a5fc623169c")}
+	to := testFile{"foo", plumbing.NewHash("0f7bc766052a5a0ee28a393d51d2370f96d8ceb8")}
+
+	fp, err := NewFilePatch(context.Background(), from, to, []byte("a\nb\n"), []byte("a\nc\n"))
+	s.Require().NoError(err)
+	s.False(fp.IsBinary())
+
+	p := NewPatch("", []fdiff.FilePatch{fp})
+	s.Equal(FileStats{{Name: "foo", Addition: 1, Deletion: 1}}, p.Stats())
+	s.Equal("diff --git a/foo b/foo\n"+
+		"index 422c2b7ab3b3c668038da977e4e93a5fc623169c..0f7bc766052a5a0ee28a393d51d2370f96d8ceb8 100644\n"+
+		"--- a/foo\n"+
+		"+++ b/foo\n"+
+		"@@ -1,2 +1,2 @@\n"+
+		" a\n"+
+		"-b\n"+
+		"+c\n", p.String())
+
+	fp, err = NewFilePatch(context.Background(), nil, to, nil, []byte("a\x00c\n"))
+	s.Require().NoError(err)
+	s.True(fp.IsBinary())
+	s.Empty(fp.Chunks())
+}
+
+func (s *PatchSuite) TestNumStatAndShortStat() {
+	ctx := context.Background()
+	h := plumbing.NewHash("422c2b7ab3b3c668038da977e4e93a5fc623169c")
+
+	modified, err := NewFilePatch(ctx, testFile{"foo", h}, testFile{"foo", h}, []byte("a\nb\n"), []byte("a\nc\nd\n"))
+	s.Require().NoError(err)
+	renamed, err := NewFilePatch(ctx, testFile{"dir/a.go", h}, testFile{"dir/b.go", h}, []byte("a\n"), []byte("a\n"))
+	s.Require().NoError(err)
+	binary, err := NewFilePatch(ctx, nil, testFile{"img.png", h}, nil, []byte("\x00\x01"))
+	s.Require().NoError(err)
+	deleted, err := NewFilePatch(ctx, testFile{"bar", h}, nil, []byte("x\ny"), nil)
+	s.Require().NoError(err)
+
+	p := NewPatch("", []fdiff.FilePatch{modified, renamed, binary, deleted})
+	s.Equal("2\t1\tfoo\n"+
+		"0\t0\tdir/{a.go => b.go}\n"+
+		"-\t-\timg.png\n"+
+		"0\t2\tbar\n", p.NumStat())
+	s.Equal(" 4 files changed, 2 insertions(+), 3 deletions(-)\n", p.ShortStat())
+
+	s.Equal(" 1 file changed, 0 insertions(+), 0 deletions(-)\n", NewPatch("", []fdiff.FilePatch{binary}).ShortStat())
+	s.Equal(" 1 file changed, 2 deletions(-)\n", NewPatch("", []fdiff.FilePatch{deleted}).ShortStat())
+	s.Empty(NewPatch("", nil).ShortStat())
+}
+
+func TestRenameName(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct{ from, to, expected string }{
+		{"a", "b", "a => b"},
+		{"dir/a.go", "dir/b.go", "dir/{a.go => b.go}"},
+		{"a/x/f", "b/x/f", "{a => b}/x/f"},
+		{"src/a/f.go", "src/b/f.go", "src/{a => b}/f.go"},
+		{"f.go", "dir/f.go", "f.go => dir/f.go"},
+		{"dir/f.go", "f.go", "dir/f.go => f.go"},
+		{"foo", "foo", "foo"},
+	} {
+		assert.Equal(t, tc.expected, renameName(tc.from, tc.to), "%s => %s", tc.from, tc.to)
+	}
+}
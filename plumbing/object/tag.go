@@ -2,6 +2,7 @@ package object
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -14,6 +15,10 @@ import (
 	"github.com/go-git/go-git/v6/utils/sync"
 )
 
+// ErrTagCycle is returned when peeling a chain of tags pointing back at one
+// of them.
+var ErrTagCycle = errors.New("cycle in chain of tags")
+
 // Tag represents an annotated tag object. It points to a single git object of
 // any type, but tags typically are applied to commit or blob objects. It
 // provides a reference that associates the target with a tag name. It also
@@ -40,6 +45,11 @@ type Tag struct {
 	Target plumbing.Hash
 
 	s storer.EncodedObjectStorer
+
+	// raw holds the bytes of a tag decoded with DecodeLenient, written as
+	// is by Encode as long as the tag is unchanged from decoded.
+	raw     []byte
+	decoded *Tag
 }
 
 // GetTag gets a tag from an object storer and decodes it.
@@ -52,6 +62,35 @@ func GetTag(s storer.EncodedObjectStorer, h plumbing.Hash) (*Tag, error) {
 	return DecodeTag(s, o)
 }
 
+// Peel returns the hash of the object that the object with hash h points to
+// through a chain of annotated tags, the first one which is not a tag, as
+// git peels the tags with <rev>^{}. It is h if the object is not a tag.
+func Peel(s storer.EncodedObjectStorer, h plumbing.Hash) (plumbing.Hash, error) {
+	seen := make(map[plumbing.Hash]bool)
+	for {
+		o, err := s.EncodedObject(plumbing.AnyObject, h)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		if o.Type() != plumbing.TagObject {
+			return h, nil
+		}
+
+		if seen[h] {
+			return plumbing.ZeroHash, fmt.Errorf("%w: %s", ErrTagCycle, h)
+		}
+
+		seen[h] = true
+		t, err := DecodeTag(s, o)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		h = t.Target
+	}
+}
+
 // DecodeTag decodes an encoded object into a *Commit and associates it to the
 // given object storer.
 func DecodeTag(s storer.EncodedObjectStorer, o plumbing.EncodedObject) (*Tag, error) {
@@ -80,13 +119,18 @@ func (t *Tag) Type() plumbing.ObjectType {
 
 // Decode transforms a plumbing.EncodedObject into a Tag struct.
 func (t *Tag) Decode(o plumbing.EncodedObject) (err error) {
+	return t.decode(o, nil)
+}
+
+func (t *Tag) decode(o plumbing.EncodedObject, ld *lenientDecoder) (err error) {
 	if o.Type() != plumbing.TagObject {
 		return ErrUnsupportedObject
 	}
 
 	t.Hash = o.Hash()
+	t.raw, t.decoded = nil, nil
 
-	reader, err := o.Reader()
+	reader, err := ld.reader(o)
 	if err != nil {
 		return err
 	}
@@ -102,27 +146,45 @@ func (t *Tag) Decode(o plumbing.EncodedObject) (err error) {
 			return err
 		}
 
+		ld.nextLine()
+
 		line = bytes.TrimSpace(line)
 		if len(line) == 0 {
 			break // Start of message
 		}
 
 		split := bytes.SplitN(line, []byte{' '}, 2)
+		var data []byte
+		if len(split) == 2 {
+			data = split[1]
+		}
+
+		if ld.duplicate(string(split[0])) {
+			continue
+		}
+
 		switch string(split[0]) {
 		case "object":
-			t.Target = plumbing.NewHash(string(split[1]))
+			t.Target = ld.hash(data)
 		case "type":
-			t.TargetType, err = plumbing.ParseObjectType(string(split[1]))
-			if err != nil {
-				return err
+			var perr error
+			t.TargetType, perr = plumbing.ParseObjectType(string(data))
+			if perr != nil {
+				if ld == nil {
+					return perr
+				}
+
+				ld.warn(fmt.Sprintf("invalid object type %q", data))
 			}
 		case "tag":
-			t.Name = string(split[1])
+			t.Name = string(data)
 		case "tagger":
-			t.Tagger.Decode(split[1])
+			ld.signature(&t.Tagger, data)
 		}
 
 		if err == io.EOF {
+			ld.warn("missing blank line before the message")
+			t.setDecoded(ld)
 			return nil
 		}
 	}
@@ -136,10 +198,19 @@ func (t *Tag) Decode(o plumbing.EncodedObject) (err error) {
 		data = data[:sm]
 	}
 	t.Message = string(data)
+	t.setDecoded(ld)
 
 	return nil
 }
 
+// setDecoded keeps the bytes of a tag decoded with DecodeLenient.
+func (t *Tag) setDecoded(ld *lenientDecoder) {
+	if ld != nil {
+		t.raw = ld.raw
+		t.decoded = t.snapshot()
+	}
+}
+
 // Encode transforms a Tag into a plumbing.EncodedObject.
 func (t *Tag) Encode(o plumbing.EncodedObject) error {
 	return t.encode(o, true)
@@ -158,6 +229,11 @@ func (t *Tag) encode(o plumbing.EncodedObject, includeSig bool) (err error) {
 	}
 	defer ioutil.CheckClose(w, &err)
 
+	if includeSig && t.raw != nil && t.unchanged() {
+		_, err = w.Write(t.raw)
+		return err
+	}
+
 	if _, err = fmt.Fprintf(w,
 		"object %s\ntype %s\ntag %s\ntagger ",
 		t.Target.String(), t.TargetType.Bytes(), t.Name); err != nil {
@@ -190,14 +266,20 @@ func (t *Tag) encode(o plumbing.EncodedObject, includeSig bool) (err error) {
 	return err
 }
 
-// Commit returns the commit pointed to by the tag. If the tag points to a
-// different type of object ErrUnsupportedObject will be returned.
+// Commit returns the commit pointed to by the tag, through a chain of tags
+// if needed. If the tag points to a different type of object
+// ErrUnsupportedObject will be returned.
 func (t *Tag) Commit() (*Commit, error) {
-	if t.TargetType != plumbing.CommitObject {
+	typ, h, err := t.peeledTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != plumbing.CommitObject {
 		return nil, ErrUnsupportedObject
 	}
 
-	o, err := t.s.EncodedObject(plumbing.CommitObject, t.Target)
+	o, err := t.s.EncodedObject(plumbing.CommitObject, h)
 	if err != nil {
 		return nil, err
 	}
@@ -205,36 +287,48 @@ func (t *Tag) Commit() (*Commit, error) {
 	return DecodeCommit(t.s, o)
 }
 
-// Tree returns the tree pointed to by the tag. If the tag points to a commit
-// object the tree of that commit will be returned. If the tag does not point
-// to a commit or tree object ErrUnsupportedObject will be returned.
+// Tree returns the tree pointed to by the tag, through a chain of tags if
+// needed. If the tag points to a commit object the tree of that commit will
+// be returned. If the tag does not point to a commit or tree object
+// ErrUnsupportedObject will be returned.
 func (t *Tag) Tree() (*Tree, error) {
-	switch t.TargetType {
+	typ, h, err := t.peeledTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
 	case plumbing.CommitObject:
-		c, err := t.Commit()
+		c, err := GetCommit(t.s, h)
 		if err != nil {
 			return nil, err
 		}
 
 		return c.Tree()
 	case plumbing.TreeObject:
-		return GetTree(t.s, t.Target)
+		return GetTree(t.s, h)
 	default:
 		return nil, ErrUnsupportedObject
 	}
 }
 
-// Blob returns the blob pointed to by the tag. If the tag points to a
-// different type of object ErrUnsupportedObject will be returned.
+// Blob returns the blob pointed to by the tag, through a chain of tags if
+// needed. If the tag points to a different type of object
+// ErrUnsupportedObject will be returned.
 func (t *Tag) Blob() (*Blob, error) {
-	if t.TargetType != plumbing.BlobObject {
+	typ, h, err := t.peeledTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != plumbing.BlobObject {
 		return nil, ErrUnsupportedObject
 	}
 
-	return GetBlob(t.s, t.Target)
+	return GetBlob(t.s, h)
 }
 
-// Object returns the object pointed to by the tag.
+// Object returns the object pointed to by the tag, which may be another tag.
 func (t *Tag) Object() (Object, error) {
 	o, err := t.s.EncodedObject(t.TargetType, t.Target)
 	if err != nil {
@@ -244,6 +338,37 @@ func (t *Tag) Object() (Object, error) {
 	return DecodeObject(t.s, o)
 }
 
+// Peel returns the object pointed to by the tag through the chain of tags
+// starting with it, the first one which is not a tag. ErrTagCycle is
+// returned if the chain points back at one of its tags.
+func (t *Tag) Peel() (Object, error) {
+	if t.TargetType != plumbing.TagObject {
+		return t.Object()
+	}
+
+	h, err := Peel(t.s, t.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetObject(t.s, h)
+}
+
+// peeledTarget returns the type and the hash of the object pointed to by the
+// chain of tags starting with the tag.
+func (t *Tag) peeledTarget() (plumbing.ObjectType, plumbing.Hash, error) {
+	if t.TargetType != plumbing.TagObject {
+		return t.TargetType, t.Target, nil
+	}
+
+	o, err := t.Peel()
+	if err != nil {
+		return plumbing.InvalidObject, plumbing.ZeroHash, err
+	}
+
+	return o.Type(), o.ID(), nil
+}
+
 // String returns the meta information contained in the tag as a formatted
 // string.
 func (t *Tag) String() string {
@@ -131,6 +131,70 @@ func (s *TagSuite) TestObject() {
 	s.Equal("f7b877701fbf855b44c0a9e86f3fdce2c298b07f", obj.ID().String())
 }
 
+func (s *TagSuite) TestTagChain() {
+	st := memory.NewStorage()
+
+	blob := &plumbing.MemoryObject{}
+	blob.SetType(plumbing.BlobObject)
+	_, err := blob.Write([]byte("content"))
+	s.Require().NoError(err)
+	blobHash, err := st.SetEncodedObject(blob)
+	s.Require().NoError(err)
+
+	target, targetType := blobHash, plumbing.BlobObject
+	var tag *Tag
+	for _, name := range []string{"inner", "middle", "outer"} {
+		tag = &Tag{Name: name, TargetType: targetType, Target: target, s: st}
+		o := st.NewEncodedObject()
+		s.Require().NoError(tag.Encode(o))
+		tag.Hash, err = st.SetEncodedObject(o)
+		s.Require().NoError(err)
+
+		target, targetType = tag.Hash, plumbing.TagObject
+	}
+
+	peeled, err := Peel(st, tag.Hash)
+	s.Require().NoError(err)
+	s.Equal(blobHash, peeled)
+
+	peeled, err = Peel(st, blobHash)
+	s.Require().NoError(err)
+	s.Equal(blobHash, peeled)
+
+	obj, err := tag.Peel()
+	s.Require().NoError(err)
+	s.Equal(blobHash, obj.ID())
+
+	b, err := tag.Blob()
+	s.Require().NoError(err)
+	s.Equal(blobHash, b.Hash)
+
+	_, err = tag.Commit()
+	s.ErrorIs(err, ErrUnsupportedObject)
+
+	obj, err = tag.Object()
+	s.Require().NoError(err)
+	s.Equal(plumbing.TagObject, obj.Type())
+}
+
+func (s *TagSuite) TestTagCycle() {
+	st := memory.NewStorage()
+
+	// A tag stored under the hash of its target, as a corrupted or replaced
+	// object could be.
+	h := plumbing.NewHash("b742a2a9fa0afcfa9a6fad080980fbc26b007c69")
+	tag := &Tag{Name: "cycle", TargetType: plumbing.TagObject, Target: h, s: st}
+	o := st.NewEncodedObject()
+	s.Require().NoError(tag.Encode(o))
+	st.Objects[h] = o
+
+	_, err := Peel(st, h)
+	s.ErrorIs(err, ErrTagCycle)
+
+	_, err = tag.Commit()
+	s.ErrorIs(err, ErrTagCycle)
+}
+
 func (s *TagSuite) TestTagItter() {
 	iter, err := s.Storer.IterEncodedObjects(plumbing.TagObject)
 	s.NoError(err)
@@ -487,3 +551,36 @@ func (s *TagSuite) TestEncodeWithoutSignature() {
 		string(payload),
 	)
 }
+
+func (s *TagSuite) TestDecodeLenient() {
+	raw := "object f7b877701fbf855b44c0a9e86f3fdce2c298b07f\n" +
+		"type unknown\n" +
+		"tag v1.0\n" +
+		"tag v2.0\n" +
+		"tagger\n" +
+		"\n" +
+		"message\n"
+
+	obj := &plumbing.MemoryObject{}
+	obj.SetType(plumbing.TagObject)
+	_, err := obj.Write([]byte(raw))
+	s.Require().NoError(err)
+
+	strict := &Tag{}
+	s.Error(strict.Decode(obj))
+
+	tag := &Tag{}
+	warnings, err := tag.DecodeLenient(obj)
+	s.Require().NoError(err)
+	s.Equal([]DecodeWarning{
+		{Line: 2, Message: `invalid object type "unknown"`},
+		{Line: 4, Message: `duplicate "tag" header ignored`},
+		{Line: 5, Message: `malformed signature ""`},
+	}, warnings)
+	s.Equal("v1.0", tag.Name)
+	s.Equal("message\n", tag.Message)
+
+	encoded := &plumbing.MemoryObject{}
+	s.Require().NoError(tag.Encode(encoded))
+	s.Equal(obj.Hash(), encoded.Hash())
+}
@@ -374,6 +374,17 @@ func (t *Tree) PatchContext(ctx context.Context, to *Tree) (*Patch, error) {
 	return changes.PatchContext(ctx)
 }
 
+// PatchWithOptions returns the Patch between trees, as PatchContext does,
+// computed with the given options.
+func (t *Tree) PatchWithOptions(ctx context.Context, to *Tree, opts *PatchOptions) (*Patch, error) {
+	changes, err := t.DiffContext(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes.PatchWithOptions(ctx, opts)
+}
+
 // treeEntryIter facilitates iterating through the TreeEntry objects in a Tree.
 type treeEntryIter struct {
 	t   *Tree
@@ -394,27 +405,71 @@ type TreeWalker struct {
 	base      string
 	recursive bool
 	seen      map[plumbing.Hash]bool
+	prefetch  bool
 
 	s storer.EncodedObjectStorer
 	t *Tree
 }
 
+// TreeWalkerOptions are the options of a TreeWalker.
+type TreeWalkerOptions struct {
+	// Recursive walks the subtrees too.
+	Recursive bool
+	// Seen are the hashes of the entries to skip.
+	Seen map[plumbing.Hash]bool
+	// Prefetch reads the objects of the entries of each tree before they
+	// are walked, in one batch, when the storer implements
+	// storer.ObjectPrefetcher. On packed repositories, the objects are then
+	// read in the order they are stored instead of one at a time.
+	Prefetch bool
+}
+
 // NewTreeWalker returns a new TreeWalker for the given tree.
 //
 // It is the caller's responsibility to call Close() when finished with the
 // tree walker.
 func NewTreeWalker(t *Tree, recursive bool, seen map[plumbing.Hash]bool) *TreeWalker {
-	stack := make([]*treeEntryIter, 0, startingStackSize)
-	stack = append(stack, &treeEntryIter{t, 0})
+	return NewTreeWalkerWithOptions(t, TreeWalkerOptions{Recursive: recursive, Seen: seen})
+}
 
-	return &TreeWalker{
-		stack:     stack,
-		recursive: recursive,
-		seen:      seen,
+// NewTreeWalkerWithOptions returns a new TreeWalker for the given tree, with
+// the given options.
+//
+// It is the caller's responsibility to call Close() when finished with the
+// tree walker.
+func NewTreeWalkerWithOptions(t *Tree, opts TreeWalkerOptions) *TreeWalker {
+	w := &TreeWalker{
+		stack:     make([]*treeEntryIter, 0, startingStackSize),
+		recursive: opts.Recursive,
+		seen:      opts.Seen,
+		prefetch:  opts.Prefetch,
 
 		s: t.s,
 		t: t,
 	}
+
+	w.push(t)
+	return w
+}
+
+// push adds the tree t to the stack, prefetching the objects of its entries
+// if requested.
+func (w *TreeWalker) push(t *Tree) {
+	w.stack = append(w.stack, &treeEntryIter{t, 0})
+	if !w.prefetch || w.s == nil {
+		return
+	}
+
+	hashes := make([]plumbing.Hash, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		if e.Mode != filemode.Submodule && !w.seen[e.Hash] {
+			hashes = append(hashes, e.Hash)
+		}
+	}
+
+	// Prefetching is an optimization only, the objects which could not be
+	// prefetched are read when needed.
+	_ = storer.PrefetchObjects(w.s, hashes)
 }
 
 // Next returns the next object from the tree. Objects are returned in order
@@ -476,7 +531,7 @@ func (w *TreeWalker) Next() (name string, entry TreeEntry, err error) {
 	}
 
 	if obj != nil {
-		w.stack = append(w.stack, &treeEntryIter{obj, 0})
+		w.push(obj)
 		w.base = simpleJoin(w.base, entry.Name)
 	}
 
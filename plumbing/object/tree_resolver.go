@@ -0,0 +1,99 @@
+package object
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+)
+
+// PathResolver resolves paths relative to a root tree, memoizing every
+// subtree it decodes for its lifetime. Listing a directory and reading the
+// files under it decode each tree in their paths once.
+//
+// A PathResolver is not safe for concurrent use.
+type PathResolver struct {
+	root  *Tree
+	trees map[string]*Tree
+}
+
+// NewPathResolver returns a PathResolver for paths relative to root.
+func NewPathResolver(root *Tree) *PathResolver {
+	return &PathResolver{
+		root:  root,
+		trees: make(map[string]*Tree),
+	}
+}
+
+// Tree returns the tree at the given path, or the root tree if the path is
+// empty. The returned tree is shared by all callers and must not be modified.
+func (r *PathResolver) Tree(p string) (*Tree, error) {
+	p = cleanTreePath(p)
+	if p == "" {
+		return r.root, nil
+	}
+
+	if t, ok := r.trees[p]; ok {
+		return t, nil
+	}
+
+	parent, err := r.Tree(path.Dir(p))
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := parent.entry(path.Base(p))
+	if err != nil || e.Mode != filemode.Dir {
+		return nil, ErrDirectoryNotFound
+	}
+
+	t, err := GetTree(r.root.s, e.Hash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, ErrDirectoryNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.trees[p] = t
+	return t, nil
+}
+
+// FindEntry returns the entry at the given path.
+func (r *PathResolver) FindEntry(p string) (*TreeEntry, error) {
+	p = cleanTreePath(p)
+	t, err := r.Tree(path.Dir(p))
+	if err != nil {
+		return nil, err
+	}
+
+	return t.entry(path.Base(p))
+}
+
+// File returns the file at the given path.
+func (r *PathResolver) File(p string) (*File, error) {
+	e, err := r.FindEntry(p)
+	if err != nil || !e.Mode.IsFile() {
+		return nil, ErrFileNotFound
+	}
+
+	blob, err := GetBlob(r.root.s, e.Hash)
+	if err != nil {
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+
+	return NewFile(cleanTreePath(p), e.Mode, blob), nil
+}
+
+// cleanTreePath returns p without leading and trailing slashes, and "" for
+// the root.
+func cleanTreePath(p string) string {
+	p = path.Clean("/" + p)
+	return strings.TrimPrefix(p, "/")
+}
@@ -217,6 +217,56 @@ func (s *TreeSuite) TestFindEntryCacheDepth2() {
 	s.Equal(1, cs.calls[jsonHash], "second FindEntry should reuse cached json tree")
 }
 
+func (s *TreeSuite) TestPathResolver() {
+	cs := newCountingStorer(s.Storer)
+
+	hash := plumbing.NewHash("a8d315b2b1c615d43042c3a62402b8a54288cf5c")
+	tree, err := GetTree(cs, hash)
+	s.Require().NoError(err)
+
+	jsonEntry, err := tree.entry("json")
+	s.Require().NoError(err)
+
+	cs.calls = make(map[plumbing.Hash]int)
+	r := NewPathResolver(tree)
+
+	root, err := r.Tree("")
+	s.Require().NoError(err)
+	s.Same(tree, root)
+
+	dir, err := r.Tree("json/")
+	s.Require().NoError(err)
+	s.Equal(jsonEntry.Hash, dir.Hash)
+
+	for _, e := range dir.Entries {
+		f, err := r.File("json/" + e.Name)
+		s.Require().NoError(err)
+		s.Equal("json/"+e.Name, f.Name)
+		s.Equal(e.Hash, f.Hash)
+	}
+
+	e, err := r.FindEntry("/json/short.json")
+	s.Require().NoError(err)
+	s.Equal("short.json", e.Name)
+
+	s.Equal(1, cs.calls[jsonEntry.Hash], "the json tree should be decoded once")
+}
+
+func (s *TreeSuite) TestPathResolverNotFound() {
+	r := NewPathResolver(s.Tree)
+
+	_, err := r.Tree("not-found")
+	s.ErrorIs(err, ErrDirectoryNotFound)
+	_, err = r.Tree("LICENSE")
+	s.ErrorIs(err, ErrDirectoryNotFound)
+	_, err = r.FindEntry("json/not-found")
+	s.ErrorIs(err, ErrEntryNotFound)
+	_, err = r.FindEntry("not-found/not-found")
+	s.ErrorIs(err, ErrDirectoryNotFound)
+	_, err = r.File("json")
+	s.ErrorIs(err, ErrFileNotFound)
+}
+
 // Overrides returned plumbing.EncodedObject for given hash.
 // Otherwise, delegates to actual storer to get real object
 type fakeStorer struct {
@@ -437,6 +487,31 @@ func (s *TreeSuite) TestTreeWalkerNext() {
 	}
 }
 
+func (s *TreeSuite) TestTreeWalkerNextPrefetch() {
+	ch := cache.NewObjectLRUDefault()
+	st := filesystem.NewStorage(s.Fixture.DotGit(), ch)
+	commit, err := GetCommit(st, plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"))
+	s.NoError(err)
+	tree, err := commit.Tree()
+	s.NoError(err)
+
+	walker := NewTreeWalkerWithOptions(tree, TreeWalkerOptions{Recursive: true, Prefetch: true})
+	defer walker.Close()
+
+	for _, e := range treeWalkerExpects {
+		name, entry, err := walker.Next()
+		s.Require().NoError(err)
+		s.Equal(e.Path, name)
+		s.Equal(e.Hash, entry.Hash.String())
+
+		_, ok := ch.Get(entry.Hash)
+		s.True(ok, name)
+	}
+
+	_, _, err = walker.Next()
+	s.ErrorIs(err, io.EOF)
+}
+
 func (s *TreeSuite) TestTreeWalkerNextSkipSeen() {
 	commit, err := GetCommit(s.Storer, plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"))
 	s.NoError(err)
@@ -64,6 +64,12 @@ type ObjectID struct {
 	format format.ObjectFormat
 }
 
+// Format returns the object format of the ObjectID, SHA-1 ones having an
+// unset format.
+func (s ObjectID) Format() format.ObjectFormat {
+	return s.format
+}
+
 // HexSize returns the size for the hex representation of the current
 // object.
 func (s ObjectID) HexSize() int {
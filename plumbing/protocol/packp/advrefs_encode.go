@@ -6,6 +6,7 @@ import (
 	"sort"
 
 	"github.com/go-git/go-git/v6/plumbing"
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
 	"github.com/go-git/go-git/v6/plumbing/format/pktline"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp/capability"
 )
@@ -92,7 +93,14 @@ func encodeFirstLine(e *advRefsEncoder) encoderStateFn {
 	capabilities := formatCaps(e.data.Capabilities)
 
 	if e.firstRefName == "" {
-		firstLine = fmt.Sprintf(formatFirstLine, plumbing.ZeroHash.String(), "capabilities^{}", capabilities)
+		of := format.SHA1
+		if e.data.Capabilities != nil {
+			if v := e.data.Capabilities.Get(capability.ObjectFormat); len(v) > 0 {
+				of = format.ObjectFormat(v[0])
+			}
+		}
+
+		firstLine = fmt.Sprintf(formatFirstLine, plumbing.ZeroHashFor(of).String(), "capabilities^{}", capabilities)
 	} else {
 		firstLine = fmt.Sprintf(formatFirstLine, e.firstRefHash.String(), e.firstRefName, capabilities)
 	}
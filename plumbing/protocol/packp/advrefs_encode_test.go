@@ -75,6 +75,21 @@ func (s *AdvRefsEncodeSuite) TestCapsNoHead() {
 	testEncode(s, ar, expected)
 }
 
+func (s *AdvRefsEncodeSuite) TestCapsNoHeadSHA256() {
+	capabilities := capability.NewList()
+	capabilities.Add(capability.ObjectFormat, "sha256")
+	ar := &AdvRefs{
+		Capabilities: capabilities,
+	}
+
+	expected := pktlines(s.T(),
+		strings.Repeat("0", 64)+" capabilities^{}\x00object-format=sha256\n",
+		"",
+	)
+
+	testEncode(s, ar, expected)
+}
+
 func (s *AdvRefsEncodeSuite) TestCapsWithHead() {
 	hash := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
 	capabilities := capability.NewList()
@@ -19,7 +19,8 @@ const chLen = 1
 //
 // If t is equal to `Sideband` the max pack size is set to MaxPackedSize, in any
 // other value is given, max pack is set to MaxPackedSize64k, that is the
-// maximum length of a line in pktline format.
+// maximum length of a line in pktline format. The size of the packets written
+// includes their length and channel.
 func NewMuxer(t Type, w io.Writer) *Muxer {
 	maxSize := MaxPackedSize64k
 	if t == Sideband {
@@ -27,7 +28,7 @@ func NewMuxer(t Type, w io.Writer) *Muxer {
 	}
 
 	return &Muxer{
-		max: maxSize - chLen,
+		max: maxSize - pktline.LenSize - chLen,
 		w:   w,
 	}
 }
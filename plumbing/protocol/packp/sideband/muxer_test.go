@@ -9,10 +9,22 @@ func (s *SidebandSuite) TestMuxerWrite() {
 
 	m := NewMuxer(Sideband, buf)
 
-	n, err := m.Write(bytes.Repeat([]byte{'F'}, (MaxPackedSize-1)*2))
+	n, err := m.Write(bytes.Repeat([]byte{'F'}, (MaxPackedSize-5)*2))
 	s.NoError(err)
-	s.Equal(1998, n)
-	s.Equal(2008, buf.Len())
+	s.Equal(1990, n)
+	s.Equal(2000, buf.Len())
+}
+
+func (s *SidebandSuite) TestMuxerWrite64k() {
+	buf := bytes.NewBuffer(nil)
+
+	m := NewMuxer(Sideband64k, buf)
+
+	n, err := m.Write(bytes.Repeat([]byte{'F'}, MaxPackedSize64k))
+	s.NoError(err)
+	s.Equal(MaxPackedSize64k, n)
+	s.Equal("fff0\x01", buf.String()[:5])
+	s.Equal(MaxPackedSize64k+10, buf.Len())
 }
 
 func (s *SidebandSuite) TestMuxerWriteChannelMultipleChannels() {
@@ -3,6 +3,7 @@ package packp
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v6/plumbing"
@@ -21,7 +22,7 @@ type UploadRequest struct {
 }
 
 // Depth values stores the desired depth of the requested packfile: see
-// DepthCommit, DepthSince and DepthReference.
+// DepthCommit, DepthSince, DepthReference and DepthReferences.
 type Depth interface {
 	fmt.Stringer
 	IsZero() bool
@@ -65,6 +66,19 @@ func (d DepthReference) String() string {
 	return string(d)
 }
 
+// DepthReferences requests only commits not found in any of the specified
+// references, sending a deepen-not line for each of them.
+type DepthReferences []string
+
+// IsZero returns true if there are no references.
+func (d DepthReferences) IsZero() bool {
+	return len(d) == 0
+}
+
+func (d DepthReferences) String() string {
+	return strings.Join(d, ",")
+}
+
 // NewUploadRequest returns a pointer to a new UploadRequest value, ready to be
 // used. It has no capabilities, wants or shallows and an infinite depth. Please
 // note that to encode an upload-request it has to have at least one wanted hash.
@@ -235,9 +235,25 @@ func (d *ulReqDecoder) decodeDeepenSince() stateFn {
 func (d *ulReqDecoder) decodeDeepenReference() stateFn {
 	d.line = bytes.TrimPrefix(d.line, deepenReference)
 
-	d.data.Depth = DepthReference(string(d.line))
+	reference := string(d.line)
+	switch depth := d.data.Depth.(type) {
+	case DepthReference:
+		d.data.Depth = DepthReferences{string(depth), reference}
+	case DepthReferences:
+		d.data.Depth = append(depth, reference)
+	default:
+		d.data.Depth = DepthReference(reference)
+	}
 
-	return d.decodeFlush
+	if ok := d.nextLine(); !ok {
+		return nil
+	}
+
+	if bytes.HasPrefix(d.line, deepenReference) {
+		return d.decodeDeepenReference
+	}
+
+	return d.checkFlush
 }
 
 func (d *ulReqDecoder) decodeFlush() stateFn {
@@ -245,6 +261,10 @@ func (d *ulReqDecoder) decodeFlush() stateFn {
 		return nil
 	}
 
+	return d.checkFlush
+}
+
+func (d *ulReqDecoder) checkFlush() stateFn {
 	if len(d.line) != 0 {
 		d.err = fmt.Errorf("unexpected payload while expecting a flush-pkt: %q", d.line)
 	}
@@ -527,6 +527,18 @@ func (s *UlReqDecodeSuite) TestDeepenReference() {
 	s.Equal(expected, string(reference))
 }
 
+func (s *UlReqDecodeSuite) TestDeepenReferences() {
+	payloads := []string{
+		"want 3333333333333333333333333333333333333333 ofs-delta multi_ack",
+		"deepen-not refs/heads/master",
+		"deepen-not refs/tags/v1.0.0",
+		"",
+	}
+	ur, _ := s.testDecodeOK(payloads, 0)
+
+	s.Equal(DepthReferences{"refs/heads/master", "refs/tags/v1.0.0"}, ur.Depth)
+}
+
 func (s *UlReqDecodeSuite) TestAll() {
 	payloads := []string{
 		"want 3333333333333333333333333333333333333333 ofs-delta multi_ack\n",
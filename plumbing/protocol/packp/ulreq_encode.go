@@ -125,6 +125,13 @@ func (e *ulReqEncoder) encodeDepth() stateFn {
 			e.err = fmt.Errorf("encoding depth %s: %s", reference, err)
 			return nil
 		}
+	case DepthReferences:
+		for _, reference := range depth {
+			if _, err := pktline.Writef(e.w, "deepen-not %s\n", reference); err != nil {
+				e.err = fmt.Errorf("encoding depth %s: %s", reference, err)
+				return nil
+			}
+		}
 	default:
 		e.err = fmt.Errorf("unsupported depth type")
 		return nil
@@ -280,6 +280,21 @@ func (s *UlReqEncodeSuite) TestDepthReference() {
 	testUlReqEncode(s, ur, expected)
 }
 
+func (s *UlReqEncodeSuite) TestDepthReferences() {
+	ur := NewUploadRequest()
+	ur.Wants = append(ur.Wants, plumbing.NewHash("1111111111111111111111111111111111111111"))
+	ur.Depth = DepthReferences{"refs/heads/feature-foo", "v1.0.0"}
+
+	expected := []string{
+		"want 1111111111111111111111111111111111111111\n",
+		"deepen-not refs/heads/feature-foo\n",
+		"deepen-not v1.0.0\n",
+		"",
+	}
+
+	testUlReqEncode(s, ur, expected)
+}
+
 func (s *UlReqEncodeSuite) TestFilter() {
 	ur := NewUploadRequest()
 	ur.Wants = append(ur.Wants, plumbing.NewHash("1111111111111111111111111111111111111111"))
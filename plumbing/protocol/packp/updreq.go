@@ -64,15 +64,15 @@ type Command struct {
 
 // Action returns the action type of the command.
 func (c *Command) Action() Action {
-	if c.Old == plumbing.ZeroHash && c.New == plumbing.ZeroHash {
+	if c.Old.IsZero() && c.New.IsZero() {
 		return Invalid
 	}
 
-	if c.Old == plumbing.ZeroHash {
+	if c.Old.IsZero() {
 		return Create
 	}
 
-	if c.New == plumbing.ZeroHash {
+	if c.New.IsZero() {
 		return Delete
 	}
 
@@ -222,6 +222,11 @@ const (
 	Master ReferenceName = "refs/heads/master"
 	// Main is the main branch reference.
 	Main ReferenceName = "refs/heads/main"
+	// OrigHead is the reference to the previous HEAD, recorded by the
+	// operations moving it drastically, such as a reset or a merge.
+	OrigHead ReferenceName = "ORIG_HEAD"
+	// CherryPickHead is the reference to the commit being cherry-picked.
+	CherryPickHead ReferenceName = "CHERRY_PICK_HEAD"
 	// Invalid defines an invalid reference target which is used for specific
 	// workflows on upstream Git.
 	Invalid ReferenceName = "refs/heads/.invalid"
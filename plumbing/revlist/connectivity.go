@@ -0,0 +1,168 @@
+package revlist
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+)
+
+// MissingObjectError is returned by CheckConnectivity when an object reachable
+// from the tips is not in the storage.
+type MissingObjectError struct {
+	// Hash is the hash of the missing object.
+	Hash plumbing.Hash
+	// Referrer is the hash of the object referencing the missing one, zero if
+	// the missing object is one of the tips.
+	Referrer plumbing.Hash
+	// Path is the path of the missing object relative to the root tree it is
+	// reachable from, empty for commits, tags and root trees.
+	Path string
+}
+
+func (e *MissingObjectError) Error() string {
+	switch {
+	case e.Referrer.IsZero():
+		return fmt.Sprintf("missing object %s", e.Hash)
+	case e.Path == "":
+		return fmt.Sprintf("missing object %s referenced by %s", e.Hash, e.Referrer)
+	default:
+		return fmt.Sprintf("missing object %s at %q referenced by %s", e.Hash, e.Path, e.Referrer)
+	}
+}
+
+// Unwrap returns plumbing.ErrObjectNotFound.
+func (e *MissingObjectError) Unwrap() error {
+	return plumbing.ErrObjectNotFound
+}
+
+// CheckConnectivity checks that all the objects reachable from the given tips
+// are in the storage, failing with a *MissingObjectError on the first missing
+// one. The parents of the shallow commits are not checked. Blobs are checked
+// for existence without being read. When s is a storer.PromisorObjectStorer,
+// the missing objects referenced by promisor objects are skipped, as they are
+// promised by the remote of a partial clone.
+func CheckConnectivity(s storer.EncodedObjectStorer, tips, shallow []plumbing.Hash) error {
+	return CheckConnectivityWithHaves(s, tips, nil, shallow)
+}
+
+// CheckConnectivityWithHaves is like CheckConnectivity, but the haves are
+// assumed to be connected, as the tips of the existing references are: the
+// walk stops at them, so only the objects new to the storage are usually
+// checked.
+func CheckConnectivityWithHaves(s storer.EncodedObjectStorer, tips, haves, shallow []plumbing.Hash) error {
+	c := &connectivityChecker{
+		s:       s,
+		shallow: hashListToSet(shallow),
+		seen:    hashListToSet(haves),
+	}
+
+	for _, h := range tips {
+		c.push(h, plumbing.ZeroHash, "")
+	}
+
+	for len(c.pending) > 0 {
+		p := c.pending[len(c.pending)-1]
+		c.pending = c.pending[:len(c.pending)-1]
+		if err := c.check(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pendingObject is an object to be checked by a connectivityChecker.
+type pendingObject struct {
+	hash     plumbing.Hash
+	referrer plumbing.Hash
+	path     string
+}
+
+type connectivityChecker struct {
+	s       storer.EncodedObjectStorer
+	shallow map[plumbing.Hash]bool
+	seen    map[plumbing.Hash]bool
+	pending []pendingObject
+}
+
+func (c *connectivityChecker) push(h, referrer plumbing.Hash, path string) {
+	if c.seen[h] {
+		return
+	}
+
+	c.seen[h] = true
+	c.pending = append(c.pending, pendingObject{hash: h, referrer: referrer, path: path})
+}
+
+// missing returns the error of the missing object p, or nil if it is
+// promised.
+func (c *connectivityChecker) missing(p pendingObject, err error) error {
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return err
+	}
+
+	if !p.referrer.IsZero() && isPromised(c.s, p.referrer, err) {
+		return nil
+	}
+
+	return &MissingObjectError{Hash: p.hash, Referrer: p.referrer, Path: p.path}
+}
+
+func (c *connectivityChecker) check(p pendingObject) error {
+	o, err := c.s.EncodedObject(plumbing.AnyObject, p.hash)
+	if err != nil {
+		return c.missing(p, err)
+	}
+
+	do, err := object.DecodeObject(c.s, o)
+	if err != nil {
+		return fmt.Errorf("decoding object: %w", err)
+	}
+
+	switch do := do.(type) {
+	case *object.Commit:
+		if !c.shallow[do.Hash] {
+			for _, h := range do.ParentHashes {
+				c.push(h, do.Hash, "")
+			}
+		}
+
+		c.push(do.TreeHash, do.Hash, "")
+	case *object.Tree:
+		return c.checkTree(do, p.path)
+	case *object.Tag:
+		c.push(do.Target, do.Hash, "")
+	case *object.Blob:
+	default:
+		return fmt.Errorf("object type not valid: %s. "+
+			"Object reference: %s", o.Type(), o.Hash())
+	}
+
+	return nil
+}
+
+func (c *connectivityChecker) checkTree(t *object.Tree, dir string) error {
+	for _, e := range t.Entries {
+		if e.Mode == filemode.Submodule || c.seen[e.Hash] {
+			continue
+		}
+
+		name := path.Join(dir, e.Name)
+		if e.Mode == filemode.Dir {
+			c.push(e.Hash, t.Hash, name)
+			continue
+		}
+
+		c.seen[e.Hash] = true
+		if err := c.s.HasEncodedObject(e.Hash); err != nil {
+			return c.missing(pendingObject{hash: e.Hash, referrer: t.Hash, path: name}, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package revlist
+
+import (
+	"errors"
+	"testing"
+
+	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// copyStorageWithout returns a copy of the objects of s, except the given
+// ones.
+func copyStorageWithout(t *testing.T, s storer.EncodedObjectStorer, skip ...plumbing.Hash) storer.EncodedObjectStorer {
+	t.Helper()
+
+	dst := memory.NewStorage()
+	iter, err := s.IterEncodedObjects(plumbing.AnyObject)
+	require.NoError(t, err)
+	err = iter.ForEach(func(o plumbing.EncodedObject) error {
+		for _, h := range skip {
+			if o.Hash() == h {
+				return nil
+			}
+		}
+
+		_, err := dst.SetEncodedObject(o)
+		return err
+	})
+	require.NoError(t, err)
+
+	return dst
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	t.Parallel()
+
+	sto := filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault())
+	tips := []plumbing.Hash{
+		plumbing.NewHash(someCommitBranch),
+		plumbing.NewHash(someCommitOtherBranch),
+	}
+
+	assert.NoError(t, CheckConnectivity(sto, tips, nil))
+}
+
+func TestCheckConnectivityMissingBlob(t *testing.T) {
+	t.Parallel()
+
+	sto := filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault())
+	second, err := object.GetCommit(sto, plumbing.NewHash(secondCommit))
+	require.NoError(t, err)
+
+	changelog := plumbing.NewHash("d3ff53e0564a9f87d8e84b6e28e5060e517008aa")
+	s := copyStorageWithout(t, sto, changelog)
+
+	err = CheckConnectivity(s, []plumbing.Hash{second.Hash}, nil)
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+
+	var missing *MissingObjectError
+	require.True(t, errors.As(err, &missing))
+	assert.Equal(t, &MissingObjectError{
+		Hash:     changelog,
+		Referrer: second.TreeHash,
+		Path:     "CHANGELOG",
+	}, missing)
+
+	// The blob is not reachable from the initial commit.
+	assert.NoError(t, CheckConnectivity(s, []plumbing.Hash{plumbing.NewHash(initialCommit)}, nil))
+}
+
+// promisorStorer marks the given objects as promisor objects.
+type promisorStorer struct {
+	storer.EncodedObjectStorer
+	promisor map[plumbing.Hash]bool
+}
+
+func (s *promisorStorer) MarkPromisorPack(plumbing.Hash) error { return nil }
+
+func (s *promisorStorer) IsPromisorObject(h plumbing.Hash) bool { return s.promisor[h] }
+
+func TestCheckConnectivityPromisor(t *testing.T) {
+	t.Parallel()
+
+	sto := filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault())
+	second, err := object.GetCommit(sto, plumbing.NewHash(secondCommit))
+	require.NoError(t, err)
+
+	changelog := plumbing.NewHash("d3ff53e0564a9f87d8e84b6e28e5060e517008aa")
+	s := &promisorStorer{
+		EncodedObjectStorer: copyStorageWithout(t, sto, changelog, second.TreeHash),
+		promisor:            map[plumbing.Hash]bool{second.Hash: true},
+	}
+
+	// The tree missing is promised by the commit, yet the blob missing
+	// would not be.
+	assert.NoError(t, CheckConnectivity(s, []plumbing.Hash{second.Hash}, nil))
+
+	s.promisor = nil
+	var missing *MissingObjectError
+	require.ErrorAs(t, CheckConnectivity(s, []plumbing.Hash{second.Hash}, nil), &missing)
+	assert.Equal(t, second.TreeHash, missing.Hash)
+
+	objs, err := Objects(s, []plumbing.Hash{second.Hash}, []plumbing.Hash{second.ParentHashes[0]})
+	assert.Nil(t, objs)
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+
+	s.promisor = map[plumbing.Hash]bool{second.Hash: true}
+	objs, err = Objects(s, []plumbing.Hash{second.Hash}, []plumbing.Hash{second.ParentHashes[0]})
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{second.Hash}, objs)
+}
+
+func TestCheckConnectivityShallow(t *testing.T) {
+	t.Parallel()
+
+	sto := filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault())
+	initial := plumbing.NewHash(initialCommit)
+	s := copyStorageWithout(t, sto, initial)
+
+	tips := []plumbing.Hash{plumbing.NewHash(secondCommit)}
+	var missing *MissingObjectError
+	require.True(t, errors.As(CheckConnectivity(s, tips, nil), &missing))
+	assert.Equal(t, initial, missing.Hash)
+	assert.Equal(t, plumbing.NewHash(secondCommit), missing.Referrer)
+
+	assert.NoError(t, CheckConnectivity(s, tips, tips))
+}
+
+func TestCheckConnectivityWithHaves(t *testing.T) {
+	t.Parallel()
+
+	sto := filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault())
+	initial := plumbing.NewHash(initialCommit)
+	s := copyStorageWithout(t, sto, initial)
+
+	tips := []plumbing.Hash{plumbing.NewHash(secondCommit)}
+	assert.ErrorIs(t, CheckConnectivityWithHaves(s, tips, nil, nil), plumbing.ErrObjectNotFound)
+	assert.NoError(t, CheckConnectivityWithHaves(s, tips, []plumbing.Hash{initial}, nil))
+}
@@ -98,7 +98,7 @@ func processObject(
 
 	switch do := do.(type) {
 	case *object.Commit:
-		return reachableObjects(do, seen, visited, ignore, walkerFunc)
+		return reachableObjects(s, do, seen, visited, ignore, walkerFunc)
 	case *object.Tree:
 		return iterateCommitTrees(seen, do, walkerFunc)
 	case *object.Tag:
@@ -117,8 +117,10 @@ func processObject(
 // reachableObjects returns, using the callback function, all the reachable
 // objects from the specified commit. To avoid to iterate over seen commits,
 // if a commit hash is into the 'seen' set, we will not iterate all his trees
-// and blobs objects.
+// and blobs objects. The missing trees of the commits stored by s as promisor
+// objects are skipped, as they are promised by the remote of a partial clone.
 func reachableObjects(
+	s storer.EncodedObjectStorer,
 	commit *object.Commit,
 	seen map[plumbing.Hash]bool,
 	visited map[plumbing.Hash]bool,
@@ -156,6 +158,10 @@ func reachableObjects(
 
 		tree, err := commit.Tree()
 		if err != nil {
+			if isPromised(s, commit.Hash, err) {
+				continue
+			}
+
 			return err
 		}
 
@@ -167,6 +173,13 @@ func reachableObjects(
 	return nil
 }
 
+// isPromised returns whether err reports an object missing from s while
+// referenced by the promisor object referrer.
+func isPromised(s storer.EncodedObjectStorer, referrer plumbing.Hash, err error) bool {
+	ps, ok := s.(storer.PromisorObjectStorer)
+	return ok && errors.Is(err, plumbing.ErrObjectNotFound) && ps.IsPromisorObject(referrer)
+}
+
 func addPendingParents(pending, visited map[plumbing.Hash]bool, commit *object.Commit) {
 	for _, p := range commit.ParentHashes {
 		if !visited[p] {
@@ -265,6 +265,7 @@ func (s *RevListSuite) TestReachableObjectsNoRevisit() {
 
 	var visited []plumbing.Hash
 	err = reachableObjects(
+		s.Storer,
 		commit,
 		map[plumbing.Hash]bool{
 			plumbing.NewHash("35e85108805c84807bc66a02d91535e1e24b38b9"): true,
@@ -1,6 +1,7 @@
 package storer
 
 import (
+	"context"
 	"errors"
 	"io"
 	"time"
@@ -46,6 +47,59 @@ type EncodedObjectStorer interface {
 	AddAlternate(remote string) error
 }
 
+// ContextEncodedObjectStorer is an optional interface for EncodedObjectStorer,
+// allowing reads of objects from slow storage to be cancelled.
+type ContextEncodedObjectStorer interface {
+	// EncodedObjectContext is the same as EncodedObject, but returns
+	// ctx.Err() as soon as the context is cancelled.
+	EncodedObjectContext(context.Context, plumbing.ObjectType, plumbing.Hash) (plumbing.EncodedObject, error)
+}
+
+// EncodedObjectContext gets an object by hash with the given
+// plumbing.ObjectType from s, using EncodedObjectContext if s implements
+// ContextEncodedObjectStorer. Otherwise the read can't be cancelled, and the
+// context is only checked before and after it.
+func EncodedObjectContext(ctx context.Context, s EncodedObjectStorer, t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	if cs, ok := s.(ContextEncodedObjectStorer); ok {
+		return cs.EncodedObjectContext(ctx, t, h)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	obj, err := s.EncodedObject(t, h)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// ObjectPrefetcher is an optional interface for EncodedObjectStorer, allowing
+// the objects about to be read to be read ahead in one batch, in the order
+// they are stored, instead of one at a time.
+type ObjectPrefetcher interface {
+	// PrefetchObjects reads the objects with the given hashes ahead of their
+	// use, so that reading them later is served from the cache. The hashes
+	// of missing objects are ignored.
+	PrefetchObjects([]plumbing.Hash) error
+}
+
+// PrefetchObjects reads the objects with the given hashes from s ahead of
+// their use, if s implements ObjectPrefetcher. Otherwise it does nothing.
+func PrefetchObjects(s EncodedObjectStorer, hashes []plumbing.Hash) error {
+	if p, ok := s.(ObjectPrefetcher); ok && len(hashes) > 0 {
+		return p.PrefetchObjects(hashes)
+	}
+
+	return nil
+}
+
 // DeltaObjectStorer is an EncodedObjectStorer that can return delta
 // objects.
 type DeltaObjectStorer interface {
@@ -89,6 +143,64 @@ type PackedObjectStorer interface {
 	DeleteOldObjectPackAndIndex(plumbing.Hash, time.Time) error
 }
 
+// ObjectTimeStorer is an optional interface for ObjectStorer, tracking the
+// modification time of the objects, which drives their expiration. The time
+// of a packed object is the one recorded by the mtimes file of its cruft
+// pack, or the time of its packfile.
+type ObjectTimeStorer interface {
+	// ObjectTime returns the modification time of the object, loose or
+	// packed.
+	ObjectTime(plumbing.Hash) (time.Time, error)
+	// ForEachObjectTime calls the function with each object stored, loose
+	// or packed, and its modification time, without reading the objects.
+	ForEachObjectTime(func(plumbing.Hash, time.Time) error) error
+	// SetObjectPackMtimes records the modification time of each object of
+	// the packfile, making it a cruft pack.
+	SetObjectPackMtimes(pack plumbing.Hash, mtimes map[plumbing.Hash]time.Time) error
+}
+
+// PromisorObjectStorer is an optional interface for ObjectStorer, tracking
+// the packfiles fetched from a promisor remote in a partial clone, as git
+// does with their .promisor files. The objects referenced by the objects of
+// those packfiles may be missing, as the remote promises to send them later.
+type PromisorObjectStorer interface {
+	// MarkPromisorPack marks the packfile as fetched from a promisor remote.
+	MarkPromisorPack(plumbing.Hash) error
+	// IsPromisorObject returns whether the object is stored in a packfile
+	// fetched from a promisor remote.
+	IsPromisorObject(plumbing.Hash) bool
+}
+
+// ObjectCounter is an optional interface for ObjectStorer, reporting the
+// number of objects stored and the space they use, as git count-objects does.
+type ObjectCounter interface {
+	// CountObjects returns the statistics of the stored objects.
+	CountObjects() (*ObjectsCount, error)
+}
+
+// ObjectsCount holds the statistics reported by an ObjectCounter. Sizes are
+// in bytes.
+type ObjectsCount struct {
+	// Count is the number of loose objects.
+	Count int64
+	// Size is the space used by the loose objects.
+	Size int64
+	// InPack is the number of objects in packfiles.
+	InPack int64
+	// Packs is the number of packfiles.
+	Packs int64
+	// SizePack is the space used by the packfiles and their indexes.
+	SizePack int64
+	// PrunePackable is the number of loose objects that are also present
+	// in packfiles, and can be pruned.
+	PrunePackable int64
+	// Garbage is the number of files in the object database that are
+	// neither valid loose objects nor packfiles with their index.
+	Garbage int64
+	// SizeGarbage is the space used by the garbage files.
+	SizeGarbage int64
+}
+
 // PackfileWriter is an optional method for ObjectStorer, it enables directly writing
 // a packfile to storage.
 type PackfileWriter interface {
@@ -7,8 +7,9 @@ type TagMode int
 const (
 	InvalidTagMode TagMode = iota
 	// TagFollowing any tag that points into the histories being fetched is also
-	// fetched. TagFollowing requires a server with `include-tag` capability
-	// in order to fetch the annotated tags objects.
+	// fetched. The annotated tag objects are sent along with the objects they
+	// point to by servers with the `include-tag` capability, and fetched
+	// afterwards from the others.
 	TagFollowing
 	// AllTags fetch all tags from the remote (i.e., fetch remote tags
 	// refs/tags/* into local tags with the same name)
@@ -98,15 +98,47 @@ type FetchRequest struct {
 	// TODO: Build this slice in the transport package.
 	Haves []plumbing.Hash
 
+	// Negotiator, if set, selects the haves sent to the server instead of
+	// Haves.
+	Negotiator Negotiator
+
+	// MaxRounds is the maximum number of rounds of haves sent to the server
+	// before ending the negotiation. Zero means no limit.
+	MaxRounds int
+
 	// Depth is the depth of the fetch.
 	Depth int
 
+	// DeepenNot are the references whose history is excluded from the fetch,
+	// making the shallow boundary the commits right above them. It cannot be
+	// used together with Depth.
+	DeepenNot []string
+
 	// Filter holds the filters to be applied when deciding what
 	// objects will be added to the packfile.
 	Filter packp.Filter
 
 	// IncludeTags indicates whether tags should be fetched.
 	IncludeTags bool
+
+	// Promisor marks the packfile as fetched from a promisor remote, whose
+	// objects may reference objects missing from the storage, when it
+	// implements storer.PromisorObjectStorer.
+	Promisor bool
+
+	// MaxBytesPerSec, if positive, limits the rate at which the packfile is
+	// received, in bytes per second.
+	MaxBytesPerSec int64
+
+	// MaxBlobSize, if positive, is the maximum size of the blobs received,
+	// in bytes. The fetch fails with a *packfile.ObjectLimitError on the
+	// first blob above it.
+	MaxBlobSize int64
+
+	// MaxTreeEntries, if positive, is the maximum number of entries of the
+	// trees received. The fetch fails with a *packfile.ObjectLimitError on
+	// the first tree above it.
+	MaxTreeEntries int
 }
 
 // PushRequest contains the parameters for a push request.
@@ -132,6 +164,10 @@ type PushRequest struct {
 	// Quiet indicates whether the server should suppress human-readable
 	// output.
 	Quiet bool
+
+	// MaxBytesPerSec, if positive, limits the rate at which the packfile is
+	// sent, in bytes per second.
+	MaxBytesPerSec int64
 }
 
 // Session is a Git protocol transfer session.
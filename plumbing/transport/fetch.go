@@ -3,13 +3,19 @@ package transport
 import (
 	"context"
 	"io"
+	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp/capability"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp/sideband"
+	"github.com/go-git/go-git/v6/plumbing/storer"
 	"github.com/go-git/go-git/v6/storage"
 	"github.com/go-git/go-git/v6/utils/ioutil"
+	"github.com/go-git/go-git/v6/utils/trace"
 )
 
 // FetchPack fetches a packfile from the remote connection into the given
@@ -24,9 +30,21 @@ func FetchPack(
 ) (err error) {
 	packf = ioutil.NewContextReadCloser(ctx, packf)
 
+	reader := ioutil.NewRateLimitedReader(ctx, packf, req.MaxBytesPerSec)
+	if trace.General.Emitting() {
+		start := time.Now()
+		counter := &countingReader{r: reader}
+		reader = counter
+		defer func() {
+			trace.General.Emit("transport.fetch_pack",
+				slog.Int64("bytes", counter.n),
+				slog.Duration("duration", time.Since(start)),
+				slog.Any("error", err))
+		}()
+	}
+
 	// Do we have sideband enabled?
 	var demuxer *sideband.Demuxer
-	var reader io.Reader = packf
 	caps := conn.Capabilities()
 	if caps.Supports(capability.Sideband64k) {
 		demuxer = sideband.NewDemuxer(sideband.Sideband64k, reader)
@@ -34,15 +52,37 @@ func FetchPack(
 		demuxer = sideband.NewDemuxer(sideband.Sideband, reader)
 	}
 
-	if demuxer != nil && req.Progress != nil {
+	if demuxer != nil {
 		demuxer.Progress = req.Progress
 		reader = demuxer
 	}
 
-	if err := packfile.UpdateObjectStorage(st, reader); err != nil {
+	var packs []plumbing.Hash
+	if req.Promisor {
+		packs, err = objectPacks(st)
+		if err != nil {
+			return err
+		}
+	}
+
+	limits := packfile.ObjectLimits{MaxBlobSize: req.MaxBlobSize, MaxTreeEntries: req.MaxTreeEntries}
+	if err := packfile.UpdateObjectStorageWithLimits(st, reader, limits); err != nil {
 		return err
 	}
 
+	if req.Promisor {
+		if err := markPromisorPacks(st, packs); err != nil {
+			return err
+		}
+	}
+
+	// Consume the remaining packets of the sideband, up to its flush.
+	if demuxer != nil {
+		if _, err := io.Copy(io.Discard, demuxer); err != nil {
+			return err
+		}
+	}
+
 	if err := packf.Close(); err != nil {
 		return err
 	}
@@ -57,6 +97,18 @@ func FetchPack(
 	return nil
 }
 
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 func updateShallow(st storage.Storer, shallowInfo *packp.ShallowUpdate) error {
 	shallows, err := st.Shallow()
 	if err != nil {
@@ -85,3 +137,43 @@ outer:
 
 	return st.SetShallow(shallows)
 }
+
+// objectPacks returns the packfiles of st, if it marks the promisor ones.
+func objectPacks(st storage.Storer) ([]plumbing.Hash, error) {
+	if _, ok := st.(storer.PromisorObjectStorer); !ok {
+		return nil, nil
+	}
+
+	ps, ok := st.(storer.PackedObjectStorer)
+	if !ok {
+		return nil, nil
+	}
+
+	return ps.ObjectPacks()
+}
+
+// markPromisorPacks marks the packfiles of st missing from old, which were
+// just fetched, as fetched from a promisor remote.
+func markPromisorPacks(st storage.Storer, old []plumbing.Hash) error {
+	ps, ok := st.(storer.PromisorObjectStorer)
+	if !ok {
+		return nil
+	}
+
+	packs, err := objectPacks(st)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range packs {
+		if slices.Contains(old, h) {
+			continue
+		}
+
+		if err := ps.MarkPromisorPack(h); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
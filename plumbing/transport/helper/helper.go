@@ -0,0 +1,321 @@
+// Package helper implements a transport running the git remote helpers, the
+// git-remote-<transport> programs serving the URLs of the form
+// "<transport>::<address>" and the ones with a scheme no other transport
+// supports.
+//
+// As running the program named after the scheme of any URL is unsafe, the
+// helpers are only run for the transports allowed by Allow, none by default,
+// as git does with its protocol.allow config for the URLs it does not trust.
+//
+// Only the helpers with the connect capability are supported: the helper is
+// asked to connect to git-upload-pack or git-receive-pack, and the pack
+// protocol is then spoken over its standard input and output. The helpers
+// implementing the fetch and push commands, or import and export, without
+// connect, as git-remote-http and the foreign VCS helpers do, are rejected
+// with ErrConnectUnsupported.
+//
+// Ref: https://git-scm.com/docs/gitremote-helpers
+package helper
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v6/plumbing/transport"
+)
+
+func init() {
+	transport.RegisterFallback(Lookup)
+}
+
+// Prefix is the prefix of the name of the remote helper programs.
+const Prefix = "git-remote-"
+
+var (
+	// ErrHelperNotAllowed is returned when the remote helper of a transport
+	// not allowed by Allow would be run.
+	ErrHelperNotAllowed = errors.New("remote helper not allowed")
+	// ErrHelperNotFound is returned when the remote helper of a transport is
+	// not found in the PATH.
+	ErrHelperNotFound = errors.New("remote helper not found")
+	// ErrConnectUnsupported is returned when the remote helper cannot
+	// connect to the git services.
+	ErrConnectUnsupported = errors.New("remote helper does not support connect")
+	// ErrUnexpectedResponse is returned when the remote helper answers a
+	// command with an unexpected response.
+	ErrUnexpectedResponse = errors.New("unexpected response from remote helper")
+)
+
+// DefaultTransport is the transport running the remote helpers.
+var DefaultTransport = NewTransport()
+
+// NewTransport returns a transport running the remote helpers.
+func NewTransport() transport.Transport {
+	return transport.NewPackTransport(&runner{})
+}
+
+var (
+	allowed   = map[string]bool{}
+	allowedMu sync.RWMutex
+)
+
+// Allow allows the remote helpers of the given transports to be run.
+func Allow(names ...string) {
+	allowedMu.Lock()
+	defer allowedMu.Unlock()
+
+	for _, name := range names {
+		allowed[name] = true
+	}
+}
+
+// Disallow disallows the remote helpers of the given transports, allowed by
+// Allow, to be run.
+func Disallow(names ...string) {
+	allowedMu.Lock()
+	defer allowedMu.Unlock()
+
+	for _, name := range names {
+		delete(allowed, name)
+	}
+}
+
+// IsAllowed returns whether the remote helper of the transport named name is
+// allowed to be run.
+func IsAllowed(name string) bool {
+	allowedMu.RLock()
+	defer allowedMu.RUnlock()
+
+	return allowed[name]
+}
+
+// Lookup returns DefaultTransport if the remote helper of the transport
+// named name is allowed and in the PATH, and an ErrHelperNotAllowed or
+// ErrHelperNotFound error otherwise. It is the fallback of the transports not
+// registered.
+func Lookup(name string) (transport.Transport, error) {
+	if !IsAllowed(name) {
+		return nil, fmt.Errorf("unsupported scheme %q: %w", name, ErrHelperNotAllowed)
+	}
+
+	if _, err := exec.LookPath(Prefix + name); err != nil {
+		return nil, fmt.Errorf("unsupported scheme %q: %w: %w", name, ErrHelperNotFound, err)
+	}
+
+	return DefaultTransport, nil
+}
+
+// address returns the address given to the remote helper of the endpoint:
+// the part following "::" in the "<transport>::<address>" endpoints, and the
+// whole URL otherwise.
+func address(ep *transport.Endpoint) string {
+	if addr, ok := strings.CutPrefix(ep.Opaque, ":"); ok {
+		return addr
+	}
+
+	return ep.String()
+}
+
+type runner struct{}
+
+func (*runner) Command(ctx context.Context, cmd string, ep *transport.Endpoint, _ transport.AuthMethod, params ...string) (transport.Command, error) {
+	switch transport.Service(cmd) {
+	case transport.UploadPackService, transport.ReceivePackService:
+		// do nothing
+	default:
+		return nil, transport.ErrUnsupportedService
+	}
+
+	if !IsAllowed(ep.Scheme) {
+		return nil, fmt.Errorf("%w: %s", ErrHelperNotAllowed, ep.Scheme)
+	}
+
+	path, err := exec.LookPath(Prefix + ep.Scheme)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrHelperNotFound, err)
+	}
+
+	// The remote name is the address, as for the anonymous remotes of git.
+	addr := address(ep)
+	c := exec.CommandContext(ctx, path, addr, addr)
+	if len(params) > 0 {
+		c.Env = append(os.Environ(), "GIT_PROTOCOL="+strings.Join(params, ":"))
+	}
+
+	return &command{Cmd: c, service: cmd}, nil
+}
+
+type command struct {
+	*exec.Cmd
+	service string
+
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *io.PipeWriter
+
+	closed bool
+	mu     sync.Mutex
+}
+
+func (c *command) StdinPipe() (io.WriteCloser, error) {
+	w, err := c.Cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	c.stdin = w
+	return w, nil
+}
+
+// StdoutPipe returns the standard output of the helper, buffered as its
+// responses to the commands are read from it.
+func (c *command) StdoutPipe() (io.Reader, error) {
+	r, err := c.Cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	c.stdout = bufio.NewReader(r)
+	return c.stdout, nil
+}
+
+// StderrPipe returns the standard error of the helper. Unlike the one of
+// exec.Cmd, it is closed once the helper exited, and may be read after.
+func (c *command) StderrPipe() (io.Reader, error) {
+	pr, pw := io.Pipe()
+	c.Cmd.Stderr = pw
+	c.stderr = pw
+	return pr, nil
+}
+
+// Start starts the helper and asks it to connect to the service.
+func (c *command) Start() error {
+	if c.stdin == nil {
+		if _, err := c.StdinPipe(); err != nil {
+			return err
+		}
+	}
+
+	if c.stdout == nil {
+		if _, err := c.StdoutPipe(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Cmd.Start(); err != nil {
+		if c.stderr != nil {
+			_ = c.stderr.Close()
+		}
+
+		return err
+	}
+
+	if err := c.connect(); err != nil {
+		_ = c.Close()
+		return err
+	}
+
+	return nil
+}
+
+// connect checks the capabilities of the helper and sends it the connect
+// command. The helper answers with an empty line once connected.
+func (c *command) connect() error {
+	if _, err := io.WriteString(c.stdin, "capabilities\n"); err != nil {
+		return err
+	}
+
+	var connect bool
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+
+		if line == "" {
+			break
+		}
+
+		// The mandatory capabilities are prefixed by "*".
+		if strings.TrimPrefix(line, "*") == "connect" {
+			connect = true
+		}
+	}
+
+	if !connect {
+		return ErrConnectUnsupported
+	}
+
+	if _, err := fmt.Fprintf(c.stdin, "connect %s\n", c.service); err != nil {
+		return err
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+
+	switch line {
+	case "":
+		return nil
+	case "fallback":
+		return ErrConnectUnsupported
+	default:
+		return fmt.Errorf("%w: %q", ErrUnexpectedResponse, line)
+	}
+}
+
+func (c *command) readLine() (string, error) {
+	line, err := c.stdout.ReadString('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", io.ErrUnexpectedEOF
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSuffix(line, "\n"), nil
+}
+
+// Close closes the standard input of the helper and waits for it to exit.
+// The exit status of the helper is ignored, the errors of the git services
+// being reported through the protocol.
+func (c *command) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || c.Process == nil {
+		return nil
+	}
+
+	c.closed = true
+	_ = c.stdin.Close()
+	err := c.Wait()
+	if c.stderr != nil {
+		_ = c.stderr.Close()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil
+	}
+
+	return err
+}
+
+// Kill kills the helper and waits for it to exit.
+func (c *command) Kill() error {
+	if c.Process != nil {
+		_ = c.Process.Kill()
+	}
+
+	return c.Close()
+}
@@ -0,0 +1,171 @@
+package helper
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/internal/transport/test"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// connectHelper is a remote helper connecting to the git services of the
+// repository at its address.
+const connectHelper = `#!/bin/sh
+while read line; do
+	case "$line" in
+	capabilities)
+		printf 'option\n*connect\n\n'
+		;;
+	"connect "*)
+		printf '\n'
+		service="${line#connect git-}"
+		exec git "$service" "$2"
+		;;
+	esac
+done
+`
+
+// listHelper is a remote helper without the connect capability.
+const listHelper = `#!/bin/sh
+read line
+printf 'list\nfetch\n\n'
+`
+
+// installHelpers writes the remote helpers in a directory added to the PATH,
+// and allows them.
+func installHelpers(t *testing.T) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the remote helpers of the tests are shell scripts")
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	for name, script := range map[string]string{
+		"testconnect": connectHelper,
+		"testlist":    listHelper,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, Prefix+name), []byte(script), 0o755))
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	Allow("testconnect", "testlist", "testmissing")
+	t.Cleanup(func() { Disallow("testconnect", "testlist", "testmissing") })
+}
+
+//nolint:paralleltest // sets PATH
+func TestConnect(t *testing.T) {
+	installHelpers(t)
+
+	base := filepath.Join(t.TempDir(), "go-git-helper")
+	basic := test.PrepareRepository(t, fixtures.Basic().One(), base, "basic.git")
+
+	ep, err := transport.NewEndpoint("testconnect::" + basic.Root())
+	require.NoError(t, err)
+
+	tr, err := transport.Get(ep.Scheme)
+	require.NoError(t, err)
+
+	st := memory.NewStorage()
+	sess, err := tr.NewSession(st, ep, nil)
+	require.NoError(t, err)
+
+	conn, err := sess.Handshake(context.Background(), transport.UploadPackService)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, conn.Close()) }()
+
+	refs, err := conn.GetRemoteRefs(context.Background())
+	require.NoError(t, err)
+
+	var master *plumbing.Reference
+	for _, ref := range refs {
+		if ref.Name() == plumbing.Master {
+			master = ref
+		}
+	}
+	require.NotNil(t, master)
+	assert.Equal(t, "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", master.Hash().String())
+
+	err = conn.Fetch(context.Background(), &transport.FetchRequest{
+		Wants: []plumbing.Hash{master.Hash()},
+	})
+	require.NoError(t, err)
+
+	_, err = st.EncodedObject(plumbing.CommitObject, master.Hash())
+	assert.NoError(t, err)
+}
+
+//nolint:paralleltest // sets PATH
+func TestConnectUnsupported(t *testing.T) {
+	installHelpers(t)
+
+	ep, err := transport.NewEndpoint("testlist::example")
+	require.NoError(t, err)
+
+	tr, err := transport.Get(ep.Scheme)
+	require.NoError(t, err)
+
+	sess, err := tr.NewSession(memory.NewStorage(), ep, nil)
+	require.NoError(t, err)
+
+	_, err = sess.Handshake(context.Background(), transport.UploadPackService)
+	assert.ErrorIs(t, err, ErrConnectUnsupported)
+}
+
+//nolint:paralleltest // sets PATH
+func TestLookupNotFound(t *testing.T) {
+	installHelpers(t)
+
+	_, err := transport.Get("testmissing")
+	assert.ErrorIs(t, err, ErrHelperNotFound)
+
+	_, err = Lookup("testconnect")
+	assert.NoError(t, err)
+}
+
+//nolint:paralleltest // sets PATH
+func TestLookupNotAllowed(t *testing.T) {
+	installHelpers(t)
+	Disallow("testconnect")
+
+	_, err := transport.Get("testconnect")
+	assert.ErrorIs(t, err, ErrHelperNotAllowed)
+
+	ep, err := transport.NewEndpoint("testconnect::example")
+	require.NoError(t, err)
+
+	sess, err := DefaultTransport.NewSession(memory.NewStorage(), ep, nil)
+	require.NoError(t, err)
+
+	_, err = sess.Handshake(context.Background(), transport.UploadPackService)
+	assert.ErrorIs(t, err, ErrHelperNotAllowed)
+}
+
+func TestAddress(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]string{
+		"gcrypt::rsync://example.com/repository": "rsync://example.com/repository",
+		"ext::ssh example.com %S repository":     "ssh example.com %S repository",
+		"foo://example.com/repository":           "foo://example.com/repository",
+	} {
+		ep, err := transport.NewEndpoint(input)
+		require.NoError(t, err)
+		assert.Equal(t, want, address(ep))
+	}
+}
@@ -101,6 +101,12 @@ func applyHeaders(
 		req.Header.Set("Git-Protocol", protocol)
 	}
 
+	for _, header := range ep.ExtraHeaders {
+		if name, value, ok := strings.Cut(header, ":"); ok {
+			req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+	}
+
 	// Set auth headers
 	if auth != nil {
 		auth.SetAuth(req)
@@ -255,6 +261,28 @@ func (s *HTTPSession) IsSmart() bool {
 
 var _ transport.Session = (*HTTPSession)(nil)
 
+// clientFor returns the client sending the requests, which follows their
+// redirects as set by the FollowRedirects of the endpoint. initial tells
+// whether the request is the initial one of the session.
+func (s *HTTPSession) clientFor(initial bool) *http.Client {
+	switch s.ep.FollowRedirects {
+	case "false":
+	case "initial":
+		if initial {
+			return s.client
+		}
+	default:
+		return s.client
+	}
+
+	c := *s.client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &c
+}
+
 func transportWithInsecureTLS(transport *http.Transport) {
 	if transport.TLSClientConfig == nil {
 		transport.TLSClientConfig = &tls.Config{}
@@ -396,7 +424,7 @@ func (s *HTTPSession) Handshake(ctx context.Context, service transport.Service,
 	}
 
 	applyHeaders(req, service.String(), s.ep, s.auth, s.gitProtocol, !s.useDumb)
-	res, err := doRequest(s.client, req)
+	res, err := doRequest(s.clientFor(true), req)
 	if err != nil {
 		return nil, err
 	}
@@ -601,7 +629,7 @@ func (r *requester) Close() (err error) {
 	}
 
 	applyHeaders(r.req, r.service, r.ep, r.auth, r.gitProtocol, r.IsSmart())
-	r.res, err = doRequest(r.client, r.req)
+	r.res, err = doRequest(r.clientFor(false), r.req)
 	if err != nil {
 		return err
 	}
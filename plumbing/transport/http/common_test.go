@@ -8,6 +8,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/cgi"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"os/exec"
@@ -466,3 +467,61 @@ func TestRedactedURL(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyHeadersExtraHeaders(t *testing.T) {
+	t.Parallel()
+
+	ep, err := transport.NewEndpoint("https://example.com/repo")
+	require.NoError(t, err)
+	ep.ExtraHeaders = []string{"X-Foo: bar", "X-Foo:baz", "invalid"}
+
+	req, err := http.NewRequest(http.MethodGet, ep.String(), nil)
+	require.NoError(t, err)
+
+	applyHeaders(req, transport.UploadPackService.String(), ep, nil, "", true)
+	require.Equal(t, []string{"bar", "baz"}, req.Header.Values("X-Foo"))
+}
+
+func TestFollowRedirects(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/other/") {
+			http.Redirect(w, r, "/other"+r.URL.Path, http.StatusFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cl := NewTransport(nil).(*client)
+	for _, tt := range []struct {
+		followRedirects string
+		initial         bool
+		redirected      bool
+	}{
+		{"", true, true},
+		{"true", false, true},
+		{"false", true, false},
+		{"initial", true, true},
+		{"initial", false, false},
+	} {
+		ep, err := transport.NewEndpoint(server.URL + "/repo")
+		require.NoError(t, err)
+		ep.FollowRedirects = tt.followRedirects
+
+		session, err := newSession(nil, cl, ep, nil, false)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, ep.String(), nil)
+		require.NoError(t, err)
+
+		res, err := session.clientFor(tt.initial).Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		if tt.redirected {
+			require.Equal(t, http.StatusOK, res.StatusCode, tt)
+		} else {
+			require.Equal(t, http.StatusFound, res.StatusCode, tt)
+		}
+	}
+}
@@ -27,7 +27,7 @@ import (
 )
 
 func (s *HTTPSession) fetchDumb(ctx context.Context, req *transport.FetchRequest) error {
-	if req.Depth != 0 {
+	if req.Depth != 0 || len(req.DeepenNot) > 0 {
 		return errors.New("dumb http protocol does not support shallow capabilities")
 	}
 
@@ -40,6 +40,8 @@ func (s *HTTPSession) fetchDumb(ctx context.Context, req *transport.FetchRequest
 
 	repoFs := fsi.Filesystem()
 	r := newFetchWalker(s, ctx, repoFs)
+	r.limiter = ioutil.NewRateLimiter(ctx, req.MaxBytesPerSec)
+	r.limits = packfile.ObjectLimits{MaxBlobSize: req.MaxBlobSize, MaxTreeEntries: req.MaxTreeEntries}
 	if err := r.process(); err != nil {
 		return err
 	}
@@ -58,6 +60,12 @@ type fetchWalker struct {
 	fs      billy.Filesystem
 	queue   []plumbing.Hash
 	packIdx map[plumbing.Hash]string
+
+	// limiter, if not nil, limits the rate at which the files are
+	// downloaded, shared by all of them.
+	limiter *ioutil.RateLimiter
+	// limits are the limits on the objects fetched.
+	limits packfile.ObjectLimits
 }
 
 func newFetchWalker(s *HTTPSession, ctx context.Context, fs billy.Filesystem) *fetchWalker {
@@ -81,7 +89,7 @@ func (r *fetchWalker) getInfoPacks() ([]string, error) {
 	}
 
 	applyHeaders(req, "", r.ep, r.auth, "", false)
-	res, err := doRequest(r.client, req)
+	res, err := doRequest(r.clientFor(false), req)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +128,7 @@ func (r *fetchWalker) downloadFile(fp string) (rErr error) {
 	}
 
 	applyHeaders(req, "", r.ep, r.auth, "", false)
-	res, err := doRequest(r.client, req)
+	res, err := doRequest(r.clientFor(false), req)
 	if err != nil {
 		return err
 	}
@@ -130,7 +138,8 @@ func (r *fetchWalker) downloadFile(fp string) (rErr error) {
 	}
 
 	copyFn := func(w io.Writer) error {
-		if _, err := ioutil.CopyBufferPool(w, res.Body); err != nil {
+		body := r.limiter.Reader(res.Body)
+		if _, err := ioutil.CopyBufferPool(w, body); err != nil {
 			return err
 		}
 
@@ -177,7 +186,7 @@ func (r *fetchWalker) getHead() (ref *plumbing.Reference, err error) {
 	}
 
 	applyHeaders(req, "", r.ep, r.auth, "", false)
-	res, err := doRequest(r.client, req)
+	res, err := doRequest(r.clientFor(false), req)
 	if err != nil {
 		return nil, err
 	}
@@ -306,7 +315,7 @@ func (r *fetchWalker) fetchObject(hash plumbing.Hash, obj plumbing.EncodedObject
 	}
 
 	applyHeaders(req, "", r.ep, r.auth, "", false)
-	res, err := doRequest(r.client, req)
+	res, err := doRequest(r.clientFor(false), req)
 	if errors.Is(err, transport.ErrRepositoryNotFound) {
 		// TODO: better error handling
 		return io.EOF
@@ -324,7 +333,7 @@ func (r *fetchWalker) fetchObject(hash plumbing.Hash, obj plumbing.EncodedObject
 		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
 	}
 
-	rd, err := objfile.NewReader(res.Body)
+	rd, err := objfile.NewReader(r.limiter.Reader(res.Body))
 	if err != nil {
 		return err
 	}
@@ -421,6 +430,10 @@ LOOP:
 			return err
 		}
 
+		if err := r.limits.CheckObject(obj); err != nil {
+			return err
+		}
+
 		switch obj.Type() {
 		case plumbing.CommitObject:
 			commit, err := object.DecodeCommit(r.st, obj)
@@ -471,7 +484,7 @@ LOOP:
 			return err
 		}
 
-		if err := packfile.UpdateObjectStorage(r.st, f); err != nil {
+		if err := packfile.UpdateObjectStorageWithLimits(r.st, f, r.limits); err != nil {
 			_ = f.Close()
 			return err
 		}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/lfs"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+)
+
+const lfsMediaType = "application/vnd.git-lfs+json"
+
+var (
+	// ErrLFSUnsupportedEndpoint is returned by ResolveLFS when the endpoint
+	// is not an HTTP one.
+	ErrLFSUnsupportedEndpoint = errors.New("lfs: unsupported endpoint")
+	// ErrLFSContentMismatch is returned while reading the content resolved
+	// by ResolveLFS, when it does not match the pointer.
+	ErrLFSContentMismatch = errors.New("lfs: content does not match pointer")
+)
+
+type lfsBatchRequest struct {
+	Operation string           `json:"operation"`
+	Transfers []string         `json:"transfers"`
+	Objects   []lfsBatchObject `json:"objects"`
+}
+
+type lfsBatchResponse struct {
+	Objects []lfsBatchObject `json:"objects"`
+}
+
+type lfsBatchObject struct {
+	Oid     string               `json:"oid"`
+	Size    int64                `json:"size"`
+	Actions map[string]lfsAction `json:"actions,omitempty"`
+	Error   *lfsObjectError      `json:"error,omitempty"`
+}
+
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header,omitempty"`
+}
+
+type lfsObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ResolveLFS fetches the content of the Git LFS pointer from the LFS server
+// of the repository at the endpoint, using the batch API with the basic
+// transfer adapter. The server is expected at <repository>.git/info/lfs, as
+// git-lfs does by default. If auth is nil, the credentials of the endpoint
+// are used.
+//
+// The content is checked against the pointer while being read, the returned
+// reader failing with ErrLFSContentMismatch at its end if it does not match.
+func ResolveLFS(ctx context.Context, p *lfs.Pointer, ep *transport.Endpoint, auth AuthMethod) (io.ReadCloser, error) {
+	if ep.Scheme != "http" && ep.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s", ErrLFSUnsupportedEndpoint, ep.Scheme)
+	}
+
+	if auth == nil {
+		auth = basicAuthFromEndpoint(ep)
+	}
+
+	client := http.DefaultClient
+	if len(ep.CaBundle) > 0 || ep.InsecureSkipTLS || ep.Proxy.URL != "" {
+		tr := http.DefaultTransport.(*http.Transport).Clone()
+		if err := configureTransport(tr, ep); err != nil {
+			return nil, err
+		}
+
+		client = &http.Client{Transport: tr}
+	}
+
+	u := ep.URL
+	u.User = nil
+	if !strings.HasSuffix(u.Path, ".git") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + ".git"
+	}
+
+	u.Path += "/info/lfs/objects/batch"
+
+	action, err := lfsDownloadAction(ctx, client, u.String(), p, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, action.Href, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range action.Header {
+		req.Header.Set(k, v)
+	}
+
+	if req.Header.Get("Authorization") == "" && req.URL.Host == u.Host && auth != nil {
+		auth.SetAuth(req)
+	}
+
+	res, err := doRequest(client, req)
+	if err != nil {
+		if res != nil {
+			_ = res.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	return &lfsContentReader{ReadCloser: res.Body, p: p, h: sha256.New()}, nil
+}
+
+// lfsDownloadAction requests the download action of the pointer to the batch
+// API at url.
+func lfsDownloadAction(ctx context.Context, client *http.Client, url string, p *lfs.Pointer, auth AuthMethod) (*lfsAction, error) {
+	body, err := json.Marshal(&lfsBatchRequest{
+		Operation: "download",
+		Transfers: []string{"basic"},
+		Objects:   []lfsBatchObject{{Oid: p.Oid, Size: p.Size}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+	if auth != nil {
+		auth.SetAuth(req)
+	}
+
+	res, err := doRequest(client, req)
+	if res != nil {
+		defer res.Body.Close()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var batch lfsBatchResponse
+	if err := json.NewDecoder(res.Body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("lfs: decoding batch response: %w", err)
+	}
+
+	for _, o := range batch.Objects {
+		if o.Oid != p.Oid {
+			continue
+		}
+
+		if o.Error != nil {
+			err := fmt.Errorf("lfs: object %s: %s", o.Oid, o.Error.Message)
+			if o.Error.Code == http.StatusNotFound {
+				err = fmt.Errorf("%w: %w", plumbing.ErrObjectNotFound, err)
+			}
+
+			return nil, err
+		}
+
+		if action, ok := o.Actions["download"]; ok {
+			return &action, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: lfs object %s", plumbing.ErrObjectNotFound, p.Oid)
+}
+
+// lfsContentReader checks the size and hash of the content it reads against
+// the pointer.
+type lfsContentReader struct {
+	io.ReadCloser
+	p *lfs.Pointer
+	h hash.Hash
+	n int64
+}
+
+func (r *lfsContentReader) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.h.Write(b[:n])
+	r.n += int64(n)
+	if errors.Is(err, io.EOF) && (r.n != r.p.Size || hex.EncodeToString(r.h.Sum(nil)) != r.p.Oid) {
+		return n, ErrLFSContentMismatch
+	}
+
+	return n, err
+}
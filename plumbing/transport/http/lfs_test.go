@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/lfs"
+	"github.com/go-git/go-git/v6/plumbing/transport"
+)
+
+func newLFSServer(t *testing.T, content string) (*httptest.Server, *lfs.Pointer) {
+	sum := sha256.Sum256([]byte(content))
+	p := &lfs.Pointer{Oid: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("POST /foo.git/info/lfs/objects/batch", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req lfsBatchRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "download", req.Operation)
+
+		var res lfsBatchResponse
+		for _, o := range req.Objects {
+			if o.Oid != p.Oid {
+				o.Error = &lfsObjectError{Code: http.StatusNotFound, Message: "Object does not exist"}
+			} else {
+				o.Actions = map[string]lfsAction{"download": {
+					Href:   srv.URL + "/objects/" + o.Oid,
+					Header: map[string]string{"X-Token": "token"},
+				}}
+			}
+
+			res.Objects = append(res.Objects, o)
+		}
+
+		w.Header().Set("Content-Type", lfsMediaType)
+		assert.NoError(t, json.NewEncoder(w).Encode(&res))
+	})
+	mux.HandleFunc("GET /objects/{oid}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-Token"))
+		_, _ = io.WriteString(w, content)
+	})
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, p
+}
+
+func TestResolveLFS(t *testing.T) {
+	t.Parallel()
+
+	srv, p := newLFSServer(t, "large content\n")
+	ep, err := transport.NewEndpoint(srv.URL + "/foo")
+	require.NoError(t, err)
+
+	_, err = ResolveLFS(context.TODO(), p, ep, nil)
+	require.ErrorIs(t, err, transport.ErrAuthenticationRequired)
+
+	r, err := ResolveLFS(context.TODO(), p, ep, &BasicAuth{Username: "user", Password: "pass"})
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "large content\n", string(b))
+
+	ep, err = transport.NewEndpoint("http://user:pass@" + srv.Listener.Addr().String() + "/foo.git")
+	require.NoError(t, err)
+	r, err = ResolveLFS(context.TODO(), &lfs.Pointer{Oid: p.Oid, Size: p.Size + 1}, ep, nil)
+	require.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.ErrorIs(t, err, ErrLFSContentMismatch)
+	require.NoError(t, r.Close())
+
+	_, err = ResolveLFS(context.TODO(), &lfs.Pointer{Oid: "00" + p.Oid[2:], Size: 1}, ep, nil)
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+
+	ep, err = transport.NewEndpoint("git@example.com:foo.git")
+	require.NoError(t, err)
+	_, err = ResolveLFS(context.TODO(), p, ep, nil)
+	assert.ErrorIs(t, err, ErrLFSUnsupportedEndpoint)
+}
@@ -0,0 +1,87 @@
+package transport
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing/format/pktline"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp/sideband"
+)
+
+// DefaultKeepAlive is the default interval of the keepalive packets sent by
+// the upload-pack service, as uploadpack.keepAlive defaults to in git.
+const DefaultKeepAlive = 5 * time.Second
+
+// keepAliveWriter writes the pack in the PackData channel of the sideband,
+// and an empty packet in it each time nothing was written for the interval,
+// so that the client doesn't time out while the pack is computed.
+type keepAliveWriter struct {
+	w   io.Writer
+	mux *sideband.Muxer
+
+	mu      sync.Mutex
+	written bool
+	err     error
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newKeepAliveWriter returns a keepAliveWriter writing the pack through mux,
+// the sideband muxer of w, which must be stopped once the pack is written.
+func newKeepAliveWriter(w io.Writer, mux *sideband.Muxer, interval time.Duration) *keepAliveWriter {
+	k := &keepAliveWriter{
+		w:    w,
+		mux:  mux,
+		done: make(chan struct{}),
+	}
+
+	k.wg.Add(1)
+	go k.run(interval)
+
+	return k
+}
+
+func (k *keepAliveWriter) run(interval time.Duration) {
+	defer k.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-k.done:
+			return
+		case <-ticker.C:
+		}
+
+		k.mu.Lock()
+		if !k.written && k.err == nil {
+			_, k.err = pktline.Write(k.w, sideband.PackData.WithPayload(nil))
+		}
+
+		k.written = false
+		k.mu.Unlock()
+	}
+}
+
+// Write implements io.Writer. It returns the error of the last keepalive
+// packet, if any.
+func (k *keepAliveWriter) Write(p []byte) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.err != nil {
+		return 0, k.err
+	}
+
+	k.written = true
+	return k.mux.Write(p)
+}
+
+// Stop stops sending the keepalive packets.
+func (k *keepAliveWriter) Stop() {
+	close(k.done)
+	k.wg.Wait()
+}
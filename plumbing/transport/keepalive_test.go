@@ -0,0 +1,29 @@
+package transport
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp/sideband"
+)
+
+func TestKeepAliveWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	k := newKeepAliveWriter(&buf, sideband.NewMuxer(sideband.Sideband64k, &buf), time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := k.Write([]byte("foo"))
+	require.NoError(t, err)
+	k.Stop()
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "0005\x01"), "%q", out)
+	assert.Equal(t, "0008\x01foo", strings.ReplaceAll(out, "0005\x01", ""))
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"slices"
+	"time"
 
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/format/config"
@@ -14,13 +16,16 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp/capability"
 	"github.com/go-git/go-git/v6/storage"
 	"github.com/go-git/go-git/v6/utils/ioutil"
+	"github.com/go-git/go-git/v6/utils/trace"
 	xstorage "github.com/go-git/go-git/v6/x/storage"
 )
 
 // Negotiation errors.
 var (
-	ErrFilterNotSupported  = errors.New("server does not support filters")
-	ErrShallowNotSupported = errors.New("server does not support shallow clients")
+	ErrFilterNotSupported    = errors.New("server does not support filters")
+	ErrShallowNotSupported   = errors.New("server does not support shallow clients")
+	ErrDeepenNotNotSupported = errors.New("server does not support deepen-not")
+	ErrDepthWithDeepenNot    = errors.New("depth cannot be used together with deepen-not")
 )
 
 // NegotiatePack returns the result of the pack negotiation phase of the fetch operation.
@@ -37,6 +42,20 @@ func NegotiatePack(
 	writer = ioutil.NewContextWriteCloser(ctx, writer)
 	caps := conn.Capabilities()
 
+	var rounds int
+	if trace.General.Emitting() {
+		start := time.Now()
+		wants, haves := len(req.Wants), len(req.Haves)
+		defer func() {
+			trace.General.Emit("transport.negotiate",
+				slog.Int("wants", wants),
+				slog.Int("haves", haves),
+				slog.Int("rounds", rounds),
+				slog.Duration("duration", time.Since(start)),
+				slog.Any("error", err))
+		}()
+	}
+
 	// Create upload-request
 	upreq := packp.NewUploadRequest()
 	multiAck := caps.Supports(capability.MultiACK)
@@ -47,13 +66,15 @@ func NegotiatePack(
 		_ = upreq.Capabilities.Set(capability.MultiACK)
 	}
 
-	if req.Progress != nil {
-		if caps.Supports(capability.Sideband64k) {
-			_ = upreq.Capabilities.Set(capability.Sideband64k)
-		} else if caps.Supports(capability.Sideband) {
-			_ = upreq.Capabilities.Set(capability.Sideband)
-		}
-	} else if caps.Supports(capability.NoProgress) {
+	// The sideband is used even without progress, as the keepalive packets
+	// the server sends while computing the pack flow through it.
+	if caps.Supports(capability.Sideband64k) {
+		_ = upreq.Capabilities.Set(capability.Sideband64k)
+	} else if caps.Supports(capability.Sideband) {
+		_ = upreq.Capabilities.Set(capability.Sideband)
+	}
+
+	if req.Progress == nil && caps.Supports(capability.NoProgress) {
 		_ = upreq.Capabilities.Set(capability.NoProgress)
 	}
 
@@ -135,12 +156,26 @@ func NegotiatePack(
 
 	upreq.Wants = req.Wants
 
-	if req.Depth > 0 {
+	if req.Depth > 0 && len(req.DeepenNot) > 0 {
+		return nil, ErrDepthWithDeepenNot
+	}
+
+	if req.Depth > 0 || len(req.DeepenNot) > 0 {
 		if !caps.Supports(capability.Shallow) {
 			return nil, ErrShallowNotSupported
 		}
 
-		upreq.Depth = packp.DepthCommits(req.Depth)
+		switch {
+		case req.Depth > 0:
+			upreq.Depth = packp.DepthCommits(req.Depth)
+		case !caps.Supports(capability.DeepenNot):
+			return nil, ErrDeepenNotNotSupported
+		case len(req.DeepenNot) == 1:
+			upreq.Depth = packp.DepthReference(req.DeepenNot[0])
+		default:
+			upreq.Depth = packp.DepthReferences(req.DeepenNot)
+		}
+
 		upreq.Shallows, err = st.Shallow()
 		if err != nil {
 			return nil, err
@@ -165,27 +200,30 @@ func NegotiatePack(
 	}
 
 	// Create upload-haves
-	common := map[plumbing.Hash]struct{}{}
+	negotiator := req.Negotiator
+	if negotiator == nil {
+		negotiator = &havesNegotiator{haves: req.Haves}
+	}
 
 	var inVein int
 	var done bool
 	var gotContinue bool // whether we got a continue from the server
 	firstRound := true
+	next, more := negotiator.Next()
 	for !done {
-		// Pop the last 32 or depth have commits from the pending list and
-		// insert their parents into the pending list.
-		// TODO: Properly build and implement haves negotiation, and move it
-		// from remote.go to this package.
+		// Send the next 32 haves selected by the negotiator.
 		var uphav packp.UploadHaves
-		for i := 0; i < 32 && len(req.Haves) > 0; i++ {
-			uphav.Haves = append(uphav.Haves, req.Haves[len(req.Haves)-1])
-			req.Haves = req.Haves[:len(req.Haves)-1]
+		for i := 0; i < 32 && more; i++ {
+			uphav.Haves = append(uphav.Haves, next)
+			next, more = negotiator.Next()
 			inVein++
 		}
 
 		// Let the server know we're done
 		const maxInVein = 256
-		done = len(req.Haves) == 0 || (gotContinue && inVein >= maxInVein)
+		rounds++
+		done = !more || (gotContinue && inVein >= maxInVein) ||
+			(req.MaxRounds > 0 && rounds >= req.MaxRounds)
 		uphav.Done = done
 
 		// Note: empty request means haves are a subset of wants, in that case we have
@@ -252,9 +290,7 @@ func NegotiatePack(
 					if !gotContinue && ack.Status > 0 {
 						gotContinue = true
 					}
-					if ack.Status == packp.ACKCommon {
-						common[ack.Hash] = struct{}{}
-					}
+					negotiator.Ack(ack.Hash)
 				}
 			}
 
@@ -298,7 +334,7 @@ func readShallows(
 	// Decode shallow-update
 	// If depth is not zero, then we expect a shallow update from the
 	// server.
-	if (firstRound || conn.StatelessRPC()) && req.Depth > 0 {
+	if (firstRound || conn.StatelessRPC()) && (req.Depth > 0 || len(req.DeepenNot) > 0) {
 		var shupd packp.ShallowUpdate
 		if err := shupd.Decode(r); err != nil {
 			return fmt.Errorf("decoding shallow-update: %w", err)
@@ -3,7 +3,9 @@ package transport
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -132,3 +134,87 @@ func TestNegotiatePackCompleteWithNonEOFCloseError(t *testing.T) {
 	assert.Contains(t, err.Error(), "closing writer")
 	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
 }
+
+// TestNegotiatePackMaxRounds tests that NegotiatePack ends the negotiation
+// once MaxRounds rounds of haves have been sent.
+func TestNegotiatePackMaxRounds(t *testing.T) {
+	t.Parallel()
+
+	caps := capability.NewList()
+	conn := &mockConnection{caps: caps}
+
+	reader := bytes.NewReader([]byte("0008NAK\n"))
+	writer := newMockRWC(nil)
+
+	var haves []plumbing.Hash
+	for i := range 40 {
+		haves = append(haves, plumbing.NewHash(fmt.Sprintf("%040x", i+1)))
+	}
+
+	req := &FetchRequest{
+		Wants:     []plumbing.Hash{plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")},
+		Haves:     haves,
+		MaxRounds: 1,
+	}
+
+	storer := memory.NewStorage()
+	_, err := NegotiatePack(context.TODO(), storer, conn, reader, writer, req)
+	require.NoError(t, err)
+
+	sent := writer.writeBuf.String()
+	assert.Equal(t, 32, strings.Count(sent, "have "))
+	assert.True(t, strings.HasSuffix(sent, "0009done\n"))
+}
+
+// recordingNegotiator sends its haves in order, and records the ones
+// acknowledged by the server.
+type recordingNegotiator struct {
+	haves []plumbing.Hash
+	acks  []plumbing.Hash
+}
+
+func (n *recordingNegotiator) Next() (plumbing.Hash, bool) {
+	if len(n.haves) == 0 {
+		return plumbing.ZeroHash, false
+	}
+
+	h := n.haves[0]
+	n.haves = n.haves[1:]
+	return h, true
+}
+
+func (n *recordingNegotiator) Ack(h plumbing.Hash) {
+	n.acks = append(n.acks, h)
+}
+
+func TestNegotiatePackNegotiator(t *testing.T) {
+	t.Parallel()
+
+	caps := capability.NewList()
+	conn := &mockConnection{caps: caps}
+
+	ack := plumbing.NewHash(fmt.Sprintf("%040x", 1))
+	reader := bytes.NewReader([]byte(fmt.Sprintf("0031ACK %s\n", ack)))
+	writer := newMockRWC(nil)
+
+	n := &recordingNegotiator{}
+	for i := range 40 {
+		n.haves = append(n.haves, plumbing.NewHash(fmt.Sprintf("%040x", i+1)))
+	}
+
+	req := &FetchRequest{
+		Wants:      []plumbing.Hash{plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")},
+		Haves:      []plumbing.Hash{plumbing.NewHash("9632f02833b2f9613afb5e75682132b0b22e4a31")},
+		Negotiator: n,
+		MaxRounds:  1,
+	}
+
+	_, err := NegotiatePack(context.TODO(), memory.NewStorage(), conn, reader, writer, req)
+	require.NoError(t, err)
+
+	sent := writer.writeBuf.String()
+	assert.Equal(t, 32, strings.Count(sent, "have "))
+	assert.Contains(t, sent, "have "+ack.String())
+	assert.NotContains(t, sent, "have 9632f02833b2f9613afb5e75682132b0b22e4a31")
+	assert.Equal(t, []plumbing.Hash{ack}, n.acks)
+}
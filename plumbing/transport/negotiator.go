@@ -0,0 +1,167 @@
+package transport
+
+import (
+	"container/heap"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// Negotiator selects the haves sent to the server during the pack
+// negotiation of a fetch, as the fetch.negotiationAlgorithm of git.
+type Negotiator interface {
+	// Next returns the next have to send, false once there are none left.
+	Next() (plumbing.Hash, bool)
+	// Ack is called with the haves the server acknowledged having.
+	Ack(h plumbing.Hash)
+}
+
+// havesNegotiator sends the haves of a FetchRequest, from the last one.
+type havesNegotiator struct {
+	haves []plumbing.Hash
+}
+
+func (n *havesNegotiator) Next() (plumbing.Hash, bool) {
+	if len(n.haves) == 0 {
+		return plumbing.ZeroHash, false
+	}
+
+	h := n.haves[len(n.haves)-1]
+	n.haves = n.haves[:len(n.haves)-1]
+	return h, true
+}
+
+func (n *havesNegotiator) Ack(plumbing.Hash) {}
+
+// skippingNegotiator walks the history from the tips by commit date, as the
+// skipping negotiation algorithm of git: along each line of history, the
+// number of commits skipped between two haves grows exponentially, so that
+// the common commits of a long history are found in few rounds.
+type skippingNegotiator struct {
+	s        storage.Storer
+	queue    skippingQueue
+	seen     map[plumbing.Hash]*skippingEntry
+	common   map[plumbing.Hash]bool
+	shallows map[plumbing.Hash]bool
+}
+
+// skippingEntry is a commit walked by a skippingNegotiator, with the number
+// of commits left to skip before sending one, ttl, along its line of history.
+type skippingEntry struct {
+	commit      *object.Commit
+	ttl         int
+	originalTTL int
+}
+
+// NewSkippingNegotiator returns a Negotiator walking the history of the
+// commits tips, from the storage s, which sends exponentially spaced haves,
+// as the skipping value of fetch.negotiationAlgorithm. The shallow commits of
+// s, and the ancestors of the haves acknowledged by the server, are not sent.
+func NewSkippingNegotiator(s storage.Storer, tips []plumbing.Hash) (Negotiator, error) {
+	n := &skippingNegotiator{
+		s:        s,
+		seen:     make(map[plumbing.Hash]*skippingEntry),
+		common:   make(map[plumbing.Hash]bool),
+		shallows: make(map[plumbing.Hash]bool),
+	}
+
+	shallows, err := s.Shallow()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range shallows {
+		n.shallows[h] = true
+	}
+
+	for _, h := range tips {
+		n.push(h, 0, 0)
+	}
+
+	return n, nil
+}
+
+// push adds the commit h to the queue, unless it was already seen or isn't
+// a commit of the storage.
+func (n *skippingNegotiator) push(h plumbing.Hash, ttl, originalTTL int) {
+	if _, ok := n.seen[h]; ok {
+		return
+	}
+
+	c, err := object.GetCommit(n.s, h)
+	if err != nil {
+		return
+	}
+
+	e := &skippingEntry{commit: c, ttl: ttl, originalTTL: originalTTL}
+	n.seen[h] = e
+	heap.Push(&n.queue, e)
+}
+
+func (n *skippingNegotiator) Next() (plumbing.Hash, bool) {
+	for n.queue.Len() > 0 {
+		e := heap.Pop(&n.queue).(*skippingEntry)
+		h := e.commit.Hash
+		if n.common[h] {
+			continue
+		}
+
+		originalTTL := e.originalTTL
+		ttl := e.ttl - 1
+		if e.ttl == 0 {
+			originalTTL = e.originalTTL*3/2 + 1
+			ttl = originalTTL
+		}
+
+		if !n.shallows[h] {
+			for _, p := range e.commit.ParentHashes {
+				n.push(p, ttl, originalTTL)
+			}
+		}
+
+		if e.ttl == 0 && !n.shallows[h] {
+			return h, true
+		}
+	}
+
+	return plumbing.ZeroHash, false
+}
+
+// Ack marks the commit h, and its ancestors already walked, as common, so
+// that they are not sent.
+func (n *skippingNegotiator) Ack(h plumbing.Hash) {
+	pending := []plumbing.Hash{h}
+	for len(pending) > 0 {
+		h := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if n.common[h] {
+			continue
+		}
+
+		n.common[h] = true
+		e, ok := n.seen[h]
+		if !ok {
+			continue
+		}
+
+		pending = append(pending, e.commit.ParentHashes...)
+	}
+}
+
+// skippingQueue is a priority queue of the commits walked, the most recent
+// first.
+type skippingQueue []*skippingEntry
+
+func (q skippingQueue) Len() int { return len(q) }
+func (q skippingQueue) Less(i, j int) bool {
+	return q[i].commit.Committer.When.After(q[j].commit.Committer.When)
+}
+func (q skippingQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *skippingQueue) Push(x any)   { *q = append(*q, x.(*skippingEntry)) }
+func (q *skippingQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,75 @@
+package transport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// linearHistory stores a history of n commits, and returns them from the
+// most recent one.
+func linearHistory(t *testing.T, st *memory.Storage, n int) []plumbing.Hash {
+	t.Helper()
+
+	hashes := make([]plumbing.Hash, n)
+	var parents []plumbing.Hash
+	for i := range n {
+		sig := object.Signature{Name: "foo", Email: "foo@foo.foo", When: time.Unix(int64(i)*60, 0)}
+		c := &object.Commit{Author: sig, Committer: sig, Message: "commit", ParentHashes: parents}
+		obj := st.NewEncodedObject()
+		require.NoError(t, c.Encode(obj))
+		h, err := st.SetEncodedObject(obj)
+		require.NoError(t, err)
+
+		hashes[n-1-i] = h
+		parents = []plumbing.Hash{h}
+	}
+
+	return hashes
+}
+
+func TestSkippingNegotiator(t *testing.T) {
+	t.Parallel()
+
+	st := memory.NewStorage()
+	history := linearHistory(t, st, 64)
+	n, err := NewSkippingNegotiator(st, history[:1])
+	require.NoError(t, err)
+
+	var sent []plumbing.Hash
+	for h, ok := n.Next(); ok; h, ok = n.Next() {
+		sent = append(sent, h)
+	}
+
+	var expected []plumbing.Hash
+	for _, i := range []int{0, 2, 5, 10, 18, 30, 48} {
+		expected = append(expected, history[i])
+	}
+
+	assert.Equal(t, expected, sent)
+}
+
+func TestSkippingNegotiatorAck(t *testing.T) {
+	t.Parallel()
+
+	st := memory.NewStorage()
+	history := linearHistory(t, st, 64)
+	n, err := NewSkippingNegotiator(st, history[:1])
+	require.NoError(t, err)
+
+	for _, i := range []int{0, 2, 5} {
+		h, ok := n.Next()
+		require.True(t, ok)
+		assert.Equal(t, history[i], h)
+	}
+
+	n.Ack(history[5])
+	_, ok := n.Next()
+	assert.False(t, ok)
+}
@@ -109,7 +109,8 @@ func SendPack(
 
 	// Send the packfile.
 	if req.Packfile != nil {
-		if _, err := ioutil.CopyBufferPool(writer, req.Packfile); err != nil {
+		w := ioutil.NewRateLimitedWriter(ctx, writer, req.MaxBytesPerSec)
+		if _, err := ioutil.CopyBufferPool(w, req.Packfile); err != nil {
 			return err
 		}
 
@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v6/internal/hook"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp/sideband"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// Receive hook errors, reported to the client as the status of the rejected
+// references.
+var (
+	ErrPreReceiveHookDeclined = errors.New("pre-receive hook declined")
+	ErrHookDeclined           = errors.New("hook declined")
+)
+
+// ReceiveHook is a server-side hook run by ReceivePack on the repository
+// stored in st. Anything written to stdout is sent to the client as progress
+// messages, if the client supports the sideband. A non-nil error rejects the
+// references the hook was run for.
+type ReceiveHook func(ctx context.Context, st storage.Storer, stdin io.Reader, stdout io.Writer, args ...string) error
+
+// ReceiveHooks are the server-side hooks run by ReceivePack once the packfile
+// has been received. Nil hooks are skipped.
+type ReceiveHooks struct {
+	// PreReceive is run once before updating any reference, with a
+	// "<old> <new> <ref>" line per command as stdin. If it fails, no
+	// reference is updated.
+	PreReceive ReceiveHook
+	// Update is run before updating each reference, with the reference name
+	// and its old and new hashes as arguments. If it fails, the reference is
+	// not updated.
+	Update ReceiveHook
+	// PostReceive is run once after updating the references, with a
+	// "<old> <new> <ref>" line per updated reference as stdin. Its error is
+	// ignored, since the references are already updated.
+	PostReceive ReceiveHook
+}
+
+// ExecReceiveHooks returns ReceiveHooks running the pre-receive, update and
+// post-receive executables in the hooks directory of the repository, or the
+// directory set by core.hooksPath, as git-receive-pack does. They are run
+// from the git directory, which requires a storage implementing
+// storer.FilesystemStorer. Missing hooks, and on Unix hooks not executable,
+// are skipped.
+func ExecReceiveHooks() *ReceiveHooks {
+	return &ReceiveHooks{
+		PreReceive:  execReceiveHook("pre-receive"),
+		Update:      execReceiveHook("update"),
+		PostReceive: execReceiveHook("post-receive"),
+	}
+}
+
+func execReceiveHook(name string) ReceiveHook {
+	return func(ctx context.Context, st storage.Storer, stdin io.Reader, stdout io.Writer, args ...string) error {
+		var env []string
+		if q, ok := st.(*quarantineStorer); ok {
+			env = q.env()
+			st = q.Storer
+		}
+
+		fs, ok := st.(storer.FilesystemStorer)
+		if !ok {
+			return nil
+		}
+
+		gitDir, err := filepath.Abs(fs.Filesystem().Root())
+		if err != nil {
+			return err
+		}
+
+		cfg, err := st.Config()
+		if err != nil {
+			return err
+		}
+
+		runner, err := hook.NewRunner(cfg, gitDir, gitDir)
+		if err != nil {
+			return err
+		}
+
+		runner.Env = append([]string{"GIT_DIR=."}, env...)
+		return runner.Run(ctx, name, stdin, stdout, args...)
+	}
+}
+
+// receiveHookInput returns the stdin of the pre-receive and post-receive
+// hooks for the given commands.
+func receiveHookInput(cmds []*packp.Command) io.Reader {
+	var buf bytes.Buffer
+	for _, cmd := range cmds {
+		fmt.Fprintf(&buf, "%s %s %s\n", cmd.Old, cmd.New, cmd.Name)
+	}
+
+	return &buf
+}
+
+// hookOutput returns the writer the output of the hooks is sent to, the
+// progress channel of the sideband if used.
+func hookOutput(w io.Writer) io.Writer {
+	m, ok := w.(*sideband.Muxer)
+	if !ok {
+		return io.Discard
+	}
+
+	return progressWriter{m}
+}
+
+type progressWriter struct {
+	m *sideband.Muxer
+}
+
+func (w progressWriter) Write(p []byte) (int, error) {
+	return w.m.WriteChannel(sideband.ProgressMessage, p)
+}
@@ -3,17 +3,18 @@ package transport
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
 
 	"github.com/go-git/go-git/v6/plumbing"
-	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/plumbing/format/pktline"
 	"github.com/go-git/go-git/v6/plumbing/protocol"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp/capability"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp/sideband"
-	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/plumbing/revlist"
 	"github.com/go-git/go-git/v6/storage"
 	"github.com/go-git/go-git/v6/utils/ioutil"
 )
@@ -23,10 +24,11 @@ type ReceivePackOptions struct {
 	GitProtocol   string
 	AdvertiseRefs bool
 	StatelessRPC  bool
+	// Hooks are run while updating the references. If nil, no hooks are run.
+	Hooks *ReceiveHooks
 }
 
 // ReceivePack is a server command that serves the receive-pack service.
-// TODO: support hooks
 func ReceivePack(
 	ctx context.Context,
 	st storage.Storer,
@@ -110,10 +112,21 @@ func ReceivePack(
 		}
 	}
 
-	// Receive the packfile
-	var unpackErr error
+	// Receive the packfile in quarantine. Storages able to write packfiles
+	// get it streamed to disk, indexed as it is written, and the
+	// connectivity of the new objects is checked once it is complete. The
+	// objects are only migrated to the repository once the pre-receive hook
+	// accepted them, and are discarded otherwise.
+	var (
+		unpackErr  error
+		quarantine *quarantineStorer
+	)
 	if needPackfile {
-		unpackErr = packfile.UpdateObjectStorage(st, rd)
+		quarantine, unpackErr = newQuarantineStorer(st)
+		if unpackErr == nil {
+			defer func() { _ = quarantine.discard() }()
+			unpackErr = quarantine.receive(rd)
+		}
 	}
 
 	// Done with the request, now close the reader
@@ -150,7 +163,11 @@ func ReceivePack(
 
 	var firstErr error
 	cmdStatus := make(map[plumbing.ReferenceName]error)
-	updateReferences(st, updreq, cmdStatus, &firstErr)
+	if quarantine != nil {
+		updateReferences(ctx, quarantine, updreq, opts.Hooks, hookOutput(writer), quarantine.migrate, cmdStatus, &firstErr)
+	} else {
+		updateReferences(ctx, st, updreq, opts.Hooks, hookOutput(writer), nil, cmdStatus, &firstErr)
+	}
 
 	if err := sendReportStatus(writeCloser, firstErr, cmdStatus); err != nil {
 		return err
@@ -207,50 +224,271 @@ func setStatus(cmdStatus map[plumbing.ReferenceName]error, firstErr *error, ref
 	}
 }
 
-func referenceExists(s storer.ReferenceStorer, n plumbing.ReferenceName) (bool, error) {
-	_, err := s.Reference(n)
-	if err == plumbing.ErrReferenceNotFound {
-		return false, nil
+// connectedCommands returns the commands whose new objects are all in the
+// storage, as git-receive-pack checks once the packfile is received, setting
+// the status of the others to ErrMissingObjects. The objects reachable from
+// the existing references are assumed to be in the storage.
+func connectedCommands(
+	st storage.Storer,
+	cmds []*packp.Command,
+	cmdStatus map[plumbing.ReferenceName]error,
+	firstErr *error,
+) []*packp.Command {
+	haves, shallow, err := connectivityHaves(st)
+	if err != nil {
+		for _, cmd := range cmds {
+			setStatus(cmdStatus, firstErr, cmd.Name, err)
+		}
+
+		return nil
+	}
+
+	var connected []*packp.Command
+	for _, cmd := range cmds {
+		if cmd.Action() != packp.Delete {
+			err := revlist.CheckConnectivityWithHaves(st, []plumbing.Hash{cmd.New}, haves, shallow)
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				err = ErrMissingObjects
+			}
+
+			if err != nil {
+				setStatus(cmdStatus, firstErr, cmd.Name, err)
+				continue
+			}
+		}
+
+		connected = append(connected, cmd)
 	}
 
-	return err == nil, err
+	return connected
 }
 
-func updateReferences(st storage.Storer, req *packp.UpdateRequests, cmdStatus map[plumbing.ReferenceName]error, firstErr *error) {
-	for _, cmd := range req.Commands {
-		exists, err := referenceExists(st, cmd.Name)
-		if err != nil {
-			setStatus(cmdStatus, firstErr, cmd.Name, err)
-			continue
+// connectivityHaves returns the tips of the references of the storage, and
+// its shallow commits.
+func connectivityHaves(st storage.Storer) (haves, shallow []plumbing.Hash, err error) {
+	iter, err := st.IterReferences()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			haves = append(haves, ref.Hash())
 		}
 
-		switch cmd.Action() {
-		case packp.Create:
-			if exists {
-				setStatus(cmdStatus, firstErr, cmd.Name, ErrUpdateReference)
-				continue
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shallow, err = st.Shallow()
+	return haves, shallow, err
+}
+
+// updateReferences updates the references of the commands whose objects are
+// all in st, running the hooks. migrate, if not nil, is called to migrate the
+// received objects to the repository once the pre-receive hook passed.
+func updateReferences(
+	ctx context.Context,
+	st storage.Storer,
+	req *packp.UpdateRequests,
+	hooks *ReceiveHooks,
+	hookOut io.Writer,
+	migrate func() error,
+	cmdStatus map[plumbing.ReferenceName]error,
+	firstErr *error,
+) {
+	if hooks == nil {
+		hooks = &ReceiveHooks{}
+	}
+
+	cmds := connectedCommands(st, req.Commands, cmdStatus, firstErr)
+	if req.Capabilities.Supports(capability.Atomic) && len(cmds) < len(req.Commands) {
+		rejectCommands(req.Commands, cmdStatus, firstErr)
+		return
+	}
+
+	if hooks.PreReceive != nil && len(cmds) > 0 {
+		if err := hooks.PreReceive(ctx, st, receiveHookInput(cmds), hookOut); err != nil {
+			for _, cmd := range cmds {
+				setStatus(cmdStatus, firstErr, cmd.Name, ErrPreReceiveHookDeclined)
 			}
 
-			ref := plumbing.NewHashReference(cmd.Name, cmd.New)
-			err := st.SetReference(ref)
+			return
+		}
+	}
+
+	if migrate != nil && len(cmds) > 0 {
+		if err := migrate(); err != nil {
+			for _, cmd := range cmds {
+				setStatus(cmdStatus, firstErr, cmd.Name, err)
+			}
+
+			return
+		}
+	}
+
+	policy, err := newReceivePolicy(st)
+	if err != nil {
+		for _, cmd := range cmds {
 			setStatus(cmdStatus, firstErr, cmd.Name, err)
-		case packp.Delete:
-			if !exists {
-				setStatus(cmdStatus, firstErr, cmd.Name, ErrUpdateReference)
-				continue
+		}
+
+		return
+	}
+
+	var updated []*packp.Command
+	if req.Capabilities.Supports(capability.Atomic) {
+		updated = updateReferencesAtomic(ctx, st, cmds, policy, hooks, hookOut, cmdStatus, firstErr)
+	} else {
+		for _, cmd := range cmds {
+			_, err := checkCommand(ctx, st, cmd, policy, hooks, hookOut)
+			if err == nil {
+				err = applyCommand(st, cmd)
 			}
 
-			err := st.RemoveReference(cmd.Name)
 			setStatus(cmdStatus, firstErr, cmd.Name, err)
-		case packp.Update:
-			if !exists {
-				setStatus(cmdStatus, firstErr, cmd.Name, ErrUpdateReference)
-				continue
+			if err == nil {
+				updated = append(updated, cmd)
 			}
+		}
+	}
 
-			ref := plumbing.NewHashReference(cmd.Name, cmd.New)
-			err := st.SetReference(ref)
+	if hooks.PostReceive != nil && len(updated) > 0 {
+		_ = hooks.PostReceive(ctx, st, receiveHookInput(updated), hookOut)
+	}
+}
+
+// checkCommand checks that the command can be applied, running the update
+// hook, and returns the current value of its reference, nil if it doesn't
+// exist.
+func checkCommand(
+	ctx context.Context,
+	st storage.Storer,
+	cmd *packp.Command,
+	policy *receivePolicy,
+	hooks *ReceiveHooks,
+	hookOut io.Writer,
+) (*plumbing.Reference, error) {
+	current, err := st.Reference(cmd.Name)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		current, err = nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch cmd.Action() {
+	case packp.Create:
+		if current != nil {
+			return nil, ErrUpdateReference
+		}
+	default:
+		if current == nil {
+			return nil, ErrUpdateReference
+		}
+
+		if current.Hash() != cmd.Old {
+			return nil, ErrStaleReference
+		}
+	}
+
+	if err := policy.check(cmd, current); err != nil {
+		return nil, err
+	}
+
+	if hooks.Update != nil {
+		err := hooks.Update(ctx, st, nil, hookOut, cmd.Name.String(), cmd.Old.String(), cmd.New.String())
+		if err != nil {
+			return nil, ErrHookDeclined
+		}
+	}
+
+	return current, nil
+}
+
+// applyCommand updates the reference of the checked command.
+func applyCommand(st storage.Storer, cmd *packp.Command) error {
+	if cmd.Action() == packp.Delete {
+		return st.RemoveReference(cmd.Name)
+	}
+
+	return st.SetReference(plumbing.NewHashReference(cmd.Name, cmd.New))
+}
+
+// updateReferencesAtomic updates the references of the commands of an atomic
+// push: either all of them are updated, or none if any fails, the others
+// failing with ErrAtomicPushFailed. The storage not supporting transactions
+// of references, the updates already applied are rolled back when one fails
+// to be stored.
+func updateReferencesAtomic(
+	ctx context.Context,
+	st storage.Storer,
+	cmds []*packp.Command,
+	policy *receivePolicy,
+	hooks *ReceiveHooks,
+	hookOut io.Writer,
+	cmdStatus map[plumbing.ReferenceName]error,
+	firstErr *error,
+) []*packp.Command {
+	currents := make([]*plumbing.Reference, len(cmds))
+	failed := false
+	for i, cmd := range cmds {
+		current, err := checkCommand(ctx, st, cmd, policy, hooks, hookOut)
+		if err != nil {
+			setStatus(cmdStatus, firstErr, cmd.Name, err)
+			failed = true
+			continue
+		}
+
+		currents[i] = current
+	}
+
+	if failed {
+		rejectCommands(cmds, cmdStatus, firstErr)
+		return nil
+	}
+
+	for i, cmd := range cmds {
+		if err := applyCommand(st, cmd); err != nil {
+			err = errors.Join(err, rollbackCommands(st, cmds[:i], currents[:i]))
 			setStatus(cmdStatus, firstErr, cmd.Name, err)
+			rejectCommands(cmds, cmdStatus, firstErr)
+			return nil
+		}
+	}
+
+	for _, cmd := range cmds {
+		setStatus(cmdStatus, firstErr, cmd.Name, nil)
+	}
+
+	return cmds
+}
+
+// rollbackCommands restores the references of the applied commands to their
+// previous value.
+func rollbackCommands(st storage.Storer, applied []*packp.Command, currents []*plumbing.Reference) error {
+	var errs []error
+	for i, cmd := range slices.Backward(applied) {
+		if currents[i] == nil {
+			errs = append(errs, st.RemoveReference(cmd.Name))
+			continue
+		}
+
+		errs = append(errs, st.SetReference(currents[i]))
+	}
+
+	return errors.Join(errs...)
+}
+
+// rejectCommands sets the status of the commands not failed yet to
+// ErrAtomicPushFailed.
+func rejectCommands(cmds []*packp.Command, cmdStatus map[plumbing.ReferenceName]error, firstErr *error) {
+	for _, cmd := range cmds {
+		if cmdStatus[cmd.Name] == nil {
+			setStatus(cmdStatus, firstErr, cmd.Name, ErrAtomicPushFailed)
 		}
 	}
 }
@@ -1,9 +1,29 @@
 package transport
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
+	"github.com/go-git/go-billy/v6/osfs"
+	fixtures "github.com/go-git/go-git-fixtures/v5"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v6/storage"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/storage/memory"
+	"github.com/go-git/go-git/v6/utils/ioutil"
 )
 
 type ReceivePackSuite struct {
@@ -28,3 +48,474 @@ func (s *ReceivePackSuite) TestReceivePackAdvertiseV1() {
 	buf := testAdvertise(s.T(), ReceivePack, "version=1", false)
 	s.Containsf(buf.String(), "version 1", "advertisement should contain version 1")
 }
+
+func newReceivePackHooksRequest(s *ReceivePackSuite, caps ...capability.Capability) (storage.Storer, io.ReadCloser) {
+	st := memory.NewStorage()
+	hash := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	req := packp.NewUpdateRequests()
+	for _, name := range []plumbing.ReferenceName{"refs/heads/a", "refs/heads/b"} {
+		s.Require().NoError(st.SetReference(plumbing.NewHashReference(name, hash)))
+		req.Commands = append(req.Commands, &packp.Command{Name: name, Old: hash})
+	}
+
+	s.Require().NoError(req.Capabilities.Set(capability.ReportStatus))
+	for _, c := range caps {
+		s.Require().NoError(req.Capabilities.Set(c))
+	}
+
+	var buf bytes.Buffer
+	s.Require().NoError(req.Encode(&buf))
+	return st, io.NopCloser(&buf)
+}
+
+func (s *ReceivePackSuite) TestReceivePackPreReceiveHookDeclined() {
+	st, r := newReceivePackHooksRequest(s, capability.Sideband64k)
+
+	var stdin []byte
+	hooks := &ReceiveHooks{
+		PreReceive: func(_ context.Context, _ storage.Storer, in io.Reader, out io.Writer, _ ...string) error {
+			stdin, _ = io.ReadAll(in)
+			_, _ = io.WriteString(out, "policy violation\n")
+			return errors.New("exit status 1")
+		},
+	}
+
+	var out bytes.Buffer
+	err := ReceivePack(context.TODO(), st, r, ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+		StatelessRPC: true,
+		Hooks:        hooks,
+	})
+	s.ErrorIs(err, ErrPreReceiveHookDeclined)
+	s.Equal(""+
+		"6ecf0ef2c2dffb796033e5a02219af86ec6584e5 0000000000000000000000000000000000000000 refs/heads/a\n"+
+		"6ecf0ef2c2dffb796033e5a02219af86ec6584e5 0000000000000000000000000000000000000000 refs/heads/b\n",
+		string(stdin))
+	s.Contains(out.String(), "\x02policy violation\n")
+	s.Contains(out.String(), "ng refs/heads/a pre-receive hook declined")
+
+	_, err = st.Reference("refs/heads/a")
+	s.NoError(err)
+}
+
+func (s *ReceivePackSuite) TestReceivePackUpdateHookDeclined() {
+	st, r := newReceivePackHooksRequest(s)
+
+	var updates [][]string
+	var postStdin []byte
+	hooks := &ReceiveHooks{
+		Update: func(_ context.Context, _ storage.Storer, _ io.Reader, _ io.Writer, args ...string) error {
+			updates = append(updates, args)
+			if args[0] == "refs/heads/a" {
+				return errors.New("exit status 1")
+			}
+
+			return nil
+		},
+		PostReceive: func(_ context.Context, _ storage.Storer, in io.Reader, _ io.Writer, _ ...string) error {
+			postStdin, _ = io.ReadAll(in)
+			return nil
+		},
+	}
+
+	var out bytes.Buffer
+	err := ReceivePack(context.TODO(), st, r, ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+		StatelessRPC: true,
+		Hooks:        hooks,
+	})
+	s.ErrorIs(err, ErrHookDeclined)
+	s.Equal([][]string{
+		{"refs/heads/a", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", "0000000000000000000000000000000000000000"},
+		{"refs/heads/b", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5", "0000000000000000000000000000000000000000"},
+	}, updates)
+	s.Equal("6ecf0ef2c2dffb796033e5a02219af86ec6584e5 0000000000000000000000000000000000000000 refs/heads/b\n",
+		string(postStdin))
+
+	rs := packp.NewReportStatus()
+	s.Require().NoError(rs.Decode(&out))
+	s.Require().Len(rs.CommandStatuses, 2)
+
+	_, err = st.Reference("refs/heads/a")
+	s.NoError(err)
+	_, err = st.Reference("refs/heads/b")
+	s.ErrorIs(err, plumbing.ErrReferenceNotFound)
+}
+
+func (s *ReceivePackSuite) TestReceivePackAtomicUpdateHookDeclined() {
+	st, r := newReceivePackHooksRequest(s, capability.Atomic)
+
+	var postReceive bool
+	hooks := &ReceiveHooks{
+		Update: func(_ context.Context, _ storage.Storer, _ io.Reader, _ io.Writer, args ...string) error {
+			if args[0] == "refs/heads/a" {
+				return errors.New("exit status 1")
+			}
+
+			return nil
+		},
+		PostReceive: func(_ context.Context, _ storage.Storer, _ io.Reader, _ io.Writer, _ ...string) error {
+			postReceive = true
+			return nil
+		},
+	}
+
+	var out bytes.Buffer
+	err := ReceivePack(context.TODO(), st, r, ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+		StatelessRPC: true,
+		Hooks:        hooks,
+	})
+	s.ErrorIs(err, ErrHookDeclined)
+	s.False(postReceive)
+
+	rs := packp.NewReportStatus()
+	s.Require().NoError(rs.Decode(&out))
+	statuses := make(map[plumbing.ReferenceName]string)
+	for _, cs := range rs.CommandStatuses {
+		statuses[cs.ReferenceName] = cs.Status
+	}
+	s.Equal(map[plumbing.ReferenceName]string{
+		"refs/heads/a": ErrHookDeclined.Error(),
+		"refs/heads/b": ErrAtomicPushFailed.Error(),
+	}, statuses)
+
+	_, err = st.Reference("refs/heads/a")
+	s.NoError(err)
+	_, err = st.Reference("refs/heads/b")
+	s.NoError(err)
+}
+
+func (s *ReceivePackSuite) TestReceivePackAtomic() {
+	st, r := newReceivePackHooksRequest(s, capability.Atomic)
+
+	var out bytes.Buffer
+	err := ReceivePack(context.TODO(), st, r, ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+		StatelessRPC: true,
+	})
+	s.NoError(err)
+
+	rs := packp.NewReportStatus()
+	s.Require().NoError(rs.Decode(&out))
+	s.NoError(rs.Error())
+
+	_, err = st.Reference("refs/heads/a")
+	s.ErrorIs(err, plumbing.ErrReferenceNotFound)
+	_, err = st.Reference("refs/heads/b")
+	s.ErrorIs(err, plumbing.ErrReferenceNotFound)
+}
+
+func (s *ReceivePackSuite) TestReceivePackPolicy() {
+	dot := fixtures.Basic().One().DotGit(fixtures.WithTargetDir(s.T().TempDir))
+	st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+
+	cfg, err := st.Config()
+	s.Require().NoError(err)
+	cfg.Core.IsBare = false
+	cfg.Receive.DenyNonFastForwards = config.NewOptBool(true)
+	cfg.Receive.DenyDeletes = config.NewOptBool(true)
+	cfg.Receive.DenyCurrentBranch = "refuse"
+	s.Require().NoError(st.SetConfig(cfg))
+
+	head := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	parent := plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	s.Require().NoError(st.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/master")))
+
+	req := packp.NewUpdateRequests()
+	s.Require().NoError(req.Capabilities.Set(capability.ReportStatus))
+	for _, cmd := range []*packp.Command{
+		{Name: "refs/heads/master", Old: head, New: parent},
+		{Name: "refs/heads/a", Old: head, New: parent},
+		{Name: "refs/heads/b", Old: parent, New: head},
+		{Name: "refs/heads/c", Old: head},
+		{Name: "refs/tags/t", Old: head},
+	} {
+		s.Require().NoError(st.SetReference(plumbing.NewHashReference(cmd.Name, cmd.Old)))
+		req.Commands = append(req.Commands, cmd)
+	}
+
+	var in, out bytes.Buffer
+	s.Require().NoError(req.Encode(&in))
+	in.Write(emptyPackfile())
+	err = ReceivePack(context.TODO(), st, io.NopCloser(&in), ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+		StatelessRPC: true,
+	})
+	s.Error(err)
+
+	rs := packp.NewReportStatus()
+	s.Require().NoError(rs.Decode(&out))
+	statuses := map[plumbing.ReferenceName]string{}
+	for _, cs := range rs.CommandStatuses {
+		statuses[cs.ReferenceName] = cs.Status
+	}
+
+	s.Equal(map[plumbing.ReferenceName]string{
+		"refs/heads/master": "branch is currently checked out",
+		"refs/heads/a":      "non-fast-forward",
+		"refs/heads/b":      "ok",
+		"refs/heads/c":      "deletion prohibited",
+		"refs/tags/t":       "ok",
+	}, statuses)
+
+	ref, err := st.Reference("refs/heads/a")
+	s.Require().NoError(err)
+	s.Equal(head, ref.Hash())
+
+	_, err = st.Reference("refs/tags/t")
+	s.ErrorIs(err, plumbing.ErrReferenceNotFound)
+}
+
+func (s *ReceivePackSuite) TestReceivePackPolicyForgedOld() {
+	dot := fixtures.Basic().One().DotGit(fixtures.WithTargetDir(s.T().TempDir))
+	st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+
+	cfg, err := st.Config()
+	s.Require().NoError(err)
+	cfg.Receive.DenyNonFastForwards = config.NewOptBool(true)
+	s.Require().NoError(st.SetConfig(cfg))
+
+	head := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	parent := plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	grandparent := plumbing.NewHash("af2d6a6954d532f8ffb47615169c8fdf9d383a1a")
+	s.Require().NoError(st.SetReference(plumbing.NewHashReference("refs/heads/a", head)))
+	s.Require().NoError(st.SetReference(plumbing.NewHashReference("refs/heads/b", parent)))
+
+	req := packp.NewUpdateRequests()
+	s.Require().NoError(req.Capabilities.Set(capability.ReportStatus))
+	req.Commands = []*packp.Command{
+		{Name: "refs/heads/a", Old: grandparent, New: parent},
+		{Name: "refs/heads/b", Old: parent, New: head},
+	}
+
+	var in, out bytes.Buffer
+	s.Require().NoError(req.Encode(&in))
+	in.Write(emptyPackfile())
+	err = ReceivePack(context.TODO(), st, io.NopCloser(&in), ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+		StatelessRPC: true,
+	})
+	s.Error(err)
+
+	rs := packp.NewReportStatus()
+	s.Require().NoError(rs.Decode(&out))
+	statuses := map[plumbing.ReferenceName]string{}
+	for _, cs := range rs.CommandStatuses {
+		statuses[cs.ReferenceName] = cs.Status
+	}
+
+	s.Equal(map[plumbing.ReferenceName]string{
+		"refs/heads/a": "stale info",
+		"refs/heads/b": "ok",
+	}, statuses)
+
+	ref, err := st.Reference("refs/heads/a")
+	s.Require().NoError(err)
+	s.Equal(head, ref.Hash())
+}
+
+func (s *ReceivePackSuite) TestReceivePackDenyCurrentBranchDefault() {
+	dot := fixtures.Basic().One().DotGit(fixtures.WithTargetDir(s.T().TempDir))
+	st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+
+	cfg, err := st.Config()
+	s.Require().NoError(err)
+	cfg.Core.IsBare = false
+	s.Require().NoError(st.SetConfig(cfg))
+
+	head := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	parent := plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	s.Require().NoError(st.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/master")))
+
+	req := packp.NewUpdateRequests()
+	s.Require().NoError(req.Capabilities.Set(capability.ReportStatus))
+	req.Commands = []*packp.Command{{Name: "refs/heads/master", Old: head, New: parent}}
+
+	var in, out bytes.Buffer
+	s.Require().NoError(req.Encode(&in))
+	in.Write(emptyPackfile())
+	err = ReceivePack(context.TODO(), st, io.NopCloser(&in), ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+		StatelessRPC: true,
+	})
+	s.Error(err)
+
+	rs := packp.NewReportStatus()
+	s.Require().NoError(rs.Decode(&out))
+	s.Require().Len(rs.CommandStatuses, 1)
+	s.Equal("branch is currently checked out", rs.CommandStatuses[0].Status)
+
+	ref, err := st.Reference("refs/heads/master")
+	s.Require().NoError(err)
+	s.Equal(head, ref.Hash())
+}
+
+func (s *ReceivePackSuite) TestReceivePackMissingObjects() {
+	dot := fixtures.Basic().One().DotGit(fixtures.WithTargetDir(s.T().TempDir))
+	st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+
+	missing := plumbing.NewHash("0000000000000000000000000000000000000001")
+	req := packp.NewUpdateRequests()
+	s.Require().NoError(req.Capabilities.Set(capability.ReportStatus))
+	req.Commands = []*packp.Command{
+		{Name: "refs/heads/missing", New: missing},
+		{Name: "refs/heads/present", New: plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")},
+	}
+
+	var in, out bytes.Buffer
+	s.Require().NoError(req.Encode(&in))
+	in.Write(emptyPackfile())
+	err := ReceivePack(context.TODO(), st, io.NopCloser(&in), ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+		StatelessRPC: true,
+	})
+	s.ErrorIs(err, ErrMissingObjects)
+
+	rs := packp.NewReportStatus()
+	s.Require().NoError(rs.Decode(&out))
+	statuses := map[plumbing.ReferenceName]string{}
+	for _, cs := range rs.CommandStatuses {
+		statuses[cs.ReferenceName] = cs.Status
+	}
+
+	s.Equal(map[plumbing.ReferenceName]string{
+		"refs/heads/missing": "missing necessary objects",
+		"refs/heads/present": "ok",
+	}, statuses)
+
+	_, err = st.Reference("refs/heads/missing")
+	s.ErrorIs(err, plumbing.ErrReferenceNotFound)
+}
+
+// emptyPackfile returns a packfile without objects.
+func emptyPackfile() []byte {
+	pack := []byte("PACK\x00\x00\x00\x02\x00\x00\x00\x00")
+	sum := sha1.Sum(pack)
+	return append(pack, sum[:]...)
+}
+
+func (s *ReceivePackSuite) TestExecReceiveHooks() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("hooks are shell scripts")
+	}
+
+	dir := s.T().TempDir()
+	st := filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault())
+	s.Require().NoError(st.Init())
+
+	hook := "#!/bin/sh\necho \"$@\" > args\ncat > stdin\necho rejected\nexit 1\n"
+	s.Require().NoError(os.MkdirAll(filepath.Join(dir, "hooks"), 0o755))
+	s.Require().NoError(os.WriteFile(filepath.Join(dir, "hooks", "update"), []byte(hook), 0o755))
+
+	var out bytes.Buffer
+	err := ExecReceiveHooks().Update(context.TODO(), st, nil, &out, "refs/heads/a", "old", "new")
+	s.Error(err)
+	s.Equal("rejected\n", out.String())
+
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	s.Require().NoError(err)
+	s.Equal("refs/heads/a old new\n", string(args))
+
+	// Missing hooks are skipped.
+	s.NoError(ExecReceiveHooks().PreReceive(context.TODO(), st, nil, &out))
+}
+
+// newReceivePackQuarantineRequest returns a request creating refs/heads/master
+// with the objects of the basic fixture.
+func newReceivePackQuarantineRequest(s *ReceivePackSuite) io.ReadCloser {
+	req := packp.NewUpdateRequests()
+	s.Require().NoError(req.Capabilities.Set(capability.ReportStatus))
+	req.Commands = []*packp.Command{{
+		Name: plumbing.Master,
+		New:  plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"),
+	}}
+
+	var buf bytes.Buffer
+	s.Require().NoError(req.Encode(&buf))
+
+	pack := fixtures.Basic().One().Packfile()
+	defer pack.Close()
+	_, err := io.Copy(&buf, pack)
+	s.Require().NoError(err)
+
+	return io.NopCloser(&buf)
+}
+
+func (s *ReceivePackSuite) TestReceivePackQuarantine() {
+	head := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	for _, declined := range []bool{true, false} {
+		dir := s.T().TempDir()
+		fs := osfs.New(dir)
+		st := filesystem.NewStorage(fs, cache.NewObjectLRUDefault())
+		s.Require().NoError(st.Init())
+
+		var quarantined bool
+		hooks := &ReceiveHooks{
+			PreReceive: func(_ context.Context, hst storage.Storer, _ io.Reader, _ io.Writer, _ ...string) error {
+				s.NoError(hst.HasEncodedObject(head))
+				quarantined = st.HasEncodedObject(head) != nil
+				if declined {
+					return errors.New("exit status 1")
+				}
+
+				return nil
+			},
+		}
+
+		var out bytes.Buffer
+		err := ReceivePack(context.TODO(), st, newReceivePackQuarantineRequest(s), ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+			StatelessRPC: true,
+			Hooks:        hooks,
+		})
+		s.True(quarantined)
+
+		entries, rerr := os.ReadDir(filepath.Join(dir, "objects"))
+		s.Require().NoError(rerr)
+		for _, e := range entries {
+			s.NotContains(e.Name(), quarantinePrefix)
+		}
+
+		if declined {
+			s.ErrorIs(err, ErrPreReceiveHookDeclined)
+			s.ErrorIs(st.HasEncodedObject(head), plumbing.ErrObjectNotFound)
+			continue
+		}
+
+		s.NoError(err)
+		s.NoError(st.HasEncodedObject(head))
+
+		packs, err := st.ObjectPacks()
+		s.NoError(err)
+		s.Len(packs, 1)
+
+		ref, err := st.Reference(plumbing.Master)
+		s.Require().NoError(err)
+		s.Equal(head, ref.Hash())
+	}
+}
+
+func (s *ReceivePackSuite) TestReceivePackQuarantineMemory() {
+	head := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	for _, declined := range []bool{true, false} {
+		st := memory.NewStorage()
+		hooks := &ReceiveHooks{
+			PreReceive: func(_ context.Context, hst storage.Storer, _ io.Reader, _ io.Writer, _ ...string) error {
+				s.NoError(hst.HasEncodedObject(head))
+				s.ErrorIs(st.HasEncodedObject(head), plumbing.ErrObjectNotFound)
+				if declined {
+					return errors.New("exit status 1")
+				}
+
+				return nil
+			},
+		}
+
+		var out bytes.Buffer
+		err := ReceivePack(context.TODO(), st, newReceivePackQuarantineRequest(s), ioutil.WriteNopCloser(&out), &ReceivePackOptions{
+			StatelessRPC: true,
+			Hooks:        hooks,
+		})
+
+		if declined {
+			s.ErrorIs(err, ErrPreReceiveHookDeclined)
+			s.Empty(st.Objects)
+			continue
+		}
+
+		s.NoError(err)
+		s.Len(st.Objects, 31)
+	}
+}
@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// Receive policy errors, reported to the client as the status of the
+// references rejected by the receive.* config of the repository.
+var (
+	ErrNonFastForwardDenied = errors.New("non-fast-forward")
+	ErrDeletionDenied       = errors.New("deletion prohibited")
+	ErrCurrentBranchDenied  = errors.New("branch is currently checked out")
+)
+
+// receivePolicy enforces the receive.denyNonFastForwards,
+// receive.denyDeletes and receive.denyCurrentBranch config, as
+// git-receive-pack does.
+type receivePolicy struct {
+	st  storage.Storer
+	cfg *config.Config
+	// current is the branch checked out, empty in bare repositories.
+	current plumbing.ReferenceName
+}
+
+func newReceivePolicy(st storage.Storer) (*receivePolicy, error) {
+	cfg, err := st.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &receivePolicy{st: st, cfg: cfg}
+	if cfg.Core.IsBare {
+		return p, nil
+	}
+
+	head, err := st.Reference(plumbing.HEAD)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return p, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if head.Type() == plumbing.SymbolicReference {
+		p.current = head.Target()
+	}
+
+	return p, nil
+}
+
+// check returns the error rejecting the command, if any. current is the
+// value of the reference being updated, nil if it doesn't exist.
+func (p *receivePolicy) check(cmd *packp.Command, current *plumbing.Reference) error {
+	action := cmd.Action()
+	if action != packp.Delete && cmd.Name == p.current && p.denyCurrentBranch() {
+		return ErrCurrentBranchDenied
+	}
+
+	if !strings.HasPrefix(cmd.Name.String(), "refs/heads/") {
+		return nil
+	}
+
+	switch {
+	case action == packp.Delete && p.cfg.Receive.DenyDeletes.IsTrue():
+		return ErrDeletionDenied
+	case action == packp.Update && p.cfg.Receive.DenyNonFastForwards.IsTrue():
+		if current == nil || !p.isFastForward(current.Hash(), cmd.New) {
+			return ErrNonFastForwardDenied
+		}
+	}
+
+	return nil
+}
+
+func (p *receivePolicy) denyCurrentBranch() bool {
+	switch strings.ToLower(p.cfg.Receive.DenyCurrentBranch) {
+	case "warn", "ignore", "false":
+		return false
+	default:
+		return true
+	}
+}
+
+// isFastForward returns whether the commit old is an ancestor of new, false
+// if any of them is not a commit.
+func (p *receivePolicy) isFastForward(old, new plumbing.Hash) bool {
+	oldCommit, err := object.GetCommit(p.st, old)
+	if err != nil {
+		return false
+	}
+
+	newCommit, err := object.GetCommit(p.st, new)
+	if err != nil {
+		return false
+	}
+
+	ok, err := oldCommit.IsAncestor(newCommit)
+	return err == nil && ok
+}
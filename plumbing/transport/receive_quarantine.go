@@ -0,0 +1,358 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/util"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	formatcfg "github.com/go-git/go-git/v6/plumbing/format/config"
+	"github.com/go-git/go-git/v6/plumbing/format/packfile"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+const (
+	quarantineDir    = "objects"
+	quarantinePrefix = "tmp_objdir-incoming-"
+)
+
+// quarantineStorer is the storage ReceivePack writes the received objects
+// to, as git-receive-pack does with its quarantine object directory: they
+// are read along with the objects of the repository, but only migrated to it
+// once the connectivity check and the pre-receive hook pass, and discarded
+// otherwise, so that rejected pushes leave no objects behind.
+type quarantineStorer struct {
+	storage.Storer
+
+	incoming objectQuarantine
+	migrated bool
+}
+
+// objectQuarantine holds the objects received by ReceivePack until they are
+// migrated to the repository.
+type objectQuarantine interface {
+	storer.EncodedObjectStorer
+	// receive writes the objects of the packfile, reading the bases of its
+	// deltas from st.
+	receive(st storer.Storer, r io.Reader) error
+	// migrate moves the objects to the repository.
+	migrate() error
+	// discard removes the objects.
+	discard() error
+	// env returns the environment variables pointing the hooks to the
+	// quarantine, as git sets them.
+	env() []string
+}
+
+// newQuarantineStorer returns a quarantineStorer for st. Storages with a
+// filesystem get the received objects in a temporary object directory, the
+// others in memory.
+func newQuarantineStorer(st storage.Storer) (*quarantineStorer, error) {
+	cfg, err := st.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	format := cfg.Extensions.ObjectFormat
+
+	var incoming objectQuarantine
+	if fs, ok := st.(storer.FilesystemStorer); ok {
+		incoming, err = newFilesystemQuarantine(fs.Filesystem(), st, format)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		incoming = &memoryQuarantine{
+			ObjectStorage: &memory.NewStorage(memory.WithObjectFormat(format)).ObjectStorage,
+			dst:           st,
+		}
+	}
+
+	return &quarantineStorer{Storer: st, incoming: incoming}, nil
+}
+
+// receive writes the objects of the packfile to the quarantine.
+func (q *quarantineStorer) receive(r io.Reader) error {
+	return q.incoming.receive(q, r)
+}
+
+// migrate moves the received objects to the repository. The objects are
+// read from the repository only from then on.
+func (q *quarantineStorer) migrate() error {
+	if q.migrated {
+		return nil
+	}
+
+	q.migrated = true
+	if err := q.incoming.migrate(); err != nil {
+		return fmt.Errorf("migrating quarantined objects: %w", err)
+	}
+
+	return nil
+}
+
+// discard removes the received objects, unless they were migrated.
+func (q *quarantineStorer) discard() error {
+	if q.migrated {
+		return nil
+	}
+
+	q.migrated = true
+	return q.incoming.discard()
+}
+
+// env returns the environment variables of the hooks run while the objects
+// are in quarantine.
+func (q *quarantineStorer) env() []string {
+	if q.migrated {
+		return nil
+	}
+
+	return q.incoming.env()
+}
+
+// NewEncodedObject implements storer.EncodedObjectStorer.
+func (q *quarantineStorer) NewEncodedObject() plumbing.EncodedObject {
+	if q.migrated {
+		return q.Storer.NewEncodedObject()
+	}
+
+	return q.incoming.NewEncodedObject()
+}
+
+// SetEncodedObject implements storer.EncodedObjectStorer.
+func (q *quarantineStorer) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	if q.migrated {
+		return q.Storer.SetEncodedObject(obj)
+	}
+
+	return q.incoming.SetEncodedObject(obj)
+}
+
+// RawObjectWriter implements storer.EncodedObjectStorer.
+func (q *quarantineStorer) RawObjectWriter(typ plumbing.ObjectType, sz int64) (io.WriteCloser, error) {
+	if q.migrated {
+		return q.Storer.RawObjectWriter(typ, sz)
+	}
+
+	return q.incoming.RawObjectWriter(typ, sz)
+}
+
+// EncodedObject implements storer.EncodedObjectStorer.
+func (q *quarantineStorer) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	if !q.migrated {
+		obj, err := q.incoming.EncodedObject(t, h)
+		if !errors.Is(err, plumbing.ErrObjectNotFound) {
+			return obj, err
+		}
+	}
+
+	return q.Storer.EncodedObject(t, h)
+}
+
+// HasEncodedObject implements storer.EncodedObjectStorer.
+func (q *quarantineStorer) HasEncodedObject(h plumbing.Hash) error {
+	if !q.migrated {
+		err := q.incoming.HasEncodedObject(h)
+		if !errors.Is(err, plumbing.ErrObjectNotFound) {
+			return err
+		}
+	}
+
+	return q.Storer.HasEncodedObject(h)
+}
+
+// EncodedObjectSize implements storer.EncodedObjectStorer.
+func (q *quarantineStorer) EncodedObjectSize(h plumbing.Hash) (int64, error) {
+	if !q.migrated {
+		size, err := q.incoming.EncodedObjectSize(h)
+		if !errors.Is(err, plumbing.ErrObjectNotFound) {
+			return size, err
+		}
+	}
+
+	return q.Storer.EncodedObjectSize(h)
+}
+
+// IterEncodedObjects implements storer.EncodedObjectStorer.
+func (q *quarantineStorer) IterEncodedObjects(t plumbing.ObjectType) (storer.EncodedObjectIter, error) {
+	iter, err := q.Storer.IterEncodedObjects(t)
+	if err != nil || q.migrated {
+		return iter, err
+	}
+
+	incoming, err := q.incoming.IterEncodedObjects(t)
+	if err != nil {
+		iter.Close()
+		return nil, err
+	}
+
+	return storer.NewMultiEncodedObjectIter([]storer.EncodedObjectIter{incoming, iter}), nil
+}
+
+// memoryQuarantine holds the received objects in memory, and migrates them
+// to dst one by one.
+type memoryQuarantine struct {
+	*memory.ObjectStorage
+	dst storer.EncodedObjectStorer
+}
+
+func (m *memoryQuarantine) receive(st storer.Storer, r io.Reader) error {
+	return packfile.UpdateObjectStorage(st, r)
+}
+
+func (m *memoryQuarantine) migrate() error {
+	for _, obj := range m.Objects {
+		if _, err := m.dst.SetEncodedObject(obj); err != nil {
+			return err
+		}
+	}
+
+	return m.discard()
+}
+
+func (m *memoryQuarantine) discard() error {
+	m.ObjectStorage = nil
+	return nil
+}
+
+func (*memoryQuarantine) env() []string { return nil }
+
+// filesystemQuarantine holds the received objects in a temporary object
+// directory of the repository, from which they are moved to its object
+// directory.
+type filesystemQuarantine struct {
+	*filesystem.Storage
+
+	fs  billy.Filesystem
+	dir string
+	dst storage.Storer
+}
+
+func newFilesystemQuarantine(fs billy.Filesystem, dst storage.Storer, format formatcfg.ObjectFormat) (*filesystemQuarantine, error) {
+	dir, err := util.TempDir(fs, quarantineDir, quarantinePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	qfs, err := fs.Chroot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &filesystemQuarantine{
+		Storage: filesystem.NewStorageWithOptions(qfs, cache.NewObjectLRUDefault(), filesystem.Options{
+			ObjectFormat: format,
+		}),
+		fs:  fs,
+		dir: dir,
+		dst: dst,
+	}, nil
+}
+
+func (f *filesystemQuarantine) receive(_ storer.Storer, r io.Reader) error {
+	return packfile.WritePackfileToObjectStorage(f.Storage, r)
+}
+
+// objectsDir is the object directory of the quarantine.
+func (f *filesystemQuarantine) objectsDir() string {
+	return f.fs.Join(f.dir, "objects")
+}
+
+// migrate moves the packfiles, their index last so that they are complete
+// once found, and then the loose objects to the object directory of the
+// repository.
+func (f *filesystemQuarantine) migrate() error {
+	if err := f.Storage.Close(); err != nil {
+		return err
+	}
+
+	packs, err := f.fs.ReadDir(f.fs.Join(f.objectsDir(), "pack"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, last := range []bool{false, true} {
+		for _, fi := range packs {
+			if strings.HasSuffix(fi.Name(), ".idx") != last || strings.HasPrefix(fi.Name(), "tmp_") {
+				continue
+			}
+
+			if err := f.move(path.Join("pack", fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	dirs, err := f.fs.ReadDir(f.objectsDir())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+
+		objs, err := f.fs.ReadDir(f.fs.Join(f.objectsDir(), dir.Name()))
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range objs {
+			if err := f.move(path.Join(dir.Name(), obj.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r, ok := f.dst.(interface{ Reindex() }); ok {
+		r.Reindex()
+	}
+
+	return util.RemoveAll(f.fs, f.dir)
+}
+
+// move moves the file name of the quarantine to the object directory of the
+// repository, unless it already has it.
+func (f *filesystemQuarantine) move(name string) error {
+	dst := f.fs.Join(quarantineDir, name)
+	if _, err := f.fs.Lstat(dst); err == nil {
+		return nil
+	}
+
+	if err := f.fs.MkdirAll(path.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	return f.fs.Rename(f.fs.Join(f.objectsDir(), name), dst)
+}
+
+func (f *filesystemQuarantine) discard() error {
+	return errors.Join(f.Storage.Close(), util.RemoveAll(f.fs, f.dir))
+}
+
+func (f *filesystemQuarantine) env() []string {
+	root, err := filepath.Abs(f.fs.Root())
+	if err != nil {
+		return nil
+	}
+
+	objects := filepath.Join(root, filepath.FromSlash(f.objectsDir()))
+	return []string{
+		"GIT_QUARANTINE_PATH=" + objects,
+		"GIT_OBJECT_DIRECTORY=" + objects,
+		"GIT_ALTERNATE_OBJECT_DIRECTORIES=" + filepath.Join(root, quarantineDir),
+	}
+}
@@ -8,6 +8,7 @@ import (
 // registry are the protocols supported by default.
 var (
 	registry = map[string]Transport{}
+	fallback func(protocol string) (Transport, error)
 	mtx      sync.RWMutex
 )
 
@@ -26,12 +27,25 @@ func Unregister(scheme string) {
 	mtx.Unlock()
 }
 
+// RegisterFallback sets the function returning the client of the protocols
+// not registered, as the transport running the git remote helpers does. It
+// replaces the previous one, if any, and a nil function removes it.
+func RegisterFallback(f func(protocol string) (Transport, error)) {
+	mtx.Lock()
+	fallback = f
+	mtx.Unlock()
+}
+
 // Get returns the appropriate client for the given protocol.
 func Get(p string) (Transport, error) {
 	mtx.RLock()
 	defer mtx.RUnlock()
 	f, ok := registry[p]
 	if !ok {
+		if fallback != nil {
+			return fallback(p)
+		}
+
 		return nil, fmt.Errorf("unsupported scheme %q", p)
 	}
 
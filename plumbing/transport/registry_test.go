@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -61,6 +62,24 @@ func (s *RegistrySuite) TestInstallProtocolNilValue() {
 	s.Error(err)
 }
 
+func (s *RegistrySuite) TestRegisterFallback() {
+	RegisterFallback(func(p string) (Transport, error) {
+		if p != "fallback" {
+			return nil, fmt.Errorf("unsupported scheme %q", p)
+		}
+
+		return &dummyClient{}, nil
+	})
+	defer RegisterFallback(nil)
+
+	p, err := Get("fallback")
+	s.NoError(err)
+	s.NotNil(p)
+
+	_, err = Get("unknown")
+	s.Error(err)
+}
+
 type dummyClient struct {
 	*http.Client
 }
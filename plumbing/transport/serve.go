@@ -15,8 +15,19 @@ import (
 	"github.com/go-git/go-git/v6/storage"
 )
 
-// ErrUpdateReference is returned when a reference update fails.
-var ErrUpdateReference = errors.New("failed to update ref")
+var (
+	// ErrUpdateReference is returned when a reference update fails.
+	ErrUpdateReference = errors.New("failed to update ref")
+	// ErrStaleReference is returned when the old value of a reference update
+	// doesn't match the current value of the reference.
+	ErrStaleReference = errors.New("stale info")
+	// ErrMissingObjects is returned when a reference update points to
+	// objects that are not all in the repository after the push.
+	ErrMissingObjects = errors.New("missing necessary objects")
+	// ErrAtomicPushFailed is the status of the reference updates of an atomic
+	// push not applied because another one of the push failed.
+	ErrAtomicPushFailed = errors.New("atomic push failure")
+)
 
 // AdvertiseReferences is a server command that implements the reference
 // discovery phase of the Git transfer protocol.
@@ -43,7 +54,7 @@ func AdvertiseReferences(
 	if forPush {
 		// TODO: support thin-pack
 		_ = ar.Capabilities.Set(capability.NoThin)
-		// TODO: support atomic
+		_ = ar.Capabilities.Set(capability.Atomic)
 		_ = ar.Capabilities.Set(capability.DeleteRefs)
 		_ = ar.Capabilities.Set(capability.ReportStatus)
 		_ = ar.Capabilities.Set(capability.PushOptions)
@@ -58,11 +69,22 @@ func AdvertiseReferences(
 		_ = ar.Capabilities.Set(capability.NoProgress)
 		_ = ar.Capabilities.Set(capability.SymRef)
 		_ = ar.Capabilities.Set(capability.Shallow)
+		_ = ar.Capabilities.Set(capability.DeepenNot)
 
 		cfg, err := st.Config()
 		var objectformat config.ObjectFormat
 		if err == nil && cfg != nil {
 			objectformat = cfg.Extensions.ObjectFormat
+
+			// allowAnySHA1InWant implies the others, as in git.
+			anySHA1 := cfg.UploadPack.AllowAnySHA1InWant.IsTrue()
+			if anySHA1 || cfg.UploadPack.AllowTipSHA1InWant.IsTrue() {
+				_ = ar.Capabilities.Set(capability.AllowTipSHA1InWant)
+			}
+
+			if anySHA1 || cfg.UploadPack.AllowReachableSHA1InWant.IsTrue() {
+				_ = ar.Capabilities.Set(capability.AllowReachableSHA1InWant)
+			}
 		}
 
 		if objectformat == config.UnsetObjectFormat {
@@ -123,8 +145,8 @@ func addReferences(st storage.Storer, ar *packp.AdvRefs, addHead bool) error {
 		}
 		ar.References[name.String()] = hash
 		if r.Name().IsTag() {
-			if tag, err := object.GetTag(st, hash); err == nil {
-				ar.Peeled[name.String()] = tag.Target
+			if peeled, err := object.Peel(st, hash); err == nil && peeled != hash {
+				ar.Peeled[name.String()] = peeled
 			}
 		}
 		return nil
@@ -67,6 +67,13 @@ type Endpoint struct {
 	CaBundle []byte
 	// Proxy provides info required for connecting to a proxy.
 	Proxy ProxyOptions
+	// ExtraHeaders are added to the requests of the HTTP transport, as
+	// "Name: value", as http.extraHeader.
+	ExtraHeaders []string
+	// FollowRedirects selects the redirects followed by the HTTP transport,
+	// as http.followRedirects: "true", "false", or "initial" to follow the
+	// ones of the initial request only. All of them are followed if empty.
+	FollowRedirects string
 }
 
 // ProxyOptions provides configuration for proxy connections.
@@ -103,8 +110,14 @@ func (o *ProxyOptions) FullURL() (*url.URL, error) {
 
 var fileIssueWindows = regexp.MustCompile(`^/[A-Za-z]:(/|\\)`)
 
+var helperRegExp = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*)::(.+)$`)
+
 // NewEndpoint parses an endpoint string and returns an Endpoint.
 func NewEndpoint(endpoint string) (*Endpoint, error) {
+	if e, ok := parseHelper(endpoint); ok {
+		return e, nil
+	}
+
 	if e, ok := parseSCPLike(endpoint); ok {
 		return e, nil
 	}
@@ -150,6 +163,24 @@ func parseURL(endpoint string) (*Endpoint, error) {
 	}, nil
 }
 
+// parseHelper parses the "<transport>::<address>" endpoints, served by the
+// git-remote-<transport> remote helper. The address is kept in the opaque part
+// of the URL, prefixed by a colon, so that the endpoint is printed back as
+// given.
+func parseHelper(endpoint string) (*Endpoint, bool) {
+	m := helperRegExp.FindStringSubmatch(endpoint)
+	if m == nil {
+		return nil, false
+	}
+
+	return &Endpoint{
+		URL: url.URL{
+			Scheme: m[1],
+			Opaque: ":" + m[2],
+		},
+	}, true
+}
+
 func parseSCPLike(endpoint string) (*Endpoint, bool) {
 	if giturl.MatchesScheme(endpoint) || !giturl.MatchesScpLike(endpoint) {
 		return nil, false
@@ -77,6 +77,14 @@ func TestNewEndpoint(t *testing.T) {
 			input: "git://github.com/user/repository.git?foo#bar",
 			want:  "git://github.com/user/repository.git?foo#bar",
 		},
+		{
+			input: "gcrypt::rsync://example.com/repository",
+			want:  "gcrypt::rsync://example.com/repository",
+		},
+		{
+			input: "ext::ssh -i key example.com %S repository",
+			want:  "ext::ssh -i key example.com %S repository",
+		},
 	}
 
 	for _, tc := range tests {
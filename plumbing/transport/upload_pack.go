@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"time"
 
+	"github.com/go-git/go-git/v6/internal/repository"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/plumbing/format/pktline"
@@ -25,6 +27,11 @@ type UploadPackOptions struct {
 	GitProtocol   string
 	AdvertiseRefs bool
 	StatelessRPC  bool
+	// KeepAlive is the interval after which an empty packet is sent to the
+	// client when nothing was written while the pack is computed, if it
+	// supports the sideband. If zero, DefaultKeepAlive is used, and if
+	// negative no keepalive packet is sent.
+	KeepAlive time.Duration
 }
 
 // UploadPack is a server command that serves the upload-pack service.
@@ -86,13 +93,18 @@ func UploadPack(
 		return nil
 	}
 
+	policy, err := newUploadPolicy(st, opts.StatelessRPC)
+	if err != nil {
+		return fmt.Errorf("reading upload policy: %w", err)
+	}
+
 	var done bool
 	var haves []plumbing.Hash
 	var upreq *packp.UploadRequest
 	var havesWithRef map[plumbing.Hash][]plumbing.Hash
 	var multiAck, multiAckDetailed bool
 	var caps *capability.List
-	var wants []plumbing.Hash
+	var wants, excluded []plumbing.Hash
 	firstRound := true
 	for !done {
 		writec := make(chan error)
@@ -105,6 +117,11 @@ func UploadPack(
 			wants = upreq.Wants
 			caps = upreq.Capabilities
 
+			if err := policy.check(wants); err != nil {
+				rejectUpload(rd, r, w, err)
+				return err
+			}
+
 			if err := r.Close(); err != nil {
 				return fmt.Errorf("closing reader: %w", err)
 			}
@@ -120,7 +137,7 @@ func UploadPack(
 			multiAckDetailed = caps.Supports(capability.MultiACKDetailed)
 
 			go func() {
-				// TODO: support deepen-since, and deepen-not
+				// TODO: support deepen-since
 				var shupd packp.ShallowUpdate
 				if !upreq.Depth.IsZero() {
 					switch depth := upreq.Depth.(type) {
@@ -129,6 +146,18 @@ func UploadPack(
 							writec <- fmt.Errorf("getting shallow commits: %w", err)
 							return
 						}
+					case packp.DepthReference, packp.DepthReferences:
+						refs := []string{depth.String()}
+						if r, ok := depth.(packp.DepthReferences); ok {
+							refs = r
+						}
+
+						var err error
+						excluded, err = getShallowCommitsNot(st, wants, refs, &shupd)
+						if err != nil {
+							writec <- fmt.Errorf("getting shallow commits: %w", err)
+							return
+						}
 					default:
 						writec <- fmt.Errorf("unsupported depth type %T", upreq.Depth)
 						return
@@ -245,30 +274,37 @@ func UploadPack(
 		return fmt.Errorf("closing reader: %w", err)
 	}
 
-	objs, err := objectsToUpload(st, wants, haves)
-	if err != nil {
-		_ = w.Close()
-		return fmt.Errorf("getting objects to upload: %w", err)
-	}
-
 	var (
-		useSideband bool
-		writer      io.Writer = w
+		useSideband  bool
+		sidebandType sideband.Type
+		writer       io.Writer = w
+		stop                   = func() {}
 	)
 	if caps.Supports(capability.Sideband64k) {
-		writer = sideband.NewMuxer(sideband.Sideband64k, w)
-		useSideband = true
+		sidebandType, useSideband = sideband.Sideband64k, true
 	} else if caps.Supports(capability.Sideband) {
-		writer = sideband.NewMuxer(sideband.Sideband, w)
-		useSideband = true
+		sidebandType, useSideband = sideband.Sideband, true
 	}
 
-	// TODO: Support shallow-file
-	// TODO: Support thin-pack
-	e := packfile.NewEncoder(writer, st, false)
-	_, err = e.Encode(objs, 10)
-	if err != nil {
-		return fmt.Errorf("encoding packfile: %w", err)
+	if useSideband {
+		mux := sideband.NewMuxer(sidebandType, w)
+		writer = mux
+
+		// The objects to upload are walked and deltified before the pack is
+		// written, which can take long enough for the client to time out.
+		if keepAlive := opts.KeepAlive; keepAlive >= 0 {
+			if keepAlive == 0 {
+				keepAlive = DefaultKeepAlive
+			}
+
+			k := newKeepAliveWriter(w, mux, keepAlive)
+			writer, stop = k, k.Stop
+		}
+	}
+
+	if err := encodePack(st, writer, stop, wants, append(haves, excluded...)); err != nil {
+		_ = w.Close()
+		return err
 	}
 
 	if useSideband {
@@ -284,10 +320,111 @@ func UploadPack(
 	return nil
 }
 
+// rejectUpload reports err to the client in an error packet. The rest of the
+// request is read first, as the client only reads the response once sent.
+func rejectUpload(rd io.Reader, r io.Closer, w io.WriteCloser, err error) {
+	var uphav packp.UploadHaves
+	_ = uphav.Decode(rd)
+	_ = r.Close()
+
+	errl := pktline.ErrorLine{Text: "upload-pack: " + err.Error()}
+	_ = errl.Encode(w)
+	_ = w.Close()
+}
+
+// encodePack encodes the pack of the objects reachable from wants but not
+// from haves to w, calling stop once done to stop the keepalive packets.
+func encodePack(st storage.Storer, w io.Writer, stop func(), wants, haves []plumbing.Hash) error {
+	defer stop()
+
+	objs, err := objectsToUpload(st, wants, haves)
+	if err != nil {
+		return fmt.Errorf("getting objects to upload: %w", err)
+	}
+
+	// TODO: Support shallow-file
+	// TODO: Support thin-pack
+	e := packfile.NewEncoder(w, st, false)
+	if _, err := e.Encode(objs, 10); err != nil {
+		return fmt.Errorf("encoding packfile: %w", err)
+	}
+
+	return nil
+}
+
 func objectsToUpload(st storage.Storer, wants, haves []plumbing.Hash) ([]plumbing.Hash, error) {
 	return revlist.Objects(st, wants, haves)
 }
 
+// getShallowCommitsNot sets as shallow the commits reachable from heads but
+// not from the references, which have a parent reachable from them, as
+// deepen-not does. It returns the commits the references resolve to.
+func getShallowCommitsNot(st storage.Storer, heads []plumbing.Hash, refs []string, upd *packp.ShallowUpdate) ([]plumbing.Hash, error) {
+	var tips []plumbing.Hash
+	excluded := map[plumbing.Hash]bool{}
+	for _, name := range refs {
+		ref, err := repository.ExpandRef(st, plumbing.ReferenceName(name))
+		if err != nil {
+			return nil, fmt.Errorf("deepen-not %s: %w", name, err)
+		}
+
+		obj, err := object.GetObject(st, ref.Hash())
+		for err == nil {
+			t, ok := obj.(*object.Tag)
+			if !ok {
+				break
+			}
+
+			obj, err = t.Object()
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		commit, ok := obj.(*object.Commit)
+		if !ok {
+			return nil, fmt.Errorf("deepen-not %s: %w", name, object.ErrUnsupportedObject)
+		}
+
+		tips = append(tips, commit.Hash)
+		if err := object.NewCommitPreorderIter(commit, excluded, nil).ForEach(func(c *object.Commit) error {
+			excluded[c.Hash] = true
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := map[plumbing.Hash]bool{}
+	for h := range excluded {
+		seen[h] = true
+	}
+
+	for _, h := range heads {
+		commit, err := object.GetCommit(st, h)
+		if err != nil {
+			continue
+		}
+
+		if err := object.NewCommitPreorderIter(commit, seen, nil).ForEach(func(c *object.Commit) error {
+			seen[c.Hash] = true
+			for _, p := range c.ParentHashes {
+				if excluded[p] {
+					upd.Shallows = append(upd.Shallows, c.Hash)
+					return nil
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return tips, nil
+}
+
 func getShallowCommits(st storage.Storer, heads []plumbing.Hash, depth int, upd *packp.ShallowUpdate) error {
 	var i, curDepth int
 	var commit *object.Commit
@@ -2,6 +2,7 @@ package transport
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"testing"
 
@@ -9,11 +10,13 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/cache"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp/capability"
 	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/utils/ioutil"
 )
 
 type UploadPackSuite struct {
@@ -67,3 +70,94 @@ func (s *UploadPackSuite) TestUploadPackAlwaysUseSidebandWhenAvailable() {
 	expected := "0008NAK\n0009\x01PACK" // NAK response + sideband pack header + PACK marker
 	s.Equal(expected, buf.String()[:len(expected)], "pack file should be sent via sideband")
 }
+
+func (s *UploadPackSuite) TestUploadPackWantPolicy() {
+	commit := plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")
+	blob := plumbing.NewHash("32858aad3c383ed1ff0a0f9bdf231d54a00c9e88")
+	tip := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+
+	tests := []struct {
+		name      string
+		want      plumbing.Hash
+		config    func(cfg *config.Config)
+		stateless bool
+		allowed   bool
+	}{
+		{name: "tip", want: tip, allowed: true},
+		{name: "reachable commit", want: commit},
+		{name: "reachable commit, stateless", want: commit, stateless: true, allowed: true},
+		{name: "reachable commit, allowTipSHA1InWant", want: commit, config: func(cfg *config.Config) {
+			cfg.UploadPack.AllowTipSHA1InWant = config.NewOptBool(true)
+		}},
+		{name: "reachable commit, allowReachableSHA1InWant", want: commit, allowed: true, config: func(cfg *config.Config) {
+			cfg.UploadPack.AllowReachableSHA1InWant = config.NewOptBool(true)
+		}},
+		{name: "blob, allowReachableSHA1InWant", want: blob, config: func(cfg *config.Config) {
+			cfg.UploadPack.AllowReachableSHA1InWant = config.NewOptBool(true)
+		}},
+		{name: "blob, allowAnySHA1InWant", want: blob, allowed: true, config: func(cfg *config.Config) {
+			cfg.UploadPack.AllowAnySHA1InWant = config.NewOptBool(true)
+		}},
+	}
+
+	for _, tc := range tests {
+		s.Run(tc.name, func() {
+			dot := fixtures.Basic().One().DotGit(fixtures.WithTargetDir(s.T().TempDir))
+			st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+			if tc.config != nil {
+				cfg, err := st.Config()
+				s.Require().NoError(err)
+				tc.config(cfg)
+				s.Require().NoError(st.SetConfig(cfg))
+			}
+
+			upreq := packp.NewUploadRequest()
+			upreq.Wants = append(upreq.Wants, tc.want)
+			uphav := packp.UploadHaves{Done: true}
+
+			var reqW bytes.Buffer
+			s.Require().NoError(upreq.Encode(&reqW))
+			s.Require().NoError(uphav.Encode(&reqW))
+
+			var out bytes.Buffer
+			err := UploadPack(context.TODO(), st, io.NopCloser(&reqW), ioutil.WriteNopCloser(&out), &UploadPackOptions{
+				StatelessRPC: tc.stateless,
+			})
+			if tc.allowed {
+				s.NoError(err)
+				s.Contains(out.String(), "PACK")
+				return
+			}
+
+			s.ErrorIs(err, ErrNotOurRef)
+			s.Contains(out.String(), "ERR upload-pack: not our ref "+tc.want.String())
+			s.NotContains(out.String(), "PACK")
+		})
+	}
+}
+
+func (s *UploadPackSuite) TestUploadPackAdvertiseWantPolicy() {
+	dot := fixtures.Basic().One().DotGit(fixtures.WithTargetDir(s.T().TempDir))
+	st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+
+	advertise := func() *packp.AdvRefs {
+		var buf bytes.Buffer
+		s.Require().NoError(AdvertiseReferences(context.TODO(), st, &buf, UploadPackService, false))
+		ar := packp.NewAdvRefs()
+		s.Require().NoError(ar.Decode(&buf))
+		return ar
+	}
+
+	ar := advertise()
+	s.False(ar.Capabilities.Supports(capability.AllowTipSHA1InWant))
+	s.False(ar.Capabilities.Supports(capability.AllowReachableSHA1InWant))
+
+	cfg, err := st.Config()
+	s.Require().NoError(err)
+	cfg.UploadPack.AllowAnySHA1InWant = config.NewOptBool(true)
+	s.Require().NoError(st.SetConfig(cfg))
+
+	ar = advertise()
+	s.True(ar.Capabilities.Supports(capability.AllowTipSHA1InWant))
+	s.True(ar.Capabilities.Supports(capability.AllowReachableSHA1InWant))
+}
@@ -0,0 +1,157 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// ErrNotOurRef is returned by UploadPack, and reported to the client, when it
+// wants an object the uploadpack.* config of the repository does not allow
+// it to fetch.
+var ErrNotOurRef = errors.New("not our ref")
+
+// uploadPolicy enforces the uploadpack.allowTipSHA1InWant,
+// uploadpack.allowReachableSHA1InWant and uploadpack.allowAnySHA1InWant
+// config, as git-upload-pack does. The objects wanted must otherwise be
+// advertised, the tips of the references or the peeled values of the tags.
+//
+// So, as with git, the objects omitted by a partial clone can only be fetched
+// on demand, as the BlobFetcher of go-git does, when
+// uploadpack.allowAnySHA1InWant is set: the blobs and trees are not the tips
+// of references, and only the commits are walked for
+// uploadpack.allowReachableSHA1InWant.
+type uploadPolicy struct {
+	st storage.Storer
+	// allowReachable allows the commits reachable from the tips.
+	allowReachable bool
+	// allowAny allows any object.
+	allowAny bool
+}
+
+func newUploadPolicy(st storage.Storer, statelessRPC bool) (*uploadPolicy, error) {
+	cfg, err := st.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	// As no reference is hidden, allowTipSHA1InWant allows no more than the
+	// advertised objects. The references can be updated between the
+	// advertisement and the request of a stateless client, which is then
+	// allowed the reachable commits, as git does.
+	return &uploadPolicy{
+		st:             st,
+		allowReachable: statelessRPC || cfg.UploadPack.AllowReachableSHA1InWant.IsTrue(),
+		allowAny:       cfg.UploadPack.AllowAnySHA1InWant.IsTrue(),
+	}, nil
+}
+
+// check returns the error rejecting the first object of wants not allowed,
+// if any.
+func (p *uploadPolicy) check(wants []plumbing.Hash) error {
+	if p.allowAny || len(wants) == 0 {
+		return nil
+	}
+
+	tips, err := p.tips(wants)
+	if err != nil {
+		return err
+	}
+
+	pending := map[plumbing.Hash]bool{}
+	for _, h := range wants {
+		if !tips[h] {
+			pending[h] = true
+		}
+	}
+
+	if len(pending) > 0 && p.allowReachable {
+		if err := p.walkReachable(tips, pending); err != nil {
+			return err
+		}
+	}
+
+	for _, h := range wants {
+		if pending[h] {
+			return fmt.Errorf("%w %s", ErrNotOurRef, h)
+		}
+	}
+
+	return nil
+}
+
+// tips returns the objects the references point to and the peeled values of
+// the tags, as advertised. The tags are only peeled when some of the wants
+// are not the tip of a reference.
+func (p *uploadPolicy) tips(wants []plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	iter, err := p.st.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	tips := map[plumbing.Hash]bool{}
+	var tags []plumbing.Hash
+	err = iter.ForEach(func(r *plumbing.Reference) error {
+		if r.Type() == plumbing.SymbolicReference {
+			return nil
+		}
+
+		tips[r.Hash()] = true
+		if r.Name().IsTag() {
+			tags = append(tags, r.Hash())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.ContainsFunc(wants, func(h plumbing.Hash) bool { return !tips[h] }) {
+		return tips, nil
+	}
+
+	for _, h := range tags {
+		if peeled, err := object.Peel(p.st, h); err == nil {
+			tips[peeled] = true
+		}
+	}
+
+	return tips, nil
+}
+
+// walkReachable removes from pending the commits reachable from the tips,
+// stopping once none is left.
+func (p *uploadPolicy) walkReachable(tips, pending map[plumbing.Hash]bool) error {
+	seen := map[plumbing.Hash]bool{}
+	for h := range tips {
+		commit, err := object.GetCommit(p.st, h)
+		if err != nil {
+			continue
+		}
+
+		err = object.NewCommitPreorderIter(commit, seen, nil).ForEach(func(c *object.Commit) error {
+			seen[c.Hash] = true
+			delete(pending, c.Hash)
+			if len(pending) == 0 {
+				return storer.ErrStop
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -42,6 +42,11 @@ func (r *Repository) Prune(opt PruneOptions) error {
 		return ErrLooseObjectsNotSupported
 	}
 
+	objectTime := los.LooseObjectTime
+	if ots, ok := r.Storer.(storer.ObjectTimeStorer); ok {
+		objectTime = ots.ObjectTime
+	}
+
 	pw := newObjectWalker(r.Storer)
 	err := pw.walkAllRefs()
 	if err != nil {
@@ -58,7 +63,7 @@ func (r *Repository) Prune(opt PruneOptions) error {
 		if !opt.OnlyObjectsOlderThan.IsZero() {
 			// Errors here are non-fatal. The object may be e.g. packed.
 			// Or concurrently deleted. Skip such objects.
-			t, err := los.LooseObjectTime(hash)
+			t, err := objectTime(hash)
 			if err != nil {
 				return nil
 			}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/go-git/go-git/v6/internal/url"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/cache"
+	formatcfg "github.com/go-git/go-git/v6/plumbing/format/config"
 	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
@@ -28,6 +30,7 @@ import (
 	"github.com/go-git/go-git/v6/storage/memory"
 	"github.com/go-git/go-git/v6/utils/ioutil"
 	"github.com/go-git/go-git/v6/utils/trace"
+	"github.com/go-git/go-git/v6/x/plugin"
 )
 
 // Remote operation errors and sentinel values.
@@ -111,7 +114,7 @@ func (r *Remote) PushContext(ctx context.Context, o *PushOptions) (err error) {
 		o.RemoteURL = r.c.URLs[len(r.c.URLs)-1]
 	}
 
-	c, ep, err := newClient(o.RemoteURL, o.InsecureSkipTLS, o.CABundle, o.ProxyOptions)
+	c, ep, err := newClient(r.s, o.RemoteURL, o.InsecureSkipTLS, o.CABundle, o.ProxyOptions)
 	if err != nil {
 		return err
 	}
@@ -188,6 +191,18 @@ func (r *Remote) sendPack(ctx context.Context, conn transport.Connection, remote
 		return NoErrAlreadyUpToDate
 	}
 
+	if o.HooksEnabled {
+		hooks, err := newHookRunner(r.s, nil)
+		if err != nil {
+			return err
+		}
+
+		stdin := prePushInput(cmds, o.RefSpecs, localRefs)
+		if err := hooks.run(ctx, hookPrePush, stdin, o.RemoteName, o.RemoteURL); err != nil {
+			return err
+		}
+	}
+
 	objects := objectsToPush(cmds)
 	haves, err := referencesToHashes(remoteRefs)
 	if err != nil {
@@ -381,7 +396,8 @@ func (r *Remote) fetch(ctx context.Context, o *FetchOptions) (sto storer.Referen
 		return nil, err
 	}
 
-	if len(o.RefSpecs) == 0 {
+	explicitRefSpecs := len(o.RefSpecs) > 0 || len(o.Hashes) > 0
+	if !explicitRefSpecs {
 		o.RefSpecs = r.c.Fetch
 	}
 
@@ -389,7 +405,7 @@ func (r *Remote) fetch(ctx context.Context, o *FetchOptions) (sto storer.Referen
 		o.RemoteURL = r.c.URLs[0]
 	}
 
-	c, ep, err := newClient(o.RemoteURL, o.InsecureSkipTLS, o.CABundle, o.ProxyOptions)
+	c, ep, err := newClient(r.s, o.RemoteURL, o.InsecureSkipTLS, o.CABundle, o.ProxyOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -413,6 +429,10 @@ func (r *Remote) fetch(ctx context.Context, o *FetchOptions) (sto storer.Referen
 		return nil, err
 	}
 
+	if err := r.isSupportedHashes(o.Hashes, rRefs, conn.Capabilities()); err != nil {
+		return nil, err
+	}
+
 	remoteRefs := referenceStorageFromRefs(rRefs, true)
 	localRefs, err := reference.References(r.s)
 	if err != nil {
@@ -424,27 +444,52 @@ func (r *Remote) fetch(ctx context.Context, o *FetchOptions) (sto storer.Referen
 	}
 
 	var shallows []plumbing.Hash
-	if o.Depth != 0 {
+	if o.Depth != 0 || len(o.ShallowExclude) > 0 {
 		shallows, err = r.s.Shallow()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	isWildcard := true
-	for _, s := range o.RefSpecs {
-		if !s.IsWildcard() {
-			isWildcard = false
-			break
-		}
-	}
-
 	var haves []plumbing.Hash
 	wants, _ := getWants(r.s, refs, o.Depth)
+	wants, err = addHashWants(r.s, wants, o.Hashes, o.Depth)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(wants) > 0 {
-		haves, err = getHaves(localRefs, remoteRefs, r.s, o.Depth)
-		if err != nil {
-			return nil, err
+		var negotiation *Negotiation
+		if o.NegotiationCallback != nil {
+			negotiation, err = o.NegotiationCallback(rRefs)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if negotiation == nil {
+			negotiation = &Negotiation{}
+		}
+
+		var negotiator transport.Negotiator
+		haves = slices.Clone(negotiation.Haves)
+		if haves == nil {
+			algorithm, err := r.negotiationAlgorithm(o)
+			if err != nil {
+				return nil, err
+			}
+
+			switch algorithm {
+			case NoopNegotiationAlgorithm:
+			case SkippingNegotiationAlgorithm:
+				negotiator, err = transport.NewSkippingNegotiator(r.s, localRefsHashes(localRefs))
+			default:
+				haves, err = getHaves(localRefs, remoteRefs, r.s, o.Depth)
+			}
+
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		// When performing a shallow fetch, exclude any shallow-boundary commits
@@ -470,12 +515,19 @@ func (r *Remote) fetch(ctx context.Context, o *FetchOptions) (sto storer.Referen
 		}
 
 		req := &transport.FetchRequest{
-			Wants:       wants,
-			Haves:       haves,
-			Depth:       o.Depth,
-			Progress:    o.Progress,
-			IncludeTags: isWildcard && o.Tags == plumbing.TagFollowing,
-			Filter:      o.Filter,
+			Wants:          wants,
+			Haves:          haves,
+			Negotiator:     negotiator,
+			MaxRounds:      negotiation.MaxRounds,
+			Depth:          o.Depth,
+			DeepenNot:      o.ShallowExclude,
+			Progress:       o.Progress,
+			IncludeTags:    o.Tags == plumbing.TagFollowing,
+			Filter:         o.Filter,
+			Promisor:       o.Filter != "" || r.c.Promisor,
+			MaxBytesPerSec: o.MaxBytesPerSec,
+			MaxBlobSize:    o.MaxBlobSize,
+			MaxTreeEntries: o.MaxTreeEntries,
 		}
 
 		if err := conn.Fetch(ctx, req); err != nil && !errors.Is(err, transport.ErrNoChange) {
@@ -489,12 +541,26 @@ func (r *Remote) fetch(ctx context.Context, o *FetchOptions) (sto storer.Referen
 		return nil, fmt.Errorf("error closing connection: %w", err)
 	}
 
+	if o.Tags == plumbing.TagFollowing {
+		if err := r.backfillTags(ctx, sess, o, rRefs); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Filter != "" {
+		if err := r.setPromisor(o.Filter); err != nil {
+			return nil, err
+		}
+	}
+
 	var updatedPrune bool
 	if o.Prune {
-		updatedPrune, err = r.pruneRemotes(o.RefSpecs, localRefs, remoteRefs)
+		pruned, err := r.prune(o.RefSpecs, o.PruneTags, localRefs, remoteRefs, false)
 		if err != nil {
 			return nil, err
 		}
+
+		updatedPrune = len(pruned) > 0
 	}
 
 	updated, err := r.updateLocalReferenceStorage(o.RefSpecs, refs, remoteRefs, specToRefs, o.Tags, o.Force)
@@ -502,6 +568,21 @@ func (r *Remote) fetch(ctx context.Context, o *FetchOptions) (sto storer.Referen
 		return nil, err
 	}
 
+	if o.UpdateRemoteHead {
+		headUpdated, err := r.updateRemoteHead(o.RemoteName, o.RefSpecs, remoteRefs)
+		if err != nil {
+			return nil, err
+		}
+
+		updated = updated || headUpdated
+	}
+
+	if o.WriteFetchHead {
+		if err := r.writeFetchHead(o.RemoteURL, explicitRefSpecs, o.RefSpecs, specToRefs); err != nil {
+			return nil, err
+		}
+	}
+
 	if !updated {
 		updated, err = depthChanged(shallows, r.s)
 		if err != nil {
@@ -527,6 +608,37 @@ func (r *Remote) fetch(ctx context.Context, o *FetchOptions) (sto storer.Referen
 	return remoteRefs, nil
 }
 
+// updateRemoteHead points the remote HEAD of the remote with the given name
+// to the remote-tracking branch, as mapped by specs, of the branch the
+// advertised HEAD points to. Nothing is done unless HEAD is advertised as a
+// symbolic reference and the remote-tracking branch exists.
+func (r *Remote) updateRemoteHead(name string, specs []config.RefSpec, remoteRefs memory.ReferenceStorage) (bool, error) {
+	head, err := remoteRefs.Reference(plumbing.HEAD)
+	if err != nil || head.Type() != plumbing.SymbolicReference {
+		return false, nil
+	}
+
+	for _, spec := range specs {
+		if spec.IsDelete() || !spec.Match(head.Target()) {
+			continue
+		}
+
+		target := spec.Dst(head.Target())
+		if _, err := r.s.Reference(target); err != nil {
+			if errors.Is(err, plumbing.ErrReferenceNotFound) {
+				continue
+			}
+
+			return false, err
+		}
+
+		ref := plumbing.NewSymbolicReference(plumbing.NewRemoteHEADReferenceName(name), target)
+		return updateReferenceStorerIfNeeded(r.s, ref)
+	}
+
+	return false, nil
+}
+
 func referenceStorageFromRefs(refs []*plumbing.Reference, filterPeeled bool) memory.ReferenceStorage {
 	refStore := memory.ReferenceStorage{}
 	for _, ref := range refs {
@@ -561,7 +673,16 @@ func depthChanged(before []plumbing.Hash, s storage.Storer) (bool, error) {
 	return false, nil
 }
 
-func newClient(url string, insecure bool, cabundle []byte, proxyOpts transport.ProxyOptions) (transport.Transport, *transport.Endpoint, error) {
+// newClient returns the transport and the endpoint of the url, with the
+// settings of the http.<url> sections of the config of s applied to the HTTP
+// ones.
+func newClient(
+	s config.ConfigStorer,
+	url string,
+	insecure bool,
+	cabundle []byte,
+	proxyOpts transport.ProxyOptions,
+) (transport.Transport, *transport.Endpoint, error) {
 	ep, err := transport.NewEndpoint(url)
 	if err != nil {
 		return nil, nil, err
@@ -570,6 +691,12 @@ func newClient(url string, insecure bool, cabundle []byte, proxyOpts transport.P
 	ep.CaBundle = cabundle
 	ep.Proxy = proxyOpts
 
+	if ep.Scheme == "http" || ep.Scheme == "https" {
+		if err := applyHTTPConfig(s, ep); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	c, err := transport.Get(ep.Scheme)
 	if err != nil {
 		return nil, nil, err
@@ -578,25 +705,115 @@ func newClient(url string, insecure bool, cabundle []byte, proxyOpts transport.P
 	return c, ep, err
 }
 
-func (r *Remote) pruneRemotes(specs []config.RefSpec, localRefs []*plumbing.Reference, remoteRefs storer.ReferenceStorer) (bool, error) {
-	var updatedPrune bool
-	for _, spec := range specs {
-		rev := spec.Reverse()
-		for _, ref := range localRefs {
+// applyHTTPConfig applies to the endpoint ep the settings of the http.<url>
+// sections matching it. The options given explicitly take precedence.
+func applyHTTPConfig(s config.ConfigStorer, ep *transport.Endpoint) error {
+	scope := config.SystemScope
+	if !plugin.Has(plugin.ConfigLoader()) {
+		scope = config.LocalScope
+	}
+
+	cfg, err := configScoped(s, scope)
+	if err != nil {
+		return err
+	}
+
+	h := cfg.HTTPFor(ep.String())
+	if h.SSLVerify.IsSet() && !h.SSLVerify.IsTrue() {
+		ep.InsecureSkipTLS = true
+	}
+
+	if ep.Proxy.URL == "" && h.Proxy != "" {
+		ep.Proxy.URL = h.Proxy
+		if !strings.Contains(h.Proxy, "://") {
+			ep.Proxy.URL = "http://" + h.Proxy
+		}
+	}
+
+	ep.ExtraHeaders = h.ExtraHeaders
+	ep.FollowRedirects = h.FollowRedirects
+	return nil
+}
+
+// prune removes the local references managed by specs, along with the tags
+// when pruneTags is set, which no longer exist in remoteRefs, and returns
+// their names. When dryRun is set, the references are only reported. It is
+// run by fetch when FetchOptions.Prune is set, and by PruneContext.
+func (r *Remote) prune(
+	specs []config.RefSpec,
+	pruneTags bool,
+	localRefs []*plumbing.Reference,
+	remoteRefs storer.ReferenceStorer,
+	dryRun bool,
+) ([]plumbing.ReferenceName, error) {
+	if pruneTags {
+		specs = append(slices.Clip(specs), refspecAllTags)
+	}
+
+	stale, err := staleReferences(specs, localRefs, remoteRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return stale, nil
+	}
+
+	for _, name := range stale {
+		if err := r.s.RemoveReference(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return stale, nil
+}
+
+// staleReferences returns, sorted by name, the local references that are
+// managed by any of the given fetch RefSpecs but whose source no longer
+// exists in remoteRefs. A local reference that several RefSpecs map to is
+// only stale when none of its sources exist remotely. Symbolic references,
+// such as refs/remotes/origin/HEAD, are never considered stale.
+func staleReferences(
+	specs []config.RefSpec,
+	localRefs []*plumbing.Reference,
+	remoteRefs storer.ReferenceStorer,
+) ([]plumbing.ReferenceName, error) {
+	var stale []plumbing.ReferenceName
+	for _, ref := range localRefs {
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+
+		managed, exists := false, false
+		for _, spec := range specs {
+			if spec.IsDelete() || spec.IsExactSHA1() {
+				continue
+			}
+
+			rev := spec.Reverse()
 			if !rev.Match(ref.Name()) {
 				continue
 			}
+
+			managed = true
 			_, err := remoteRefs.Reference(rev.Dst(ref.Name()))
-			if errors.Is(err, plumbing.ErrReferenceNotFound) {
-				updatedPrune = true
-				err := r.s.RemoveReference(ref.Name())
-				if err != nil {
-					return false, err
-				}
+			if err == nil {
+				exists = true
+				break
 			}
+
+			if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+				return nil, err
+			}
+		}
+
+		if managed && !exists {
+			stale = append(stale, ref.Name())
 		}
 	}
-	return updatedPrune, nil
+
+	slices.Sort(stale)
+	return stale, nil
 }
 
 func (r *Remote) addReferencesToUpdate(
@@ -700,7 +917,7 @@ func (r *Remote) deleteReferences(rs config.RefSpec,
 		cmd := &packp.Command{
 			Name: ref.Name(),
 			Old:  ref.Hash(),
-			New:  plumbing.ZeroHash,
+			New:  plumbing.ZeroHashFor(ref.Hash().Format()),
 		}
 		*cmds = append(*cmds, cmd)
 		return nil
@@ -717,7 +934,7 @@ func (r *Remote) addObject(rs config.RefSpec,
 
 	cmd := &packp.Command{
 		Name: rs.Dst(""),
-		Old:  plumbing.ZeroHash,
+		Old:  plumbing.ZeroHashFor(localObject.Format()),
 		New:  localObject,
 	}
 	remoteRef, err := remoteRefs.Reference(cmd.Name)
@@ -758,7 +975,7 @@ func (r *Remote) addReferenceIfRefSpecMatches(rs config.RefSpec,
 
 	cmd := &packp.Command{
 		Name: rs.Dst(localRef.Name()),
-		Old:  plumbing.ZeroHash,
+		Old:  plumbing.ZeroHashFor(localRef.Hash().Format()),
 		New:  localRef.Hash(),
 	}
 
@@ -838,6 +1055,33 @@ func getRemoteRefsFromStorer(remoteRefStorer storer.ReferenceStorer) (
 	return remoteRefs, nil
 }
 
+// negotiationAlgorithm returns the negotiation algorithm of the fetch, from
+// the options or else the config of the repository.
+func (r *Remote) negotiationAlgorithm(o *FetchOptions) (NegotiationAlgorithm, error) {
+	if o.NegotiationAlgorithm != "" {
+		return parseNegotiationAlgorithm(string(o.NegotiationAlgorithm))
+	}
+
+	cfg, err := r.s.Config()
+	if err != nil {
+		return "", err
+	}
+
+	return parseNegotiationAlgorithm(cfg.Fetch.NegotiationAlgorithm)
+}
+
+// localRefsHashes returns the hashes the local references point to.
+func localRefsHashes(localRefs []*plumbing.Reference) []plumbing.Hash {
+	var hashes []plumbing.Hash
+	for _, ref := range localRefs {
+		if ref.Type() == plumbing.HashReference {
+			hashes = append(hashes, ref.Hash())
+		}
+	}
+
+	return hashes
+}
+
 // getHavesFromRef populates the given `haves` map with the given
 // reference, and up to `maxHavesToVisitPerRef` ancestor commits.
 func getHavesFromRef(
@@ -1055,7 +1299,7 @@ func objectExists(s storer.EncodedObjectStorer, h plumbing.Hash) (bool, error) {
 }
 
 func checkFastForwardUpdate(s storer.EncodedObjectStorer, remoteRefs storer.ReferenceStorer, cmd *packp.Command) error {
-	if cmd.Old == plumbing.ZeroHash {
+	if cmd.Old.IsZero() {
 		_, err := remoteRefs.Reference(cmd.Name)
 		if errors.Is(err, plumbing.ErrReferenceNotFound) {
 			return nil
@@ -1151,6 +1395,33 @@ func isFastForward(s storer.EncodedObjectStorer, old, newHash plumbing.Hash, sha
 	return found, nil
 }
 
+// setPromisor records the remote as the promisor remote of a partial clone
+// fetched with filter, as git does, unless the repository already has one.
+func (r *Remote) setPromisor(filter packp.Filter) error {
+	cfg, err := r.s.Config()
+	if err != nil {
+		return err
+	}
+
+	c, ok := cfg.Remotes[r.c.Name]
+	if !ok {
+		return nil
+	}
+
+	if cfg.Extensions.PartialClone != "" && cfg.Extensions.PartialClone != c.Name {
+		return nil
+	}
+
+	c.Promisor = true
+	c.PartialCloneFilter = string(filter)
+	cfg.Extensions.PartialClone = c.Name
+	cfg.Core.RepositoryFormatVersion = formatcfg.Version1
+	r.c.Promisor = true
+	r.c.PartialCloneFilter = c.PartialCloneFilter
+
+	return r.s.SetConfig(cfg)
+}
+
 func (r *Remote) isSupportedRefSpec(refs []config.RefSpec, caps *capability.List) error {
 	var containsIsExact bool
 	for _, ref := range refs {
@@ -1171,6 +1442,54 @@ func (r *Remote) isSupportedRefSpec(refs []config.RefSpec, caps *capability.List
 	return ErrExactSHA1NotSupported
 }
 
+// isSupportedHashes returns ErrExactSHA1NotSupported if any of the hashes
+// wanted is not a reference tip advertised by the remote, while the remote
+// does not allow such hashes to be wanted.
+func (r *Remote) isSupportedHashes(hashes []plumbing.Hash, remoteRefs []*plumbing.Reference, caps *capability.List) error {
+	if len(hashes) == 0 ||
+		caps.Supports(capability.AllowReachableSHA1InWant) ||
+		caps.Supports(capability.AllowTipSHA1InWant) {
+		return nil
+	}
+
+	tips := make(map[plumbing.Hash]bool, len(remoteRefs))
+	for _, ref := range remoteRefs {
+		tips[ref.Hash()] = true
+	}
+
+	for _, h := range hashes {
+		if !tips[h] {
+			return ErrExactSHA1NotSupported
+		}
+	}
+
+	return nil
+}
+
+// addHashWants adds to wants the hashes which are missing locally, or all of
+// them when deepening a shallow repository, as getWants does.
+func addHashWants(s storage.Storer, wants, hashes []plumbing.Hash, depth int) ([]plumbing.Hash, error) {
+	shallow := false
+	if depth != 1 {
+		if s, _ := s.Shallow(); len(s) > 0 {
+			shallow = true
+		}
+	}
+
+	for _, h := range hashes {
+		exists, err := objectExists(s, h)
+		if err != nil {
+			return nil, err
+		}
+
+		if (!exists || shallow) && !slices.Contains(wants, h) {
+			wants = append(wants, h)
+		}
+	}
+
+	return wants, nil
+}
+
 func (r *Remote) updateLocalReferenceStorage(
 	specs []config.RefSpec,
 	fetchedRefs, remoteRefs memory.ReferenceStorage,
@@ -1239,8 +1558,10 @@ func (r *Remote) updateLocalReferenceStorage(
 		return updated, nil
 	}
 
+	// Following tags, every tag pointing to an object of the repository is
+	// stored, as git does.
 	tags := fetchedRefs
-	if isWildcard {
+	if isWildcard || tagMode == plumbing.TagFollowing {
 		tags = remoteRefs
 	}
 	tagUpdated, err := r.buildFetchedTags(tags)
@@ -1259,6 +1580,71 @@ func (r *Remote) updateLocalReferenceStorage(
 	return updated, err
 }
 
+// backfillTags fetches the annotated tags advertised in refs which are missing
+// from the repository but point to one of its objects, as git does when
+// following tags, since the server may not have sent them along with the
+// objects they point to.
+func (r *Remote) backfillTags(ctx context.Context, sess transport.Session, o *FetchOptions, refs []*plumbing.Reference) error {
+	advertised := make(map[string]plumbing.Hash, len(refs))
+	for _, ref := range refs {
+		advertised[ref.Name().String()] = ref.Hash()
+	}
+
+	var wants, haves []plumbing.Hash
+	for _, ref := range refs {
+		name, peeled := strings.CutSuffix(ref.Name().String(), peeledSuffix)
+		if !peeled || !plumbing.ReferenceName(name).IsTag() {
+			continue
+		}
+
+		tag, ok := advertised[name]
+		if !ok || slices.Contains(wants, tag) {
+			continue
+		}
+
+		if exists, _ := objectExists(r.s, tag); exists {
+			continue
+		}
+
+		if exists, _ := objectExists(r.s, ref.Hash()); !exists {
+			continue
+		}
+
+		wants = append(wants, tag)
+		haves = append(haves, ref.Hash())
+	}
+
+	if len(wants) == 0 {
+		return nil
+	}
+
+	conn, err := sess.Handshake(ctx, transport.UploadPackService)
+	if err != nil {
+		return err
+	}
+
+	err = conn.Fetch(ctx, &transport.FetchRequest{
+		Wants:          wants,
+		Haves:          haves,
+		Progress:       o.Progress,
+		Filter:         o.Filter,
+		Promisor:       o.Filter != "" || r.c.Promisor,
+		MaxBytesPerSec: o.MaxBytesPerSec,
+		MaxBlobSize:    o.MaxBlobSize,
+		MaxTreeEntries: o.MaxTreeEntries,
+	})
+	if err != nil && !errors.Is(err, transport.ErrNoChange) {
+		_ = conn.Close()
+		return err
+	}
+
+	if err := conn.Close(); err != nil {
+		return fmt.Errorf("error closing connection: %w", err)
+	}
+
+	return nil
+}
+
 func (r *Remote) buildFetchedTags(refs memory.ReferenceStorage) (updated bool, err error) {
 	for _, ref := range refs {
 		if !ref.Name().IsTag() {
@@ -1287,6 +1673,50 @@ func (r *Remote) buildFetchedTags(refs memory.ReferenceStorage) (updated bool, e
 	return updated, err
 }
 
+// PruneContext removes the local references managed by the RefSpecs of the
+// remote that no longer exist on the remote repository, and returns the names
+// of the pruned references. The references are pruned as by a fetch with
+// FetchOptions.Prune set, without fetching any object.
+//
+// The provided Context must be non-nil. If the context expires before the
+// operation is complete, an error is returned. The context only affects the
+// transport operations.
+func (r *Remote) PruneContext(ctx context.Context, o *PruneRefsOptions) ([]plumbing.ReferenceName, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	specs := o.RefSpecs
+	if len(specs) == 0 {
+		specs = r.c.Fetch
+	}
+
+	rRefs, err := r.list(ctx, &ListOptions{
+		Auth:            o.Auth,
+		InsecureSkipTLS: o.InsecureSkipTLS,
+		CABundle:        o.CABundle,
+		ProxyOptions:    o.ProxyOptions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	localRefs, err := reference.References(r.s)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteRefs := referenceStorageFromRefs(rRefs, true)
+	return r.prune(specs, o.PruneTags, localRefs, remoteRefs, o.DryRun)
+}
+
+// Prune removes the local references managed by the RefSpecs of the remote
+// that no longer exist on the remote repository, and returns the names of the
+// pruned references.
+func (r *Remote) Prune(o *PruneRefsOptions) ([]plumbing.ReferenceName, error) {
+	return r.PruneContext(context.Background(), o)
+}
+
 // ListContext lists the references on the remote repository.
 // The provided Context must be non-nil. If the context expires before the
 // operation is complete, an error is returned. The context only affects to the
@@ -1315,7 +1745,7 @@ func (r *Remote) list(ctx context.Context, o *ListOptions) (rfs []*plumbing.Refe
 		return nil, ErrEmptyUrls
 	}
 
-	c, ep, err := newClient(r.c.URLs[0], o.InsecureSkipTLS, o.CABundle, o.ProxyOptions)
+	c, ep, err := newClient(r.s, r.c.URLs[0], o.InsecureSkipTLS, o.CABundle, o.ProxyOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -1339,6 +1769,10 @@ func (r *Remote) list(ctx context.Context, o *ListOptions) (rfs []*plumbing.Refe
 
 	var resultRefs []*plumbing.Reference
 	for _, ref := range allRefs {
+		if !hasRefPrefix(ref.Name(), o.RefPrefixFilter) {
+			continue
+		}
+
 		isPeeled := strings.HasSuffix(ref.Name().String(), peeledSuffix)
 		switch o.PeelingOption {
 		case IgnorePeeled:
@@ -1357,10 +1791,22 @@ func (r *Remote) list(ctx context.Context, o *ListOptions) (rfs []*plumbing.Refe
 	return resultRefs, nil
 }
 
+// hasRefPrefix returns whether the name of the reference starts with any of
+// the prefixes, or there are no prefixes.
+func hasRefPrefix(name plumbing.ReferenceName, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(prefixes, func(prefix string) bool {
+		return strings.HasPrefix(name.String(), prefix)
+	})
+}
+
 func objectsToPush(commands []*packp.Command) []plumbing.Hash {
 	objects := make([]plumbing.Hash, 0, len(commands))
 	for _, cmd := range commands {
-		if cmd.New == plumbing.ZeroHash {
+		if cmd.New.IsZero() {
 			continue
 		}
 		objects = append(objects, cmd.New)
@@ -1412,18 +1858,19 @@ func pushHashes(
 	// to the channel.
 	done := make(chan error, 1)
 	req := &transport.PushRequest{
-		Commands: cmds,
-		Progress: o.Progress,
-		Options:  o.Options,
-		Atomic:   o.Atomic,
-		Quiet:    o.Quiet,
+		Commands:       cmds,
+		Progress:       o.Progress,
+		Options:        o.Options,
+		Atomic:         o.Atomic,
+		Quiet:          o.Quiet,
+		MaxBytesPerSec: o.MaxBytesPerSec,
 	}
 
 	if !allDelete {
 		req.Packfile = rd
 		go func() {
 			e := packfile.NewEncoder(wr, s, useRefDeltas)
-			if _, err := e.Encode(hs, config.Pack.Window); err != nil {
+			if _, err := e.EncodeWithOptions(hs, packfile.NewEncodeOptions(config)); err != nil {
 				done <- wr.CloseWithError(err)
 				return
 			}
@@ -6,12 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	iofs "io/fs"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/emirpasic/gods/trees/binaryheap"
 	"github.com/go-git/go-billy/v6"
 	"github.com/go-git/go-billy/v6/osfs"
 
@@ -24,6 +29,7 @@ import (
 	formatcfg "github.com/go-git/go-git/v6/plumbing/format/config"
 	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/revlist"
 	"github.com/go-git/go-git/v6/plumbing/storer"
 	"github.com/go-git/go-git/v6/plumbing/transport"
 	"github.com/go-git/go-git/v6/storage"
@@ -37,6 +43,14 @@ import (
 // GitDirName this is a special folder where all the git stuff is.
 const GitDirName = ".git"
 
+const (
+	// minAbbrevLength is the minimum length of an abbreviated hash.
+	minAbbrevLength = 4
+	// defaultAbbrevLength is the length of the abbreviated hashes when
+	// core.abbrev is not set.
+	defaultAbbrevLength = 7
+)
+
 var (
 	// ErrBranchExists an error stating the specified branch already exists
 	ErrBranchExists = errors.New("branch already exists")
@@ -69,6 +83,17 @@ var (
 	ErrUnableToResolveCommit = errors.New("unable to resolve commit")
 	// ErrPackedObjectsNotSupported is returned when packed objects are not supported.
 	ErrPackedObjectsNotSupported = errors.New("packed objects not supported")
+	// ErrCruftPacksNotSupported is returned when the storage can not record
+	// the modification time of the packed objects.
+	ErrCruftPacksNotSupported = errors.New("cruft packs not supported")
+	// ErrCountObjectsNotSupported is returned when the storage can not count
+	// its objects.
+	ErrCountObjectsNotSupported = errors.New("count objects not supported")
+	// ErrVerifyPackNotSupported is returned when the storage can not verify
+	// its packfiles.
+	ErrVerifyPackNotSupported = errors.New("verify pack not supported")
+	// ErrInvalidPackName is returned when a packfile name is not valid.
+	ErrInvalidPackName = errors.New("invalid pack name")
 	// ErrAlternatePathNotSupported is returned when the alternate path is not a file scheme.
 	ErrAlternatePathNotSupported = errors.New("alternate path must use the file scheme")
 	// ErrUnsupportedMergeStrategy is returned when an unsupported merge strategy is used.
@@ -77,6 +102,21 @@ var (
 	ErrFastForwardMergeNotPossible = errors.New("not possible to fast-forward merge changes")
 	// ErrTargetDirNotEmpty is returned when the destination path is not empty.
 	ErrTargetDirNotEmpty = errors.New("destination path already exists and is not empty")
+	// ErrNotSymbolicRef is returned when reading a reference that is not a
+	// symbolic reference as such.
+	ErrNotSymbolicRef = errors.New("reference is not a symbolic reference")
+	// ErrInvalidSymbolicRefTarget is returned when pointing HEAD outside of
+	// refs/.
+	ErrInvalidSymbolicRefTarget = errors.New("refusing to point HEAD outside of refs/")
+	// ErrInvalidHashPrefix is returned when expanding a string that is not
+	// an abbreviated hash.
+	ErrInvalidHashPrefix = errors.New("invalid hash prefix")
+	// ErrAmbiguousHashPrefix is returned when expanding an abbreviated hash
+	// matching several objects.
+	ErrAmbiguousHashPrefix = errors.New("ambiguous hash prefix")
+	// ErrInvalidRange is returned when a commit range is not in the A..B or
+	// A...B form.
+	ErrInvalidRange = errors.New("invalid commit range")
 )
 
 // Repository represents a git repository
@@ -85,6 +125,10 @@ type Repository struct {
 
 	r  map[string]*Remote
 	wt billy.Filesystem
+
+	noReplaceObjects bool
+	noGrafts         bool
+	objects          objectStorerCache
 }
 
 type initOptions struct {
@@ -430,6 +474,12 @@ func PlainOpenWithOptions(path string, o *PlainOpenOptions) (*Repository, error)
 		return nil, err
 	}
 
+	if o.CheckOwnership {
+		if err := checkPlainOwnership(dot, wt); err != nil {
+			return nil, err
+		}
+	}
+
 	var repositoryFs billy.Filesystem
 
 	dotGitCommon, err := dotGitCommonDirectory(dot)
@@ -443,6 +493,23 @@ func PlainOpenWithOptions(path string, o *PlainOpenOptions) (*Repository, error)
 	return Open(s, wt)
 }
 
+// OpenFS opens a read-only git repository from fsys, such as an embed.FS or
+// a zip.Reader, without extracting it. The root of fsys is either the git
+// directory or a directory containing a .git one. The repository is opened
+// without a worktree, and any operation writing to it fails.
+func OpenFS(fsys iofs.FS) (*Repository, error) {
+	if fi, err := iofs.Stat(fsys, GitDirName); err == nil && fi.IsDir() {
+		sub, err := iofs.Sub(fsys, GitDirName)
+		if err != nil {
+			return nil, err
+		}
+
+		fsys = sub
+	}
+
+	return Open(filesystem.NewStorageFromFS(fsys, cache.NewObjectLRUDefault()), nil)
+}
+
 func dotGitToOSFilesystems(path string, detect bool) (dot, wt billy.Filesystem, err error) {
 	path, err = pathutil.ReplaceTildeWithHome(path)
 	if err != nil {
@@ -463,8 +530,11 @@ func dotGitToOSFilesystems(path string, detect bool) (dot, wt billy.Filesystem,
 			if pathinfo == nil {
 				return nil, nil, err
 			}
-			if !pathinfo.IsDir() && detect {
-				fs = osfs.New(filepath.Dir(path), osfs.WithBoundOS())
+			// a relative gitdir of a .git file is resolved from the
+			// directory containing it, so the path given must be one
+			if !pathinfo.IsDir() && (detect || filepath.Base(path) == GitDirName) {
+				path = filepath.Dir(path)
+				fs = osfs.New(path, osfs.WithBoundOS())
 			}
 		}
 
@@ -677,8 +747,13 @@ func (r *Repository) SetConfig(cfg *config.Config) error {
 // are returned merged in one config value.
 func (r *Repository) ConfigScoped(scope config.Scope) (*config.Config, error) {
 	// TODO(mcuadros): v6, add this as ConfigOptions.Scoped
+	return configScoped(r.Storer, scope)
+}
 
-	local, err := r.Storer.Config()
+// configScoped returns the config of s, merged with the requested scope and
+// lower, as Repository.ConfigScoped.
+func configScoped(s config.ConfigStorer, scope config.Scope) (*config.Config, error) {
+	local, err := s.Config()
 	if err != nil {
 		return nil, err
 	}
@@ -862,6 +937,35 @@ func (r *Repository) DeleteBranch(name string) error {
 	return r.Storer.SetConfig(cfg)
 }
 
+// CommitTree creates a commit object of the given tree and parents, as git
+// commit-tree does, without using the worktree or the index nor updating any
+// reference. The tree and the parents must exist in the repository.
+func (r *Repository) CommitTree(tree plumbing.Hash, parents []plumbing.Hash, opts *CommitTreeOptions) (plumbing.Hash, error) {
+	if err := opts.Validate(r); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if _, err := r.TreeObject(tree); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("tree %s: %w", tree, err)
+	}
+
+	for _, p := range parents {
+		if _, err := r.CommitObject(p); err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("parent %s: %w", p, err)
+		}
+	}
+
+	commit := &object.Commit{
+		Author:       sanitizeSignature(*opts.Author),
+		Committer:    sanitizeSignature(*opts.Committer),
+		Message:      opts.Message,
+		TreeHash:     tree,
+		ParentHashes: parents,
+	}
+
+	return r.storeCommit(commit, opts.Signer)
+}
+
 // CreateTag creates a tag. If opts is included, the tag is an annotated tag,
 // otherwise a lightweight tag is created.
 func (r *Repository) CreateTag(name string, hash plumbing.Hash, opts *CreateTagOptions) (*plumbing.Reference, error) {
@@ -1095,6 +1199,7 @@ func (r *Repository) clone(ctx context.Context, o *CloneOptions) error {
 	ref, err := r.fetchAndUpdateReferences(ctx, &FetchOptions{
 		RefSpecs:        c.Fetch,
 		Depth:           o.Depth,
+		ShallowExclude:  o.ShallowExclude,
 		Auth:            o.Auth,
 		Progress:        o.Progress,
 		Tags:            o.Tags,
@@ -1103,6 +1208,9 @@ func (r *Repository) clone(ctx context.Context, o *CloneOptions) error {
 		CABundle:        o.CABundle,
 		ProxyOptions:    o.ProxyOptions,
 		Filter:          o.Filter,
+		MaxBytesPerSec:  o.MaxBytesPerSec,
+		MaxBlobSize:     o.MaxBlobSize,
+		MaxTreeEntries:  o.MaxTreeEntries,
 	}, o.ReferenceName)
 
 	hr, err1 := r.Storer.Reference(plumbing.HEAD)
@@ -1114,6 +1222,12 @@ func (r *Repository) clone(ctx context.Context, o *CloneOptions) error {
 		return err
 	}
 
+	if head, err := r.Head(); err == nil {
+		if err := r.logHEADUpdate(plumbing.ZeroHash, head.Hash(), nil, "clone: from "+o.URL); err != nil {
+			return err
+		}
+	}
+
 	err = r.setWorktreeAndStoragePaths()
 	if err != nil {
 		return err
@@ -1130,7 +1244,7 @@ func (r *Repository) clone(ctx context.Context, o *CloneOptions) error {
 			return err
 		}
 
-		if err := w.Reset(&ResetOptions{
+		if err := w.reset(&ResetOptions{
 			Mode:   MergeReset,
 			Commit: head.Hash(),
 		}); err != nil {
@@ -1394,6 +1508,39 @@ func (r *Repository) FetchContext(ctx context.Context, o *FetchOptions) error {
 	return remote.FetchContext(ctx, o)
 }
 
+// FetchCommit fetches the commit h along with its history, without updating
+// any reference, so it can be checked out by its hash, from the remote named
+// as FetchOptions.RemoteName. The other options apply as with Fetch, Depth
+// limiting the fetched history, except that no tags are fetched unless
+// FetchOptions.Tags is set.
+//
+// Unless h is a reference tip advertised by the remote, the server must allow
+// it to be wanted, or ErrExactSHA1NotSupported is returned. Returns
+// NoErrAlreadyUpToDate if the commit is already present.
+func (r *Repository) FetchCommit(h plumbing.Hash, o *FetchOptions) error {
+	return r.FetchCommitContext(context.Background(), h, o)
+}
+
+// FetchCommitContext fetches the commit h along with its history, as
+// FetchCommit does.
+//
+// The provided Context must be non-nil. If the context expires before the
+// operation is complete, an error is returned. The context only affects the
+// transport operations.
+func (r *Repository) FetchCommitContext(ctx context.Context, h plumbing.Hash, o *FetchOptions) error {
+	if o == nil {
+		o = &FetchOptions{}
+	}
+
+	opts := *o
+	opts.Hashes = append(slices.Clone(o.Hashes), h)
+	if opts.Tags == plumbing.InvalidTagMode {
+		opts.Tags = plumbing.NoTags
+	}
+
+	return r.FetchContext(ctx, &opts)
+}
+
 // Push performs a push to the remote. Returns NoErrAlreadyUpToDate if
 // the remote was already up-to-date, from the remote named as
 // FetchOptions.RemoteName.
@@ -1423,19 +1570,38 @@ func (r *Repository) PushContext(ctx context.Context, o *PushOptions) error {
 
 // Log returns the commit history from the given LogOptions.
 func (r *Repository) Log(o *LogOptions) (object.CommitIter, error) {
-	fn := commitIterFunc(o.Order)
-	if fn == nil {
+	if commitIterFunc(o.Order, nil) == nil {
 		return nil, fmt.Errorf("invalid Order=%v", o.Order)
 	}
 
-	var (
-		it  object.CommitIter
-		err error
-	)
+	from, excludes := []plumbing.Hash{o.From}, o.Excludes
+	if o.Range != "" {
+		var err error
+		from, excludes, err = r.resolveLogRange(o.Range)
+		if err != nil {
+			return nil, err
+		}
+
+		excludes = append(excludes, o.Excludes...)
+	}
+
+	var includes []plumbing.Hash
+	if !o.All {
+		includes = from
+	}
+
+	ignore, err := r.excludedCommits(includes, excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	fn := commitIterFunc(o.Order, ignore)
+
+	var it object.CommitIter
 	if o.All {
 		it, err = r.logAll(fn)
 	} else {
-		it, err = r.log(o.From, fn)
+		it, err = r.log(from, fn)
 	}
 
 	if err != nil {
@@ -1450,34 +1616,261 @@ func (r *Repository) Log(o *LogOptions) (object.CommitIter, error) {
 		it = r.logWithPathFilter(o.PathFilter, it, o.All)
 	}
 
-	if o.Since != nil || o.Until != nil || !o.To.IsZero() {
-		limitOptions := object.LogLimitOptions{Since: o.Since, Until: o.Until, TailHash: o.To}
+	if o.Since != nil || o.Until != nil || !o.To.IsZero() ||
+		o.Author != nil || o.Committer != nil || len(o.MessageGrep) > 0 {
+		limitOptions := object.LogLimitOptions{
+			Since:       o.Since,
+			Until:       o.Until,
+			TailHash:    o.To,
+			Author:      o.Author,
+			Committer:   o.Committer,
+			MessageGrep: o.MessageGrep,
+			AllMatch:    o.AllMatch,
+		}
 		it = r.logWithLimit(it, limitOptions)
 	}
 
+	if o.Mailmap != nil {
+		it = newCommitMailmapIter(it, o.Mailmap)
+	}
+
 	return it, nil
 }
 
-func (r *Repository) log(from plumbing.Hash, commitIterFunc func(*object.Commit) object.CommitIter) (object.CommitIter, error) {
-	h := from
-	if from == plumbing.ZeroHash {
-		head, err := r.Head()
+func (r *Repository) log(from []plumbing.Hash, commitIterFunc func(*object.Commit) object.CommitIter) (object.CommitIter, error) {
+	iters := make([]object.CommitIter, 0, len(from))
+	for _, h := range from {
+		if h == plumbing.ZeroHash {
+			head, err := r.Head()
+			if err != nil {
+				return nil, err
+			}
+
+			h = head.Hash()
+		}
+
+		commit, err := r.CommitObject(h)
 		if err != nil {
 			return nil, err
 		}
 
-		h = head.Hash()
+		iters = append(iters, commitIterFunc(commit))
+	}
+
+	if len(iters) == 1 {
+		return iters[0], nil
 	}
 
-	commit, err := r.CommitObject(h)
+	return &commitChainIter{iters: iters}, nil
+}
+
+// resolveLogRange resolves a range in the A..B or A...B form, returning the
+// commits to log from and the ones to exclude.
+func (r *Repository) resolveLogRange(spec string) (from, excludes []plumbing.Hash, err error) {
+	a, b, symmetric := strings.Cut(spec, "...")
+	if !symmetric {
+		var ok bool
+		if a, b, ok = strings.Cut(spec, ".."); !ok {
+			return nil, nil, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+		}
+	}
+
+	var sides [2]*object.Commit
+	for i, rev := range []string{a, b} {
+		if rev == "" {
+			rev = "HEAD"
+		}
+
+		h, err := r.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %q: %w", ErrInvalidRange, spec, err)
+		}
+
+		if sides[i], err = r.CommitObject(*h); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if !symmetric {
+		return []plumbing.Hash{sides[1].Hash}, []plumbing.Hash{sides[0].Hash}, nil
+	}
+
+	bases, err := r.mergeBase(sides[0], sides[1])
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	for _, c := range bases {
+		excludes = append(excludes, c.Hash)
+	}
+
+	return []plumbing.Hash{sides[1].Hash, sides[0].Hash}, excludes, nil
+}
+
+// excludedCommits returns the commits reachable from the excluded ones
+// which a walk from the included ones could reach, so that it stops at them.
+// As git does, both sides are walked together from the most recent commits,
+// the excluded walk stopping once every commit left to walk is reachable from
+// an excluded one. Without included commits, everything reachable from the
+// excluded ones is returned. A zero included hash stands for HEAD.
+func (r *Repository) excludedCommits(includes, excludes []plumbing.Hash) ([]plumbing.Hash, error) {
+	if len(excludes) == 0 {
+		return nil, nil
+	}
+
+	const (
+		seen uint8 = 1 << iota
+		excluded
+		// pending is set while the commit is queued and not excluded.
+		pending
+	)
+
+	flags := map[plumbing.Hash]uint8{}
+	// pendings is the number of the commits flagged pending, the walk
+	// stopping when it drops to zero.
+	pendings := 0
+	queue := binaryheap.NewWith(func(a, b any) int {
+		return b.(*object.Commit).Committer.When.Compare(a.(*object.Commit).Committer.When)
+	})
+
+	// push queues the commit, again if it becomes excluded once walked so
+	// that its parents are too.
+	push := func(h plumbing.Hash, exclude bool) error {
+		f := flags[h]
+		switch {
+		case exclude && f&excluded == 0:
+			if f&pending != 0 {
+				pendings--
+			}
+
+			flags[h] = (f | seen | excluded) &^ pending
+		case f&seen == 0:
+			flags[h] = f | seen | pending
+			pendings++
+		default:
+			return nil
+		}
+
+		c, err := r.CommitObject(h)
+		if err != nil {
+			return err
+		}
+
+		queue.Push(c)
+		return nil
+	}
+
+	for _, h := range excludes {
+		if err := push(h, true); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, h := range includes {
+		if h.IsZero() {
+			head, err := r.Head()
+			if err != nil {
+				return nil, err
+			}
+
+			h = head.Hash()
+		}
+
+		if err := push(h, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for !queue.Empty() && (len(includes) == 0 || pendings > 0) {
+		v, _ := queue.Pop()
+		c := v.(*object.Commit)
+		f := flags[c.Hash]
+		if f&pending != 0 {
+			flags[c.Hash] = f &^ pending
+			pendings--
+		}
+
+		exclude := f&excluded != 0
+		for _, p := range c.ParentHashes {
+			if err := push(p, exclude); err != nil {
+				if errors.Is(err, plumbing.ErrObjectNotFound) {
+					// The parents missing from a shallow clone.
+					continue
+				}
+
+				return nil, err
+			}
+		}
+	}
+
+	var commits []plumbing.Hash
+	for h, f := range flags {
+		if f&excluded != 0 {
+			commits = append(commits, h)
+		}
+	}
+
+	return commits, nil
+}
+
+// commitChainIter iterates the commits of its iterators one after the
+// other.
+type commitChainIter struct {
+	iters []object.CommitIter
+}
+
+func (i *commitChainIter) Next() (*object.Commit, error) {
+	for len(i.iters) > 0 {
+		c, err := i.iters[0].Next()
+		if errors.Is(err, io.EOF) {
+			i.iters[0].Close()
+			i.iters = i.iters[1:]
+			continue
+		}
+
+		return c, err
 	}
-	return commitIterFunc(commit), nil
+
+	return nil, io.EOF
+}
+
+func (i *commitChainIter) ForEach(cb func(*object.Commit) error) error {
+	defer i.Close()
+	for {
+		c, err := i.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := cb(c); err != nil {
+			if errors.Is(err, storer.ErrStop) {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+func (i *commitChainIter) Close() {
+	for _, it := range i.iters {
+		it.Close()
+	}
+
+	i.iters = nil
 }
 
 func (r *Repository) logAll(commitIterFunc func(*object.Commit) object.CommitIter) (object.CommitIter, error) {
-	return object.NewCommitAllIter(r.Storer, commitIterFunc)
+	s, err := r.objectStorer()
+	if err != nil {
+		return nil, err
+	}
+
+	return object.NewCommitAllIter(s, commitIterFunc)
 }
 
 func (*Repository) logWithFile(fileName string, commitIter object.CommitIter, checkParent bool) object.CommitIter {
@@ -1502,31 +1895,31 @@ func (*Repository) logWithLimit(commitIter object.CommitIter, limitOptions objec
 	return object.NewCommitLimitIterFromIter(commitIter, limitOptions)
 }
 
-func commitIterFunc(order LogOrder) func(c *object.Commit) object.CommitIter {
+func commitIterFunc(order LogOrder, ignore []plumbing.Hash) func(c *object.Commit) object.CommitIter {
 	switch order {
 	case LogOrderDefault:
 		return func(c *object.Commit) object.CommitIter {
-			return object.NewCommitPreorderIter(c, nil, nil)
+			return object.NewCommitPreorderIter(c, nil, ignore)
 		}
 	case LogOrderDFS:
 		return func(c *object.Commit) object.CommitIter {
-			return object.NewCommitPreorderIter(c, nil, nil)
+			return object.NewCommitPreorderIter(c, nil, ignore)
 		}
 	case LogOrderDFSPost:
 		return func(c *object.Commit) object.CommitIter {
-			return object.NewCommitPostorderIter(c, nil)
+			return object.NewCommitPostorderIter(c, ignore)
 		}
 	case LogOrderBSF:
 		return func(c *object.Commit) object.CommitIter {
-			return object.NewCommitIterBSF(c, nil, nil)
+			return object.NewCommitIterBSF(c, nil, ignore)
 		}
 	case LogOrderCommitterTime:
 		return func(c *object.Commit) object.CommitIter {
-			return object.NewCommitIterCTime(c, nil, nil)
+			return object.NewCommitIterCTime(c, nil, ignore)
 		}
 	case LogOrderDFSPostFirstParent:
 		return func(c *object.Commit) object.CommitIter {
-			return object.NewCommitPostorderIterFirstParent(c, nil)
+			return object.NewCommitPostorderIterFirstParent(c, ignore)
 		}
 	}
 	return nil
@@ -1598,7 +1991,12 @@ func (r *Repository) Notes() (storer.ReferenceIter, error) {
 // TreeObject return a Tree with the given hash. If not found
 // plumbing.ErrObjectNotFound is returned
 func (r *Repository) TreeObject(h plumbing.Hash) (*object.Tree, error) {
-	return object.GetTree(r.Storer, h)
+	s, err := r.objectStorer()
+	if err != nil {
+		return nil, err
+	}
+
+	return object.GetTree(s, h)
 }
 
 // TreeObjects returns an unsorted TreeIter with all the trees in the repository
@@ -1614,7 +2012,12 @@ func (r *Repository) TreeObjects() (*object.TreeIter, error) {
 // CommitObject return a Commit with the given hash. If not found
 // plumbing.ErrObjectNotFound is returned.
 func (r *Repository) CommitObject(h plumbing.Hash) (*object.Commit, error) {
-	return object.GetCommit(r.Storer, h)
+	s, err := r.objectStorer()
+	if err != nil {
+		return nil, err
+	}
+
+	return object.GetCommit(s, h)
 }
 
 // CommitObjects returns an unsorted CommitIter with all the commits in the repository.
@@ -1630,7 +2033,33 @@ func (r *Repository) CommitObjects() (object.CommitIter, error) {
 // BlobObject returns a Blob with the given hash. If not found
 // plumbing.ErrObjectNotFound is returned.
 func (r *Repository) BlobObject(h plumbing.Hash) (*object.Blob, error) {
-	return object.GetBlob(r.Storer, h)
+	s, err := r.objectStorer()
+	if err != nil {
+		return nil, err
+	}
+
+	return object.GetBlob(s, h)
+}
+
+// DiffBlobs returns the patch turning the content of the blob hashA into the
+// one of hashB, as object.DiffBlobs does. A zero hash stands for a missing
+// blob, to diff the content of the other one as added or deleted.
+func (r *Repository) DiffBlobs(hashA, hashB plumbing.Hash) (*object.Patch, error) {
+	var blobs [2]*object.Blob
+	for i, h := range []plumbing.Hash{hashA, hashB} {
+		if h.IsZero() {
+			continue
+		}
+
+		b, err := r.BlobObject(h)
+		if err != nil {
+			return nil, err
+		}
+
+		blobs[i] = b
+	}
+
+	return object.DiffBlobs(blobs[0], blobs[1], nil)
 }
 
 // BlobObjects returns an unsorted BlobIter with all the blobs in the repository.
@@ -1647,7 +2076,12 @@ func (r *Repository) BlobObjects() (*object.BlobIter, error) {
 // plumbing.ErrObjectNotFound is returned. This method only returns
 // annotated Tags, no lightweight Tags.
 func (r *Repository) TagObject(h plumbing.Hash) (*object.Tag, error) {
-	return object.GetTag(r.Storer, h)
+	s, err := r.objectStorer()
+	if err != nil {
+		return nil, err
+	}
+
+	return object.GetTag(s, h)
 }
 
 // TagObjects returns a unsorted TagIter that can step through all of the annotated
@@ -1664,12 +2098,17 @@ func (r *Repository) TagObjects() (*object.TagIter, error) {
 // Object returns an Object with the given hash. If not found
 // plumbing.ErrObjectNotFound is returned.
 func (r *Repository) Object(t plumbing.ObjectType, h plumbing.Hash) (object.Object, error) {
-	obj, err := r.Storer.EncodedObject(t, h)
+	s, err := r.objectStorer()
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := s.EncodedObject(t, h)
 	if err != nil {
 		return nil, err
 	}
 
-	return object.DecodeObject(r.Storer, obj)
+	return object.DecodeObject(s, obj)
 }
 
 // Objects returns an unsorted ObjectIter with all the objects in the repository.
@@ -1687,6 +2126,28 @@ func (r *Repository) Head() (*plumbing.Reference, error) {
 	return storer.ResolveReference(r.Storer, plumbing.HEAD)
 }
 
+// OrigHead returns the reference to the commit HEAD pointed to before the
+// last reset, merge or pull, recorded in ORIG_HEAD so that they can be
+// undone. It returns plumbing.ErrReferenceNotFound if none was recorded.
+func (r *Repository) OrigHead() (*plumbing.Reference, error) {
+	return r.Storer.Reference(plumbing.OrigHead)
+}
+
+// recordOrigHead records the commit HEAD points to in ORIG_HEAD, before an
+// operation moving it. Nothing is recorded if HEAD is unborn.
+func (r *Repository) recordOrigHead() error {
+	head, err := r.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return r.Storer.SetReference(plumbing.NewHashReference(plumbing.OrigHead, head.Hash()))
+}
+
 // Reference returns the reference for a given reference name. If resolved is
 // true, any symbolic reference will be resolved.
 func (r *Repository) Reference(name plumbing.ReferenceName, resolved bool) (
@@ -1699,11 +2160,118 @@ func (r *Repository) Reference(name plumbing.ReferenceName, resolved bool) (
 	return r.Storer.Reference(name)
 }
 
+// SymbolicRef returns the target of the symbolic reference with the given
+// name, as git symbolic-ref does. If the reference exists but points to a
+// hash, ErrNotSymbolicRef is returned.
+func (r *Repository) SymbolicRef(name plumbing.ReferenceName) (plumbing.ReferenceName, error) {
+	ref, err := r.Storer.Reference(name)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.Type() != plumbing.SymbolicReference {
+		return "", fmt.Errorf("%w: %s", ErrNotSymbolicRef, name)
+	}
+
+	return ref.Target(), nil
+}
+
+// SetSymbolicRef creates or updates the symbolic reference with the given
+// name to point to target, as git symbolic-ref does, e.g.
+// refs/remotes/origin/HEAD to refs/remotes/origin/main. The target doesn't
+// need to exist, but HEAD can only point to references under refs/.
+func (r *Repository) SetSymbolicRef(name, target plumbing.ReferenceName) error {
+	if err := name.Validate(); err != nil {
+		return err
+	}
+
+	if err := target.Validate(); err != nil {
+		return err
+	}
+
+	if name == plumbing.HEAD && !strings.HasPrefix(target.String(), "refs/") {
+		return fmt.Errorf("%w: %s", ErrInvalidSymbolicRefTarget, target)
+	}
+
+	return r.Storer.SetReference(plumbing.NewSymbolicReference(name, target))
+}
+
 // References returns an unsorted ReferenceIter for all references.
 func (r *Repository) References() (storer.ReferenceIter, error) {
 	return r.Storer.IterReferences()
 }
 
+// RefsPointingAt returns the references pointing at the object with the
+// given hash, directly or through annotated tags, as git for-each-ref
+// --points-at does, sorted by name. Symbolic references are included when
+// their target points at it, except HEAD.
+func (r *Repository) RefsPointingAt(h plumbing.Hash) ([]*plumbing.Reference, error) {
+	iter, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []*plumbing.Reference
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() == plumbing.HEAD {
+			return nil
+		}
+
+		target := ref
+		if ref.Type() == plumbing.SymbolicReference {
+			target, err = storer.ResolveReference(r.Storer, ref.Name())
+			if errors.Is(err, plumbing.ErrReferenceNotFound) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		ok, err := r.pointsAt(target.Hash(), h)
+		if ok {
+			refs = append(refs, ref)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name() < refs[j].Name()
+	})
+
+	return refs, nil
+}
+
+// pointsAt returns whether the object with hash obj is h or an annotated tag
+// pointing at h, through a chain of tags if needed.
+func (r *Repository) pointsAt(obj, h plumbing.Hash) (bool, error) {
+	seen := make(map[plumbing.Hash]bool)
+	for !seen[obj] {
+		if obj == h {
+			return true, nil
+		}
+
+		seen[obj] = true
+		tag, err := object.GetTag(r.Storer, obj)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		obj = tag.Target
+	}
+
+	return false, nil
+}
+
 // Worktree returns a worktree based on the given fs, if nil the default
 // worktree will be used.
 func (r *Repository) Worktree() (*Worktree, error) {
@@ -1734,7 +2302,7 @@ func expandRef(s storer.ReferenceStorer, ref plumbing.ReferenceName) (*plumbing.
 // ResolveRevision resolves revision to corresponding hash. It will always
 // resolve to a commit hash, not a tree or annotated tag.
 //
-// Implemented resolvers : HEAD, branch, tag, heads/branch, refs/heads/branch,
+// Implemented resolvers : HEAD, ORIG_HEAD, branch, tag, heads/branch, refs/heads/branch,
 // refs/tags/tag, refs/remotes/origin/branch, refs/remotes/origin/HEAD, tilde and caret (HEAD~1, master~^, tag~2, ref/heads/master~1, ...), selection by text (HEAD^{/fix nasty bug}), hash (prefix and full)
 func (r *Repository) ResolveRevision(in plumbing.Revision) (*plumbing.Hash, error) {
 	rev := in.String()
@@ -1912,6 +2480,137 @@ func (r *Repository) resolveHashPrefix(hashStr string) []plumbing.Hash {
 	return hashes
 }
 
+// AbbreviateHash returns the shortest prefix of h, at least minLen
+// hexadecimal digits long, that no other object of the repository starts
+// with, as git rev-parse --short does. If minLen is not positive, the length
+// set by core.abbrev is used, 7 by default.
+func (r *Repository) AbbreviateHash(h plumbing.Hash, minLen int) (string, error) {
+	if minLen <= 0 {
+		var err error
+		if minLen, err = r.abbrevLength(); err != nil {
+			return "", err
+		}
+	}
+
+	hexStr := h.String()
+	n := min(max(minLen, minAbbrevLength), len(hexStr))
+	for _, c := range r.resolveHashPrefix(hexStr[:n]) {
+		if c.Equal(h) {
+			continue
+		}
+
+		other := c.String()
+		common := 0
+		for common < len(hexStr) && common < len(other) && hexStr[common] == other[common] {
+			common++
+		}
+
+		n = max(n, min(common+1, len(hexStr)))
+	}
+
+	return hexStr[:n], nil
+}
+
+// ExpandHash returns the hash of the only object of the repository starting
+// with the given hexadecimal prefix, at least 4 digits long. It returns
+// ErrAmbiguousHashPrefix if several objects start with it, and
+// plumbing.ErrObjectNotFound if none does.
+func (r *Repository) ExpandHash(prefix string) (plumbing.Hash, error) {
+	prefix = strings.ToLower(prefix)
+	if len(prefix) < minAbbrevLength || strings.Trim(prefix, "0123456789abcdef") != "" {
+		return plumbing.ZeroHash, fmt.Errorf("%w: %q", ErrInvalidHashPrefix, prefix)
+	}
+
+	hashes := r.resolveHashPrefix(prefix)
+	switch len(hashes) {
+	case 0:
+		return plumbing.ZeroHash, plumbing.ErrObjectNotFound
+	case 1:
+		if err := r.Storer.HasEncodedObject(hashes[0]); err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		return hashes[0], nil
+	default:
+		return plumbing.ZeroHash, fmt.Errorf("%w: %s", ErrAmbiguousHashPrefix, prefix)
+	}
+}
+
+// abbrevLength returns the length of the abbreviated hashes set by
+// core.abbrev.
+func (r *Repository) abbrevLength() (int, error) {
+	cfg, err := r.Config()
+	if err != nil {
+		return 0, err
+	}
+
+	switch cfg.Core.Abbrev {
+	case "", "auto":
+		return defaultAbbrevLength, nil
+	case "no":
+		return cfg.Extensions.ObjectFormat.HexSize(), nil
+	}
+
+	n, err := strconv.Atoi(cfg.Core.Abbrev)
+	if err != nil || n < minAbbrevLength {
+		return 0, fmt.Errorf("invalid core.abbrev %q", cfg.Core.Abbrev)
+	}
+
+	return n, nil
+}
+
+// CountObjects returns the number of loose and packed objects in the
+// repository and the disk space they use, mirroring git count-objects -v. It
+// allows to decide when the repository would benefit from RepackObjects or
+// Prune.
+func (r *Repository) CountObjects() (*storer.ObjectsCount, error) {
+	oc, ok := r.Storer.(storer.ObjectCounter)
+	if !ok {
+		return nil, ErrCountObjectsNotSupported
+	}
+
+	return oc.CountObjects()
+}
+
+// VerifyPack decodes all the objects of a packfile of the repository and
+// checks them against its index, mirroring git verify-pack -v. The packfile
+// is named by its hash, by its file name, as pack-<hash>.pack, or by the
+// name of its index. The report lists the type, size, packed size and delta
+// chain of each object, with a histogram of the delta chain lengths.
+func (r *Repository) VerifyPack(name string) (*packfile.VerifyReport, error) {
+	type packVerifier interface {
+		VerifyPack(plumbing.Hash) (*packfile.VerifyReport, error)
+	}
+
+	pv, ok := r.Storer.(packVerifier)
+	if !ok {
+		return nil, ErrVerifyPackNotSupported
+	}
+
+	id := path.Base(filepath.ToSlash(name))
+	id = strings.TrimSuffix(strings.TrimSuffix(id, ".pack"), ".idx")
+	id = strings.TrimPrefix(id, "pack-")
+	if !plumbing.IsHash(id) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPackName, name)
+	}
+
+	return pv.VerifyPack(plumbing.NewHash(id))
+}
+
+// CheckConnectivity checks that all the objects reachable from the given tips
+// are in the storage, as a server does after receiving a push and before
+// updating the references. It fails with a *revlist.MissingObjectError on the
+// first missing object, which includes the path that referenced it. The
+// history beyond the shallow commits is not checked.
+func (r *Repository) CheckConnectivity(tips []plumbing.Hash) error {
+	shallow, err := r.Storer.Shallow()
+	if err != nil {
+		return err
+	}
+
+	return revlist.CheckConnectivity(r.Storer, tips, shallow)
+}
+
 // RepackConfig configures the repack operation.
 type RepackConfig struct {
 	// UseRefDeltas configures whether packfile encoder will use reference deltas.
@@ -1920,6 +2619,15 @@ type RepackConfig struct {
 	// OnlyDeletePacksOlderThan if set to non-zero value
 	// selects only objects older than the time provided.
 	OnlyDeletePacksOlderThan time.Time
+	// Cruft packs the unreachable objects, loose or packed, in a cruft pack
+	// recording their modification time, as git repack --cruft does, instead
+	// of dropping the packed ones and keeping the loose ones. The storage
+	// must implement storer.ObjectTimeStorer.
+	Cruft bool
+	// CruftExpiration if set to non-zero value drops the unreachable
+	// objects older than the time provided instead of packing them in the
+	// cruft pack.
+	CruftExpiration time.Time
 }
 
 // RepackObjects repacks all objects in the repository into a single packfile.
@@ -1929,22 +2637,47 @@ func (r *Repository) RepackObjects(cfg *RepackConfig) (err error) {
 		return ErrPackedObjectsNotSupported
 	}
 
+	ots, ok := r.Storer.(storer.ObjectTimeStorer)
+	if cfg.Cruft && !ok {
+		return ErrCruftPacksNotSupported
+	}
+
 	// Get the existing object packs.
 	hs, err := pos.ObjectPacks()
 	if err != nil {
 		return err
 	}
 
+	ow := newObjectWalker(r.Storer)
+	err = ow.walkAllRefs()
+	if err != nil {
+		return err
+	}
+
+	// Pack the unreachable objects before they are dropped with the old
+	// packs.
+	newPacks := make(map[plumbing.Hash]bool)
+	if cfg.Cruft {
+		ch, err := r.createCruftPack(cfg, ots, ow)
+		if err != nil {
+			return err
+		}
+
+		newPacks[ch] = true
+	}
+
 	// Create a new pack.
-	nh, err := r.createNewObjectPack(cfg)
+	nh, err := r.createNewObjectPack(cfg, ow)
 	if err != nil {
 		return err
 	}
 
+	newPacks[nh] = true
+
 	// Delete old packs.
 	for _, h := range hs {
 		// Skip if new hash is the same as an old one.
-		if h == nh {
+		if newPacks[h] {
 			continue
 		}
 		err = pos.DeleteOldObjectPackAndIndex(h, cfg.OnlyDeletePacksOlderThan)
@@ -1984,37 +2717,27 @@ func (r *Repository) Merge(ref plumbing.Reference, opts MergeOptions) error {
 		return ErrFastForwardMergeNotPossible
 	}
 
-	return r.Storer.SetReference(plumbing.NewHashReference(head.Name(), ref.Hash()))
+	if err := r.recordOrigHead(); err != nil {
+		return err
+	}
+
+	if err := r.Storer.SetReference(plumbing.NewHashReference(head.Name(), ref.Hash())); err != nil {
+		return err
+	}
+
+	return r.logHEADUpdate(head.Hash(), ref.Hash(), nil, fmt.Sprintf("merge %s: Fast-forward", ref.Name().Short()))
 }
 
 // createNewObjectPack is a helper for RepackObjects taking care
 // of creating a new pack. It is used so the PackfileWriter
 // deferred close has the right scope.
-func (r *Repository) createNewObjectPack(cfg *RepackConfig) (h plumbing.Hash, err error) {
-	ow := newObjectWalker(r.Storer)
-	err = ow.walkAllRefs()
-	if err != nil {
-		return h, err
-	}
+func (r *Repository) createNewObjectPack(cfg *RepackConfig, ow *objectWalker) (h plumbing.Hash, err error) {
 	objs := make([]plumbing.Hash, 0, len(ow.seen))
 	for h := range ow.seen {
 		objs = append(objs, h)
 	}
-	pfw, ok := r.Storer.(storer.PackfileWriter)
-	if !ok {
-		return h, fmt.Errorf("Repository storer is not a storer.PackfileWriter")
-	}
-	wc, err := pfw.PackfileWriter()
-	if err != nil {
-		return h, err
-	}
-	defer ioutil.CheckClose(wc, &err)
-	scfg, err := r.Config()
-	if err != nil {
-		return h, err
-	}
-	enc := packfile.NewEncoder(wc, r.Storer, cfg.UseRefDeltas)
-	h, err = enc.Encode(objs, scfg.Pack.Window)
+
+	h, err = r.writeObjectPack(cfg, objs)
 	if err != nil {
 		return h, err
 	}
@@ -2038,6 +2761,72 @@ func (r *Repository) createNewObjectPack(cfg *RepackConfig) (h plumbing.Hash, er
 	return h, err
 }
 
+// createCruftPack is a helper for RepackObjects packing the objects not seen
+// by the walker in a cruft pack, with their modification time. The expired
+// ones are dropped, and the loose ones deleted.
+func (r *Repository) createCruftPack(cfg *RepackConfig, ots storer.ObjectTimeStorer, ow *objectWalker) (h plumbing.Hash, err error) {
+	mtimes := make(map[plumbing.Hash]time.Time)
+	var objs []plumbing.Hash
+	err = ots.ForEachObjectTime(func(hash plumbing.Hash, t time.Time) error {
+		if _, ok := mtimes[hash]; ok || ow.isSeen(hash) {
+			return nil
+		}
+
+		mtimes[hash] = t
+		if cfg.CruftExpiration.IsZero() || !t.Before(cfg.CruftExpiration) {
+			objs = append(objs, hash)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return h, err
+	}
+
+	if len(objs) > 0 {
+		h, err = r.writeObjectPack(cfg, objs)
+		if err != nil {
+			return h, err
+		}
+
+		if err := ots.SetObjectPackMtimes(h, mtimes); err != nil {
+			return h, err
+		}
+	}
+
+	// Delete the unreachable, loose objects, now packed or expired.
+	if los, ok := r.Storer.(storer.LooseObjectStorer); ok {
+		err = los.ForEachObjectHash(func(hash plumbing.Hash) error {
+			if _, ok := mtimes[hash]; ok {
+				return los.DeleteLooseObject(hash)
+			}
+			return nil
+		})
+	}
+
+	return h, err
+}
+
+// writeObjectPack writes a pack of the given objects. It is used so the
+// PackfileWriter deferred close has the right scope.
+func (r *Repository) writeObjectPack(cfg *RepackConfig, objs []plumbing.Hash) (h plumbing.Hash, err error) {
+	pfw, ok := r.Storer.(storer.PackfileWriter)
+	if !ok {
+		return h, fmt.Errorf("Repository storer is not a storer.PackfileWriter")
+	}
+	wc, err := pfw.PackfileWriter()
+	if err != nil {
+		return h, err
+	}
+	defer ioutil.CheckClose(wc, &err)
+	scfg, err := r.Config()
+	if err != nil {
+		return h, err
+	}
+	enc := packfile.NewEncoder(wc, r.Storer, cfg.UseRefDeltas)
+	return enc.EncodeWithOptions(objs, packfile.NewEncodeOptions(scfg))
+}
+
 func expandPartialHash(st storer.EncodedObjectStorer, prefix []byte) (hashes []plumbing.Hash) {
 	// The fast version is implemented by storage/filesystem.ObjectStorage.
 	type fastIter interface {
@@ -0,0 +1,85 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ShortStat returns the summary of the changes from the tree of from to the
+// one of to, as git diff --shortstat prints it. Both hashes identify a
+// commit, a tree, or a tag pointing to one of them. If from is the zero hash
+// the changes are the ones of the commit to, from its first parent or from
+// the empty tree when it has none.
+func (r *Repository) ShortStat(from, to plumbing.Hash) (string, error) {
+	p, err := r.statPatch(from, to)
+	if err != nil {
+		return "", err
+	}
+
+	return p.ShortStat(), nil
+}
+
+// NumStat returns the changes from the tree of from to the one of to, as
+// git diff --numstat prints them. The hashes are interpreted as by
+// ShortStat.
+func (r *Repository) NumStat(from, to plumbing.Hash) (string, error) {
+	p, err := r.statPatch(from, to)
+	if err != nil {
+		return "", err
+	}
+
+	return p.NumStat(), nil
+}
+
+// statPatch returns the patch from the tree of from to the one of to.
+func (r *Repository) statPatch(from, to plumbing.Hash) (*object.Patch, error) {
+	toTree, toCommit, err := r.statTree(to)
+	if err != nil {
+		return nil, err
+	}
+
+	fromTree := &object.Tree{}
+	switch {
+	case !from.IsZero():
+		fromTree, _, err = r.statTree(from)
+	case toCommit != nil && toCommit.NumParents() != 0:
+		var parent *object.Commit
+		parent, err = toCommit.Parent(0)
+		if err == nil {
+			fromTree, err = parent.Tree()
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fromTree.Patch(toTree)
+}
+
+// statTree returns the tree identified by h, with its commit if h is the one
+// of a commit or of a tag pointing to one.
+func (r *Repository) statTree(h plumbing.Hash) (*object.Tree, *object.Commit, error) {
+	obj, err := r.Object(plumbing.AnyObject, h)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		switch o := obj.(type) {
+		case *object.Tag:
+			if obj, err = o.Object(); err != nil {
+				return nil, nil, err
+			}
+		case *object.Commit:
+			t, err := o.Tree()
+			return t, o, err
+		case *object.Tree:
+			return o, nil, nil
+		default:
+			return nil, nil, fmt.Errorf("%w: %s is a %s", plumbing.ErrInvalidType, h, obj.Type())
+		}
+	}
+}
@@ -41,6 +41,10 @@ var (
 		// noop-v1 does not change git’s behavior at all.
 		// It is useful only for testing format-1 compatibility.
 		"noop-v1": {},
+
+		// partialclone names the promisor remote of a partial clone, from
+		// which the missing objects are fetched when read.
+		"partialclone": {},
 	}
 
 	// Some Git extensions were supported upstream before the introduction
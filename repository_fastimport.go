@@ -0,0 +1,761 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/fastimport"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+)
+
+// FastExport writes the history of the references of opts to w, as the
+// stream of git fast-export: the blobs and the commits, identified by
+// marks, the resets of the branches and the annotated tags. The stream can
+// be read back by FastImport, or by git fast-import.
+//
+// The signatures and encodings of the commits and tags are not exported.
+// The references not pointing to a commit, nor to an annotated tag of a
+// commit, are skipped.
+func (r *Repository) FastExport(w io.Writer, opts *FastExportOptions) error {
+	if opts == nil {
+		opts = &FastExportOptions{}
+	}
+
+	if err := opts.Validate(r); err != nil {
+		return err
+	}
+
+	x := &fastExporter{
+		r:     r,
+		e:     fastimport.NewEncoder(w),
+		marks: make(map[plumbing.Hash]int),
+	}
+
+	for _, name := range opts.Refs {
+		if err := x.exportRef(name); err != nil {
+			return fmt.Errorf("exporting %s: %w", name, err)
+		}
+	}
+
+	if err := x.e.Encode(&fastimport.Done{}); err != nil {
+		return err
+	}
+
+	return x.e.Flush()
+}
+
+type fastExporter struct {
+	r *Repository
+	e *fastimport.Encoder
+
+	// marks holds the marks of the blobs and commits exported.
+	marks    map[plumbing.Hash]int
+	lastMark int
+}
+
+func (x *fastExporter) mark(h plumbing.Hash) int {
+	x.lastMark++
+	x.marks[h] = x.lastMark
+	return x.lastMark
+}
+
+func (x *fastExporter) exportRef(name plumbing.ReferenceName) error {
+	ref, err := x.r.Storer.Reference(name)
+	if err != nil {
+		return err
+	}
+
+	if ref.Type() != plumbing.HashReference {
+		return nil
+	}
+
+	obj, err := x.r.Storer.EncodedObject(plumbing.AnyObject, ref.Hash())
+	if err != nil {
+		return err
+	}
+
+	switch obj.Type() {
+	case plumbing.CommitObject:
+		return x.exportCommits(name, ref.Hash())
+	case plumbing.TagObject:
+		tag, err := object.DecodeTag(x.r.Storer, obj)
+		if err != nil {
+			return err
+		}
+
+		if tag.TargetType != plumbing.CommitObject {
+			return nil
+		}
+
+		if err := x.exportCommits(name, tag.Target); err != nil {
+			return err
+		}
+
+		return x.e.Encode(&fastimport.Tag{
+			Name:    tag.Name,
+			Mark:    x.mark(tag.Hash),
+			From:    fastimport.MarkRef(x.marks[tag.Target]),
+			Tagger:  &tag.Tagger,
+			Message: tag.Message,
+		})
+	}
+
+	return nil
+}
+
+// exportCommits exports the commit tip and its ancestors not yet exported,
+// the parents before their children, on the branch name. If tip was
+// already exported, the branch is reset to it.
+func (x *fastExporter) exportCommits(name plumbing.ReferenceName, tip plumbing.Hash) error {
+	if m, ok := x.marks[tip]; ok {
+		return x.e.Encode(&fastimport.Reset{Ref: name.String(), From: fastimport.MarkRef(m)})
+	}
+
+	commits := make(map[plumbing.Hash]*object.Commit)
+	stack := []plumbing.Hash{tip}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		if _, ok := x.marks[h]; ok {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		c, ok := commits[h]
+		if !ok {
+			var err error
+			c, err = x.r.CommitObject(h)
+			if err != nil {
+				return err
+			}
+
+			commits[h] = c
+			for _, p := range c.ParentHashes {
+				if _, ok := x.marks[p]; !ok {
+					stack = append(stack, p)
+				}
+			}
+
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		if err := x.exportCommit(name, c); err != nil {
+			return err
+		}
+
+		delete(commits, h)
+	}
+
+	return nil
+}
+
+// exportCommit exports c, whose parents are already exported, with the
+// blobs changed from its first parent.
+func (x *fastExporter) exportCommit(name plumbing.ReferenceName, c *object.Commit) error {
+	var parent *object.Tree
+	if c.NumParents() > 0 {
+		p, err := x.r.CommitObject(c.ParentHashes[0])
+		if err != nil {
+			return err
+		}
+
+		if parent, err = p.Tree(); err != nil {
+			return err
+		}
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return err
+	}
+
+	changes, err := x.exportChanges(parent, tree)
+	if err != nil {
+		return err
+	}
+
+	fc := &fastimport.Commit{
+		Ref:       name.String(),
+		Author:    &c.Author,
+		Committer: c.Committer,
+		Message:   c.Message,
+		Changes:   changes,
+	}
+
+	if c.NumParents() == 0 {
+		// The commit would follow the tip of the branch otherwise.
+		if err := x.e.Encode(&fastimport.Reset{Ref: fc.Ref}); err != nil {
+			return err
+		}
+	}
+
+	for i, p := range c.ParentHashes {
+		ref := fastimport.MarkRef(x.marks[p])
+		if i == 0 {
+			fc.From = ref
+		} else {
+			fc.Merges = append(fc.Merges, ref)
+		}
+	}
+
+	fc.Mark = x.mark(c.Hash)
+	return x.e.Encode(fc)
+}
+
+// exportChanges returns the changes of the files from the tree parent to
+// tree, the deletions first, exporting the blobs not yet exported.
+func (x *fastExporter) exportChanges(parent, tree *object.Tree) ([]fastimport.FileChange, error) {
+	before, err := treeFiles(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := treeFiles(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted, modified []string
+	for p := range before {
+		if _, ok := after[p]; !ok {
+			deleted = append(deleted, p)
+		}
+	}
+
+	for p, e := range after {
+		if b, ok := before[p]; !ok || b.Hash != e.Hash || b.Mode != e.Mode {
+			modified = append(modified, p)
+		}
+	}
+
+	sort.Strings(deleted)
+	sort.Strings(modified)
+
+	changes := make([]fastimport.FileChange, 0, len(deleted)+len(modified))
+	for _, p := range deleted {
+		changes = append(changes, fastimport.FileChange{Type: fastimport.FileDelete, Path: p})
+	}
+
+	for _, p := range modified {
+		e := after[p]
+		ref, err := x.exportBlob(e)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, fastimport.FileChange{
+			Type:    fastimport.FileModify,
+			Path:    p,
+			Mode:    e.Mode,
+			DataRef: ref,
+		})
+	}
+
+	return changes, nil
+}
+
+// exportBlob exports the blob of e if not yet exported, returning how it is
+// referenced. Submodules are referenced by the hash of their commit.
+func (x *fastExporter) exportBlob(e object.TreeEntry) (string, error) {
+	if e.Mode == filemode.Submodule {
+		return e.Hash.String(), nil
+	}
+
+	if m, ok := x.marks[e.Hash]; ok {
+		return fastimport.MarkRef(m), nil
+	}
+
+	blob, err := x.r.BlobObject(e.Hash)
+	if err != nil {
+		return "", err
+	}
+
+	rd, err := blob.Reader()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(rd)
+	_ = rd.Close()
+	if err != nil {
+		return "", err
+	}
+
+	m := x.mark(e.Hash)
+	if err := x.e.Encode(&fastimport.Blob{Mark: m, Data: data}); err != nil {
+		return "", err
+	}
+
+	return fastimport.MarkRef(m), nil
+}
+
+// FastImport reads a stream of git fast-import from r, as written by
+// FastExport or git fast-export, and creates its blobs, commits and
+// annotated tags. The branches and tags of the stream are updated once it
+// is read in full. The worktree and the index are left untouched.
+//
+// The branches committed to without a from command, nor a previous reset,
+// follow their current tip in the repository.
+func (r *Repository) FastImport(rd io.Reader) error {
+	im := &fastImporter{
+		r:        r,
+		marks:    make(map[int]plumbing.Hash),
+		branches: make(map[string]plumbing.Hash),
+		trees:    make(map[string]*importTree),
+		tags:     make(map[string]plumbing.Hash),
+	}
+
+	d := fastimport.NewDecoder(rd)
+	for {
+		cmd, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if _, ok := cmd.(*fastimport.Done); ok {
+			break
+		}
+
+		if err := im.importCommand(cmd); err != nil {
+			return err
+		}
+	}
+
+	return im.updateRefs()
+}
+
+type fastImporter struct {
+	r *Repository
+
+	// marks holds the objects created, by mark.
+	marks map[int]plumbing.Hash
+	// branches holds the tips of the branches committed to or reset, which
+	// are zero when the next commit is a root commit.
+	branches map[string]plumbing.Hash
+	// trees holds the trees of the tips of the branches committed to, which
+	// the next commits of the branches change.
+	trees map[string]*importTree
+	// tags holds the annotated tags created, by name.
+	tags map[string]plumbing.Hash
+}
+
+func (im *fastImporter) importCommand(cmd fastimport.Command) error {
+	switch c := cmd.(type) {
+	case *fastimport.Blob:
+		h, err := im.storeBlob(c.Data)
+		if err != nil {
+			return err
+		}
+
+		im.setMark(c.Mark, h)
+	case *fastimport.Commit:
+		return im.importCommit(c)
+	case *fastimport.Reset:
+		var h plumbing.Hash
+		if c.From != "" {
+			var err error
+			if h, err = im.resolve(c.From); err != nil {
+				return err
+			}
+		}
+
+		im.branches[c.Ref] = h
+	case *fastimport.Tag:
+		return im.importTag(c)
+	}
+
+	return nil
+}
+
+func (im *fastImporter) setMark(mark int, h plumbing.Hash) {
+	if mark > 0 {
+		im.marks[mark] = h
+	}
+}
+
+func (im *fastImporter) importCommit(c *fastimport.Commit) error {
+	var parents []plumbing.Hash
+	if c.From != "" {
+		h, err := im.resolve(c.From)
+		if err != nil {
+			return err
+		}
+
+		parents = append(parents, h)
+	} else if tip, ok := im.branches[c.Ref]; ok {
+		if !tip.IsZero() {
+			parents = append(parents, tip)
+		}
+	} else if ref, err := im.r.Reference(plumbing.ReferenceName(c.Ref), true); err == nil {
+		parents = append(parents, ref.Hash())
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
+
+	for _, m := range c.Merges {
+		h, err := im.resolve(m)
+		if err != nil {
+			return err
+		}
+
+		parents = append(parents, h)
+	}
+
+	tree, err := im.parentTree(c.Ref, parents, len(c.Merges))
+	if err != nil {
+		return err
+	}
+
+	for _, fc := range c.Changes {
+		if err := im.applyChange(tree, fc); err != nil {
+			return err
+		}
+	}
+
+	treeHash, err := tree.store(im.r.Storer)
+	if err != nil {
+		return err
+	}
+
+	commit := &object.Commit{
+		Committer:    c.Committer,
+		Message:      c.Message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+
+	commit.Author = c.Committer
+	if c.Author != nil {
+		commit.Author = *c.Author
+	}
+
+	h, err := im.store(commit)
+	if err != nil {
+		return err
+	}
+
+	im.setMark(c.Mark, h)
+	im.branches[c.Ref] = h
+	tree.commit = h
+	im.trees[c.Ref] = tree
+	return nil
+}
+
+// parentTree returns the tree the commit to the branch ref with the given
+// parents changes: the one of the first parent, unless the commit only has
+// merges. The tree of the tip of the branch is reused, when it is the first
+// parent, so that only the directories changed since are written again.
+func (im *fastImporter) parentTree(ref string, parents []plumbing.Hash, merges int) (*importTree, error) {
+	if len(parents) == merges {
+		return &importTree{entries: map[string]*importTreeEntry{}}, nil
+	}
+
+	if t, ok := im.trees[ref]; ok && t.commit == parents[0] {
+		return t, nil
+	}
+
+	p, err := im.r.Storer.EncodedObject(plumbing.CommitObject, parents[0])
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := object.DecodeCommit(im.r.Storer, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &importTree{hash: c.TreeHash}, nil
+}
+
+// applyChange applies the file change fc to the tree t.
+func (im *fastImporter) applyChange(t *importTree, fc fastimport.FileChange) error {
+	switch fc.Type {
+	case fastimport.FileDeleteAll:
+		t.hash, t.entries = plumbing.ZeroHash, map[string]*importTreeEntry{}
+		return nil
+	case fastimport.FileDelete:
+		_, err := t.remove(im.r.Storer, strings.Split(fc.Path, "/"))
+		return err
+	}
+
+	var h plumbing.Hash
+	var err error
+	switch {
+	case fc.DataRef == "":
+		h, err = im.storeBlob(fc.Data)
+	case fc.Mode == filemode.Submodule:
+		h, err = im.resolveHash(fc.DataRef)
+	default:
+		h, err = im.resolve(fc.DataRef)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return t.set(im.r.Storer, strings.Split(fc.Path, "/"), &importTreeEntry{mode: fc.Mode, hash: h})
+}
+
+// importTree is a tree changed by the commits of a fast-import stream, whose
+// subtrees are read from the storage when changed, and written again only if
+// they are, as git fast-import does.
+type importTree struct {
+	// hash is the hash of the tree stored, zero if it changed since.
+	hash plumbing.Hash
+	// entries are the entries of the tree by name, nil until it is read.
+	entries map[string]*importTreeEntry
+	// commit is the commit of the tree, for the tree of a branch.
+	commit plumbing.Hash
+}
+
+// importTreeEntry is an entry of an importTree, a file or a directory.
+type importTreeEntry struct {
+	mode filemode.FileMode
+	hash plumbing.Hash
+	// tree is the tree of a directory.
+	tree *importTree
+}
+
+// load reads the entries of the tree, if they are not already.
+func (t *importTree) load(s storer.EncodedObjectStorer) error {
+	if t.entries != nil {
+		return nil
+	}
+
+	tree, err := object.GetTree(s, t.hash)
+	if err != nil {
+		return err
+	}
+
+	t.entries = make(map[string]*importTreeEntry, len(tree.Entries))
+	for _, e := range tree.Entries {
+		entry := &importTreeEntry{mode: e.Mode, hash: e.Hash}
+		if e.Mode == filemode.Dir {
+			entry.tree = &importTree{hash: e.Hash}
+		}
+
+		t.entries[e.Name] = entry
+	}
+
+	return nil
+}
+
+// set sets the entry of the path, replacing the files along it with
+// directories.
+func (t *importTree) set(s storer.EncodedObjectStorer, path []string, e *importTreeEntry) error {
+	if err := t.load(s); err != nil {
+		return err
+	}
+
+	t.hash = plumbing.ZeroHash
+	if len(path) == 1 {
+		t.entries[path[0]] = e
+		return nil
+	}
+
+	dir, ok := t.entries[path[0]]
+	if !ok || dir.tree == nil {
+		dir = &importTreeEntry{mode: filemode.Dir, tree: &importTree{entries: map[string]*importTreeEntry{}}}
+		t.entries[path[0]] = dir
+	}
+
+	return dir.tree.set(s, path[1:], e)
+}
+
+// remove removes the file or the directory of the path, and the directories
+// left empty, returning whether the tree changed.
+func (t *importTree) remove(s storer.EncodedObjectStorer, path []string) (bool, error) {
+	if err := t.load(s); err != nil {
+		return false, err
+	}
+
+	e, ok := t.entries[path[0]]
+	switch {
+	case !ok:
+		return false, nil
+	case len(path) == 1:
+		delete(t.entries, path[0])
+	case e.tree == nil:
+		return false, nil
+	default:
+		changed, err := e.tree.remove(s, path[1:])
+		if !changed || err != nil {
+			return false, err
+		}
+
+		if len(e.tree.entries) == 0 {
+			delete(t.entries, path[0])
+		}
+	}
+
+	t.hash = plumbing.ZeroHash
+	return true, nil
+}
+
+// store writes the trees changed since they were last stored, returning the
+// hash of the tree.
+func (t *importTree) store(s storer.EncodedObjectStorer) (plumbing.Hash, error) {
+	if !t.hash.IsZero() {
+		return t.hash, nil
+	}
+
+	tree := &object.Tree{Entries: make([]object.TreeEntry, 0, len(t.entries))}
+	for name, e := range t.entries {
+		if e.tree != nil {
+			h, err := e.tree.store(s)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+
+			e.hash = h
+		}
+
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: e.mode, Hash: e.hash})
+	}
+
+	sort.Sort(sortableEntries(tree.Entries))
+	obj := s.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	h, err := s.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	t.hash = h
+	return h, nil
+}
+
+func (im *fastImporter) importTag(t *fastimport.Tag) error {
+	target, err := im.resolve(t.From)
+	if err != nil {
+		return err
+	}
+
+	obj, err := im.r.Storer.EncodedObject(plumbing.AnyObject, target)
+	if err != nil {
+		return err
+	}
+
+	tag := &object.Tag{
+		Name:       t.Name,
+		Message:    t.Message,
+		Target:     target,
+		TargetType: obj.Type(),
+	}
+
+	if t.Tagger != nil {
+		tag.Tagger = *t.Tagger
+	}
+
+	h, err := im.store(tag)
+	if err != nil {
+		return err
+	}
+
+	im.setMark(t.Mark, h)
+	im.tags[t.Name] = h
+	return nil
+}
+
+// resolve returns the object referenced by ref, as a mark, a branch of the
+// stream, a hash or a revision of the repository.
+func (im *fastImporter) resolve(ref string) (plumbing.Hash, error) {
+	if m, ok := fastimport.ParseMarkRef(ref); ok {
+		h, ok := im.marks[m]
+		if !ok {
+			return plumbing.ZeroHash, fmt.Errorf("%w: unknown mark %s", fastimport.ErrMalformedStream, ref)
+		}
+
+		return h, nil
+	}
+
+	if h, ok := im.branches[ref]; ok && !h.IsZero() {
+		return h, nil
+	}
+
+	if plumbing.IsHash(ref) {
+		return plumbing.NewHash(ref), nil
+	}
+
+	h, err := im.r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return *h, nil
+}
+
+// resolveHash returns the hash ref, which may reference an object missing
+// from the repository, as the commit of a submodule.
+func (im *fastImporter) resolveHash(ref string) (plumbing.Hash, error) {
+	if !plumbing.IsHash(ref) {
+		return plumbing.ZeroHash, fmt.Errorf("%w: invalid hash %q", fastimport.ErrMalformedStream, ref)
+	}
+
+	return plumbing.NewHash(ref), nil
+}
+
+// updateRefs sets the branches and tags of the stream.
+func (im *fastImporter) updateRefs() error {
+	for name, h := range im.branches {
+		if h.IsZero() {
+			continue
+		}
+
+		if err := im.r.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(name), h)); err != nil {
+			return err
+		}
+	}
+
+	for name, h := range im.tags {
+		if err := im.r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), h)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (im *fastImporter) storeBlob(data []byte) (plumbing.Hash, error) {
+	obj := im.r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(data)))
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return plumbing.ZeroHash, err
+	}
+
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return im.r.Storer.SetEncodedObject(obj)
+}
+
+// store encodes and stores o, returning its hash.
+func (im *fastImporter) store(o object.Object) (plumbing.Hash, error) {
+	obj := im.r.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return im.r.Storer.SetEncodedObject(obj)
+}
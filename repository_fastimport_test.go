@@ -0,0 +1,278 @@
+package git
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestFastExportImport(t *testing.T) {
+	t.Parallel()
+
+	fs := fixtures.Basic().One().DotGit()
+	src, err := Open(filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, src.FastExport(&buf, nil))
+
+	dst, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+	require.NoError(t, dst.FastImport(&buf))
+
+	refs, err := src.References()
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, refs.ForEach(func(ref *plumbing.Reference) error {
+		if !ref.Name().IsBranch() && !ref.Name().IsTag() {
+			return nil
+		}
+
+		count++
+		imported, err := dst.Reference(ref.Name(), false)
+		require.NoError(t, err, ref.Name())
+		assert.Equal(t, ref.Hash(), imported.Hash(), ref.Name())
+		return nil
+	}))
+	assert.Positive(t, count)
+}
+
+func TestFastExportRefs(t *testing.T) {
+	t.Parallel()
+
+	fs := fixtures.Basic().One().DotGit()
+	r, err := Open(filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.FastExport(&buf, &FastExportOptions{
+		Refs: []plumbing.ReferenceName{"refs/heads/master", "refs/remotes/origin/master"},
+	}))
+
+	stream := buf.String()
+	assert.Equal(t, 8, strings.Count(stream, "commit refs/heads/master\n"))
+	assert.NotContains(t, stream, "commit refs/remotes/origin/master\n")
+	assert.Contains(t, stream, "reset refs/remotes/origin/master\nfrom :")
+	assert.True(t, strings.HasSuffix(stream, "done\n"))
+}
+
+func TestFastImport(t *testing.T) {
+	t.Parallel()
+
+	stream := `blob
+mark :1
+data 6
+hello
+
+commit refs/heads/master
+mark :2
+author Foo <foo@example.com> 1234567890 +0100
+committer Bar <bar@example.com> 1234567891 +0100
+data 6
+first
+
+M 644 :1 hello.txt
+M 644 inline dir/a.txt
+data 1
+a
+
+commit refs/heads/master
+mark :3
+committer Bar <bar@example.com> 1234567892 +0100
+data 7
+second
+
+D dir
+M 755 :1 run.sh
+
+reset refs/heads/root
+
+commit refs/heads/root
+committer Bar <bar@example.com> 1234567893 +0100
+data 5
+root
+
+M 644 :1 other.txt
+merge :3
+
+tag v1.0
+from :2
+tagger Foo <foo@example.com> 1234567894 +0000
+data 8
+release
+
+done
+`
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+	require.NoError(t, r.FastImport(strings.NewReader(stream)))
+
+	master, err := r.Reference(plumbing.NewBranchReferenceName("master"), false)
+	require.NoError(t, err)
+
+	second, err := r.CommitObject(master.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", second.Message)
+	assert.Equal(t, "Bar", second.Author.Name)
+	require.Len(t, second.ParentHashes, 1)
+
+	files := func(c *object.Commit) map[string]string {
+		tree, err := c.Tree()
+		require.NoError(t, err)
+
+		files := map[string]string{}
+		require.NoError(t, tree.Files().ForEach(func(f *object.File) error {
+			content, err := f.Contents()
+			files[f.Name] = f.Mode.String() + " " + content
+			return err
+		}))
+
+		return files
+	}
+
+	assert.Equal(t, map[string]string{
+		"hello.txt": "0100644 hello\n",
+		"run.sh":    "0100755 hello\n",
+	}, files(second))
+
+	first, err := r.CommitObject(second.ParentHashes[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Foo", first.Author.Name)
+	assert.Equal(t, "Bar", first.Committer.Name)
+	assert.Empty(t, first.ParentHashes)
+	assert.Equal(t, map[string]string{
+		"hello.txt": "0100644 hello\n",
+		"dir/a.txt": "0100644 a",
+	}, files(first))
+
+	root, err := r.Reference(plumbing.NewBranchReferenceName("root"), false)
+	require.NoError(t, err)
+
+	merge, err := r.CommitObject(root.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{second.Hash}, merge.ParentHashes)
+	assert.Equal(t, map[string]string{"other.txt": "0100644 hello\n"}, files(merge))
+
+	tagRef, err := r.Reference(plumbing.NewTagReferenceName("v1.0"), false)
+	require.NoError(t, err)
+
+	tag, err := r.TagObject(tagRef.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, first.Hash, tag.Target)
+	assert.Equal(t, plumbing.CommitObject, tag.TargetType)
+	assert.Equal(t, "release\n", tag.Message)
+}
+
+func TestFastImportTrees(t *testing.T) {
+	t.Parallel()
+
+	stream := `commit refs/heads/master
+committer Bar <bar@example.com> 1234567891 +0100
+data 6
+first
+
+M 644 inline a/b/c.txt
+data 1
+c
+M 644 inline a/d.txt
+data 1
+d
+M 644 inline e/f.txt
+data 1
+f
+M 644 inline g/h.txt
+data 1
+h
+
+commit refs/heads/master
+committer Bar <bar@example.com> 1234567892 +0100
+data 7
+second
+
+M 644 inline a/b/c.txt
+data 2
+c2
+M 644 inline e/f.txt/i
+data 1
+i
+D a/d.txt
+
+commit refs/heads/master
+committer Bar <bar@example.com> 1234567893 +0100
+data 6
+third
+
+M 644 inline a/b
+data 1
+b
+D e/f.txt/i
+`
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+	require.NoError(t, r.FastImport(strings.NewReader(stream)))
+
+	master, err := r.Reference(plumbing.NewBranchReferenceName("master"), false)
+	require.NoError(t, err)
+
+	third, err := r.CommitObject(master.Hash())
+	require.NoError(t, err)
+	second, err := third.Parent(0)
+	require.NoError(t, err)
+	first, err := second.Parent(0)
+	require.NoError(t, err)
+
+	files := func(c *object.Commit) []string {
+		tree, err := c.Tree()
+		require.NoError(t, err)
+
+		var files []string
+		require.NoError(t, tree.Files().ForEach(func(f *object.File) error {
+			content, err := f.Contents()
+			files = append(files, f.Name+" "+content)
+			return err
+		}))
+
+		return files
+	}
+
+	assert.ElementsMatch(t, []string{"a/b/c.txt c", "a/d.txt d", "e/f.txt f", "g/h.txt h"}, files(first))
+	assert.ElementsMatch(t, []string{"a/b/c.txt c2", "e/f.txt/i i", "g/h.txt h"}, files(second))
+	assert.ElementsMatch(t, []string{"a/b b", "g/h.txt h"}, files(third))
+
+	// The directory emptied is removed, the one unchanged is reused.
+	tree, err := third.Tree()
+	require.NoError(t, err)
+	_, err = tree.FindEntry("e")
+	assert.ErrorIs(t, err, object.ErrEntryNotFound)
+
+	firstTree, err := first.Tree()
+	require.NoError(t, err)
+	g, err := firstTree.FindEntry("g")
+	require.NoError(t, err)
+	g3, err := tree.FindEntry("g")
+	require.NoError(t, err)
+	assert.Equal(t, g.Hash, g3.Hash)
+}
+
+func TestFastImportUnknownMark(t *testing.T) {
+	t.Parallel()
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+
+	err = r.FastImport(strings.NewReader("reset refs/heads/master\nfrom :1\n"))
+	assert.ErrorContains(t, err, "unknown mark :1")
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/go-git/go-git/v6/plumbing"
+)
+
+const graftsPath = "info/grafts"
+
+// SetGrafts enables or disables the grafts of $GIT_DIR/info/grafts, which
+// replace the parents of the commits read, as git does. Grafts are
+// deprecated in favor of the replacements of refs/replace/, and are enabled
+// by default.
+func (r *Repository) SetGrafts(enabled bool) {
+	r.noGrafts = !enabled
+}
+
+// grafts returns the parents of the grafted commits, by commit, as read from
+// $GIT_DIR/info/grafts. Each of its lines holds the hash of a commit followed
+// by the ones of its parents, if any. As git does, the malformed lines are
+// ignored. The file is read again only once it changes.
+func (r *Repository) grafts() (map[plumbing.Hash][]plumbing.Hash, error) {
+	if r.noGrafts {
+		return nil, nil
+	}
+
+	stamp, ok := storerStamp(r.Storer, graftsPath)
+	if !ok {
+		return nil, nil
+	}
+
+	c := &r.objects
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stamp == c.graftsStamp {
+		return c.grafts, nil
+	}
+
+	grafts, err := readGrafts(r.Storer.(fsBasedStorer).Filesystem())
+	if err != nil {
+		return nil, err
+	}
+
+	c.graftsStamp, c.grafts = stamp, grafts
+	return grafts, nil
+}
+
+// readGrafts reads the grafts file of fs, if any.
+func readGrafts(fs billy.Filesystem) (map[plumbing.Hash][]plumbing.Hash, error) {
+	f, err := fs.Open(graftsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	var grafts map[plumbing.Hash][]plumbing.Hash
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		commit, parents, ok := parseGraft(line)
+		if !ok {
+			continue
+		}
+
+		if grafts == nil {
+			grafts = make(map[plumbing.Hash][]plumbing.Hash)
+		}
+
+		grafts[commit] = parents
+	}
+
+	return grafts, scanner.Err()
+}
+
+// parseGraft parses a line of the grafts file.
+func parseGraft(line string) (commit plumbing.Hash, parents []plumbing.Hash, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return commit, nil, false
+	}
+
+	commit, ok = plumbing.FromHex(fields[0])
+	if !ok {
+		return commit, nil, false
+	}
+
+	parents = make([]plumbing.Hash, 0, len(fields)-1)
+	for _, field := range fields[1:] {
+		parent, ok := plumbing.FromHex(field)
+		if !ok {
+			return commit, nil, false
+		}
+
+		parents = append(parents, parent)
+	}
+
+	return commit, parents, true
+}
@@ -0,0 +1,73 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+func TestGrafts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	r, err := PlainInit(dir, false)
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	var hashes []plumbing.Hash
+	for _, msg := range []string{"A", "B", "C", "D"} {
+		h, err := w.Commit(msg, &CommitOptions{Author: defaultSignature(), AllowEmptyCommits: true})
+		require.NoError(t, err)
+		hashes = append(hashes, h)
+	}
+
+	// D is grafted onto A, skipping B and C, and B is made a root commit.
+	grafts := "# grafts\n" +
+		hashes[3].String() + " " + hashes[0].String() + "\n" +
+		hashes[1].String() + "\n" +
+		"malformed line\n"
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, GitDirName, "info"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, GitDirName, "info", "grafts"), []byte(grafts), 0o644))
+
+	logMessages := func(from plumbing.Hash) []string {
+		iter, err := r.Log(&LogOptions{From: from})
+		require.NoError(t, err)
+
+		var messages []string
+		require.NoError(t, iter.ForEach(func(c *object.Commit) error {
+			messages = append(messages, c.Message)
+			return nil
+		}))
+
+		return messages
+	}
+
+	assert.Equal(t, []string{"D", "A"}, logMessages(hashes[3]))
+	assert.Equal(t, []string{"C", "B"}, logMessages(hashes[2]))
+
+	d, err := r.CommitObject(hashes[3])
+	require.NoError(t, err)
+	assert.Equal(t, hashes[3], d.Hash)
+	assert.Equal(t, []plumbing.Hash{hashes[0]}, d.ParentHashes)
+
+	c, err := r.CommitObject(hashes[2])
+	require.NoError(t, err)
+	bases, err := d.MergeBase(c)
+	require.NoError(t, err)
+	assert.Empty(t, bases)
+
+	r.SetGrafts(false)
+	assert.Equal(t, []string{"D", "C", "B", "A"}, logMessages(hashes[3]))
+
+	r.SetGrafts(true)
+	require.NoError(t, os.Remove(filepath.Join(dir, GitDirName, "info", "grafts")))
+	assert.Equal(t, []string{"D", "C", "B", "A"}, logMessages(hashes[3]))
+}
@@ -0,0 +1,380 @@
+package git
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/emirpasic/gods/trees/binaryheap"
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	commitgraphfmt "github.com/go-git/go-git/v6/plumbing/format/commitgraph"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/object/commitgraph"
+)
+
+// GraphNode is a commit of the graph returned by Repository.ObjectGraph.
+type GraphNode struct {
+	Hash plumbing.Hash
+	// Parents are the hashes of the parents of the commit, the ones missing
+	// from a shallow repository included.
+	Parents []plumbing.Hash
+	// Refs are the names of the references pointing to the commit, sorted,
+	// HEAD included.
+	Refs []plumbing.ReferenceName
+	// Lane is the column of the commit in a graph drawn as git log --graph
+	// does: each lane holds a line of history, the first parent of a commit
+	// continuing in its lane and the other ones opening new lanes when they
+	// are not already expected in one.
+	Lane int
+}
+
+// ObjectGraph walks the history from the given commits and returns the commit
+// DAG in topological order, children before their parents and the most
+// recent commits first otherwise, as git log --graph --date-order does. The
+// parents are read from the commit-graph file when the repository has one,
+// unless they are altered by grafts, replacements or a shallow clone.
+func (r *Repository) ObjectGraph(o *ObjectGraphOptions) ([]*GraphNode, error) {
+	if o == nil {
+		o = &ObjectGraphOptions{}
+	}
+
+	refs, err := r.graphRefs(o)
+	if err != nil {
+		return nil, err
+	}
+
+	tips := o.From
+	if len(tips) == 0 {
+		for h := range refs {
+			tips = append(tips, h)
+		}
+	}
+
+	index, closer, err := r.commitNodeIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	// The commits are explored, counting their children, only as deep as
+	// needed to know that every child of the next commits is counted: the
+	// children of a commit of the commit-graph have a higher generation. So
+	// the history below the commits returned is not walked when limited.
+	nodes := map[plumbing.Hash]commitgraph.CommitNode{}
+	children := map[plumbing.Hash]int{}
+	explore := binaryheap.NewWith(func(a, b any) int {
+		return compareGraphGeneration(a.(commitgraph.CommitNode), b.(commitgraph.CommitNode))
+	})
+
+	discover := func(h plumbing.Hash) error {
+		if _, ok := nodes[h]; ok {
+			return nil
+		}
+
+		n, err := index.Get(h)
+		if errors.Is(err, plumbing.ErrObjectNotFound) && !slices.Contains(tips, h) {
+			// Beyond a shallow commit.
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		nodes[h] = n
+		explore.Push(n)
+		return nil
+	}
+
+	exploreTo := func(gen uint64) error {
+		for {
+			v, ok := explore.Peek()
+			if !ok {
+				return nil
+			}
+
+			if g := graphGeneration(v.(commitgraph.CommitNode)); g != math.MaxUint64 && g <= gen {
+				return nil
+			}
+
+			explore.Pop()
+			for _, p := range v.(commitgraph.CommitNode).ParentHashes() {
+				children[p]++
+				if err := discover(p); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	depth := uint64(math.MaxUint64)
+	for _, h := range tips {
+		if err := discover(h); err != nil {
+			return nil, err
+		}
+
+		depth = min(depth, graphGeneration(nodes[h]))
+	}
+
+	if err := exploreTo(depth); err != nil {
+		return nil, err
+	}
+
+	ready := binaryheap.NewWith(func(a, b any) int {
+		na, nb := a.(commitgraph.CommitNode), b.(commitgraph.CommitNode)
+		if c := nb.CommitTime().Compare(na.CommitTime()); c != 0 {
+			return c
+		}
+
+		return na.ID().Compare(nb.ID().Bytes())
+	})
+
+	queued := map[plumbing.Hash]bool{}
+	for _, h := range tips {
+		if children[h] == 0 && !queued[h] {
+			queued[h] = true
+			ready.Push(nodes[h])
+		}
+	}
+
+	var graph []*GraphNode
+	var lanes []plumbing.Hash
+	for !ready.Empty() && (o.Limit <= 0 || len(graph) < o.Limit) {
+		v, _ := ready.Pop()
+		n := v.(commitgraph.CommitNode)
+		parents := n.ParentHashes()
+		if len(parents) == 0 {
+			parents = nil
+		}
+
+		node := &GraphNode{Hash: n.ID(), Parents: parents, Refs: refs[n.ID()]}
+		node.Lane, lanes = assignLane(lanes, node.Hash, parents)
+		graph = append(graph, node)
+
+		// Its parents are discovered once it is explored.
+		if err := exploreTo(graphGeneration(n) - 1); err != nil {
+			return nil, err
+		}
+
+		for _, p := range parents {
+			pn, ok := nodes[p]
+			if !ok {
+				continue
+			}
+
+			if err := exploreTo(graphGeneration(pn)); err != nil {
+				return nil, err
+			}
+
+			children[p]--
+			if children[p] == 0 {
+				ready.Push(pn)
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// graphGeneration returns the generation of the commit, the highest one when
+// it is unknown: for the commits outside the commit-graph and the ones written
+// by old versions of git, with a zero generation.
+func graphGeneration(n commitgraph.CommitNode) uint64 {
+	if g := n.Generation(); g != 0 {
+		return g
+	}
+
+	return math.MaxUint64
+}
+
+// compareGraphGeneration orders the commits by descending generation.
+func compareGraphGeneration(a, b commitgraph.CommitNode) int {
+	return cmp.Compare(graphGeneration(b), graphGeneration(a))
+}
+
+// assignLane returns the lane of the commit given the commits expected in
+// each lane, and the lanes expecting its parents afterwards. As in git, the
+// commits not expected get a new lane, and the lanes left empty are removed.
+func assignLane(lanes []plumbing.Hash, h plumbing.Hash, parents []plumbing.Hash) (int, []plumbing.Hash) {
+	lane := slices.Index(lanes, h)
+	if lane < 0 {
+		lane = len(lanes)
+		lanes = append(lanes, h)
+	}
+
+	for i := range lanes {
+		if lanes[i] == h {
+			lanes[i] = plumbing.ZeroHash
+		}
+	}
+
+	for i, p := range parents {
+		switch {
+		case slices.Contains(lanes, p):
+		case i == 0:
+			lanes[lane] = p
+		default:
+			lanes = append(lanes, p)
+		}
+	}
+
+	return lane, slices.DeleteFunc(lanes, plumbing.Hash.IsZero)
+}
+
+// graphRefs returns the names of the references included in the graph by the
+// commit they point to, tags being peeled.
+func (r *Repository) graphRefs(o *ObjectGraphOptions) (map[plumbing.Hash][]plumbing.ReferenceName, error) {
+	refs := map[plumbing.Hash][]plumbing.ReferenceName{}
+	if head, err := r.Head(); err == nil {
+		refs[head.Hash()] = append(refs[head.Hash()], plumbing.HEAD)
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, err
+	}
+
+	iter, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		switch {
+		case ref.Type() != plumbing.HashReference:
+			return nil
+		case name.IsBranch(), name.IsTag() && o.Tags, name.IsRemote() && o.Remotes:
+		default:
+			return nil
+		}
+
+		h := ref.Hash()
+		if name.IsTag() {
+			// Tags of other objects than commits are not in the graph.
+			if tag, err := r.TagObject(h); err == nil {
+				c, err := tag.Commit()
+				if err != nil {
+					return nil
+				}
+
+				h = c.Hash
+			} else if _, err := r.CommitObject(h); err != nil {
+				return nil
+			}
+		}
+
+		refs[h] = append(refs[h], name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, names := range refs {
+		slices.SortFunc(names, func(a, b plumbing.ReferenceName) int {
+			return strings.Compare(a.String(), b.String())
+		})
+	}
+
+	return refs, nil
+}
+
+// mergeBase returns the best common ancestors of the commits a and b, as
+// object.Commit.MergeBase does. When the repository has a commit-graph, the
+// walk is pruned by its generation numbers.
+func (r *Repository) mergeBase(a, b *object.Commit) ([]*object.Commit, error) {
+	index, closer, err := r.commitNodeIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	if closer == nil {
+		return a.MergeBase(b)
+	}
+
+	defer closer.Close()
+
+	na, err := index.Get(a.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	nb, err := index.Get(b.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := commitgraph.MergeBase(na, nb)
+	if err != nil {
+		return nil, err
+	}
+
+	bases := make([]*object.Commit, 0, len(nodes))
+	for _, n := range nodes {
+		c, err := n.Commit()
+		if err != nil {
+			return nil, err
+		}
+
+		bases = append(bases, c)
+	}
+
+	return bases, nil
+}
+
+// commitNodeIndex returns the commit-graph of the repository if it has one
+// and it can be used, the commits being read from the object storage
+// otherwise.
+func (r *Repository) commitNodeIndex() (commitgraph.CommitNodeIndex, interface{ Close() error }, error) {
+	type fsBased interface {
+		Filesystem() billy.Filesystem
+	}
+
+	compatible, err := r.commitGraphCompatible()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fs, ok := r.Storer.(fsBased); ok && compatible {
+		index, err := commitgraphfmt.OpenChainOrFileIndex(fs.Filesystem())
+		if err == nil {
+			return commitgraph.NewGraphCommitNodeIndex(index, r.Storer), index, nil
+		}
+	}
+
+	s, err := r.objectStorer()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return commitgraph.NewObjectCommitNodeIndex(s), nil, nil
+}
+
+// commitGraphCompatible returns whether the commit-graph of the repository
+// can be used. As in git, it can't when the parents of the commits are
+// altered, by grafts, replacements or a shallow clone whose shallow commits
+// have no known parents, as the parents and the generation numbers it holds
+// would then be wrong.
+func (r *Repository) commitGraphCompatible() (bool, error) {
+	shallows, err := r.Storer.Shallow()
+	if err != nil || len(shallows) > 0 {
+		return false, err
+	}
+
+	grafts, err := r.grafts()
+	if err != nil || len(grafts) > 0 {
+		return false, err
+	}
+
+	replacements, err := r.replacements()
+	if err != nil || len(replacements) > 0 {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,481 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/utils/binary"
+	"github.com/go-git/go-git/v6/utils/merge"
+)
+
+var (
+	// ErrMergeConflict is returned, wrapped in a *MergeConflictError, when
+	// the changes being merged conflict.
+	ErrMergeConflict = errors.New("merge conflict")
+	// ErrUnrelatedHistories is returned when merging commits without a
+	// common ancestor.
+	ErrUnrelatedHistories = errors.New("refusing to merge unrelated histories")
+)
+
+// MergeConflictError is returned when the changes being merged or rebased
+// conflict. Nothing is changed in the repository nor in the worktree.
+type MergeConflictError struct {
+	// Commit is the hash of the commit that could not be rebased, zero when
+	// merging.
+	Commit plumbing.Hash
+	// Paths are the conflicting paths, sorted.
+	Paths []string
+}
+
+func (e *MergeConflictError) Error() string {
+	if e.Commit.IsZero() {
+		return fmt.Sprintf("%s: %s", ErrMergeConflict, strings.Join(e.Paths, ", "))
+	}
+
+	return fmt.Sprintf("%s rebasing %s: %s", ErrMergeConflict, e.Commit, strings.Join(e.Paths, ", "))
+}
+
+// Unwrap returns ErrMergeConflict.
+func (e *MergeConflictError) Unwrap() error {
+	return ErrMergeConflict
+}
+
+// mergeCommits stores a merge commit of theirs into ours, with the given
+// message and signatures, returning a *MergeConflictError if their changes
+// conflict.
+func (r *Repository) mergeCommits(ours, theirs *object.Commit, msg string, author, committer *object.Signature) (plumbing.Hash, error) {
+	bases, err := r.mergeBase(ours, theirs)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if len(bases) == 0 {
+		return plumbing.ZeroHash, ErrUnrelatedHistories
+	}
+
+	tree, err := r.mergeCommitTrees(bases[0], ours, theirs)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return r.storeCommit(&object.Commit{
+		Author:       *author,
+		Committer:    *committer,
+		Message:      msg,
+		TreeHash:     tree,
+		ParentHashes: []plumbing.Hash{ours.Hash, theirs.Hash},
+	}, nil)
+}
+
+// rebaseCommits replays the commits of head not reachable from onto on top
+// of it, as git rebase does, returning the hash of the last one. Merge
+// commits, and commits whose changes are already in onto, are dropped. The
+// authors of the commits are kept, and sig is their committer.
+func (r *Repository) rebaseCommits(head, onto *object.Commit, sig *object.Signature) (plumbing.Hash, error) {
+	upstream := map[plumbing.Hash]bool{}
+	err := object.NewCommitPreorderIter(onto, nil, nil).ForEach(func(c *object.Commit) error {
+		upstream[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	var commits []*object.Commit
+	err = object.NewCommitPreorderIter(head, upstream, nil).ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	current := onto
+	for _, c := range parentsFirst(commits) {
+		if c.NumParents() > 1 {
+			continue
+		}
+
+		var base *object.Commit
+		if c.NumParents() == 1 {
+			if base, err = c.Parent(0); err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+
+		tree, err := r.mergeCommitTrees(base, current, c)
+
+		var conflict *MergeConflictError
+		if errors.As(err, &conflict) {
+			conflict.Commit = c.Hash
+		}
+
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		if tree == current.TreeHash {
+			continue
+		}
+
+		h, err := r.storeCommit(&object.Commit{
+			Author:       c.Author,
+			Committer:    *sig,
+			Message:      c.Message,
+			TreeHash:     tree,
+			ParentHashes: []plumbing.Hash{current.Hash},
+		}, nil)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		if current, err = r.CommitObject(h); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	return current.Hash, nil
+}
+
+// parentsFirst returns the commits sorted so that each one comes after its
+// parents.
+func parentsFirst(commits []*object.Commit) []*object.Commit {
+	byHash := make(map[plumbing.Hash]*object.Commit, len(commits))
+	for _, c := range commits {
+		byHash[c.Hash] = c
+	}
+
+	sorted := make([]*object.Commit, 0, len(commits))
+	visited := make(map[plumbing.Hash]bool, len(commits))
+	var visit func(c *object.Commit)
+	visit = func(c *object.Commit) {
+		if visited[c.Hash] {
+			return
+		}
+
+		visited[c.Hash] = true
+		for _, p := range c.ParentHashes {
+			if pc, ok := byHash[p]; ok {
+				visit(pc)
+			}
+		}
+
+		sorted = append(sorted, c)
+	}
+
+	for i := len(commits) - 1; i >= 0; i-- {
+		visit(commits[i])
+	}
+
+	return sorted
+}
+
+// mergeCommitTrees merges the trees of the commits, base being nil for an
+// empty tree, and stores the result.
+func (r *Repository) mergeCommitTrees(base, ours, theirs *object.Commit) (plumbing.Hash, error) {
+	var trees [3]*object.Tree
+	for i, c := range []*object.Commit{base, ours, theirs} {
+		if c == nil {
+			continue
+		}
+
+		t, err := c.Tree()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		trees[i] = t
+	}
+
+	return r.mergeTrees(trees[0], trees[1], trees[2])
+}
+
+// mergeTrees merges the changes from base to theirs into ours and stores the
+// resulting tree. Files changed on both sides are merged line by line. If
+// any change conflicts, a *MergeConflictError is returned.
+func (r *Repository) mergeTrees(base, ours, theirs *object.Tree) (plumbing.Hash, error) {
+	var files [3]map[string]object.TreeEntry
+	for i, t := range []*object.Tree{base, ours, theirs} {
+		var err error
+		if files[i], err = treeFiles(t); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	attrs, err := r.mergeAttributes(ours)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	var opts merge.Options
+	if cfg.Merge.ConflictStyle != "" {
+		if opts.Style, err = merge.ParseConflictStyle(cfg.Merge.ConflictStyle); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	baseFiles, oursFiles, theirsFiles := files[0], files[1], files[2]
+	paths := map[string]struct{}{}
+	for _, m := range files {
+		for p := range m {
+			paths[p] = struct{}{}
+		}
+	}
+
+	result := make(map[string]object.TreeEntry, len(oursFiles))
+	for p, e := range oursFiles {
+		result[p] = e
+	}
+
+	var conflicts []string
+	for p := range paths {
+		b, bok := baseFiles[p]
+		ou, ook := oursFiles[p]
+		th, tok := theirsFiles[p]
+		switch {
+		case sameFile(ou, ook, th, tok), sameFile(b, bok, th, tok):
+		case sameFile(b, bok, ou, ook):
+			if tok {
+				result[p] = th
+			} else {
+				delete(result, p)
+			}
+		case ook && tok:
+			e, ok, err := r.mergeFiles(p, attrs, opts, b, bok, ou, th)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+
+			if !ok {
+				conflicts = append(conflicts, p)
+				continue
+			}
+
+			result[p] = e
+		default:
+			conflicts = append(conflicts, p)
+		}
+	}
+
+	for p := range result {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			if _, ok := result[dir]; ok {
+				conflicts = append(conflicts, dir, p)
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return plumbing.ZeroHash, &MergeConflictError{Paths: slices.Compact(conflicts)}
+	}
+
+	idx := &index.Index{Version: 2}
+	for p, e := range result {
+		idx.Entries = append(idx.Entries, &index.Entry{Name: p, Hash: e.Hash, Mode: e.Mode})
+	}
+
+	h := &buildTreeHelper{s: r.Storer}
+	return h.BuildTree(idx, nil)
+}
+
+// mergeAttributes returns the gitattributes of the worktree, which select
+// how the files are merged. Bare repositories use the ones of our tree, as
+// git does with HEAD.
+func (r *Repository) mergeAttributes(ours *object.Tree) ([]gitattributes.MatchAttribute, error) {
+	if r.wt == nil {
+		return gitattributes.ReadTreePatterns(ours)
+	}
+
+	attrs, err := gitattributes.ReadPatterns(r.wt, nil)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return attrs, nil
+}
+
+// mergeFiles merges the changes made to the file at path on both sides,
+// returning false if they conflict. Its merge attribute selects the merge
+// driver registered with its value, or else the built-in text, binary or
+// union driver, the text one merging line by line and being the default.
+// Binary files conflict unless merged by a registered driver. The conflict
+// markers are written
+// with the options opts, their size set by the conflict-marker-size
+// attribute of the file.
+func (r *Repository) mergeFiles(
+	p string,
+	attrs []gitattributes.MatchAttribute,
+	opts merge.Options,
+	base object.TreeEntry,
+	hasBase bool,
+	ours, theirs object.TreeEntry,
+) (object.TreeEntry, bool, error) {
+	mode := ours.Mode
+	switch {
+	case ours.Mode == theirs.Mode:
+	case hasBase && base.Mode == ours.Mode:
+		mode = theirs.Mode
+	case hasBase && base.Mode == theirs.Mode:
+	default:
+		return object.TreeEntry{}, false, nil
+	}
+
+	if !isMergeableMode(ours.Mode) || !isMergeableMode(theirs.Mode) || (hasBase && !isMergeableMode(base.Mode)) {
+		return object.TreeEntry{}, false, nil
+	}
+
+	parts := strings.Split(p, "/")
+	attr := gitattributes.MatchMerge(attrs, parts)
+	if attr != nil && attr.IsUnset() {
+		return object.TreeEntry{}, false, nil
+	}
+
+	var driver merge.Driver
+	if attr != nil && attr.IsValueSet() {
+		var ok bool
+		driver, ok = merge.GetDriver(attr.Value())
+		switch {
+		case ok:
+		case attr.Value() == merge.BinaryDriver:
+			return object.TreeEntry{}, false, nil
+		case attr.Value() == merge.UnionDriver:
+			opts.Union = true
+		}
+	}
+
+	var contents [3]string
+	for i, e := range []object.TreeEntry{base, ours, theirs} {
+		if i == 0 && !hasBase {
+			continue
+		}
+
+		content, isBinary, err := r.blobContent(e.Hash)
+		if err != nil || (isBinary && driver == nil) {
+			return object.TreeEntry{}, false, err
+		}
+
+		contents[i] = content
+	}
+
+	var merged string
+	var conflict bool
+	if driver != nil {
+		var baseContent []byte
+		if hasBase {
+			baseContent = []byte(contents[0])
+		}
+
+		b, ok := driver(baseContent, []byte(contents[1]), []byte(contents[2]), p)
+		merged, conflict = string(b), !ok
+	} else {
+		opts.MarkerSize = gitattributes.MatchConflictMarkerSize(attrs, parts)
+		merged, conflict = merge.Merge(contents[0], contents[1], contents[2], &opts)
+	}
+
+	if conflict {
+		return object.TreeEntry{}, false, nil
+	}
+
+	obj := r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return object.TreeEntry{}, false, err
+	}
+
+	if _, err := io.WriteString(w, merged); err != nil {
+		return object.TreeEntry{}, false, err
+	}
+
+	if err := w.Close(); err != nil {
+		return object.TreeEntry{}, false, err
+	}
+
+	h, err := r.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return object.TreeEntry{}, false, err
+	}
+
+	return object.TreeEntry{Name: ours.Name, Mode: mode, Hash: h}, true, nil
+}
+
+// blobContent returns the content of the blob and whether it is binary.
+func (r *Repository) blobContent(h plumbing.Hash) (string, bool, error) {
+	blob, err := r.BlobObject(h)
+	if err != nil {
+		return "", false, err
+	}
+
+	rd, err := blob.Reader()
+	if err != nil {
+		return "", false, err
+	}
+	defer rd.Close()
+
+	b, err := io.ReadAll(rd)
+	if err != nil {
+		return "", false, err
+	}
+
+	isBinary, err := binary.IsBinary(bytes.NewReader(b))
+	return string(b), isBinary, err
+}
+
+// isMergeableMode returns whether the content of files with the mode can be
+// merged line by line.
+func isMergeableMode(m filemode.FileMode) bool {
+	return m.IsRegular() || m == filemode.Executable
+}
+
+// sameFile returns whether both entries are the same file, or both missing.
+func sameFile(a object.TreeEntry, aok bool, b object.TreeEntry, bok bool) bool {
+	if !aok || !bok {
+		return aok == bok
+	}
+
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+// treeFiles returns the entries of the files of the tree by path, nil being
+// an empty tree.
+func treeFiles(t *object.Tree) (map[string]object.TreeEntry, error) {
+	files := map[string]object.TreeEntry{}
+	if t == nil {
+		return files, nil
+	}
+
+	w := object.NewTreeWalker(t, true, nil)
+	defer w.Close()
+	for {
+		name, e, err := w.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Mode != filemode.Dir {
+			files[name] = e
+		}
+	}
+}
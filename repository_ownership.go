@@ -0,0 +1,148 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/internal/pathutil"
+	"github.com/go-git/go-git/v6/x/plugin"
+)
+
+// ErrDubiousOwnership is returned, wrapped in a *DubiousOwnershipError, when
+// opening a repository owned by another user, which isn't listed in the
+// safe.directory config.
+var ErrDubiousOwnership = errors.New("detected dubious ownership in repository")
+
+// DubiousOwnershipError is returned by PlainOpenWithOptions, when the
+// ownership is checked, if the repository is owned by another user, as git
+// does since CVE-2022-24765, so that the config of a repository created by
+// another user isn't trusted.
+type DubiousOwnershipError struct {
+	// Path is the path of the repository, the one to add to safe.directory.
+	Path string
+}
+
+func (e *DubiousOwnershipError) Error() string {
+	return fmt.Sprintf("%s at %q", ErrDubiousOwnership, e.Path)
+}
+
+// Unwrap returns ErrDubiousOwnership.
+func (e *DubiousOwnershipError) Unwrap() error {
+	return ErrDubiousOwnership
+}
+
+// checkOwnership returns a *DubiousOwnershipError if the repository at
+// repoPath, or any of the paths, isn't owned by the current user, unless
+// repoPath is a safe directory.
+func checkOwnership(repoPath string, paths ...string) error {
+	owned := true
+	for _, p := range append([]string{repoPath}, paths...) {
+		ok, err := isOwnedByCurrentUser(p)
+		if err != nil {
+			return err
+		}
+
+		owned = owned && ok
+	}
+
+	if owned {
+		return nil
+	}
+
+	dirs, err := safeDirectories()
+	if err != nil {
+		return err
+	}
+
+	if isSafeDirectory(repoPath, dirs) {
+		return nil
+	}
+
+	return &DubiousOwnershipError{Path: repoPath}
+}
+
+// safeDirectories returns the safe.directory values of the system and
+// global configs, read through the config loader plugin. The values of the
+// repository config are ignored, as the repository isn't trusted.
+func safeDirectories() ([]string, error) {
+	if !plugin.Has(plugin.ConfigLoader()) {
+		return nil, nil
+	}
+
+	src, err := plugin.Get(plugin.ConfigLoader())
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, scope := range []config.Scope{config.SystemScope, config.GlobalScope} {
+		storer, err := src.Load(scope)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg, err := storer.Config()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range cfg.Safe.Directories {
+			if dir == "" {
+				dirs = nil
+				continue
+			}
+
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs, nil
+}
+
+// isSafeDirectory returns whether p is listed in dirs, as is, with a
+// trailing "/*" matching the directories it contains, or as "*" matching
+// all of them.
+func isSafeDirectory(p string, dirs []string) bool {
+	p = filepath.ToSlash(filepath.Clean(p))
+	for _, dir := range dirs {
+		if dir == "*" {
+			return true
+		}
+
+		dir, err := pathutil.ReplaceTildeWithHome(dir)
+		if err != nil {
+			continue
+		}
+
+		dir = filepath.ToSlash(dir)
+		if prefix, ok := strings.CutSuffix(dir, "/*"); ok {
+			if strings.HasPrefix(p, filepath.ToSlash(filepath.Clean(prefix))+"/") {
+				return true
+			}
+
+			continue
+		}
+
+		if filepath.ToSlash(filepath.Clean(dir)) == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkPlainOwnership checks the ownership of the repository with the git
+// directory dot, and the worktree wt, nil if bare. The path of a repository
+// with a worktree is the one of its worktree.
+func checkPlainOwnership(dot, wt billy.Filesystem) error {
+	if wt == nil {
+		return checkOwnership(dot.Root())
+	}
+
+	return checkOwnership(wt.Root(), dot.Root())
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package git
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// isOwnedByCurrentUser returns whether the file p is owned by the current
+// user, or by the user who ran sudo when running as root, as git allows.
+func isOwnedByCurrentUser(p string) (bool, error) {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return false, err
+	}
+
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true, nil
+	}
+
+	euid := os.Geteuid()
+	if int(st.Uid) == euid {
+		return true, nil
+	}
+
+	if euid == 0 {
+		if uid := os.Getenv("SUDO_UID"); uid != "" && uid == strconv.FormatUint(uint64(st.Uid), 10) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
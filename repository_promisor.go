@@ -0,0 +1,145 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// promisorObjectStorer fetches the objects missing from a partial clone from
+// its promisor remote when they are read, as git does. Checking whether an
+// object exists doesn't fetch it.
+type promisorObjectStorer struct {
+	storage.Storer
+	fetch func(plumbing.Hash) error
+}
+
+// promisorStorer returns the storer the objects are read from, fetching the
+// missing ones from the promisor remotes when the repository is a partial
+// clone. It is built again only once the config changes.
+func (r *Repository) promisorStorer() (storage.Storer, error) {
+	stamp, ok := storerStamp(r.Storer, "config", "config.worktree")
+
+	c := &r.objects
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok && stamp == c.promisorStamp {
+		return c.promisor, nil
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	var s storage.Storer = r.Storer
+	if names := promisorRemotes(cfg); len(names) > 0 {
+		fetchers := &promisorFetchers{r: r}
+		s = &promisorObjectStorer{
+			Storer: r.Storer,
+			fetch: func(h plumbing.Hash) error {
+				var errs []error
+				for _, name := range names {
+					err := fetchers.fetch(context.Background(), name, h)
+					if err == nil {
+						return nil
+					}
+
+					errs = append(errs, err)
+				}
+
+				return errors.Join(errs...)
+			},
+		}
+	}
+
+	if ok {
+		c.promisorStamp, c.promisor = stamp, s
+	}
+
+	return s, nil
+}
+
+// promisorRemotes returns the names of the promisor remotes, in the order
+// they are asked for the missing objects: the one of extensions.partialClone
+// first, then the ones with remote.<name>.promisor set, as git does.
+func promisorRemotes(cfg *config.Config) []string {
+	var names []string
+	if cfg.Extensions.PartialClone != "" {
+		names = append(names, cfg.Extensions.PartialClone)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Remotes)) {
+		if cfg.Remotes[name].Promisor && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// promisorFetchers holds a BlobFetcher per promisor remote, so that their
+// sessions are reused by the following fetches of missing objects.
+type promisorFetchers struct {
+	r *Repository
+
+	mu       sync.Mutex
+	fetchers map[string]*BlobFetcher
+}
+
+// fetch fetches the object h, along with the objects it references, from the
+// promisor remote name. The packfile received is marked as a promisor one.
+func (p *promisorFetchers) fetch(ctx context.Context, name string, h plumbing.Hash) error {
+	f, err := p.fetcher(name)
+	if err != nil {
+		return err
+	}
+
+	return f.fetch(ctx, []plumbing.Hash{h})
+}
+
+func (p *promisorFetchers) fetcher(name string) (*BlobFetcher, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if f, ok := p.fetchers[name]; ok {
+		return f, nil
+	}
+
+	remote, err := p.r.Remote(name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := newBlobFetcher(p.r, &FetchOptions{RemoteName: name, RemoteURL: remote.Config().URLs[0]})
+	if err != nil {
+		return nil, err
+	}
+
+	if p.fetchers == nil {
+		p.fetchers = map[string]*BlobFetcher{}
+	}
+
+	p.fetchers[name] = f
+	return f, nil
+}
+
+func (s *promisorObjectStorer) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := s.Storer.EncodedObject(t, h)
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return obj, err
+	}
+
+	if err := s.fetch(h); err != nil {
+		return nil, fmt.Errorf("%w: fetching it from the promisor remote: %w", plumbing.ErrObjectNotFound, err)
+	}
+
+	return s.Storer.EncodedObject(t, h)
+}
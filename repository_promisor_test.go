@@ -0,0 +1,71 @@
+package git
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-git/go-billy/v6/osfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	formatcfg "github.com/go-git/go-git/v6/plumbing/format/config"
+	"github.com/go-git/go-git/v6/plumbing/revlist"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+)
+
+func TestPartialCloneFetchesMissingObjects(t *testing.T) {
+	t.Parallel()
+
+	url, otherBlob := newBlobFetcherTestRemote(t)
+
+	fs := osfs.New(t.TempDir())
+	st := filesystem.NewStorage(fs, cache.NewObjectLRUDefault())
+	r, err := Init(st)
+	require.NoError(t, err)
+
+	_, err = r.CreateRemote(&config.RemoteConfig{
+		Name:     DefaultRemoteName,
+		URLs:     []string{url},
+		Promisor: true,
+	})
+	require.NoError(t, err)
+
+	err = r.Fetch(&FetchOptions{
+		RefSpecs: []config.RefSpec{"refs/heads/master:refs/remotes/origin/master"},
+	})
+	require.NoError(t, err)
+
+	packs, err := st.ObjectPacks()
+	require.NoError(t, err)
+	require.Len(t, packs, 1)
+	_, err = fs.Stat(fmt.Sprintf("objects/pack/pack-%s.promisor", packs[0]))
+	require.NoError(t, err)
+
+	ref, err := r.Reference("refs/remotes/origin/master", true)
+	require.NoError(t, err)
+	assert.True(t, st.IsPromisorObject(ref.Hash()))
+	require.NoError(t, revlist.CheckConnectivity(st, []plumbing.Hash{ref.Hash()}, nil))
+
+	// Checking whether an object exists doesn't fetch it.
+	require.ErrorIs(t, st.HasEncodedObject(otherBlob), plumbing.ErrObjectNotFound)
+
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	cfg.Core.RepositoryFormatVersion = formatcfg.Version1
+	cfg.Extensions.PartialClone = DefaultRemoteName
+	require.NoError(t, r.SetConfig(cfg))
+
+	r, err = Open(st, nil)
+	require.NoError(t, err)
+
+	b, err := r.BlobObject(otherBlob)
+	require.NoError(t, err)
+	assert.Equal(t, otherBlob, b.Hash)
+	assert.True(t, st.IsPromisorObject(otherBlob))
+
+	_, err = r.BlobObject(plumbing.NewHash("0000000000000000000000000000000000000001"))
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+}
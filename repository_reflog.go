@@ -0,0 +1,160 @@
+package git
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/reflog"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/x/plugin"
+)
+
+// logHEADUpdate records the move of HEAD from old to new in the reflog of
+// HEAD and, when HEAD points to a branch, in the one of the branch too, as
+// git does when committing or resetting. A nil committer is read from the
+// config.
+func (r *Repository) logHEADUpdate(old, new plumbing.Hash, committer *object.Signature, msg string) error {
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return err
+	}
+
+	names := []plumbing.ReferenceName{plumbing.HEAD}
+	if head.Type() == plumbing.SymbolicReference {
+		names = append(names, head.Target())
+	}
+
+	return r.logRefUpdate(names, old, new, committer, msg)
+}
+
+// logRefUpdate appends an entry recording the move of the references names
+// from old to new to their reflogs, when core.logAllRefUpdates allows it:
+// if "always" the reflogs are created for every reference, if true, the
+// default in non-bare repositories, only for HEAD, the branches, the
+// remote-tracking branches and the notes. Otherwise only the existing
+// reflogs are appended to.
+func (r *Repository) logRefUpdate(names []plumbing.ReferenceName, old, new plumbing.Hash, committer *object.Signature, msg string) error {
+	rs, ok := r.Storer.(storer.ReflogStorer)
+	if !ok {
+		return nil
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+
+	var entry *reflog.Entry
+	for _, name := range names {
+		if !shouldCreateReflog(cfg.Core.LogAllRefUpdates, cfg.Core.IsBare, name) {
+			entries, err := rs.Reflog(name)
+			if err != nil {
+				return err
+			}
+
+			if len(entries) == 0 {
+				continue
+			}
+		}
+
+		if entry == nil {
+			if committer == nil {
+				if committer, err = r.reflogCommitter(); err != nil {
+					return err
+				}
+			}
+
+			entry = &reflog.Entry{
+				OldHash:   old,
+				NewHash:   new,
+				Committer: reflog.Signature{Name: committer.Name, Email: committer.Email, When: committer.When},
+				Message:   msg,
+			}
+		}
+
+		if err := rs.AppendReflog(name, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shouldCreateReflog returns whether the reflog of the reference name is
+// created when missing, according to the value of core.logAllRefUpdates.
+func shouldCreateReflog(logAllRefUpdates string, isBare bool, name plumbing.ReferenceName) bool {
+	switch strings.ToLower(logAllRefUpdates) {
+	case "always":
+		return true
+	case "":
+		if isBare {
+			return false
+		}
+	case "true", "yes", "on", "1":
+	default:
+		return false
+	}
+
+	if name == plumbing.HEAD {
+		return true
+	}
+
+	for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/notes/"} {
+		if strings.HasPrefix(name.String(), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reflogCommitter returns the committer of the reflog entries, as set in the
+// config. Without a config loader only the config of the repository is read.
+func (r *Repository) reflogCommitter() (*object.Signature, error) {
+	scope := config.SystemScope
+	if !plugin.Has(plugin.ConfigLoader()) {
+		scope = config.LocalScope
+	}
+
+	cfg, err := r.ConfigScoped(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := &object.Signature{
+		Name:  cfg.Committer.Name,
+		Email: cfg.Committer.Email,
+		When:  time.Now(),
+	}
+
+	if sig.Name == "" {
+		sig.Name = cfg.User.Name
+	}
+
+	if sig.Email == "" {
+		sig.Email = cfg.User.Email
+	}
+
+	return sig, nil
+}
+
+// reflogSubject returns the subject of the commit message msg, as used in
+// the reflog messages.
+func reflogSubject(msg string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(msg), "\n")
+	return subject
+}
+
+// reflogRefName returns how the reference or commit HEAD points to is named
+// in the reflog messages: the short name of its branch, or its hash when
+// detached.
+func reflogRefName(head *plumbing.Reference) string {
+	if head.Type() == plumbing.SymbolicReference {
+		return head.Target().Short()
+	}
+
+	return head.Hash().String()
+}
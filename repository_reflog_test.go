@@ -0,0 +1,129 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestHEADReflog(t *testing.T) {
+	t.Parallel()
+
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(fs, "foo", []byte("foo"), 0o644))
+	_, err = w.Add("foo")
+	require.NoError(t, err)
+	first, err := w.Commit("first\n\nbody\n", &CommitOptions{Author: defaultSignature()})
+	require.NoError(t, err)
+
+	branch := plumbing.NewBranchReferenceName("topic")
+	require.NoError(t, w.Checkout(&CheckoutOptions{Branch: branch, Create: true}))
+
+	require.NoError(t, util.WriteFile(fs, "bar", []byte("bar"), 0o644))
+	_, err = w.Add("bar")
+	require.NoError(t, err)
+	second, err := w.Commit("second\n", &CommitOptions{Author: defaultSignature()})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Checkout(&CheckoutOptions{Branch: plumbing.Master}))
+	require.NoError(t, r.Merge(*plumbing.NewHashReference(branch, second), MergeOptions{}))
+	require.NoError(t, w.Reset(&ResetOptions{Commit: first, Mode: HardReset}))
+	require.NoError(t, w.Checkout(&CheckoutOptions{Hash: second}))
+
+	rs := r.Storer.(storer.ReflogStorer)
+	entries, err := rs.Reflog(plumbing.HEAD)
+	require.NoError(t, err)
+
+	var messages []string
+	for _, e := range entries {
+		messages = append(messages, e.Message)
+	}
+
+	assert.Equal(t, []string{
+		"commit (initial): first",
+		"checkout: moving from master to topic",
+		"commit: second",
+		"checkout: moving from topic to master",
+		"merge topic: Fast-forward",
+		"reset: moving to " + first.String(),
+		"checkout: moving from master to " + second.String(),
+	}, messages)
+	assert.Equal(t, plumbing.ZeroHash, entries[0].OldHash)
+	assert.Equal(t, first, entries[0].NewHash)
+	assert.Equal(t, second, entries[4].NewHash)
+	assert.Equal(t, second, entries[5].OldHash)
+
+	entries, err = rs.Reflog(branch)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "branch: Created from HEAD", entries[0].Message)
+	assert.Equal(t, "commit: second", entries[1].Message)
+
+	entries, err = rs.Reflog(plumbing.Master)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+}
+
+func TestHEADReflogLogAllRefUpdatesFalse(t *testing.T) {
+	t.Parallel()
+
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	cfg.Core.LogAllRefUpdates = "false"
+	require.NoError(t, r.SetConfig(cfg))
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(fs, "foo", []byte("foo"), 0o644))
+	_, err = w.Add("foo")
+	require.NoError(t, err)
+	_, err = w.Commit("first\n", &CommitOptions{Author: defaultSignature()})
+	require.NoError(t, err)
+
+	rs := r.Storer.(storer.ReflogStorer)
+	entries, err := rs.Reflog(plumbing.HEAD)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestShouldCreateReflog(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value  string
+		isBare bool
+		name   plumbing.ReferenceName
+		want   bool
+	}{
+		{"", false, plumbing.HEAD, true},
+		{"", false, "refs/heads/foo", true},
+		{"", false, "refs/tags/foo", false},
+		{"", true, plumbing.HEAD, false},
+		{"true", true, "refs/remotes/origin/foo", true},
+		{"true", false, "refs/notes/commits", true},
+		{"false", false, plumbing.HEAD, false},
+		{"always", true, "refs/tags/foo", true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, shouldCreateReflog(tt.value, tt.isBare, tt.name), "%q %v %s", tt.value, tt.isBare, tt.name)
+	}
+}
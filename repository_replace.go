@@ -0,0 +1,257 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+const (
+	replaceRefPrefix = "refs/replace/"
+
+	// maxReplaceDepth is the length of the chains of replacements followed,
+	// as git does.
+	maxReplaceDepth = 5
+)
+
+// SetReplaceObjects enables or disables the replacement of the objects read
+// by the ones their refs/replace/<object> references point to, as git does
+// unless --no-replace-objects is given. Replacements are enabled by default,
+// unless core.useReplaceRefs is false.
+func (r *Repository) SetReplaceObjects(enabled bool) {
+	r.noReplaceObjects = !enabled
+}
+
+// objectStorer returns the storer the objects are read from, replacing them
+// as defined by the refs/replace/ references, and the parents of the commits
+// as defined by the grafts. The objects missing from a partial clone are
+// fetched from its promisor remote.
+func (r *Repository) objectStorer() (storage.Storer, error) {
+	replacements, err := r.replacements()
+	if err != nil {
+		return nil, err
+	}
+
+	grafts, err := r.grafts()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := r.promisorStorer()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(replacements) == 0 && len(grafts) == 0 {
+		return s, nil
+	}
+
+	return &replaceObjectStorer{Storer: s, replacements: replacements, grafts: grafts}, nil
+}
+
+// objectStorerCache holds what the storer returned by objectStorer is built
+// from, along with the stamps of the files they were read from. They are
+// read again only once these files change, or every time if the repository
+// is not stored on a filesystem.
+type objectStorerCache struct {
+	mu sync.Mutex
+
+	replacementsStamp string
+	replacements      map[plumbing.Hash]plumbing.Hash
+
+	graftsStamp string
+	grafts      map[plumbing.Hash][]plumbing.Hash
+
+	promisorStamp string
+	promisor      storage.Storer
+}
+
+// replacements returns the objects replacing others, by replaced object,
+// unless the replacements are disabled.
+func (r *Repository) replacements() (map[plumbing.Hash]plumbing.Hash, error) {
+	if r.noReplaceObjects {
+		return nil, nil
+	}
+
+	stamp, ok := storerStamp(r.Storer, "config", "config.worktree", "packed-refs", replaceRefPrefix)
+
+	c := &r.objects
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ok && stamp == c.replacementsStamp {
+		return c.replacements, nil
+	}
+
+	replacements, err := r.readReplacements()
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		c.replacementsStamp, c.replacements = stamp, replacements
+	}
+
+	return replacements, nil
+}
+
+// readReplacements reads the replacements from the refs/replace/ references,
+// unless core.useReplaceRefs is false.
+func (r *Repository) readReplacements() (map[plumbing.Hash]plumbing.Hash, error) {
+	refs, err := r.Storer.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	var replacements map[plumbing.Hash]plumbing.Hash
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if ref.Type() != plumbing.HashReference || !strings.HasPrefix(name, replaceRefPrefix) {
+			return nil
+		}
+
+		replaced, ok := plumbing.FromHex(strings.TrimPrefix(name, replaceRefPrefix))
+		if !ok {
+			return nil
+		}
+
+		if replacements == nil {
+			replacements = make(map[plumbing.Hash]plumbing.Hash)
+		}
+
+		replacements[replaced] = ref.Hash()
+		return nil
+	})
+
+	if err != nil || len(replacements) == 0 {
+		return nil, err
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Core.UseReplaceRefs == config.OptBoolFalse {
+		return nil, nil
+	}
+
+	return replacements, nil
+}
+
+// fsBasedStorer is a storer stored on a filesystem.
+type fsBasedStorer interface {
+	Filesystem() billy.Filesystem
+}
+
+// storerStamp returns a stamp of the files of the storer at the given paths,
+// and of the ones in them for directories, which changes when they do. It
+// returns false if the storer is not stored on a filesystem.
+func storerStamp(s storage.Storer, paths ...string) (string, bool) {
+	fsBased, ok := s.(fsBasedStorer)
+	if !ok {
+		return "", false
+	}
+
+	fs := fsBased.Filesystem()
+	var b strings.Builder
+	var stamp func(name string, fi os.FileInfo)
+	stamp = func(name string, fi os.FileInfo) {
+		fmt.Fprintf(&b, "%s:%d:%d;", name, fi.Size(), fi.ModTime().UnixNano())
+		if !fi.IsDir() {
+			return
+		}
+
+		entries, err := fs.ReadDir(name)
+		if err != nil {
+			return
+		}
+
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil {
+				stamp(path.Join(name, e.Name()), info)
+			}
+		}
+	}
+
+	for _, name := range paths {
+		fi, err := fs.Lstat(name)
+		if err != nil {
+			fmt.Fprintf(&b, "%s:-;", name)
+			continue
+		}
+
+		stamp(name, fi)
+	}
+
+	return b.String(), true
+}
+
+// replaceObjectStorer reads the replacements of the objects, which keep the
+// hash of the objects they replace.
+type replaceObjectStorer struct {
+	storage.Storer
+	replacements map[plumbing.Hash]plumbing.Hash
+	grafts       map[plumbing.Hash][]plumbing.Hash
+}
+
+func (s *replaceObjectStorer) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	replacement := h
+	for range maxReplaceDepth {
+		next, ok := s.replacements[replacement]
+		if !ok {
+			break
+		}
+
+		replacement = next
+	}
+
+	obj, err := s.Storer.EncodedObject(t, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	if parents, ok := s.grafts[h]; ok && obj.Type() == plumbing.CommitObject {
+		if obj, err = s.graft(obj, parents); err != nil {
+			return nil, err
+		}
+	} else if replacement == h {
+		return obj, nil
+	}
+
+	return &replacedObject{EncodedObject: obj, hash: h}, nil
+}
+
+// graft returns the commit obj with the parents defined by its graft.
+func (s *replaceObjectStorer) graft(obj plumbing.EncodedObject, parents []plumbing.Hash) (plumbing.EncodedObject, error) {
+	c, err := object.DecodeCommit(s.Storer, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ParentHashes = parents
+	grafted := s.Storer.NewEncodedObject()
+	if err := c.Encode(grafted); err != nil {
+		return nil, err
+	}
+
+	return grafted, nil
+}
+
+// replacedObject is the replacement of an object, which has its hash.
+type replacedObject struct {
+	plumbing.EncodedObject
+	hash plumbing.Hash
+}
+
+func (o *replacedObject) Hash() plumbing.Hash {
+	return o.hash
+}
@@ -0,0 +1,105 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestReplaceObjects(t *testing.T) {
+	t.Parallel()
+
+	r, err := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	var hashes []plumbing.Hash
+	for _, msg := range []string{"A", "B", "C"} {
+		h, err := w.Commit(msg, &CommitOptions{Author: defaultSignature(), AllowEmptyCommits: true})
+		require.NoError(t, err)
+		hashes = append(hashes, h)
+	}
+
+	b, err := r.CommitObject(hashes[1])
+	require.NoError(t, err)
+
+	// B is replaced by a root commit, cutting the history before it.
+	replacement, err := r.CommitTree(b.TreeHash, nil, &CommitTreeOptions{Author: defaultSignature(), Message: "B'"})
+	require.NoError(t, err)
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(
+		plumbing.ReferenceName("refs/replace/"+hashes[1].String()), replacement)))
+
+	logMessages := func() []string {
+		iter, err := r.Log(&LogOptions{From: hashes[2]})
+		require.NoError(t, err)
+
+		var messages []string
+		require.NoError(t, iter.ForEach(func(c *object.Commit) error {
+			messages = append(messages, c.Message)
+			return nil
+		}))
+
+		return messages
+	}
+
+	assert.Equal(t, []string{"C", "B'"}, logMessages())
+
+	b, err = r.CommitObject(hashes[1])
+	require.NoError(t, err)
+	assert.Equal(t, hashes[1], b.Hash)
+	assert.Equal(t, "B'", b.Message)
+
+	r.SetReplaceObjects(false)
+	assert.Equal(t, []string{"C", "B", "A"}, logMessages())
+
+	r.SetReplaceObjects(true)
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	cfg.Core.UseReplaceRefs = config.OptBoolFalse
+	require.NoError(t, r.SetConfig(cfg))
+	assert.Equal(t, []string{"C", "B", "A"}, logMessages())
+}
+
+func TestReplaceObjectsCache(t *testing.T) {
+	t.Parallel()
+
+	st := filesystem.NewStorage(memfs.New(), cache.NewObjectLRUDefault())
+	r, err := Init(st, WithWorkTree(memfs.New()))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	h, err := w.Commit("A", &CommitOptions{Author: defaultSignature(), AllowEmptyCommits: true})
+	require.NoError(t, err)
+
+	message := func() string {
+		c, err := r.CommitObject(h)
+		require.NoError(t, err)
+		return c.Message
+	}
+
+	c, err := r.CommitObject(h)
+	require.NoError(t, err)
+	assert.Equal(t, "A", message())
+
+	replacement, err := r.CommitTree(c.TreeHash, nil, &CommitTreeOptions{Author: defaultSignature(), Message: "A'"})
+	require.NoError(t, err)
+	name := plumbing.ReferenceName("refs/replace/" + h.String())
+	require.NoError(t, st.SetReference(plumbing.NewHashReference(name, replacement)))
+	assert.Equal(t, "A'", message())
+
+	require.NoError(t, st.RemoveReference(name))
+	assert.Equal(t, "A", message())
+}
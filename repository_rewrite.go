@@ -0,0 +1,321 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+// ErrRewriteTreeEntryDir is returned by RewriteHistory when the
+// TreeEntryFilter turns a file into a directory.
+var ErrRewriteTreeEntryDir = errors.New("tree entry filter cannot turn a file into a directory")
+
+// RewriteHistory rewrites the commits reachable from the references of opts,
+// as git filter-repo does, and updates the references to the rewritten
+// commits. The commits are rewritten after their parents, with the trees and
+// metadata returned by the filters of opts, and keep their hash when nothing
+// of them changed. The signatures of the rewritten commits and tags are
+// dropped, as they would no longer be valid. The worktree and the index are
+// left untouched.
+//
+// It returns the hashes of the rewritten commits and annotated tags, by
+// original hash.
+func (r *Repository) RewriteHistory(opts *RewriteOptions) (map[plumbing.Hash]plumbing.Hash, error) {
+	if opts == nil {
+		opts = &RewriteOptions{}
+	}
+
+	if err := opts.Validate(r); err != nil {
+		return nil, err
+	}
+
+	rw := &historyRewriter{
+		r:       r,
+		opts:    opts,
+		rewrite: make(map[plumbing.Hash]plumbing.Hash),
+		trees:   make(map[treeRewriteKey]rewrittenTree),
+	}
+
+	refs := make([]*plumbing.Reference, 0, len(opts.Refs))
+	for _, name := range opts.Refs {
+		ref, err := r.Storer.Reference(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+
+		if _, err := rw.rewriteObject(ref.Hash()); err != nil {
+			return nil, fmt.Errorf("rewriting %s: %w", name, err)
+		}
+
+		refs = append(refs, ref)
+	}
+
+	for _, ref := range refs {
+		h := rw.rewrite[ref.Hash()]
+		if h == ref.Hash() {
+			continue
+		}
+
+		if err := r.Storer.CheckAndSetReference(plumbing.NewHashReference(ref.Name(), h), ref); err != nil {
+			return nil, err
+		}
+	}
+
+	return rw.rewrite, nil
+}
+
+// treeRewriteKey identifies a tree rewritten by the TreeEntryFilter, which
+// depends on the path of the tree as much as on its entries.
+type treeRewriteKey struct {
+	dir  string
+	hash plumbing.Hash
+}
+
+type historyRewriter struct {
+	r    *Repository
+	opts *RewriteOptions
+
+	// rewrite holds the hashes of the rewritten commits and tags.
+	rewrite map[plumbing.Hash]plumbing.Hash
+	// trees holds the rewritten trees, as most of them are shared by
+	// consecutive commits.
+	trees map[treeRewriteKey]rewrittenTree
+}
+
+type rewrittenTree struct {
+	hash  plumbing.Hash
+	empty bool
+}
+
+// rewriteObject rewrites the commit or the annotated tag h, returning its new
+// hash. Other objects are left as is.
+func (rw *historyRewriter) rewriteObject(h plumbing.Hash) (plumbing.Hash, error) {
+	if nh, ok := rw.rewrite[h]; ok {
+		return nh, nil
+	}
+
+	obj, err := rw.r.Storer.EncodedObject(plumbing.AnyObject, h)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	switch obj.Type() {
+	case plumbing.CommitObject:
+		return rw.rewriteCommits(h)
+	case plumbing.TagObject:
+		return rw.rewriteTag(h)
+	default:
+		rw.rewrite[h] = h
+		return h, nil
+	}
+}
+
+func (rw *historyRewriter) rewriteTag(h plumbing.Hash) (plumbing.Hash, error) {
+	tag, err := object.GetTag(rw.r.Storer, h)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	target, err := rw.rewriteObject(tag.Target)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if target == tag.Target {
+		rw.rewrite[h] = h
+		return h, nil
+	}
+
+	nt := *tag
+	nt.Target = target
+	nt.Signature = ""
+
+	nh, err := rw.store(&nt)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	rw.rewrite[h] = nh
+	return nh, nil
+}
+
+// rewriteCommits rewrites the commit tip and its ancestors, the parents
+// before their children. It walks the history iteratively, so that long
+// histories don't exhaust the stack.
+func (rw *historyRewriter) rewriteCommits(tip plumbing.Hash) (plumbing.Hash, error) {
+	commits := make(map[plumbing.Hash]*object.Commit)
+	stack := []plumbing.Hash{tip}
+	for len(stack) > 0 {
+		h := stack[len(stack)-1]
+		if _, ok := rw.rewrite[h]; ok {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		c, ok := commits[h]
+		if !ok {
+			var err error
+			c, err = object.GetCommit(rw.r.Storer, h)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+
+			commits[h] = c
+			for _, p := range c.ParentHashes {
+				if _, ok := rw.rewrite[p]; !ok {
+					stack = append(stack, p)
+				}
+			}
+
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		if err := rw.rewriteCommit(c); err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		delete(commits, h)
+	}
+
+	return rw.rewrite[tip], nil
+}
+
+// rewriteCommit rewrites c, whose parents are already rewritten.
+func (rw *historyRewriter) rewriteCommit(c *object.Commit) error {
+	nc := *c
+	nc.ParentHashes = make([]plumbing.Hash, len(c.ParentHashes))
+	for i, p := range c.ParentHashes {
+		nc.ParentHashes[i] = rw.rewrite[p]
+	}
+
+	nc.ExtraHeaders = slices.Clone(c.ExtraHeaders)
+
+	if rw.opts.TreeEntryFilter != nil {
+		t, _, err := rw.rewriteTree("", c.TreeHash)
+		if err != nil {
+			return err
+		}
+
+		nc.TreeHash = t
+	}
+
+	if rw.opts.CommitFilter != nil {
+		if err := rw.opts.CommitFilter(&nc); err != nil {
+			return err
+		}
+	}
+
+	if !commitChanged(c, &nc) {
+		rw.rewrite[c.Hash] = c.Hash
+		return nil
+	}
+
+	if nc.Signature == c.Signature {
+		nc.Signature = ""
+	}
+
+	h, err := rw.store(&nc)
+	if err != nil {
+		return err
+	}
+
+	rw.rewrite[c.Hash] = h
+	return nil
+}
+
+// commitChanged returns whether the rewritten commit nc differs from c.
+func commitChanged(c, nc *object.Commit) bool {
+	return c.TreeHash != nc.TreeHash ||
+		!slices.Equal(c.ParentHashes, nc.ParentHashes) ||
+		!signatureEqual(c.Author, nc.Author) ||
+		!signatureEqual(c.Committer, nc.Committer) ||
+		c.Message != nc.Message ||
+		c.MergeTag != nc.MergeTag ||
+		c.Signature != nc.Signature ||
+		c.Encoding != nc.Encoding ||
+		!slices.Equal(c.ExtraHeaders, nc.ExtraHeaders)
+}
+
+func signatureEqual(a, b object.Signature) bool {
+	_, aOffset := a.When.Zone()
+	_, bOffset := b.When.Zone()
+	return a.Name == b.Name && a.Email == b.Email &&
+		a.When.Unix() == b.When.Unix() && aOffset == bOffset
+}
+
+// rewriteTree rewrites the tree h at dir with the TreeEntryFilter, returning
+// its new hash and whether it is empty.
+func (rw *historyRewriter) rewriteTree(dir string, h plumbing.Hash) (plumbing.Hash, bool, error) {
+	key := treeRewriteKey{dir: dir, hash: h}
+	if rt, ok := rw.trees[key]; ok {
+		return rt.hash, rt.empty, nil
+	}
+
+	t, err := object.GetTree(rw.r.Storer, h)
+	if err != nil {
+		return plumbing.ZeroHash, false, err
+	}
+
+	nt := &object.Tree{Entries: make([]object.TreeEntry, 0, len(t.Entries))}
+	for _, e := range t.Entries {
+		p := path.Join(dir, e.Name)
+		if e.Mode == filemode.Dir {
+			sub, empty, err := rw.rewriteTree(p, e.Hash)
+			if err != nil {
+				return plumbing.ZeroHash, false, err
+			}
+
+			if !empty {
+				nt.Entries = append(nt.Entries, object.TreeEntry{Name: e.Name, Mode: e.Mode, Hash: sub})
+			}
+
+			continue
+		}
+
+		ne, err := rw.opts.TreeEntryFilter(p, e)
+		if err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+
+		if ne == nil {
+			continue
+		}
+
+		if ne.Mode == filemode.Dir {
+			return plumbing.ZeroHash, false, fmt.Errorf("%w: %s", ErrRewriteTreeEntryDir, p)
+		}
+
+		nt.Entries = append(nt.Entries, object.TreeEntry{Name: e.Name, Mode: ne.Mode, Hash: ne.Hash})
+	}
+
+	nh := h
+	if !slices.Equal(t.Entries, nt.Entries) {
+		if nh, err = rw.store(nt); err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+	}
+
+	empty := len(nt.Entries) == 0
+	rw.trees[key] = rewrittenTree{hash: nh, empty: empty}
+	return nh, empty, nil
+}
+
+// store encodes and stores o, returning its hash.
+func (rw *historyRewriter) store(o object.Object) (plumbing.Hash, error) {
+	obj := rw.r.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return rw.r.Storer.SetEncodedObject(obj)
+}
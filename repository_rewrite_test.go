@@ -0,0 +1,194 @@
+package git
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestRewriteHistory(t *testing.T) {
+	t.Parallel()
+
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	commit := func(files map[string]string, msg string) plumbing.Hash {
+		for name, content := range files {
+			require.NoError(t, util.WriteFile(fs, name, []byte(content), 0o644))
+			_, err := w.Add(name)
+			require.NoError(t, err)
+		}
+
+		h, err := w.Commit(msg, &CommitOptions{Author: defaultSignature()})
+		require.NoError(t, err)
+		return h
+	}
+
+	first := commit(map[string]string{"README": "readme"}, "first")
+	second := commit(map[string]string{"config/secret.txt": "password", "a.txt": "a"}, "add secret")
+	third := commit(map[string]string{"a.txt": "b"}, "change a")
+
+	_, err = r.CreateTag("v1", third, &CreateTagOptions{Tagger: defaultSignature(), Message: "v1"})
+	require.NoError(t, err)
+	_, err = r.CreateTag("v0", first, nil)
+	require.NoError(t, err)
+
+	mapping, err := r.RewriteHistory(&RewriteOptions{
+		TreeEntryFilter: func(path string, e object.TreeEntry) (*object.TreeEntry, error) {
+			if path == "config/secret.txt" {
+				return nil, nil
+			}
+
+			return &e, nil
+		},
+		CommitFilter: func(c *object.Commit) error {
+			c.Message = strings.ToUpper(c.Message)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.NewBranchReferenceName("master"), head.Name())
+	assert.Equal(t, mapping[third], head.Hash())
+	assert.NotEqual(t, third, head.Hash())
+
+	iter, err := r.Log(&LogOptions{From: head.Hash()})
+	require.NoError(t, err)
+
+	var messages []string
+	require.NoError(t, iter.ForEach(func(c *object.Commit) error {
+		messages = append(messages, c.Message)
+
+		tree, err := c.Tree()
+		require.NoError(t, err)
+		_, err = tree.FindEntry("config")
+		assert.ErrorIs(t, err, object.ErrEntryNotFound)
+		return nil
+	}))
+	assert.Equal(t, []string{"CHANGE A", "ADD SECRET", "FIRST"}, messages)
+
+	c, err := r.CommitObject(mapping[second])
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{mapping[first]}, c.ParentHashes)
+
+	tag, err := r.Tag("v1")
+	require.NoError(t, err)
+	to, err := r.TagObject(tag.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, mapping[third], to.Target)
+
+	tag, err = r.Tag("v0")
+	require.NoError(t, err)
+	assert.Equal(t, mapping[first], tag.Hash())
+}
+
+func TestRewriteHistoryUnchanged(t *testing.T) {
+	t.Parallel()
+
+	r, err := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	var hashes []plumbing.Hash
+	for _, msg := range []string{"A", "B"} {
+		h, err := w.Commit(msg, &CommitOptions{Author: defaultSignature(), AllowEmptyCommits: true})
+		require.NoError(t, err)
+		hashes = append(hashes, h)
+	}
+
+	mapping, err := r.RewriteHistory(&RewriteOptions{
+		TreeEntryFilter: func(_ string, e object.TreeEntry) (*object.TreeEntry, error) {
+			return &e, nil
+		},
+	})
+	require.NoError(t, err)
+
+	for _, h := range hashes {
+		assert.Equal(t, h, mapping[h])
+	}
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	assert.Equal(t, hashes[1], head.Hash())
+}
+
+func TestRewriteHistoryTreeEntryDir(t *testing.T) {
+	t.Parallel()
+
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(fs, "a", []byte("a"), 0o644))
+	_, err = w.Add("a")
+	require.NoError(t, err)
+	_, err = w.Commit("A", &CommitOptions{Author: defaultSignature()})
+	require.NoError(t, err)
+
+	_, err = r.RewriteHistory(&RewriteOptions{
+		TreeEntryFilter: func(_ string, e object.TreeEntry) (*object.TreeEntry, error) {
+			e.Mode = filemode.Dir
+			return &e, nil
+		},
+	})
+	assert.ErrorIs(t, err, ErrRewriteTreeEntryDir)
+}
+
+func TestRewriteHistoryReplacedCommit(t *testing.T) {
+	t.Parallel()
+
+	r, err := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	var hashes []plumbing.Hash
+	for _, msg := range []string{"A", "B"} {
+		h, err := w.Commit(msg, &CommitOptions{Author: defaultSignature(), AllowEmptyCommits: true})
+		require.NoError(t, err)
+		hashes = append(hashes, h)
+	}
+
+	b, err := r.CommitObject(hashes[1])
+	require.NoError(t, err)
+
+	// The replacement of B, a root commit, is not what is rewritten.
+	replacement, err := r.CommitTree(b.TreeHash, nil, &CommitTreeOptions{Author: defaultSignature(), Message: "B'"})
+	require.NoError(t, err)
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(
+		plumbing.ReferenceName("refs/replace/"+hashes[1].String()), replacement)))
+
+	mapping, err := r.RewriteHistory(&RewriteOptions{
+		CommitFilter: func(c *object.Commit) error {
+			c.Message = strings.ToLower(c.Message)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	c, err := object.GetCommit(r.Storer, mapping[hashes[1]])
+	require.NoError(t, err)
+	assert.Equal(t, "b", c.Message)
+	assert.Equal(t, []plumbing.Hash{mapping[hashes[0]]}, c.ParentHashes)
+}
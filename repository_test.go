@@ -1,11 +1,13 @@
 package git
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"os/user"
@@ -27,12 +29,16 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/internal/repository"
 	"github.com/go-git/go-git/v6/internal/server"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
 	formatcfg "github.com/go-git/go-git/v6/plumbing/format/config"
+	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/go-git/go-git/v6/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v6/plumbing/revlist"
 	"github.com/go-git/go-git/v6/plumbing/storer"
 	"github.com/go-git/go-git/v6/plumbing/transport"
 	"github.com/go-git/go-git/v6/storage"
@@ -1038,6 +1044,56 @@ func (s *RepositorySuite) TestMergeFF() {
 	head, err = r.Head()
 	s.NoError(err)
 	s.Equal(fooHash, head.Hash())
+
+	origHead, err := r.OrigHead()
+	s.NoError(err)
+	s.Equal(lastCommit, origHead.Hash())
+}
+
+func (s *RepositorySuite) TestOrigHead() {
+	r, err := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
+	s.Require().NoError(err)
+
+	_, err = r.OrigHead()
+	s.ErrorIs(err, plumbing.ErrReferenceNotFound)
+
+	first := createCommit(s.T(), r)
+	createCommit(s.T(), r)
+	last := createCommit(s.T(), r)
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+	s.Require().NoError(w.Reset(&ResetOptions{Mode: HardReset, Commit: first}))
+
+	origHead, err := r.OrigHead()
+	s.Require().NoError(err)
+	s.Equal(last, origHead.Hash())
+
+	h, err := r.ResolveRevision("ORIG_HEAD~1")
+	s.Require().NoError(err)
+
+	c, err := r.CommitObject(last)
+	s.Require().NoError(err)
+	s.Equal(c.ParentHashes[0], *h)
+
+	// Undoing the reset.
+	s.Require().NoError(w.Reset(&ResetOptions{Mode: HardReset, Commit: origHead.Hash()}))
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	s.Equal(last, head.Hash())
+
+	origHead, err = r.OrigHead()
+	s.Require().NoError(err)
+	s.Equal(first, origHead.Hash())
+
+	// Resetting files and checking out leave ORIG_HEAD as is.
+	s.Require().NoError(w.Reset(&ResetOptions{Mode: MixedReset, Commit: last, Files: []string{"foo.txt"}}))
+	s.Require().NoError(w.Checkout(&CheckoutOptions{Hash: first}))
+
+	origHead, err = r.OrigHead()
+	s.Require().NoError(err)
+	s.Equal(first, origHead.Hash())
 }
 
 func (s *RepositorySuite) TestMergeFF_Invalid() {
@@ -1365,6 +1421,37 @@ func (s *RepositorySuite) TestPlainOpenDetectDotGit() {
 	s.Nil(r)
 }
 
+func (s *RepositorySuite) TestPlainOpenSubmoduleGitDirFile() {
+	dir := s.T().TempDir()
+
+	r, err := PlainInit(filepath.Join(dir, ".git", "modules", "sub"), true)
+	s.Require().NoError(err)
+	s.NotNil(r)
+
+	sub := filepath.Join(dir, "sub")
+	s.Require().NoError(os.MkdirAll(sub, 0o755))
+
+	err = os.WriteFile(filepath.Join(sub, GitDirName), []byte("gitdir: ../.git/modules/sub\n"), 0o644)
+	s.Require().NoError(err)
+
+	file := filepath.Join(sub, "file.txt")
+	s.Require().NoError(os.WriteFile(file, nil, 0o644))
+
+	for _, path := range []string{sub, file, filepath.Join(sub, GitDirName)} {
+		r, err = PlainOpenWithOptions(path, &PlainOpenOptions{DetectDotGit: true})
+		s.NoError(err, path)
+		s.NotNil(r, path)
+	}
+
+	r, err = PlainOpen(filepath.Join(sub, GitDirName))
+	s.NoError(err)
+	s.Require().NotNil(r)
+
+	wt, err := r.Worktree()
+	s.NoError(err)
+	s.Equal(sub, wt.Filesystem.Root())
+}
+
 func (s *RepositorySuite) TestPlainOpenNotExistsDetectDotGit() {
 	dir := s.T().TempDir()
 	opt := &PlainOpenOptions{DetectDotGit: true}
@@ -1734,6 +1821,70 @@ func (s *RepositorySuite) TestFetch() {
 	s.Equal("6ecf0ef2c2dffb796033e5a02219af86ec6584e5", branch.Hash().String())
 }
 
+func (s *RepositorySuite) TestFetchCommit() {
+	r, _ := Init(memory.NewStorage())
+	_, err := r.CreateRemote(&config.RemoteConfig{
+		Name: DefaultRemoteName,
+		URLs: []string{s.GetBasicLocalRepositoryURL()},
+	})
+	s.NoError(err)
+
+	// The local server does not allow the commits which are not a reference
+	// tip to be wanted.
+	err = r.FetchCommit(plumbing.NewHash("35e85108805c84807bc66a02d91535e1e24b38b9"), nil)
+	s.ErrorIs(err, ErrExactSHA1NotSupported)
+
+	head := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	s.NoError(r.FetchCommit(head, &FetchOptions{Depth: 1}))
+
+	commit, err := r.CommitObject(head)
+	s.NoError(err)
+	s.Equal(head, commit.Hash)
+
+	refs, err := r.References()
+	s.NoError(err)
+	s.NoError(refs.ForEach(func(ref *plumbing.Reference) error {
+		s.Equal(plumbing.HEAD, ref.Name())
+		return nil
+	}))
+
+	s.ErrorIs(r.FetchCommit(head, &FetchOptions{Depth: 1}), NoErrAlreadyUpToDate)
+}
+
+func (s *RepositorySuite) TestFetchUpdateRemoteHead() {
+	url := s.GetBasicLocalRepositoryURL()
+	server, err := PlainOpen(url)
+	s.Require().NoError(err)
+
+	r, _ := Init(memory.NewStorage())
+	_, err = r.CreateRemote(&config.RemoteConfig{
+		Name: DefaultRemoteName,
+		URLs: []string{url},
+	})
+	s.Require().NoError(err)
+
+	s.Require().NoError(r.Fetch(&FetchOptions{UpdateRemoteHead: true}))
+
+	remoteHead := plumbing.NewRemoteHEADReferenceName(DefaultRemoteName)
+	target, err := r.SymbolicRef(remoteHead)
+	s.Require().NoError(err)
+	s.Equal(plumbing.NewRemoteReferenceName(DefaultRemoteName, "master"), target)
+
+	s.Require().NoError(server.SetSymbolicRef(plumbing.HEAD, plumbing.NewBranchReferenceName("branch")))
+	s.Require().NoError(r.Fetch(&FetchOptions{UpdateRemoteHead: true}))
+
+	target, err = r.SymbolicRef(remoteHead)
+	s.Require().NoError(err)
+	s.Equal(plumbing.NewRemoteReferenceName(DefaultRemoteName, "branch"), target)
+
+	s.Require().NoError(server.SetSymbolicRef(plumbing.HEAD, plumbing.Master))
+	s.ErrorIs(r.Fetch(&FetchOptions{}), NoErrAlreadyUpToDate)
+
+	target, err = r.SymbolicRef(remoteHead)
+	s.Require().NoError(err)
+	s.Equal(plumbing.NewRemoteReferenceName(DefaultRemoteName, "branch"), target)
+}
+
 func (s *RepositorySuite) TestFetchContext() {
 	r, _ := Init(memory.NewStorage())
 	_, err := r.CreateRemote(&config.RemoteConfig{
@@ -1773,9 +1924,13 @@ func (s *RepositorySuite) TestFetchWithFiltersReal() {
 		Filter: packp.FilterBlobNone(),
 	})
 	s.NoError(err)
-	blob, err := r.BlobObject(plumbing.NewHash("9a48f23120e880dfbe41f7c9b7b708e9ee62a492"))
-	s.NotNil(err)
-	s.Nil(blob)
+	err = r.Storer.HasEncodedObject(plumbing.NewHash("9a48f23120e880dfbe41f7c9b7b708e9ee62a492"))
+	s.ErrorIs(err, plumbing.ErrObjectNotFound)
+
+	cfg, err := r.Config()
+	s.NoError(err)
+	s.Equal(DefaultRemoteName, cfg.Extensions.PartialClone)
+	s.True(cfg.Remotes[DefaultRemoteName].Promisor)
 }
 
 func (s *RepositorySuite) TestCloneWithProgress() {
@@ -2131,9 +2286,8 @@ func (s *RepositorySuite) TestCloneWithFilter() {
 		Filter: packp.FilterTreeDepth(0),
 	})
 	s.Require().NoError(err)
-	blob, err := r.BlobObject(plumbing.NewHash("9a48f23120e880dfbe41f7c9b7b708e9ee62a492"))
-	s.Require().Error(err)
-	s.Nil(blob)
+	err = r.Storer.HasEncodedObject(plumbing.NewHash("9a48f23120e880dfbe41f7c9b7b708e9ee62a492"))
+	s.Require().ErrorIs(err, plumbing.ErrObjectNotFound)
 }
 
 func (s *RepositorySuite) TestPush() {
@@ -2233,12 +2387,56 @@ func (s *RepositorySuite) TestPushWithProgress() {
 	s.Equal([]byte(m), (&p).Bytes())
 }
 
+func (s *RepositorySuite) TestCloneShallowExclude() {
+	server, err := PlainClone(s.T().TempDir(), &CloneOptions{
+		URL: s.GetBasicLocalRepositoryURL(),
+	})
+	s.Require().NoError(err)
+
+	_, err = server.CreateTag("release", plumbing.NewHash("af2d6a6954d532f8ffb47615169c8fdf9d383a1a"), nil)
+	s.Require().NoError(err)
+
+	r, err := Clone(memory.NewStorage(), nil, &CloneOptions{
+		URL:            server.wt.Root(),
+		ReferenceName:  plumbing.Master,
+		SingleBranch:   true,
+		Tags:           plumbing.NoTags,
+		ShallowExclude: []string{"release"},
+	})
+	s.Require().NoError(err)
+
+	shallows, err := r.Storer.Shallow()
+	s.NoError(err)
+	s.Equal([]plumbing.Hash{plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294")}, shallows)
+
+	_, err = r.CommitObject(plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))
+	s.NoError(err)
+
+	_, err = r.CommitObject(plumbing.NewHash("af2d6a6954d532f8ffb47615169c8fdf9d383a1a"))
+	s.ErrorIs(err, plumbing.ErrObjectNotFound)
+}
+
+func (s *RepositorySuite) TestCloneShallowExcludeWithDepth() {
+	_, err := Clone(memory.NewStorage(), nil, &CloneOptions{
+		URL:            s.GetBasicLocalRepositoryURL(),
+		Depth:          1,
+		ShallowExclude: []string{"v1.0.0"},
+	})
+	s.ErrorIs(err, transport.ErrDepthWithDeepenNot)
+}
+
 func (s *RepositorySuite) TestPushDepth() {
 	server, err := PlainClone(s.T().TempDir(), &CloneOptions{
 		URL: s.GetBasicLocalRepositoryURL(),
 	})
 	s.Require().NoError(err)
 
+	// The pushed branch is checked out in the server.
+	cfg, err := server.Config()
+	s.Require().NoError(err)
+	cfg.Receive.DenyCurrentBranch = "ignore"
+	s.Require().NoError(server.SetConfig(cfg))
+
 	r, err := Clone(memory.NewStorage(), memfs.New(), &CloneOptions{
 		URL:   server.wt.Root(),
 		Depth: 1,
@@ -2311,6 +2509,219 @@ func (s *RepositorySuite) TestLog() {
 	s.ErrorIs(err, io.EOF)
 }
 
+func (s *RepositorySuite) TestObjectGraph() {
+	r, _ := Init(memory.NewStorage())
+	err := r.clone(context.Background(), &CloneOptions{
+		URL: s.GetBasicLocalRepositoryURL(),
+	})
+	s.Require().NoError(err)
+
+	graph, err := r.ObjectGraph(&ObjectGraphOptions{Remotes: true})
+	s.Require().NoError(err)
+
+	type line struct {
+		hash string
+		lane int
+	}
+
+	var lines []line
+	for _, n := range graph {
+		lines = append(lines, line{n.Hash.String(), n.Lane})
+	}
+
+	s.Equal([]line{
+		{"6ecf0ef2c2dffb796033e5a02219af86ec6584e5", 0},
+		{"e8d3ffab552895c19b9fcf7aa264d277cde33881", 1},
+		{"918c48b83bd081e863dbe1b80f8998f058cd8294", 0},
+		{"af2d6a6954d532f8ffb47615169c8fdf9d383a1a", 0},
+		{"1669dce138d9b841a518c64b10914d88f5e488ea", 0},
+		{"a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69", 1},
+		{"35e85108805c84807bc66a02d91535e1e24b38b9", 0},
+		{"b8e471f58bcbca63b07bda20e428190409c2db47", 1},
+		{"b029517f6300c2da0f4b651b8642506cd6aaf45d", 0},
+	}, lines)
+
+	s.Equal([]plumbing.ReferenceName{
+		plumbing.HEAD,
+		"refs/heads/master",
+		"refs/remotes/origin/master",
+	}, graph[0].Refs)
+	s.Equal([]plumbing.Hash{
+		plumbing.NewHash("35e85108805c84807bc66a02d91535e1e24b38b9"),
+		plumbing.NewHash("a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69"),
+	}, graph[4].Parents)
+
+	graph, err = r.ObjectGraph(&ObjectGraphOptions{
+		From:  []plumbing.Hash{plumbing.NewHash("e8d3ffab552895c19b9fcf7aa264d277cde33881")},
+		Limit: 2,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(graph, 2)
+	s.Nil(graph[0].Refs)
+	s.Equal("918c48b83bd081e863dbe1b80f8998f058cd8294", graph[1].Hash.String())
+}
+
+func (s *RepositorySuite) TestObjectGraphCommitGraph() {
+	f := fixtures.ByTag("commit-graph").One()
+	dot := f.DotGit(fixtures.WithTargetDir(s.T().TempDir))
+	st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+	p := f.Packfile()
+	defer p.Close()
+	s.Require().NoError(packfile.UpdateObjectStorage(st, p))
+
+	r, err := Open(st, nil)
+	s.Require().NoError(err)
+
+	withGraph, err := r.ObjectGraph(&ObjectGraphOptions{Tags: true, Remotes: true})
+	s.Require().NoError(err)
+	s.NotEmpty(withGraph)
+
+	limited, err := r.ObjectGraph(&ObjectGraphOptions{Tags: true, Remotes: true, Limit: 3})
+	s.Require().NoError(err)
+	s.Equal(withGraph[:3], limited)
+
+	s.Require().NoError(dot.Remove("objects/info/commit-graph"))
+	withoutGraph, err := r.ObjectGraph(&ObjectGraphOptions{Tags: true, Remotes: true})
+	s.Require().NoError(err)
+	s.Equal(withoutGraph, withGraph)
+}
+
+func (s *RepositorySuite) TestObjectGraphCommitGraphAlteredParents() {
+	f := fixtures.ByTag("commit-graph").One()
+	dot := f.DotGit(fixtures.WithTargetDir(s.T().TempDir))
+	st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+	p := f.Packfile()
+	defer p.Close()
+	s.Require().NoError(packfile.UpdateObjectStorage(st, p))
+
+	r, err := Open(st, nil)
+	s.Require().NoError(err)
+
+	compatible, err := r.commitGraphCompatible()
+	s.Require().NoError(err)
+	s.True(compatible)
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+
+	// The commit-graph does not know the graft of HEAD, which has no
+	// parent then.
+	s.Require().NoError(util.WriteFile(dot, "info/grafts", []byte(head.Hash().String()+"\n"), 0o644))
+	graph, err := r.ObjectGraph(&ObjectGraphOptions{From: []plumbing.Hash{head.Hash()}})
+	s.Require().NoError(err)
+	s.Require().Len(graph, 1)
+	s.Nil(graph[0].Parents)
+
+	s.Require().NoError(dot.Remove("info/grafts"))
+	s.Require().NoError(st.SetShallow([]plumbing.Hash{head.Hash()}))
+	compatible, err = r.commitGraphCompatible()
+	s.Require().NoError(err)
+	s.False(compatible)
+}
+
+func (s *RepositorySuite) TestMergeBaseCommitGraph() {
+	f := fixtures.ByTag("commit-graph").One()
+	dot := f.DotGit(fixtures.WithTargetDir(s.T().TempDir))
+	st := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
+	p := f.Packfile()
+	defer p.Close()
+	s.Require().NoError(packfile.UpdateObjectStorage(st, p))
+
+	r, err := Open(st, nil)
+	s.Require().NoError(err)
+
+	iter, err := r.Branches()
+	s.Require().NoError(err)
+
+	var commits []*object.Commit
+	s.Require().NoError(iter.ForEach(func(ref *plumbing.Reference) error {
+		c, err := r.CommitObject(ref.Hash())
+		commits = append(commits, c)
+		return err
+	}))
+	s.Require().Greater(len(commits), 1)
+
+	hashes := func(commits []*object.Commit) []plumbing.Hash {
+		var hs []plumbing.Hash
+		for _, c := range commits {
+			hs = append(hs, c.Hash)
+		}
+
+		return hs
+	}
+
+	for _, a := range commits {
+		for _, b := range commits {
+			want, err := a.MergeBase(b)
+			s.Require().NoError(err)
+
+			got, err := r.mergeBase(a, b)
+			s.Require().NoError(err)
+			s.ElementsMatch(hashes(want), hashes(got), "%s %s", a.Hash, b.Hash)
+		}
+	}
+}
+
+func (s *RepositorySuite) TestLogRange() {
+	r, _ := Init(memory.NewStorage())
+	err := r.clone(context.Background(), &CloneOptions{
+		URL: s.GetBasicLocalRepositoryURL(),
+	})
+	s.Require().NoError(err)
+
+	for _, tc := range []struct {
+		opts     *LogOptions
+		expected []string
+	}{{
+		opts: &LogOptions{Range: "35e85108805c84807bc66a02d91535e1e24b38b9..af2d6a6954d532f8ffb47615169c8fdf9d383a1a"},
+		expected: []string{
+			"af2d6a6954d532f8ffb47615169c8fdf9d383a1a",
+			"1669dce138d9b841a518c64b10914d88f5e488ea",
+			"a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69",
+			"b8e471f58bcbca63b07bda20e428190409c2db47",
+		},
+	}, {
+		opts: &LogOptions{
+			From:     plumbing.NewHash("af2d6a6954d532f8ffb47615169c8fdf9d383a1a"),
+			Excludes: []plumbing.Hash{plumbing.NewHash("35e85108805c84807bc66a02d91535e1e24b38b9")},
+		},
+		expected: []string{
+			"af2d6a6954d532f8ffb47615169c8fdf9d383a1a",
+			"1669dce138d9b841a518c64b10914d88f5e488ea",
+			"a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69",
+			"b8e471f58bcbca63b07bda20e428190409c2db47",
+		},
+	}, {
+		opts: &LogOptions{Range: "a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69.."},
+		expected: []string{
+			"6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+			"918c48b83bd081e863dbe1b80f8998f058cd8294",
+			"af2d6a6954d532f8ffb47615169c8fdf9d383a1a",
+			"1669dce138d9b841a518c64b10914d88f5e488ea",
+			"35e85108805c84807bc66a02d91535e1e24b38b9",
+		},
+	}, {
+		opts: &LogOptions{Range: "master...origin/branch"},
+		expected: []string{
+			"e8d3ffab552895c19b9fcf7aa264d277cde33881",
+			"6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+		},
+	}} {
+		iter, err := r.Log(tc.opts)
+		s.Require().NoError(err)
+
+		var hashes []string
+		s.Require().NoError(iter.ForEach(func(c *object.Commit) error {
+			hashes = append(hashes, c.Hash.String())
+			return nil
+		}))
+		s.Equal(tc.expected, hashes, tc.opts.Range)
+	}
+
+	_, err = r.Log(&LogOptions{Range: "master"})
+	s.ErrorIs(err, ErrInvalidRange)
+}
+
 func (s *RepositorySuite) TestLogAll() {
 	r, _ := Init(memory.NewStorage())
 	err := r.clone(context.Background(), &CloneOptions{
@@ -2894,6 +3305,75 @@ func (s *RepositorySuite) TestLogLimitWithOtherParamsPass() {
 	s.Equal(io.EOF, iterErr)
 }
 
+func (s *RepositorySuite) TestLogContentFilters() {
+	r, err := Open(filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault()), nil)
+	s.Require().NoError(err)
+
+	for _, tc := range []struct {
+		opts     LogOptions
+		expected []string
+	}{{
+		opts:     LogOptions{Author: regexp.MustCompile("Ripolles")},
+		expected: []string{"b8e471f58bcbca63b07bda20e428190409c2db47"},
+	}, {
+		opts:     LogOptions{Committer: regexp.MustCompile(`@lordran\.local>$`)},
+		expected: []string{"b8e471f58bcbca63b07bda20e428190409c2db47"},
+	}, {
+		opts: LogOptions{MessageGrep: []*regexp.Regexp{regexp.MustCompile("^some")}},
+		expected: []string{
+			"918c48b83bd081e863dbe1b80f8998f058cd8294",
+			"af2d6a6954d532f8ffb47615169c8fdf9d383a1a",
+		},
+	}, {
+		opts: LogOptions{
+			Author:      regexp.MustCompile("Ripolles"),
+			MessageGrep: []*regexp.Regexp{regexp.MustCompile("json")},
+		},
+		expected: nil,
+	}, {
+		opts: LogOptions{
+			Author:      regexp.MustCompile("Ortiz"),
+			MessageGrep: []*regexp.Regexp{regexp.MustCompile("json")},
+		},
+		expected: []string{"af2d6a6954d532f8ffb47615169c8fdf9d383a1a"},
+	}, {
+		opts: LogOptions{
+			MessageGrep: []*regexp.Regexp{regexp.MustCompile("code"), regexp.MustCompile("json")},
+		},
+		expected: []string{
+			"918c48b83bd081e863dbe1b80f8998f058cd8294",
+			"af2d6a6954d532f8ffb47615169c8fdf9d383a1a",
+		},
+	}, {
+		opts: LogOptions{
+			MessageGrep: []*regexp.Regexp{regexp.MustCompile("^some"), regexp.MustCompile("json")},
+			AllMatch:    true,
+		},
+		expected: []string{"af2d6a6954d532f8ffb47615169c8fdf9d383a1a"},
+	}, {
+		opts: LogOptions{
+			MessageGrep: []*regexp.Regexp{regexp.MustCompile("^some")},
+			To:          plumbing.NewHash("35e85108805c84807bc66a02d91535e1e24b38b9"),
+			Order:       LogOrderCommitterTime,
+		},
+		expected: []string{
+			"918c48b83bd081e863dbe1b80f8998f058cd8294",
+			"af2d6a6954d532f8ffb47615169c8fdf9d383a1a",
+		},
+	}} {
+		it, err := r.Log(&tc.opts)
+		s.Require().NoError(err)
+
+		var hashes []string
+		err = it.ForEach(func(c *object.Commit) error {
+			hashes = append(hashes, c.Hash.String())
+			return nil
+		})
+		s.Require().NoError(err)
+		s.Equal(tc.expected, hashes)
+	}
+}
+
 func (s *RepositorySuite) TestConfigScoped() {
 	r, _ := Init(memory.NewStorage())
 	err := r.clone(context.Background(), &CloneOptions{
@@ -2979,6 +3459,29 @@ func (s *RepositorySuite) TestBlob() {
 	s.Equal(plumbing.BlobObject, blob.Type())
 }
 
+func (s *RepositorySuite) TestDiffBlobs() {
+	r, _ := Init(memory.NewStorage())
+	err := r.clone(context.Background(), &CloneOptions{URL: s.GetBasicLocalRepositoryURL()})
+	s.Require().NoError(err)
+
+	gitignore := plumbing.NewHash("32858aad3c383ed1ff0a0f9bdf231d54a00c9e88")
+	changelog := plumbing.NewHash("d3ff53e0564a9f87d8e84b6e28e5060e517008aa")
+
+	p, err := r.DiffBlobs(gitignore, changelog)
+	s.Require().NoError(err)
+	s.Require().Len(p.FilePatches(), 1)
+	s.Contains(p.String(), "-*.class\n")
+	s.Contains(p.String(), "+Initial changelog\n")
+
+	p, err = r.DiffBlobs(plumbing.ZeroHash, changelog)
+	s.Require().NoError(err)
+	s.Contains(p.String(), "new file mode 100644\n")
+	s.Contains(p.String(), "@@ -0,0 +1 @@\n+Initial changelog\n")
+
+	_, err = r.DiffBlobs(gitignore, plumbing.NewHash("0000000000000000000000000000000000000001"))
+	s.ErrorIs(err, plumbing.ErrObjectNotFound)
+}
+
 func (s *RepositorySuite) TestBlobs() {
 	r, _ := Init(memory.NewStorage())
 	err := r.clone(context.Background(), &CloneOptions{URL: s.GetBasicLocalRepositoryURL()})
@@ -3521,6 +4024,32 @@ func (s *RepositorySuite) TestObject() {
 	s.Equal(plumbing.CommitObject, o.Type())
 }
 
+func (s *RepositorySuite) TestShortStatAndNumStat() {
+	r, _ := Init(memory.NewStorage())
+	err := r.clone(context.Background(), &CloneOptions{URL: s.GetBasicLocalRepositoryURL()})
+	s.Require().NoError(err)
+
+	stat, err := r.ShortStat(plumbing.ZeroHash, plumbing.NewHash("918c48b83bd081e863dbe1b80f8998f058cd8294"))
+	s.Require().NoError(err)
+	s.Equal(" 2 files changed, 401 insertions(+)\n", stat)
+
+	stat, err = r.NumStat(
+		plumbing.NewHash("b029517f6300c2da0f4b651b8642506cd6aaf45d"),
+		plumbing.NewHash("a8d315b2b1c615d43042c3a62402b8a54288cf5c"),
+	)
+	s.Require().NoError(err)
+	s.Equal("1\t0\tCHANGELOG\n"+
+		"-\t-\tbinary.jpg\n"+
+		"142\t0\tgo/example.go\n"+
+		"6492\t0\tjson/long.json\n"+
+		"22\t0\tjson/short.json\n"+
+		"259\t0\tphp/crappy.php\n"+
+		"7\t0\tvendor/foo.go\n", stat)
+
+	_, err = r.ShortStat(plumbing.ZeroHash, plumbing.NewHash("d3ff53e0564a9f87d8e84b6e28e5060e517008aa"))
+	s.ErrorIs(err, plumbing.ErrInvalidType)
+}
+
 func (s *RepositorySuite) TestObjects() {
 	r, _ := Init(memory.NewStorage())
 	err := r.clone(context.Background(), &CloneOptions{URL: s.GetBasicLocalRepositoryURL()})
@@ -3720,6 +4249,162 @@ func (s *RepositorySuite) TestRepackObjectsWithNoDelete() {
 	s.testRepackObjects(time.Unix(0, 1), 3)
 }
 
+func TestRepackObjectsCruft(t *testing.T) {
+	t.Parallel()
+
+	fs := fixtures.Basic().One().DotGit(fixtures.WithTargetDir(t.TempDir))
+	sto := filesystem.NewStorage(fs, cache.NewObjectLRUDefault())
+	r, err := Open(sto, nil)
+	require.NoError(t, err)
+
+	// Two unreachable loose blobs, an expired and a recent one.
+	now := time.Now().Truncate(time.Second)
+	blobs := make(map[string]plumbing.Hash)
+	for name, mtime := range map[string]time.Time{
+		"expired": now.Add(-30 * 24 * time.Hour),
+		"recent":  now.Add(-time.Hour),
+	} {
+		obj := sto.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		require.NoError(t, err)
+		_, err = w.Write([]byte(name))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		h, err := sto.SetEncodedObject(obj)
+		require.NoError(t, err)
+		blobs[name] = h
+
+		path := filepath.Join(fs.Root(), "objects", h.String()[:2], h.String()[2:])
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	err = r.RepackObjects(&RepackConfig{
+		Cruft:           true,
+		CruftExpiration: now.Add(-14 * 24 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	packs, err := sto.ObjectPacks()
+	require.NoError(t, err)
+	assert.Len(t, packs, 2)
+
+	err = sto.ForEachObjectHash(func(h plumbing.Hash) error {
+		return fmt.Errorf("unexpected loose object %s", h)
+	})
+	require.NoError(t, err)
+
+	sto = filesystem.NewStorage(fs, cache.NewObjectLRUDefault())
+	mtime, err := sto.ObjectTime(blobs["recent"])
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour), mtime)
+
+	_, err = sto.EncodedObject(plumbing.BlobObject, blobs["expired"])
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+
+	// The reachable objects are still there, and the recent blob is dropped
+	// once expired.
+	r, err = Open(sto, nil)
+	require.NoError(t, err)
+	_, err = r.CommitObject(plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"))
+	require.NoError(t, err)
+
+	err = r.RepackObjects(&RepackConfig{Cruft: true, CruftExpiration: now})
+	require.NoError(t, err)
+
+	packs, err = sto.ObjectPacks()
+	require.NoError(t, err)
+	assert.Len(t, packs, 1)
+
+	_, err = sto.EncodedObject(plumbing.BlobObject, blobs["recent"])
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+
+	r, err = Init(memory.NewStorage())
+	require.NoError(t, err)
+	err = r.RepackObjects(&RepackConfig{Cruft: true})
+	assert.ErrorIs(t, err, ErrCruftPacksNotSupported)
+}
+
+func TestRepositoryCountObjects(t *testing.T) {
+	t.Parallel()
+
+	fs := fixtures.Basic().One().DotGit()
+	r, err := Open(filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	c, err := r.CountObjects()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), c.Count)
+	assert.Equal(t, int64(1), c.Packs)
+	assert.Equal(t, int64(31), c.InPack)
+	assert.Equal(t, int64(86734), c.SizePack)
+	assert.Equal(t, int64(0), c.Garbage)
+
+	// A new loose object and a loose copy of a packed one.
+	packed, err := r.Storer.EncodedObject(plumbing.AnyObject,
+		plumbing.NewHash("32858aad3c383ed1ff0a0f9bdf231d54a00c9e88"))
+	require.NoError(t, err)
+	_, err = r.Storer.SetEncodedObject(packed)
+	require.NoError(t, err)
+
+	obj := r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	require.NoError(t, err)
+	_, err = w.Write([]byte("foo"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	_, err = r.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(fs, "objects/pack/tmp_pack_foo", []byte("foo"), 0o644))
+
+	c, err = r.CountObjects()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), c.Count)
+	assert.Positive(t, c.Size)
+	assert.Equal(t, int64(1), c.PrunePackable)
+	assert.Equal(t, int64(1), c.Garbage)
+	assert.Equal(t, int64(3), c.SizeGarbage)
+
+	r, err = Init(memory.NewStorage())
+	require.NoError(t, err)
+	_, err = r.CountObjects()
+	assert.ErrorIs(t, err, ErrCountObjectsNotSupported)
+}
+
+func TestRepositoryVerifyPack(t *testing.T) {
+	t.Parallel()
+
+	f := fixtures.Basic().One()
+	r, err := Open(filesystem.NewStorage(f.DotGit(), cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	for _, name := range []string{
+		f.PackfileHash,
+		"pack-" + f.PackfileHash + ".pack",
+		".git/objects/pack/pack-" + f.PackfileHash + ".idx",
+	} {
+		report, err := r.VerifyPack(name)
+		require.NoError(t, err)
+		assert.Equal(t, plumbing.NewHash(f.PackfileHash), report.Checksum)
+		assert.Len(t, report.Objects, 31)
+		assert.Equal(t, []int{23, 3, 4, 1}, report.ChainLengths)
+	}
+
+	_, err = r.VerifyPack("pack-foo.pack")
+	assert.ErrorIs(t, err, ErrInvalidPackName)
+
+	_, err = r.VerifyPack("0000000000000000000000000000000000000000")
+	assert.Error(t, err)
+
+	r, err = Init(memory.NewStorage())
+	require.NoError(t, err)
+	_, err = r.VerifyPack(f.PackfileHash)
+	assert.ErrorIs(t, err, ErrVerifyPackNotSupported)
+}
+
 func ExecuteOnPath(t *testing.T, path string, cmds ...string) error {
 	for _, cmd := range cmds {
 		err := executeOnPath(path, cmd)
@@ -4018,3 +4703,322 @@ func TestCreateTagSignerSelection(t *testing.T) { //nolint:paralleltest // modif
 		})
 	}
 }
+
+func TestRepositoryCheckConnectivity(t *testing.T) {
+	t.Parallel()
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+
+	// A tree referencing a blob which is not stored.
+	blob := plumbing.NewHash("d3ff53e0564a9f87d8e84b6e28e5060e517008aa")
+	tree := &object.Tree{Entries: []object.TreeEntry{
+		{Name: "foo", Mode: filemode.Regular, Hash: blob},
+	}}
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(t, tree.Encode(obj))
+	treeHash, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+
+	err = r.CheckConnectivity([]plumbing.Hash{treeHash})
+	var missing *revlist.MissingObjectError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, blob, missing.Hash)
+	assert.Equal(t, "foo", missing.Path)
+
+	fs := fixtures.Basic().One().DotGit()
+	r, err = Open(filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	assert.NoError(t, r.CheckConnectivity([]plumbing.Hash{head.Hash()}))
+}
+
+func TestRepositoryCommitTree(t *testing.T) {
+	t.Parallel()
+
+	fs := fixtures.Basic().One().DotGit()
+	r, err := Open(filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	parent, err := r.CommitObject(head.Hash())
+	require.NoError(t, err)
+
+	sig := &object.Signature{Name: "foo", Email: "foo@foo.foo", When: time.Now().Truncate(time.Second)}
+	signer := &mockSigner{}
+	h, err := r.CommitTree(parent.TreeHash, []plumbing.Hash{parent.Hash}, &CommitTreeOptions{
+		Author:  sig,
+		Message: "foo\n",
+		Signer:  signer,
+	})
+	require.NoError(t, err)
+
+	c, err := r.CommitObject(h)
+	require.NoError(t, err)
+	assert.Equal(t, parent.TreeHash, c.TreeHash)
+	assert.Equal(t, []plumbing.Hash{parent.Hash}, c.ParentHashes)
+	assert.Equal(t, "foo\n", c.Message)
+	assert.Equal(t, sig.Name, c.Committer.Name)
+	assert.True(t, signer.called)
+	assert.Equal(t, mockSignature, strings.TrimSpace(c.Signature))
+
+	// No reference is updated.
+	ref, err := r.Head()
+	require.NoError(t, err)
+	assert.Equal(t, head.Hash(), ref.Hash())
+
+	_, err = r.CommitTree(parent.Hash, nil, &CommitTreeOptions{Author: sig})
+	assert.Error(t, err)
+
+	_, err = r.CommitTree(parent.TreeHash, []plumbing.Hash{parent.TreeHash}, &CommitTreeOptions{Author: sig})
+	assert.Error(t, err)
+}
+
+func TestRepositorySymbolicRef(t *testing.T) {
+	t.Parallel()
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+
+	target, err := r.SymbolicRef(plumbing.HEAD)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.Master, target)
+
+	originHead := plumbing.NewRemoteHEADReferenceName(DefaultRemoteName)
+	originMain := plumbing.NewRemoteReferenceName(DefaultRemoteName, "main")
+	_, err = r.SymbolicRef(originHead)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+
+	require.NoError(t, r.SetSymbolicRef(originHead, originMain))
+	target, err = r.SymbolicRef(originHead)
+	require.NoError(t, err)
+	assert.Equal(t, originMain, target)
+
+	require.NoError(t, r.SetSymbolicRef(plumbing.HEAD, plumbing.Main))
+	target, err = r.SymbolicRef(plumbing.HEAD)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.Main, target)
+
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(originMain, plumbing.ZeroHash)))
+	_, err = r.SymbolicRef(originMain)
+	assert.ErrorIs(t, err, ErrNotSymbolicRef)
+
+	err = r.SetSymbolicRef(plumbing.HEAD, "foo/bar")
+	assert.ErrorIs(t, err, ErrInvalidSymbolicRefTarget)
+	err = r.SetSymbolicRef(originHead, "refs/heads/foo..bar")
+	assert.ErrorIs(t, err, plumbing.ErrInvalidReferenceName)
+}
+
+func TestRepositoryRefsPointingAt(t *testing.T) {
+	t.Parallel()
+
+	fs := fixtures.ByTag("tags").One().DotGit()
+	r, err := Open(filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	// commit-tag and annotated-tag point at the commit through annotated
+	// tags, lightweight-tag directly, and origin/HEAD through origin/master.
+	commit := plumbing.NewHash("f7b877701fbf855b44c0a9e86f3fdce2c298b07f")
+	refs, err := r.RefsPointingAt(commit)
+	require.NoError(t, err)
+
+	var names []string
+	for _, ref := range refs {
+		names = append(names, ref.Name().String())
+	}
+
+	assert.Equal(t, []string{
+		"refs/heads/master",
+		"refs/remotes/origin/HEAD",
+		"refs/remotes/origin/master",
+		"refs/tags/annotated-tag",
+		"refs/tags/commit-tag",
+		"refs/tags/lightweight-tag",
+	}, names)
+	assert.Equal(t, plumbing.SymbolicReference, refs[1].Type())
+
+	tag, err := r.TagObject(refs[4].Hash())
+	require.NoError(t, err)
+	refs, err = r.RefsPointingAt(tag.Hash)
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+	assert.Equal(t, plumbing.ReferenceName("refs/tags/commit-tag"), refs[0].Name())
+
+	refs, err = r.RefsPointingAt(plumbing.NewHash("0000000000000000000000000000000000000001"))
+	require.NoError(t, err)
+	assert.Empty(t, refs)
+}
+
+func TestRepositoryTagChain(t *testing.T) {
+	t.Parallel()
+
+	fs := fixtures.ByTag("tags").One().DotGit()
+	r, err := Open(filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	// outer is a tag of annotated-tag, itself a tag of the commit.
+	inner := plumbing.NewHash("b742a2a9fa0afcfa9a6fad080980fbc26b007c69")
+	commit := plumbing.NewHash("f7b877701fbf855b44c0a9e86f3fdce2c298b07f")
+	outer, err := r.CreateTag("outer", inner, &CreateTagOptions{
+		Tagger:  defaultSignature(),
+		Message: "tag of a tag",
+	})
+	require.NoError(t, err)
+
+	h, err := r.ResolveRevision("outer")
+	require.NoError(t, err)
+	assert.Equal(t, commit, *h)
+
+	h, err = r.ResolveRevision("outer~0")
+	require.NoError(t, err)
+	assert.Equal(t, commit, *h)
+
+	refs, err := r.RefsPointingAt(commit)
+	require.NoError(t, err)
+	assert.Contains(t, refs, outer)
+
+	var buf bytes.Buffer
+	require.NoError(t, repository.WriteInfoRefs(&buf, r.Storer))
+	assert.Contains(t, buf.String(), commit.String()+"\trefs/tags/outer^{}\n")
+}
+
+func TestRepositoryAbbreviateHash(t *testing.T) {
+	t.Parallel()
+
+	r, err := Init(memory.NewStorage())
+	require.NoError(t, err)
+
+	// Store blobs until two of them share the first 4 digits of their hashes.
+	seen := make(map[string]plumbing.Hash)
+	var a, b plumbing.Hash
+	for i := 0; b.IsZero(); i++ {
+		obj := r.Storer.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		require.NoError(t, err)
+		_, err = fmt.Fprintf(w, "blob %d\n", i)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		h, err := r.Storer.SetEncodedObject(obj)
+		require.NoError(t, err)
+
+		if other, ok := seen[h.String()[:4]]; ok {
+			a, b = other, h
+		}
+
+		seen[h.String()[:4]] = h
+	}
+
+	common := 4
+	for a.String()[common] == b.String()[common] {
+		common++
+	}
+
+	short, err := r.AbbreviateHash(a, 4)
+	require.NoError(t, err)
+	assert.Equal(t, a.String()[:max(common+1, 4)], short)
+
+	short, err = r.AbbreviateHash(a, 12)
+	require.NoError(t, err)
+	assert.Equal(t, a.String()[:max(common+1, 12)], short)
+
+	short, err = r.AbbreviateHash(a, 0)
+	require.NoError(t, err)
+	assert.Equal(t, a.String()[:max(common+1, 7)], short)
+
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	cfg.Core.Abbrev = "10"
+	require.NoError(t, r.SetConfig(cfg))
+
+	short, err = r.AbbreviateHash(a, 0)
+	require.NoError(t, err)
+	assert.Equal(t, a.String()[:max(common+1, 10)], short)
+
+	h, err := r.ExpandHash(a.String()[:common+1])
+	require.NoError(t, err)
+	assert.Equal(t, a, h)
+
+	h, err = r.ExpandHash(strings.ToUpper(b.String()[:common+1]))
+	require.NoError(t, err)
+	assert.Equal(t, b, h)
+
+	h, err = r.ExpandHash(b.String())
+	require.NoError(t, err)
+	assert.Equal(t, b, h)
+
+	_, err = r.ExpandHash(a.String()[:common])
+	assert.ErrorIs(t, err, ErrAmbiguousHashPrefix)
+
+	_, err = r.ExpandHash("abc")
+	assert.ErrorIs(t, err, ErrInvalidHashPrefix)
+
+	_, err = r.ExpandHash("abcg")
+	assert.ErrorIs(t, err, ErrInvalidHashPrefix)
+
+	_, err = r.ExpandHash(strings.Repeat("0", 40))
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+}
+
+func TestOpenFS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	r, err := PlainInit(dir, false)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo"), []byte("foo\n"), 0o644))
+	w, err := r.Worktree()
+	require.NoError(t, err)
+	_, err = w.Add("foo")
+	require.NoError(t, err)
+	commit, err := w.Commit("foo\n", &CommitOptions{Author: defaultSignature()})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	require.NoError(t, zw.AddFS(os.DirFS(dir)))
+	require.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	for name, fsys := range map[string]fs.FS{
+		"dir":    os.DirFS(dir),
+		"zip":    zr,
+		"gitdir": os.DirFS(filepath.Join(dir, GitDirName)),
+	} {
+		r, err := OpenFS(fsys)
+		require.NoError(t, err, name)
+
+		head, err := r.Head()
+		require.NoError(t, err, name)
+		assert.Equal(t, commit, head.Hash(), name)
+
+		c, err := r.CommitObject(head.Hash())
+		require.NoError(t, err, name)
+		f, err := c.File("foo")
+		require.NoError(t, err, name)
+		content, err := f.Contents()
+		require.NoError(t, err, name)
+		assert.Equal(t, "foo\n", content, name)
+
+		idx, err := r.Storer.Index()
+		require.NoError(t, err, name)
+		require.Len(t, idx.Entries, 1, name)
+		assert.Equal(t, "foo", idx.Entries[0].Name, name)
+
+		_, err = r.Worktree()
+		assert.ErrorIs(t, err, ErrIsBareRepository, name)
+
+		_, err = r.CreateTag("v1", commit, nil)
+		assert.Error(t, err, name)
+	}
+
+	_, err = OpenFS(os.DirFS(t.TempDir()))
+	assert.ErrorIs(t, err, ErrRepositoryNotExists)
+}
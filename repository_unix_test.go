@@ -3,11 +3,18 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/x/plugin"
+	xconfig "github.com/go-git/go-git/v6/x/plugin/config"
 )
 
 // preReceiveHook returns the bytes of a pre-receive hook script
@@ -26,3 +33,68 @@ func TestPlainInitFileMode(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, cfg.Core.FileMode)
 }
+
+func TestPlainOpenCheckOwnership(t *testing.T) { //nolint:paralleltest // modifies global plugin state
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a directory requires root")
+	}
+
+	t.Setenv("SUDO_UID", "")
+
+	dir := t.TempDir()
+	_, err := PlainInit(dir, false)
+	require.NoError(t, err)
+
+	_, err = PlainOpenWithOptions(dir, &PlainOpenOptions{CheckOwnership: true})
+	require.NoError(t, err)
+
+	require.NoError(t, os.Chown(dir, 12345, 12345))
+
+	_, err = PlainOpenWithOptions(dir, nil)
+	require.NoError(t, err)
+
+	_, err = PlainOpenWithOptions(dir, &PlainOpenOptions{CheckOwnership: true})
+	require.ErrorIs(t, err, ErrDubiousOwnership)
+
+	var ownershipErr *DubiousOwnershipError
+	require.True(t, errors.As(err, &ownershipErr))
+	assert.Equal(t, dir, ownershipErr.Path)
+
+	t.Setenv("SUDO_UID", "12345")
+	_, err = PlainOpenWithOptions(dir, &PlainOpenOptions{CheckOwnership: true})
+	require.NoError(t, err)
+	t.Setenv("SUDO_UID", "")
+
+	global := config.NewConfig()
+	global.Safe.Directories = []string{dir}
+	resetPluginEntry("config-loader")
+	t.Cleanup(registerTestConfigLoader)
+	require.NoError(t, plugin.Register(plugin.ConfigLoader(), func() plugin.ConfigSource {
+		return xconfig.NewStatic(*global, *config.NewConfig())
+	}))
+
+	_, err = PlainOpenWithOptions(filepath.Join(dir, ".git"), &PlainOpenOptions{CheckOwnership: true, DetectDotGit: true})
+	require.NoError(t, err)
+}
+
+func TestIsSafeDirectory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path string
+		dirs []string
+		want bool
+	}{
+		{"/srv/repo", nil, false},
+		{"/srv/repo", []string{"/srv/other"}, false},
+		{"/srv/repo", []string{"/srv/other", "/srv/repo/"}, true},
+		{"/srv/repo", []string{"*"}, true},
+		{"/srv/repo", []string{"/srv/*"}, true},
+		{"/srv", []string{"/srv/*"}, false},
+		{"/srv/repository", []string{"/srv/repo/*"}, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isSafeDirectory(tt.path, tt.dirs), "%s %v", tt.path, tt.dirs)
+	}
+}
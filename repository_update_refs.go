@@ -0,0 +1,391 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+var (
+	// ErrInvalidRefUpdate is returned by Repository.UpdateRefs when a command
+	// can't be parsed, or is not allowed at that point of the transaction.
+	ErrInvalidRefUpdate = errors.New("invalid ref update")
+	// ErrRefUpdateRejected is returned when an update of a transaction can't
+	// be applied, such as when the reference doesn't have its expected old
+	// value. None of the updates of the transaction are applied then.
+	ErrRefUpdateRejected = errors.New("ref update rejected")
+)
+
+// RefUpdate is an update of a reference made by Repository.UpdateReferences,
+// as the commands of git update-ref --stdin.
+type RefUpdate struct {
+	// Name is the name of the reference.
+	Name plumbing.ReferenceName
+	// New is the value the reference is set to, the zero hash deleting it.
+	New plumbing.Hash
+	// Old, if not nil, is the value the reference must have for the update
+	// to be applied, the zero hash meaning that it must not exist.
+	Old *plumbing.Hash
+	// Verify only checks that the reference has its Old value, without
+	// updating it.
+	Verify bool
+	// NoDeref updates the reference itself when it is symbolic, instead of
+	// the reference it points to.
+	NoDeref bool
+}
+
+// preparedRefUpdate is a RefUpdate whose old value was checked.
+type preparedRefUpdate struct {
+	RefUpdate
+	// target is the name of the reference updated, once dereferenced.
+	target plumbing.ReferenceName
+	// current is the value of the reference, nil if it doesn't exist.
+	current *plumbing.Reference
+}
+
+// UpdateReferences applies the updates of the references atomically: either
+// all of them are applied, or none if any is rejected, in which case an
+// error wrapping ErrRefUpdateRejected is returned. The storage not
+// supporting transactions of references, the updates already applied are
+// rolled back when one fails to be stored.
+func (r *Repository) UpdateReferences(updates []RefUpdate) error {
+	prepared, err := r.prepareRefUpdates(updates)
+	if err != nil {
+		return err
+	}
+
+	return r.commitRefUpdates(prepared)
+}
+
+// UpdateRefs reads the commands of git update-ref --stdin from rd, one per
+// line, and applies them:
+//
+//	update SP <ref> SP <new> [SP <old>] LF
+//	create SP <ref> SP <new> LF
+//	delete SP <ref> [SP <old>] LF
+//	verify SP <ref> [SP <old>] LF
+//	option SP no-deref LF
+//	start LF
+//	prepare LF
+//	commit LF
+//	abort LF
+//
+// The values are hashes or revisions, an empty or zero value meaning the
+// reference doesn't exist. The commands are applied atomically by
+// transactions, opened by start, checked by prepare and applied by commit or
+// discarded by abort. Without start, all of them are applied at the end of
+// rd as a single transaction, and a transaction left open is discarded.
+func (r *Repository) UpdateRefs(rd io.Reader) error {
+	var (
+		updates  []RefUpdate
+		prepared []preparedRefUpdate
+		started  bool
+		noDeref  bool
+	)
+
+	s := bufio.NewScanner(rd)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		fields := strings.Split(line, " ")
+		cmd, args := fields[0], fields[1:]
+
+		if prepared != nil && cmd != "commit" && cmd != "abort" {
+			return fmt.Errorf("%w: line %d: %q after prepare", ErrInvalidRefUpdate, n, line)
+		}
+
+		switch cmd {
+		case "start":
+			if started || len(updates) > 0 {
+				return fmt.Errorf("%w: line %d: transaction already started", ErrInvalidRefUpdate, n)
+			}
+
+			started = true
+		case "prepare":
+			var err error
+			if prepared, err = r.prepareRefUpdates(updates); err != nil {
+				return err
+			}
+		case "commit":
+			if prepared == nil {
+				var err error
+				if prepared, err = r.prepareRefUpdates(updates); err != nil {
+					return err
+				}
+			}
+
+			if err := r.commitRefUpdates(prepared); err != nil {
+				return err
+			}
+
+			updates, prepared, started = nil, nil, false
+		case "abort":
+			updates, prepared, started = nil, nil, false
+		case "option":
+			if len(args) != 1 || args[0] != "no-deref" {
+				return fmt.Errorf("%w: line %d: unsupported option %q", ErrInvalidRefUpdate, n, line)
+			}
+
+			noDeref = true
+			continue
+		case "update", "create", "delete", "verify":
+			u, err := r.parseRefUpdate(cmd, args)
+			if err != nil {
+				return fmt.Errorf("%w: line %d: %w", ErrInvalidRefUpdate, n, err)
+			}
+
+			u.NoDeref = noDeref
+			updates = append(updates, u)
+		default:
+			return fmt.Errorf("%w: line %d: unknown command %q", ErrInvalidRefUpdate, n, cmd)
+		}
+
+		noDeref = false
+	}
+
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	if started {
+		return nil
+	}
+
+	return r.UpdateReferences(updates)
+}
+
+// parseRefUpdate parses the arguments of an update, create, delete or verify
+// command.
+func (r *Repository) parseRefUpdate(cmd string, args []string) (RefUpdate, error) {
+	minArgs, maxArgs := 1, 2
+	switch cmd {
+	case "update":
+		minArgs, maxArgs = 2, 3
+	case "create":
+		minArgs = 2
+	}
+
+	if len(args) < minArgs || len(args) > maxArgs {
+		return RefUpdate{}, fmt.Errorf("%s: wrong number of arguments", cmd)
+	}
+
+	u := RefUpdate{Name: plumbing.ReferenceName(args[0]), Verify: cmd == "verify"}
+	if err := u.Name.Validate(); err != nil {
+		return u, err
+	}
+
+	values := args[1:]
+	if cmd == "update" || cmd == "create" {
+		h, err := r.refUpdateValue(values[0])
+		if err != nil {
+			return u, err
+		}
+
+		if cmd == "create" && h.IsZero() {
+			return u, errors.New("create: zero new value")
+		}
+
+		u.New, values = h, values[1:]
+	}
+
+	switch {
+	case cmd == "create", cmd == "verify" && len(values) == 0:
+		zero := plumbing.ZeroHash
+		u.Old = &zero
+	case len(values) > 0:
+		h, err := r.refUpdateValue(values[0])
+		if err != nil {
+			return u, err
+		}
+
+		u.Old = &h
+	}
+
+	return u, nil
+}
+
+// refUpdateValue resolves a value of an update-ref command, which is a hash
+// or a revision, and the zero hash if empty.
+func (r *Repository) refUpdateValue(v string) (plumbing.Hash, error) {
+	if v == "" {
+		return plumbing.ZeroHash, nil
+	}
+
+	if h, ok := plumbing.FromHex(v); ok {
+		return h, nil
+	}
+
+	h, err := r.ResolveRevision(plumbing.Revision(v))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("invalid value %q: %w", v, err)
+	}
+
+	return *h, nil
+}
+
+// prepareRefUpdates checks the old values of the updates, and that the new
+// values exist, returning the references they update.
+func (r *Repository) prepareRefUpdates(updates []RefUpdate) ([]preparedRefUpdate, error) {
+	prepared := make([]preparedRefUpdate, 0, len(updates))
+	targets := make(map[plumbing.ReferenceName]bool, len(updates))
+	for _, u := range updates {
+		p := preparedRefUpdate{RefUpdate: u, target: u.Name}
+		if !u.NoDeref {
+			p.target = r.derefRefName(u.Name)
+		}
+
+		if targets[p.target] {
+			return nil, fmt.Errorf("%w: multiple updates of %s", ErrRefUpdateRejected, p.target)
+		}
+
+		targets[p.target] = true
+
+		ref, err := r.Storer.Reference(p.target)
+		if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, err
+		}
+
+		p.current = ref
+		if err := p.checkOld(); err != nil {
+			return nil, err
+		}
+
+		if !u.Verify && !u.New.IsZero() {
+			if err := r.Storer.HasEncodedObject(u.New); err != nil {
+				return nil, fmt.Errorf("%w: %s: new value %s: %w", ErrRefUpdateRejected, u.Name, u.New, err)
+			}
+		}
+
+		prepared = append(prepared, p)
+	}
+
+	return prepared, nil
+}
+
+// derefRefName returns the name of the reference name points to, following
+// its symbolic references, as they are updated instead of name.
+func (r *Repository) derefRefName(name plumbing.ReferenceName) plumbing.ReferenceName {
+	for range 10 {
+		ref, err := r.Storer.Reference(name)
+		if err != nil || ref.Type() != plumbing.SymbolicReference {
+			break
+		}
+
+		name = ref.Target()
+	}
+
+	return name
+}
+
+// checkOld checks that the reference has the old value of the update.
+func (p *preparedRefUpdate) checkOld() error {
+	if p.Old == nil {
+		return nil
+	}
+
+	switch {
+	case p.Old.IsZero() && p.current != nil:
+		return fmt.Errorf("%w: %s: reference already exists", ErrRefUpdateRejected, p.Name)
+	case p.Old.IsZero():
+		return nil
+	case p.current == nil:
+		return fmt.Errorf("%w: %s: reference is missing, expected %s", ErrRefUpdateRejected, p.Name, p.Old)
+	case p.current.Type() != plumbing.HashReference || p.current.Hash() != *p.Old:
+		return fmt.Errorf("%w: %s: is at %s, expected %s", ErrRefUpdateRejected, p.Name, p.current.Hash(), p.Old)
+	}
+
+	return nil
+}
+
+// commitRefUpdates applies the prepared updates, rolling back the ones
+// applied if one fails.
+func (r *Repository) commitRefUpdates(prepared []preparedRefUpdate) error {
+	var applied []preparedRefUpdate
+	for _, p := range prepared {
+		if p.Verify {
+			continue
+		}
+
+		var err error
+		if p.New.IsZero() {
+			if p.current != nil {
+				err = r.checkAndRemoveReference(p.current)
+			}
+		} else {
+			err = r.Storer.CheckAndSetReference(plumbing.NewHashReference(p.target, p.New), p.current)
+		}
+
+		if err != nil {
+			if errors.Is(err, storage.ErrReferenceHasChanged) {
+				err = fmt.Errorf("%w: %s: %w", ErrRefUpdateRejected, p.Name, err)
+			}
+
+			return errors.Join(err, r.rollbackRefUpdates(applied))
+		}
+
+		applied = append(applied, p)
+	}
+
+	for _, p := range applied {
+		if p.New.IsZero() {
+			continue
+		}
+
+		var old plumbing.Hash
+		if p.current != nil {
+			old = p.current.Hash()
+		}
+
+		names := []plumbing.ReferenceName{p.target}
+		if p.Name != p.target {
+			names = append(names, p.Name)
+		}
+
+		if err := r.logRefUpdate(names, old, p.New, nil, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAndRemoveReference removes the reference if it still has the value
+// old, returning storage.ErrReferenceHasChanged otherwise, as
+// CheckAndSetReference does for the updates. The storers not being able to
+// remove a reference conditionally, it is checked right before its removal.
+func (r *Repository) checkAndRemoveReference(old *plumbing.Reference) error {
+	ref, err := r.Storer.Reference(old.Name())
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return storage.ErrReferenceHasChanged
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if ref.Type() != old.Type() || ref.Hash() != old.Hash() || ref.Target() != old.Target() {
+		return storage.ErrReferenceHasChanged
+	}
+
+	return r.Storer.RemoveReference(old.Name())
+}
+
+// rollbackRefUpdates restores the references updated to their old value.
+func (r *Repository) rollbackRefUpdates(applied []preparedRefUpdate) error {
+	var errs []error
+	for _, p := range slices.Backward(applied) {
+		if p.current == nil {
+			errs = append(errs, r.Storer.RemoveReference(p.target))
+			continue
+		}
+
+		errs = append(errs, r.Storer.SetReference(p.current))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func newUpdateRefsTestRepository(t *testing.T) (*Repository, []plumbing.Hash) {
+	t.Helper()
+
+	r, err := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	var hashes []plumbing.Hash
+	for _, msg := range []string{"A", "B"} {
+		h, err := w.Commit(msg, &CommitOptions{Author: defaultSignature(), AllowEmptyCommits: true})
+		require.NoError(t, err)
+		hashes = append(hashes, h)
+	}
+
+	return r, hashes
+}
+
+func TestUpdateRefs(t *testing.T) {
+	t.Parallel()
+
+	r, hashes := newUpdateRefsTestRepository(t)
+	a, b := hashes[0], hashes[1]
+
+	input := strings.Join([]string{
+		fmt.Sprintf("create refs/heads/feature %s", a),
+		fmt.Sprintf("update refs/heads/master %s %s", a, b),
+		"create refs/tags/v1 HEAD~1",
+		"verify refs/heads/missing",
+		"",
+	}, "\n")
+	require.NoError(t, r.UpdateRefs(strings.NewReader(input)))
+
+	for name, h := range map[plumbing.ReferenceName]plumbing.Hash{
+		"refs/heads/feature": a,
+		"refs/heads/master":  a,
+		"refs/tags/v1":       a,
+	} {
+		ref, err := r.Reference(name, false)
+		require.NoError(t, err)
+		assert.Equal(t, h, ref.Hash(), name)
+	}
+
+	input = strings.Join([]string{
+		"start",
+		"delete refs/tags/v1",
+		"prepare",
+		"abort",
+		"start",
+		fmt.Sprintf("delete refs/heads/feature %s", a),
+		"option no-deref",
+		"update HEAD refs/heads/feature",
+		"commit",
+		"",
+	}, "\n")
+	require.NoError(t, r.UpdateRefs(strings.NewReader(input)))
+
+	_, err := r.Reference("refs/tags/v1", false)
+	require.NoError(t, err)
+	_, err = r.Reference("refs/heads/feature", false)
+	assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+
+	head, err := r.Reference(plumbing.HEAD, false)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.HashReference, head.Type())
+	assert.Equal(t, a, head.Hash())
+}
+
+func TestUpdateRefsRejected(t *testing.T) {
+	t.Parallel()
+
+	r, hashes := newUpdateRefsTestRepository(t)
+	a, b := hashes[0], hashes[1]
+
+	tests := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{"old value mismatch", fmt.Sprintf("create refs/heads/new %s\nupdate HEAD %s %s\n", a, b, a), ErrRefUpdateRejected},
+		{"create existing", fmt.Sprintf("create refs/heads/new %s\ncreate refs/heads/master %s\n", a, a), ErrRefUpdateRejected},
+		{"verify missing", fmt.Sprintf("create refs/heads/new %s\nverify refs/heads/other %s\n", a, a), ErrRefUpdateRejected},
+		{"missing object", "create refs/heads/new 1111111111111111111111111111111111111111\n", ErrRefUpdateRejected},
+		{"multiple updates", fmt.Sprintf("create refs/heads/new %s\ndelete refs/heads/new\n", a), ErrRefUpdateRejected},
+		{"unknown command", "frobnicate refs/heads/new\n", ErrInvalidRefUpdate},
+		{"missing argument", "create refs/heads/new\n", ErrInvalidRefUpdate},
+		{"after prepare", fmt.Sprintf("start\nprepare\ncreate refs/heads/new %s\n", a), ErrInvalidRefUpdate},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := r.UpdateRefs(strings.NewReader(tc.input))
+			assert.ErrorIs(t, err, tc.err)
+
+			_, err = r.Reference("refs/heads/new", false)
+			assert.ErrorIs(t, err, plumbing.ErrReferenceNotFound)
+
+			master, err := r.Reference(plumbing.Master, false)
+			require.NoError(t, err)
+			assert.Equal(t, b, master.Hash())
+		})
+	}
+}
+
+func TestUpdateReferences(t *testing.T) {
+	t.Parallel()
+
+	r, hashes := newUpdateRefsTestRepository(t)
+	a, b := hashes[0], hashes[1]
+
+	err := r.UpdateReferences([]RefUpdate{
+		{Name: "refs/heads/a", New: a},
+		{Name: plumbing.HEAD, New: a, Old: &b},
+	})
+	require.NoError(t, err)
+
+	master, err := r.Reference(plumbing.Master, false)
+	require.NoError(t, err)
+	assert.Equal(t, a, master.Hash())
+
+	head, err := r.Reference(plumbing.HEAD, false)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.SymbolicReference, head.Type())
+
+	err = r.UpdateReferences([]RefUpdate{
+		{Name: "refs/heads/a", New: plumbing.ZeroHash},
+		{Name: plumbing.Master, New: b, Old: &b},
+	})
+	assert.ErrorIs(t, err, ErrRefUpdateRejected)
+
+	_, err = r.Reference("refs/heads/a", false)
+	assert.NoError(t, err)
+}
+
+func TestUpdateReferencesDeleteChanged(t *testing.T) {
+	t.Parallel()
+
+	r, hashes := newUpdateRefsTestRepository(t)
+	a, b := hashes[0], hashes[1]
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference("refs/heads/a", a)))
+
+	prepared, err := r.prepareRefUpdates([]RefUpdate{{Name: "refs/heads/a", New: plumbing.ZeroHash}})
+	require.NoError(t, err)
+
+	// The reference changes between the preparation and the commit.
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference("refs/heads/a", b)))
+
+	err = r.commitRefUpdates(prepared)
+	assert.ErrorIs(t, err, ErrRefUpdateRejected)
+
+	ref, err := r.Reference("refs/heads/a", false)
+	require.NoError(t, err)
+	assert.Equal(t, b, ref.Hash())
+}
@@ -15,6 +15,9 @@ type signableObject interface {
 // message is a reader containing the encoded object to be signed.
 // Implementors should return the encoded signature and an error if any.
 // See https://git-scm.com/docs/gitformat-signature for more information.
+//
+// The packages of x/signer implement it by delegating the signing to
+// gpg-agent or to an SSH agent, so that the keys are never loaded in memory.
 type Signer interface {
 	Sign(message io.Reader) ([]byte, error)
 }
@@ -29,6 +29,19 @@ func (s Status) IsUntracked(path string) bool {
 	return ok && stat.Worktree == Untracked
 }
 
+// IsUnmerged checks if file for given path is unmerged, its conflicts with
+// a merge not resolved yet.
+func (s Status) IsUnmerged(path string) bool {
+	stat, ok := (s)[filepath.ToSlash(path)]
+	if !ok {
+		return false
+	}
+
+	return stat.Staging == UpdatedButUnmerged || stat.Worktree == UpdatedButUnmerged ||
+		(stat.Staging == Added && stat.Worktree == Added) ||
+		(stat.Staging == Deleted && stat.Worktree == Deleted)
+}
+
 // IsClean returns true if all the files are in Unmodified status.
 func (s Status) IsClean() bool {
 	for _, status := range s {
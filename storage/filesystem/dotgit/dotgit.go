@@ -25,6 +25,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing"
 	formatcfg "github.com/go-git/go-git/v6/plumbing/format/config"
 	"github.com/go-git/go-git/v6/plumbing/format/idxfile"
+	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/plumbing/format/revfile"
 	plumbhash "github.com/go-git/go-git/v6/plumbing/hash"
 	"github.com/go-git/go-git/v6/storage"
@@ -48,6 +49,7 @@ const (
 	logsPath           = "logs"
 	worktreesPath      = "worktrees"
 	alternatesPath     = "alternates"
+	fetchHeadPath      = "FETCH_HEAD"
 
 	tmpPackedRefsPrefix = "._packed-refs"
 
@@ -80,6 +82,9 @@ var (
 	// ErrEmptyRefFile is returned when a reference file is attempted to be read,
 	// but the file is empty
 	ErrEmptyRefFile = errors.New("ref file is empty")
+	// ErrLockTimeout is returned when a lock could not be acquired before
+	// the end of the lock timeout.
+	ErrLockTimeout = errors.New("timeout acquiring lock")
 )
 
 // Options holds configuration for the storage.
@@ -104,6 +109,27 @@ type Options struct {
 	// WriteReverseIndex controls whether .rev files are written when
 	// creating new packfiles. Defaults to true.
 	WriteReverseIndex bool
+
+	// LockTimeout is how long acquiring a lock on a file is retried when it
+	// fails, as it may on network filesystems, before ErrLockTimeout is
+	// returned. Zero does not retry, except for the packed-refs file which is
+	// retried for 15 seconds.
+	LockTimeout time.Duration
+	// LockRetryInterval is the time waited between two attempts to acquire a
+	// lock. Defaults to 100 milliseconds.
+	LockRetryInterval time.Duration
+	// StaleLockAge makes the lock files git creates next to the files it
+	// updates (e.g. packed-refs.lock) be honoured: the lock file is created
+	// exclusively while the file is updated, waiting for up to LockTimeout
+	// for the one of another process to be gone, the lock files older than
+	// StaleLockAge being removed as left behind by a crashed process. Zero
+	// ignores the lock files of git.
+	StaleLockAge time.Duration
+	// TempFilesInTargetDir writes the temporary file of a loose object in
+	// the directory of the object before renaming it, guaranteeing the
+	// rename is on the same device, on filesystems where renames across
+	// directories may fail.
+	TempFilesInTargetDir bool
 }
 
 // The DotGit type represents a local git repository on disk. This
@@ -233,6 +259,11 @@ func (d *DotGit) ShallowWriter() (billy.File, error) {
 	return d.fs.Create(shallowPath)
 }
 
+// FetchHeadWriter returns a file pointer for write to the FETCH_HEAD file.
+func (d *DotGit) FetchHeadWriter() (billy.File, error) {
+	return d.fs.Create(fetchHeadPath)
+}
+
 // Shallow returns a file pointer for read to the shallow file
 func (d *DotGit) Shallow() (billy.File, error) {
 	f, err := d.fs.Open(shallowPath)
@@ -288,6 +319,14 @@ func (d *DotGit) NewObjectPack() (*PackWriter, error) {
 	return newPackWrite(d.fs, d.options.ObjectFormat, d.options.WriteReverseIndex)
 }
 
+// NewLimitedObjectPack returns a writer for a new packfile, as NewObjectPack
+// does, failing with a *packfile.ObjectLimitError if one of its objects
+// exceeds the limits.
+func (d *DotGit) NewLimitedObjectPack(limits packfile.ObjectLimits) (*PackWriter, error) {
+	d.cleanPackList()
+	return newPackWrite(d.fs, d.options.ObjectFormat, d.options.WriteReverseIndex, packfile.WithObjectLimits(limits))
+}
+
 // ObjectPacks returns the list of availables packfiles
 func (d *DotGit) ObjectPacks() ([]plumbing.Hash, error) {
 	if !d.options.ExclusiveAccess {
@@ -399,6 +438,41 @@ func (d *DotGit) ObjectPackRev(hash plumbing.Hash) (billy.File, error) {
 	return d.objectPackOpen(hash, `rev`)
 }
 
+// ObjectPackMtimes returns a fs.File of the mtimes file of a given cruft
+// packfile.
+func (d *DotGit) ObjectPackMtimes(hash plumbing.Hash) (billy.File, error) {
+	err := d.hasPack(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.objectPackOpen(hash, `mtimes`)
+}
+
+// ObjectPackMtimesWriter returns a writer for the mtimes file of the given
+// packfile, making it a cruft pack.
+func (d *DotGit) ObjectPackMtimesWriter(hash plumbing.Hash) (billy.File, error) {
+	if _, err := d.fs.Lstat(d.objectPackPath(hash, `pack`)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrPackfileNotFound
+		}
+
+		return nil, err
+	}
+
+	return d.fs.Create(d.objectPackPath(hash, `mtimes`))
+}
+
+// ObjectPackStat returns the os.FileInfo of the given packfile.
+func (d *DotGit) ObjectPackStat(hash plumbing.Hash) (os.FileInfo, error) {
+	fi, err := d.fs.Stat(d.objectPackPath(hash, `pack`))
+	if os.IsNotExist(err) {
+		return nil, ErrPackfileNotFound
+	}
+
+	return fi, err
+}
+
 // OpenPackRev returns a [idxfile.ReadAtCloser] for the reverse index of the given
 // packfile. When ReadReverseIndex is true the .rev file is read from disk;
 // otherwise the reverse index is generated in memory on demand.
@@ -475,14 +549,61 @@ func (d *DotGit) DeleteOldObjectPackAndIndex(hash plumbing.Hash, t time.Time) er
 	if err != nil {
 		return err
 	}
+
+	for _, ext := range []string{`rev`, `promisor`, `mtimes`} {
+		err = d.fs.Remove(d.objectPackPath(hash, ext))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
 	return d.fs.Remove(d.objectPackPath(hash, `idx`))
 }
 
+// SetObjectPackPromisor marks the given packfile as fetched from a promisor
+// remote, creating its .promisor file.
+func (d *DotGit) SetObjectPackPromisor(hash plumbing.Hash) error {
+	if _, err := d.fs.Lstat(d.objectPackPath(hash, `pack`)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrPackfileNotFound
+		}
+
+		return err
+	}
+
+	f, err := d.fs.Create(d.objectPackPath(hash, `promisor`))
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// IsObjectPackPromisor returns whether the given packfile has a .promisor
+// file, marking it as fetched from a promisor remote.
+func (d *DotGit) IsObjectPackPromisor(hash plumbing.Hash) bool {
+	_, err := d.fs.Lstat(d.objectPackPath(hash, `promisor`))
+	return err == nil
+}
+
 // NewObject return a writer for a new object file.
 func (d *DotGit) NewObject() (*ObjectWriter, error) {
 	d.cleanObjectList()
 
-	return newObjectWriter(d.fs)
+	return newObjectWriter(d.fs, d.fs.Join(objectsPath, packPath), d.options.TempFilesInTargetDir)
+}
+
+// NewObjectOf returns a writer for the object file of the object with the
+// given hash. With TempFilesInTargetDir, its temporary file is written in the
+// directory of the object, instead of being copied there once written.
+func (d *DotGit) NewObjectOf(h plumbing.Hash) (*ObjectWriter, error) {
+	if !d.options.TempFilesInTargetDir {
+		return d.NewObject()
+	}
+
+	d.cleanObjectList()
+
+	return newObjectWriter(d.fs, d.fs.Join(objectsPath, h.String()[0:2]), true)
 }
 
 // ObjectsWithPrefix returns the hashes of objects that have the given prefix.
@@ -612,6 +733,13 @@ func (d *DotGit) forEachObjectHash(fun func(plumbing.Hash) error) error {
 	return nil
 }
 
+// Reindex drops the lists of packfiles and loose objects kept with
+// ExclusiveAccess, so that the ones added externally are found.
+func (d *DotGit) Reindex() {
+	d.cleanPackList()
+	d.cleanObjectList()
+}
+
 func (d *DotGit) cleanObjectList() {
 	d.objectMap = nil
 	d.objectList = nil
@@ -805,6 +933,13 @@ func (d *DotGit) readReferenceFrom(rd io.Reader, name string) (ref *plumbing.Ref
 	}
 
 	line := strings.TrimSpace(string(b))
+	if name == fetchHeadPath {
+		// FETCH_HEAD lists the fetched references, the first one being the
+		// one it resolves to.
+		line, _, _ = strings.Cut(line, "\n")
+		line, _, _ = strings.Cut(line, "\t")
+	}
+
 	return plumbing.NewReferenceFromStrings(name, line), nil
 }
 
@@ -986,14 +1121,28 @@ func (d *DotGit) openAndLockPackedRefs(doCreate bool) (
 		openFlags |= os.O_CREATE
 	}
 
+	release, err := d.lockFile(packedRefsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil || pr == nil {
+			_ = release()
+		}
+	}()
+
+	timeout := d.options.LockTimeout
+	if timeout <= 0 {
+		timeout = packedRefsLockTimeout
+	}
+
 	start := time.Now()
 	// Keep trying to open and lock the file until we're sure the file
 	// didn't change between the open and the lock.
 	for {
-		// The arbitrary timeout should eventually be replaced with
-		// context-based check.
-		if time.Since(start) > 15*time.Second {
-			return nil, errors.New("timeout trying to lock packed refs")
+		if time.Since(start) > timeout {
+			return nil, fmt.Errorf("%w: %s", ErrLockTimeout, packedRefsPath)
 		}
 		f, err = d.fs.OpenFile(packedRefsPath, openFlags, 0o600)
 		if err != nil {
@@ -1009,11 +1158,9 @@ func (d *DotGit) openAndLockPackedRefs(doCreate bool) (
 		}
 		mtime := fi.ModTime()
 
-		if locker, ok := f.(billy.Locker); ok {
-			err = locker.Lock()
-			if err != nil {
-				return nil, err
-			}
+		err = d.lock(f)
+		if err != nil {
+			return nil, err
 		}
 
 		fi, err = d.fs.Stat(packedRefsPath)
@@ -1029,7 +1176,22 @@ func (d *DotGit) openAndLockPackedRefs(doCreate bool) (
 			return nil, err
 		}
 	}
-	return f, nil
+	return &lockedFile{File: f, release: release}, nil
+}
+
+// lockedFile is a file whose lock file is released once it is closed.
+type lockedFile struct {
+	billy.File
+	release func() error
+}
+
+func (f *lockedFile) Close() error {
+	err := f.File.Close()
+	if rerr := f.release(); err == nil {
+		err = rerr
+	}
+
+	return err
 }
 
 func (d *DotGit) rewritePackedRefsWithoutRef(name plumbing.ReferenceName) (err error) {
@@ -1055,7 +1217,7 @@ func (d *DotGit) rewritePackedRefsWithoutRef(name plumbing.ReferenceName) (err e
 	}()
 
 	s := bufio.NewScanner(pr)
-	found := false
+	found, removed := false, false
 	for s.Scan() {
 		line := s.Text()
 		ref, err := d.processLine(line)
@@ -1064,10 +1226,18 @@ func (d *DotGit) rewritePackedRefsWithoutRef(name plumbing.ReferenceName) (err e
 		}
 
 		if ref != nil && ref.Name() == name {
-			found = true
+			found, removed = true, true
 			continue
 		}
 
+		// The peeled line of the removed tag is removed with it, so that it
+		// is not taken for the one of the previous reference.
+		if removed && strings.HasPrefix(line, "^") {
+			continue
+		}
+
+		removed = false
+
 		if _, err := fmt.Fprintln(tmp, line); err != nil {
 			return err
 		}
@@ -1290,7 +1460,7 @@ func (d *DotGit) AddAlternate(remote string) error {
 		// https://github.com/go-git/go-git/pull/860#issuecomment-1751823044
 		// do not lock on windows platform.
 		if runtime.GOOS != "windows" {
-			if err = locker.Lock(); err != nil {
+			if err = d.lock(f); err != nil {
 				return fmt.Errorf("cannot lock file: %w", err)
 			}
 			defer func() { _ = locker.Unlock() }()
@@ -0,0 +1,178 @@
+package dotgit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/format/idxfile"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+const (
+	idxExt = ".idx"
+
+	// idxCountOffset is the offset of the last entry of the fanout table of
+	// a version 2 idx file, which holds the number of objects in the pack.
+	idxCountOffset = 8 + 255*4
+)
+
+// idxMagic is the magic number at the start of version 2 idx files.
+var idxMagic = []byte{255, 't', 'O', 'c'}
+
+// packExtensions are the extensions of the files that may accompany a
+// packfile and its index in the objects/pack directory.
+var packExtensions = map[string]bool{
+	packExt:     true,
+	idxExt:      true,
+	".rev":      true,
+	".keep":     true,
+	".bitmap":   true,
+	".promisor": true,
+	".mtimes":   true,
+}
+
+// CountObjects returns the statistics of the objects stored in the
+// repository, as reported by git count-objects -v. The loose objects are not
+// checked against the packfiles, so PrunePackable is always zero.
+func (d *DotGit) CountObjects() (*storer.ObjectsCount, error) {
+	c := &storer.ObjectsCount{}
+	if err := d.countLooseObjects(c); err != nil {
+		return nil, err
+	}
+
+	if err := d.countPacks(c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (d *DotGit) countLooseObjects(c *storer.ObjectsCount) error {
+	dirs, err := d.fs.ReadDir(objectsPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	nameSize := d.options.ObjectFormat.HexSize() - 2
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 || !isHex(dir.Name()) {
+			continue
+		}
+
+		files, err := d.fs.ReadDir(d.fs.Join(objectsPath, dir.Name()))
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			fi, err := f.Info()
+			if err != nil {
+				return err
+			}
+
+			if f.IsDir() || len(f.Name()) != nameSize || !isHex(f.Name()) {
+				c.Garbage++
+				c.SizeGarbage += fi.Size()
+				continue
+			}
+
+			c.Count++
+			c.Size += fi.Size()
+		}
+	}
+
+	return nil
+}
+
+func (d *DotGit) countPacks(c *storer.ObjectsCount) error {
+	packDir := d.fs.Join(objectsPath, packPath)
+	files, err := d.fs.ReadDir(packDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	sizes := make(map[string]int64, len(files))
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		fi, err := f.Info()
+		if err != nil {
+			return err
+		}
+
+		sizes[f.Name()] = fi.Size()
+	}
+
+	for name, size := range sizes {
+		ext := filepath.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		_, hasPack := sizes[base+packExt]
+		_, hasIdx := sizes[base+idxExt]
+		if !strings.HasPrefix(name, packPrefix) || !packExtensions[ext] || !hasPack || !hasIdx {
+			c.Garbage++
+			c.SizeGarbage += size
+			continue
+		}
+
+		if ext != packExt {
+			continue
+		}
+
+		n, err := d.countIdxObjects(d.fs.Join(packDir, base+idxExt))
+		if err != nil {
+			return err
+		}
+
+		c.Packs++
+		c.InPack += n
+		c.SizePack += size + sizes[base+idxExt]
+	}
+
+	return nil
+}
+
+// countIdxObjects returns the number of objects of the pack indexed by the
+// idx file at path, reading only its header and the last fanout entry.
+func (d *DotGit) countIdxObjects(path string) (n int64, err error) {
+	f, err := d.fs.Open(path)
+	if err != nil {
+		return 0, err
+	}
+
+	defer ioutil.CheckClose(f, &err)
+
+	var hdr [8]byte
+	if _, err := f.ReadAt(hdr[:], 0); err != nil {
+		return 0, fmt.Errorf("cannot read idx header of %s: %w", path, err)
+	}
+
+	if !bytes.Equal(hdr[:4], idxMagic) {
+		return 0, fmt.Errorf("%w: %s", idxfile.ErrMalformedIdxFile, path)
+	}
+
+	if binary.BigEndian.Uint32(hdr[4:]) != idxfile.VersionSupported {
+		return 0, fmt.Errorf("%w: %s", idxfile.ErrUnsupportedVersion, path)
+	}
+
+	var count [4]byte
+	if _, err := f.ReadAt(count[:], idxCountOffset); err != nil {
+		return 0, fmt.Errorf("cannot read idx fanout of %s: %w", path, err)
+	}
+
+	return int64(binary.BigEndian.Uint32(count[:])), nil
+}
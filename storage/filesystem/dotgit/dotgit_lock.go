@@ -0,0 +1,142 @@
+package dotgit
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/go-git/go-git/v6/utils/trace"
+)
+
+const (
+	lockFileSuffix           = ".lock"
+	defaultLockRetryInterval = 100 * time.Millisecond
+	packedRefsLockTimeout    = 15 * time.Second
+)
+
+var errLockFileExists = errors.New("lock file exists")
+
+// lockRetryInterval returns the time waited between two attempts to acquire
+// a lock.
+func (d *DotGit) lockRetryInterval() time.Duration {
+	if d.options.LockRetryInterval > 0 {
+		return d.options.LockRetryInterval
+	}
+
+	return defaultLockRetryInterval
+}
+
+// lock locks f, if its filesystem supports it, retrying up to the lock
+// timeout when it fails.
+func (d *DotGit) lock(f billy.File) error {
+	locker, ok := f.(billy.Locker)
+	if !ok {
+		return nil
+	}
+
+	start := time.Now()
+	deadline := start.Add(d.options.LockTimeout)
+	for attempts := 1; ; attempts++ {
+		err := locker.Lock()
+		if err == nil || d.options.LockTimeout <= 0 {
+			traceLockWait(f.Name(), start, attempts, err)
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			err = fmt.Errorf("%w: %s: %w", ErrLockTimeout, f.Name(), err)
+			traceLockWait(f.Name(), start, attempts, err)
+			return err
+		}
+
+		time.Sleep(d.lockRetryInterval())
+	}
+}
+
+// traceLockWait emits the wait for the lock of the file at path, if it took
+// more than one attempt.
+func traceLockWait(path string, start time.Time, attempts int, err error) {
+	if attempts <= 1 || !trace.General.Emitting() {
+		return
+	}
+
+	trace.General.Emit("dotgit.lock_wait",
+		slog.String("path", path),
+		slog.Int("attempts", attempts),
+		slog.Duration("duration", time.Since(start)),
+		slog.Any("error", err))
+}
+
+// lockFile takes the lock file git creates while updating the file at path,
+// when the lock files of git are honoured, returning the function releasing
+// it. The lock file is created exclusively, waiting for up to the lock
+// timeout for the one of another process to be gone, and one older than the
+// stale lock age is removed.
+func (d *DotGit) lockFile(path string) (release func() error, err error) {
+	if d.options.StaleLockAge <= 0 {
+		return func() error { return nil }, nil
+	}
+
+	lockPath := path + lockFileSuffix
+	start := time.Now()
+	deadline := start.Add(d.options.LockTimeout)
+	for attempts := 1; ; attempts++ {
+		f, err := d.createLockFile(lockPath)
+		if !errors.Is(err, errLockFileExists) {
+			traceLockWait(lockPath, start, attempts, err)
+			if err != nil {
+				return nil, err
+			}
+
+			return func() error {
+				err := f.Close()
+				if rerr := d.fs.Remove(lockPath); err == nil {
+					err = rerr
+				}
+
+				return err
+			}, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			err = fmt.Errorf("%w: %s exists", ErrLockTimeout, lockPath)
+			traceLockWait(lockPath, start, attempts, err)
+			return nil, err
+		}
+
+		time.Sleep(d.lockRetryInterval())
+	}
+}
+
+// createLockFile creates the lock file at lockPath, failing if it exists. It
+// returns errLockFileExists if the lock file exists and is not stale,
+// removing it and creating it again if it is.
+func (d *DotGit) createLockFile(lockPath string) (billy.File, error) {
+	for {
+		f, err := d.fs.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+		if !os.IsExist(err) {
+			return f, err
+		}
+
+		fi, err := d.fs.Lstat(lockPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if time.Since(fi.ModTime()) <= d.options.StaleLockAge {
+			return nil, errLockFileExists
+		}
+
+		if err := d.fs.Remove(lockPath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
@@ -11,6 +11,17 @@ import (
 )
 
 func (d *DotGit) setRef(fileName, content string, old *plumbing.Reference) (err error) {
+	release, err := d.lockFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if rerr := release(); err == nil {
+			err = rerr
+		}
+	}()
+
 	if billy.CapabilityCheck(d.fs, billy.ReadAndWriteCapability) {
 		return d.setRefRwfs(fileName, content, old)
 	}
@@ -36,11 +47,9 @@ func (d *DotGit) setRefRwfs(fileName, content string, old *plumbing.Reference) (
 	// does not imply a fsync and thus there would be a race between
 	// Unlock+Close and other concurrent writers. Adding Sync to go-billy
 	// could work, but this is better (and avoids superfluous syncs).
-	if locker, ok := f.(billy.Locker); ok {
-		err = locker.Lock()
-		if err != nil {
-			return err
-		}
+	err = d.lock(f)
+	if err != nil {
+		return err
 	}
 
 	// this is a no-op to call even when old is nil.
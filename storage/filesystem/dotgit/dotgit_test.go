@@ -13,6 +13,7 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-billy/v6"
 	"github.com/go-git/go-billy/v6/memfs"
@@ -241,6 +242,32 @@ func (s *SuiteDotGit) TestRemoveRefFromPackedRefs() {
 		string(b))
 }
 
+func (s *SuiteDotGit) TestRemoveRefFromPackedRefsPeeled() {
+	fs := memfs.New()
+	dir := New(fs)
+
+	err := util.WriteFile(fs, packedRefsPath, []byte(""+
+		"# pack-refs with: peeled fully-peeled sorted \n"+
+		"6ecf0ef2c2dffb796033e5a02219af86ec6584e5 refs/heads/master\n"+
+		"b742a2a9fa0afcfa9a6fad080980fbc26b007c69 refs/tags/annotated-tag\n"+
+		"^f7b877701fbf855b44c0a9e86f3fdce2c298b07f\n"+
+		"fe6cb94756faa81e5ed9240f9191b833db5f40ae refs/tags/blob-tag\n"+
+		"^e69de29bb2d1d6434b8b29ae775ad8c2e48c5391\n"), 0o644)
+	s.Require().NoError(err)
+
+	s.Require().NoError(dir.RemoveRef("refs/tags/annotated-tag"))
+
+	b, err := util.ReadFile(fs, packedRefsPath)
+	s.Require().NoError(err)
+
+	s.Equal(""+
+		"# pack-refs with: peeled fully-peeled sorted \n"+
+		"6ecf0ef2c2dffb796033e5a02219af86ec6584e5 refs/heads/master\n"+
+		"fe6cb94756faa81e5ed9240f9191b833db5f40ae refs/tags/blob-tag\n"+
+		"^e69de29bb2d1d6434b8b29ae775ad8c2e48c5391\n",
+		string(b))
+}
+
 func (s *SuiteDotGit) TestRemoveRefFromReferenceFileAndPackedRefs() {
 	fs := fixtures.Basic().ByTag(".git").One().DotGit()
 	dir := New(fs)
@@ -705,6 +732,39 @@ func TestOpenPackRevUsableByLazyIndex(t *testing.T) {
 // createPackWithRev creates a packfile from the basic fixture and returns
 // the DotGit, the pack hash, and the filesystem. The DotGit is created
 // with the given options.
+func TestDeleteOldObjectPackAndIndexRemovesRev(t *testing.T) {
+	t.Parallel()
+
+	dot, h, fs := createPackWithRev(t, Options{
+		ReadReverseIndex:  true,
+		WriteReverseIndex: true,
+	})
+
+	require.NoError(t, dot.DeleteOldObjectPackAndIndex(h, time.Time{}))
+
+	for _, ext := range []string{"pack", "idx", "rev"} {
+		_, err := fs.Stat(fmt.Sprintf("objects/pack/pack-%s.%s", h, ext))
+		assert.True(t, os.IsNotExist(err), ext)
+	}
+}
+
+func TestObjectPackPromisor(t *testing.T) {
+	t.Parallel()
+
+	dot, h, fs := createPackWithRev(t, Options{})
+	assert.False(t, dot.IsObjectPackPromisor(h))
+
+	require.NoError(t, dot.SetObjectPackPromisor(h))
+	assert.True(t, dot.IsObjectPackPromisor(h))
+
+	err := dot.SetObjectPackPromisor(plumbing.NewHash("0000000000000000000000000000000000000001"))
+	assert.ErrorIs(t, err, ErrPackfileNotFound)
+
+	require.NoError(t, dot.DeleteOldObjectPackAndIndex(h, time.Time{}))
+	_, err = fs.Stat(fmt.Sprintf("objects/pack/pack-%s.promisor", h))
+	assert.True(t, os.IsNotExist(err))
+}
+
 func createPackWithRev(t *testing.T, opts Options) (*DotGit, plumbing.Hash, billy.Filesystem) {
 	t.Helper()
 
@@ -774,6 +834,78 @@ func (s *SuiteDotGit) TestNewObject() {
 	s.Equal(int64(34), i.Size())
 }
 
+func (s *SuiteDotGit) TestNewObjectTempFilesInTargetDir() {
+	fs := s.EmptyFS()
+
+	dir := NewWithOptions(fs, Options{TempFilesInTargetDir: true})
+	w, err := dir.NewObject()
+	s.Require().NoError(err)
+
+	err = w.WriteHeader(plumbing.BlobObject, 14)
+	s.Require().NoError(err)
+	_, err = w.Write([]byte("this is a test"))
+	s.Require().NoError(err)
+	s.Require().NoError(w.Close())
+
+	entries, err := fs.ReadDir("objects/a8")
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	s.Equal("a940627d132695a9769df883f85992f0ff4a43", entries[0].Name())
+
+	entries, err = fs.ReadDir("objects/pack")
+	s.Require().NoError(err)
+	s.Empty(entries)
+
+	h := plumbing.NewHash("e69de29bb2d1d6434b8b29ae775ad8c2e48c5391")
+	w, err = dir.NewObjectOf(h)
+	s.Require().NoError(err)
+	s.Equal("objects/e6", filepath.ToSlash(filepath.Dir(w.f.Name())))
+
+	s.Require().NoError(w.WriteHeader(plumbing.BlobObject, 0))
+	s.Require().NoError(w.Close())
+
+	entries, err = fs.ReadDir("objects/e6")
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	s.Equal(h.String()[2:], entries[0].Name())
+}
+
+func (s *SuiteDotGit) TestSetRefLockFile() {
+	fs := s.EmptyFS()
+	ref := plumbing.NewReferenceFromStrings("refs/heads/foo", "e8d3ffab552895c19b9fcf7aa264d277cde33881")
+	s.Require().NoError(util.WriteFile(fs, "refs/heads/foo.lock", nil, 0o644))
+
+	dir := NewWithOptions(fs, Options{
+		LockTimeout:       20 * time.Millisecond,
+		LockRetryInterval: time.Millisecond,
+		StaleLockAge:      time.Hour,
+	})
+	err := dir.SetRef(ref, nil)
+	s.ErrorIs(err, ErrLockTimeout)
+	_, err = fs.Stat("refs/heads/foo")
+	s.True(os.IsNotExist(err))
+
+	dir = NewWithOptions(fs, Options{StaleLockAge: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	s.Require().NoError(dir.SetRef(ref, nil))
+
+	_, err = fs.Stat("refs/heads/foo.lock")
+	s.True(os.IsNotExist(err))
+	_, err = dir.Ref(ref.Name())
+	s.NoError(err)
+
+	s.Require().NoError(util.WriteFile(fs, "packed-refs.lock", nil, 0o644))
+	dir = NewWithOptions(fs, Options{
+		LockTimeout:       20 * time.Millisecond,
+		LockRetryInterval: time.Millisecond,
+		StaleLockAge:      time.Hour,
+	})
+	s.ErrorIs(dir.PackRefs(), ErrLockTimeout)
+
+	dir = NewWithOptions(fs, Options{})
+	s.NoError(dir.PackRefs())
+}
+
 func (s *SuiteDotGit) TestObjects() {
 	fs := fixtures.ByTag(".git").ByTag("unpacked").One().DotGit()
 	dir := New(fs)
@@ -8,6 +8,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/format/objfile"
 	"github.com/go-git/go-git/v6/utils/ioutil"
+	"github.com/go-git/go-git/v6/utils/sync"
 )
 
 var _ (plumbing.EncodedObject) = &EncodedObject{}
@@ -34,13 +35,13 @@ func (e *EncodedObject) Reader() (io.ReadCloser, error) {
 	}
 	r, err := objfile.NewReader(f)
 	if err != nil {
-		return nil, err
+		return nil, looseObjectError(e.h, f.Name(), err)
 	}
 
 	t, size, err := r.Header()
 	if err != nil {
 		_ = r.Close()
-		return nil, err
+		return nil, looseObjectError(e.h, f.Name(), err)
 	}
 	if t != e.t {
 		_ = r.Close()
@@ -50,7 +51,32 @@ func (e *EncodedObject) Reader() (io.ReadCloser, error) {
 		_ = r.Close()
 		return nil, objfile.ErrHeader
 	}
-	return ioutil.NewReadCloserWithCloser(r, f.Close), nil
+	lr := &looseObjectReader{Reader: r, h: e.h, name: f.Name()}
+	return ioutil.NewReadCloserWithCloser(lr, f.Close), nil
+}
+
+// looseObjectReader reads the content of a loose object, returning an
+// ObjectCorruptError if it can't be inflated.
+type looseObjectReader struct {
+	*objfile.Reader
+	h    plumbing.Hash
+	name string
+}
+
+func (r *looseObjectReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	return n, looseObjectError(r.h, r.name, err)
+}
+
+// looseObjectError returns an ObjectCorruptError for the loose object h read
+// from the file name if err was returned when inflating it, and err
+// otherwise.
+func looseObjectError(h plumbing.Hash, name string, err error) error {
+	if !sync.IsInflateError(err) {
+		return err
+	}
+
+	return &plumbing.ObjectCorruptError{Hash: h, Source: name, Err: err}
 }
 
 func (e *EncodedObject) SetType(plumbing.ObjectType) {}
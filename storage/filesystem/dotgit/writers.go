@@ -16,6 +16,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/format/objfile"
 	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/plumbing/format/revfile"
+	"github.com/go-git/go-git/v6/utils/ioutil"
 )
 
 // PackWriter is a io.Writer that generates the packfile index simultaneously,
@@ -36,9 +37,13 @@ type PackWriter struct {
 	result   chan error
 	format   formatcfg.ObjectFormat
 	writeRev bool
+	opts     []packfile.ParserOption
+
+	// failed is set when the packfile can't be parsed, failing the writes.
+	failed atomic.Pointer[error]
 }
 
-func newPackWrite(fs billy.Filesystem, format formatcfg.ObjectFormat, writeRev bool) (*PackWriter, error) {
+func newPackWrite(fs billy.Filesystem, format formatcfg.ObjectFormat, writeRev bool, opts ...packfile.ParserOption) (*PackWriter, error) {
 	fw, err := fs.TempFile(fs.Join(objectsPath, packPath), "tmp_pack_")
 	if err != nil {
 		return nil, err
@@ -57,6 +62,7 @@ func newPackWrite(fs billy.Filesystem, format formatcfg.ObjectFormat, writeRev b
 		result:   make(chan error),
 		format:   format,
 		writeRev: writeRev,
+		opts:     opts,
 	}
 
 	writer.checksum.ResetBySize(format.Size())
@@ -69,12 +75,15 @@ func (w *PackWriter) buildIndex() {
 	w.writer = new(idxfile.Writer)
 	var err error
 
-	w.parser = packfile.NewParser(w.synced,
+	opts := append([]packfile.ParserOption{
 		packfile.WithScannerObservers(w.writer),
-		packfile.WithObjectFormat(w.format))
+		packfile.WithObjectFormat(w.format),
+	}, w.opts...)
+	w.parser = packfile.NewParser(w.synced, opts...)
 
 	h, err := w.parser.Parse()
 	if err != nil {
+		w.failed.Store(&err)
 		w.result <- err
 		return
 	}
@@ -96,6 +105,12 @@ func (w *PackWriter) waitBuildIndex() error {
 }
 
 func (w *PackWriter) Write(p []byte) (int, error) {
+	// There is no point in writing the rest of a packfile which can't be
+	// parsed.
+	if err := w.failed.Load(); err != nil && !errors.Is(*err, packfile.ErrEmptyPackfile) {
+		return 0, *err
+	}
+
 	return w.synced.Write(p)
 }
 
@@ -295,18 +310,32 @@ type ObjectWriter struct {
 	objfile.Writer
 	fs billy.Filesystem
 	f  billy.File
+	// tmpDir is the directory of the temporary file.
+	tmpDir string
+
+	// inTargetDir makes the temporary file be moved to the directory of
+	// the object before being renamed, if it is not already there.
+	inTargetDir bool
 }
 
-func newObjectWriter(fs billy.Filesystem) (*ObjectWriter, error) {
-	f, err := fs.TempFile(fs.Join(objectsPath, packPath), "tmp_obj_")
+func newObjectWriter(fs billy.Filesystem, tmpDir string, inTargetDir bool) (*ObjectWriter, error) {
+	if tmpDir != fs.Join(objectsPath, packPath) {
+		if err := fs.MkdirAll(tmpDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := fs.TempFile(tmpDir, "tmp_obj_")
 	if err != nil {
 		return nil, err
 	}
 
 	return &ObjectWriter{
-		Writer: (*objfile.NewWriter(f)),
-		fs:     fs,
-		f:      f,
+		Writer:      (*objfile.NewWriter(f)),
+		fs:          fs,
+		f:           f,
+		tmpDir:      tmpDir,
+		inTargetDir: inTargetDir,
 	}, nil
 }
 
@@ -325,7 +354,8 @@ func (w *ObjectWriter) Close() error {
 func (w *ObjectWriter) save() error {
 	h := w.Hash()
 	hex := h.String()
-	file := w.fs.Join(objectsPath, hex[0:2], hex[2:h.HexSize()])
+	dir := w.fs.Join(objectsPath, hex[0:2])
+	file := w.fs.Join(dir, hex[2:h.HexSize()])
 
 	// Loose objects are content addressable, if they already exist
 	// we can safely delete the temporary file and short-circuit the
@@ -334,10 +364,52 @@ func (w *ObjectWriter) save() error {
 		return w.fs.Remove(w.f.Name())
 	}
 
-	if err := w.fs.Rename(w.f.Name(), file); err != nil {
+	tmp := w.f.Name()
+	if w.inTargetDir && w.tmpDir != dir {
+		var err error
+		if tmp, err = w.copyToDir(dir); err != nil {
+			return err
+		}
+	}
+
+	if err := w.fs.Rename(tmp, file); err != nil {
 		return err
 	}
 	fixPermissions(w.fs, file)
 
 	return nil
 }
+
+// copyToDir copies the temporary file to a temporary file in dir, so that
+// renaming it to an object file of dir does not cross devices, returning its
+// name.
+func (w *ObjectWriter) copyToDir(dir string) (name string, err error) {
+	src, err := w.fs.Open(w.f.Name())
+	if err != nil {
+		return "", err
+	}
+
+	defer ioutil.CheckClose(src, &err)
+
+	if err = w.fs.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	dst, err := w.fs.TempFile(dir, "tmp_obj_")
+	if err != nil {
+		return "", err
+	}
+
+	defer ioutil.CheckClose(dst, &err)
+
+	if _, err = io.Copy(dst, src); err != nil {
+		_ = w.fs.Remove(dst.Name())
+		return "", err
+	}
+
+	if err = w.fs.Remove(w.f.Name()); err != nil {
+		return "", err
+	}
+
+	return dst.Name(), nil
+}
@@ -0,0 +1,182 @@
+package filesystem
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/go-git/go-git/v6/plumbing/cache"
+)
+
+// NewStorageFromFS returns a read-only Storage backed by the git directory at
+// the root of fsys, such as an embed.FS, a zip.Reader or a sub tree of them.
+// The references, the config, the loose and packed objects and the index can
+// be read, while any write fails with billy.ErrReadOnly.
+func NewStorageFromFS(fsys fs.FS, c cache.Object) *Storage {
+	return NewStorage(&ioFS{fsys: fsys}, c)
+}
+
+// ioFS is a read-only billy.Filesystem backed by a fs.FS.
+type ioFS struct {
+	fsys fs.FS
+}
+
+// name returns the fs.FS path of the given billy path.
+func (*ioFS) name(filename string) string {
+	name := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(filename)), "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
+
+func (f *ioFS) Create(string) (billy.File, error) {
+	return nil, billy.ErrReadOnly
+}
+
+func (f *ioFS) Open(filename string) (billy.File, error) {
+	return f.OpenFile(filename, os.O_RDONLY, 0)
+}
+
+func (f *ioFS) OpenFile(filename string, flag int, _ os.FileMode) (billy.File, error) {
+	if flag&(os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_RDWR|os.O_EXCL|os.O_TRUNC) != 0 {
+		return nil, billy.ErrReadOnly
+	}
+
+	file, err := f.fsys.Open(f.name(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		_ = file.Close()
+		return nil, &fs.PathError{Op: "open", Path: filename, Err: billy.ErrNotSupported}
+	}
+
+	r, ok := file.(ioFile)
+	if !ok {
+		// Files not supporting random access, as the ones of a zip, are read
+		// into memory.
+		data, err := io.ReadAll(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		r = nopCloser{bytes.NewReader(data)}
+	}
+
+	return &ioFSFile{ioFile: r, name: filename, fi: fi}, nil
+}
+
+func (f *ioFS) Stat(filename string) (os.FileInfo, error) {
+	return fs.Stat(f.fsys, f.name(filename))
+}
+
+func (f *ioFS) Rename(string, string) error {
+	return billy.ErrReadOnly
+}
+
+func (f *ioFS) Remove(string) error {
+	return billy.ErrReadOnly
+}
+
+func (f *ioFS) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+func (f *ioFS) TempFile(string, string) (billy.File, error) {
+	return nil, billy.ErrReadOnly
+}
+
+func (f *ioFS) ReadDir(dirname string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.fsys, f.name(dirname))
+}
+
+func (f *ioFS) MkdirAll(string, os.FileMode) error {
+	return billy.ErrReadOnly
+}
+
+// Lstat returns the same as Stat, as fs.FS follows the symbolic links.
+func (f *ioFS) Lstat(filename string) (os.FileInfo, error) {
+	return f.Stat(filename)
+}
+
+func (f *ioFS) Symlink(string, string) error {
+	return billy.ErrReadOnly
+}
+
+func (f *ioFS) Readlink(string) (string, error) {
+	return "", billy.ErrNotSupported
+}
+
+func (f *ioFS) Chroot(p string) (billy.Filesystem, error) {
+	sub, err := fs.Sub(f.fsys, f.name(p))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ioFS{fsys: sub}, nil
+}
+
+func (f *ioFS) Root() string {
+	return ""
+}
+
+func (f *ioFS) Capabilities() billy.Capability {
+	return billy.ReadCapability | billy.SeekCapability
+}
+
+// ioFile is a fs.File supporting random access.
+type ioFile interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// ioFSFile is a read-only billy.File.
+type ioFSFile struct {
+	ioFile
+	name string
+	fi   os.FileInfo
+}
+
+func (f *ioFSFile) Name() string {
+	return f.name
+}
+
+func (f *ioFSFile) Write([]byte) (int, error) {
+	return 0, billy.ErrReadOnly
+}
+
+func (f *ioFSFile) WriteAt([]byte, int64) (int, error) {
+	return 0, billy.ErrReadOnly
+}
+
+func (f *ioFSFile) Truncate(int64) error {
+	return billy.ErrReadOnly
+}
+
+func (f *ioFSFile) Stat() (os.FileInfo, error) {
+	return f.fi, nil
+}
@@ -0,0 +1,51 @@
+package filesystem_test
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/helper/iofs"
+	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+)
+
+func TestNewStorageFromFS(t *testing.T) {
+	t.Parallel()
+
+	f := fixtures.Basic().One()
+	head := plumbing.NewHash(f.Head)
+	sto := filesystem.NewStorageFromFS(iofs.New(f.DotGit()), cache.NewObjectLRUDefault())
+
+	cfg, err := sto.Config()
+	require.NoError(t, err)
+	assert.Contains(t, cfg.Remotes, "origin")
+
+	ref, err := sto.Reference(plumbing.HEAD)
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.ReferenceName("refs/heads/master"), ref.Target())
+
+	ref, err = sto.Reference(ref.Target())
+	require.NoError(t, err)
+	assert.Equal(t, head, ref.Hash())
+
+	obj, err := sto.EncodedObject(plumbing.CommitObject, head)
+	require.NoError(t, err)
+	assert.Equal(t, head, obj.Hash())
+
+	iter, err := sto.IterEncodedObjects(plumbing.AnyObject)
+	require.NoError(t, err)
+	count := 0
+	require.NoError(t, iter.ForEach(func(plumbing.EncodedObject) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 31, count)
+
+	err = sto.SetReference(plumbing.NewHashReference("refs/heads/foo", head))
+	assert.ErrorIs(t, err, billy.ErrReadOnly)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/storer"
 	"github.com/go-git/go-git/v6/storage/filesystem/dotgit"
 	"github.com/go-git/go-git/v6/utils/ioutil"
+	gogitsync "github.com/go-git/go-git/v6/utils/sync"
 )
 
 type ObjectStorage struct {
@@ -40,6 +41,11 @@ type ObjectStorage struct {
 	muI         sync.RWMutex
 	muP         sync.RWMutex
 
+	// mtimes caches the modification times of the objects of the cruft
+	// packs, by packfile. Protected by muT.
+	mtimes map[plumbing.Hash]map[plumbing.Hash]time.Time
+	muT    sync.RWMutex
+
 	oh *plumbing.ObjectHasher
 
 	// alternates holds cached ObjectStorage instances for alternate repositories.
@@ -206,6 +212,7 @@ func (s *ObjectStorage) requireIndex() error {
 
 // Reindex indexes again all packfiles. Useful if git changed packfiles externally
 func (s *ObjectStorage) Reindex() {
+	s.dir.Reindex()
 	s.index = nil
 }
 
@@ -299,23 +306,54 @@ func (s *ObjectStorage) PackfileWriter() (io.WriteCloser, error) {
 		return nil, err
 	}
 
-	w.Notify = func(h plumbing.Hash, writer *idxfile.Writer) {
-		index, err := writer.Index()
-		if err == nil {
-			s.index[h] = index
-		}
+	w.Notify = s.notifyPack
+	return w, nil
+}
+
+// notifyPack adds the index of the packfile written to the storage.
+func (s *ObjectStorage) notifyPack(h plumbing.Hash, writer *idxfile.Writer) {
+	index, err := writer.Index()
+	if err == nil {
+		s.index[h] = index
+	}
+}
+
+// LimitedPackfileWriter returns a writer for writing a packfile to the
+// storage, failing with a *packfile.ObjectLimitError if one of its objects
+// exceeds the limits.
+func (s *ObjectStorage) LimitedPackfileWriter(limits packfile.ObjectLimits) (io.WriteCloser, error) {
+	if err := s.requireIndex(); err != nil {
+		return nil, err
+	}
+
+	w, err := s.dir.NewLimitedObjectPack(limits)
+	if err != nil {
+		return nil, err
 	}
 
+	w.Notify = s.notifyPack
 	return w, nil
 }
 
-// SetEncodedObject adds a new object to the storage.
+// SetEncodedObject adds a new object to the storage. Objects already held
+// by one of the alternates are not written.
 func (s *ObjectStorage) SetEncodedObject(o plumbing.EncodedObject) (h plumbing.Hash, err error) {
 	if o.Type() == plumbing.OFSDeltaObject || o.Type() == plumbing.REFDeltaObject {
 		return plumbing.ZeroHash, plumbing.ErrInvalidType
 	}
 
-	ow, err := s.dir.NewObject()
+	// Objects already in an alternate are not written again, so that they
+	// stay shared.
+	shared, err := s.hasInAlternates(o.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if shared {
+		return o.Hash(), nil
+	}
+
+	ow, err := s.dir.NewObjectOf(o.Hash())
 	if err != nil {
 		return plumbing.ZeroHash, err
 	}
@@ -383,6 +421,21 @@ func (s *ObjectStorage) HasEncodedObject(h plumbing.Hash) (err error) {
 	return err
 }
 
+// hasInAlternates returns whether one of the alternates holds the object.
+func (s *ObjectStorage) hasInAlternates(h plumbing.Hash) (bool, error) {
+	_, err := findInAlternates(s, func(alt *ObjectStorage) (struct{}, error) {
+		return struct{}{}, alt.HasEncodedObject(h)
+	})
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, plumbing.ErrObjectNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
 func (s *ObjectStorage) encodedObjectSizeFromUnpacked(h plumbing.Hash) (size int64, err error) {
 	f, err := s.dir.Object(h)
 	if err != nil {
@@ -395,12 +448,23 @@ func (s *ObjectStorage) encodedObjectSizeFromUnpacked(h plumbing.Hash) (size int
 
 	r, err := objfile.NewReader(f)
 	if err != nil {
-		return 0, err
+		return 0, looseObjectError(h, f.Name(), err)
 	}
 	defer ioutil.CheckClose(r, &err)
 
 	_, size, err = r.Header()
-	return size, err
+	return size, looseObjectError(h, f.Name(), err)
+}
+
+// looseObjectError returns an ObjectCorruptError for the loose object h read
+// from the file name if err was returned when inflating it, and err
+// otherwise.
+func looseObjectError(h plumbing.Hash, name string, err error) error {
+	if !gogitsync.IsInflateError(err) {
+		return err
+	}
+
+	return &plumbing.ObjectCorruptError{Hash: h, Source: name, Err: err}
 }
 
 func (s *ObjectStorage) packfile(idx idxfile.Index, pack plumbing.Hash) (*packfile.Packfile, error) {
@@ -418,10 +482,18 @@ func (s *ObjectStorage) packfile(idx idxfile.Index, pack plumbing.Hash) (*packfi
 		packfile.WithFs(s.dir.Fs()),
 		packfile.WithCache(s.objectCache),
 		packfile.WithObjectIDSize(pack.Size()),
+		packfile.WithExternalBases(s.externalDeltaBase),
 	)
 	return p, s.storePackfileInCache(pack, p)
 }
 
+// externalDeltaBase returns the base of a REF_DELTA object stored in a
+// different packfile than the delta itself, as found after incremental
+// repacks of thin packs.
+func (s *ObjectStorage) externalDeltaBase(ctx context.Context, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	return s.getFromPackfile(ctx, h, false)
+}
+
 func (s *ObjectStorage) packfileFromCache(hash plumbing.Hash) *packfile.Packfile {
 	s.muP.Lock()
 	defer s.muP.Unlock()
@@ -535,24 +607,35 @@ func (s *ObjectStorage) EncodedObjectSize(h plumbing.Hash) (size int64, err erro
 // EncodedObject returns the object with the given hash, by searching for it in
 // the packfile and the git object directories.
 func (s *ObjectStorage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	return s.EncodedObjectContext(context.Background(), t, h)
+}
+
+// EncodedObjectContext is like EncodedObject, but returns ctx.Err() as soon
+// as the context is cancelled while reading a loose object or resolving the
+// deltas of a packed one.
+func (s *ObjectStorage) EncodedObjectContext(ctx context.Context, t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var obj plumbing.EncodedObject
 	var err error
 
 	if s.index != nil {
-		obj, err = s.getFromPackfile(h, false)
+		obj, err = s.getFromPackfile(ctx, h, false)
 		if errors.Is(err, plumbing.ErrObjectNotFound) {
-			obj, err = s.getFromUnpacked(h)
+			obj, err = s.getFromUnpacked(ctx, h)
 		}
 	} else {
-		obj, err = s.getFromUnpacked(h)
+		obj, err = s.getFromUnpacked(ctx, h)
 		if errors.Is(err, plumbing.ErrObjectNotFound) {
-			obj, err = s.getFromPackfile(h, false)
+			obj, err = s.getFromPackfile(ctx, h, false)
 		}
 	}
 
 	if errors.Is(err, plumbing.ErrObjectNotFound) {
 		obj, err = findInAlternates(s, func(alt *ObjectStorage) (plumbing.EncodedObject, error) {
-			return alt.EncodedObject(t, h)
+			return alt.EncodedObjectContext(ctx, t, h)
 		})
 	}
 
@@ -570,9 +653,9 @@ func (s *ObjectStorage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (p
 // DeltaObject returns the object with the given hash, by searching for
 // it in the packfile and the git object directories.
 func (s *ObjectStorage) DeltaObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
-	obj, err := s.getFromUnpacked(h)
+	obj, err := s.getFromUnpacked(context.Background(), h)
 	if errors.Is(err, plumbing.ErrObjectNotFound) {
-		obj, err = s.getFromPackfile(h, true)
+		obj, err = s.getFromPackfile(context.Background(), h, true)
 	}
 
 	if err != nil {
@@ -586,7 +669,7 @@ func (s *ObjectStorage) DeltaObject(t plumbing.ObjectType, h plumbing.Hash) (plu
 	return obj, nil
 }
 
-func (s *ObjectStorage) getFromUnpacked(h plumbing.Hash) (obj plumbing.EncodedObject, err error) {
+func (s *ObjectStorage) getFromUnpacked(ctx context.Context, h plumbing.Hash) (obj plumbing.EncodedObject, err error) {
 	f, err := s.dir.Object(h)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -603,14 +686,14 @@ func (s *ObjectStorage) getFromUnpacked(h plumbing.Hash) (obj plumbing.EncodedOb
 
 	r, err := objfile.NewReader(f)
 	if err != nil {
-		return nil, err
+		return nil, looseObjectError(h, f.Name(), err)
 	}
 
 	defer ioutil.CheckClose(r, &err)
 
 	t, size, err := r.Header()
 	if err != nil {
-		return nil, err
+		return nil, looseObjectError(h, f.Name(), err)
 	}
 
 	if s.options.LargeObjectThreshold > 0 && size > s.options.LargeObjectThreshold {
@@ -629,9 +712,14 @@ func (s *ObjectStorage) getFromUnpacked(h plumbing.Hash) (obj plumbing.EncodedOb
 
 	defer ioutil.CheckClose(w, &err)
 
-	_, err = ioutil.CopyBufferPool(w, r)
+	var src io.Reader = r
+	if ctx.Done() != nil {
+		src = ioutil.NewContextReader(ctx, r)
+	}
+
+	_, err = ioutil.CopyBufferPool(w, src)
 	if err != nil {
-		return nil, err
+		return nil, looseObjectError(h, f.Name(), err)
 	}
 
 	s.objectCache.Put(obj)
@@ -641,7 +729,7 @@ func (s *ObjectStorage) getFromUnpacked(h plumbing.Hash) (obj plumbing.EncodedOb
 
 // Get returns the object with the given hash, by searching for it in
 // the packfile.
-func (s *ObjectStorage) getFromPackfile(h plumbing.Hash, canBeDelta bool) (plumbing.EncodedObject, error) {
+func (s *ObjectStorage) getFromPackfile(ctx context.Context, h plumbing.Hash, canBeDelta bool) (plumbing.EncodedObject, error) {
 	if err := s.requireIndex(); err != nil {
 		return nil, err
 	}
@@ -668,7 +756,7 @@ func (s *ObjectStorage) getFromPackfile(h plumbing.Hash, canBeDelta bool) (plumb
 		return s.decodeDeltaObjectAt(p, offset, hash)
 	}
 
-	return p.GetByOffset(offset)
+	return p.GetByOffsetContext(ctx, offset)
 }
 
 // TODO: refactor this logic into packfile package.
@@ -902,5 +990,143 @@ func (s *ObjectStorage) ObjectPacks() ([]plumbing.Hash, error) {
 }
 
 func (s *ObjectStorage) DeleteOldObjectPackAndIndex(h plumbing.Hash, t time.Time) error {
-	return s.dir.DeleteOldObjectPackAndIndex(h, t)
+	if err := s.dir.DeleteOldObjectPackAndIndex(h, t); err != nil {
+		return err
+	}
+
+	// The packfile is forgotten once deleted, the too recent ones being kept.
+	if _, err := s.dir.ObjectPackStat(h); errors.Is(err, dotgit.ErrPackfileNotFound) {
+		s.forgetPackfile(h)
+	}
+
+	return nil
+}
+
+// forgetPackfile drops the index, the open packfile and the mtimes of a
+// deleted packfile.
+func (s *ObjectStorage) forgetPackfile(h plumbing.Hash) {
+	s.muI.Lock()
+	if closer, ok := s.index[h].(io.Closer); ok {
+		_ = closer.Close()
+	}
+	delete(s.index, h)
+	s.muI.Unlock()
+
+	s.muP.Lock()
+	if p := s.packfiles[h]; p != nil {
+		_ = p.Close()
+	}
+	delete(s.packfiles, h)
+	s.muP.Unlock()
+
+	s.muT.Lock()
+	delete(s.mtimes, h)
+	s.muT.Unlock()
+}
+
+// MarkPromisorPack implements storer.PromisorObjectStorer, creating the
+// .promisor file of the packfile.
+func (s *ObjectStorage) MarkPromisorPack(h plumbing.Hash) error {
+	return s.dir.SetObjectPackPromisor(h)
+}
+
+// IsPromisorObject implements storer.PromisorObjectStorer.
+func (s *ObjectStorage) IsPromisorObject(h plumbing.Hash) bool {
+	if err := s.requireIndex(); err != nil {
+		return false
+	}
+
+	pack, _, offset := s.findObjectInPackfile(h)
+	return offset != -1 && s.dir.IsObjectPackPromisor(pack)
+}
+
+// CountObjects returns the statistics of the objects in the repository,
+// excluding its alternates.
+func (s *ObjectStorage) CountObjects() (*storer.ObjectsCount, error) {
+	c, err := s.dir.CountObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Count == 0 || c.Packs == 0 {
+		return c, nil
+	}
+
+	if err := s.requireIndex(); err != nil {
+		return nil, err
+	}
+
+	s.muI.RLock()
+	defer s.muI.RUnlock()
+
+	err = s.dir.ForEachObjectHash(func(h plumbing.Hash) error {
+		for _, idx := range s.index {
+			ok, err := idx.Contains(h)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				c.PrunePackable++
+				return nil
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// VerifyPack decodes all the objects of the packfile with the given hash and
+// checks them against its index, as git verify-pack does, reporting their
+// sizes and delta chains.
+func (s *ObjectStorage) VerifyPack(h plumbing.Hash) (report *packfile.VerifyReport, err error) {
+	if err := s.requireIndex(); err != nil {
+		return nil, err
+	}
+
+	s.muI.RLock()
+	idx, ok := s.index[h]
+	s.muI.RUnlock()
+	if !ok {
+		return nil, dotgit.ErrPackfileNotFound
+	}
+
+	f, err := s.dir.ObjectPack(h)
+	if err != nil {
+		return nil, err
+	}
+
+	defer ioutil.CheckClose(f, &err)
+
+	report, err = packfile.Verify(f, packfile.WithObjectFormat(s.options.ObjectFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Checksum != h {
+		return nil, fmt.Errorf("%w: packfile mismatch: checksum is %q not %q", idxfile.ErrMalformedIdxFile, report.Checksum.String(), h.String())
+	}
+
+	count, err := idx.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	if count != int64(len(report.Objects)) {
+		return nil, fmt.Errorf("%w: %d objects indexed, %d packed", idxfile.ErrMalformedIdxFile, count, len(report.Objects))
+	}
+
+	for _, o := range report.Objects {
+		offset, err := idx.FindOffset(o.Hash)
+		if err != nil || offset != o.Offset {
+			return nil, fmt.Errorf("%w: object %s at offset %d not indexed", idxfile.ErrMalformedIdxFile, o.Hash, o.Offset)
+		}
+	}
+
+	return report, nil
 }
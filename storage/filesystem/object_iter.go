@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"context"
 	"crypto"
 	"io"
 
@@ -183,7 +184,7 @@ func (iter *objectsIter) Next() (plumbing.EncodedObject, error) {
 		return nil, io.EOF
 	}
 
-	obj, err := iter.s.getFromUnpacked(iter.h[0])
+	obj, err := iter.s.getFromUnpacked(context.Background(), iter.h[0])
 	iter.h = iter.h[1:]
 
 	if err != nil {
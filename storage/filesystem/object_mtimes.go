@@ -0,0 +1,249 @@
+package filesystem
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/mtimes"
+	"github.com/go-git/go-git/v6/plumbing/hash"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage/filesystem/dotgit"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+var _ storer.ObjectTimeStorer = (*ObjectStorage)(nil)
+
+// ObjectTime returns the modification time of the object: the one of its
+// file if it is loose, and otherwise the one recorded by the mtimes file of
+// its cruft pack, or the one of its packfile.
+func (s *ObjectStorage) ObjectTime(h plumbing.Hash) (time.Time, error) {
+	if t, err := s.LooseObjectTime(h); err == nil {
+		return t, nil
+	}
+
+	if err := s.requireIndex(); err != nil {
+		return time.Time{}, err
+	}
+
+	pack, _, offset := s.findObjectInPackfile(h)
+	if offset == -1 {
+		return time.Time{}, plumbing.ErrObjectNotFound
+	}
+
+	packMtimes, err := s.packMtimes(pack)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if t, ok := packMtimes[h]; ok {
+		return t, nil
+	}
+
+	fi, err := s.dir.ObjectPackStat(pack)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return fi.ModTime(), nil
+}
+
+// ForEachObjectTime calls fun with each object and its modification time, as
+// ObjectTime returns them. The hashes are read from the loose object files and
+// the packfile indexes, the objects themselves are not read.
+func (s *ObjectStorage) ForEachObjectTime(fun func(plumbing.Hash, time.Time) error) error {
+	err := s.dir.ForEachObjectHash(func(h plumbing.Hash) error {
+		t, err := s.LooseObjectTime(h)
+		if err != nil {
+			return err
+		}
+
+		return fun(h, t)
+	})
+	if err != nil {
+		if errors.Is(err, storer.ErrStop) {
+			return nil
+		}
+
+		return err
+	}
+
+	packs, err := s.dir.ObjectPacks()
+	if err != nil {
+		return err
+	}
+
+	for _, pack := range packs {
+		hashes, err := s.packHashes(pack)
+		if err != nil {
+			return err
+		}
+
+		packMtimes, err := s.packMtimes(pack)
+		if err != nil {
+			return err
+		}
+
+		fi, err := s.dir.ObjectPackStat(pack)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range hashes {
+			t, ok := packMtimes[h]
+			if !ok {
+				t = fi.ModTime()
+			}
+
+			if err := fun(h, t); err != nil {
+				if errors.Is(err, storer.ErrStop) {
+					return nil
+				}
+
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetObjectPackMtimes writes the mtimes file of the packfile, recording the
+// modification time of each of its objects, as git does for its cruft packs.
+func (s *ObjectStorage) SetObjectPackMtimes(pack plumbing.Hash, objectMtimes map[plumbing.Hash]time.Time) (err error) {
+	hashes, err := s.packHashes(pack)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]uint32, len(hashes))
+	for i, h := range hashes {
+		t, ok := objectMtimes[h]
+		if !ok {
+			return fmt.Errorf("missing mtime of object %s", h)
+		}
+
+		entries[i] = uint32(t.Unix())
+	}
+
+	hasher := hash.New(crypto.SHA1)
+	if pack.Size() == crypto.SHA256.Size() {
+		hasher = hash.New(crypto.SHA256)
+	}
+
+	w, err := s.dir.ObjectPackMtimesWriter(pack)
+	if err != nil {
+		return err
+	}
+	defer ioutil.CheckClose(w, &err)
+
+	if err := mtimes.Encode(w, hasher, pack, entries); err != nil {
+		return err
+	}
+
+	s.muT.Lock()
+	delete(s.mtimes, pack)
+	s.muT.Unlock()
+
+	return nil
+}
+
+// packMtimes returns the modification times recorded by the mtimes file of
+// the packfile, or nil if it is not a cruft pack. They are cached, as the
+// packfiles are never modified.
+func (s *ObjectStorage) packMtimes(pack plumbing.Hash) (map[plumbing.Hash]time.Time, error) {
+	s.muT.RLock()
+	m, ok := s.mtimes[pack]
+	s.muT.RUnlock()
+	if ok {
+		return m, nil
+	}
+
+	m, err := s.readPackMtimes(pack)
+	if err != nil {
+		return nil, err
+	}
+
+	s.muT.Lock()
+	if s.mtimes == nil {
+		s.mtimes = make(map[plumbing.Hash]map[plumbing.Hash]time.Time)
+	}
+	s.mtimes[pack] = m
+	s.muT.Unlock()
+
+	return m, nil
+}
+
+func (s *ObjectStorage) readPackMtimes(pack plumbing.Hash) (m map[plumbing.Hash]time.Time, err error) {
+	f, err := s.dir.ObjectPackMtimes(pack)
+	if errors.Is(err, dotgit.ErrPackfileNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer ioutil.CheckClose(f, &err)
+
+	hashes, err := s.packHashes(pack)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := mtimes.Decode(f, int64(len(hashes)), pack)
+	if err != nil {
+		return nil, err
+	}
+
+	m = make(map[plumbing.Hash]time.Time, len(hashes))
+	for i, h := range hashes {
+		m[h] = time.Unix(int64(entries[i]), 0)
+	}
+
+	return m, nil
+}
+
+// packHashes returns the hashes of the objects of the packfile, in the order
+// of its index.
+func (s *ObjectStorage) packHashes(pack plumbing.Hash) ([]plumbing.Hash, error) {
+	if err := s.requireIndex(); err != nil {
+		return nil, err
+	}
+
+	s.muI.RLock()
+	idx, ok := s.index[pack]
+	s.muI.RUnlock()
+	if !ok {
+		return nil, dotgit.ErrPackfileNotFound
+	}
+
+	iter, err := idx.Entries()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var hashes []plumbing.Hash
+	for {
+		e, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		hashes = append(hashes, e.Hash)
+	}
+
+	slices.SortFunc(hashes, func(a, b plumbing.Hash) int {
+		return a.Compare(b.Bytes())
+	})
+
+	return hashes, nil
+}
@@ -0,0 +1,130 @@
+package filesystem
+
+import (
+	"slices"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/format/packfile"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+var _ storer.ObjectPrefetcher = (*ObjectStorage)(nil)
+
+// PrefetchObjects reads the packed objects with the given hashes, packfile by
+// packfile in the order of their offsets, and puts them in the object cache.
+// Random reads of the packfiles are so turned into sequential ones. The
+// objects already cached, loose or missing are ignored, as the ones larger
+// than the LargeObjectThreshold. So that the objects prefetched are not
+// evicted before their use, no more than half of the object cache is
+// filled, the objects left being read when used.
+func (s *ObjectStorage) PrefetchObjects(hashes []plumbing.Hash) error {
+	if err := s.requireIndex(); err != nil {
+		return err
+	}
+
+	offsets := make(map[plumbing.Hash][]int64)
+	for _, h := range hashes {
+		if _, ok := s.objectCache.Get(h); ok {
+			continue
+		}
+
+		pack, _, offset := s.findObjectInPackfile(h)
+		if offset == -1 {
+			continue
+		}
+
+		offsets[pack] = append(offsets[pack], offset)
+	}
+
+	budget := s.prefetchBudget()
+	for pack, packOffsets := range offsets {
+		slices.Sort(packOffsets)
+		if err := s.prefetchFromPackfile(pack, slices.Compact(packOffsets), &budget); err != nil {
+			return err
+		}
+
+		if budget <= 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+// prefetchBudget returns the number of bytes of objects that can be
+// prefetched, half of the size of the object cache.
+func (s *ObjectStorage) prefetchBudget() int64 {
+	size := cache.DefaultMaxSize
+	if lru, ok := s.objectCache.(*cache.ObjectLRU); ok {
+		size = lru.MaxSize
+	}
+
+	return int64(size / 2)
+}
+
+func (s *ObjectStorage) prefetchFromPackfile(pack plumbing.Hash, offsets []int64, budget *int64) (err error) {
+	s.muI.RLock()
+	idx := s.index[pack]
+	s.muI.RUnlock()
+
+	p, err := s.packfile(idx, pack)
+	if err != nil {
+		return err
+	}
+
+	if !s.options.KeepDescriptors && s.options.MaxOpenDescriptors == 0 {
+		defer ioutil.CheckClose(p, &err)
+	}
+
+	for _, offset := range offsets {
+		obj, err := p.GetByOffset(offset)
+		if err != nil {
+			return err
+		}
+
+		if s.options.LargeObjectThreshold > 0 && obj.Size() > s.options.LargeObjectThreshold {
+			continue
+		}
+
+		if *budget -= obj.Size(); *budget < 0 {
+			return nil
+		}
+
+		// The objects which are not deltas are read lazily, their content
+		// is read now to be cached.
+		if _, ok := obj.(*packfile.FSObject); !ok {
+			continue
+		}
+
+		cached := s.NewEncodedObject()
+		if err := copyObject(cached, obj); err != nil {
+			return err
+		}
+
+		s.objectCache.Put(cached)
+	}
+
+	return nil
+}
+
+// copyObject copies the type, the size and the content of src to dst.
+func copyObject(dst, src plumbing.EncodedObject) (err error) {
+	r, err := src.Reader()
+	if err != nil {
+		return err
+	}
+	defer ioutil.CheckClose(r, &err)
+
+	dst.SetType(src.Type())
+	dst.SetSize(src.Size())
+	w, err := dst.Writer()
+	if err != nil {
+		return err
+	}
+	defer ioutil.CheckClose(w, &err)
+
+	_, err = ioutil.CopyBufferPool(w, r)
+	return err
+}
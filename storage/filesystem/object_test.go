@@ -1,21 +1,37 @@
 package filesystem
 
 import (
+	"bytes"
+	"compress/zlib"
+	"context"
 	"crypto"
+	"crypto/sha1"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
 	"github.com/go-git/go-billy/v6/osfs"
+	"github.com/go-git/go-billy/v6/util"
 	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/cache"
+	format "github.com/go-git/go-git/v6/plumbing/format/config"
+	"github.com/go-git/go-git/v6/plumbing/format/idxfile"
+	"github.com/go-git/go-git/v6/plumbing/format/packfile"
 	"github.com/go-git/go-git/v6/storage/filesystem/dotgit"
 )
 
@@ -45,6 +61,44 @@ func (s *FsSuite) TestGetFromObjectFile() {
 	s.Equal(expected, obj.Hash())
 }
 
+func (s *FsSuite) TestGetCorruptObjectFile() {
+	fs := memfs.New()
+	o := NewObjectStorage(dotgit.New(fs), cache.NewObjectLRUDefault())
+
+	obj := o.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	s.Require().NoError(err)
+	_, err = w.Write(bytes.Repeat([]byte("corrupt "), 100))
+	s.Require().NoError(err)
+	s.Require().NoError(w.Close())
+
+	h, err := o.SetEncodedObject(obj)
+	s.Require().NoError(err)
+
+	name := fs.Join("objects", h.String()[:2], h.String()[2:])
+	data, err := util.ReadFile(fs, name)
+	s.Require().NoError(err)
+
+	for _, corrupt := range [][]byte{
+		data[:len(data)/2],
+		[]byte("not zlib"),
+	} {
+		s.Require().NoError(util.WriteFile(fs, name, corrupt, 0o444))
+
+		_, err = o.EncodedObject(plumbing.AnyObject, h)
+		s.Require().ErrorIs(err, plumbing.ErrObjectCorrupt)
+
+		var cerr *plumbing.ObjectCorruptError
+		s.Require().ErrorAs(err, &cerr)
+		s.Equal(h, cerr.Hash)
+		s.Equal(name, cerr.Source)
+	}
+
+	_, err = o.EncodedObjectSize(h)
+	s.ErrorIs(err, plumbing.ErrObjectCorrupt)
+}
+
 func (s *FsSuite) TestGetFromPackfile() {
 	for _, f := range fixtures.Basic().ByTag(".git") {
 		fs := f.DotGit()
@@ -57,6 +111,28 @@ func (s *FsSuite) TestGetFromPackfile() {
 	}
 }
 
+func (s *FsSuite) TestEncodedObjectContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	unpacked := fixtures.ByTag(".git").ByTag("unpacked").One().DotGit()
+	packed := fixtures.Basic().ByTag(".git").One().DotGit()
+	for fs, h := range map[billy.Filesystem]string{
+		unpacked: "f3dfe29d268303fc6e1bbce268605fc99573406e",
+		packed:   "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+	} {
+		o := NewObjectStorage(dotgit.New(fs), cache.NewObjectLRUDefault())
+		expected := plumbing.NewHash(h)
+
+		_, err := o.EncodedObjectContext(ctx, plumbing.AnyObject, expected)
+		s.ErrorIs(err, context.Canceled)
+
+		obj, err := o.EncodedObjectContext(context.Background(), plumbing.AnyObject, expected)
+		s.Require().NoError(err)
+		s.Equal(expected, obj.Hash())
+	}
+}
+
 func firstNonMatching(packfileHash string) *fixtures.Fixture {
 	for _, fix := range fixtures.ByTag(".git") {
 		if fix.PackfileHash != packfileHash {
@@ -131,12 +207,12 @@ func (s *FsSuite) TestGetFromPackfileMaxOpenDescriptors() {
 	o := NewObjectStorageWithOptions(dotgit.New(fs), cache.NewObjectLRUDefault(), Options{MaxOpenDescriptors: 1})
 
 	expected := plumbing.NewHash("8d45a34641d73851e01d3754320b33bb5be3c4d3")
-	obj, err := o.getFromPackfile(expected, false)
+	obj, err := o.getFromPackfile(context.Background(), expected, false)
 	s.Require().NoError(err)
 	s.Equal(expected, obj.Hash())
 
 	expected = plumbing.NewHash("e9cfa4c9ca160546efd7e8582ec77952a27b17db")
-	obj, err = o.getFromPackfile(expected, false)
+	obj, err = o.getFromPackfile(context.Background(), expected, false)
 	s.Require().NoError(err)
 	s.Equal(expected, obj.Hash())
 
@@ -152,12 +228,12 @@ func (s *FsSuite) TestGetFromPackfileMaxOpenDescriptorsLargeObjectThreshold() {
 	})
 
 	expected := plumbing.NewHash("8d45a34641d73851e01d3754320b33bb5be3c4d3")
-	obj, err := o.getFromPackfile(expected, false)
+	obj, err := o.getFromPackfile(context.Background(), expected, false)
 	s.Require().NoError(err)
 	s.Equal(expected, obj.Hash())
 
 	expected = plumbing.NewHash("e9cfa4c9ca160546efd7e8582ec77952a27b17db")
-	obj, err = o.getFromPackfile(expected, false)
+	obj, err = o.getFromPackfile(context.Background(), expected, false)
 	s.Require().NoError(err)
 	s.Equal(expected, obj.Hash())
 
@@ -208,12 +284,12 @@ func (s *FsSuite) TestGetFromPackfileMultiplePackfiles() {
 	o := NewObjectStorage(dotgit.New(fs), cache.NewObjectLRUDefault())
 
 	expected := plumbing.NewHash("8d45a34641d73851e01d3754320b33bb5be3c4d3")
-	obj, err := o.getFromPackfile(expected, false)
+	obj, err := o.getFromPackfile(context.Background(), expected, false)
 	s.Require().NoError(err)
 	s.Equal(expected, obj.Hash())
 
 	expected = plumbing.NewHash("e9cfa4c9ca160546efd7e8582ec77952a27b17db")
-	obj, err = o.getFromPackfile(expected, false)
+	obj, err = o.getFromPackfile(context.Background(), expected, false)
 	s.Require().NoError(err)
 	s.Equal(expected, obj.Hash())
 }
@@ -223,14 +299,114 @@ func (s *FsSuite) TestGetFromPackfileMultiplePackfilesLargeObjectThreshold() {
 	o := NewObjectStorageWithOptions(dotgit.New(fs), cache.NewObjectLRUDefault(), Options{LargeObjectThreshold: 1})
 
 	expected := plumbing.NewHash("8d45a34641d73851e01d3754320b33bb5be3c4d3")
-	obj, err := o.getFromPackfile(expected, false)
+	obj, err := o.getFromPackfile(context.Background(), expected, false)
 	s.Require().NoError(err)
 	s.Equal(expected, obj.Hash())
 
 	expected = plumbing.NewHash("e9cfa4c9ca160546efd7e8582ec77952a27b17db")
-	obj, err = o.getFromPackfile(expected, false)
+	obj, err = o.getFromPackfile(context.Background(), expected, false)
+	s.Require().NoError(err)
+	s.Equal(expected, obj.Hash())
+}
+
+func (s *FsSuite) TestGetFromPackfileRefDeltaBaseInOtherPackfile() {
+	fs := fixtures.Basic().ByTag(".git").One().DotGit()
+	o := NewObjectStorage(dotgit.New(fs), cache.NewObjectLRUDefault())
+
+	baseHash := plumbing.NewHash("32858aad3c383ed1ff0a0f9bdf231d54a00c9e88")
+	base, err := o.EncodedObject(plumbing.BlobObject, baseHash)
+	s.Require().NoError(err)
+	src, err := readAll(base)
+	s.Require().NoError(err)
+
+	tgt := append(append([]byte(nil), src...), "foo\n"...)
+	h := plumbing.NewHasher(format.SHA1, plumbing.BlobObject, int64(len(tgt)))
+	_, err = h.Write(tgt)
+	s.Require().NoError(err)
+	expected := h.Sum()
+
+	// A thin packfile with a single REF_DELTA, whose base lives in the
+	// packfile of the fixture.
+	s.Require().NoError(writeRefDeltaPackfile(fs, expected, baseHash, packfile.DiffDelta(src, tgt)))
+
+	o = NewObjectStorage(dotgit.New(fs), cache.NewObjectLRUDefault())
+	obj, err := o.EncodedObject(plumbing.AnyObject, expected)
 	s.Require().NoError(err)
 	s.Equal(expected, obj.Hash())
+	s.Equal(plumbing.BlobObject, obj.Type())
+
+	content, err := readAll(obj)
+	s.Require().NoError(err)
+	s.Equal(tgt, content)
+}
+
+func readAll(o plumbing.EncodedObject) ([]byte, error) {
+	r, err := o.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// writeRefDeltaPackfile writes to fs a packfile and its index holding a
+// single REF_DELTA object, with the given hash, base and delta.
+func writeRefDeltaPackfile(fs billy.Filesystem, h, base plumbing.Hash, delta []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString("PACK")
+	_ = binary.Write(&buf, binary.BigEndian, uint32(2))
+	_ = binary.Write(&buf, binary.BigEndian, uint32(1))
+
+	offset := buf.Len()
+	size := len(delta)
+	c := byte(plumbing.REFDeltaObject)<<4 | byte(size&0x0f)
+	for size >>= 4; size > 0; size >>= 7 {
+		buf.WriteByte(c | 0x80)
+		c = byte(size & 0x7f)
+	}
+	buf.WriteByte(c)
+	buf.Write(base.Bytes())
+
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(delta); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	crc := crc32.ChecksumIEEE(buf.Bytes()[offset:])
+	sum := sha1.Sum(buf.Bytes())
+	buf.Write(sum[:])
+
+	var checksum plumbing.Hash
+	checksum.ResetBySize(len(sum))
+	_, _ = checksum.Write(sum[:])
+
+	w := new(idxfile.Writer)
+	_ = w.OnHeader(1)
+	w.Add(h, uint64(offset), crc)
+	if err := w.OnFooter(checksum); err != nil {
+		return err
+	}
+
+	idx, err := w.Index()
+	if err != nil {
+		return err
+	}
+
+	var idxBuf bytes.Buffer
+	if err := idxfile.Encode(&idxBuf, sha1.New(), idx); err != nil {
+		return err
+	}
+
+	name := fs.Join("objects", "pack", "pack-"+checksum.String())
+	if err := util.WriteFile(fs, name+".pack", buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	return util.WriteFile(fs, name+".idx", idxBuf.Bytes(), 0o644)
 }
 
 func (s *FsSuite) TestIter() {
@@ -414,6 +590,52 @@ func (s *FsSuite) TestPackfileIterKeepDescriptors() {
 	}
 }
 
+func (s *FsSuite) TestPrefetchObjects() {
+	fs := fixtures.Basic().ByTag(".git").One().DotGit()
+	ch := cache.NewObjectLRUDefault()
+	o := NewObjectStorage(dotgit.New(fs), ch)
+
+	hashes := []plumbing.Hash{
+		plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"),
+		plumbing.NewHash("a8d315b2b1c615d43042c3a62402b8a54288cf5c"),
+		plumbing.NewHash("32858aad3c383ed1ff0a0f9bdf231d54a00c9e88"),
+		plumbing.NewHash("0000000000000000000000000000000000000000"),
+	}
+	s.Require().NoError(o.PrefetchObjects(hashes))
+
+	for _, h := range hashes[:3] {
+		obj, ok := ch.Get(h)
+		s.Require().True(ok, h.String())
+		s.IsType(&plumbing.MemoryObject{}, obj)
+
+		read, err := o.EncodedObject(plumbing.AnyObject, h)
+		s.Require().NoError(err)
+		s.Equal(obj, read)
+	}
+}
+
+func (s *FsSuite) TestPrefetchObjectsCacheSize() {
+	fs := fixtures.Basic().ByTag(".git").One().DotGit()
+	ch := cache.NewObjectLRU(300 * cache.Byte)
+	o := NewObjectStorage(dotgit.New(fs), ch)
+
+	// Only half of the cache is filled: the commit of 245 bytes exceeds it,
+	// so the blob stored after it is not read ahead.
+	hashes := []plumbing.Hash{
+		plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"),
+		plumbing.NewHash("32858aad3c383ed1ff0a0f9bdf231d54a00c9e88"),
+	}
+	s.Require().NoError(o.PrefetchObjects(hashes))
+
+	_, ok := ch.Get(hashes[1])
+	s.False(ok)
+
+	for _, h := range hashes {
+		_, err := o.EncodedObject(plumbing.AnyObject, h)
+		s.Require().NoError(err)
+	}
+}
+
 func (s *FsSuite) TestGetFromObjectFileSharedCache() {
 	f1 := fixtures.ByTag("worktree").One().DotGit()
 	f2 := fixtures.ByTag("worktree").ByTag("submodule").One().DotGit()
@@ -713,6 +935,53 @@ func (s *FsSuite) TestObjectStorageAlternatesHasEncodedObject() {
 	s.Require().NoError(err)
 }
 
+// TestObjectStorageAlternatesSetEncodedObject verifies SetEncodedObject
+// does not write the objects held by the alternates.
+func (s *FsSuite) TestObjectStorageAlternatesSetEncodedObject() {
+	baseDir := s.T().TempDir()
+	templateFs := fixtures.Basic().ByTag(".git").One().DotGit(fixtures.WithTargetDir(func() string { return baseDir }))
+	commitHash := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+
+	workDotGit := filepath.Join(baseDir, "work", ".git")
+	alternatesDir := filepath.Join(workDotGit, "objects", "info")
+	err := os.MkdirAll(alternatesDir, 0o755)
+	s.Require().NoError(err)
+	alternatesFile := filepath.Join(alternatesDir, "alternates")
+	err = os.WriteFile(alternatesFile, []byte(templateFs.Root()+"/objects\n"), 0o644)
+	s.Require().NoError(err)
+
+	rootFs := osfs.New(baseDir)
+	workFs, err := rootFs.Chroot(filepath.Join("work", ".git"))
+	s.Require().NoError(err)
+	dg := dotgit.NewWithOptions(workFs, dotgit.Options{AlternatesFS: rootFs})
+	storage := NewObjectStorage(dg, cache.NewObjectLRUDefault())
+
+	shared, err := storage.EncodedObject(plumbing.CommitObject, commitHash)
+	s.Require().NoError(err)
+
+	h, err := storage.SetEncodedObject(shared)
+	s.Require().NoError(err)
+	s.Equal(commitHash, h)
+
+	_, err = dg.Object(commitHash)
+	s.True(os.IsNotExist(err))
+
+	o := &plumbing.MemoryObject{}
+	o.SetType(plumbing.BlobObject)
+	_, err = o.Write([]byte("not shared"))
+	s.Require().NoError(err)
+
+	h, err = storage.SetEncodedObject(o)
+	s.Require().NoError(err)
+
+	f, err := dg.Object(h)
+	s.Require().NoError(err)
+	s.NoError(f.Close())
+
+	err = storage.Close()
+	s.Require().NoError(err)
+}
+
 // TestObjectStorageAlternatesEncodedObjectSize verifies EncodedObjectSize
 // correctly checks alternates.
 func (s *FsSuite) TestObjectStorageAlternatesEncodedObjectSize() {
@@ -811,3 +1080,142 @@ func (s *FsSuite) TestObjectStorageAlternatesInitError() {
 	s.Error(err)
 	s.NotErrorIs(err, plumbing.ErrObjectNotFound)
 }
+
+func (s *FsSuite) TestTransaction() {
+	fs := memfs.New()
+	st := NewStorage(fs, cache.NewObjectLRUDefault())
+
+	tx := st.Begin()
+	var hashes []plumbing.Hash
+	for _, content := range []string{"foo", "bar", "qux"} {
+		obj := st.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		s.Require().NoError(err)
+		_, err = w.Write([]byte(content))
+		s.Require().NoError(err)
+		s.Require().NoError(w.Close())
+
+		h, err := tx.SetEncodedObject(obj)
+		s.Require().NoError(err)
+		hashes = append(hashes, h)
+	}
+
+	_, err := tx.EncodedObject(plumbing.BlobObject, hashes[0])
+	s.NoError(err)
+	s.ErrorIs(st.HasEncodedObject(hashes[0]), plumbing.ErrObjectNotFound)
+
+	s.Require().NoError(tx.Commit())
+
+	packs, err := st.ObjectPacks()
+	s.Require().NoError(err)
+	s.Len(packs, 1)
+
+	for _, h := range hashes {
+		s.NoError(st.HasEncodedObject(h))
+	}
+
+	err = st.ForEachObjectHash(func(plumbing.Hash) error {
+		s.Fail("unexpected loose object")
+		return nil
+	})
+	s.NoError(err)
+
+	tx = st.Begin()
+	obj := st.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	h, err := tx.SetEncodedObject(obj)
+	s.Require().NoError(err)
+	s.Require().NoError(tx.Rollback())
+	s.Require().NoError(tx.Commit())
+	s.ErrorIs(st.HasEncodedObject(h), plumbing.ErrObjectNotFound)
+}
+
+func (s *FsSuite) TestObjectTime() {
+	fs := fixtures.Basic().ByTag(".git").One().DotGit()
+	o := NewObjectStorage(dotgit.New(fs), cache.NewObjectLRUDefault())
+
+	pack := plumbing.NewHash("a3fed42da1e8189a077c0e6846c040dcf73fc9dd")
+	fi, err := fs.Stat(fmt.Sprintf("objects/pack/pack-%s.pack", pack))
+	s.Require().NoError(err)
+
+	commit := plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	t, err := o.ObjectTime(commit)
+	s.Require().NoError(err)
+	s.Equal(fi.ModTime(), t)
+
+	_, err = o.ObjectTime(plumbing.NewHash("0000000000000000000000000000000000000001"))
+	s.ErrorIs(err, plumbing.ErrObjectNotFound)
+
+	hashes, err := o.packHashes(pack)
+	s.Require().NoError(err)
+	s.Len(hashes, 31)
+
+	mtimes := make(map[plumbing.Hash]time.Time)
+	for i, h := range hashes {
+		mtimes[h] = time.Unix(int64(1700000000+i), 0)
+	}
+
+	s.Error(o.SetObjectPackMtimes(pack, map[plumbing.Hash]time.Time{commit: time.Now()}))
+	s.Require().NoError(o.SetObjectPackMtimes(pack, mtimes))
+
+	// The mtimes are read back from the file by a new storage.
+	o = NewObjectStorage(dotgit.New(fs), cache.NewObjectLRUDefault())
+	for h, want := range mtimes {
+		t, err := o.ObjectTime(h)
+		s.Require().NoError(err)
+		s.Equal(want, t)
+	}
+
+	all := make(map[plumbing.Hash]time.Time)
+	s.Require().NoError(o.ForEachObjectTime(func(h plumbing.Hash, t time.Time) error {
+		all[h] = t
+		return nil
+	}))
+	s.Equal(mtimes, all)
+}
+
+func TestObjectTimeGitCruftPack(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=foo", "GIT_AUTHOR_EMAIL=foo@example.com",
+			"GIT_COMMITTER_NAME=foo", "GIT_COMMITTER_EMAIL=foo@example.com")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "foo")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unreachable"), []byte("foo"), 0o644))
+	blob := plumbing.NewHash(git("hash-object", "-w", "unreachable"))
+
+	mtime := time.Unix(1600000000, 0)
+	loose := filepath.Join(dir, ".git", "objects", blob.String()[:2], blob.String()[2:])
+	require.NoError(t, os.Chtimes(loose, mtime, mtime))
+
+	cmd := exec.Command("git", "repack", "--cruft", "-d")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("git does not support cruft packs: %s", out)
+	}
+
+	o := NewObjectStorage(dotgit.New(osfs.New(filepath.Join(dir, ".git"))), cache.NewObjectLRUDefault())
+	_, err := o.LooseObjectTime(blob)
+	require.Error(t, err)
+
+	got, err := o.ObjectTime(blob)
+	require.NoError(t, err)
+	assert.Equal(t, mtime, got)
+}
@@ -0,0 +1,123 @@
+package filesystem
+
+import (
+	"errors"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/packfile"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+// txPackWindow is the number of objects the deltas of the packfile written
+// by a transaction are searched among.
+const txPackWindow = 10
+
+var errTxStorerReadOnly = errors.New("transaction storer is read-only")
+
+// Begin returns a new transaction. The objects set in the transaction are
+// kept in memory until Commit, which writes them all to the storage as a
+// single packfile and its index.
+func (s *ObjectStorage) Begin() storer.Transaction {
+	return &TxObjectStorage{
+		Storage: s,
+		Objects: make(map[plumbing.Hash]plumbing.EncodedObject),
+	}
+}
+
+// TxObjectStorage implements storer.Transaction for filesystem storage.
+type TxObjectStorage struct {
+	Storage *ObjectStorage
+	Objects map[plumbing.Hash]plumbing.EncodedObject
+}
+
+// SetEncodedObject stores the given EncodedObject in the transaction.
+func (tx *TxObjectStorage) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	if obj.Type() == plumbing.OFSDeltaObject || obj.Type() == plumbing.REFDeltaObject {
+		return plumbing.ZeroHash, plumbing.ErrInvalidType
+	}
+
+	h := obj.Hash()
+	tx.Objects[h] = obj
+
+	return h, nil
+}
+
+// EncodedObject returns the object with the given type and hash from the transaction.
+func (tx *TxObjectStorage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, ok := tx.Objects[h]
+	if !ok || (plumbing.AnyObject != t && obj.Type() != t) {
+		return nil, plumbing.ErrObjectNotFound
+	}
+
+	return obj, nil
+}
+
+// Commit writes all objects in the transaction to the storage as a single
+// packfile. The packfile and its index are only moved into the objects
+// directory once fully written, so either all the objects are stored or
+// none of them is.
+func (tx *TxObjectStorage) Commit() (err error) {
+	if len(tx.Objects) == 0 {
+		return nil
+	}
+
+	hashes := make([]plumbing.Hash, 0, len(tx.Objects))
+	for h := range tx.Objects {
+		hashes = append(hashes, h)
+	}
+
+	w, err := tx.Storage.PackfileWriter()
+	if err != nil {
+		return err
+	}
+
+	defer ioutil.CheckClose(w, &err)
+
+	e := packfile.NewEncoder(w, &txObjectStorer{ObjectStorage: tx.Storage, tx: tx}, false)
+	if _, err := e.Encode(hashes, txPackWindow); err != nil {
+		return err
+	}
+
+	clear(tx.Objects)
+	return nil
+}
+
+// Rollback discards all objects in the transaction.
+func (tx *TxObjectStorage) Rollback() error {
+	clear(tx.Objects)
+	return nil
+}
+
+// txObjectStorer is the storer the packfile of a transaction is encoded
+// from, reading the objects of the transaction.
+type txObjectStorer struct {
+	*ObjectStorage
+	tx *TxObjectStorage
+}
+
+func (s *txObjectStorer) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	return s.tx.EncodedObject(t, h)
+}
+
+func (s *txObjectStorer) DeltaObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	return s.tx.EncodedObject(t, h)
+}
+
+// Config returns a config holding the object format of the storage, for the
+// encoder to hash the packfile accordingly.
+func (s *txObjectStorer) Config() (*config.Config, error) {
+	cfg := config.NewConfig()
+	cfg.Extensions.ObjectFormat = s.options.ObjectFormat
+
+	return cfg, nil
+}
+
+func (s *txObjectStorer) SetConfig(*config.Config) error {
+	return errTxStorerReadOnly
+}
+
+func (s *txObjectStorer) AddAlternate(string) error {
+	return errTxStorerReadOnly
+}
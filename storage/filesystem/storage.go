@@ -4,6 +4,7 @@ package filesystem
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-git/go-billy/v6"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/cache"
 	formatcfg "github.com/go-git/go-git/v6/plumbing/format/config"
 	"github.com/go-git/go-git/v6/storage/filesystem/dotgit"
+	"github.com/go-git/go-git/v6/utils/ioutil"
 )
 
 // Storage is an implementation of git.Storer that stores data on disk in the
@@ -43,7 +45,9 @@ type Options struct {
 	// open. If KeepDescriptors is true, all file descriptors will remain open.
 	MaxOpenDescriptors int
 	// LargeObjectThreshold maximum object size (in bytes) that will be read in to memory.
-	// If left unset or set to 0 there is no limit
+	// If left unset or set to 0 there is no limit. The packed objects are
+	// streamed from their packfile regardless, except the ones stored as
+	// deltas, which are always inflated in memory.
 	LargeObjectThreshold int64
 	// AlternatesFS provides the billy filesystem to be used for Git Alternates.
 	// If none is provided, it falls back to using the underlying instance used for
@@ -68,6 +72,21 @@ type Options struct {
 	// IndexCache provides an optional cache implementation for index data.
 	// If left as nil, a default stat-based implementation is created automatically.
 	IndexCache IndexCache
+
+	// LockTimeout is how long acquiring a lock on a file is retried when it
+	// fails, as it may on network filesystems, before dotgit.ErrLockTimeout
+	// is returned. Zero does not retry.
+	LockTimeout time.Duration
+	// LockRetryInterval is the time waited between two attempts to acquire a
+	// lock. Defaults to 100 milliseconds.
+	LockRetryInterval time.Duration
+	// StaleLockAge makes the lock files of git be honoured, the ones older
+	// than it being removed. See dotgit.Options.
+	StaleLockAge time.Duration
+	// TempFilesInTargetDir writes the temporary files of loose objects in
+	// the directory they are renamed into, for filesystems where renames
+	// across directories may fail.
+	TempFilesInTargetDir bool
 }
 
 // NewStorage returns a new Storage backed by a given `fs.Filesystem` and cache.
@@ -105,6 +124,11 @@ func NewStorageWithOptions(fs billy.Filesystem, c cache.Object, ops Options) *St
 		ObjectFormat:      ops.ObjectFormat,
 		ReadReverseIndex:  readRevIdx,
 		WriteReverseIndex: writeRevIdx,
+
+		LockTimeout:          ops.LockTimeout,
+		LockRetryInterval:    ops.LockRetryInterval,
+		StaleLockAge:         ops.StaleLockAge,
+		TempFilesInTargetDir: ops.TempFilesInTargetDir,
 	}
 	dir := dotgit.NewWithOptions(fs, dirOps)
 
@@ -217,6 +241,19 @@ func (s *Storage) AddAlternate(remote string) error {
 	return nil
 }
 
+// SetFetchHead writes the FETCH_HEAD file, recording the references of the
+// last fetch as formatted by git fetch.
+func (s *Storage) SetFetchHead(content []byte) (err error) {
+	f, err := s.dir.FetchHeadWriter()
+	if err != nil {
+		return err
+	}
+
+	defer ioutil.CheckClose(f, &err)
+	_, err = f.Write(content)
+	return err
+}
+
 // LowMemoryMode returns true if low memory mode is enabled.
 func (s *Storage) LowMemoryMode() bool {
 	return !s.options.HighMemoryMode
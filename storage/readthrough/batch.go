@@ -0,0 +1,116 @@
+package readthrough
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+// ErrBatchBroken is returned by a BatchFetcher once a request failed midway,
+// as the responses read would no longer match the requests.
+var ErrBatchBroken = errors.New("readthrough: batch stream out of sync")
+
+// BatchFetcher fetches the objects through the protocol of
+// git cat-file --batch: the hash of each object is written, followed by a
+// newline, and the object is read as
+//
+//	<hash> SP <type> SP <size> LF
+//	<contents> LF
+//
+// or as "<hash> SP missing LF" if the remote doesn't hold it. It is safe for
+// concurrent use, the objects being fetched one at a time.
+//
+// If a request fails once sent, such as on a short read of the contents, the
+// BatchFetcher is broken and every following request fails with an error
+// wrapping ErrBatchBroken.
+type BatchFetcher struct {
+	mu sync.Mutex
+	w  io.Writer
+	r  *bufio.Reader
+	// broken is the error that broke the stream, if any.
+	broken error
+}
+
+// NewBatchFetcher returns a new BatchFetcher writing the requests to w and
+// reading the objects from r, such as the stdin and stdout of a
+// git cat-file --batch process.
+func NewBatchFetcher(w io.Writer, r io.Reader) *BatchFetcher {
+	return &BatchFetcher{w: w, r: bufio.NewReader(r)}
+}
+
+// FetchObject honors the Fetcher interface. The context is only checked
+// before the request is sent.
+func (f *BatchFetcher) FetchObject(ctx context.Context, h plumbing.Hash, obj plumbing.EncodedObject) (err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.broken != nil {
+		return fmt.Errorf("%w: %w", ErrBatchBroken, f.broken)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+			f.broken = err
+		}
+	}()
+
+	if _, err := fmt.Fprintf(f.w, "%s\n", h); err != nil {
+		return err
+	}
+
+	line, err := f.r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 2 && fields[1] == "missing" {
+		return plumbing.ErrObjectNotFound
+	}
+
+	if len(fields) != 3 || fields[0] != h.String() {
+		return fmt.Errorf("readthrough: malformed batch header: %q", line)
+	}
+
+	t, err := plumbing.ParseObjectType(fields[1])
+	if err != nil {
+		return err
+	}
+
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("readthrough: malformed batch header: %q", line)
+	}
+
+	obj.SetType(t)
+	obj.SetSize(size)
+
+	w, err := obj.Writer()
+	if err != nil {
+		return err
+	}
+
+	defer ioutil.CheckClose(w, &err)
+
+	if _, err := io.CopyN(w, f.r, size); err != nil {
+		return err
+	}
+
+	if b, err := f.r.ReadByte(); err != nil || b != '\n' {
+		return fmt.Errorf("readthrough: malformed batch contents of %s", h)
+	}
+
+	return nil
+}
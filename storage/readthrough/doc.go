@@ -0,0 +1,9 @@
+// Package readthrough is an implementation of git.Storer reading through to
+// a remote object backend: the objects missing from its storage are fetched
+// one by one from the remote, and stored before being returned. Unlike a
+// partial clone, the remote is not a git remote of the repository but any
+// backend serving its objects, such as a shared object store.
+//
+// The API and functionality of this package are considered EXPERIMENTAL and is
+// not considered stable nor production ready.
+package readthrough
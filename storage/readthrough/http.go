@@ -0,0 +1,84 @@
+package readthrough
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/objfile"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+// HTTPFetcher fetches the objects as loose objects from an HTTP endpoint, at
+// objects/<first two hex digits>/<remaining hex digits> under its URL, as
+// served to the dumb HTTP protocol.
+type HTTPFetcher struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPFetcher returns a new HTTPFetcher fetching the objects under u with
+// client, http.DefaultClient if nil.
+func NewHTTPFetcher(u string, client *http.Client) *HTTPFetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTPFetcher{url: u, client: client}
+}
+
+// FetchObject honors the Fetcher interface.
+func (f *HTTPFetcher) FetchObject(ctx context.Context, h plumbing.Hash, obj plumbing.EncodedObject) (err error) {
+	hex := h.String()
+	u, err := url.JoinPath(f.url, "objects", hex[:2], hex[2:])
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer ioutil.CheckClose(res.Body, &err)
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return plumbing.ErrObjectNotFound
+	default:
+		return fmt.Errorf("readthrough: unexpected status code fetching %s: %d", h, res.StatusCode)
+	}
+
+	rd, err := objfile.NewReader(res.Body)
+	if err != nil {
+		return err
+	}
+
+	defer ioutil.CheckClose(rd, &err)
+
+	t, size, err := rd.Header()
+	if err != nil {
+		return err
+	}
+
+	obj.SetType(t)
+	obj.SetSize(size)
+
+	w, err := obj.Writer()
+	if err != nil {
+		return err
+	}
+
+	defer ioutil.CheckClose(w, &err)
+
+	_, err = ioutil.CopyBufferPool(w, rd)
+	return err
+}
@@ -0,0 +1,128 @@
+package readthrough
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage"
+)
+
+// ErrInvalidObject is returned when a fetched object doesn't match the hash
+// it was fetched by.
+var ErrInvalidObject = errors.New("readthrough: fetched object does not match its hash")
+
+// Fetcher fetches single objects from a remote object backend.
+type Fetcher interface {
+	// FetchObject writes the object with the given hash to obj, setting its
+	// type and size. It returns plumbing.ErrObjectNotFound if the remote
+	// doesn't hold the object.
+	FetchObject(ctx context.Context, h plumbing.Hash, obj plumbing.EncodedObject) error
+}
+
+// Storage is a storage.Storer reading the objects missing from its storage
+// through a Fetcher. The fetched objects are stored, so that they are only
+// fetched once.
+type Storage struct {
+	storage.Storer
+	fetcher Fetcher
+}
+
+// packfileWriter implements the storer.PackfileWriter interface over a
+// storage supporting it.
+type packfileWriter struct {
+	*Storage
+	pw storer.PackfileWriter
+}
+
+// NewStorage returns a new Storage reading through s, fetching the objects
+// missing from it with f. The returned storer implements
+// storer.PackfileWriter if s does.
+func NewStorage(s storage.Storer, f Fetcher) storage.Storer {
+	st := &Storage{Storer: s, fetcher: f}
+	if pw, ok := s.(storer.PackfileWriter); ok {
+		return &packfileWriter{Storage: st, pw: pw}
+	}
+
+	return st
+}
+
+// EncodedObject honors the storer.EncodedObjectStorer interface.
+func (s *Storage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	return s.EncodedObjectContext(context.Background(), t, h)
+}
+
+// EncodedObjectContext honors the storer.ContextEncodedObjectStorer interface.
+func (s *Storage) EncodedObjectContext(ctx context.Context, t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := storer.EncodedObjectContext(ctx, s.Storer, t, h)
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return obj, err
+	}
+
+	obj, err = s.fetch(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+
+	if t != plumbing.AnyObject && obj.Type() != t {
+		return nil, plumbing.ErrObjectNotFound
+	}
+
+	return obj, nil
+}
+
+// HasEncodedObject honors the storer.EncodedObjectStorer interface. As a
+// Fetcher only tells whether the remote holds an object by fetching it, the
+// objects missing from the storage are downloaded and stored, so that
+// checking for a large object costs as much as reading it.
+func (s *Storage) HasEncodedObject(h plumbing.Hash) error {
+	err := s.Storer.HasEncodedObject(h)
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return err
+	}
+
+	_, err = s.fetch(context.Background(), h)
+	return err
+}
+
+// EncodedObjectSize honors the storer.EncodedObjectStorer interface. The
+// objects missing from the storage are fetched.
+func (s *Storage) EncodedObjectSize(h plumbing.Hash) (int64, error) {
+	sz, err := s.Storer.EncodedObjectSize(h)
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return sz, err
+	}
+
+	obj, err := s.fetch(context.Background(), h)
+	if err != nil {
+		return 0, err
+	}
+
+	return obj.Size(), nil
+}
+
+// fetch fetches the object from the remote and stores it.
+func (s *Storage) fetch(ctx context.Context, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj := s.NewEncodedObject()
+	if err := s.fetcher.FetchObject(ctx, h, obj); err != nil {
+		return nil, err
+	}
+
+	if obj.Hash() != h {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidObject, h)
+	}
+
+	if _, err := s.SetEncodedObject(obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// PackfileWriter honors the storer.PackfileWriter interface.
+func (s *packfileWriter) PackfileWriter() (io.WriteCloser, error) {
+	return s.pw.PackfileWriter()
+}
@@ -0,0 +1,191 @@
+package readthrough
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/format/objfile"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/storage/filesystem"
+	"github.com/go-git/go-git/v6/storage/memory"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+)
+
+var (
+	commitHash  = plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5")
+	missingHash = plumbing.NewHash("0000000000000000000000000000000000000001")
+)
+
+// serveObjects serves the objects of st as loose objects, counting the
+// requests.
+func serveObjects(t *testing.T, st storer.EncodedObjectStorer, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		h, ok := plumbing.FromHex(strings.ReplaceAll(strings.TrimPrefix(r.URL.Path, "/objects/"), "/", ""))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		obj, err := st.EncodedObject(plumbing.AnyObject, h)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		ow := objfile.NewWriter(w)
+		defer ow.Close()
+
+		rd, err := obj.Reader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rd.Close()
+
+		if err := ow.WriteHeader(obj.Type(), obj.Size()); err == nil {
+			_, _ = ioutil.CopyBufferPool(ow, rd)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestStorageHTTP(t *testing.T) {
+	t.Parallel()
+
+	remote := filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault())
+
+	var requests atomic.Int32
+	srv := serveObjects(t, remote, &requests)
+
+	local := memory.NewStorage()
+	st := NewStorage(local, NewHTTPFetcher(srv.URL, srv.Client()))
+
+	c, err := object.GetCommit(st, commitHash)
+	require.NoError(t, err)
+	assert.Equal(t, "mcuadros@gmail.com", c.Author.Email)
+
+	tree, err := c.Tree()
+	require.NoError(t, err)
+	assert.NotEmpty(t, tree.Entries)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// The fetched objects are stored locally.
+	require.NoError(t, local.HasEncodedObject(commitHash))
+	_, err = object.GetCommit(st, commitHash)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+
+	_, err = st.EncodedObject(plumbing.TreeObject, commitHash)
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+
+	size, err := st.EncodedObjectSize(tree.Entries[0].Hash)
+	require.NoError(t, err)
+	assert.Positive(t, size)
+	require.NoError(t, local.HasEncodedObject(tree.Entries[0].Hash))
+
+	assert.ErrorIs(t, st.HasEncodedObject(missingHash), plumbing.ErrObjectNotFound)
+}
+
+func TestStorageBatch(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := fixtures.Basic().One().DotGit(fixtures.WithTargetDir(t.TempDir)).Root()
+	cmd := exec.Command("git", "--git-dir", dir, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = stdin.Close()
+		_ = cmd.Wait()
+	})
+
+	local := memory.NewStorage()
+	st := NewStorage(local, NewBatchFetcher(stdin, stdout))
+
+	c, err := object.GetCommit(st, commitHash)
+	require.NoError(t, err)
+	assert.Equal(t, "mcuadros@gmail.com", c.Author.Email)
+	require.NoError(t, local.HasEncodedObject(commitHash))
+
+	_, err = st.EncodedObject(plumbing.AnyObject, missingHash)
+	assert.ErrorIs(t, err, plumbing.ErrObjectNotFound)
+
+	_, err = c.Tree()
+	require.NoError(t, err)
+}
+
+func TestBatchFetcherShortRead(t *testing.T) {
+	t.Parallel()
+
+	// The contents are cut short, leaving the stream out of sync.
+	out := strings.NewReader(commitHash.String() + " blob 10\nabc")
+	f := NewBatchFetcher(io.Discard, out)
+
+	err := f.FetchObject(context.Background(), commitHash, &plumbing.MemoryObject{})
+	assert.ErrorIs(t, err, io.EOF)
+
+	err = f.FetchObject(context.Background(), commitHash, &plumbing.MemoryObject{})
+	assert.ErrorIs(t, err, ErrBatchBroken)
+}
+
+type fetcherFunc func(ctx context.Context, h plumbing.Hash, obj plumbing.EncodedObject) error
+
+func (f fetcherFunc) FetchObject(ctx context.Context, h plumbing.Hash, obj plumbing.EncodedObject) error {
+	return f(ctx, h, obj)
+}
+
+func TestStorageInvalidObject(t *testing.T) {
+	t.Parallel()
+
+	local := memory.NewStorage()
+	st := NewStorage(local, fetcherFunc(func(_ context.Context, _ plumbing.Hash, obj plumbing.EncodedObject) error {
+		obj.SetType(plumbing.BlobObject)
+		w, err := obj.Writer()
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write([]byte("not the object"))
+		return err
+	}))
+
+	_, err := st.EncodedObject(plumbing.BlobObject, missingHash)
+	assert.ErrorIs(t, err, ErrInvalidObject)
+	assert.ErrorIs(t, local.HasEncodedObject(missingHash), plumbing.ErrObjectNotFound)
+}
+
+func TestNewStoragePackfileWriter(t *testing.T) {
+	t.Parallel()
+
+	fs := filesystem.NewStorage(fixtures.Basic().One().DotGit(fixtures.WithTargetDir(t.TempDir)), cache.NewObjectLRUDefault())
+	_, ok := NewStorage(fs, nil).(storer.PackfileWriter)
+	assert.True(t, ok)
+
+	_, ok = NewStorage(memory.NewStorage(), nil).(storer.PackfileWriter)
+	assert.False(t, ok)
+}
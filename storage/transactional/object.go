@@ -1,6 +1,7 @@
 package transactional
 
 import (
+	"context"
 	"errors"
 
 	"github.com/go-git/go-git/v6/plumbing"
@@ -54,6 +55,16 @@ func (o *ObjectStorage) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (p
 	return obj, err
 }
 
+// EncodedObjectContext honors the storer.ContextEncodedObjectStorer interface.
+func (o *ObjectStorage) EncodedObjectContext(ctx context.Context, t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := storer.EncodedObjectContext(ctx, o.EncodedObjectStorer, t, h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return storer.EncodedObjectContext(ctx, o.temporal, t, h)
+	}
+
+	return obj, err
+}
+
 // IterEncodedObjects honors the storer.EncodedObjectStorer interface.
 func (o *ObjectStorage) IterEncodedObjects(t plumbing.ObjectType) (storer.EncodedObjectIter, error) {
 	baseIter, err := o.EncodedObjectStorer.IterEncodedObjects(t)
@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
 	"github.com/go-git/go-git/v6/plumbing/format/index"
 	"github.com/go-git/go-git/v6/plumbing/transport"
 )
@@ -283,6 +284,120 @@ func (s *Submodule) fetchAndCheckout(
 	return r.Storer.SetReference(head)
 }
 
+// checkout updates the working tree of the submodule to the commit recorded
+// in the given index of the superproject, following the strategy. As nothing
+// is fetched, the submodules not initialized or not populated are skipped.
+func (s *Submodule) checkout(idx *index.Index, strategy SubmoduleStrategy, force bool) error {
+	if !s.initialized {
+		return nil
+	}
+
+	e, err := idx.Entry(s.c.Path)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if e.Mode != filemode.Submodule {
+		return nil
+	}
+
+	populated, err := s.populated()
+	if err != nil || !populated {
+		return err
+	}
+
+	r, err := s.Repository()
+	if err != nil {
+		return err
+	}
+
+	head, err := r.Head()
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
+
+	if head != nil && head.Hash() == e.Hash {
+		return nil
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if strategy != MergeSubmoduleUpdate || head == nil || !head.Name().IsBranch() {
+		return w.Checkout(&CheckoutOptions{
+			Hash:              e.Hash,
+			Force:             force,
+			SubmoduleStrategy: strategy,
+		})
+	}
+
+	err = r.Merge(*plumbing.NewHashReference(head.Name(), e.Hash), MergeOptions{
+		Strategy: FastForwardMerge,
+	})
+	if err != nil {
+		return err
+	}
+
+	mode := MergeReset
+	if force {
+		mode = HardReset
+	}
+
+	if err := w.reset(&ResetOptions{Commit: e.Hash, Mode: mode}); err != nil {
+		return err
+	}
+
+	return w.checkoutSubmodules(strategy, force)
+}
+
+// populated returns whether the repository of the submodule was created,
+// with a HEAD.
+func (s *Submodule) populated() (bool, error) {
+	st, err := s.w.r.Storer.Module(s.c.Name)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = st.Reference(plumbing.HEAD)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// checkoutSubmodules updates the working trees of the populated submodules
+// to the commits recorded in the index, following the strategy.
+func (w *Worktree) checkoutSubmodules(strategy SubmoduleStrategy, force bool) error {
+	l, err := w.Submodules()
+	if err != nil {
+		return err
+	}
+
+	if len(l) == 0 {
+		return nil
+	}
+
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range l {
+		if err := s.checkout(idx, strategy, force); err != nil {
+			return fmt.Errorf("submodule %q: %w", s.c.Name, err)
+		}
+	}
+
+	return nil
+}
+
 // Submodules list of several submodules from the same repository.
 type Submodules []*Submodule
 
@@ -2,10 +2,16 @@ package git
 
 import (
 	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/go-git/go-billy/v6/memfs"
 	fixtures "github.com/go-git/go-git-fixtures/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/go-git/go-git/v6/config"
@@ -274,3 +280,97 @@ func (s *SubmoduleSuite) TestSubmoduleParseScp() {
 	_, err := submodule.Repository()
 	s.Require().NoError(err)
 }
+
+func TestCheckoutSubmoduleStrategy(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=foo", "GIT_AUTHOR_EMAIL=foo@example.com",
+			"GIT_COMMITTER_NAME=foo", "GIT_COMMITTER_EMAIL=foo@example.com")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+
+	// A submodule with two commits, recorded by the master and old branches
+	// of the superproject.
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	git(sub, "init", "-q", "-b", "main")
+	var commits []string
+	for _, content := range []string{"old", "new"} {
+		require.NoError(t, os.WriteFile(filepath.Join(sub, "file"), []byte(content), 0o644))
+		git(sub, "add", "file")
+		git(sub, "commit", "-q", "-m", content)
+		commits = append(commits, git(sub, "rev-parse", "HEAD"))
+	}
+
+	super := filepath.Join(dir, "super")
+	require.NoError(t, os.Mkdir(super, 0o755))
+	git(super, "init", "-q", "-b", "master")
+	git(super, "-c", "protocol.file.allow=always", "submodule", "add", "-q", sub, "sub")
+	git(super, "commit", "-q", "-m", "new")
+	git(super, "checkout", "-q", "-b", "old")
+	git(filepath.Join(super, "sub"), "checkout", "-q", commits[0])
+	git(super, "commit", "-q", "-am", "old")
+	git(super, "checkout", "-q", "master")
+	git(filepath.Join(super, "sub"), "checkout", "-q", "-B", "work", commits[1])
+
+	r, err := PlainOpen(super)
+	require.NoError(t, err)
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	assertSubmodule := func(commit, content string, branch plumbing.ReferenceName) {
+		t.Helper()
+
+		sm, err := w.Submodule("sub")
+		require.NoError(t, err)
+		sr, err := sm.Repository()
+		require.NoError(t, err)
+
+		head, err := sr.Storer.Reference(plumbing.HEAD)
+		require.NoError(t, err)
+		assert.Equal(t, branch, head.Target())
+
+		resolved, err := sr.Head()
+		require.NoError(t, err)
+		assert.Equal(t, commit, resolved.Hash().String())
+
+		got, err := os.ReadFile(filepath.Join(super, "sub", "file"))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(got))
+	}
+
+	// By default the submodule is left untouched.
+	require.NoError(t, w.Checkout(&CheckoutOptions{Branch: "refs/heads/old"}))
+	assertSubmodule(commits[1], "new", "refs/heads/work")
+
+	// The merge strategy fast-forwards the current branch of the submodule.
+	git(filepath.Join(super, "sub"), "checkout", "-q", "-B", "work", commits[0])
+	require.NoError(t, w.Checkout(&CheckoutOptions{
+		Branch:            plumbing.Master,
+		SubmoduleStrategy: MergeSubmoduleUpdate,
+	}))
+	assertSubmodule(commits[1], "new", "refs/heads/work")
+
+	// The checkout strategy detaches the HEAD of the submodule.
+	require.NoError(t, w.Checkout(&CheckoutOptions{
+		Branch:            "refs/heads/old",
+		SubmoduleStrategy: CheckoutSubmoduleUpdate,
+	}))
+	assertSubmodule(commits[0], "old", "")
+
+	status, err := w.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), status.String())
+}
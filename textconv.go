@@ -0,0 +1,126 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/x/plugin"
+)
+
+// ErrTextConvFailed is returned when the textconv command of a diff driver
+// fails.
+var ErrTextConvFailed = errors.New("textconv failed")
+
+// TextConv returns the conversion of the files diffed by the patches, to be
+// set as object.PatchOptions.TextConv. The files whose diff attribute is set
+// to a driver with a diff.<driver>.textconv command are converted by running
+// it, as git diff does. The attributes are the ones of the worktree, and of
+// the tree of HEAD in bare repositories. Without a config loader only the
+// config of the repository is read.
+func (r *Repository) TextConv() (object.TextConv, error) {
+	scope := config.SystemScope
+	if !plugin.Has(plugin.ConfigLoader()) {
+		scope = config.LocalScope
+	}
+
+	cfg, err := r.ConfigScoped(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := r.diffAttributes()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(path string, content []byte) ([]byte, bool, error) {
+		attr := gitattributes.MatchDiff(attrs, strings.Split(path, "/"))
+		if attr == nil || !attr.IsValueSet() {
+			return nil, false, nil
+		}
+
+		driver, ok := cfg.DiffDrivers[attr.Value()]
+		if !ok || driver.TextConv == "" {
+			return nil, false, nil
+		}
+
+		text, err := runTextConv(driver.TextConv, content)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", path, err)
+		}
+
+		return text, true, nil
+	}, nil
+}
+
+// diffAttributes returns the gitattributes of the worktree, which select how
+// the files are diffed. Bare repositories use the ones of the tree of HEAD.
+func (r *Repository) diffAttributes() ([]gitattributes.MatchAttribute, error) {
+	if r.wt != nil {
+		attrs, err := gitattributes.ReadPatterns(r.wt, nil)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		return attrs, nil
+	}
+
+	head, err := r.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return gitattributes.ReadTreePatterns(t)
+}
+
+// runTextConv runs the textconv command by the shell, with the path of a
+// temporary file holding the content as argument, and returns its output.
+func runTextConv(command string, content []byte) (text []byte, err error) {
+	f, err := os.CreateTemp("", "go-git-textconv-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command+` "$@"`, command, f.Name())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w: %s", ErrTextConvFailed, command, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/config"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+func TestTextConv(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the textconv command of the test is a shell command")
+	}
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+
+	dir := t.TempDir()
+	r, err := PlainInit(dir, false)
+	require.NoError(t, err)
+
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	// The textconv command drops the leading NUL byte making the files
+	// binary.
+	cfg.DiffDrivers["nul"] = &config.DiffDriver{Name: "nul", TextConv: "tail -c +2"}
+	require.NoError(t, r.SetConfig(cfg))
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	sig := &object.Signature{Name: "foo", Email: "foo@example.com", When: time.Now()}
+	var commits []*object.Commit
+	for _, content := range []string{"\x00old\n", "\x00new\n"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("*.bin diff=nul\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.bin"), []byte(content), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "b.dat"), []byte(content), 0o644))
+		_, err := w.Add(".")
+		require.NoError(t, err)
+
+		h, err := w.Commit("update", &CommitOptions{Author: sig, AllowEmptyCommits: true})
+		require.NoError(t, err)
+
+		c, err := r.CommitObject(h)
+		require.NoError(t, err)
+		commits = append(commits, c)
+	}
+
+	textConv, err := r.TextConv()
+	require.NoError(t, err)
+
+	p, err := commits[0].PatchWithOptions(context.Background(), commits[1], &object.PatchOptions{TextConv: textConv})
+	require.NoError(t, err)
+
+	fps := p.FilePatches()
+	require.Len(t, fps, 2)
+
+	_, to := fps[0].Files()
+	assert.Equal(t, "a.bin", to.Path())
+	assert.False(t, fps[0].IsBinary())
+	assert.Contains(t, p.String(), "@@ -1 +1 @@\n-old\n+new\n")
+
+	_, to = fps[1].Files()
+	assert.Equal(t, "b.dat", to.Path())
+	assert.True(t, fps[1].IsBinary())
+
+	cfg.DiffDrivers["nul"].TextConv = "false"
+	require.NoError(t, r.SetConfig(cfg))
+
+	textConv, err = r.TextConv()
+	require.NoError(t, err)
+
+	_, err = commits[0].PatchWithOptions(context.Background(), commits[1], &object.PatchOptions{TextConv: textConv})
+	assert.ErrorIs(t, err, ErrTextConvFailed)
+}
@@ -1,6 +1,16 @@
 package convert
 
-import "io"
+import (
+	"bytes"
+	"io"
+)
+
+// DefaultSampleSize is the number of bytes at the start of the content git
+// looks at to detect whether it is binary.
+const DefaultSampleSize = 8000
+
+// statBufferSize is the size of the chunks the content is read by.
+const statBufferSize = 32 * 1024
 
 // Stat holds statistics about the content being analyzed.
 type Stat struct {
@@ -26,61 +36,142 @@ func (s Stat) IsBinary() bool {
 // GetStat returns [Stat] of the reader based on:
 // https://git.kernel.org/pub/scm/git/git.git/tree/convert.c?id=HEAD#n45
 func GetStat(r io.Reader) (stat Stat, err error) {
-	var hadCR bool
+	var g statGatherer
+	buf := make([]byte, statBufferSize)
+	for {
+		n, err := r.Read(buf)
+		g.add(buf[:n])
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return Stat{}, err
+		}
+	}
+
+	return g.end(), nil
+}
+
+// GetStatSample returns the [Stat] of the first size bytes of the reader, as
+// GetStat does for the whole content, so that large contents are not read
+// in full to detect whether they are binary. The CRLF count only covers the
+// sample. If size isn't positive, the whole content is read.
+func GetStatSample(r io.Reader, size int) (Stat, error) {
+	if size <= 0 {
+		return GetStat(r)
+	}
+
+	// One more byte is read to know whether the content goes on, and
+	// whether a CR ending the sample is followed by a LF.
+	buf := make([]byte, size+1)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Stat{}, err
+	}
+
+	var g statGatherer
+	if n <= size {
+		g.add(buf[:n])
+		return g.end(), nil
+	}
 
-	buf := make([]byte, 1)
+	g.add(buf[:size])
+	if g.hadCR {
+		if buf[size] == '\n' {
+			g.stat.CRLF++
+		} else {
+			g.stat.LoneCR++
+		}
+	}
 
+	return g.stat, nil
+}
+
+// CountCRLF returns the number of CRLF sequences of the reader, reading it
+// by chunks.
+func CountCRLF(r io.Reader) (uint, error) {
+	var count uint
+	var hadCR bool
+	buf := make([]byte, statBufferSize)
 	for {
-		if _, err := r.Read(buf); err != nil {
-			if err == io.EOF {
-				break
+		n, err := r.Read(buf)
+		if n > 0 {
+			if hadCR && buf[0] == '\n' {
+				count++
 			}
-			return Stat{}, err
+
+			count += uint(bytes.Count(buf[:n], []byte("\r\n")))
+			hadCR = buf[n-1] == '\r'
 		}
 
-		b := buf[0]
+		if err == io.EOF {
+			return count, nil
+		}
 
-		if b != '\n' && hadCR {
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// statGatherer computes the [Stat] of a content given by chunks.
+type statGatherer struct {
+	stat  Stat
+	hadCR bool
+	last  byte
+}
+
+func (g *statGatherer) add(data []byte) {
+	for _, b := range data {
+		if b != '\n' && g.hadCR {
 			// CR not followed by LF. Lone CR is considered binary.
-			stat.LoneCR++
-			hadCR = false
+			g.stat.LoneCR++
+			g.hadCR = false
 		}
 
 		switch {
 		case b == '\n':
-			if hadCR {
-				stat.CRLF++
-				hadCR = false
+			if g.hadCR {
+				g.stat.CRLF++
+				g.hadCR = false
 			} else {
-				stat.LoneLF++
+				g.stat.LoneLF++
 			}
 		case b == '\r':
-			hadCR = true
+			g.hadCR = true
 		case b == 127: // DEL
-			stat.NonPrintable++
+			g.stat.NonPrintable++
 		case b < 32:
 			switch b {
 			case '\b', '\t', '\033', '\014': // BS, HT, ESC and FF
-				stat.Printable++
+				g.stat.Printable++
 			case 0:
-				stat.NUL++
+				g.stat.NUL++
 			default:
-				stat.NonPrintable++
+				g.stat.NonPrintable++
 			}
 		default:
-			stat.Printable++
+			g.stat.Printable++
 		}
 	}
 
-	if hadCR {
+	if len(data) > 0 {
+		g.last = data[len(data)-1]
+	}
+}
+
+// end returns the Stat of the content, once all of it was added.
+func (g *statGatherer) end() Stat {
+	if g.hadCR {
 		// Last byte is lone CR.
-		stat.LoneCR++
+		g.stat.LoneCR++
 	}
 
 	// If file ends with EOF then don't count this EOF as non-printable.
-	if buf[0] == '\032' {
-		stat.NonPrintable--
+	if g.last == '\032' {
+		g.stat.NonPrintable--
 	}
 
-	return stat, nil
+	return g.stat
 }
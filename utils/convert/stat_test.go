@@ -3,7 +3,9 @@ package convert
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -76,6 +78,47 @@ func TestGetStat(t *testing.T) {
 	}
 }
 
+func TestGetStatSample(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input    string
+		size     int
+		expected Stat
+	}{
+		{"ABC\x00", 3, Stat{Printable: 3}},
+		{"ABC\x00", 4, Stat{Printable: 3, NUL: 1}},
+		{"ABC\x00", 0, Stat{Printable: 3, NUL: 1}},
+		{"AB\r\n", 3, Stat{Printable: 2, CRLF: 1}},
+		{"AB\rC", 3, Stat{Printable: 2, LoneCR: 1}},
+		{"AB\r", 3, Stat{Printable: 2, LoneCR: 1}},
+		{"AB\032C", 3, Stat{Printable: 2, NonPrintable: 1}},
+		{"AB\032", 3, Stat{Printable: 2}},
+	}
+
+	for idx, test := range tests {
+		t.Run(fmt.Sprintf("#%d %q", idx, test.input), func(t *testing.T) {
+			t.Parallel()
+
+			stat, err := GetStatSample(strings.NewReader(test.input), test.size)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, stat)
+		})
+	}
+}
+
+func TestCountCRLF(t *testing.T) {
+	t.Parallel()
+
+	input := strings.Repeat("x", statBufferSize-1) + "\r\n" + "a\r\nb\n\r"
+	count, err := CountCRLF(iotest.OneByteReader(strings.NewReader(input)))
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	count, err = CountCRLF(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+}
+
 func TestIsBinary(t *testing.T) {
 	t.Parallel()
 	stat := Stat{}
@@ -0,0 +1,152 @@
+package ioutil
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// RateLimiter is a token bucket, filled with rate tokens per second up to
+// its size. Each byte transferred takes a token, the transfers waiting for
+// the missing ones. The readers and writers wrapped by the same RateLimiter
+// share its rate. It is not safe for concurrent use.
+type RateLimiter struct {
+	ctx    context.Context
+	rate   float64
+	size   int
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewRateLimiter returns a RateLimiter transferring at most bytesPerSec bytes
+// per second on average, the transfers waiting as needed until the context is
+// done. It returns nil, which limits nothing, if bytesPerSec is not positive.
+func NewRateLimiter(ctx context.Context, bytesPerSec int64) *RateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+
+	// The bucket holds the tokens of a tenth of a second, so that the
+	// transfers are spread evenly.
+	size := max(int(bytesPerSec/10), 1)
+	return &RateLimiter{
+		ctx:    ctx,
+		rate:   float64(bytesPerSec),
+		size:   size,
+		tokens: float64(size),
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+}
+
+// chunk returns the part of p transferred at once, at most the size of the
+// bucket.
+func (l *RateLimiter) chunk(p []byte) []byte {
+	return p[:min(len(p), l.size)]
+}
+
+// take takes n tokens from the bucket, waiting until it holds them.
+func (l *RateLimiter) take(n int) error {
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, float64(l.size))
+	}
+
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return nil
+	}
+
+	return l.sleep(l.ctx, time.Duration(-l.tokens/l.rate*float64(time.Second)))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+type rateLimitedReader struct {
+	r io.Reader
+	l *RateLimiter
+}
+
+// NewRateLimitedReader wraps a reader to read at most bytesPerSec bytes per
+// second on average, the reads waiting as needed until the context is done.
+// The reader is returned as is if bytesPerSec is not positive.
+func NewRateLimitedReader(ctx context.Context, r io.Reader, bytesPerSec int64) io.Reader {
+	return NewRateLimiter(ctx, bytesPerSec).Reader(r)
+}
+
+// Reader wraps r to read at the rate of the limiter. The reader is returned as
+// is if the limiter is nil.
+func (l *RateLimiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+
+	return &rateLimitedReader{r: r, l: l}
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(r.l.chunk(p))
+	if n > 0 {
+		if werr := r.l.take(n); werr != nil {
+			return n, werr
+		}
+	}
+
+	return n, err
+}
+
+type rateLimitedWriter struct {
+	w io.Writer
+	l *RateLimiter
+}
+
+// NewRateLimitedWriter wraps a writer to write at most bytesPerSec bytes per
+// second on average, the writes waiting as needed until the context is done.
+// The writer is returned as is if bytesPerSec is not positive.
+func NewRateLimitedWriter(ctx context.Context, w io.Writer, bytesPerSec int64) io.Writer {
+	return NewRateLimiter(ctx, bytesPerSec).Writer(w)
+}
+
+// Writer wraps w to write at the rate of the limiter. The writer is returned
+// as is if the limiter is nil.
+func (l *RateLimiter) Writer(w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+
+	return &rateLimitedWriter{w: w, l: l}
+}
+
+func (w *rateLimitedWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := w.l.chunk(p)
+		if err := w.l.take(len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
@@ -0,0 +1,104 @@
+package ioutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock advanced by the sleeps only.
+type fakeClock struct {
+	t     time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.t = c.t.Add(d)
+	c.slept += d
+	return nil
+}
+
+func (c *fakeClock) install(l *RateLimiter) {
+	l.now = c.now
+	l.sleep = c.sleep
+}
+
+func TestRateLimitedReader(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	r := NewRateLimitedReader(context.Background(), strings.NewReader(strings.Repeat("x", 10000)), 1000)
+	clock.install(r.(*rateLimitedReader).l)
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Len(t, b, 10000)
+	// The first 100 bytes are read at once, the others at 1000 bytes per
+	// second.
+	assert.InDelta(t, 9.9, clock.slept.Seconds(), 0.01)
+}
+
+func TestRateLimitedWriter(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var buf bytes.Buffer
+	w := NewRateLimitedWriter(context.Background(), &buf, 1000)
+	clock.install(w.(*rateLimitedWriter).l)
+
+	n, err := w.Write(bytes.Repeat([]byte("x"), 5000))
+	require.NoError(t, err)
+	assert.Equal(t, 5000, n)
+	assert.Equal(t, 5000, buf.Len())
+	assert.InDelta(t, 4.9, clock.slept.Seconds(), 0.01)
+}
+
+func TestRateLimiterShared(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := NewRateLimiter(context.Background(), 1000)
+	clock.install(l)
+
+	for range 2 {
+		b, err := io.ReadAll(l.Reader(strings.NewReader(strings.Repeat("x", 5000))))
+		require.NoError(t, err)
+		assert.Len(t, b, 5000)
+	}
+
+	// The readers share the rate, as a single one reading 10000 bytes.
+	assert.InDelta(t, 9.9, clock.slept.Seconds(), 0.01)
+}
+
+func TestRateLimitedCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := NewRateLimitedReader(ctx, strings.NewReader(strings.Repeat("x", 1000)), 10)
+	_, err := io.ReadAll(r)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRateLimitedUnlimited(t *testing.T) {
+	t.Parallel()
+
+	r := strings.NewReader("x")
+	assert.Same(t, r, NewRateLimitedReader(context.Background(), r, 0))
+
+	var buf bytes.Buffer
+	assert.Same(t, &buf, NewRateLimitedWriter(context.Background(), &buf, 0))
+}
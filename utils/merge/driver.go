@@ -0,0 +1,56 @@
+package merge
+
+import "sync"
+
+// The built-in merge drivers, selected by name unless a driver is registered
+// with the same name, as in git.
+const (
+	// TextDriver merges the files line by line, writing the conflicts
+	// between conflict markers. It is the default.
+	TextDriver = "text"
+	// BinaryDriver keeps our version of the files, reporting a conflict when
+	// both sides changed them.
+	BinaryDriver = "binary"
+	// UnionDriver merges the files line by line, resolving the conflicts by
+	// keeping the lines of both sides. See Options.Union.
+	UnionDriver = "union"
+)
+
+// Driver is a merge driver, merging the changes made to the file at path
+// from base to ours and from base to theirs, base being nil when the file
+// is added on both sides. It returns the merged content and whether the
+// changes could be merged without conflicts.
+//
+// The drivers are selected by the merge attribute of the files, a file with
+// merge=<name> in its gitattributes being merged by the driver registered
+// with the name, instead of line by line, or by the built-in driver with the
+// name.
+type Driver func(base, ours, theirs []byte, path string) ([]byte, bool)
+
+// drivers are the merge drivers registered by name.
+var (
+	drivers = map[string]Driver{}
+	mtx     sync.RWMutex
+)
+
+// RegisterDriver adds or replaces the merge driver with the given name.
+func RegisterDriver(name string, d Driver) {
+	mtx.Lock()
+	drivers[name] = d
+	mtx.Unlock()
+}
+
+// UnregisterDriver removes the merge driver with the given name.
+func UnregisterDriver(name string) {
+	mtx.Lock()
+	delete(drivers, name)
+	mtx.Unlock()
+}
+
+// GetDriver returns the merge driver registered with the given name.
+func GetDriver(name string) (Driver, bool) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	d, ok := drivers[name]
+	return d, ok && d != nil
+}
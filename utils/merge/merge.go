@@ -0,0 +1,304 @@
+// Package merge implements the three-way merge of text files, line by line,
+// as git merge-file does.
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+
+	"github.com/go-git/go-git/v6/utils/diff"
+)
+
+// DefaultMarkerSize is the default length of the conflict markers.
+const DefaultMarkerSize = 7
+
+// ErrUnknownConflictStyle is returned by ParseConflictStyle for the unknown
+// conflict styles.
+var ErrUnknownConflictStyle = errors.New("unknown conflict style")
+
+// ConflictStyle is the style of the conflicts written, as set by the
+// merge.conflictStyle option of git.
+type ConflictStyle int8
+
+const (
+	// MergeStyle writes the lines of both sides of the conflicts, leaving
+	// out the lines they start and end with in common. This is the default.
+	MergeStyle ConflictStyle = iota
+	// Diff3Style writes the lines of the base between the ones of both sides
+	// of the conflicts, after a ||||||| marker.
+	Diff3Style
+	// ZDiff3Style is Diff3Style, leaving out of the conflicts the lines both
+	// sides start and end with in common.
+	ZDiff3Style
+)
+
+// ParseConflictStyle returns the conflict style of the given name, "merge",
+// "diff3" or "zdiff3".
+func ParseConflictStyle(name string) (ConflictStyle, error) {
+	switch name {
+	case "merge":
+		return MergeStyle, nil
+	case "diff3":
+		return Diff3Style, nil
+	case "zdiff3":
+		return ZDiff3Style, nil
+	default:
+		return MergeStyle, fmt.Errorf("%w: %q", ErrUnknownConflictStyle, name)
+	}
+}
+
+// Options holds the options of a merge.
+type Options struct {
+	// OursLabel is the label of the conflict marker opening our side.
+	OursLabel string
+	// BaseLabel is the label of the conflict marker opening the base, with
+	// the Diff3Style and ZDiff3Style styles.
+	BaseLabel string
+	// TheirsLabel is the label of the conflict marker closing their side.
+	TheirsLabel string
+	// MarkerSize is the length of the conflict markers, as set by the
+	// conflict-marker-size gitattribute. It defaults to DefaultMarkerSize.
+	MarkerSize int
+	// Style is the style of the conflicts written.
+	Style ConflictStyle
+	// Union resolves the conflicts by writing the lines of both sides, ours
+	// first, without conflict markers, as the union merge driver of git.
+	Union bool
+}
+
+// hunk is the replacement of the lines [start, end) of the base file.
+type hunk struct {
+	start, end int
+	lines      []string
+}
+
+// Merge merges the changes made from base to ours and from base to theirs.
+// The changes of the same or adjacent lines of base conflict, unless they are
+// identical, in which case the conflicting lines of both sides are written
+// between conflict markers. It returns the merged content and whether it has
+// conflicts.
+func Merge(base, ours, theirs string, o *Options) (string, bool) {
+	if o == nil {
+		o = &Options{}
+	}
+
+	baseLines := splitLines(base)
+	a, b := hunks(base, ours), hunks(base, theirs)
+
+	var out strings.Builder
+	var conflict bool
+	pos := 0
+	for len(a) > 0 || len(b) > 0 {
+		lo, hi := groupRange(a, b)
+
+		var na, nb int
+		for grown := true; grown; {
+			grown = false
+			for na < len(a) && a[na].start <= hi {
+				hi = max(hi, a[na].end)
+				na++
+				grown = true
+			}
+
+			for nb < len(b) && b[nb].start <= hi {
+				hi = max(hi, b[nb].end)
+				nb++
+				grown = true
+			}
+		}
+
+		writeLines(&out, baseLines[pos:lo])
+		oursLines := apply(baseLines, lo, hi, a[:na])
+		theirsLines := apply(baseLines, lo, hi, b[:nb])
+		switch {
+		case nb == 0:
+			writeLines(&out, oursLines)
+		case na == 0 || equalLines(oursLines, theirsLines):
+			writeLines(&out, theirsLines)
+		case o.Union:
+			writeUnion(&out, oursLines, theirsLines)
+		default:
+			conflict = true
+			writeConflict(&out, baseLines[lo:hi], oursLines, theirsLines, o)
+		}
+
+		a, b, pos = a[na:], b[nb:], hi
+	}
+
+	writeLines(&out, baseLines[pos:])
+	return out.String(), conflict
+}
+
+// groupRange returns the range of base replaced by the first of the hunks.
+func groupRange(a, b []hunk) (int, int) {
+	switch {
+	case len(a) == 0:
+		return b[0].start, b[0].end
+	case len(b) == 0 || a[0].start <= b[0].start:
+		return a[0].start, a[0].end
+	default:
+		return b[0].start, b[0].end
+	}
+}
+
+// hunks returns the hunks turning base into other.
+func hunks(base, other string) []hunk {
+	var hs []hunk
+	var cur *hunk
+	line := 0
+	for _, d := range diff.Do(base, other) {
+		lines := splitLines(d.Text)
+		if d.Type == diffmatchpatch.DiffEqual {
+			if cur != nil {
+				hs = append(hs, *cur)
+				cur = nil
+			}
+
+			line += len(lines)
+			continue
+		}
+
+		if cur == nil {
+			cur = &hunk{start: line, end: line}
+		}
+
+		if d.Type == diffmatchpatch.DiffDelete {
+			cur.end += len(lines)
+			line += len(lines)
+		} else {
+			cur.lines = append(cur.lines, lines...)
+		}
+	}
+
+	if cur != nil {
+		hs = append(hs, *cur)
+	}
+
+	return hs
+}
+
+// apply returns the lines [lo, hi) of base with the hunks applied.
+func apply(base []string, lo, hi int, hs []hunk) []string {
+	var lines []string
+	pos := lo
+	for _, h := range hs {
+		lines = append(lines, base[pos:h.start]...)
+		lines = append(lines, h.lines...)
+		pos = h.end
+	}
+
+	return append(lines, base[pos:hi]...)
+}
+
+// writeConflict writes the conflicting lines of both sides between conflict
+// markers, with the lines of base in the diff3 styles. Unless the style is
+// Diff3Style, the lines both sides start and end with in common are left out.
+func writeConflict(out *strings.Builder, base, ours, theirs []string, o *Options) {
+	prefix, suffix := 0, 0
+	if o.Style != Diff3Style {
+		prefix, suffix = commonLines(ours, theirs)
+	}
+
+	size := o.MarkerSize
+	if size <= 0 {
+		size = DefaultMarkerSize
+	}
+
+	writeLines(out, ours[:prefix])
+	writeMarker(out, '<', size, o.OursLabel)
+	writeLines(out, ours[prefix:len(ours)-suffix])
+	terminateLine(out)
+	if o.Style != MergeStyle {
+		writeMarker(out, '|', size, o.BaseLabel)
+		writeLines(out, base)
+		terminateLine(out)
+	}
+
+	writeMarker(out, '=', size, "")
+	writeLines(out, theirs[prefix:len(theirs)-suffix])
+	terminateLine(out)
+	writeMarker(out, '>', size, o.TheirsLabel)
+	writeLines(out, ours[len(ours)-suffix:])
+}
+
+// writeUnion writes the conflicting lines of both sides, ours first, the
+// lines they start and end with in common being written once.
+func writeUnion(out *strings.Builder, ours, theirs []string) {
+	prefix, suffix := commonLines(ours, theirs)
+	writeLines(out, ours[:len(ours)-suffix])
+	terminateLine(out)
+	writeLines(out, theirs[prefix:])
+}
+
+// writeMarker writes a conflict marker of size times the character c.
+func writeMarker(out *strings.Builder, c byte, size int, label string) {
+	for range size {
+		out.WriteByte(c)
+	}
+
+	if label != "" {
+		out.WriteString(" ")
+		out.WriteString(label)
+	}
+
+	out.WriteString("\n")
+}
+
+// terminateLine ends the last line written if it has no line feed.
+func terminateLine(out *strings.Builder) {
+	if s := out.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		out.WriteString("\n")
+	}
+}
+
+// commonLines returns the number of lines a and b start with in common,
+// then the number of the other lines they end with in common.
+func commonLines(a, b []string) (prefix, suffix int) {
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	return prefix, suffix
+}
+
+func writeLines(out *strings.Builder, lines []string) {
+	for _, l := range lines {
+		out.WriteString(l)
+	}
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitLines splits s in lines, keeping their line feeds.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
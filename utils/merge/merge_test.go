@@ -0,0 +1,127 @@
+package merge_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-git/go-git/v6/utils/merge"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	base := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name         string
+		ours, theirs string
+		expected     string
+		conflict     bool
+	}{
+		{"unchanged", base, base, base, false},
+		{"ours only", "a\nB\nc\nd\ne\n", base, "a\nB\nc\nd\ne\n", false},
+		{"theirs only", base, "a\nb\nc\nd\nE\n", "a\nb\nc\nd\nE\n", false},
+		{"both distant", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", false},
+		{"both identical", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", false},
+		{"insert and delete", "a\nb\nx\nc\nd\ne\n", "a\nb\nc\nd\n", "a\nb\nx\nc\nd\n", false},
+		{
+			"same line",
+			"a\nb\nC1\nd\ne\n", "a\nb\nC2\nd\ne\n",
+			"a\nb\n<<<<<<< ours\nC1\n=======\nC2\n>>>>>>> theirs\nd\ne\n", true,
+		},
+		{
+			"adjacent lines",
+			"a\nB\nc\nd\ne\n", "a\nb\nC\nd\ne\n",
+			"a\n<<<<<<< ours\nB\nc\n=======\nb\nC\n>>>>>>> theirs\nd\ne\n", true,
+		},
+		{
+			"common lines trimmed",
+			"a\nX\nc1\nd\ne\n", "a\nX\nc2\nd\ne\n",
+			"a\nX\n<<<<<<< ours\nc1\n=======\nc2\n>>>>>>> theirs\nd\ne\n", true,
+		},
+		{
+			"no line feed at end",
+			"a\nb\nc\nd\ne1", "a\nb\nc\nd\ne2",
+			"a\nb\nc\nd\n<<<<<<< ours\ne1\n=======\ne2\n>>>>>>> theirs\n", true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			merged, conflict := merge.Merge(base, tc.ours, tc.theirs, &merge.Options{
+				OursLabel:   "ours",
+				TheirsLabel: "theirs",
+			})
+			assert.Equal(t, tc.expected, merged)
+			assert.Equal(t, tc.conflict, conflict)
+		})
+	}
+}
+
+func TestMergeNoLabels(t *testing.T) {
+	t.Parallel()
+
+	merged, conflict := merge.Merge("a\n", "b\n", "c\n", nil)
+	assert.True(t, conflict)
+	assert.Equal(t, "<<<<<<<\nb\n=======\nc\n>>>>>>>\n", merged)
+}
+
+func TestMergeConflictStyle(t *testing.T) {
+	t.Parallel()
+
+	base, ours, theirs := "a\nb\nc\nd\ne\n", "a\nX\nc1\nd\ne\n", "a\nX\nc2\nd\ne\n"
+	tests := []struct {
+		style    merge.ConflictStyle
+		expected string
+	}{
+		{
+			merge.MergeStyle,
+			"a\nX\n<<<<<<<<< ours\nc1\n=========\nc2\n>>>>>>>>> theirs\nd\ne\n",
+		},
+		{
+			merge.Diff3Style,
+			"a\n<<<<<<<<< ours\nX\nc1\n||||||||| base\nb\nc\n=========\nX\nc2\n>>>>>>>>> theirs\nd\ne\n",
+		},
+		{
+			merge.ZDiff3Style,
+			"a\nX\n<<<<<<<<< ours\nc1\n||||||||| base\nb\nc\n=========\nc2\n>>>>>>>>> theirs\nd\ne\n",
+		},
+	}
+
+	for _, tc := range tests {
+		merged, conflict := merge.Merge(base, ours, theirs, &merge.Options{
+			OursLabel:   "ours",
+			BaseLabel:   "base",
+			TheirsLabel: "theirs",
+			MarkerSize:  9,
+			Style:       tc.style,
+		})
+		assert.True(t, conflict)
+		assert.Equal(t, tc.expected, merged)
+	}
+}
+
+func TestMergeUnion(t *testing.T) {
+	t.Parallel()
+
+	merged, conflict := merge.Merge("a\nb\nc\nd\ne\n", "a\nX\nc1\nd\ne\n", "a\nX\nc2\nd\ne\n", &merge.Options{Union: true})
+	assert.False(t, conflict)
+	assert.Equal(t, "a\nX\nc1\nc2\nd\ne\n", merged)
+
+	merged, conflict = merge.Merge("a", "b", "c", &merge.Options{Union: true})
+	assert.False(t, conflict)
+	assert.Equal(t, "b\nc", merged)
+}
+
+func TestParseConflictStyle(t *testing.T) {
+	t.Parallel()
+
+	style, err := merge.ParseConflictStyle("zdiff3")
+	assert.NoError(t, err)
+	assert.Equal(t, merge.ZDiff3Style, style)
+
+	_, err = merge.ParseConflictStyle("foo")
+	assert.ErrorIs(t, err, merge.ErrUnknownConflictStyle)
+}
@@ -5,13 +5,16 @@ import (
 	"io"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-billy/v6"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/filemode"
 	format "github.com/go-git/go-git/v6/plumbing/format/config"
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
 	"github.com/go-git/go-git/v6/plumbing/format/index"
 	"github.com/go-git/go-git/v6/utils/convert"
 	"github.com/go-git/go-git/v6/utils/ioutil"
@@ -28,10 +31,42 @@ type Options struct {
 	// AutoCRLF converts CRLF line endings in text files into LF line endings.
 	AutoCRLF bool
 
+	// Attributes are the gitattributes patterns of the filesystem, in
+	// ascending order of priority. The text attribute of a file, possibly set
+	// through the binary macro, overrides AutoCRLF and the binary detection
+	// heuristic.
+	Attributes []gitattributes.MatchAttribute
+
 	// Index is used to enable the metadata-first comparison optimization while
 	// correctly handling the "racy git" condition. If no index is provided,
 	// the function works without the optimization.
 	Index *index.Index
+
+	// SymlinksAsFiles treats the regular files tracked as symbolic links in
+	// the Index as links whose target is their content, as git does when
+	// core.symlinks is false.
+	SymlinksAsFiles bool
+
+	// Concurrency is the number of goroutines used to read the directories
+	// and hash the files of the filesystem. If greater than one, the whole
+	// tree is walked upfront on the first access to the root children, and
+	// the files whose metadata does not match their entry of the Index are
+	// hashed, the other ones being hashed lazily if needed. Otherwise it is
+	// walked lazily as the nodes are visited.
+	Concurrency int
+
+	// IgnoreCase matches the files and directories with the entries of the
+	// Index regardless of their case, as git does when core.ignoreCase is
+	// true. The nodes are named as in the Index, so a file tracked as README
+	// and present as readme is neither deleted nor untracked.
+	IgnoreCase bool
+
+	// SampleSize, if positive, is the number of bytes read at the start of
+	// the files to detect whether they are binary when their line endings
+	// are converted, as git does with its first convert.DefaultSampleSize
+	// bytes, instead of reading them in full. The files normalized are still
+	// read once more to count their CRLF line endings.
+	SampleSize int
 }
 
 // The node represents a file or a directory in a billy.Filesystem. It
@@ -44,10 +79,16 @@ type node struct {
 	submodules map[string]plumbing.Hash
 	idx        *index.Index
 	idxMap     map[string]*index.Entry
+	// idxPaths are the paths of the entries of the index and of their
+	// directories, by case-folded path, when the case is ignored.
+	idxPaths map[string]string
 
 	options *Options
 
+	// path is the path of the node in the filesystem, and key its path as
+	// tracked in the index, which only differ in case.
 	path     string
+	key      string
 	hash     []byte
 	children []noder.Noder
 	isDir    bool
@@ -88,12 +129,17 @@ func NewRootNodeWithOptions(
 	options Options,
 ) noder.Noder {
 	var idxMap map[string]*index.Entry
+	var idxPaths map[string]string
 
 	if options.Index != nil {
 		idxMap = make(map[string]*index.Entry, len(options.Index.Entries))
 		for _, entry := range options.Index.Entries {
 			idxMap[entry.Name] = entry
 		}
+
+		if options.IgnoreCase {
+			idxPaths = indexPaths(options.Index)
+		}
 	}
 
 	return &node{
@@ -101,11 +147,30 @@ func NewRootNodeWithOptions(
 		submodules: submodules,
 		idx:        options.Index,
 		idxMap:     idxMap,
+		idxPaths:   idxPaths,
 		options:    &options,
 		isDir:      true,
 	}
 }
 
+// indexPaths returns the paths of the entries of idx and of their
+// directories, by case-folded path.
+func indexPaths(idx *index.Index) map[string]string {
+	paths := make(map[string]string, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		for p := entry.Name; p != "." && p != "/"; p = path.Dir(p) {
+			folded := strings.ToLower(p)
+			if _, ok := paths[folded]; ok {
+				break
+			}
+
+			paths[folded] = p
+		}
+	}
+
+	return paths
+}
+
 // Hash the hash of a filesystem is the result of concatenating the computed
 // plumbing.Hash of the file as a Blob and its plumbing.FileMode; that way the
 // difftree algorithm will detect changes in the contents of files and also in
@@ -124,7 +189,7 @@ func (n *node) Hash() []byte {
 }
 
 func (n *node) Name() string {
-	return path.Base(n.path)
+	return path.Base(n.key)
 }
 
 func (n *node) IsDir() bool {
@@ -160,6 +225,60 @@ func (n *node) calculateChildren() error {
 		return nil
 	}
 
+	if n.path == "" && n.options.Concurrency > 1 {
+		return n.walk(n.options.Concurrency)
+	}
+
+	return n.readChildren()
+}
+
+// walk reads every directory below n, level by level, and hashes the files
+// whose content must be read, with up to concurrency goroutines. The results
+// are cached in the nodes, so the order of the children is the same as when
+// walking sequentially.
+func (n *node) walk(concurrency int) error {
+	var files []*node
+	dirs := []*node{n}
+	for len(dirs) > 0 {
+		g := new(errgroup.Group)
+		g.SetLimit(concurrency)
+		for _, d := range dirs {
+			g.Go(d.readChildren)
+		}
+
+		if err := g.Wait(); err != nil {
+			return err
+		}
+
+		var next []*node
+		for _, d := range dirs {
+			for _, c := range d.children {
+				c := c.(*node)
+				switch {
+				case c.isDir:
+					next = append(next, c)
+				case c.statMismatch():
+					files = append(files, c)
+				}
+			}
+		}
+
+		dirs = next
+	}
+
+	g := new(errgroup.Group)
+	g.SetLimit(concurrency)
+	for _, f := range files {
+		g.Go(func() error {
+			f.calculateHash()
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+func (n *node) readChildren() error {
 	files, err := n.fs.ReadDir(n.path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -193,23 +312,28 @@ func (n *node) calculateChildren() error {
 }
 
 func (n *node) newChildNode(file os.FileInfo) (*node, error) {
-	path := path.Join(n.path, file.Name())
+	key := path.Join(n.key, file.Name())
+	if p, ok := n.idxPaths[strings.ToLower(key)]; ok {
+		key = p
+	}
 
 	node := &node{
 		fs:         n.fs,
 		submodules: n.submodules,
 		idx:        n.idx,
 		idxMap:     n.idxMap,
+		idxPaths:   n.idxPaths,
 		options:    n.options,
 
-		path:    path,
+		path:    path.Join(n.path, file.Name()),
+		key:     key,
 		isDir:   file.IsDir(),
 		size:    file.Size(),
 		mode:    file.Mode(),
 		modTime: file.ModTime(),
 	}
 
-	if _, isSubmodule := n.submodules[path]; isSubmodule {
+	if _, isSubmodule := n.submodules[key]; isSubmodule {
 		node.isDir = false
 	}
 
@@ -221,18 +345,25 @@ func (n *node) calculateHash() {
 		n.hash = make([]byte, 24)
 		return
 	}
-	mode, err := filemode.NewFromOSFileMode(n.mode)
+	mode, err := n.fileMode()
 	if err != nil {
 		n.hash = plumbing.ZeroHash.Bytes()
 		return
 	}
-	if submoduleHash, isSubmodule := n.submodules[n.path]; isSubmodule {
+	if submoduleHash, isSubmodule := n.submodules[n.key]; isSubmodule {
 		n.hash = append(submoduleHash.Bytes(), filemode.Submodule.Bytes()...)
 		return
 	}
 
 	if n.idxMap != nil {
-		if entry, ok := n.idxMap[n.path]; ok {
+		if entry, ok := n.idxMap[n.key]; ok {
+			// Files assumed unchanged are never read, being taken as they
+			// are in the index.
+			if entry.AssumeUnchanged {
+				n.hash = append(entry.Hash.Bytes(), entry.Mode.Bytes()...)
+				return
+			}
+
 			if n.metadataMatches(entry) {
 				n.hash = append(entry.Hash.Bytes(), mode.Bytes()...)
 				return
@@ -241,14 +372,33 @@ func (n *node) calculateHash() {
 	}
 
 	var hash plumbing.Hash
-	if n.mode&os.ModeSymlink != 0 {
+	switch {
+	case n.mode&os.ModeSymlink != 0:
 		hash = n.doCalculateHashForSymlink()
-	} else {
+	case mode == filemode.Symlink:
+		hash = n.doCalculateHashForSymlinkFile()
+	default:
 		hash = n.doCalculateHashForRegular()
 	}
 	n.hash = append(hash.Bytes(), mode.Bytes()...)
 }
 
+// statMismatch returns whether the content of the file must be read to be
+// hashed: it is tracked in the index, and neither assumed unchanged nor with
+// metadata matching its entry. Without an index, all the files are.
+func (n *node) statMismatch() bool {
+	if n.idxMap == nil {
+		return true
+	}
+
+	if _, isSubmodule := n.submodules[n.key]; isSubmodule {
+		return false
+	}
+
+	entry, ok := n.idxMap[n.key]
+	return ok && !entry.AssumeUnchanged && !n.metadataMatches(entry)
+}
+
 func (n *node) metadataMatches(entry *index.Entry) bool {
 	if entry == nil {
 		return false
@@ -262,7 +412,7 @@ func (n *node) metadataMatches(entry *index.Entry) bool {
 		return false
 	}
 
-	mode, err := filemode.NewFromOSFileMode(n.mode)
+	mode, err := n.fileMode()
 	if err != nil {
 		return false
 	}
@@ -297,11 +447,17 @@ func (n *node) doCalculateHashForRegular() plumbing.Hash {
 	h := plumbing.NewHasher(format.SHA1, plumbing.BlobObject, n.size)
 	var dst io.Writer = h
 
-	if n.options != nil && n.options.AutoCRLF {
+	text := gitattributes.TextUnspecified
+	autoCRLF := n.options != nil && n.options.AutoCRLF
+	if n.options != nil && len(n.options.Attributes) > 0 {
+		text = gitattributes.MatchText(n.options.Attributes, strings.Split(n.key, "/"))
+	}
+
+	if text != gitattributes.TextUnset && (autoCRLF || text != gitattributes.TextUnspecified) {
 		br := sync.GetBufioReader(f)
 		defer sync.PutBufioReader(br)
 
-		stat, err := convert.GetStat(br)
+		stat, err := convert.GetStatSample(br, n.options.SampleSize)
 		if err != nil {
 			return plumbing.ZeroHash
 		}
@@ -310,8 +466,15 @@ func (n *node) doCalculateHashForRegular() plumbing.Hash {
 			return plumbing.ZeroHash
 		}
 
-		if !stat.IsBinary() {
-			h.Reset(plumbing.BlobObject, n.size-int64(stat.CRLF))
+		if text.Normalize(autoCRLF, stat.IsBinary()) {
+			crlf := stat.CRLF
+			if n.options.SampleSize > 0 && n.size > int64(n.options.SampleSize) {
+				if crlf, err = n.countCRLF(f); err != nil {
+					return plumbing.ZeroHash
+				}
+			}
+
+			h.Reset(plumbing.BlobObject, n.size-int64(crlf))
 			dst = convert.NewLFWriter(dst)
 		}
 	}
@@ -323,6 +486,50 @@ func (n *node) doCalculateHashForRegular() plumbing.Hash {
 	return h.Sum()
 }
 
+// countCRLF counts the CRLF line endings of the file f, whose stat was only
+// sampled, and rewinds it.
+func (n *node) countCRLF(f io.ReadSeeker) (uint, error) {
+	crlf, err := convert.CountCRLF(f)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = f.Seek(0, io.SeekStart)
+	return crlf, err
+}
+
+// fileMode returns the mode of the file, a regular file tracked as a
+// symbolic link being one when SymlinksAsFiles is set.
+func (n *node) fileMode() (filemode.FileMode, error) {
+	mode, err := filemode.NewFromOSFileMode(n.mode)
+	if err != nil || mode != filemode.Regular || n.options == nil || !n.options.SymlinksAsFiles {
+		return mode, err
+	}
+
+	if entry, ok := n.idxMap[n.key]; ok && entry.Mode == filemode.Symlink {
+		return filemode.Symlink, nil
+	}
+
+	return mode, nil
+}
+
+// doCalculateHashForSymlinkFile hashes the content of a regular file holding
+// the target of a symbolic link, without any conversion.
+func (n *node) doCalculateHashForSymlinkFile() plumbing.Hash {
+	f, err := n.fs.Open(n.path)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	defer func() { _ = f.Close() }()
+
+	h := plumbing.NewHasher(format.SHA1, plumbing.BlobObject, n.size)
+	if _, err := ioutil.CopyBufferPool(h, f); err != nil {
+		return plumbing.ZeroHash
+	}
+
+	return h.Sum()
+}
+
 func (n *node) doCalculateHashForSymlink() plumbing.Hash {
 	target, err := n.fs.Readlink(n.path)
 	if err != nil {
@@ -338,5 +545,5 @@ func (n *node) doCalculateHashForSymlink() plumbing.Hash {
 }
 
 func (n *node) String() string {
-	return n.path
+	return n.key
 }
@@ -9,6 +9,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/filemode"
 	format "github.com/go-git/go-git/v6/plumbing/format/config"
 	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/utils/convert"
 	"github.com/go-git/go-git/v6/utils/merkletrie"
 	"github.com/go-git/go-git/v6/utils/merkletrie/noder"
 )
@@ -81,6 +83,39 @@ func (s *NoderSuite) TestDiffCRLF() {
 	s.Len(ch, 0)
 }
 
+func (s *NoderSuite) TestDiffCRLFSampled() {
+	text := strings.Repeat("foo bar\n", 2000)
+
+	fsA := memfs.New()
+	WriteFile(fsA, "text", []byte(text), 0o644)
+	WriteFile(fsA, "binary", []byte(text+"\x00"), 0o644)
+
+	fsB := memfs.New()
+	crlf := strings.ReplaceAll(text, "\n", "\r\n")
+	WriteFile(fsB, "text", []byte(crlf), 0o644)
+	WriteFile(fsB, "binary", []byte(crlf+"\x00"), 0o644)
+
+	// The NUL at the end of binary is out of the sample, so that it is
+	// normalized as a text file.
+	ch, err := merkletrie.DiffTree(
+		NewRootNode(fsA, nil),
+		NewRootNodeWithOptions(fsB, nil, Options{AutoCRLF: true, SampleSize: convert.DefaultSampleSize}),
+		IsEquals,
+	)
+
+	s.NoError(err)
+	s.Len(ch, 0)
+
+	ch, err = merkletrie.DiffTree(
+		NewRootNode(fsA, nil),
+		NewRootNodeWithOptions(fsB, nil, Options{AutoCRLF: true}),
+		IsEquals,
+	)
+
+	s.NoError(err)
+	s.Len(ch, 1)
+}
+
 func (s *NoderSuite) TestDiffChangeLink() {
 	fsA := memfs.New()
 	fsA.Symlink("qux", "foo")
@@ -119,6 +154,60 @@ func (s *NoderSuite) TestDiffChangeContent() {
 	s.Len(ch, 1)
 }
 
+func (s *NoderSuite) TestDiffConcurrency() {
+	fsA := memfs.New()
+	fsB := memfs.New()
+	for i := range 20 {
+		name := fmt.Sprintf("dir%d/sub%d/file%d", i%3, i%5, i)
+		WriteFile(fsA, name, []byte(name), 0o644)
+		WriteFile(fsB, name, []byte(name), 0o644)
+	}
+	WriteFile(fsB, "dir1/sub1/file1", []byte("changed"), 0o644)
+	WriteFile(fsB, "dir2/new", []byte("new"), 0o644)
+
+	ch, err := merkletrie.DiffTree(
+		NewRootNodeWithOptions(fsA, nil, Options{Concurrency: 4}),
+		NewRootNodeWithOptions(fsB, nil, Options{Concurrency: 4}),
+		IsEquals,
+	)
+	s.Require().NoError(err)
+
+	s.Require().Len(ch, 2)
+	s.Equal("<Modify dir1/sub1/file1>", ch[0].String())
+	s.Equal("<Insert dir2/new>", ch[1].String())
+}
+
+func (s *NoderSuite) TestConcurrencyHashesStatMismatches() {
+	fs := memfs.New()
+	WriteFile(fs, "tracked", []byte("foo"), 0o644)
+	WriteFile(fs, "changed", []byte("bar"), 0o644)
+	WriteFile(fs, "untracked", []byte("qux"), 0o644)
+
+	idx := &index.Index{ModTime: time.Now().Add(time.Hour)}
+	for _, name := range []string{"tracked", "changed"} {
+		fi, err := fs.Lstat(name)
+		s.Require().NoError(err)
+
+		idx.Entries = append(idx.Entries, &index.Entry{
+			Name:       name,
+			Mode:       filemode.Regular,
+			Size:       uint32(fi.Size()),
+			ModifiedAt: fi.ModTime(),
+		})
+	}
+	idx.Entries[1].Size++
+
+	children, err := NewRootNodeWithOptions(fs, nil, Options{Index: idx, Concurrency: 4}).Children()
+	s.Require().NoError(err)
+	s.Require().Len(children, 3)
+
+	// Only the file whose metadata does not match the index is hashed
+	// upfront.
+	for _, c := range children {
+		s.Equal(c.Name() == "changed", c.(*node).hash != nil, c.Name())
+	}
+}
+
 func (s *NoderSuite) TestDiffSymlinkDirOnA() {
 	fsA := memfs.New()
 	WriteFile(fsA, "qux/qux", []byte("foo"), 0o644)
@@ -411,3 +500,31 @@ func (s *NoderSuite) TestZeroIndexModTime() {
 
 	s.Equal(expectedHash, fileHash, "should hash actual file content when idx.ModTime is zero, not use stale index hash")
 }
+
+func (s *NoderSuite) TestIgnoreCase() {
+	fs := memfs.New()
+	WriteFile(fs, "docs/readme", []byte("foo"), 0o644)
+
+	idx := &index.Index{
+		Version: 2,
+		Entries: []*index.Entry{{Name: "Docs/README", Mode: filemode.Regular}},
+	}
+
+	root := NewRootNodeWithOptions(fs, nil, Options{Index: idx})
+	children, err := root.Children()
+	s.Require().NoError(err)
+	s.Require().Len(children, 1)
+	s.Equal("docs", children[0].Name())
+
+	root = NewRootNodeWithOptions(fs, nil, Options{Index: idx, IgnoreCase: true})
+	children, err = root.Children()
+	s.Require().NoError(err)
+	s.Require().Len(children, 1)
+	s.Equal("Docs", children[0].Name())
+
+	children, err = children[0].Children()
+	s.Require().NoError(err)
+	s.Require().Len(children, 1)
+	s.Equal("README", children[0].Name())
+	s.Equal("Docs/README", children[0].String())
+}
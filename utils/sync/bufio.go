@@ -31,3 +31,29 @@ func PutBufioReader(reader *bufio.Reader) {
 	}
 	bufioReader.Put(reader)
 }
+
+var bufioWriter = sync.Pool{
+	New: func() any {
+		return bufio.NewWriter(nil)
+	},
+}
+
+// GetBufioWriter returns a *bufio.Writer that is managed by a sync.Pool.
+// Returns a bufio.Writer that is reset with writer and ready for use.
+//
+// After use, the *bufio.Writer should be flushed and put back into the
+// sync.Pool by calling PutBufioWriter.
+func GetBufioWriter(writer io.Writer) *bufio.Writer {
+	w := bufioWriter.Get().(*bufio.Writer)
+	w.Reset(writer)
+	return w
+}
+
+// PutBufioWriter puts writer back into its sync.Pool.
+func PutBufioWriter(writer *bufio.Writer) {
+	if writer == nil {
+		return
+	}
+	writer.Reset(nil)
+	bufioWriter.Put(writer)
+}
@@ -2,7 +2,9 @@ package sync
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/zlib"
+	"errors"
 	"io"
 	"sync"
 )
@@ -46,6 +48,17 @@ func (r *ZLibReader) Close() error {
 	return r.reader.Close()
 }
 
+// IsInflateError returns whether err was returned by a ZLibReader because the
+// stream it reads is truncated or corrupt.
+func IsInflateError(err error) bool {
+	var corrupt flate.CorruptInputError
+	return errors.As(err, &corrupt) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, zlib.ErrChecksum) ||
+		errors.Is(err, zlib.ErrDictionary) ||
+		errors.Is(err, zlib.ErrHeader)
+}
+
 // GetZlibReader returns a ZLibReader that is managed by a sync.Pool.
 // Returns a ZLibReader that is reset using a dictionary that is
 // also managed by a sync.Pool.
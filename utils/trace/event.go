@@ -0,0 +1,69 @@
+package trace
+
+import (
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// handler is the handler of the events, nil when the events are disabled.
+var handler atomic.Pointer[Handler]
+
+// Event is a structured event of a go-git operation, such as the negotiation
+// of a fetch, the parsing of a packfile or the wait for a lock.
+type Event struct {
+	// Target is the tracing target of the event.
+	Target Target
+	// Name identifies the event, such as "transport.negotiate".
+	Name string
+	// Attrs are the attributes of the event, such as the number of objects
+	// decoded or the duration of the operation.
+	Attrs []slog.Attr
+}
+
+// String returns the event as it is printed when its target is enabled.
+func (e Event) String() string {
+	var b strings.Builder
+	b.WriteString(e.Name)
+	for _, a := range e.Attrs {
+		b.WriteByte(' ')
+		b.WriteString(a.String())
+	}
+
+	return b.String()
+}
+
+// Handler handles the events. It may be called concurrently.
+type Handler func(Event)
+
+// SetHandler sets the handler the events are emitted to. A nil handler
+// disables them, as they are by default.
+func SetHandler(h Handler) {
+	if h == nil {
+		handler.Store(nil)
+		return
+	}
+
+	handler.Store(&h)
+}
+
+// Emitting returns whether the events of the target are handled, either by
+// the handler or by the logger when the target is enabled. The callers check
+// it before measuring an operation, so that the events cost nothing when
+// disabled.
+func (t Target) Emitting() bool {
+	return handler.Load() != nil || t.Enabled()
+}
+
+// Emit emits the event name with the attributes attrs to the handler, and
+// prints it when the target is enabled.
+func (t Target) Emit(name string, attrs ...slog.Attr) {
+	e := Event{Target: t, Name: name, Attrs: attrs}
+	if h := handler.Load(); h != nil {
+		(*h)(e)
+	}
+
+	if t.Enabled() {
+		_ = logger.Output(2, e.String())
+	}
+}
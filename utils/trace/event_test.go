@@ -0,0 +1,65 @@
+package trace
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestEmitDisabled(t *testing.T) { //nolint:paralleltest // modifies global trace target
+	var buf bytes.Buffer
+	setUpTest(t, &buf)
+	if General.Emitting() {
+		t.Error("expected events to be disabled")
+	}
+
+	General.Emit("test", slog.Int("a", 1))
+	if buf.String() != "" {
+		t.Error("expected empty string")
+	}
+}
+
+func TestEmitHandler(t *testing.T) { //nolint:paralleltest // modifies global trace handler
+	var buf bytes.Buffer
+	setUpTest(t, &buf)
+
+	var events []Event
+	SetHandler(func(e Event) { events = append(events, e) })
+	t.Cleanup(func() { SetHandler(nil) })
+
+	if !Packet.Emitting() {
+		t.Error("expected events to be emitted")
+	}
+
+	Packet.Emit("test", slog.Int("a", 1), slog.String("b", "c"))
+	if len(events) != 1 || events[0].Target != Packet || events[0].String() != "test a=1 b=c" {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	if buf.String() != "" {
+		t.Error("expected empty string")
+	}
+}
+
+func TestEmitTarget(t *testing.T) { //nolint:paralleltest // modifies global trace target
+	var buf bytes.Buffer
+	setUpTest(t, &buf)
+	SetTarget(General)
+	if !General.Emitting() {
+		t.Error("expected events to be emitted")
+	}
+
+	General.Emit("test", slog.Int("a", 1))
+	if buf.String() != "test a=1\n" {
+		t.Errorf("expected 'test a=1', got %q", buf.String())
+	}
+}
+
+func BenchmarkEmitDisabled(b *testing.B) {
+	setUpTest(b, nil)
+	for b.Loop() {
+		if General.Emitting() {
+			General.Emit("test", slog.Int("a", 1))
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	giturl "github.com/go-git/go-git/v6/internal/url"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
 	"github.com/go-git/go-git/v6/plumbing/format/gitignore"
 	"github.com/go-git/go-git/v6/plumbing/format/index"
 	"github.com/go-git/go-git/v6/plumbing/object"
@@ -68,7 +70,9 @@ type Worktree struct {
 // Returns nil if the operation is successful, NoErrAlreadyUpToDate if there are
 // no changes to be fetched, or an error.
 //
-// Pull only supports merges where the can be resolved as a fast-forward.
+// When the current branch diverged from the fetched one, it is merged or
+// rebased as set by PullOptions.Strategy or by the pull.rebase config, and
+// only fast-forwarded otherwise.
 func (w *Worktree) Pull(o *PullOptions) error {
 	return w.PullContext(context.Background(), o)
 }
@@ -77,7 +81,9 @@ func (w *Worktree) Pull(o *PullOptions) error {
 // branch. Returns nil if the operation is successful, NoErrAlreadyUpToDate if
 // there are no changes to be fetched, or an error.
 //
-// Pull only supports merges where the can be resolved as a fast-forward.
+// When the current branch diverged from the fetched one, it is merged or
+// rebased as set by PullOptions.Strategy or by the pull.rebase config, and
+// only fast-forwarded otherwise.
 //
 // The provided Context must be non-nil. If the context expires before the
 // operation is complete, an error is returned. The context only affects the
@@ -109,6 +115,7 @@ func (w *Worktree) PullContext(ctx context.Context, o *PullOptions) error {
 		InsecureSkipTLS: o.InsecureSkipTLS,
 		CABundle:        o.CABundle,
 		ProxyOptions:    o.ProxyOptions,
+		MaxBytesPerSec:  o.MaxBytesPerSec,
 	})
 
 	updated := true
@@ -123,6 +130,8 @@ func (w *Worktree) PullContext(ctx context.Context, o *PullOptions) error {
 		return err
 	}
 
+	target := ref.Hash()
+	msg := "pull: Fast-forward"
 	head, err := w.r.Head()
 	if err == nil {
 		// if we don't have a shallows list, just ignore it
@@ -143,7 +152,10 @@ func (w *Worktree) PullContext(ctx context.Context, o *PullOptions) error {
 		}
 
 		if !ff {
-			return ErrNonFastForwardUpdate
+			target, msg, err = w.integrate(remote, head, ref, o)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -151,15 +163,42 @@ func (w *Worktree) PullContext(ctx context.Context, o *PullOptions) error {
 		return err
 	}
 
-	if err := w.updateHEAD(ref.Hash()); err != nil {
-		return err
+	var stash plumbing.Hash
+	if o.Autostash && head != nil {
+		stash, err = w.autostash(head)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := w.r.recordOrigHead(); err != nil {
+		return w.keepAutostash(stash, err)
+	}
+
+	if err := w.updateHEAD(target); err != nil {
+		return w.keepAutostash(stash, err)
+	}
+
+	var old plumbing.Hash
+	if head != nil {
+		old = head.Hash()
+	}
+
+	if err := w.r.logHEADUpdate(old, target, nil, msg); err != nil {
+		return w.keepAutostash(stash, err)
 	}
 
-	if err := w.Reset(&ResetOptions{
+	if err := w.reset(&ResetOptions{
 		Mode:   MergeReset,
-		Commit: ref.Hash(),
+		Commit: target,
 	}); err != nil {
-		return err
+		return w.keepAutostash(stash, err)
+	}
+
+	if !stash.IsZero() {
+		if err := w.applyAutostash(head.Hash(), stash); err != nil {
+			return err
+		}
 	}
 
 	if o.RecurseSubmodules != NoRecurseSubmodules {
@@ -172,6 +211,82 @@ func (w *Worktree) PullContext(ctx context.Context, o *PullOptions) error {
 	return nil
 }
 
+// integrate merges or rebases the current branch head with the fetched
+// reference ref, which diverged from it, according to the pull strategy. It
+// returns the commit the branch is updated to and the message recording the
+// update in the reflogs.
+func (w *Worktree) integrate(remote *Remote, head, ref *plumbing.Reference, o *PullOptions) (plumbing.Hash, string, error) {
+	cfg, err := w.r.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return plumbing.ZeroHash, "", err
+	}
+
+	strategy := o.Strategy
+	if strategy == PullDefault {
+		strategy = pullStrategyFromConfig(cfg, head.Name())
+	}
+
+	if strategy == PullFastForwardOnly {
+		return plumbing.ZeroHash, "", ErrNonFastForwardUpdate
+	}
+
+	sig := &CommitOptions{}
+	if err := sig.loadConfigAuthorAndCommitter(w.r); err != nil {
+		return plumbing.ZeroHash, "", err
+	}
+
+	if sig.Committer == nil {
+		sig.Committer = sig.Author
+	}
+
+	ours, err := w.r.CommitObject(head.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, "", err
+	}
+
+	theirs, err := w.r.CommitObject(ref.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, "", err
+	}
+
+	if strategy == PullRebase {
+		h, err := w.r.rebaseCommits(ours, theirs, sig.Committer)
+		return h, "pull --rebase (finish): returning to " + head.Name().String(), err
+	}
+
+	url := o.RemoteURL
+	if url == "" && len(remote.Config().URLs) > 0 {
+		url = remote.Config().URLs[0]
+	}
+
+	msg := fmt.Sprintf("Merge %s\n", url)
+	if name := ref.Name(); name.IsBranch() {
+		msg = fmt.Sprintf("Merge branch '%s' of %s\n", name.Short(), url)
+	}
+
+	h, err := w.r.mergeCommits(ours, theirs, msg, sig.Author, sig.Committer)
+	return h, "pull: Merge made by the 'ort' strategy.", err
+}
+
+// pullStrategyFromConfig returns the pull strategy set by
+// branch.<name>.rebase, or else by pull.rebase, for the branch name.
+func pullStrategyFromConfig(cfg *config.Config, name plumbing.ReferenceName) PullStrategy {
+	rebase := cfg.Pull.Rebase
+	if b, ok := cfg.Branches[name.Short()]; ok && name.IsBranch() && b.Rebase != "" {
+		rebase = b.Rebase
+	}
+
+	if rebase == "" {
+		return PullFastForwardOnly
+	}
+
+	if v, err := strconv.ParseBool(rebase); err == nil && !v {
+		return PullMerge
+	}
+
+	return PullRebase
+}
+
 func (w *Worktree) updateSubmodules(ctx context.Context, o *SubmoduleUpdateOptions) error {
 	s, err := w.Submodules()
 	if err != nil {
@@ -194,6 +309,27 @@ func (w *Worktree) Checkout(opts *CheckoutOptions) error {
 		return err
 	}
 
+	if !opts.Force {
+		if err := w.checkUnmerged(); err != nil {
+			return err
+		}
+	}
+
+	from, err := w.r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return err
+	}
+
+	var oldHead plumbing.Hash
+	head, err := w.r.Head()
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
+
+	if head != nil {
+		oldHead = head.Hash()
+	}
+
 	if opts.Create {
 		if err := w.createBranch(opts); err != nil {
 			return err
@@ -209,6 +345,8 @@ func (w *Worktree) Checkout(opts *CheckoutOptions) error {
 		Commit:     c,
 		Mode:       MergeReset,
 		SparseDirs: opts.SparseCheckoutDirectories,
+		PathSpecs:  opts.PathSpecs,
+		Prefetch:   opts.Prefetch,
 	}
 	if opts.Force {
 		ro.Mode = HardReset
@@ -216,7 +354,9 @@ func (w *Worktree) Checkout(opts *CheckoutOptions) error {
 		ro.Mode = SoftReset
 	}
 
+	to := opts.Branch.Short()
 	if !opts.Hash.IsZero() && !opts.Create {
+		to = c.String()
 		err = w.setHEADToCommit(opts.Hash)
 	} else {
 		err = w.setHEADToBranch(opts.Branch, c)
@@ -226,7 +366,44 @@ func (w *Worktree) Checkout(opts *CheckoutOptions) error {
 		return err
 	}
 
-	return w.Reset(ro)
+	msg := fmt.Sprintf("checkout: moving from %s to %s", reflogRefName(from), to)
+	if err := w.r.logRefUpdate([]plumbing.ReferenceName{plumbing.HEAD}, oldHead, c, nil, msg); err != nil {
+		return err
+	}
+
+	// Only changing the sparse directories of the commit checked out, the
+	// files whose SkipWorktree flag changes are updated, instead of the whole
+	// worktree.
+	var sparseOnly bool
+	if ro.Mode == MergeReset && len(ro.SparseDirs) > 0 && len(ro.PathSpecs) == 0 && c == oldHead {
+		if sparseOnly, err = w.checkoutSparseDirs(ro); err != nil {
+			return err
+		}
+	}
+
+	if !sparseOnly {
+		if err := w.reset(ro); err != nil {
+			return err
+		}
+	}
+
+	if opts.SubmoduleStrategy != NoSubmoduleUpdate {
+		if err := w.checkoutSubmodules(opts.SubmoduleStrategy, opts.Force); err != nil {
+			return err
+		}
+	}
+
+	if !opts.HooksEnabled {
+		return nil
+	}
+
+	hooks, err := newHookRunner(w.r.Storer, w.Filesystem)
+	if err != nil {
+		return err
+	}
+
+	return hooks.run(context.Background(), hookPostCheckout, nil,
+		oldHead.String(), c.String(), "1")
 }
 
 func (w *Worktree) createBranch(opts *CheckoutOptions) error {
@@ -243,18 +420,26 @@ func (w *Worktree) createBranch(opts *CheckoutOptions) error {
 		return err
 	}
 
+	from := opts.Hash.String()
 	if opts.Hash.IsZero() {
 		ref, err := w.r.Head()
 		if err != nil {
 			return err
 		}
 
+		from = "HEAD"
 		opts.Hash = ref.Hash()
 	}
 
-	return w.r.Storer.SetReference(
+	err = w.r.Storer.SetReference(
 		plumbing.NewHashReference(opts.Branch, opts.Hash),
 	)
+	if err != nil {
+		return err
+	}
+
+	return w.r.logRefUpdate([]plumbing.ReferenceName{opts.Branch}, plumbing.ZeroHash, opts.Hash, nil,
+		"branch: Created from "+from)
 }
 
 func (w *Worktree) getCommitFromCheckoutOptions(opts *CheckoutOptions) (plumbing.Hash, error) {
@@ -308,8 +493,25 @@ func (w *Worktree) setHEADToBranch(branch plumbing.ReferenceName, commit plumbin
 	return w.r.Storer.SetReference(head)
 }
 
-// Reset the worktree to a specified state.
+// Reset the worktree to a specified state. Unless only some files are reset,
+// the commit HEAD pointed to is recorded in ORIG_HEAD, as git reset does.
 func (w *Worktree) Reset(opts *ResetOptions) error {
+	if len(opts.Files) == 0 && len(opts.PathSpecs) == 0 {
+		if err := opts.Validate(w.r); err != nil {
+			return err
+		}
+
+		if err := w.r.recordOrigHead(); err != nil {
+			return err
+		}
+	}
+
+	return w.reset(opts)
+}
+
+// reset resets the worktree as Reset does, without recording ORIG_HEAD, for
+// the operations using it internally.
+func (w *Worktree) reset(opts *ResetOptions) error {
 	if trace.Performance.Enabled() {
 		start := time.Now()
 		defer func() {
@@ -336,6 +538,11 @@ func (w *Worktree) Reset(opts *ResetOptions) error {
 		return w.setHEADCommit(opts.Commit)
 	}
 
+	filter, err := newPathFilter(opts.Files, opts.PathSpecs)
+	if err != nil {
+		return err
+	}
+
 	t, err := w.r.getTreeFromCommitHash(opts.Commit)
 	if err != nil {
 		return err
@@ -360,7 +567,7 @@ func (w *Worktree) Reset(opts *ResetOptions) error {
 	}
 
 	if opts.Mode == KeepReset {
-		if err := w.checkKeepResetConflicts(prevTree, t, opts.SparseDirs, opts.Files); err != nil {
+		if err := w.checkKeepResetConflicts(prevTree, t, opts.SparseDirs, filter); err != nil {
 			return err
 		}
 	}
@@ -371,19 +578,19 @@ func (w *Worktree) Reset(opts *ResetOptions) error {
 
 	var removedFiles []string
 	if opts.Mode == MixedReset || opts.Mode == MergeReset || opts.Mode == HardReset || opts.Mode == KeepReset {
-		if removedFiles, err = w.resetIndex(t, opts.SparseDirs, opts.Files); err != nil {
+		if removedFiles, err = w.resetIndex(t, opts.SparseDirs, filter); err != nil {
 			return err
 		}
 	}
 
 	if opts.Mode == MergeReset && len(removedFiles) > 0 {
-		if err := w.resetWorktree(t, removedFiles); err != nil {
+		if err := w.resetWorktree(t, removedFiles, opts.Prefetch); err != nil {
 			return err
 		}
 	}
 
 	if opts.Mode == HardReset || opts.Mode == KeepReset {
-		if err := w.resetWorktreeToTree(prevTree, t, opts.Files); err != nil {
+		if err := w.resetWorktreeToTree(prevTree, t, filter, opts.Prefetch); err != nil {
 			return err
 		}
 	}
@@ -419,7 +626,9 @@ func treeContainsDirs(tree *object.Tree, dirs []string) bool {
 // If only Staged is true, then the restore source will be HEAD.
 // If only Worktree is true or neither Staged nor Worktree are true, will
 // result in ErrRestoreWorktreeOnlyNotSupported because restoring the working
-// tree while leaving the stage untouched is not currently supported.
+// tree while leaving the stage untouched is not currently supported, unless
+// Merge is true, in which case the conflicted merge of the unmerged paths is
+// written to the working tree from the stages of the index.
 //
 // Restore with no files specified will return ErrNoRestorePaths.
 func (w *Worktree) Restore(o *RestoreOptions) error {
@@ -427,6 +636,15 @@ func (w *Worktree) Restore(o *RestoreOptions) error {
 		return err
 	}
 
+	if o.Merge {
+		filter, err := newPathFilter(o.Files, nil)
+		if err != nil {
+			return err
+		}
+
+		return w.restoreConflicts(filter)
+	}
+
 	if o.Staged {
 		opts := &ResetOptions{
 			Files: o.Files,
@@ -440,13 +658,13 @@ func (w *Worktree) Restore(o *RestoreOptions) error {
 			opts.Mode = MixedReset
 		}
 
-		return w.Reset(opts)
+		return w.reset(opts)
 	}
 
 	return ErrRestoreWorktreeOnlyNotSupported
 }
 
-func (w *Worktree) resetIndex(t *object.Tree, dirs, files []string) ([]string, error) {
+func (w *Worktree) resetIndex(t *object.Tree, dirs []string, filter *pathFilter) ([]string, error) {
 	idx, err := w.r.Storer.Index()
 	if err != nil {
 		return nil, err
@@ -460,7 +678,6 @@ func (w *Worktree) resetIndex(t *object.Tree, dirs, files []string) ([]string, e
 	}
 
 	removedFiles := make([]string, 0, len(changes))
-	filesMap := buildFilePathMap(files)
 	for _, ch := range changes {
 		a, err := ch.Action()
 		if err != nil {
@@ -481,11 +698,8 @@ func (w *Worktree) resetIndex(t *object.Tree, dirs, files []string) ([]string, e
 			name = ch.From.String()
 		}
 
-		if len(files) > 0 {
-			contains := inFiles(filesMap, name)
-			if !contains {
-				continue
-			}
+		if !filter.Match(name) {
+			continue
 		}
 
 		b.Remove(name)
@@ -501,10 +715,43 @@ func (w *Worktree) resetIndex(t *object.Tree, dirs, files []string) ([]string, e
 		})
 	}
 
+	// The unmerged paths are resolved to their entry in t, whether or not
+	// it differs from the first of their stages compared above.
+	for _, c := range idx.Conflicts() {
+		if !filter.Match(c.Name) {
+			continue
+		}
+
+		b.Remove(c.Name)
+		e, err := t.FindEntry(c.Name)
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		b.Add(&index.Entry{
+			Name: c.Name,
+			Hash: e.Hash,
+			Mode: e.Mode,
+		})
+	}
+
 	b.Write(idx)
 
 	if len(dirs) > 0 {
 		idx.SkipUnless(dirs)
+	} else {
+		m, err := w.sparseCheckoutMatcher()
+		if err != nil {
+			return nil, err
+		}
+
+		if m != nil {
+			applySparseCheckout(idx, m)
+		}
 	}
 
 	return removedFiles, w.r.Storer.SetIndex(idx)
@@ -548,14 +795,12 @@ func (w *Worktree) headTree() (*object.Tree, error) {
 //     become SkipWorktree because their path is no longer under any of the new
 //     sparse directories. Step 3 of resetWorktreeToTree removes them from disk,
 //     so KeepReset must refuse if they carry local modifications.
-func (w *Worktree) checkKeepResetConflicts(fromTree, toTree *object.Tree, sparseDirs, files []string) error {
+func (w *Worktree) checkKeepResetConflicts(fromTree, toTree *object.Tree, sparseDirs []string, filter *pathFilter) error {
 	changes, err := diffTrees(fromTree, toTree)
 	if err != nil {
 		return err
 	}
 
-	filesMap := buildFilePathMap(files)
-
 	// touched: all paths that the reset will affect (checked for local mods).
 	// writtenPaths: subset of touched that will be written to disk (Insert /
 	// Modify). An untracked file at such a path would be silently overwritten,
@@ -572,7 +817,7 @@ func (w *Worktree) checkKeepResetConflicts(fromTree, toTree *object.Tree, sparse
 		} else {
 			name = ch.From.String()
 		}
-		if len(files) > 0 && !inFiles(filesMap, name) {
+		if !filter.Match(name) {
 			continue
 		}
 		if ch.From != nil {
@@ -596,7 +841,7 @@ func (w *Worktree) checkKeepResetConflicts(fromTree, toTree *object.Tree, sparse
 			if e.SkipWorktree {
 				continue // already excluded from the worktree
 			}
-			if len(files) > 0 && !inFiles(filesMap, e.Name) {
+			if !filter.Match(e.Name) {
 				continue
 			}
 			included := false
@@ -659,10 +904,8 @@ func (w *Worktree) checkKeepResetConflicts(fromTree, toTree *object.Tree, sparse
 //     merkletrie marks them skip=true. Mirror git's behaviour: any tracked
 //     file with SkipWorktree=true must not exist in the worktree.
 //
-// files optionally restricts the operation to a specific subset of paths.
-func (w *Worktree) resetWorktreeToTree(fromTree, toTree *object.Tree, files []string) error {
-	filesMap := buildFilePathMap(files)
-
+// filter optionally restricts the operation to a specific subset of paths.
+func (w *Worktree) resetWorktreeToTree(fromTree, toTree *object.Tree, filter *pathFilter, prefetch bool) error {
 	// Step 1: delete files removed from the tracked tree.
 	treeChanges, err := diffTrees(fromTree, toTree)
 	if err != nil {
@@ -677,7 +920,7 @@ func (w *Worktree) resetWorktreeToTree(fromTree, toTree *object.Tree, files []st
 			continue
 		}
 		name := ch.From.String()
-		if len(files) > 0 && !inFiles(filesMap, name) {
+		if !filter.Match(name) {
 			continue
 		}
 		if err := w.validChange(ch); err != nil {
@@ -699,13 +942,27 @@ func (w *Worktree) resetWorktreeToTree(fromTree, toTree *object.Tree, files []st
 		return err
 	}
 
+	var pf *changesPrefetcher
+	if prefetch {
+		pf = &changesPrefetcher{s: w.r.Storer, changes: worktreeChanges, tree: toTree, match: filter.Match}
+	}
+
 	idx, err := w.r.Storer.Index()
 	if err != nil {
 		return err
 	}
 	b := newIndexBuilder(idx)
 
-	for _, ch := range worktreeChanges {
+	conv, err := w.fileConversion()
+	if err != nil {
+		return err
+	}
+
+	for i, ch := range worktreeChanges {
+		if err := pf.prefetch(i); err != nil {
+			return err
+		}
+
 		a, err := ch.Action()
 		if err != nil {
 			return err
@@ -719,17 +976,14 @@ func (w *Worktree) resetWorktreeToTree(fromTree, toTree *object.Tree, files []st
 			continue
 		}
 
-		if len(files) > 0 {
-			file := ch.To.String()
-			if !inFiles(filesMap, file) {
-				continue
-			}
+		if !filter.Match(ch.To.String()) {
+			continue
 		}
 
 		if err := w.validChange(ch); err != nil {
 			return err
 		}
-		if err := w.checkoutChange(ch, toTree, b); err != nil {
+		if err := w.checkoutChange(conv, ch, toTree, b); err != nil {
 			return err
 		}
 	}
@@ -742,7 +996,7 @@ func (w *Worktree) resetWorktreeToTree(fromTree, toTree *object.Tree, files []st
 		if !e.SkipWorktree {
 			continue
 		}
-		if len(files) > 0 && !inFiles(filesMap, e.Name) {
+		if !filter.Match(e.Name) {
 			continue
 		}
 		if _, statErr := w.Filesystem.Lstat(e.Name); os.IsNotExist(statErr) {
@@ -759,20 +1013,36 @@ func (w *Worktree) resetWorktreeToTree(fromTree, toTree *object.Tree, files []st
 
 // resetWorktree updates the worktree to match the staging area.
 // files restricts the operation to the named paths; nil means all files.
-func (w *Worktree) resetWorktree(t *object.Tree, files []string) error {
+// prefetch reads the blobs to write ahead, in one batch.
+func (w *Worktree) resetWorktree(t *object.Tree, files []string, prefetch bool) error {
 	changes, err := w.diffStagingWithWorktree(true, false)
 	if err != nil {
 		return err
 	}
 
+	filesMap := buildFilePathMap(files)
+	var pf *changesPrefetcher
+	if prefetch {
+		match := func(name string) bool { return len(files) == 0 || inFiles(filesMap, name) }
+		pf = &changesPrefetcher{s: w.r.Storer, changes: changes, tree: t, match: match}
+	}
+
 	idx, err := w.r.Storer.Index()
 	if err != nil {
 		return err
 	}
 	b := newIndexBuilder(idx)
 
-	filesMap := buildFilePathMap(files)
-	for _, ch := range changes {
+	conv, err := w.fileConversion()
+	if err != nil {
+		return err
+	}
+
+	for i, ch := range changes {
+		if err := pf.prefetch(i); err != nil {
+			return err
+		}
+
 		if err := w.validChange(ch); err != nil {
 			return err
 		}
@@ -795,7 +1065,7 @@ func (w *Worktree) resetWorktree(t *object.Tree, files []string) error {
 			}
 		}
 
-		if err := w.checkoutChange(ch, t, b); err != nil {
+		if err := w.checkoutChange(conv, ch, t, b); err != nil {
 			return err
 		}
 	}
@@ -804,6 +1074,49 @@ func (w *Worktree) resetWorktree(t *object.Tree, files []string) error {
 	return w.r.Storer.SetIndex(idx)
 }
 
+// prefetchWindow is the number of changes whose blobs are read ahead in one
+// batch.
+const prefetchWindow = 1024
+
+// changesPrefetcher reads ahead the blobs of the tree written to the worktree
+// by the changes to the paths matched by match, a window of changes at a
+// time, so that the blobs read are still cached when written.
+type changesPrefetcher struct {
+	s       storer.EncodedObjectStorer
+	changes merkletrie.Changes
+	tree    *object.Tree
+	match   func(string) bool
+	// next is the index of the first change not read ahead yet.
+	next int
+}
+
+// prefetch reads ahead the blobs of the window of changes starting at the
+// change i, once the previous one is written. It does nothing on a nil
+// changesPrefetcher.
+func (p *changesPrefetcher) prefetch(i int) error {
+	if p == nil || i < p.next {
+		return nil
+	}
+
+	p.next = min(i+prefetchWindow, len(p.changes))
+
+	var hashes []plumbing.Hash
+	for _, ch := range p.changes[i:p.next] {
+		if ch.To == nil || !p.match(ch.To.String()) {
+			continue
+		}
+
+		e, err := p.tree.FindEntry(ch.To.String())
+		if err != nil || !e.Mode.IsFile() {
+			continue
+		}
+
+		hashes = append(hashes, e.Hash)
+	}
+
+	return storer.PrefetchObjects(p.s, hashes)
+}
+
 // worktreeDeny is a list of paths that are not allowed
 // to be used when resetting the worktree.
 var worktreeDeny = map[string]struct{}{
@@ -904,7 +1217,7 @@ func (w *Worktree) validChange(ch merkletrie.Change) error {
 	return nil
 }
 
-func (w *Worktree) checkoutChange(ch merkletrie.Change, t *object.Tree, idx *indexBuilder) error {
+func (w *Worktree) checkoutChange(conv *fileConversion, ch merkletrie.Change, t *object.Tree, idx *indexBuilder) error {
 	a, err := ch.Action()
 	if err != nil {
 		return err
@@ -931,7 +1244,7 @@ func (w *Worktree) checkoutChange(ch merkletrie.Change, t *object.Tree, idx *ind
 		return w.checkoutChangeSubmodule(name, a, e, idx)
 	}
 
-	return w.checkoutChangeRegularFile(name, a, t, e, idx)
+	return w.checkoutChangeRegularFile(conv, name, a, t, e, idx)
 }
 
 func (w *Worktree) containsUnstagedChanges() (bool, error) {
@@ -956,15 +1269,22 @@ func (w *Worktree) containsUnstagedChanges() (bool, error) {
 	return false, nil
 }
 
+// setHEADCommit moves HEAD, or the branch it points to, to commit, recording
+// the move in the reflogs as git reset does.
 func (w *Worktree) setHEADCommit(commit plumbing.Hash) error {
 	head, err := w.r.Reference(plumbing.HEAD, false)
 	if err != nil {
 		return err
 	}
 
+	old := head.Hash()
 	if head.Type() == plumbing.HashReference {
 		head = plumbing.NewHashReference(plumbing.HEAD, commit)
-		return w.r.Storer.SetReference(head)
+		if err := w.r.Storer.SetReference(head); err != nil {
+			return err
+		}
+
+		return w.logReset(old, commit)
 	}
 
 	branch, err := w.r.Reference(head.Target(), false)
@@ -976,8 +1296,24 @@ func (w *Worktree) setHEADCommit(commit plumbing.Hash) error {
 		return fmt.Errorf("invalid HEAD target should be a branch, found %s", branch.Type())
 	}
 
+	old = branch.Hash()
 	branch = plumbing.NewHashReference(branch.Name(), commit)
-	return w.r.Storer.SetReference(branch)
+	if err := w.r.Storer.SetReference(branch); err != nil {
+		return err
+	}
+
+	return w.logReset(old, commit)
+}
+
+// logReset records the move of HEAD from old to commit by a reset. Resetting
+// to the commit HEAD already is at, as checkouts and pulls do, is not
+// recorded.
+func (w *Worktree) logReset(old, commit plumbing.Hash) error {
+	if old == commit {
+		return nil
+	}
+
+	return w.r.logHEADUpdate(old, commit, nil, "reset: moving to "+commit.String())
 }
 
 func (w *Worktree) checkoutChangeSubmodule(name string,
@@ -1013,7 +1349,8 @@ func (w *Worktree) checkoutChangeSubmodule(name string,
 	return nil
 }
 
-func (w *Worktree) checkoutChangeRegularFile(name string,
+func (w *Worktree) checkoutChangeRegularFile(conv *fileConversion,
+	name string,
 	a merkletrie.Action,
 	t *object.Tree,
 	e *object.TreeEntry,
@@ -1036,24 +1373,24 @@ func (w *Worktree) checkoutChangeRegularFile(name string,
 			return err
 		}
 
-		if err := w.checkoutFile(f); err != nil {
+		if err := w.checkoutFile(conv, f); err != nil {
 			return err
 		}
 
-		return w.addIndexFromFile(name, e.Hash, idx)
+		return w.addIndexFromFile(name, e.Hash, e.Mode, idx)
 	}
 
 	return nil
 }
 
-func (w *Worktree) checkoutFile(f *object.File) (err error) {
+func (w *Worktree) checkoutFile(conv *fileConversion, f *object.File) (err error) {
 	mode, err := f.Mode.ToOSFileMode()
 	if err != nil {
 		return err
 	}
 
 	if mode&os.ModeSymlink != 0 {
-		return w.checkoutFileSymlink(f)
+		return w.checkoutFileSymlink(conv, f)
 	}
 
 	dstFile, err := w.Filesystem.OpenFile(f.Name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
@@ -1062,17 +1399,11 @@ func (w *Worktree) checkoutFile(f *object.File) (err error) {
 	}
 	defer ioutil.CheckClose(dstFile, &err)
 
-	return w.copyObjectToWorktree(f, dstFile)
+	return w.copyObjectToWorktree(conv, f, dstFile)
 }
 
-func (w *Worktree) copyObjectToWorktree(object *object.File, file billy.File) (err error) {
-	cfg, err := w.r.Config()
-	if err != nil {
-		return err
-	}
-
+func (w *Worktree) copyObjectToWorktree(conv *fileConversion, object *object.File, file billy.File) (err error) {
 	var src io.ReadCloser
-	var dst io.Writer = file
 
 	src, err = object.Reader()
 	if err != nil {
@@ -1080,7 +1411,12 @@ func (w *Worktree) copyObjectToWorktree(object *object.File, file billy.File) (e
 	}
 	defer ioutil.CheckClose(src, &err)
 
-	if cfg.Core.AutoCRLF == "true" {
+	text, err := conv.text(object.Name)
+	if err != nil {
+		return err
+	}
+
+	if conv.cfg.Core.AutoCRLF == "true" && text != gitattributes.TextUnset {
 		br := sync.GetBufioReader(src)
 		defer sync.PutBufioReader(br)
 
@@ -1095,16 +1431,25 @@ func (w *Worktree) copyObjectToWorktree(object *object.File, file billy.File) (e
 		}
 		defer ioutil.CheckClose(src, &err)
 
-		if !stat.IsBinary() {
-			dst = convert.NewCRLFWriter(dst)
+		if text == gitattributes.TextSet || !stat.IsBinary() {
+			// The CRLF writer writes the content line by line, which is
+			// buffered so that the blob is streamed to the file in chunks.
+			bw := sync.GetBufioWriter(file)
+			defer sync.PutBufioWriter(bw)
+
+			if _, err := ioutil.CopyBufferPool(convert.NewCRLFWriter(bw), src); err != nil {
+				return err
+			}
+
+			return bw.Flush()
 		}
 	}
 
-	_, err = ioutil.CopyBufferPool(dst, src)
+	_, err = ioutil.CopyBufferPool(file, src)
 	return err
 }
 
-func (w *Worktree) checkoutFileSymlink(f *object.File) (err error) {
+func (w *Worktree) checkoutFileSymlink(conv *fileConversion, f *object.File) (err error) {
 	// https://github.com/git/git/commit/10ecfa76491e4923988337b2e2243b05376b40de
 	if strings.EqualFold(f.Name, gitmodulesFile) {
 		return ErrGitModulesSymlink
@@ -1122,23 +1467,33 @@ func (w *Worktree) checkoutFileSymlink(f *object.File) (err error) {
 		return err
 	}
 
+	// With core.symlinks set to false, the link is written as a plain file
+	// containing its target.
+	if conv.symlinksAsFiles() {
+		return w.checkoutSymlinkAsFile(f, bytes)
+	}
+
 	err = w.Filesystem.Symlink(string(bytes), f.Name)
 
 	// On windows, this might fail.
 	// Follow Git on Windows behavior by writing the link as it is.
 	if err != nil && isSymlinkWindowsNonAdmin(err) {
-		mode, _ := f.Mode.ToOSFileMode()
-
-		to, err := w.Filesystem.OpenFile(f.Name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
-		if err != nil {
-			return err
-		}
+		return w.checkoutSymlinkAsFile(f, bytes)
+	}
+	return err
+}
 
-		defer ioutil.CheckClose(to, &err)
+func (w *Worktree) checkoutSymlinkAsFile(f *object.File, target []byte) (err error) {
+	mode, _ := filemode.Regular.ToOSFileMode()
 
-		_, err = to.Write(bytes)
+	to, err := w.Filesystem.OpenFile(f.Name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
 		return err
 	}
+
+	defer ioutil.CheckClose(to, &err)
+
+	_, err = to.Write(target)
 	return err
 }
 
@@ -1152,7 +1507,7 @@ func (w *Worktree) addIndexFromTreeEntry(name string, f *object.TreeEntry, idx *
 	return nil
 }
 
-func (w *Worktree) addIndexFromFile(name string, h plumbing.Hash, idx *indexBuilder) error {
+func (w *Worktree) addIndexFromFile(name string, h plumbing.Hash, treeMode filemode.FileMode, idx *indexBuilder) error {
 	idx.Remove(name)
 	fi, err := w.Filesystem.Lstat(name)
 	if err != nil {
@@ -1164,6 +1519,11 @@ func (w *Worktree) addIndexFromFile(name string, h plumbing.Hash, idx *indexBuil
 		return err
 	}
 
+	// A symbolic link checked out as a plain file is still tracked as a link.
+	if treeMode == filemode.Symlink && mode == filemode.Regular {
+		mode = filemode.Symlink
+	}
+
 	e := &index.Entry{
 		Hash:       h,
 		Name:       name,
@@ -1192,7 +1552,10 @@ func (r *Repository) getTreeFromCommitHash(commit plumbing.Hash) (*object.Tree,
 
 var fillSystemInfo func(e *index.Entry, sys any)
 
-const gitmodulesFile = ".gitmodules"
+const (
+	gitmodulesFile    = ".gitmodules"
+	gitattributesFile = ".gitattributes"
+)
 
 // Submodule returns the submodule with the given name
 func (w *Worktree) Submodule(name string) (*Submodule, error) {
@@ -1562,15 +1925,29 @@ func removeDirIfEmpty(fs billy.Filesystem, dir string) (bool, error) {
 
 type indexBuilder struct {
 	entries map[string]*index.Entry
+	// conflicts are the entries of the unmerged paths, in the stages of the
+	// merge, kept until the path is added or removed.
+	conflicts map[string][]*index.Entry
+	// changed are the paths added or removed, whose directories are
+	// invalidated in the TREE extension of the index.
+	changed map[string]struct{}
 }
 
 func newIndexBuilder(idx *index.Index) *indexBuilder {
 	entries := make(map[string]*index.Entry, len(idx.Entries))
+	conflicts := make(map[string][]*index.Entry)
 	for _, e := range idx.Entries {
+		if e.Stage != index.Merged {
+			conflicts[e.Name] = append(conflicts[e.Name], e)
+			continue
+		}
+
 		entries[e.Name] = e
 	}
 	return &indexBuilder{
-		entries: entries,
+		entries:   entries,
+		conflicts: conflicts,
+		changed:   make(map[string]struct{}),
 	}
 }
 
@@ -1579,14 +1956,27 @@ func (b *indexBuilder) Write(idx *index.Index) {
 	for _, e := range b.entries {
 		idx.Entries = append(idx.Entries, e)
 	}
+
+	for _, stages := range b.conflicts {
+		idx.Entries = append(idx.Entries, stages...)
+	}
+
+	for name := range b.changed {
+		idx.InvalidateCache(name)
+	}
 }
 
 func (b *indexBuilder) Add(e *index.Entry) {
+	delete(b.conflicts, e.Name)
 	b.entries[e.Name] = e
+	b.changed[e.Name] = struct{}{}
 }
 
 func (b *indexBuilder) Remove(name string) {
-	delete(b.entries, filepath.ToSlash(name))
+	name = filepath.ToSlash(name)
+	delete(b.entries, name)
+	delete(b.conflicts, name)
+	b.changed[name] = struct{}{}
 }
 
 // buildFilePathMap creates a map of cleaned file paths for efficient lookup.
@@ -0,0 +1,298 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/plumbing/format/reflog"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/plumbing/storer"
+	"github.com/go-git/go-git/v6/utils/merkletrie"
+)
+
+// ErrAutostashConflict is returned by Pull when the changes stashed with
+// PullOptions.Autostash can't be re-applied on top of the pulled commit. The
+// changes are kept in the stash, at refs/stash.
+var ErrAutostashConflict = errors.New("applying autostash resulted in conflicts")
+
+const (
+	stashRefName   plumbing.ReferenceName = "refs/stash"
+	autostashMsg                          = "autostash"
+	noBranchPrefix                        = "(no branch)"
+)
+
+// autostash saves the changes of the tracked files, staged or not, as a stash
+// commit on top of head, as git stash create does, and resets the worktree to
+// head. It returns the zero hash if there are no changes to stash.
+func (w *Worktree) autostash(head *plumbing.Reference) (plumbing.Hash, error) {
+	status, err := w.Status()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	wIdx := &index.Index{Version: idx.Version}
+	for _, e := range idx.Entries {
+		ec := *e
+		wIdx.Entries = append(wIdx.Entries, &ec)
+	}
+
+	conv, err := w.fileConversion()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	var changed bool
+	for path, fs := range status {
+		if fs.Staging == Untracked && fs.Worktree == Untracked {
+			continue
+		}
+
+		changed = true
+		switch fs.Worktree {
+		case Unmodified:
+		case Deleted:
+			if _, err := wIdx.Remove(path); err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+				return plumbing.ZeroHash, err
+			}
+		default:
+			h, err := w.copyFileToStorage(idx, conv, path)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+
+			if err := w.addOrUpdateFileToIndex(wIdx, conv, path, h); err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+	}
+
+	if !changed {
+		return plumbing.ZeroHash, nil
+	}
+
+	stash, err := w.buildStashCommit(head, idx, wIdx)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	err = w.reset(&ResetOptions{Mode: HardReset, Commit: head.Hash()})
+	return stash, w.keepAutostash(stash, err)
+}
+
+// buildStashCommit stores the commits of a stash, with the index idx and the
+// worktree state wIdx on top of head.
+func (w *Worktree) buildStashCommit(head *plumbing.Reference, idx, wIdx *index.Index) (plumbing.Hash, error) {
+	headCommit, err := w.r.CommitObject(head.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	sig, err := w.r.stashSignature()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	branch := noBranchPrefix
+	if head.Name().IsBranch() {
+		branch = head.Name().Short()
+	}
+
+	subject, _, _ := strings.Cut(headCommit.Message, "\n")
+	h := &buildTreeHelper{fs: w.Filesystem, s: w.r.Storer}
+	idxTree, err := h.BuildTree(idx, nil)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	idxCommit, err := w.r.storeCommit(&object.Commit{
+		Author:       *sig,
+		Committer:    *sig,
+		Message:      fmt.Sprintf("index on %s: %s %s\n", branch, head.Hash().String()[:7], subject),
+		TreeHash:     idxTree,
+		ParentHashes: []plumbing.Hash{head.Hash()},
+	}, nil)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	wTree, err := h.BuildTree(wIdx, nil)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return w.r.storeCommit(&object.Commit{
+		Author:       *sig,
+		Committer:    *sig,
+		Message:      fmt.Sprintf("On %s: %s\n", branch, autostashMsg),
+		TreeHash:     wTree,
+		ParentHashes: []plumbing.Hash{head.Hash(), idxCommit},
+	}, nil)
+}
+
+// stashSignature returns the signature of the stash commits, the one of the
+// configured user or a placeholder if there is none.
+func (r *Repository) stashSignature() (*object.Signature, error) {
+	o := &CommitOptions{}
+	err := o.loadConfigAuthorAndCommitter(r)
+	if errors.Is(err, ErrMissingAuthor) {
+		return &object.Signature{Name: autostashMsg, When: time.Now()}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return o.Author, nil
+}
+
+// applyAutostash re-applies the changes of the stash made on top of base to
+// the worktree, merging them with the ones between base and the current HEAD
+// as a three-way merge does. If they conflict, nothing is applied, the stash
+// is stored at refs/stash and ErrAutostashConflict is returned. As with git
+// stash apply, the changes are left unstaged except the new files.
+func (w *Worktree) applyAutostash(base, stash plumbing.Hash) error {
+	baseTree, err := w.r.getTreeFromCommitHash(base)
+	if err != nil {
+		return err
+	}
+
+	stashTree, err := w.r.getTreeFromCommitHash(stash)
+	if err != nil {
+		return err
+	}
+
+	headTree, err := w.headTree()
+	if err != nil {
+		return err
+	}
+
+	merged, err := w.r.mergeTrees(baseTree, headTree, stashTree)
+	var conflict *MergeConflictError
+	if errors.As(err, &conflict) {
+		if err := w.r.storeAutostash(stash); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("%w: %s", ErrAutostashConflict, strings.Join(conflict.Paths, ", "))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	mergedTree, err := w.r.TreeObject(merged)
+	if err != nil {
+		return err
+	}
+
+	changes, err := object.DiffTree(headTree, mergedTree)
+	if err != nil {
+		return err
+	}
+
+	conv, err := w.fileConversion()
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range changes {
+		action, err := ch.Action()
+		if err != nil {
+			return err
+		}
+
+		if ch.To.TreeEntry.Mode == filemode.Submodule || ch.From.TreeEntry.Mode == filemode.Submodule {
+			continue
+		}
+
+		if err := w.applyAutostashChange(conv, mergedTree, ch, action); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Worktree) applyAutostashChange(conv *fileConversion, tree *object.Tree, ch *object.Change, action merkletrie.Action) error {
+	if action != merkletrie.Insert {
+		err := w.Filesystem.Remove(ch.From.Name)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if action == merkletrie.Delete {
+		return nil
+	}
+
+	f, err := tree.TreeEntryFile(&ch.To.TreeEntry)
+	if err != nil {
+		return err
+	}
+
+	f.Name = ch.To.Name
+	if err := w.checkoutFile(conv, f); err != nil {
+		return err
+	}
+
+	if action == merkletrie.Insert {
+		_, err = w.Add(ch.To.Name)
+	}
+
+	return err
+}
+
+// keepAutostash stores the stash at refs/stash if err is not nil, so the
+// stashed changes are not lost when the operation fails.
+func (w *Worktree) keepAutostash(stash plumbing.Hash, err error) error {
+	if err == nil || stash.IsZero() {
+		return err
+	}
+
+	return errors.Join(err, w.r.storeAutostash(stash))
+}
+
+// storeAutostash points refs/stash to the stash, recording the previous one
+// in its reflog as git stash store does.
+func (r *Repository) storeAutostash(stash plumbing.Hash) error {
+	old := plumbing.ZeroHash
+	ref, err := r.Storer.Reference(stashRefName)
+	switch {
+	case err == nil:
+		old = ref.Hash()
+	case !errors.Is(err, plumbing.ErrReferenceNotFound):
+		return err
+	}
+
+	if err := r.Storer.SetReference(plumbing.NewHashReference(stashRefName, stash)); err != nil {
+		return err
+	}
+
+	rs, ok := r.Storer.(storer.ReflogStorer)
+	if !ok {
+		return nil
+	}
+
+	sig, err := r.stashSignature()
+	if err != nil {
+		return err
+	}
+
+	return rs.AppendReflog(stashRefName, &reflog.Entry{
+		OldHash:   old,
+		NewHash:   stash,
+		Committer: reflog.Signature{Name: sig.Name, Email: sig.Email, When: sig.When},
+		Message:   autostashMsg,
+	})
+}
@@ -1,10 +1,13 @@
 package git
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"path"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -35,7 +38,9 @@ var (
 )
 
 // Commit stores the current contents of the index in a new commit along with
-// a log message from the user describing the changes.
+// a log message from the user describing the changes. When msg is empty, the
+// message proposed in MERGE_MSG by the merge or the cherry-pick in progress,
+// if any, is used.
 func (w *Worktree) Commit(msg string, opts *CommitOptions) (plumbing.Hash, error) {
 	if trace.Performance.Enabled() {
 		start := time.Now()
@@ -54,17 +59,21 @@ func (w *Worktree) Commit(msg string, opts *CommitOptions) (plumbing.Hash, error
 		}
 	}
 
-	if opts.Amend {
-		head, err := w.r.Head()
-		if err != nil {
+	var err error
+	if msg == "" {
+		if msg, err = w.r.MergeMessage(); err != nil {
 			return plumbing.ZeroHash, err
 		}
-		headCommit, err := w.r.CommitObject(head.Hash())
-		if err != nil {
+	}
+
+	if msg, err = w.commitMessage(msg, opts); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if opts.HooksEnabled && !opts.NoVerify {
+		if msg, err = w.runCommitHooks(msg); err != nil {
 			return plumbing.ZeroHash, err
 		}
-
-		opts.Parents = headCommit.ParentHashes
 	}
 
 	idx, err := w.r.Storer.Index()
@@ -72,6 +81,10 @@ func (w *Worktree) Commit(msg string, opts *CommitOptions) (plumbing.Hash, error
 		return plumbing.ZeroHash, err
 	}
 
+	if len(idx.Conflicts()) > 0 {
+		return plumbing.ZeroHash, ErrUnmergedPaths
+	}
+
 	// First handle the case of the first commit in the repository being empty.
 	if len(opts.Parents) == 0 && len(idx.Entries) == 0 && !opts.AllowEmptyCommits {
 		return plumbing.ZeroHash, ErrEmptyCommit
@@ -96,16 +109,128 @@ func (w *Worktree) Commit(msg string, opts *CommitOptions) (plumbing.Hash, error
 		previousTree = parentCommit.TreeHash
 	}
 
-	if treeHash == previousTree && !opts.AllowEmptyCommits {
+	// A merge commit is never empty, even when it keeps the tree of its
+	// first parent.
+	if treeHash == previousTree && len(opts.Parents) < 2 && !opts.AllowEmptyCommits {
 		return plumbing.ZeroHash, ErrEmptyCommit
 	}
 
+	var old plumbing.Hash
+	head, err := w.r.Head()
+	switch {
+	case err == nil:
+		old = head.Hash()
+	case opts.Amend || !errors.Is(err, plumbing.ErrReferenceNotFound):
+		return plumbing.ZeroHash, err
+	}
+
 	commit, err := w.buildCommitObject(msg, opts, treeHash)
 	if err != nil {
 		return plumbing.ZeroHash, err
 	}
 
-	return commit, w.updateHEAD(commit)
+	if err := w.updateHEAD(commit); err != nil {
+		return commit, err
+	}
+
+	// The index is only written once the commit is created, when the trees
+	// built are not all in its TREE extension yet.
+	if cache := h.cacheTree(idx); idx.Cache == nil || !slices.Equal(cache.Entries, idx.Cache.Entries) {
+		idx.Cache = cache
+		if err := w.r.Storer.SetIndex(idx); err != nil {
+			return commit, err
+		}
+	}
+
+	if !opts.Amend {
+		if err := w.r.ClearMergeState(); err != nil {
+			return commit, err
+		}
+	}
+
+	return commit, w.r.logHEADUpdate(old, commit, opts.Committer, commitReflogMessage(msg, opts))
+}
+
+// commitReflogMessage returns the message of the reflog entries of the
+// commit with the message msg, as git commit writes it.
+func commitReflogMessage(msg string, opts *CommitOptions) string {
+	action := "commit"
+	switch {
+	case opts.Amend:
+		action = "commit (amend)"
+	case len(opts.Parents) == 0:
+		action = "commit (initial)"
+	case len(opts.Parents) > 1:
+		action = "commit (merge)"
+	}
+
+	return action + ": " + reflogSubject(msg)
+}
+
+// commitMessage returns the message msg of a commit, with its comments
+// stripped, and its sign-off and trailers added, as set by opts. Without a
+// config loader only the config of the repository is read.
+func (w *Worktree) commitMessage(msg string, opts *CommitOptions) (string, error) {
+	if !opts.StripComments && !opts.SignOff && len(opts.Trailers) == 0 {
+		return msg, nil
+	}
+
+	scope := config.SystemScope
+	if !plugin.Has(plugin.ConfigLoader()) {
+		scope = config.LocalScope
+	}
+
+	cfg, err := w.r.ConfigScoped(scope)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.StripComments {
+		msg = object.CleanupMessage(msg, cfg.Core.CommentChar)
+	}
+
+	m := object.ParseMessage(msg, cfg.Trailer.Separators)
+	if opts.SignOff {
+		signOff(m, opts.Committer)
+	}
+
+	for _, t := range opts.Trailers {
+		m.AddTrailer(t.Key, t.Value)
+	}
+
+	if !opts.SignOff && len(opts.Trailers) == 0 {
+		return msg, nil
+	}
+
+	return m.String(), nil
+}
+
+// signOff adds to the message m the Signed-off-by trailer of the signature
+// sig, as git commit --signoff does, unless it is already its last trailer.
+func signOff(m *object.Message, sig *object.Signature) {
+	const key = "Signed-off-by"
+	value := fmt.Sprintf("%s <%s>", sig.Name, sig.Email)
+	if n := len(m.Trailers); n > 0 && m.Trailers[n-1].Key == key && m.Trailers[n-1].Value == value {
+		return
+	}
+
+	m.AddTrailer(key, value)
+}
+
+// runCommitHooks runs the pre-commit and commit-msg hooks, returning the
+// commit message as edited by the latter.
+func (w *Worktree) runCommitHooks(msg string) (string, error) {
+	hooks, err := newHookRunner(w.r.Storer, w.Filesystem)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	if err := hooks.run(ctx, hookPreCommit, nil); err != nil {
+		return "", err
+	}
+
+	return hooks.runCommitMsg(ctx, msg)
 }
 
 // CherryPick cherry picks commits and merge them into the worktree based on the selected
@@ -203,12 +328,17 @@ func (w *Worktree) autoAddModifiedAndDeleted() error {
 		return err
 	}
 
+	conv, err := w.fileConversion()
+	if err != nil {
+		return err
+	}
+
 	for path, fs := range s {
 		if fs.Worktree != Modified && fs.Worktree != Deleted {
 			continue
 		}
 
-		if _, _, err := w.doAddFile(idx, s, path, nil); err != nil {
+		if _, _, err := w.doAddFile(idx, conv, s, path, nil); err != nil {
 			return err
 		}
 	}
@@ -233,16 +363,21 @@ func (w *Worktree) updateHEAD(commit plumbing.Hash) error {
 
 func (w *Worktree) buildCommitObject(msg string, opts *CommitOptions, tree plumbing.Hash) (plumbing.Hash, error) {
 	commit := &object.Commit{
-		Author:       w.sanitize(*opts.Author),
-		Committer:    w.sanitize(*opts.Committer),
+		Author:       sanitizeSignature(*opts.Author),
+		Committer:    sanitizeSignature(*opts.Committer),
 		Message:      msg,
 		TreeHash:     tree,
 		ParentHashes: opts.Parents,
 	}
 
-	signer := opts.Signer
+	return w.r.storeCommit(commit, opts.Signer)
+}
+
+// storeCommit signs the commit with signer, or with the ObjectSigner plugin if
+// signer is nil and commit.gpgSign is enabled, and stores it.
+func (r *Repository) storeCommit(commit *object.Commit, signer Signer) (plumbing.Hash, error) {
 	if signer == nil {
-		cfg, err := w.r.ConfigScoped(config.SystemScope)
+		cfg, err := r.ConfigScoped(config.SystemScope)
 		if err == nil && cfg != nil && cfg.Commit.GpgSign.IsTrue() {
 			// Use Has before Get so the key is not frozen when no plugin is
 			// registered, allowing callers to register one later.
@@ -265,14 +400,14 @@ func (w *Worktree) buildCommitObject(msg string, opts *CommitOptions, tree plumb
 		commit.Signature = string(sig)
 	}
 
-	obj := w.r.Storer.NewEncodedObject()
+	obj := r.Storer.NewEncodedObject()
 	if err := commit.Encode(obj); err != nil {
 		return plumbing.ZeroHash, err
 	}
-	return w.r.Storer.SetEncodedObject(obj)
+	return r.Storer.SetEncodedObject(obj)
 }
 
-func (w *Worktree) sanitize(signature object.Signature) object.Signature {
+func sanitizeSignature(signature object.Signature) object.Signature {
 	return object.Signature{
 		Name:  invalidCharactersRe.ReplaceAllString(signature.Name, ""),
 		Email: invalidCharactersRe.ReplaceAllString(signature.Email, ""),
@@ -289,6 +424,11 @@ type buildTreeHelper struct {
 
 	trees   map[string]*object.Tree
 	entries map[string]*object.TreeEntry
+	hashes  map[string]plumbing.Hash
+	// cached are the valid trees of the TREE extension of the index, by
+	// directory, and reused the directories whose trees are reused from it.
+	cached map[string]index.TreeEntry
+	reused map[string]bool
 }
 
 // BuildTree builds the tree objects and push its to the storer, the hash
@@ -297,6 +437,16 @@ func (h *buildTreeHelper) BuildTree(idx *index.Index, _ *CommitOptions) (plumbin
 	const rootNode = ""
 	h.trees = map[string]*object.Tree{rootNode: {}}
 	h.entries = map[string]*object.TreeEntry{}
+	h.hashes = map[string]plumbing.Hash{}
+	h.cached = validCachedTrees(idx)
+	h.reused = map[string]bool{}
+
+	if h.isCachedTree(rootNode) {
+		hash := h.cached[rootNode].Hash
+		h.hashes[rootNode] = hash
+		h.reused[rootNode] = true
+		return hash, nil
+	}
 
 	for _, e := range idx.Entries {
 		if err := h.commitIndexEntry(e); err != nil {
@@ -315,12 +465,126 @@ func (h *buildTreeHelper) commitIndexEntry(e *index.Entry) error {
 		parent := fullpath
 		fullpath = path.Join(fullpath, part)
 
+		if fullpath != e.Name && h.isCachedTree(fullpath) {
+			if !h.reused[fullpath] {
+				h.reused[fullpath] = true
+				h.trees[parent].Entries = append(h.trees[parent].Entries, object.TreeEntry{
+					Name: part,
+					Mode: filemode.Dir,
+					Hash: h.cached[fullpath].Hash,
+				})
+			}
+
+			return nil
+		}
+
 		h.doBuildTree(e, parent, fullpath)
 	}
 
 	return nil
 }
 
+// validCachedTrees returns the trees of the TREE extension of idx which are
+// valid, by directory, checking that they cover as many entries as the index
+// has in their directory.
+func validCachedTrees(idx *index.Index) map[string]index.TreeEntry {
+	if idx.Cache == nil {
+		return nil
+	}
+
+	cached := idx.Cache.ValidEntries()
+	if len(cached) == 0 {
+		return nil
+	}
+
+	counts := indexDirEntryCounts(idx)
+	for dir, e := range cached {
+		if counts[dir] != e.Entries {
+			delete(cached, dir)
+		}
+	}
+
+	return cached
+}
+
+// indexDirEntryCounts returns the number of entries of idx in each directory,
+// "" being the root one.
+func indexDirEntryCounts(idx *index.Index) map[string]int {
+	counts := map[string]int{}
+	for _, e := range idx.Entries {
+		for dir := path.Dir(e.Name); dir != "."; dir = path.Dir(dir) {
+			counts[dir]++
+		}
+
+		counts[""]++
+	}
+
+	return counts
+}
+
+// isCachedTree returns whether the tree of the directory dir can be reused
+// from the TREE extension of the index, being valid and stored.
+func (h *buildTreeHelper) isCachedTree(dir string) bool {
+	e, ok := h.cached[dir]
+	if !ok {
+		return false
+	}
+
+	if h.s.HasEncodedObject(e.Hash) != nil {
+		delete(h.cached, dir)
+		return false
+	}
+
+	return true
+}
+
+// cacheTree returns the TREE extension of idx matching the tree last built
+// from it, the trees reused keeping their cached subtrees.
+func (h *buildTreeHelper) cacheTree(idx *index.Index) *index.Tree {
+	counts := indexDirEntryCounts(idx)
+	children := map[string][]string{}
+	for dir := range counts {
+		if dir != "" {
+			parent := path.Dir(dir)
+			if parent == "." {
+				parent = ""
+			}
+
+			children[parent] = append(children[parent], dir)
+		}
+	}
+
+	t := &index.Tree{}
+	var walk func(dir string, reused bool)
+	walk = func(dir string, reused bool) {
+		// git orders the subtrees by the length of their name first.
+		slices.SortFunc(children[dir], func(a, b string) int {
+			a, b = path.Base(a), path.Base(b)
+			return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+		})
+
+		e := index.TreeEntry{Path: path.Base(dir), Entries: -1, Trees: len(children[dir])}
+		if dir == "" {
+			e.Path = ""
+		}
+
+		reused = reused || h.reused[dir]
+		if hash, ok := h.hashes[dir]; ok && !reused {
+			e.Entries, e.Hash = counts[dir], hash
+		} else if c, ok := h.cached[dir]; ok && reused {
+			e.Entries, e.Hash = c.Entries, c.Hash
+		}
+
+		t.Entries = append(t.Entries, e)
+		for _, child := range children[dir] {
+			walk(child, reused)
+		}
+	}
+
+	walk("", false)
+	return t
+}
+
 func (h *buildTreeHelper) doBuildTree(e *index.Entry, parent, fullpath string) {
 	if _, ok := h.trees[fullpath]; ok {
 		return
@@ -363,6 +627,10 @@ func (h *buildTreeHelper) copyTreeToStorageRecursive(parent string, t *object.Tr
 		}
 
 		path := path.Join(parent, e.Name)
+		if h.reused[path] {
+			h.hashes[path] = e.Hash
+			continue
+		}
 
 		var err error
 		e.Hash, err = h.copyTreeToStorageRecursive(path, h.trees[path])
@@ -379,6 +647,7 @@ func (h *buildTreeHelper) copyTreeToStorageRecursive(parent string, t *object.Tr
 	}
 
 	hash := o.Hash()
+	h.hashes[parent] = hash
 	if h.s.HasEncodedObject(hash) == nil {
 		return hash, nil
 	}
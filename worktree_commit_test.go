@@ -28,6 +28,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/go-git/go-git/v6/plumbing/storer"
 	"github.com/go-git/go-git/v6/plumbing/transport"
+	"github.com/go-git/go-git/v6/storage"
 	"github.com/go-git/go-git/v6/storage/filesystem"
 	"github.com/go-git/go-git/v6/storage/memory"
 	"github.com/go-git/go-git/v6/x/plugin"
@@ -63,6 +64,124 @@ func TestBuildTreeHelper_1773(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// countingObjectStorer counts the objects created, the trees built being
+// encoded in new objects.
+type countingObjectStorer struct {
+	storage.Storer
+	count int
+}
+
+func (s *countingObjectStorer) NewEncodedObject() plumbing.EncodedObject {
+	s.count++
+	return s.Storer.NewEncodedObject()
+}
+
+func TestBuildTreeHelperIndexCache(t *testing.T) {
+	t.Parallel()
+
+	st := &countingObjectStorer{Storer: memory.NewStorage()}
+	idx := &index.Index{}
+	for _, name := range []string{"a/b/c/foo", "a/b/bar", "a/qux", "d/e/f", "g"} {
+		idx.Entries = append(idx.Entries, &index.Entry{
+			Name: name,
+			Mode: filemode.Regular,
+			Hash: plumbing.NewHash("e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"),
+		})
+	}
+
+	build := func() plumbing.Hash {
+		h := &buildTreeHelper{s: st}
+		hash, err := h.BuildTree(idx, nil)
+		require.NoError(t, err)
+
+		idx.Cache = h.cacheTree(idx)
+		return hash
+	}
+
+	first := build()
+	assert.Equal(t, 6, st.count)
+	require.Len(t, idx.Cache.Entries, 6)
+	assert.Equal(t, index.TreeEntry{Path: "", Entries: 5, Trees: 2, Hash: first}, idx.Cache.Entries[0])
+	assert.Len(t, idx.Cache.ValidEntries(), 6)
+
+	st.count = 0
+	assert.Equal(t, first, build())
+	assert.Equal(t, 0, st.count)
+
+	idx.Entries[0].Hash = plumbing.NewHash("257cc5642cb1a054f08cc83f2d943e56fd3ebe99")
+	idx.InvalidateCache(idx.Entries[0].Name)
+	assert.Len(t, idx.Cache.ValidEntries(), 2)
+
+	st.count = 0
+	second := build()
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, 4, st.count)
+	assert.Len(t, idx.Cache.ValidEntries(), 6)
+
+	expected, err := (&buildTreeHelper{s: memory.NewStorage()}).BuildTree(idx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, expected, second)
+}
+
+func (s *WorktreeSuite) TestCommitIndexCache() {
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	s.Require().NoError(err)
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+
+	for _, name := range []string{"a/foo", "a/b/bar", "c/qux", "baz"} {
+		s.Require().NoError(util.WriteFile(fs, name, []byte(name), 0o644))
+	}
+
+	s.Require().NoError(w.AddGlob("."))
+	_, err = w.Commit("first", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	idx, err := r.Storer.Index()
+	s.Require().NoError(err)
+	s.Require().NotNil(idx.Cache)
+	s.Len(idx.Cache.ValidEntries(), 4)
+
+	s.Require().NoError(util.WriteFile(fs, "a/b/bar", []byte("changed"), 0o644))
+	_, err = w.Add("a/b/bar")
+	s.Require().NoError(err)
+
+	idx, err = r.Storer.Index()
+	s.Require().NoError(err)
+	valid := idx.Cache.ValidEntries()
+	s.Len(valid, 1)
+	s.Contains(valid, "c")
+
+	hash, err := w.Commit("second", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	commit, err := r.CommitObject(hash)
+	s.Require().NoError(err)
+	expected, err := (&buildTreeHelper{s: memory.NewStorage()}).BuildTree(&index.Index{Entries: idx.Entries}, nil)
+	s.Require().NoError(err)
+	s.Equal(expected, commit.TreeHash)
+
+	idx, err = r.Storer.Index()
+	s.Require().NoError(err)
+	s.Len(idx.Cache.ValidEntries(), 4)
+	s.Equal(commit.TreeHash, idx.Cache.Entries[0].Hash)
+
+	// A failed commit leaves the index untouched.
+	s.Require().NoError(util.WriteFile(fs, "c/new", []byte("new"), 0o644))
+	_, err = w.Add("c/new")
+	s.Require().NoError(err)
+	_, err = w.Remove("c/new")
+	s.Require().NoError(err)
+	_, err = w.Commit("empty", &CommitOptions{Author: defaultSignature()})
+	s.Require().ErrorIs(err, ErrEmptyCommit)
+
+	idx, err = r.Storer.Index()
+	s.Require().NoError(err)
+	s.Len(idx.Cache.ValidEntries(), 2)
+}
+
 func (s *WorktreeSuite) TestCommitEmptyOptions() {
 	fs := memfs.New()
 	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
@@ -316,6 +435,126 @@ func (s *WorktreeSuite) TestCommitAmendNothingToCommit() {
 	s.Equal(plumbing.ZeroHash, amendedHash)
 }
 
+func (s *WorktreeSuite) TestCommitAmendKeepsAuthor() {
+	r, err := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
+	s.Require().NoError(err)
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+
+	s.Require().NoError(util.WriteFile(w.Filesystem, "foo", []byte("foo"), 0o644))
+	_, err = w.Add("foo")
+	s.Require().NoError(err)
+	first, err := w.Commit("foo\n", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	s.Require().NoError(util.WriteFile(w.Filesystem, "bar", []byte("bar"), 0o644))
+	_, err = w.Add("bar")
+	s.Require().NoError(err)
+	prev, err := w.Commit("bar\n", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	committer := &object.Signature{Name: "bar", Email: "bar@bar.bar", When: time.Unix(1700000000, 0).UTC()}
+	amended, err := w.Commit("bar amended\n\nbody\n", &CommitOptions{Committer: committer, Amend: true})
+	s.Require().NoError(err)
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	s.Equal(amended, head.Hash())
+
+	commit, err := r.CommitObject(amended)
+	s.Require().NoError(err)
+	s.Equal([]plumbing.Hash{first}, commit.ParentHashes)
+	s.Equal(defaultSignature().Name, commit.Author.Name)
+	s.Equal("bar", commit.Committer.Name)
+
+	for _, name := range []plumbing.ReferenceName{plumbing.HEAD, head.Name()} {
+		entries, err := r.Storer.(storer.ReflogStorer).Reflog(name)
+		s.Require().NoError(err)
+		s.Require().Len(entries, 3)
+		s.Equal("commit (initial): foo", entries[0].Message)
+		s.Equal("commit: bar", entries[1].Message)
+		s.Equal(prev, entries[2].OldHash)
+		s.Equal(amended, entries[2].NewHash)
+		s.Equal("commit (amend): bar amended", entries[2].Message)
+	}
+}
+
+func (s *WorktreeSuite) TestCommitSignOff() {
+	r, err := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
+	s.Require().NoError(err)
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+
+	committer := &object.Signature{Name: "bar", Email: "bar@bar.bar", When: time.Now()}
+	h, err := w.Commit("foo\n", &CommitOptions{
+		Author:            defaultSignature(),
+		Committer:         committer,
+		AllowEmptyCommits: true,
+		SignOff:           true,
+	})
+	s.Require().NoError(err)
+
+	commit, err := r.CommitObject(h)
+	s.Require().NoError(err)
+	s.Equal("foo\n\nSigned-off-by: bar <bar@bar.bar>\n", commit.Message)
+}
+
+func (s *WorktreeSuite) TestCommitTrailers() {
+	r, err := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
+	s.Require().NoError(err)
+
+	cfg, err := r.Config()
+	s.Require().NoError(err)
+	cfg.Core.CommentChar = ";"
+	cfg.Trailer.Separators = ":#"
+	s.Require().NoError(r.SetConfig(cfg))
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+
+	h, err := w.Commit("foo\n; comment\n\n\nbar  \n\nFixes #1\n", &CommitOptions{
+		Author:            defaultSignature(),
+		AllowEmptyCommits: true,
+		SignOff:           true,
+		StripComments:     true,
+		Trailers:          []object.Trailer{{Key: "Reviewed-by", Value: "bar <bar@bar.bar>"}},
+	})
+	s.Require().NoError(err)
+
+	commit, err := r.CommitObject(h)
+	s.Require().NoError(err)
+	s.Equal("foo\n\nbar\n\nFixes #1\nSigned-off-by: foo <foo@foo.foo>\nReviewed-by: bar <bar@bar.bar>\n", commit.Message)
+}
+
+func TestSignOff(t *testing.T) {
+	t.Parallel()
+
+	sig := &object.Signature{Name: "foo", Email: "foo@foo.foo"}
+	sob := "Signed-off-by: foo <foo@foo.foo>\n"
+
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"", "\n\n" + sob},
+		{"foo", "foo\n\n" + sob},
+		{"foo\n\nbody\n", "foo\n\nbody\n\n" + sob},
+		{"Acked-by: x <y>\n", "Acked-by: x <y>\n\n" + sob},
+		{"foo\n\nAcked-by: x <y>\n", "foo\n\nAcked-by: x <y>\n" + sob},
+		{"foo\n\nAcked-by: x\n  <y>\n", "foo\n\nAcked-by: x\n  <y>\n" + sob},
+		{"foo\n\n" + sob, "foo\n\n" + sob},
+		{"foo\n\n" + sob + "Acked-by: x <y>\n", "foo\n\n" + sob + "Acked-by: x <y>\n" + sob},
+	}
+
+	for _, tt := range tests {
+		m := object.ParseMessage(tt.msg, "")
+		signOff(m, sig)
+		assert.Equal(t, tt.want, m.String(), "%q", tt.msg)
+	}
+}
+
 func TestCount(t *testing.T) {
 	t.Parallel()
 	f := fixtures.Basic().One()
@@ -0,0 +1,204 @@
+package git
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/utils/ioutil"
+	"github.com/go-git/go-git/v6/utils/merge"
+)
+
+// ErrUnmergedPaths is returned when an operation requires the conflicts of
+// the index to be resolved first, such as committing or switching branches.
+var ErrUnmergedPaths = errors.New("index contains unmerged paths")
+
+// checkUnmerged returns ErrUnmergedPaths if the index has unmerged paths.
+func (w *Worktree) checkUnmerged() error {
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	if len(idx.Conflicts()) > 0 {
+		return ErrUnmergedPaths
+	}
+
+	return nil
+}
+
+// resolveConflict removes the entries in the stages of the merge of name, if
+// it is unmerged, so that it can be added to the index as resolved.
+func resolveConflict(idx *index.Index, name string) {
+	name = filepath.ToSlash(name)
+	n := len(idx.Entries)
+	idx.Entries = slices.DeleteFunc(idx.Entries, func(e *index.Entry) bool {
+		return e.Name == name && e.Stage != index.Merged
+	})
+
+	if len(idx.Entries) != n {
+		idx.InvalidateCache(name)
+	}
+}
+
+// conflictStatus returns the status codes of an unmerged path, as reported by
+// git status from the stages of the merge present in the index.
+func conflictStatus(c *index.Conflict) (staging, worktree StatusCode) {
+	switch {
+	case c.Ours != nil && c.Theirs != nil:
+		if c.Ancestor == nil {
+			return Added, Added
+		}
+
+		return UpdatedButUnmerged, UpdatedButUnmerged
+	case c.Ours != nil:
+		if c.Ancestor == nil {
+			return Added, UpdatedButUnmerged
+		}
+
+		return UpdatedButUnmerged, Deleted
+	case c.Theirs != nil:
+		if c.Ancestor == nil {
+			return UpdatedButUnmerged, Added
+		}
+
+		return Deleted, UpdatedButUnmerged
+	default:
+		return Deleted, Deleted
+	}
+}
+
+// restoreConflicts recreates in the working tree the conflicted merge of the
+// unmerged paths matched by filter, from their stages in the index, as git
+// restore --merge does.
+func (w *Worktree) restoreConflicts(filter *pathFilter) error {
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	conv, err := w.fileConversion()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range idx.Conflicts() {
+		if !filter.Match(c.Name) {
+			continue
+		}
+
+		if err := validPath(c.Name); err != nil {
+			return err
+		}
+
+		if err := w.checkoutConflict(conv, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkoutConflict writes the content of an unmerged path to the working
+// tree: the merge of both sides with conflict markers, or the only side
+// having the path. Paths deleted on both sides, and whose sides can't be
+// merged line by line, are left untouched.
+func (w *Worktree) checkoutConflict(conv *fileConversion, c *index.Conflict) error {
+	switch {
+	case c.Ours == nil && c.Theirs == nil:
+		return nil
+	case c.Ours == nil:
+		return w.checkoutConflictStage(conv, c.Theirs)
+	case c.Theirs == nil:
+		return w.checkoutConflictStage(conv, c.Ours)
+	}
+
+	mergeable := isMergeableMode(c.Ours.Mode) && isMergeableMode(c.Theirs.Mode) &&
+		(c.Ancestor == nil || isMergeableMode(c.Ancestor.Mode))
+	if !mergeable {
+		return nil
+	}
+
+	var contents [3]string
+	for i, e := range []*index.Entry{c.Ancestor, c.Ours, c.Theirs} {
+		if e == nil {
+			continue
+		}
+
+		content, isBinary, err := w.r.blobContent(e.Hash)
+		if err != nil || isBinary {
+			return err
+		}
+
+		contents[i] = content
+	}
+
+	opts := merge.Options{OursLabel: "ours", BaseLabel: "base", TheirsLabel: "theirs"}
+	if conv.cfg.Merge.ConflictStyle != "" {
+		style, err := merge.ParseConflictStyle(conv.cfg.Merge.ConflictStyle)
+		if err != nil {
+			return err
+		}
+
+		opts.Style = style
+	}
+
+	parts := strings.Split(c.Name, "/")
+	attrs, err := conv.dirAttributes(parts[: len(parts)-1 : len(parts)-1])
+	if err != nil {
+		return err
+	}
+
+	opts.MarkerSize = gitattributes.MatchConflictMarkerSize(attrs, parts)
+	merged, _ := merge.Merge(contents[0], contents[1], contents[2], &opts)
+
+	return w.writeConflict(c.Name, c.Ours, merged)
+}
+
+// checkoutConflictStage writes the content of the entry of a stage to the
+// working tree.
+func (w *Worktree) checkoutConflictStage(conv *fileConversion, e *index.Entry) error {
+	if e.Mode == filemode.Submodule {
+		return nil
+	}
+
+	blob, err := w.r.BlobObject(e.Hash)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Filesystem.Remove(e.Name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return w.checkoutFile(conv, object.NewFile(e.Name, e.Mode, blob))
+}
+
+// writeConflict writes the merged content of an unmerged path to the working
+// tree, with the mode of the entry e.
+func (w *Worktree) writeConflict(name string, e *index.Entry, content string) (err error) {
+	mode, err := e.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+
+	if err := w.Filesystem.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := w.Filesystem.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer ioutil.CheckClose(f, &err)
+
+	_, err = io.WriteString(f, content)
+	return err
+}
@@ -0,0 +1,216 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+// newConflictedWorktree returns a worktree whose index has the stages of
+// conflicts, as left by a merge, for each kind of conflict.
+func newConflictedWorktree(t *testing.T) (*Repository, *Worktree) {
+	t.Helper()
+
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	for _, name := range []string{"both-modified", "deleted-by-them", "deleted-by-us", "both-deleted"} {
+		require.NoError(t, util.WriteFile(fs, name, []byte("a\nb\nc\n"), 0o644))
+		_, err = w.Add(name)
+		require.NoError(t, err)
+	}
+
+	_, err = w.Commit("base", &CommitOptions{Author: defaultSignature()})
+	require.NoError(t, err)
+
+	blob := func(content string) plumbing.Hash {
+		obj := r.Storer.NewEncodedObject()
+		obj.SetType(plumbing.BlobObject)
+		wr, err := obj.Writer()
+		require.NoError(t, err)
+		_, err = wr.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, wr.Close())
+		h, err := r.Storer.SetEncodedObject(obj)
+		require.NoError(t, err)
+		return h
+	}
+
+	base, ours, theirs := blob("a\nb\nc\n"), blob("a\nours\nc\n"), blob("a\ntheirs\nc\n")
+	stages := []struct {
+		name  string
+		stage index.Stage
+		hash  plumbing.Hash
+	}{
+		{"both-modified", index.AncestorMode, base},
+		{"both-modified", index.OurMode, ours},
+		{"both-modified", index.TheirMode, theirs},
+		{"both-added", index.OurMode, ours},
+		{"both-added", index.TheirMode, theirs},
+		{"deleted-by-them", index.AncestorMode, base},
+		{"deleted-by-them", index.OurMode, ours},
+		{"deleted-by-us", index.AncestorMode, base},
+		{"deleted-by-us", index.TheirMode, theirs},
+		{"both-deleted", index.AncestorMode, base},
+		{"added-by-us", index.OurMode, ours},
+		{"added-by-them", index.TheirMode, theirs},
+	}
+
+	idx, err := r.Storer.Index()
+	require.NoError(t, err)
+	idx.Entries = idx.Entries[:0]
+	for _, s := range stages {
+		idx.Entries = append(idx.Entries, &index.Entry{
+			Name:  s.name,
+			Hash:  s.hash,
+			Mode:  filemode.Regular,
+			Stage: s.stage,
+		})
+	}
+	require.NoError(t, r.Storer.SetIndex(idx))
+
+	for _, name := range []string{"deleted-by-us", "both-deleted"} {
+		require.NoError(t, fs.Remove(name))
+	}
+
+	return r, w
+}
+
+func TestStatusConflicts(t *testing.T) {
+	t.Parallel()
+
+	_, w := newConflictedWorktree(t)
+	status, err := w.Status()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		staging  StatusCode
+		worktree StatusCode
+	}{
+		{"both-modified", UpdatedButUnmerged, UpdatedButUnmerged},
+		{"both-added", Added, Added},
+		{"deleted-by-them", UpdatedButUnmerged, Deleted},
+		{"deleted-by-us", Deleted, UpdatedButUnmerged},
+		{"both-deleted", Deleted, Deleted},
+		{"added-by-us", Added, UpdatedButUnmerged},
+		{"added-by-them", UpdatedButUnmerged, Added},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := status[tc.name]
+			require.NotNil(t, fs)
+			assert.Equal(t, tc.staging, fs.Staging)
+			assert.Equal(t, tc.worktree, fs.Worktree)
+			assert.True(t, status.IsUnmerged(tc.name))
+		})
+	}
+}
+
+func TestRestoreMergeConflicts(t *testing.T) {
+	t.Parallel()
+
+	r, w := newConflictedWorktree(t)
+	err := w.Restore(&RestoreOptions{Staged: true, Merge: true, Files: []string{"both-modified"}})
+	assert.ErrorIs(t, err, ErrRestoreMergeStaged)
+
+	err = w.Restore(&RestoreOptions{
+		Worktree: true,
+		Merge:    true,
+		Files:    []string{"both-modified", "both-added", "deleted-by-us", "added-by-us", "both-deleted"},
+	})
+	require.NoError(t, err)
+
+	content, err := util.ReadFile(w.Filesystem, "both-modified")
+	require.NoError(t, err)
+	assert.Equal(t, "a\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\nc\n", string(content))
+
+	content, err = util.ReadFile(w.Filesystem, "both-added")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "<<<<<<< ours\n")
+
+	content, err = util.ReadFile(w.Filesystem, "deleted-by-us")
+	require.NoError(t, err)
+	assert.Equal(t, "a\ntheirs\nc\n", string(content))
+
+	content, err = util.ReadFile(w.Filesystem, "added-by-us")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nours\nc\n", string(content))
+
+	_, err = w.Filesystem.Lstat("both-deleted")
+	assert.Error(t, err)
+
+	idx, err := r.Storer.Index()
+	require.NoError(t, err)
+	assert.Len(t, idx.Conflicts(), 7)
+}
+
+func TestUnmergedPathsResolution(t *testing.T) {
+	t.Parallel()
+
+	r, w := newConflictedWorktree(t)
+
+	_, err := w.Commit("merge", &CommitOptions{Author: defaultSignature()})
+	assert.ErrorIs(t, err, ErrUnmergedPaths)
+	assert.ErrorIs(t, w.Checkout(&CheckoutOptions{Branch: plumbing.Master}), ErrUnmergedPaths)
+
+	require.NoError(t, util.WriteFile(w.Filesystem, "both-modified", []byte("a\nresolved\nc\n"), 0o644))
+	_, err = w.Add("both-modified")
+	require.NoError(t, err)
+	_, err = w.Remove("deleted-by-them")
+	require.NoError(t, err)
+
+	idx, err := r.Storer.Index()
+	require.NoError(t, err)
+	e, err := idx.Entry("both-modified")
+	require.NoError(t, err)
+	assert.Equal(t, index.Merged, e.Stage)
+	_, err = idx.Entry("deleted-by-them")
+	assert.ErrorIs(t, err, index.ErrEntryNotFound)
+	assert.Len(t, idx.Conflicts(), 5)
+
+	status, err := w.Status()
+	require.NoError(t, err)
+	assert.False(t, status.IsUnmerged("both-modified"))
+	assert.Equal(t, Modified, status.File("both-modified").Staging)
+	assert.True(t, status.IsUnmerged("both-added"))
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	require.NoError(t, w.Reset(&ResetOptions{Commit: head.Hash(), Mode: HardReset}))
+
+	idx, err = r.Storer.Index()
+	require.NoError(t, err)
+	assert.Empty(t, idx.Conflicts())
+
+	content, err := util.ReadFile(w.Filesystem, "deleted-by-us")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\nc\n", string(content))
+
+	c, err := r.CommitObject(head.Hash())
+	require.NoError(t, err)
+	tree, err := c.Tree()
+	require.NoError(t, err)
+	for _, e := range idx.Entries {
+		te, err := tree.FindEntry(e.Name)
+		require.NoError(t, err)
+		assert.Equal(t, te.Hash, e.Hash)
+	}
+
+	assert.Len(t, idx.Entries, 4)
+}
@@ -0,0 +1,345 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v6/plumbing/format/diff"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/plumbing/object"
+	"github.com/go-git/go-git/v6/utils/merkletrie"
+)
+
+// Diff returns the patch of the uncommitted changes. By default these are
+// the changes of the working tree not staged in the index, as git diff
+// shows, the options selecting the staged ones too or instead. Untracked
+// files are never part of the patch.
+func (w *Worktree) Diff(opts *WorktreeDiffOptions) (*object.Patch, error) {
+	return w.DiffContext(context.Background(), opts)
+}
+
+// DiffContext is like Diff, the context allowing to cancel the computation
+// of the patch.
+func (w *Worktree) DiffContext(ctx context.Context, opts *WorktreeDiffOptions) (*object.Patch, error) {
+	if opts == nil {
+		opts = &WorktreeDiffOptions{}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	var head *object.Tree
+	if opts.Staged {
+		head, err = w.headTree()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var paths []string
+	if opts.Staged {
+		changes, err := w.diffTreeWithStaging(head, false)
+		if err != nil {
+			return nil, err
+		}
+
+		paths, err = changedPaths(paths, changes, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Unstaged {
+		changes, err := w.diffStagingWithWorktree(false, true)
+		if err != nil {
+			return nil, err
+		}
+
+		paths, err = changedPaths(paths, changes, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	conv, err := w.fileConversion()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &worktreeDiff{w: w, idx: idx, head: head, conv: conv}
+	var filePatches []fdiff.FilePatch
+	for _, path := range paths {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		var from, to *worktreeDiffFile
+		if opts.Staged {
+			from, err = d.headFile(path)
+		} else {
+			from, err = d.indexFile(path)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.Unstaged {
+			to, err = d.worktreeFile(path)
+		} else {
+			to, err = d.indexFile(path)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if from == nil && to == nil || from != nil && to != nil && from.hash == to.hash && from.mode == to.mode {
+			continue
+		}
+
+		fp, err := object.NewFilePatch(ctx, from.file(), to.file(), from.contentOrNil(), to.contentOrNil())
+		if err != nil {
+			return nil, err
+		}
+
+		filePatches = append(filePatches, fp)
+	}
+
+	return object.NewPatch("", filePatches), nil
+}
+
+// changedPaths appends the paths of the changes to paths, skipping the
+// untracked files when the changes are the ones of the working tree.
+func changedPaths(paths []string, changes merkletrie.Changes, worktree bool) ([]string, error) {
+	for _, ch := range changes {
+		a, err := ch.Action()
+		if err != nil {
+			return nil, err
+		}
+
+		if worktree && a == merkletrie.Insert {
+			continue
+		}
+
+		paths = append(paths, nameFromAction(&ch))
+	}
+
+	return paths, nil
+}
+
+// worktreeDiff reads the versions of the files compared by Worktree.Diff.
+type worktreeDiff struct {
+	w          *Worktree
+	idx        *index.Index
+	head       *object.Tree
+	conv       *fileConversion
+	submodules map[string]plumbing.Hash
+}
+
+// headFile returns the file at path in HEAD, nil if missing.
+func (d *worktreeDiff) headFile(path string) (*worktreeDiffFile, error) {
+	if d.head == nil {
+		return nil, nil
+	}
+
+	e, err := d.head.FindEntry(path)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d.storedFile(path, e.Hash, e.Mode)
+}
+
+// indexFile returns the file at path in the index, nil if missing.
+func (d *worktreeDiff) indexFile(path string) (*worktreeDiffFile, error) {
+	e, err := d.idx.Entry(path)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d.storedFile(path, e.Hash, e.Mode)
+}
+
+// storedFile returns the file at path whose content is the blob h.
+func (d *worktreeDiff) storedFile(path string, h plumbing.Hash, mode filemode.FileMode) (*worktreeDiffFile, error) {
+	f := &worktreeDiffFile{path: path, hash: h, mode: mode}
+	if mode == filemode.Submodule {
+		f.content = fmt.Appendf(nil, "Subproject commit %s\n", h)
+		return f, nil
+	}
+
+	blob, err := d.w.r.BlobObject(h)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+	f.content, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// worktreeFile returns the file at path in the working tree, with the
+// content it would have once added to the index. It is nil if missing or
+// not tracked.
+func (d *worktreeDiff) worktreeFile(path string) (*worktreeDiffFile, error) {
+	e, err := d.idx.Entry(path)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := d.w.Filesystem.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Mode == filemode.Submodule {
+		return d.worktreeSubmodule(path)
+	}
+
+	if fi.IsDir() {
+		return nil, nil
+	}
+
+	mode, err := filemode.NewFromOSFileMode(fi.Mode())
+	if err != nil {
+		return nil, err
+	}
+
+	// Without core.filemode, the executable bit is the one of the index.
+	if !d.conv.cfg.Core.FileMode && mode != filemode.Symlink && (e.Mode.IsRegular() || e.Mode == filemode.Executable) {
+		mode = e.Mode
+	}
+
+	symlinkFile, err := d.conv.isSymlinkFile(d.idx, path, fi.Mode())
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		err = d.w.fillEncodedObjectFromSymlink(&buf, path, fi)
+	case symlinkFile:
+		mode = filemode.Symlink
+		err = d.w.fillEncodedObjectFromSymlinkFile(&buf, path)
+	default:
+		err = d.w.fillEncodedObjectFromFile(d.conv, &buf, path, fi)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	hasher := plumbing.NewHasher(d.conv.cfg.Extensions.ObjectFormat, plumbing.BlobObject, int64(buf.Len()))
+	if _, err := hasher.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return &worktreeDiffFile{path: path, hash: hasher.Sum(), mode: mode, content: buf.Bytes()}, nil
+}
+
+// worktreeSubmodule returns the submodule at path, summarized by the commit
+// checked out in it.
+func (d *worktreeDiff) worktreeSubmodule(path string) (*worktreeDiffFile, error) {
+	if d.submodules == nil {
+		var err error
+		d.submodules, err = d.w.getSubmodulesStatus()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	h, ok := d.submodules[path]
+	if !ok {
+		return nil, nil
+	}
+
+	return &worktreeDiffFile{
+		path:    path,
+		hash:    h,
+		mode:    filemode.Submodule,
+		content: fmt.Appendf(nil, "Subproject commit %s\n", h),
+	}, nil
+}
+
+// worktreeDiffFile is a version of a file compared by Worktree.Diff. It is
+// an implementation of fdiff.File interface.
+type worktreeDiffFile struct {
+	path    string
+	hash    plumbing.Hash
+	mode    filemode.FileMode
+	content []byte
+}
+
+func (f *worktreeDiffFile) Hash() plumbing.Hash {
+	return f.hash
+}
+
+func (f *worktreeDiffFile) Mode() filemode.FileMode {
+	return f.mode
+}
+
+func (f *worktreeDiffFile) Path() string {
+	return f.path
+}
+
+// file returns f as a fdiff.File, nil if f is nil.
+func (f *worktreeDiffFile) file() fdiff.File {
+	if f == nil {
+		return nil
+	}
+
+	return f
+}
+
+func (f *worktreeDiffFile) contentOrNil() []byte {
+	if f == nil {
+		return nil
+	}
+
+	return f.content
+}
@@ -0,0 +1,91 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/go-git/go-billy/v6/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fdiff "github.com/go-git/go-git/v6/plumbing/format/diff"
+	"github.com/go-git/go-git/v6/storage/memory"
+)
+
+func TestWorktreeDiff(t *testing.T) {
+	t.Parallel()
+
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	require.NoError(t, util.WriteFile(fs, "foo", []byte("a\nb\n"), 0o644))
+	require.NoError(t, util.WriteFile(fs, "bar", []byte("x\n"), 0o644))
+	require.NoError(t, w.AddWithOptions(&AddOptions{All: true}))
+	_, err = w.Commit("init", &CommitOptions{Author: defaultSignature()})
+	require.NoError(t, err)
+
+	patch, err := w.Diff(nil)
+	require.NoError(t, err)
+	assert.Empty(t, patch.FilePatches())
+
+	require.NoError(t, util.WriteFile(fs, "foo", []byte("a\nc\n"), 0o644))
+	require.NoError(t, util.WriteFile(fs, "untracked", []byte("u\n"), 0o644))
+
+	patch, err = w.Diff(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "diff --git a/foo b/foo\n"+
+		"index 422c2b7ab3b3c668038da977e4e93a5fc623169c..0f7bc766052a5a0ee28a393d51d2370f96d8ceb8 100644\n"+
+		"--- a/foo\n"+
+		"+++ b/foo\n"+
+		"@@ -1,2 +1,2 @@\n"+
+		" a\n"+
+		"-b\n"+
+		"+c\n", patch.String())
+
+	require.NoError(t, util.WriteFile(fs, "bar", []byte("y\n"), 0o644))
+	_, err = w.Add("bar")
+	require.NoError(t, err)
+	require.NoError(t, fs.Remove("foo"))
+
+	patch, err = w.Diff(&WorktreeDiffOptions{Staged: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, patchPaths(patch.FilePatches()))
+	assert.Equal(t, 1, patch.Stats()[0].Addition)
+	assert.Equal(t, 1, patch.Stats()[0].Deletion)
+
+	patch, err = w.Diff(&WorktreeDiffOptions{Unstaged: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, patchPaths(patch.FilePatches()))
+	from, to := patch.FilePatches()[0].Files()
+	assert.NotNil(t, from)
+	assert.Nil(t, to)
+
+	patch, err = w.Diff(&WorktreeDiffOptions{Staged: true, Unstaged: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, patchPaths(patch.FilePatches()))
+
+	require.NoError(t, util.WriteFile(fs, "bar", []byte("x\n"), 0o644))
+	require.NoError(t, util.WriteFile(fs, "foo", []byte("a\nb\n"), 0o644))
+
+	patch, err = w.Diff(&WorktreeDiffOptions{Staged: true, Unstaged: true})
+	require.NoError(t, err)
+	assert.Empty(t, patch.FilePatches())
+}
+
+func patchPaths(filePatches []fdiff.FilePatch) []string {
+	var paths []string
+	for _, fp := range filePatches {
+		from, to := fp.Files()
+		if to != nil {
+			paths = append(paths, to.Path())
+		} else {
+			paths = append(paths, from.Path())
+		}
+	}
+
+	return paths
+}
@@ -0,0 +1,233 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrUnsupportedPathSpecMagic is returned when a pathspec uses a magic word
+// other than exclude, top and literal.
+var ErrUnsupportedPathSpecMagic = errors.New("unsupported pathspec magic")
+
+// pathSpecItem is a single parsed pathspec.
+type pathSpecItem struct {
+	pattern string
+	// glob matches the wildcards of pattern, nil if it has none or is
+	// literal.
+	glob *regexp.Regexp
+}
+
+func newPathSpecItem(pattern string, literal bool) pathSpecItem {
+	item := pathSpecItem{pattern: pattern}
+	if !literal && strings.ContainsAny(pattern, "*?[") {
+		item.glob = compilePathSpecGlob(pattern)
+	}
+
+	return item
+}
+
+// compilePathSpecGlob compiles the wildcards of pattern, which as in git
+// also match slashes, into a regexp matching the paths matched by pattern
+// and the paths under them.
+func compilePathSpecGlob(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteByte('^')
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteByte('.')
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			b.WriteByte('[')
+			b.WriteString(strings.ReplaceAll(class, `\`, `\\`))
+			b.WriteByte(']')
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("(/.*)?$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "(/.*)?$")
+	}
+
+	return re
+}
+
+// match returns whether name, relative to the root of the worktree, matches
+// the pathspec: either it is the path or a path under it, or it matches its
+// wildcards.
+func (p pathSpecItem) match(name string) bool {
+	if p.pattern == "" || name == p.pattern || strings.HasPrefix(name, p.pattern+"/") {
+		return true
+	}
+
+	return p.glob != nil && p.glob.MatchString(name)
+}
+
+// pathSpec is a set of pathspecs, which matches the paths matched by any of
+// its positive pathspecs, or all of them if it only has negative ones, and
+// not matched by any of its negative pathspecs.
+type pathSpec struct {
+	include []pathSpecItem
+	exclude []pathSpecItem
+}
+
+// parsePathSpecs parses specs, which may use the exclude (or its short forms
+// '!' and '^'), top (or '/') and literal magic words. It returns nil if specs
+// is empty.
+func parsePathSpecs(specs []string) (*pathSpec, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	ps := &pathSpec{}
+	for _, spec := range specs {
+		item, exclude, err := parsePathSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		if exclude {
+			ps.exclude = append(ps.exclude, item)
+		} else {
+			ps.include = append(ps.include, item)
+		}
+	}
+
+	return ps, nil
+}
+
+// parsePathSpec parses a pathspec, returning whether it is a negative one.
+func parsePathSpec(spec string) (item pathSpecItem, exclude bool, err error) {
+	if !strings.HasPrefix(spec, ":") {
+		return newPathSpecItem(cleanPathSpec(spec), false), false, nil
+	}
+
+	if strings.HasPrefix(spec, ":(") {
+		end := strings.IndexByte(spec, ')')
+		if end < 0 {
+			return item, false, fmt.Errorf("%w: missing ')' in %q", ErrUnsupportedPathSpecMagic, spec)
+		}
+
+		var literal bool
+		for _, magic := range strings.Split(spec[2:end], ",") {
+			switch strings.TrimSpace(magic) {
+			case "exclude":
+				exclude = true
+			case "literal":
+				literal = true
+			case "top", "":
+			default:
+				return item, false, fmt.Errorf("%w: %q", ErrUnsupportedPathSpecMagic, magic)
+			}
+		}
+
+		return newPathSpecItem(cleanPathSpec(spec[end+1:]), literal), exclude, nil
+	}
+
+	rest := spec[1:]
+loop:
+	for rest != "" {
+		switch rest[0] {
+		case '!', '^':
+			exclude = true
+		case '/':
+		case ':':
+			rest = rest[1:]
+			break loop
+		default:
+			break loop
+		}
+
+		rest = rest[1:]
+	}
+
+	return newPathSpecItem(cleanPathSpec(rest), false), exclude, nil
+}
+
+// cleanPathSpec returns the path of a pathspec relative to the root of the
+// worktree, "" meaning the whole worktree.
+func cleanPathSpec(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+}
+
+// Match returns whether name is matched by the pathspecs. A nil pathSpec
+// matches every path.
+func (ps *pathSpec) Match(name string) bool {
+	if ps == nil {
+		return true
+	}
+
+	for _, item := range ps.exclude {
+		if item.match(name) {
+			return false
+		}
+	}
+
+	if len(ps.include) == 0 {
+		return true
+	}
+
+	for _, item := range ps.include {
+		if item.match(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pathFilter restricts an operation to the paths listed in files, if any,
+// and matched by the pathspecs, if any. A nil pathFilter matches every path.
+type pathFilter struct {
+	files map[string]struct{}
+	spec  *pathSpec
+}
+
+// newPathFilter returns the pathFilter of files and pathspecs, or nil if both
+// are empty.
+func newPathFilter(files, pathspecs []string) (*pathFilter, error) {
+	spec, err := parsePathSpecs(pathspecs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 && spec == nil {
+		return nil, nil
+	}
+
+	return &pathFilter{files: buildFilePathMap(files), spec: spec}, nil
+}
+
+// Match returns whether the operation applies to name.
+func (f *pathFilter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.files != nil && !inFiles(f.files, name) {
+		return false
+	}
+
+	return f.spec.Match(name)
+}
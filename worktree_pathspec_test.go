@@ -0,0 +1,71 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathSpecMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		specs   []string
+		matches []string
+		misses  []string
+	}{
+		{
+			specs:   []string{"vendor"},
+			matches: []string{"vendor", "vendor/a.go", "vendor/b/c.go"},
+			misses:  []string{"vendorx", "a/vendor/b.go"},
+		},
+		{
+			specs:   []string{":(exclude)vendor"},
+			matches: []string{"a.go", "vendorx/a.go"},
+			misses:  []string{"vendor", "vendor/a.go"},
+		},
+		{
+			specs:   []string{"src", ":!src/gen", ":^*.pb.go"},
+			matches: []string{"src/a.go", "src/generate.go"},
+			misses:  []string{"a.go", "src/gen/a.go", "src/b/c.pb.go"},
+		},
+		{
+			specs:   []string{"*.go"},
+			matches: []string{"a.go", "b/c.go"},
+			misses:  []string{"a.c", "b/c.go.txt"},
+		},
+		{
+			specs:   []string{"doc/[a-c]?.md", ":(literal)*"},
+			matches: []string{"doc/ab.md", "doc/c1.md/x", "*", "*/a"},
+			misses:  []string{"doc/d1.md", "doc/abc.md", "a"},
+		},
+		{
+			specs:   []string{":/src/./", ":(top,exclude)src/b"},
+			matches: []string{"src/a"},
+			misses:  []string{"src/b", "a"},
+		},
+	}
+
+	for _, tc := range tests {
+		ps, err := parsePathSpecs(tc.specs)
+		require.NoError(t, err)
+
+		for _, name := range tc.matches {
+			assert.True(t, ps.Match(name), "%v should match %q", tc.specs, name)
+		}
+
+		for _, name := range tc.misses {
+			assert.False(t, ps.Match(name), "%v should not match %q", tc.specs, name)
+		}
+	}
+}
+
+func TestParsePathSpecsUnsupportedMagic(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{":(icase)a", ":(exclude"} {
+		_, err := parsePathSpecs([]string{spec})
+		assert.ErrorIs(t, err, ErrUnsupportedPathSpecMagic, spec)
+	}
+}
@@ -0,0 +1,376 @@
+package git
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/go-git/go-git/v6/plumbing"
+	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
+	"github.com/go-git/go-git/v6/plumbing/object"
+)
+
+const sparseCheckoutPath = "info/sparse-checkout"
+
+// sparseCheckoutMatcher returns a matcher for the sparse checkout patterns at
+// $GIT_DIR/info/sparse-checkout, matching the paths to be checked out. It
+// returns nil if core.sparseCheckout is not enabled, or the patterns are not
+// available.
+//
+// Cone mode patterns are a restricted form of the full patterns, so both are
+// interpreted the same way.
+func (w *Worktree) sparseCheckoutMatcher() (gitignore.Matcher, error) {
+	cfg, err := w.r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cfg.Core.SparseCheckout {
+		return nil, nil
+	}
+
+	type fsBased interface {
+		Filesystem() billy.Filesystem
+	}
+
+	s, ok := w.r.Storer.(fsBased)
+	if !ok {
+		return nil, nil
+	}
+
+	f, err := s.Filesystem().Open(sparseCheckoutPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	var ps []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		ps = append(ps, gitignore.ParsePattern(line, nil))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ps) == 0 {
+		return nil, nil
+	}
+
+	return gitignore.NewMatcher(ps), nil
+}
+
+// applySparseCheckout sets the SkipWorktree flag of the entries not matched by
+// the sparse checkout patterns, and clears it on the matched ones.
+func applySparseCheckout(idx *index.Index, m gitignore.Matcher) {
+	for _, e := range idx.Entries {
+		e.SkipWorktree = !m.Match(strings.Split(e.Name, "/"), false)
+	}
+}
+
+// SparseCheckoutChanges are the changes of the worktree made by a change of
+// its sparse checkout.
+type SparseCheckoutChanges struct {
+	// Added are the paths checked out, whose SkipWorktree flag is cleared.
+	Added []string
+	// Removed are the paths removed from the worktree, whose SkipWorktree
+	// flag is set.
+	Removed []string
+	// Kept are the paths excluded by the change but left in the worktree,
+	// having local changes, as git does. Their SkipWorktree flag is left
+	// cleared.
+	Kept []string
+	// Unmerged are the conflicted paths excluded by the change but left in
+	// the worktree and the index as they are, as git does.
+	Unmerged []string
+}
+
+// SparseCheckout changes the paths of the index checked out in the worktree,
+// setting the SkipWorktree flag of the others, and returns the paths added
+// to and removed from the worktree. Only the files whose flag changes are
+// written or removed, the others being left untouched. With DryRun, the
+// index and the worktree are left untouched, so that the effect of a change
+// can be previewed. The conflicted paths are left untouched.
+//
+// The directories and patterns are not persisted: the ones of
+// $GIT_DIR/info/sparse-checkout are applied again by the checkouts if
+// core.sparseCheckout is enabled.
+func (w *Worktree) SparseCheckout(opts *SparseCheckoutOptions) (*SparseCheckoutChanges, error) {
+	if opts == nil {
+		opts = &SparseCheckoutOptions{}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := w.fileConversion()
+	if err != nil {
+		return nil, err
+	}
+
+	skip := sparseCheckoutSkip(opts)
+	changes := &SparseCheckoutChanges{}
+	for _, e := range idx.Entries {
+		if e.Stage != index.Merged {
+			// The stages of a path are next to each other in the index.
+			n := len(changes.Unmerged)
+			if skip(e.Name) && (n == 0 || changes.Unmerged[n-1] != e.Name) {
+				changes.Unmerged = append(changes.Unmerged, e.Name)
+			}
+
+			continue
+		}
+
+		switch s := skip(e.Name); {
+		case e.SkipWorktree && !s:
+			changes.Added = append(changes.Added, e.Name)
+		case !e.SkipWorktree && s:
+			modified, err := w.sparseEntryModified(idx, conv, e)
+			if err != nil {
+				return nil, err
+			}
+
+			if modified {
+				changes.Kept = append(changes.Kept, e.Name)
+				continue
+			}
+
+			changes.Removed = append(changes.Removed, e.Name)
+		}
+	}
+
+	if opts.DryRun || (len(changes.Added) == 0 && len(changes.Removed) == 0) {
+		return changes, nil
+	}
+
+	b := newIndexBuilder(idx)
+	for _, name := range changes.Added {
+		if err := w.checkoutSparseEntry(conv, b, name); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range changes.Removed {
+		b.entries[name].SkipWorktree = true
+		if _, err := w.Filesystem.Lstat(name); os.IsNotExist(err) {
+			continue
+		}
+
+		if err := rmFileAndDirsIfEmpty(w.Filesystem, name); err != nil {
+			return nil, err
+		}
+	}
+
+	b.Write(idx)
+	return changes, w.r.Storer.SetIndex(idx)
+}
+
+// sparseCheckoutSkip returns whether a path is excluded from the worktree by
+// the directories or the patterns of opts.
+func sparseCheckoutSkip(opts *SparseCheckoutOptions) func(name string) bool {
+	switch {
+	case len(opts.Dirs) > 0:
+		return func(name string) bool {
+			for _, dir := range opts.Dirs {
+				if strings.HasPrefix(name, dir) {
+					return false
+				}
+			}
+
+			return true
+		}
+	case len(opts.Patterns) > 0:
+		ps := make([]gitignore.Pattern, 0, len(opts.Patterns))
+		for _, p := range opts.Patterns {
+			if strings.HasPrefix(p, "#") || strings.TrimSpace(p) == "" {
+				continue
+			}
+
+			ps = append(ps, gitignore.ParsePattern(p, nil))
+		}
+
+		m := gitignore.NewMatcher(ps)
+		return func(name string) bool {
+			return !m.Match(strings.Split(name, "/"), false)
+		}
+	default:
+		return func(string) bool { return false }
+	}
+}
+
+// checkoutSparseEntry writes the entry name of the index to the worktree.
+// Submodules are only flagged as checked out, as git does.
+func (w *Worktree) checkoutSparseEntry(conv *fileConversion, b *indexBuilder, name string) error {
+	e := b.entries[name]
+	e.SkipWorktree = false
+	if e.Mode == filemode.Submodule {
+		return nil
+	}
+
+	blob, err := object.GetBlob(w.r.Storer, e.Hash)
+	if err != nil {
+		return err
+	}
+
+	if err := w.checkoutFile(conv, object.NewFile(name, e.Mode, blob)); err != nil {
+		return err
+	}
+
+	return w.addIndexFromFile(name, e.Hash, e.Mode, b)
+}
+
+// sparseEntryModified returns whether the file of the entry has changes in
+// the worktree, which removing it would lose. The files whose size and
+// modification time match the entry are assumed unchanged.
+func (w *Worktree) sparseEntryModified(idx *index.Index, conv *fileConversion, e *index.Entry) (modified bool, err error) {
+	if e.Mode == filemode.Submodule {
+		return false, nil
+	}
+
+	fi, err := w.Filesystem.Lstat(e.Name)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if fi.Size() == int64(e.Size) && fi.ModTime().Equal(e.ModifiedAt) {
+		return false, nil
+	}
+
+	symlinkFile, err := conv.isSymlinkFile(idx, e.Name, fi.Mode())
+	if err != nil {
+		return false, err
+	}
+
+	obj := w.r.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	writer, err := obj.Writer()
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		err = w.fillEncodedObjectFromSymlink(writer, e.Name, fi)
+	case symlinkFile:
+		err = w.fillEncodedObjectFromSymlinkFile(writer, e.Name)
+	default:
+		err = w.fillEncodedObjectFromFile(conv, writer, e.Name, fi)
+	}
+
+	if cerr := writer.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return obj.Hash() != e.Hash, nil
+}
+
+// checkoutSparseDirs changes the directories of the commit of HEAD checked
+// out to dirs, as SparseCheckout does, writing and removing only the files
+// whose SkipWorktree flag changes instead of resetting the whole worktree.
+// As the merge reset of a checkout does, the staged changes and the
+// conflicts are reset first. It returns false, leaving the worktree
+// untouched, if the index is empty, as after a clone without checkout.
+func (w *Worktree) checkoutSparseDirs(opts *ResetOptions) (bool, error) {
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return false, err
+	}
+
+	if len(idx.Entries) == 0 {
+		return false, nil
+	}
+
+	t, err := w.r.getTreeFromCommitHash(opts.Commit)
+	if err != nil {
+		return false, err
+	}
+
+	if !treeContainsDirs(t, opts.SparseDirs) {
+		return false, ErrSparseResetDirectoryNotFound
+	}
+
+	ok, err := indexHoldsTree(idx, t)
+	if err != nil {
+		return false, err
+	}
+
+	if !ok {
+		if err := w.reset(&ResetOptions{Commit: opts.Commit, Mode: MergeReset, Prefetch: opts.Prefetch}); err != nil {
+			return false, err
+		}
+	}
+
+	_, err = w.SparseCheckout(&SparseCheckoutOptions{Dirs: opts.SparseDirs})
+	return err == nil, err
+}
+
+// indexHoldsTree returns whether the index holds exactly the files of the
+// tree t, without conflicts.
+func indexHoldsTree(idx *index.Index, t *object.Tree) (bool, error) {
+	entries := make(map[string]*index.Entry, len(idx.Entries))
+	for _, e := range idx.Entries {
+		if e.Stage != index.Merged {
+			return false, nil
+		}
+
+		entries[e.Name] = e
+	}
+
+	walker := object.NewTreeWalker(t, true, nil)
+	defer walker.Close()
+
+	var n int
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if entry.Mode == filemode.Dir {
+			continue
+		}
+
+		e, ok := entries[name]
+		if !ok || e.Hash != entry.Hash || e.Mode != entry.Mode {
+			return false, nil
+		}
+
+		n++
+	}
+
+	return n == len(entries), nil
+}
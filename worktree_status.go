@@ -3,17 +3,22 @@ package git
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/go-git/go-billy/v6"
 	"github.com/go-git/go-billy/v6/util"
 
+	"github.com/go-git/go-git/v6/config"
 	"github.com/go-git/go-git/v6/plumbing"
 	"github.com/go-git/go-git/v6/plumbing/filemode"
+	"github.com/go-git/go-git/v6/plumbing/format/gitattributes"
 	"github.com/go-git/go-git/v6/plumbing/format/gitignore"
 	"github.com/go-git/go-git/v6/plumbing/format/index"
 	"github.com/go-git/go-git/v6/plumbing/object"
@@ -37,8 +42,28 @@ var (
 	// ErrUnsupportedStatusStrategy occurs when an invalid StatusStrategy is used
 	// when processing the Worktree status.
 	ErrUnsupportedStatusStrategy = errors.New("unsupported status strategy")
+	// ErrIgnored in an Add operation means that an explicitly given path is
+	// ignored by a gitignore pattern, and AddOptions.Force was not set.
+	ErrIgnored = errors.New("paths are ignored by one of the .gitignore files")
 )
 
+// IgnoredPathsError is returned by the Add operations when explicitly given
+// paths are untracked and ignored by a gitignore pattern, as git add refuses
+// to add them unless forced. The other paths are added nonetheless.
+type IgnoredPathsError struct {
+	// Paths are the ignored paths, relative to the root of the worktree.
+	Paths []string
+}
+
+func (e *IgnoredPathsError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrIgnored, strings.Join(e.Paths, ", "))
+}
+
+// Unwrap returns ErrIgnored.
+func (e *IgnoredPathsError) Unwrap() error {
+	return ErrIgnored
+}
+
 // Status returns the working tree status.
 func (w *Worktree) Status() (Status, error) {
 	return w.StatusWithOptions(StatusOptions{Strategy: defaultStatusStrategy})
@@ -47,6 +72,16 @@ func (w *Worktree) Status() (Status, error) {
 // StatusOptions defines the options for Worktree.StatusWithOptions().
 type StatusOptions struct {
 	Strategy StatusStrategy
+	// Concurrency is the number of goroutines used to read and hash the
+	// worktree files. It speeds up the status of worktrees with many files
+	// that need to be hashed, such as those missing from the index. Values
+	// lower than two compute the status sequentially.
+	Concurrency int
+	// SampleSize, if positive, is the number of bytes read at the start of
+	// the files to detect whether they are binary when their line endings
+	// are converted, instead of reading them in full, such as
+	// convert.DefaultSampleSize as git does.
+	SampleSize int
 }
 
 // StatusWithOptions returns the working tree status.
@@ -62,16 +97,44 @@ func (w *Worktree) StatusWithOptions(o StatusOptions) (Status, error) {
 		hash = ref.Hash()
 	}
 
-	return w.status(o.Strategy, hash)
+	return w.status(o, hash)
 }
 
-func (w *Worktree) status(ss StatusStrategy, commit plumbing.Hash) (Status, error) {
-	s, err := ss.new(w)
+// StatusWithState returns the working tree status along with the operation in
+// progress in the repository, such as a merge not concluded yet, as git status
+// reports them.
+func (w *Worktree) StatusWithState(o StatusOptions) (Status, RepositoryState, error) {
+	state, err := w.r.State()
+	if err != nil {
+		return nil, NoOperation, err
+	}
+
+	s, err := w.StatusWithOptions(o)
+	if err != nil {
+		return nil, NoOperation, err
+	}
+
+	return s, state, nil
+}
+
+func (w *Worktree) status(o StatusOptions, commit plumbing.Hash) (Status, error) {
+	s, err := o.Strategy.new(w)
+	if err != nil {
+		return nil, err
+	}
+
+	// The index is read once, for both diffs.
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := w.commitTree(commit)
 	if err != nil {
 		return nil, err
 	}
 
-	left, err := w.diffCommitWithStaging(commit, false)
+	left, err := w.diffTreeWithIndex(t, idx, false)
 	if err != nil {
 		return nil, err
 	}
@@ -95,18 +158,24 @@ func (w *Worktree) status(ss StatusStrategy, commit plumbing.Hash) (Status, erro
 		}
 	}
 
-	right, err := w.diffStagingWithWorktree(false, true)
+	right, err := w.diffIndexWithWorktree(idx, false, true, o)
 	if err != nil {
 		return nil, err
 	}
 
+	assumed := assumeUnchangedPaths(idx)
 	for _, ch := range right {
 		a, err := ch.Action()
 		if err != nil {
 			return nil, err
 		}
 
-		fs := s.File(nameFromAction(&ch))
+		name := nameFromAction(&ch)
+		if _, ok := assumed[name]; ok {
+			continue
+		}
+
+		fs := s.File(name)
 		if fs.Staging == Untracked {
 			fs.Staging = Unmodified
 		}
@@ -122,6 +191,11 @@ func (w *Worktree) status(ss StatusStrategy, commit plumbing.Hash) (Status, erro
 		}
 	}
 
+	for _, c := range idx.Conflicts() {
+		fs := s.File(c.Name)
+		fs.Staging, fs.Worktree = conflictStatus(c)
+	}
+
 	return s, nil
 }
 
@@ -135,11 +209,19 @@ func nameFromAction(ch *merkletrie.Change) string {
 }
 
 func (w *Worktree) diffStagingWithWorktree(reverse, excludeIgnoredChanges bool) (merkletrie.Changes, error) {
+	return w.diffStagingWithWorktreeWithOptions(reverse, excludeIgnoredChanges, StatusOptions{})
+}
+
+func (w *Worktree) diffStagingWithWorktreeWithOptions(reverse, excludeIgnoredChanges bool, o StatusOptions) (merkletrie.Changes, error) {
 	idx, err := w.r.Storer.Index()
 	if err != nil {
 		return nil, err
 	}
 
+	return w.diffIndexWithWorktree(idx, reverse, excludeIgnoredChanges, o)
+}
+
+func (w *Worktree) diffIndexWithWorktree(idx *index.Index, reverse, excludeIgnoredChanges bool, o StatusOptions) (merkletrie.Changes, error) {
 	cfg, err := w.r.Config()
 	if err != nil {
 		return nil, err
@@ -153,9 +235,19 @@ func (w *Worktree) diffStagingWithWorktree(reverse, excludeIgnoredChanges bool)
 		return nil, err
 	}
 
+	attributes, err := gitattributes.ReadPatterns(w.Filesystem, nil)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
 	fsOpts := filesystem.Options{
-		AutoCRLF: cfg.Core.AutoCRLF == "true" || cfg.Core.AutoCRLF == "input",
-		Index:    idx,
+		AutoCRLF:        cfg.Core.AutoCRLF == "true" || cfg.Core.AutoCRLF == "input",
+		Attributes:      attributes,
+		Index:           idx,
+		SymlinksAsFiles: cfg.Core.Symlinks == config.OptBoolFalse,
+		Concurrency:     o.Concurrency,
+		IgnoreCase:      cfg.Core.IgnoreCase,
+		SampleSize:      o.SampleSize,
 	}
 
 	to := filesystem.NewRootNodeWithOptions(w.Filesystem, submodules, fsOpts)
@@ -241,34 +333,49 @@ func (w *Worktree) getSubmodulesStatus() (map[string]plumbing.Hash, error) {
 }
 
 func (w *Worktree) diffCommitWithStaging(commit plumbing.Hash, reverse bool) (merkletrie.Changes, error) {
-	var t *object.Tree
-	if !commit.IsZero() {
-		c, err := w.r.CommitObject(commit)
-		if err != nil {
-			return nil, err
-		}
-
-		t, err = c.Tree()
-		if err != nil {
-			return nil, err
-		}
+	t, err := w.commitTree(commit)
+	if err != nil {
+		return nil, err
 	}
 
 	return w.diffTreeWithStaging(t, reverse)
 }
 
-func (w *Worktree) diffTreeWithStaging(t *object.Tree, reverse bool) (merkletrie.Changes, error) {
-	var from noder.Noder
-	if t != nil {
-		from = object.NewTreeRootNode(t)
+// commitTree returns the tree of the commit, nil if the hash is zero.
+func (w *Worktree) commitTree(commit plumbing.Hash) (*object.Tree, error) {
+	if commit.IsZero() {
+		return nil, nil
+	}
+
+	c, err := w.r.CommitObject(commit)
+	if err != nil {
+		return nil, err
 	}
 
+	return c.Tree()
+}
+
+func (w *Worktree) diffTreeWithStaging(t *object.Tree, reverse bool) (merkletrie.Changes, error) {
 	idx, err := w.r.Storer.Index()
 	if err != nil {
 		return nil, err
 	}
 
-	to := mindex.NewRootNode(idx)
+	return w.diffTreeWithIndex(t, idx, reverse)
+}
+
+func (w *Worktree) diffTreeWithIndex(t *object.Tree, idx *index.Index, reverse bool) (merkletrie.Changes, error) {
+	var from noder.Noder
+	if t != nil {
+		from = object.NewTreeRootNode(t)
+	}
+
+	// Entries excluded from the worktree by a sparse checkout are still part
+	// of the index, so they must be compared against the tree.
+	to := mindex.NewRootNodeWithOptions(idx, mindex.RootNodeOptions{
+		UpholdExecutableBit: true,
+		IgnoreSkipWorktree:  true,
+	})
 
 	if reverse {
 		return merkletrie.DiffTree(to, from, diffTreeIsEquals)
@@ -310,17 +417,18 @@ func diffTreeIsEquals(a, b noder.Hasher) bool {
 }
 
 // Add adds the file contents of a file in the worktree to the index. if the
-// file is already staged in the index no error is returned. If a file deleted
-// from the Workspace is given, the file is removed from the index. If a
-// directory given, adds the files and all his sub-directories recursively in
-// the worktree to the index. If any of the files is already staged in the index
-// no error is returned. When path is a file, the blob.Hash is returned.
+// file is already staged in the index no error is returned. If a file or a
+// directory deleted from the Workspace is given, it is removed from the index.
+// If a directory given, adds the files and all his sub-directories recursively
+// in the worktree to the index. If any of the files is already staged in the
+// index no error is returned. When path is a file, the blob.Hash is returned.
+// If path is untracked and ignored, an *IgnoredPathsError is returned.
 func (w *Worktree) Add(path string) (plumbing.Hash, error) {
 	// TODO(mcuadros): deprecate in favor of AddWithOption in v6.
-	return w.doAdd(path, make([]gitignore.Pattern, 0), false)
+	return w.doAdd(path, make([]gitignore.Pattern, 0), &AddOptions{})
 }
 
-func (w *Worktree) doAddDirectory(idx *index.Index, s Status, directory string, ignorePattern []gitignore.Pattern) (added bool, err error) {
+func (w *Worktree) doAddDirectory(idx *index.Index, conv *fileConversion, s Status, directory string, ignorePattern []gitignore.Pattern) (added bool, err error) {
 	if len(ignorePattern) > 0 {
 		m := gitignore.NewMatcher(ignorePattern)
 		matchPath := strings.Split(directory, string(os.PathSeparator))
@@ -338,7 +446,7 @@ func (w *Worktree) doAddDirectory(idx *index.Index, s Status, directory string,
 		}
 
 		var a bool
-		a, _, err = w.doAddFile(idx, s, name, ignorePattern)
+		a, _, err = w.doAddFile(idx, conv, s, name, ignorePattern)
 		if err != nil {
 			return added, err
 		}
@@ -349,6 +457,40 @@ func (w *Worktree) doAddDirectory(idx *index.Index, s Status, directory string,
 	return added, err
 }
 
+// doAddIgnoredFiles adds the files of directory missing from both the index
+// and the status s, which are the untracked files ignored by a gitignore
+// pattern, as a forced add does.
+func (w *Worktree) doAddIgnoredFiles(idx *index.Index, conv *fileConversion, s Status, directory string) (added bool, err error) {
+	err = util.Walk(w.Filesystem, directory, func(name string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() {
+			if fi.Name() == GitDirName {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		slashed := filepath.ToSlash(name)
+		if _, ok := s[slashed]; ok {
+			return nil
+		}
+
+		if _, err := idx.Entry(slashed); err == nil {
+			return nil
+		}
+
+		a, _, err := w.doAddFile(idx, conv, nil, name, nil)
+		added = added || a
+		return err
+	})
+
+	return added, err
+}
+
 func isPathInDirectory(path, directory string) bool {
 	return directory == "." || strings.HasPrefix(path, directory+"/")
 }
@@ -367,19 +509,19 @@ func (w *Worktree) AddWithOptions(opts *AddOptions) error {
 	}
 
 	if opts.All {
-		_, err := w.doAdd(".", w.Excludes, false)
+		_, err := w.doAdd(".", w.Excludes, &AddOptions{})
 		return err
 	}
 
 	if opts.Glob != "" {
-		return w.AddGlob(opts.Glob)
+		return w.doAddGlob(opts.Glob, opts)
 	}
 
-	_, err := w.doAdd(opts.Path, make([]gitignore.Pattern, 0), opts.SkipStatus)
+	_, err := w.doAdd(opts.Path, make([]gitignore.Pattern, 0), opts)
 	return err
 }
 
-func (w *Worktree) doAdd(path string, ignorePattern []gitignore.Pattern, skipStatus bool) (plumbing.Hash, error) {
+func (w *Worktree) doAdd(path string, ignorePattern []gitignore.Pattern, opts *AddOptions) (plumbing.Hash, error) {
 	if trace.Performance.Enabled() {
 		start := time.Now()
 		defer func() {
@@ -392,6 +534,11 @@ func (w *Worktree) doAdd(path string, ignorePattern []gitignore.Pattern, skipSta
 		return plumbing.ZeroHash, err
 	}
 
+	conv, err := w.fileConversion()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
 	var h plumbing.Hash
 	var added bool
 
@@ -400,7 +547,7 @@ func (w *Worktree) doAdd(path string, ignorePattern []gitignore.Pattern, skipSta
 	// status is required for doAddDirectory
 	var s Status
 	var err2 error
-	if !skipStatus || fi == nil || fi.IsDir() {
+	if !opts.SkipStatus || fi == nil || fi.IsDir() {
 		s, err2 = w.Status()
 		if err2 != nil {
 			return plumbing.ZeroHash, err2
@@ -409,10 +556,37 @@ func (w *Worktree) doAdd(path string, ignorePattern []gitignore.Pattern, skipSta
 
 	path = filepath.Clean(path)
 
-	if err != nil || !fi.IsDir() {
-		added, h, err = w.doAddFile(idx, s, path, ignorePattern)
-	} else {
-		added, err = w.doAddDirectory(idx, s, path, ignorePattern)
+	if !opts.Force {
+		m, merr := w.ignoreMatcher(path)
+		if merr != nil {
+			return plumbing.ZeroHash, merr
+		}
+
+		if isIgnoredPath(m, idx, path, err == nil && fi.IsDir()) {
+			return plumbing.ZeroHash, &IgnoredPathsError{Paths: []string{filepath.ToSlash(path)}}
+		}
+	}
+
+	switch {
+	case err == nil && fi.IsDir():
+		added, err = w.doAddDirectory(idx, conv, s, path, ignorePattern)
+		if err == nil && opts.Force {
+			var a bool
+			a, err = w.doAddIgnoredFiles(idx, conv, s, path)
+			added = added || a
+		}
+	case err != nil && s != nil && isIndexDirectory(idx, path):
+		// The directory was deleted from the worktree, stage the deletion of
+		// all the files it contained.
+		added, err = w.doAddDirectory(idx, conv, s, path, ignorePattern)
+	default:
+		if isAssumeUnchanged(idx, filepath.ToSlash(path)) {
+			// Status reports the files assumed unchanged as unmodified, yet
+			// they are added when explicitly targeted.
+			s = nil
+		}
+
+		added, h, err = w.doAddFile(idx, conv, s, path, ignorePattern)
 	}
 
 	if err != nil {
@@ -426,10 +600,34 @@ func (w *Worktree) doAdd(path string, ignorePattern []gitignore.Pattern, skipSta
 	return h, w.r.Storer.SetIndex(idx)
 }
 
+// isIndexDirectory returns true if path is not an entry of the index
+// but a directory containing some.
+func isIndexDirectory(idx *index.Index, path string) bool {
+	dir := filepath.ToSlash(path)
+	if _, err := idx.Entry(dir); err == nil {
+		return false
+	}
+
+	for _, e := range idx.Entries {
+		if isPathInDirectory(e.Name, dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // AddGlob adds all paths, matching pattern, to the index. If pattern matches a
-// directory path, all directory contents are added to the index recursively. No
-// error is returned if all matching paths are already staged in index.
+// directory path, all directory contents are added to the index recursively.
+// The files matching pattern in the index but deleted from the worktree are
+// removed from the index. No error is returned if all matching paths are
+// already staged in index. The untracked paths matching pattern and ignored
+// are not added, and returned in an *IgnoredPathsError.
 func (w *Worktree) AddGlob(pattern string) error {
+	return w.doAddGlob(pattern, &AddOptions{})
+}
+
+func (w *Worktree) doAddGlob(pattern string, opts *AddOptions) error {
 	if trace.Performance.Enabled() {
 		start := time.Now()
 		defer func() {
@@ -443,7 +641,17 @@ func (w *Worktree) AddGlob(pattern string) error {
 		return err
 	}
 
-	if len(files) == 0 {
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	deleted, err := w.deletedIndexEntries(idx, pattern)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 && len(deleted) == 0 {
 		return ErrGlobNoMatches
 	}
 
@@ -452,23 +660,56 @@ func (w *Worktree) AddGlob(pattern string) error {
 		return err
 	}
 
-	idx, err := w.r.Storer.Index()
+	conv, err := w.fileConversion()
 	if err != nil {
 		return err
 	}
 
+	// The matchers of the directories of the files, built once.
+	var matchers map[string]gitignore.Matcher
+	if !opts.Force {
+		matchers = make(map[string]gitignore.Matcher)
+	}
+
+	var ignored []string
 	var saveIndex bool
+	for _, name := range deleted {
+		added, _, err := w.doAddFile(idx, conv, s, name, make([]gitignore.Pattern, 0))
+		if err != nil {
+			return err
+		}
+
+		saveIndex = saveIndex || added
+	}
+
 	for _, file := range files {
 		fi, err := w.Filesystem.Lstat(file)
 		if err != nil {
 			return err
 		}
 
+		if matchers != nil {
+			dir := filepath.Dir(file)
+			m, ok := matchers[dir]
+			if !ok {
+				if m, err = w.ignoreMatcher(file); err != nil {
+					return err
+				}
+
+				matchers[dir] = m
+			}
+
+			if isIgnoredPath(m, idx, file, fi.IsDir()) {
+				ignored = append(ignored, filepath.ToSlash(file))
+				continue
+			}
+		}
+
 		var added bool
 		if fi.IsDir() {
-			added, err = w.doAddDirectory(idx, s, file, make([]gitignore.Pattern, 0))
+			added, err = w.doAddDirectory(idx, conv, s, file, make([]gitignore.Pattern, 0))
 		} else {
-			added, _, err = w.doAddFile(idx, s, file, make([]gitignore.Pattern, 0))
+			added, _, err = w.doAddFile(idx, conv, s, file, make([]gitignore.Pattern, 0))
 		}
 
 		if err != nil {
@@ -481,16 +722,83 @@ func (w *Worktree) AddGlob(pattern string) error {
 	}
 
 	if saveIndex {
-		return w.r.Storer.SetIndex(idx)
+		if err := w.r.Storer.SetIndex(idx); err != nil {
+			return err
+		}
+	}
+
+	if len(ignored) > 0 {
+		return &IgnoredPathsError{Paths: ignored}
 	}
 
 	return nil
 }
 
+// ignoreMatcher returns the matcher of the gitignore patterns applying to
+// path, read from the gitignore files of the directories along it only, and
+// of Excludes.
+func (w *Worktree) ignoreMatcher(path string) (gitignore.Matcher, error) {
+	var dirs []string
+	if dir := filepath.ToSlash(filepath.Dir(path)); dir != "." {
+		dirs = strings.Split(dir, "/")
+	}
+
+	patterns, err := gitignore.ReadPathPatterns(w.Filesystem, dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	return gitignore.NewMatcher(append(patterns, w.Excludes...)), nil
+}
+
+// isIgnoredPath returns whether path is ignored by m and not tracked in idx,
+// as the tracked paths are never ignored.
+func isIgnoredPath(m gitignore.Matcher, idx *index.Index, path string, isDir bool) bool {
+	name := filepath.ToSlash(path)
+	if name == "." {
+		return false
+	}
+
+	if _, err := idx.Entry(name); err == nil {
+		return false
+	}
+
+	if isDir && isIndexDirectory(idx, path) {
+		return false
+	}
+
+	return m.Match(strings.Split(name, "/"), isDir)
+}
+
+// deletedIndexEntries returns the paths of the index entries matching pattern
+// which do not exist in the worktree.
+func (w *Worktree) deletedIndexEntries(idx *index.Index, pattern string) ([]string, error) {
+	entries, err := idx.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, e := range entries {
+		name := filepath.FromSlash(e.Name)
+		_, err := w.Filesystem.Lstat(name)
+		if os.IsNotExist(err) {
+			deleted = append(deleted, name)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return deleted, nil
+}
+
 // doAddFile create a new blob from path and update the index, added is true if
 // the file added is different from the index.
 // if s status is nil will skip the status check and update the index anyway
-func (w *Worktree) doAddFile(idx *index.Index, s Status, path string, ignorePattern []gitignore.Pattern) (added bool, h plumbing.Hash, err error) {
+func (w *Worktree) doAddFile(idx *index.Index, conv *fileConversion, s Status, path string, ignorePattern []gitignore.Pattern) (added bool, h plumbing.Hash, err error) {
 	if s != nil && s.File(path).Worktree == Unmodified {
 		return false, h, nil
 	}
@@ -503,7 +811,7 @@ func (w *Worktree) doAddFile(idx *index.Index, s Status, path string, ignorePatt
 		}
 	}
 
-	h, err = w.copyFileToStorage(path)
+	h, err = w.copyFileToStorage(idx, conv, path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			added = true
@@ -513,19 +821,24 @@ func (w *Worktree) doAddFile(idx *index.Index, s Status, path string, ignorePatt
 		return added, h, err
 	}
 
-	if err := w.addOrUpdateFileToIndex(idx, path, h); err != nil {
+	if err := w.addOrUpdateFileToIndex(idx, conv, path, h); err != nil {
 		return false, h, err
 	}
 
 	return true, h, err
 }
 
-func (w *Worktree) copyFileToStorage(path string) (hash plumbing.Hash, err error) {
+func (w *Worktree) copyFileToStorage(idx *index.Index, conv *fileConversion, path string) (hash plumbing.Hash, err error) {
 	fi, err := w.Filesystem.Lstat(path)
 	if err != nil {
 		return plumbing.ZeroHash, err
 	}
 
+	symlinkFile, err := conv.isSymlinkFile(idx, path, fi.Mode())
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
 	obj := w.r.Storer.NewEncodedObject()
 	obj.SetType(plumbing.BlobObject)
 	obj.SetSize(fi.Size())
@@ -537,10 +850,13 @@ func (w *Worktree) copyFileToStorage(path string) (hash plumbing.Hash, err error
 
 	defer ioutil.CheckClose(writer, &err)
 
-	if fi.Mode()&os.ModeSymlink != 0 {
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
 		err = w.fillEncodedObjectFromSymlink(writer, path, fi)
-	} else {
-		err = w.fillEncodedObjectFromFile(writer, path, fi)
+	case symlinkFile:
+		err = w.fillEncodedObjectFromSymlinkFile(writer, path)
+	default:
+		err = w.fillEncodedObjectFromFile(conv, writer, path, fi)
 	}
 
 	if err != nil {
@@ -550,20 +866,20 @@ func (w *Worktree) copyFileToStorage(path string) (hash plumbing.Hash, err error
 	return w.r.Storer.SetEncodedObject(obj)
 }
 
-func (w *Worktree) fillEncodedObjectFromFile(dst io.Writer, path string, _ os.FileInfo) (err error) {
+func (w *Worktree) fillEncodedObjectFromFile(conv *fileConversion, dst io.Writer, path string, _ os.FileInfo) (err error) {
 	file, err := w.Filesystem.Open(path)
 	if err != nil {
 		return err
 	}
 	defer ioutil.CheckClose(file, &err)
 
-	cfg, err := w.r.Config()
+	text, err := conv.text(path)
 	if err != nil {
 		return err
 	}
 
-	switch cfg.Core.AutoCRLF {
-	case "true", "input":
+	autoCRLF := conv.cfg.Core.AutoCRLF == "true" || conv.cfg.Core.AutoCRLF == "input"
+	if text != gitattributes.TextUnset && (autoCRLF || text != gitattributes.TextUnspecified) {
 		br := sync.GetBufioReader(file)
 		defer sync.PutBufioReader(br)
 
@@ -576,7 +892,7 @@ func (w *Worktree) fillEncodedObjectFromFile(dst io.Writer, path string, _ os.Fi
 			return err
 		}
 
-		if !stat.IsBinary() {
+		if text.Normalize(autoCRLF, stat.IsBinary()) {
 			dst = convert.NewLFWriter(dst)
 		}
 	}
@@ -585,6 +901,70 @@ func (w *Worktree) fillEncodedObjectFromFile(dst io.Writer, path string, _ os.Fi
 	return err
 }
 
+// fileConversion holds what the conversion of the files between the
+// worktree and the objects depends on, the config and the gitattributes, so
+// that they are read once per operation.
+type fileConversion struct {
+	fs  billy.Filesystem
+	cfg *config.Config
+	// attributes are the patterns of the .gitattributes files applying to
+	// the files of each directory, read when first needed.
+	attributes map[string][]gitattributes.MatchAttribute
+}
+
+func (w *Worktree) fileConversion() (*fileConversion, error) {
+	cfg, err := w.r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileConversion{
+		fs:         w.Filesystem,
+		cfg:        cfg,
+		attributes: make(map[string][]gitattributes.MatchAttribute),
+	}, nil
+}
+
+// text returns the text attribute of the file at the given path, as set by
+// the .gitattributes files of the directories containing it.
+func (c *fileConversion) text(path string) (gitattributes.Text, error) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	stack, err := c.dirAttributes(parts[: len(parts)-1 : len(parts)-1])
+	if err != nil {
+		return gitattributes.TextUnspecified, err
+	}
+
+	return gitattributes.MatchText(stack, parts), nil
+}
+
+// dirAttributes returns the patterns applying to the files of the directory
+// dir, read from the .gitattributes files of the directories along it.
+func (c *fileConversion) dirAttributes(dir []string) ([]gitattributes.MatchAttribute, error) {
+	key := strings.Join(dir, "/")
+	if stack, ok := c.attributes[key]; ok {
+		return stack, nil
+	}
+
+	var stack []gitattributes.MatchAttribute
+	if len(dir) > 0 {
+		parent, err := c.dirAttributes(dir[: len(dir)-1 : len(dir)-1])
+		if err != nil {
+			return nil, err
+		}
+
+		stack = parent[:len(parent):len(parent)]
+	}
+
+	attrs, err := gitattributes.ReadAttributesFile(c.fs, dir, gitattributesFile, len(dir) == 0)
+	if err != nil {
+		return nil, err
+	}
+
+	stack = append(stack, attrs...)
+	c.attributes[key] = stack
+	return stack, nil
+}
+
 func (w *Worktree) fillEncodedObjectFromSymlink(dst io.Writer, path string, _ os.FileInfo) error {
 	target, err := w.Filesystem.Readlink(path)
 	if err != nil {
@@ -595,36 +975,92 @@ func (w *Worktree) fillEncodedObjectFromSymlink(dst io.Writer, path string, _ os
 	return err
 }
 
-func (w *Worktree) addOrUpdateFileToIndex(idx *index.Index, filename string, h plumbing.Hash) error {
+// fillEncodedObjectFromSymlinkFile copies the target of a symbolic link
+// checked out as a plain file, without any conversion.
+func (w *Worktree) fillEncodedObjectFromSymlinkFile(dst io.Writer, path string) (err error) {
+	file, err := w.Filesystem.Open(path)
+	if err != nil {
+		return err
+	}
+	defer ioutil.CheckClose(file, &err)
+
+	_, err = ioutil.CopyBufferPool(dst, file)
+	return err
+}
+
+// isSymlinkFile returns whether the file at path, with the given mode, is a
+// symbolic link tracked in the index and checked out as a plain file, as
+// done when core.symlinks is false.
+func (c *fileConversion) isSymlinkFile(idx *index.Index, path string, mode os.FileMode) (bool, error) {
+	if !mode.IsRegular() {
+		return false, nil
+	}
+
+	e, err := idx.Entry(path)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		return false, nil
+	}
+
+	if err != nil || e.Mode != filemode.Symlink {
+		return false, err
+	}
+
+	return c.symlinksAsFiles(), nil
+}
+
+// symlinksAsFiles returns whether the symbolic links are checked out as plain
+// files, as done when core.symlinks is false.
+func (c *fileConversion) symlinksAsFiles() bool {
+	return c.cfg.Core.Symlinks == config.OptBoolFalse
+}
+
+func (w *Worktree) addOrUpdateFileToIndex(idx *index.Index, conv *fileConversion, filename string, h plumbing.Hash) error {
+	resolveConflict(idx, filename)
+
 	e, err := idx.Entry(filename)
 	if err != nil && !errors.Is(err, index.ErrEntryNotFound) {
 		return err
 	}
 
 	if errors.Is(err, index.ErrEntryNotFound) {
-		return w.doAddFileToIndex(idx, filename, h)
+		return w.doAddFileToIndex(idx, conv, filename, h)
+	}
+
+	hash, mode := e.Hash, e.Mode
+	if err := w.doUpdateFileToIndex(e, conv, filename, h); err != nil {
+		return err
+	}
+
+	if e.Hash != hash || e.Mode != mode {
+		idx.InvalidateCache(e.Name)
 	}
 
-	return w.doUpdateFileToIndex(e, filename, h)
+	return nil
 }
 
-func (w *Worktree) doAddFileToIndex(idx *index.Index, filename string, h plumbing.Hash) error {
-	return w.doUpdateFileToIndex(idx.Add(filename), filename, h)
+func (w *Worktree) doAddFileToIndex(idx *index.Index, conv *fileConversion, filename string, h plumbing.Hash) error {
+	return w.doUpdateFileToIndex(idx.Add(filename), conv, filename, h)
 }
 
-func (w *Worktree) doUpdateFileToIndex(e *index.Entry, filename string, h plumbing.Hash) error {
+func (w *Worktree) doUpdateFileToIndex(e *index.Entry, conv *fileConversion, filename string, h plumbing.Hash) error {
 	info, err := w.Filesystem.Lstat(filename)
 	if err != nil {
 		return err
 	}
 
-	e.Hash = h
-	e.ModifiedAt = info.ModTime()
-	e.Mode, err = filemode.NewFromOSFileMode(info.Mode())
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
 	if err != nil {
 		return err
 	}
 
+	if e.Mode == filemode.Symlink && mode == filemode.Regular && conv.symlinksAsFiles() {
+		mode = filemode.Symlink
+	}
+
+	e.Hash = h
+	e.ModifiedAt = info.ModTime()
+	e.Mode = mode
+
 	// The entry size must always reflect the current state, otherwise
 	// it will cause go-git's Worktree.Status() to divert from "git status".
 	// The size of a symlink is the length of the path to the target.
@@ -635,9 +1071,72 @@ func (w *Worktree) doUpdateFileToIndex(e *index.Entry, filename string, h plumbi
 	return nil
 }
 
+// AssumeUnchanged sets or clears the assume-unchanged bit of the index entry
+// of the file at path, as git update-index --[no-]assume-unchanged does. The
+// files assumed unchanged are not read by Status, which reports them as
+// unmodified, but are still added when explicitly given to Add.
+func (w *Worktree) AssumeUnchanged(path string, assume bool) error {
+	idx, err := w.r.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	e, err := idx.Entry(filepath.ToSlash(filepath.Clean(path)))
+	if err != nil {
+		return err
+	}
+
+	e.AssumeUnchanged = assume
+	return w.r.Storer.SetIndex(idx)
+}
+
+// isAssumeUnchanged returns whether the file at path is assumed unchanged.
+func isAssumeUnchanged(idx *index.Index, path string) bool {
+	e, err := idx.Entry(path)
+	return err == nil && e.AssumeUnchanged
+}
+
+// assumeUnchangedPaths returns the paths of the files assumed unchanged, nil
+// if there are none.
+func assumeUnchangedPaths(idx *index.Index) map[string]struct{} {
+	var paths map[string]struct{}
+	for _, e := range idx.Entries {
+		if !e.AssumeUnchanged {
+			continue
+		}
+
+		if paths == nil {
+			paths = map[string]struct{}{}
+		}
+
+		paths[e.Name] = struct{}{}
+	}
+
+	return paths
+}
+
 // Remove removes files from the working tree and from the index.
 func (w *Worktree) Remove(path string) (plumbing.Hash, error) {
 	// TODO(mcuadros): remove plumbing.Hash from signature at v5.
+	return w.doRemove(path, false)
+}
+
+// RemoveWithOptions removes files from the index, and unless opts.Cached is
+// set, from the working tree.
+func (w *Worktree) RemoveWithOptions(opts *RemoveOptions) error {
+	if err := opts.Validate(w.r); err != nil {
+		return err
+	}
+
+	if opts.Glob != "" {
+		return w.doRemoveGlob(opts.Glob, opts.Cached)
+	}
+
+	_, err := w.doRemove(opts.Path, opts.Cached)
+	return err
+}
+
+func (w *Worktree) doRemove(path string, cached bool) (plumbing.Hash, error) {
 	idx, err := w.r.Storer.Index()
 	if err != nil {
 		return plumbing.ZeroHash, err
@@ -646,9 +1145,15 @@ func (w *Worktree) Remove(path string) (plumbing.Hash, error) {
 	var h plumbing.Hash
 
 	fi, err := w.Filesystem.Lstat(path)
-	if err != nil || !fi.IsDir() {
+	switch {
+	case cached:
+		h, err = w.deleteFromIndex(idx, path)
+		if errors.Is(err, index.ErrEntryNotFound) && isIndexDirectory(idx, path) {
+			h, err = plumbing.ZeroHash, deleteDirectoryFromIndex(idx, path)
+		}
+	case err != nil || !fi.IsDir():
 		h, err = w.doRemoveFile(idx, path)
-	} else {
+	default:
 		_, err = w.doRemoveDirectory(idx, path)
 	}
 	if err != nil {
@@ -658,6 +1163,23 @@ func (w *Worktree) Remove(path string) (plumbing.Hash, error) {
 	return h, w.r.Storer.SetIndex(idx)
 }
 
+// deleteDirectoryFromIndex removes all the entries under directory from the
+// index.
+func deleteDirectoryFromIndex(idx *index.Index, directory string) error {
+	directory = filepath.ToSlash(filepath.Clean(directory))
+	for _, e := range slices.Clone(idx.Entries) {
+		if !isPathInDirectory(e.Name, directory) {
+			continue
+		}
+
+		if _, err := idx.Remove(e.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (w *Worktree) doRemoveDirectory(idx *index.Index, directory string) (removed bool, err error) {
 	files, err := w.Filesystem.ReadDir(directory)
 	if err != nil {
@@ -718,6 +1240,7 @@ func (w *Worktree) deleteFromIndex(idx *index.Index, path string) (plumbing.Hash
 		return plumbing.ZeroHash, err
 	}
 
+	resolveConflict(idx, path)
 	return e.Hash, nil
 }
 
@@ -734,6 +1257,10 @@ func (w *Worktree) deleteFromFilesystem(path string) error {
 // matches a directory path, all directory contents are removed from the index
 // recursively.
 func (w *Worktree) RemoveGlob(pattern string) error {
+	return w.doRemoveGlob(pattern, false)
+}
+
+func (w *Worktree) doRemoveGlob(pattern string, cached bool) error {
 	idx, err := w.r.Storer.Index()
 	if err != nil {
 		return err
@@ -746,6 +1273,14 @@ func (w *Worktree) RemoveGlob(pattern string) error {
 
 	for _, e := range entries {
 		file := filepath.FromSlash(e.Name)
+		if cached {
+			if _, err := w.deleteFromIndex(idx, file); err != nil {
+				return err
+			}
+
+			continue
+		}
+
 		if _, err := w.Filesystem.Lstat(file); err != nil && !os.IsNotExist(err) {
 			return err
 		}
@@ -780,6 +1315,11 @@ func (w *Worktree) Move(from, to string) (plumbing.Hash, error) {
 		return plumbing.ZeroHash, err
 	}
 
+	conv, err := w.fileConversion()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
 	hash, err := w.deleteFromIndex(idx, from)
 	if err != nil {
 		return plumbing.ZeroHash, err
@@ -789,7 +1329,7 @@ func (w *Worktree) Move(from, to string) (plumbing.Hash, error) {
 		return hash, err
 	}
 
-	if err := w.addOrUpdateFileToIndex(idx, to, hash); err != nil {
+	if err := w.addOrUpdateFileToIndex(idx, conv, to, hash); err != nil {
 		return hash, err
 	}
 
@@ -8,11 +8,13 @@ import (
 
 	"github.com/go-git/go-billy/v6/memfs"
 	"github.com/go-git/go-billy/v6/osfs"
+	"github.com/go-git/go-billy/v6/util"
 	fixtures "github.com/go-git/go-git-fixtures/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/go-git/go-git/v6/plumbing/cache"
+	"github.com/go-git/go-git/v6/plumbing/format/index"
 	"github.com/go-git/go-git/v6/storage/filesystem"
 )
 
@@ -92,6 +94,83 @@ func TestIndexEntrySizeUpdatedForNonRegularFiles(t *testing.T) {
 	assert.Equal(t, uint32(len(content)+2), idx.Entries[0].Size)
 }
 
+func TestStatusWithConcurrency(t *testing.T) {
+	t.Parallel()
+
+	f := fixtures.Basic().One()
+	st := filesystem.NewStorage(f.DotGit(), cache.NewObjectLRUDefault())
+
+	fs := memfs.New()
+	r, err := Open(st, fs)
+	require.NoError(t, err)
+
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, wt.Reset(&ResetOptions{Mode: HardReset}))
+
+	require.NoError(t, util.WriteFile(fs, "go/example.go", []byte("package main\n"), 0o644))
+	require.NoError(t, util.WriteFile(fs, "new/untracked", []byte("foo"), 0o644))
+	require.NoError(t, fs.Remove("CHANGELOG"))
+
+	expected, err := wt.StatusWithOptions(StatusOptions{Strategy: Preload})
+	require.NoError(t, err)
+
+	status, err := wt.StatusWithOptions(StatusOptions{Strategy: Preload, Concurrency: 4})
+	require.NoError(t, err)
+	assert.Equal(t, expected, status)
+	assert.Equal(t, Modified, status.File("go/example.go").Worktree)
+	assert.Equal(t, Untracked, status.File("new/untracked").Worktree)
+	assert.Equal(t, Deleted, status.File("CHANGELOG").Worktree)
+}
+
+func TestStatusAssumeUnchanged(t *testing.T) {
+	t.Parallel()
+
+	f := fixtures.Basic().One()
+	st := filesystem.NewStorage(f.DotGit(), cache.NewObjectLRUDefault())
+
+	fs := memfs.New()
+	r, err := Open(st, fs)
+	require.NoError(t, err)
+
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, wt.Reset(&ResetOptions{Mode: HardReset}))
+
+	require.NoError(t, wt.AssumeUnchanged("go/example.go", true))
+	require.NoError(t, wt.AssumeUnchanged("CHANGELOG", true))
+	assert.ErrorIs(t, wt.AssumeUnchanged("missing", true), index.ErrEntryNotFound)
+
+	require.NoError(t, util.WriteFile(fs, "go/example.go", []byte("package main\n"), 0o644))
+	require.NoError(t, fs.Remove("CHANGELOG"))
+
+	status, err := wt.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean())
+
+	require.NoError(t, wt.AddWithOptions(&AddOptions{All: true}))
+	status, err = wt.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean())
+
+	_, err = wt.Add("go/example.go")
+	require.NoError(t, err)
+	status, err = wt.Status()
+	require.NoError(t, err)
+	assert.Equal(t, Modified, status.File("go/example.go").Staging)
+
+	idx, err := r.Storer.Index()
+	require.NoError(t, err)
+	e, err := idx.Entry("go/example.go")
+	require.NoError(t, err)
+	assert.True(t, e.AssumeUnchanged)
+
+	require.NoError(t, wt.AssumeUnchanged("CHANGELOG", false))
+	status, err = wt.Status()
+	require.NoError(t, err)
+	assert.Equal(t, Deleted, status.File("CHANGELOG").Worktree)
+}
+
 func BenchmarkWorktreeStatus(b *testing.B) {
 	b.StopTimer()
 
@@ -34,6 +34,7 @@ import (
 	"github.com/go-git/go-git/v6/plumbing/object"
 	"github.com/go-git/go-git/v6/storage/filesystem"
 	"github.com/go-git/go-git/v6/storage/memory"
+	"github.com/go-git/go-git/v6/utils/merge"
 )
 
 func defaultTestCommitOptions() *CommitOptions {
@@ -94,12 +95,162 @@ func (s *WorktreeSuite) TestPullFastForward() {
 	w, err = r.Worktree()
 	s.NoError(err)
 
+	old, err := r.Head()
+	s.Require().NoError(err)
+
 	err = w.Pull(&PullOptions{})
 	s.NoError(err)
 
 	head, err := r.Head()
 	s.NoError(err)
 	s.Equal(hash, head.Hash())
+
+	origHead, err := r.OrigHead()
+	s.NoError(err)
+	s.Equal(old.Hash(), origHead.Hash())
+}
+
+func (s *WorktreeSuite) TestPullAutostash() {
+	url := s.GetLocalRepositoryURL(fixtures.Basic().ByTag("worktree").One())
+
+	server, err := PlainClone(s.T().TempDir(), &CloneOptions{URL: url})
+	s.Require().NoError(err)
+
+	r, err := Clone(memory.NewStorage(), memfs.New(), &CloneOptions{URL: server.wt.Root()})
+	s.Require().NoError(err)
+
+	w, err := server.Worktree()
+	s.Require().NoError(err)
+	s.NoError(util.WriteFile(w.Filesystem, "foo", []byte("foo"), 0o644))
+	_, err = w.Add("foo")
+	s.NoError(err)
+	hash, err := w.Commit("foo", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	w, err = r.Worktree()
+	s.Require().NoError(err)
+	s.NoError(util.WriteFile(w.Filesystem, "CHANGELOG", []byte("changed"), 0o644))
+	s.NoError(w.Filesystem.Remove("LICENSE"))
+	s.NoError(util.WriteFile(w.Filesystem, "bar", []byte("bar"), 0o644))
+	_, err = w.Add("bar")
+	s.NoError(err)
+
+	err = w.Pull(&PullOptions{Autostash: true})
+	s.Require().NoError(err)
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	s.Equal(hash, head.Hash())
+
+	status, err := w.Status()
+	s.Require().NoError(err)
+	s.Equal(Modified, status.File("CHANGELOG").Worktree)
+	s.Equal(Deleted, status.File("LICENSE").Worktree)
+	s.Equal(Added, status.File("bar").Staging)
+	s.NotContains(status, "foo")
+
+	b, err := util.ReadFile(w.Filesystem, "CHANGELOG")
+	s.NoError(err)
+	s.Equal("changed", string(b))
+
+	b, err = util.ReadFile(w.Filesystem, "foo")
+	s.NoError(err)
+	s.Equal("foo", string(b))
+
+	_, err = r.Reference(stashRefName, false)
+	s.ErrorIs(err, plumbing.ErrReferenceNotFound)
+}
+
+func (s *WorktreeSuite) TestPullAutostashConflict() {
+	url := s.GetLocalRepositoryURL(fixtures.Basic().ByTag("worktree").One())
+
+	server, err := PlainClone(s.T().TempDir(), &CloneOptions{URL: url})
+	s.Require().NoError(err)
+
+	r, err := Clone(memory.NewStorage(), memfs.New(), &CloneOptions{URL: server.wt.Root()})
+	s.Require().NoError(err)
+
+	w, err := server.Worktree()
+	s.Require().NoError(err)
+	s.NoError(util.WriteFile(w.Filesystem, "CHANGELOG", []byte("upstream"), 0o644))
+	_, err = w.Add("CHANGELOG")
+	s.NoError(err)
+	hash, err := w.Commit("foo", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	w, err = r.Worktree()
+	s.Require().NoError(err)
+	oldHead, err := r.Head()
+	s.Require().NoError(err)
+	s.NoError(util.WriteFile(w.Filesystem, "CHANGELOG", []byte("local"), 0o644))
+
+	err = w.Pull(&PullOptions{Autostash: true})
+	s.ErrorIs(err, ErrAutostashConflict)
+	s.ErrorContains(err, "CHANGELOG")
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	s.Equal(hash, head.Hash())
+
+	b, err := util.ReadFile(w.Filesystem, "CHANGELOG")
+	s.NoError(err)
+	s.Equal("upstream", string(b))
+
+	ref, err := r.Reference(stashRefName, false)
+	s.Require().NoError(err)
+	stash, err := r.CommitObject(ref.Hash())
+	s.Require().NoError(err)
+	s.Len(stash.ParentHashes, 2)
+	s.Equal(oldHead.Hash(), stash.ParentHashes[0])
+
+	f, err := stash.File("CHANGELOG")
+	s.Require().NoError(err)
+	content, err := f.Contents()
+	s.NoError(err)
+	s.Equal("local", content)
+}
+
+func (s *WorktreeSuite) TestPullAutostashMerge() {
+	url := s.GetLocalRepositoryURL(fixtures.Basic().ByTag("worktree").One())
+
+	server, err := PlainClone(s.T().TempDir(), &CloneOptions{URL: url})
+	s.Require().NoError(err)
+
+	w, err := server.Worktree()
+	s.Require().NoError(err)
+	s.NoError(util.WriteFile(w.Filesystem, "foo", []byte("a\nb\nc\n"), 0o644))
+	_, err = w.Add("foo")
+	s.NoError(err)
+	_, err = w.Commit("foo", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	r, err := Clone(memory.NewStorage(), memfs.New(), &CloneOptions{URL: server.wt.Root()})
+	s.Require().NoError(err)
+
+	s.NoError(util.WriteFile(w.Filesystem, "foo", []byte("A\nb\nc\n"), 0o644))
+	_, err = w.Add("foo")
+	s.NoError(err)
+	_, err = w.Commit("foo", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	// The local change of another line of the file changed upstream is
+	// merged with it.
+	w, err = r.Worktree()
+	s.Require().NoError(err)
+	s.NoError(util.WriteFile(w.Filesystem, "foo", []byte("a\nb\nC\n"), 0o644))
+
+	s.Require().NoError(w.Pull(&PullOptions{Autostash: true}))
+
+	b, err := util.ReadFile(w.Filesystem, "foo")
+	s.NoError(err)
+	s.Equal("A\nb\nC\n", string(b))
+
+	status, err := w.Status()
+	s.Require().NoError(err)
+	s.Equal(Modified, status.File("foo").Worktree)
+
+	_, err = r.Reference(stashRefName, false)
+	s.ErrorIs(err, plumbing.ErrReferenceNotFound)
 }
 
 func (s *WorktreeSuite) TestPullNonFastForward() {
@@ -129,6 +280,219 @@ func (s *WorktreeSuite) TestPullNonFastForward() {
 	s.ErrorIs(err, ErrNonFastForwardUpdate)
 }
 
+// divergedPullRepository returns a clone, with a user in its config, and
+// the commits the clone and its origin made on top of the common history,
+// writing the given contents to the given files.
+func (s *WorktreeSuite) divergedPullRepository(remoteFile, remoteContent, localFile, localContent string) (*Repository, plumbing.Hash, plumbing.Hash) {
+	url := s.GetLocalRepositoryURL(fixtures.Basic().ByTag("worktree").One())
+
+	server, err := PlainClone(s.T().TempDir(), &CloneOptions{URL: url})
+	s.Require().NoError(err)
+
+	r, err := Clone(memory.NewStorage(), memfs.New(), &CloneOptions{URL: server.wt.Root()})
+	s.Require().NoError(err)
+
+	cfg, err := r.Config()
+	s.Require().NoError(err)
+	cfg.User.Name = "foo"
+	cfg.User.Email = "foo@foo.foo"
+	s.Require().NoError(r.SetConfig(cfg))
+
+	w, err := server.Worktree()
+	s.Require().NoError(err)
+	s.NoError(util.WriteFile(w.Filesystem, remoteFile, []byte(remoteContent), 0o644))
+	_, err = w.Add(remoteFile)
+	s.NoError(err)
+	remote, err := w.Commit("remote", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	w, err = r.Worktree()
+	s.Require().NoError(err)
+	s.NoError(util.WriteFile(w.Filesystem, localFile, []byte(localContent), 0o644))
+	_, err = w.Add(localFile)
+	s.NoError(err)
+	local, err := w.Commit("local", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	return r, remote, local
+}
+
+func (s *WorktreeSuite) TestPullMerge() {
+	r, remote, local := s.divergedPullRepository("foo", "foo", "bar", "bar")
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+	err = w.Pull(&PullOptions{Strategy: PullMerge})
+	s.Require().NoError(err)
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	commit, err := r.CommitObject(head.Hash())
+	s.Require().NoError(err)
+	s.Equal([]plumbing.Hash{local, remote}, commit.ParentHashes)
+	s.Equal("foo", commit.Author.Name)
+	s.Contains(commit.Message, "Merge branch 'master' of ")
+
+	for _, name := range []string{"foo", "bar"} {
+		_, err = commit.File(name)
+		s.NoError(err)
+	}
+
+	status, err := w.Status()
+	s.Require().NoError(err)
+	s.True(status.IsClean())
+}
+
+func (s *WorktreeSuite) TestPullRebase() {
+	r, remote, local := s.divergedPullRepository("foo", "foo", "bar", "bar")
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+	err = w.Pull(&PullOptions{Strategy: PullRebase})
+	s.Require().NoError(err)
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	s.NotEqual(local, head.Hash())
+
+	origHead, err := r.OrigHead()
+	s.Require().NoError(err)
+	s.Equal(local, origHead.Hash())
+
+	commit, err := r.CommitObject(head.Hash())
+	s.Require().NoError(err)
+	s.Equal([]plumbing.Hash{remote}, commit.ParentHashes)
+	s.Equal("local", commit.Message)
+	s.Equal(defaultSignature().Name, commit.Author.Name)
+	s.Equal("foo", commit.Committer.Name)
+
+	b, err := util.ReadFile(w.Filesystem, "foo")
+	s.NoError(err)
+	s.Equal("foo", string(b))
+}
+
+func (s *WorktreeSuite) TestPullRebaseFromConfig() {
+	r, remote, _ := s.divergedPullRepository("foo", "foo", "bar", "bar")
+
+	cfg, err := r.Config()
+	s.Require().NoError(err)
+	cfg.Pull.Rebase = "true"
+	s.Require().NoError(r.SetConfig(cfg))
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+	err = w.Pull(&PullOptions{})
+	s.Require().NoError(err)
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	commit, err := r.CommitObject(head.Hash())
+	s.Require().NoError(err)
+	s.Equal([]plumbing.Hash{remote}, commit.ParentHashes)
+
+	err = w.Pull(&PullOptions{Strategy: PullFastForwardOnly})
+	s.ErrorIs(err, NoErrAlreadyUpToDate)
+}
+
+func (s *WorktreeSuite) TestPullMergeConflict() {
+	for _, strategy := range []PullStrategy{PullMerge, PullRebase} {
+		r, _, local := s.divergedPullRepository("CHANGELOG", "remote", "CHANGELOG", "local")
+
+		w, err := r.Worktree()
+		s.Require().NoError(err)
+		err = w.Pull(&PullOptions{Strategy: strategy})
+		s.ErrorIs(err, ErrMergeConflict)
+
+		var conflict *MergeConflictError
+		s.Require().ErrorAs(err, &conflict)
+		s.Equal([]string{"CHANGELOG"}, conflict.Paths)
+		if strategy == PullRebase {
+			s.Equal(local, conflict.Commit)
+		}
+
+		head, err := r.Head()
+		s.Require().NoError(err)
+		s.Equal(local, head.Hash())
+
+		b, err := util.ReadFile(w.Filesystem, "CHANGELOG")
+		s.NoError(err)
+		s.Equal("local", string(b))
+	}
+}
+
+func (s *WorktreeSuite) TestPullMergeUnknownConflictStyle() {
+	r, _, _ := s.divergedPullRepository("CHANGELOG", "remote", "CHANGELOG", "local")
+
+	cfg, err := r.Config()
+	s.Require().NoError(err)
+	cfg.Merge.ConflictStyle = "foo"
+	s.Require().NoError(r.SetConfig(cfg))
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+	err = w.Pull(&PullOptions{Strategy: PullMerge})
+	s.ErrorIs(err, merge.ErrUnknownConflictStyle)
+}
+
+func (s *WorktreeSuite) TestPullMergeDriver() {
+	merge.RegisterDriver("test-concat", func(base, ours, theirs []byte, path string) ([]byte, bool) {
+		if path != "CHANGELOG" || base == nil {
+			return nil, false
+		}
+
+		return append(append(ours, '+'), theirs...), true
+	})
+	defer merge.UnregisterDriver("test-concat")
+
+	r, remote, local := s.divergedPullRepository("CHANGELOG", "remote", "CHANGELOG", "local")
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+	s.Require().NoError(util.WriteFile(w.Filesystem, ".gitattributes", []byte("CHANGELOG merge=test-concat\n"), 0o644))
+
+	err = w.Pull(&PullOptions{Strategy: PullMerge})
+	s.Require().NoError(err)
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	commit, err := r.CommitObject(head.Hash())
+	s.Require().NoError(err)
+	s.Equal([]plumbing.Hash{local, remote}, commit.ParentHashes)
+
+	b, err := util.ReadFile(w.Filesystem, "CHANGELOG")
+	s.NoError(err)
+	s.Equal("local+remote", string(b))
+}
+
+func (s *WorktreeSuite) TestPullMergeBuiltinDrivers() {
+	for _, tc := range []struct {
+		driver   string
+		conflict bool
+		expected string
+	}{
+		{merge.TextDriver, true, "local"},
+		{merge.BinaryDriver, true, "local"},
+		{merge.UnionDriver, false, "local\nremote"},
+	} {
+		r, _, _ := s.divergedPullRepository("CHANGELOG", "remote", "CHANGELOG", "local")
+
+		w, err := r.Worktree()
+		s.Require().NoError(err)
+		s.Require().NoError(util.WriteFile(w.Filesystem, ".gitattributes", []byte("CHANGELOG merge="+tc.driver+"\n"), 0o644))
+
+		err = w.Pull(&PullOptions{Strategy: PullMerge})
+		if tc.conflict {
+			s.ErrorIs(err, ErrMergeConflict, tc.driver)
+		} else {
+			s.Require().NoError(err, tc.driver)
+		}
+
+		b, err := util.ReadFile(w.Filesystem, "CHANGELOG")
+		s.NoError(err)
+		s.Equal(tc.expected, string(b), tc.driver)
+	}
+}
+
 func (s *WorktreeSuite) TestPullUpdateReferencesIfNeeded() {
 	r, _ := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
 	r.CreateRemote(&config.RemoteConfig{
@@ -433,6 +797,26 @@ func (s *WorktreeSuite) TestCheckout() {
 	s.Len(idx.Entries, 9)
 }
 
+func (s *WorktreeSuite) TestCheckoutPrefetch() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	s.Require().NoError(w.Checkout(&CheckoutOptions{Force: true, Prefetch: true}))
+
+	content, err := util.ReadFile(fs, "CHANGELOG")
+	s.NoError(err)
+	s.Equal("Initial changelog\n", string(content))
+
+	s.Require().NoError(w.Checkout(&CheckoutOptions{Branch: "refs/heads/branch", Prefetch: true}))
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.True(status.IsClean(), status.String())
+}
+
 func (s *WorktreeSuite) TestCheckoutForce() {
 	w := &Worktree{
 		r:          s.Repository,
@@ -526,6 +910,78 @@ func (s *WorktreeSuite) TestCheckoutSymlink() {
 	s.NoError(err)
 }
 
+func (s *WorktreeSuite) TestCheckoutSymlinkAsFile() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("git doesn't support symlinks by default in windows")
+	}
+
+	dir := s.T().TempDir()
+
+	r, err := PlainInit(dir, false)
+	s.NoError(err)
+
+	w, err := r.Worktree()
+	s.NoError(err)
+
+	s.NoError(w.Filesystem.Symlink("not-exists", "bar"))
+	_, err = w.Add("bar")
+	s.NoError(err)
+	_, err = w.Commit("foo", &CommitOptions{Author: defaultSignature()})
+	s.NoError(err)
+
+	cfg, err := r.Config()
+	s.NoError(err)
+	cfg.Core.Symlinks = config.OptBoolFalse
+	s.NoError(r.SetConfig(cfg))
+
+	r.Storer.SetIndex(&index.Index{Version: 2})
+	w.Filesystem = osfs.New(filepath.Join(dir, "worktree-empty"))
+
+	s.NoError(w.Checkout(&CheckoutOptions{}))
+
+	fi, err := w.Filesystem.Lstat("bar")
+	s.NoError(err)
+	s.True(fi.Mode().IsRegular())
+
+	content, err := util.ReadFile(w.Filesystem, "bar")
+	s.NoError(err)
+	s.Equal("not-exists", string(content))
+
+	idx, err := r.Storer.Index()
+	s.NoError(err)
+	e, err := idx.Entry("bar")
+	s.NoError(err)
+	s.Equal(filemode.Symlink, e.Mode)
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.True(status.IsClean())
+
+	s.NoError(util.WriteFile(w.Filesystem, "bar", []byte("other"), 0o644))
+
+	status, err = w.Status()
+	s.NoError(err)
+	s.Equal(Modified, status.File("bar").Worktree)
+
+	_, err = w.Add("bar")
+	s.NoError(err)
+
+	idx, err = r.Storer.Index()
+	s.NoError(err)
+	e, err = idx.Entry("bar")
+	s.NoError(err)
+	s.Equal(filemode.Symlink, e.Mode)
+
+	obj, err := r.Storer.EncodedObject(plumbing.BlobObject, e.Hash)
+	s.NoError(err)
+	s.Equal(int64(len("other")), obj.Size())
+
+	status, err = w.Status()
+	s.NoError(err)
+	s.Equal(Modified, status.File("bar").Staging)
+	s.Equal(Unmodified, status.File("bar").Worktree)
+}
+
 func (s *WorktreeSuite) TestCheckoutSparse() {
 	fs := memfs.New()
 	r, err := Clone(memory.NewStorage(), fs, &CloneOptions{
@@ -558,6 +1014,204 @@ func (s *WorktreeSuite) TestCheckoutSparse() {
 	}
 }
 
+func (s *WorktreeSuite) TestCheckoutSparseChange() {
+	fs := memfs.New()
+	r, err := Clone(memory.NewStorage(), fs, &CloneOptions{
+		URL:        s.GetBasicLocalRepositoryURL(),
+		NoCheckout: true,
+	})
+	s.Require().NoError(err)
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+
+	s.Require().NoError(w.Checkout(&CheckoutOptions{
+		SparseCheckoutDirectories: []string{"go"},
+	}))
+
+	// The local changes of the files staying checked out are kept.
+	s.Require().NoError(util.WriteFile(fs, "go/example.go", []byte("modified"), 0o644))
+
+	s.Require().NoError(w.Checkout(&CheckoutOptions{
+		SparseCheckoutDirectories: []string{"go", "php"},
+	}))
+
+	_, err = fs.Lstat("php/crappy.php")
+	s.NoError(err)
+	content, err := util.ReadFile(fs, "go/example.go")
+	s.Require().NoError(err)
+	s.Equal("modified", string(content))
+
+	status, err := w.Status()
+	s.Require().NoError(err)
+	s.Len(status, 1)
+	s.Equal(Modified, status.File("go/example.go").Worktree)
+
+	// The files with local changes are left in the worktree.
+	s.Require().NoError(w.Checkout(&CheckoutOptions{
+		SparseCheckoutDirectories: []string{"php"},
+	}))
+
+	_, err = fs.Lstat("go/example.go")
+	s.NoError(err)
+
+	idx, err := r.Storer.Index()
+	s.Require().NoError(err)
+	e, err := idx.Entry("go/example.go")
+	s.Require().NoError(err)
+	s.False(e.SkipWorktree)
+
+	err = w.Checkout(&CheckoutOptions{
+		SparseCheckoutDirectories: []string{"missing"},
+	})
+	s.ErrorIs(err, ErrSparseResetDirectoryNotFound)
+}
+
+func (s *WorktreeSuite) TestCheckoutSparseChangeResetsIndex() {
+	fs := memfs.New()
+	r, err := Clone(memory.NewStorage(), fs, &CloneOptions{
+		URL:        s.GetBasicLocalRepositoryURL(),
+		NoCheckout: true,
+	})
+	s.Require().NoError(err)
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+
+	s.Require().NoError(w.Checkout(&CheckoutOptions{
+		SparseCheckoutDirectories: []string{"go"},
+	}))
+
+	head, err := r.Head()
+	s.Require().NoError(err)
+	c, err := r.CommitObject(head.Hash())
+	s.Require().NoError(err)
+	f, err := c.File("go/example.go")
+	s.Require().NoError(err)
+
+	// The staged changes are reset, as by a full checkout.
+	s.Require().NoError(util.WriteFile(fs, "go/example.go", []byte("modified"), 0o644))
+	_, err = w.Add("go/example.go")
+	s.Require().NoError(err)
+
+	idx, err := r.Storer.Index()
+	s.Require().NoError(err)
+	e, err := idx.Entry("go/example.go")
+	s.Require().NoError(err)
+	s.Require().NotEqual(f.Hash, e.Hash)
+
+	s.Require().NoError(w.Checkout(&CheckoutOptions{
+		SparseCheckoutDirectories: []string{"go", "php"},
+	}))
+
+	idx, err = r.Storer.Index()
+	s.Require().NoError(err)
+	e, err = idx.Entry("go/example.go")
+	s.Require().NoError(err)
+	s.Equal(f.Hash, e.Hash)
+
+	_, err = fs.Lstat("php/crappy.php")
+	s.NoError(err)
+
+	// The conflicts must be resolved first.
+	conflict := *e
+	conflict.Stage = index.TheirMode
+	idx.Entries = append(idx.Entries, &conflict)
+	s.Require().NoError(r.Storer.SetIndex(idx))
+
+	err = w.Checkout(&CheckoutOptions{
+		SparseCheckoutDirectories: []string{"go"},
+	})
+	s.ErrorIs(err, ErrUnmergedPaths)
+}
+
+func (s *WorktreeSuite) TestSparseCheckout() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	s.Require().NoError(w.Checkout(&CheckoutOptions{}))
+
+	_, err := w.SparseCheckout(&SparseCheckoutOptions{Dirs: []string{"go"}, Patterns: []string{"/go/"}})
+	s.ErrorIs(err, ErrSparseCheckoutDirsAndPatterns)
+
+	changes, err := w.SparseCheckout(&SparseCheckoutOptions{Dirs: []string{"go"}, DryRun: true})
+	s.Require().NoError(err)
+	s.Empty(changes.Added)
+	s.Len(changes.Removed, 8)
+	s.NotContains(changes.Removed, "go/example.go")
+	s.Contains(changes.Removed, "json/short.json")
+
+	_, err = fs.Lstat("json/short.json")
+	s.NoError(err)
+
+	changes, err = w.SparseCheckout(&SparseCheckoutOptions{Dirs: []string{"go"}})
+	s.Require().NoError(err)
+	s.Len(changes.Removed, 8)
+
+	_, err = fs.Lstat("json/short.json")
+	s.True(os.IsNotExist(err))
+
+	changes, err = w.SparseCheckout(&SparseCheckoutOptions{Patterns: []string{"/json/"}, DryRun: true})
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{"json/long.json", "json/short.json"}, changes.Added)
+	s.Equal([]string{"go/example.go"}, changes.Removed)
+
+	changes, err = w.SparseCheckout(nil)
+	s.Require().NoError(err)
+	s.Len(changes.Added, 8)
+	s.Empty(changes.Removed)
+
+	content, err := util.ReadFile(fs, "json/short.json")
+	s.NoError(err)
+	s.NotEmpty(content)
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.True(status.IsClean())
+}
+
+func (s *WorktreeSuite) TestSparseCheckoutUnmerged() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	s.Require().NoError(w.Checkout(&CheckoutOptions{}))
+
+	idx, err := s.Repository.Storer.Index()
+	s.Require().NoError(err)
+	e, err := idx.Entry("json/short.json")
+	s.Require().NoError(err)
+	e.Stage = index.OurMode
+	theirs := *e
+	theirs.Stage = index.TheirMode
+	idx.Entries = append(idx.Entries, &theirs)
+	s.Require().NoError(s.Repository.Storer.SetIndex(idx))
+	s.Require().NoError(fs.Remove("json/short.json"))
+
+	changes, err := w.SparseCheckout(&SparseCheckoutOptions{Dirs: []string{"go"}})
+	s.Require().NoError(err)
+	s.Equal([]string{"json/short.json"}, changes.Unmerged)
+	s.NotContains(changes.Removed, "json/short.json")
+	s.Contains(changes.Removed, "json/long.json")
+
+	idx, err = s.Repository.Storer.Index()
+	s.Require().NoError(err)
+	var stages []index.Stage
+	for _, e := range idx.Entries {
+		if e.Name == "json/short.json" {
+			s.False(e.SkipWorktree)
+			stages = append(stages, e.Stage)
+		}
+	}
+
+	s.ElementsMatch([]index.Stage{index.OurMode, index.TheirMode}, stages)
+}
+
 func (s *WorktreeSuite) TestCheckoutCRLF() {
 	runTest := func(t *testing.T, autoCRLF string) (result []byte) {
 		r := NewRepositoryWithEmptyWorktree(fixtures.Basic().One())
@@ -590,10 +1244,44 @@ func (s *WorktreeSuite) TestCheckoutCRLF() {
 		s.Equal("Initial changelog\r\n", string(result))
 	})
 
-	s.Run("autocrlf=input", func() {
-		result := runTest(s.T(), "input")
-		s.Equal("Initial changelog\n", string(result))
-	})
+	s.Run("autocrlf=input", func() {
+		result := runTest(s.T(), "input")
+		s.Equal("Initial changelog\n", string(result))
+	})
+}
+
+func (s *WorktreeSuite) TestCheckoutLargeObject() {
+	fs := memfs.New()
+	dot, err := fs.Chroot(GitDirName)
+	s.Require().NoError(err)
+
+	st := filesystem.NewStorageWithOptions(dot, cache.NewObjectLRUDefault(), filesystem.Options{
+		LargeObjectThreshold: 1,
+	})
+	r, err := Init(st, WithWorkTree(fs))
+	s.Require().NoError(err)
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+
+	content := strings.Repeat("foo\n", 1<<14)
+	s.Require().NoError(util.WriteFile(fs, "large", []byte(content), 0o644))
+	_, err = w.Add("large")
+	s.Require().NoError(err)
+	_, err = w.Commit("large\n", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	cfg, err := r.Config()
+	s.Require().NoError(err)
+	cfg.Core.AutoCRLF = "true"
+	s.Require().NoError(r.SetConfig(cfg))
+
+	s.Require().NoError(fs.Remove("large"))
+	s.Require().NoError(w.Checkout(&CheckoutOptions{Force: true}))
+
+	result, err := util.ReadFile(fs, "large")
+	s.Require().NoError(err)
+	s.Equal(strings.ReplaceAll(content, "\n", "\r\n"), string(result))
 }
 
 func (s *WorktreeSuite) TestFilenameNormalization() {
@@ -1251,6 +1939,37 @@ func (s *WorktreeSuite) TestStatusEmptyDirty() {
 	s.Len(status, 1)
 }
 
+func (s *WorktreeSuite) TestStatusIgnoreCase() {
+	fs := memfs.New()
+	r, err := Init(memory.NewStorage(), WithWorkTree(fs))
+	s.Require().NoError(err)
+
+	w, err := r.Worktree()
+	s.Require().NoError(err)
+
+	s.Require().NoError(util.WriteFile(fs, "README", []byte("foo"), 0o644))
+	_, err = w.Add("README")
+	s.Require().NoError(err)
+	_, err = w.Commit("foo\n", &CommitOptions{Author: defaultSignature()})
+	s.Require().NoError(err)
+
+	s.Require().NoError(fs.Rename("README", "readme"))
+
+	status, err := w.Status()
+	s.Require().NoError(err)
+	s.Equal(Deleted, status.File("README").Worktree)
+	s.Equal(Untracked, status.File("readme").Worktree)
+
+	cfg, err := r.Config()
+	s.Require().NoError(err)
+	cfg.Core.IgnoreCase = true
+	s.Require().NoError(r.SetConfig(cfg))
+
+	status, err = w.Status()
+	s.Require().NoError(err)
+	s.True(status.IsClean(), status.String())
+}
+
 func (s *WorktreeSuite) TestStatusUnmodified() {
 	fs := memfs.New()
 	w := &Worktree{
@@ -1697,6 +2416,41 @@ func (s *WorktreeSuite) TestResetHard() {
 	s.Equal(commit, branch.Hash())
 }
 
+func (s *WorktreeSuite) TestResetHardPathSpecs() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	s.NoError(w.Checkout(&CheckoutOptions{}))
+
+	for _, name := range []string{".gitignore", "go/example.go", "json/short.json"} {
+		s.NoError(util.WriteFile(fs, name, []byte("foo"), 0o644))
+	}
+
+	err := w.Reset(&ResetOptions{Mode: HardReset, PathSpecs: []string{":(top,bar)go"}})
+	s.ErrorIs(err, ErrUnsupportedPathSpecMagic)
+
+	s.NoError(w.Reset(&ResetOptions{
+		Mode:      HardReset,
+		PathSpecs: []string{":(exclude)go", ":!*.json"},
+	}))
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.Len(status, 2)
+	s.Equal(Modified, status.File("go/example.go").Worktree)
+	s.Equal(Modified, status.File("json/short.json").Worktree)
+
+	s.NoError(w.Reset(&ResetOptions{Mode: HardReset, PathSpecs: []string{"go"}}))
+
+	status, err = w.Status()
+	s.NoError(err)
+	s.Len(status, 1)
+	s.Equal(Modified, status.File("json/short.json").Worktree)
+}
+
 func (s *WorktreeSuite) TestResetHardSubFolders() {
 	fs := memfs.New()
 	w := &Worktree{
@@ -2324,6 +3078,62 @@ func (s *WorktreeSuite) TestAddCRLF() {
 	})
 }
 
+func (s *WorktreeSuite) TestAddCRLFAttributes() {
+	runTest := func(t *testing.T, autoCRLF, attributes, name string) (result []byte) {
+		r := NewRepositoryWithEmptyWorktree(fixtures.Basic().One())
+
+		cfg, err := r.Config()
+		require.NoError(t, err)
+		cfg.Core.AutoCRLF = autoCRLF
+		require.NoError(t, r.SetConfig(cfg))
+
+		wt, err := r.Worktree()
+		require.NoError(t, err)
+
+		require.NoError(t, util.WriteFile(wt.Filesystem, "sub/.gitattributes", []byte(attributes), 0o644))
+		require.NoError(t, util.WriteFile(wt.Filesystem, "sub/"+name, []byte("FOO\r\n\x00"), 0o644))
+
+		h, err := wt.Add("sub/" + name)
+		require.NoError(t, err)
+
+		status, err := wt.Status()
+		require.NoError(t, err)
+		assert.Equal(t, Unmodified, status.File("sub/"+name).Worktree)
+
+		obj, err := r.Storer.EncodedObject(plumbing.BlobObject, h)
+		require.NoError(t, err)
+
+		reader, err := obj.Reader()
+		require.NoError(t, err)
+		defer reader.Close()
+
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+
+		return content
+	}
+
+	s.Run("text without autocrlf", func() {
+		result := runTest(s.T(), "false", "*.txt text\n", "foo.txt")
+		s.Equal("FOO\n\x00", string(result))
+	})
+
+	s.Run("eol without autocrlf", func() {
+		result := runTest(s.T(), "", "*.txt eol=lf\n", "foo.txt")
+		s.Equal("FOO\n\x00", string(result))
+	})
+
+	s.Run("binary with autocrlf", func() {
+		result := runTest(s.T(), "true", "* text\n*.dat binary\n", "foo.dat")
+		s.Equal("FOO\r\n\x00", string(result))
+	})
+
+	s.Run("unset text with autocrlf", func() {
+		result := runTest(s.T(), "input", "*.txt -text\n", "foo.txt")
+		s.Equal("FOO\r\n\x00", string(result))
+	})
+}
+
 func (s *WorktreeSuite) TestIgnored() {
 	fs := memfs.New()
 	w := &Worktree{
@@ -2526,6 +3336,29 @@ func (s *WorktreeSuite) TestAddRemovedInDirectoryDot() {
 	s.testAddRemovedInDirectory(".", Deleted)
 }
 
+func (s *WorktreeSuite) TestAddRemovedDirectory() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	err := w.Checkout(&CheckoutOptions{Force: true})
+	s.NoError(err)
+
+	err = util.RemoveAll(w.Filesystem, "json")
+	s.NoError(err)
+
+	_, err = w.Add("json")
+	s.NoError(err)
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.Len(status, 2)
+	s.Equal(Deleted, status.File("json/long.json").Staging)
+	s.Equal(Deleted, status.File("json/short.json").Staging)
+}
+
 func (s *WorktreeSuite) TestAddSymlink() {
 	dir := s.T().TempDir()
 
@@ -2767,6 +3600,28 @@ func (s *WorktreeSuite) TestAddFilenameStartingWithDot() {
 	s.Equal(Unmodified, file.Worktree)
 }
 
+func (s *WorktreeSuite) TestAddGlobRemoved() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	err := w.Checkout(&CheckoutOptions{Force: true})
+	s.NoError(err)
+
+	err = w.Filesystem.Remove("json/long.json")
+	s.NoError(err)
+
+	err = w.AddGlob(w.Filesystem.Join("json", "l*"))
+	s.NoError(err)
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.Len(status, 1)
+	s.Equal(Deleted, status.File("json/long.json").Staging)
+}
+
 func (s *WorktreeSuite) TestAddGlobErrorNoMatches() {
 	r, _ := Init(memory.NewStorage(), WithWorkTree(memfs.New()))
 	w, _ := r.Worktree()
@@ -2887,6 +3742,9 @@ func (s *WorktreeSuite) TestAddSkipStatusWithIgnoredPath() {
 	s.Equal(Untracked, file.Worktree)
 
 	err = w.AddWithOptions(&AddOptions{Path: "fileToIgnore", SkipStatus: true})
+	s.ErrorIs(err, ErrIgnored)
+
+	err = w.AddWithOptions(&AddOptions{Path: "fileToIgnore", SkipStatus: true, Force: true})
 	s.NoError(err)
 
 	idx, err = w.r.Storer.Index()
@@ -2906,6 +3764,65 @@ func (s *WorktreeSuite) TestAddSkipStatusWithIgnoredPath() {
 	s.Equal(Unmodified, file.Worktree)
 }
 
+func (s *WorktreeSuite) TestAddIgnored() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	err := w.Checkout(&CheckoutOptions{Force: true})
+	s.Require().NoError(err)
+
+	err = util.WriteFile(fs, ".gitignore", []byte("*.log\nbuild/\n"), 0o644)
+	s.Require().NoError(err)
+	err = util.WriteFile(fs, "foo.log", []byte("foo"), 0o644)
+	s.Require().NoError(err)
+	err = util.WriteFile(fs, "bar.log", []byte("bar"), 0o644)
+	s.Require().NoError(err)
+	err = util.WriteFile(fs, "build/out", []byte("out"), 0o644)
+	s.Require().NoError(err)
+	err = util.WriteFile(fs, "qux/qux.log", []byte("qux"), 0o644)
+	s.Require().NoError(err)
+	err = util.WriteFile(fs, "qux/qux", []byte("qux"), 0o644)
+	s.Require().NoError(err)
+
+	err = w.AddWithOptions(&AddOptions{Path: "foo.log"})
+	var ignoredErr *IgnoredPathsError
+	s.Require().ErrorAs(err, &ignoredErr)
+	s.Equal([]string{"foo.log"}, ignoredErr.Paths)
+
+	_, err = w.Add("build")
+	s.ErrorIs(err, ErrIgnored)
+
+	_, err = w.Add("build/out")
+	s.ErrorIs(err, ErrIgnored)
+
+	_, err = w.Add("qux")
+	s.NoError(err)
+
+	err = w.AddGlob("*.log")
+	s.Require().ErrorAs(err, &ignoredErr)
+	s.Equal([]string{"bar.log", "foo.log"}, ignoredErr.Paths)
+
+	idx, err := w.r.Storer.Index()
+	s.Require().NoError(err)
+	s.Len(idx.Entries, 10)
+	_, err = idx.Entry("qux/qux")
+	s.NoError(err)
+
+	err = w.AddWithOptions(&AddOptions{Glob: "*.log", Force: true})
+	s.NoError(err)
+	err = w.AddWithOptions(&AddOptions{Path: "build", Force: true})
+	s.NoError(err)
+
+	idx, err = w.r.Storer.Index()
+	s.Require().NoError(err)
+	s.Len(idx.Entries, 13)
+	_, err = idx.Entry("build/out")
+	s.NoError(err)
+}
+
 func (s *WorktreeSuite) TestRemove() {
 	fs := memfs.New()
 	w := &Worktree{
@@ -3016,6 +3933,91 @@ func (s *WorktreeSuite) TestRemoveDeletedFromWorktree() {
 	s.Equal(Deleted, status.File("LICENSE").Staging)
 }
 
+func (s *WorktreeSuite) TestRemoveCached() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	err := w.Checkout(&CheckoutOptions{Force: true})
+	s.NoError(err)
+
+	err = w.RemoveWithOptions(&RemoveOptions{Path: "LICENSE", Cached: true})
+	s.NoError(err)
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.Len(status, 1)
+	s.Equal(Untracked, status.File("LICENSE").Worktree)
+
+	idx, err := w.r.Storer.Index()
+	s.NoError(err)
+	_, err = idx.Entry("LICENSE")
+	s.ErrorIs(err, index.ErrEntryNotFound)
+
+	_, err = w.Filesystem.Lstat("LICENSE")
+	s.NoError(err)
+}
+
+func (s *WorktreeSuite) TestRemoveCachedDirectory() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	err := w.Checkout(&CheckoutOptions{Force: true})
+	s.NoError(err)
+
+	err = w.RemoveWithOptions(&RemoveOptions{Path: "json", Cached: true})
+	s.NoError(err)
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.Len(status, 2)
+	s.Equal(Untracked, status.File("json/long.json").Worktree)
+	s.Equal(Untracked, status.File("json/short.json").Worktree)
+
+	_, err = w.Filesystem.Lstat("json/long.json")
+	s.NoError(err)
+
+	err = w.RemoveWithOptions(&RemoveOptions{Path: "json", Cached: true})
+	s.ErrorIs(err, index.ErrEntryNotFound)
+}
+
+func (s *WorktreeSuite) TestRemoveGlobCached() {
+	fs := memfs.New()
+	w := &Worktree{
+		r:          s.Repository,
+		Filesystem: fs,
+	}
+
+	err := w.Checkout(&CheckoutOptions{Force: true})
+	s.NoError(err)
+
+	err = w.RemoveWithOptions(&RemoveOptions{Glob: "js*", Cached: true})
+	s.NoError(err)
+
+	status, err := w.Status()
+	s.NoError(err)
+	s.Len(status, 2)
+	s.Equal(Untracked, status.File("json/short.json").Worktree)
+
+	idx, err := w.r.Storer.Index()
+	s.NoError(err)
+	s.Len(idx.Entries, 7)
+
+	_, err = w.Filesystem.Lstat("json/short.json")
+	s.NoError(err)
+}
+
+func (s *WorktreeSuite) TestRemoveWithOptionsInvalid() {
+	w := &Worktree{r: s.Repository, Filesystem: memfs.New()}
+	s.Error(w.RemoveWithOptions(&RemoveOptions{}))
+	s.Error(w.RemoveWithOptions(&RemoveOptions{Path: "foo", Glob: "foo"}))
+}
+
 func (s *WorktreeSuite) TestRemoveGlob() {
 	fs := memfs.New()
 	w := &Worktree{
@@ -4014,3 +5016,66 @@ func (s *WorktreeSuite) TestRestoreBoth() {
 		{Worktree: Untracked, Staging: Untracked},
 	})
 }
+
+func TestSparseCheckoutFromConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	r, err := PlainInit(dir, false)
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	commit := func(files ...string) plumbing.Hash {
+		for _, name := range files {
+			require.NoError(t, util.WriteFile(w.Filesystem, name, []byte(name), 0o644))
+			_, err := w.Add(name)
+			require.NoError(t, err)
+		}
+
+		h, err := w.Commit("files", defaultTestCommitOptions())
+		require.NoError(t, err)
+		return h
+	}
+
+	head := commit("top", "a/f", "b/f")
+	require.NoError(t, w.Checkout(&CheckoutOptions{Branch: "refs/heads/other", Create: true}))
+	commit("b/h")
+	require.NoError(t, w.Checkout(&CheckoutOptions{Branch: plumbing.Master}))
+
+	// Configure a cone mode sparse checkout as done by the git command.
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	cfg.Core.SparseCheckout = true
+	cfg.Core.SparseCheckoutCone = true
+	require.NoError(t, r.SetConfig(cfg))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, GitDirName, "info"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, GitDirName, "info", "sparse-checkout"),
+		[]byte("/*\n!/*/\n/a/\n"), 0o644))
+
+	require.NoError(t, w.Reset(&ResetOptions{Commit: head, Mode: HardReset}))
+
+	_, err = w.Filesystem.Lstat("b/f")
+	assert.True(t, os.IsNotExist(err))
+
+	status, err := w.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), status)
+
+	require.NoError(t, w.Checkout(&CheckoutOptions{Branch: "refs/heads/other"}))
+
+	_, err = w.Filesystem.Lstat("b/h")
+	assert.True(t, os.IsNotExist(err))
+
+	idx, err := r.Storer.Index()
+	require.NoError(t, err)
+	for _, e := range idx.Entries {
+		assert.Equal(t, strings.HasPrefix(e.Name, "b/"), e.SkipWorktree, e.Name)
+	}
+	assert.Len(t, idx.Entries, 4)
+
+	status, err = w.Status()
+	require.NoError(t, err)
+	assert.True(t, status.IsClean(), status)
+}
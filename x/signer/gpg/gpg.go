@@ -0,0 +1,72 @@
+// Package gpg signs git objects with the gpg program, as git does when
+// gpg.format is openpgp. The keys are held by gpg-agent, which may delegate
+// to a smartcard or a hardware token, so that their private part is never
+// loaded in memory.
+package gpg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/go-git/go-git/v6/x/plugin"
+)
+
+// DefaultProgram is the program run to sign, as the default gpg.program.
+const DefaultProgram = "gpg"
+
+// sigCreated starts the status line written by gpg once the signature is
+// created.
+var sigCreated = []byte("\n[GNUPG:] SIG_CREATED ")
+
+// ErrSigning is returned when gpg fails to sign.
+var ErrSigning = errors.New("gpg failed to sign the data")
+
+var _ plugin.Signer = (*Signer)(nil)
+
+// Signer signs git objects by running gpg, producing armored detached
+// OpenPGP signatures. It implements the Signer interface of go-git.
+type Signer struct {
+	// Program is the gpg program run, DefaultProgram if empty.
+	Program string
+	// KeyID is the key signing, as user.signingKey. If empty, the default
+	// key of gpg is used.
+	KeyID string
+}
+
+// New returns a Signer signing with the key keyID, using DefaultProgram.
+func New(keyID string) *Signer {
+	return &Signer{KeyID: keyID}
+}
+
+// Sign returns the armored detached signature of the message, running
+// "gpg --status-fd=2 -bsau <KeyID>" as git does.
+func (s *Signer) Sign(message io.Reader) ([]byte, error) {
+	program := s.Program
+	if program == "" {
+		program = DefaultProgram
+	}
+
+	args := []string{"--status-fd=2", "-bsa"}
+	if s.KeyID != "" {
+		args = append(args, "-u", s.KeyID)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(program, args...)
+	cmd.Stdin = message
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %w: %s", ErrSigning, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	if !bytes.Contains(append([]byte("\n"), stderr.Bytes()...), sigCreated) {
+		return nil, fmt.Errorf("%w: %s", ErrSigning, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout.Bytes(), nil
+}
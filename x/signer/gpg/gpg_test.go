@@ -0,0 +1,42 @@
+package gpg
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // sets GNUPGHOME
+func TestSign(t *testing.T) {
+	if _, err := exec.LookPath(DefaultProgram); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	t.Setenv("GNUPGHOME", t.TempDir())
+	out, err := exec.Command(DefaultProgram, "--batch", "--passphrase", "",
+		"--quick-gen-key", "Foo <foo@example.com>", "ed25519", "sign", "never").CombinedOutput()
+	if err != nil {
+		t.Skipf("cannot generate a gpg key: %s", out)
+	}
+
+	keyRing, err := exec.Command(DefaultProgram, "--armor", "--export", "foo@example.com").Output()
+	require.NoError(t, err)
+
+	message := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\nmessage\n"
+	sig, err := New("foo@example.com").Sign(strings.NewReader(message))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(sig, []byte("-----BEGIN PGP SIGNATURE-----")))
+
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyRing))
+	require.NoError(t, err)
+	_, err = openpgp.CheckArmoredDetachedSignature(keys, strings.NewReader(message), bytes.NewReader(sig), nil)
+	require.NoError(t, err)
+
+	_, err = New("unknown@example.com").Sign(strings.NewReader(message))
+	assert.ErrorIs(t, err, ErrSigning)
+}
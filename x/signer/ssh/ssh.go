@@ -0,0 +1,132 @@
+// Package ssh signs git objects with SSH keys, as git does when gpg.format is
+// ssh. The keys may be held by an SSH agent, so that their private part is
+// never loaded in memory.
+//
+// Ref: https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+package ssh
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/go-git/go-git/v6/x/plugin"
+)
+
+const (
+	// Namespace is the namespace of the signatures of git objects.
+	Namespace = "git"
+
+	sigMagic     = "SSHSIG"
+	sigVersion   = 1
+	sigHashAlgo  = "sha512"
+	armorBegin   = "-----BEGIN SSH SIGNATURE-----"
+	armorEnd     = "-----END SSH SIGNATURE-----"
+	armorLineLen = 70
+)
+
+var _ plugin.Signer = (*Signer)(nil)
+
+// ErrKeyNotFound is returned when the key is not held by the SSH agent.
+var ErrKeyNotFound = errors.New("ssh: key not found in agent")
+
+// Signer signs git objects with an SSH key, producing armored SSH
+// signatures. It implements the Signer interface of go-git.
+type Signer struct {
+	signer ssh.Signer
+}
+
+// New returns a Signer signing with s.
+func New(s ssh.Signer) *Signer {
+	return &Signer{signer: s}
+}
+
+// FromAgent returns a Signer signing with the key pub held by the SSH agent
+// a, which keeps its private part.
+func FromAgent(a agent.Agent, pub ssh.PublicKey) (*Signer, error) {
+	signers, err := a.Signers()
+	if err != nil {
+		return nil, err
+	}
+
+	want := pub.Marshal()
+	for _, s := range signers {
+		if bytes.Equal(s.PublicKey().Marshal(), want) {
+			return New(s), nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// Sign returns the armored SSH signature of the message, in the git
+// namespace.
+func (s *Signer) Sign(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, err
+	}
+
+	signed := ssh.Marshal(struct {
+		Magic     [6]byte
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Hash      string
+	}{toMagic(), Namespace, "", sigHashAlgo, string(h.Sum(nil))})
+
+	sig, err := s.sign(signed)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: signing: %w", err)
+	}
+
+	blob := ssh.Marshal(struct {
+		Magic     [6]byte
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Signature string
+	}{toMagic(), sigVersion, string(s.signer.PublicKey().Marshal()), Namespace, "", sigHashAlgo, string(ssh.Marshal(sig))})
+
+	return armor(blob), nil
+}
+
+// sign signs data, with SHA-512 for the RSA keys as ssh-keygen does.
+func (s *Signer) sign(data []byte) (*ssh.Signature, error) {
+	if as, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		return as.SignWithAlgorithm(nil, data, ssh.KeyAlgoRSASHA512)
+	}
+
+	return s.signer.Sign(nil, data)
+}
+
+func toMagic() [6]byte {
+	var m [6]byte
+	copy(m[:], sigMagic)
+	return m
+}
+
+// armor returns the blob armored, its base64 encoding wrapped in lines of
+// armorLineLen characters.
+func armor(blob []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(blob)
+
+	var b bytes.Buffer
+	b.WriteString(armorBegin + "\n")
+	for len(encoded) > armorLineLen {
+		b.WriteString(encoded[:armorLineLen] + "\n")
+		encoded = encoded[armorLineLen:]
+	}
+
+	b.WriteString(encoded + "\n")
+	b.WriteString(armorEnd + "\n")
+	return b.Bytes()
+}
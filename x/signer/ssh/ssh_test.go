@@ -0,0 +1,114 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestSignFromAgent(t *testing.T) {
+	t.Parallel()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keyring := agent.NewKeyring()
+	for _, key := range []any{edKey, rsaKey} {
+		require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: key}))
+
+		signer, err := ssh.NewSignerFromKey(key)
+		require.NoError(t, err)
+		pub := signer.PublicKey()
+
+		s, err := FromAgent(keyring, pub)
+		require.NoError(t, err)
+
+		message := []byte("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n\nmessage\n")
+		armored, err := s.Sign(bytes.NewReader(message))
+		require.NoError(t, err)
+		verify(t, pub, message, armored)
+	}
+}
+
+func TestFromAgentKeyNotFound(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	_, err = FromAgent(agent.NewKeyring(), signer.PublicKey())
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+// verify verifies the armored signature of the message by pub, and with
+// ssh-keygen if available.
+func verify(t *testing.T, pub ssh.PublicKey, message, armored []byte) {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSpace(string(armored)), "\n")
+	require.Equal(t, armorBegin, lines[0])
+	require.Equal(t, armorEnd, lines[len(lines)-1])
+	for _, line := range lines[1 : len(lines)-1] {
+		assert.LessOrEqual(t, len(line), armorLineLen)
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(strings.Join(lines[1:len(lines)-1], ""))
+	require.NoError(t, err)
+
+	var sig struct {
+		Magic     [6]byte
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Signature string
+	}
+	require.NoError(t, ssh.Unmarshal(blob, &sig))
+	assert.Equal(t, sigMagic, string(sig.Magic[:]))
+	assert.Equal(t, pub.Marshal(), []byte(sig.PublicKey))
+	assert.Equal(t, Namespace, sig.Namespace)
+
+	var signature ssh.Signature
+	require.NoError(t, ssh.Unmarshal([]byte(sig.Signature), &signature))
+
+	h := sha512.Sum512(message)
+	signed := ssh.Marshal(struct {
+		Magic     [6]byte
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Hash      string
+	}{toMagic(), Namespace, "", sigHashAlgo, string(h[:])})
+	require.NoError(t, pub.Verify(signed, &signature))
+
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		return
+	}
+
+	dir := t.TempDir()
+	sigFile := filepath.Join(dir, "sig")
+	require.NoError(t, os.WriteFile(sigFile, armored, 0o600))
+
+	cmd := exec.Command("ssh-keygen", "-Y", "check-novalidate", "-n", Namespace, "-s", sigFile)
+	cmd.Stdin = bytes.NewReader(message)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}